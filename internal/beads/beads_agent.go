@@ -19,6 +19,10 @@ type AgentFields struct {
 	CleanupStatus     string // ZFC: polecat self-reports git state (clean, has_uncommitted, has_stash, has_unpushed)
 	ActiveMR          string // Currently active merge request bead ID (for traceability)
 	NotificationLevel string // DND mode: verbose, normal, muted (default: normal)
+	Runner            string // Agent and command line the session was last started with (audit trail)
+	ContextUsage      string // Self-reported context window use in percent (budget-aware cycling)
+	LifecycleState    string // Agent state record as one-line JSON (town agent state backend "bead")
+	Capabilities      string // What the agent can work on, e.g. "lang:go, repo:gastown, tool:docker"
 }
 
 // Notification level constants
@@ -77,6 +81,22 @@ func FormatAgentDescription(title string, fields *AgentFields) string {
 		lines = append(lines, "notification_level: null")
 	}
 
+	if fields.Runner != "" {
+		lines = append(lines, fmt.Sprintf("runner: %s", fields.Runner))
+	}
+
+	if fields.ContextUsage != "" {
+		lines = append(lines, fmt.Sprintf("context_usage: %s", fields.ContextUsage))
+	}
+
+	if fields.LifecycleState != "" {
+		lines = append(lines, fmt.Sprintf("lifecycle_state: %s", fields.LifecycleState))
+	}
+
+	if fields.Capabilities != "" {
+		lines = append(lines, fmt.Sprintf("capabilities: %s", fields.Capabilities))
+	}
+
 	return strings.Join(lines, "\n")
 }
 
@@ -118,6 +138,14 @@ func ParseAgentFields(description string) *AgentFields {
 			fields.ActiveMR = value
 		case "notification_level":
 			fields.NotificationLevel = value
+		case "runner":
+			fields.Runner = value
+		case "context_usage":
+			fields.ContextUsage = value
+		case "lifecycle_state":
+			fields.LifecycleState = value
+		case "capabilities":
+			fields.Capabilities = value
 		}
 	}
 
@@ -366,6 +394,65 @@ func (b *Beads) UpdateAgentActiveMR(id string, activeMR string) error {
 	return b.Update(id, UpdateOptions{Description: &description})
 }
 
+// UpdateAgentRunner records the runner (agent name and command line) an
+// agent's session was started with, so model choices can be audited later.
+func (b *Beads) UpdateAgentRunner(id string, runner string) error {
+	// First get current issue to preserve other fields
+	issue, err := b.Show(id)
+	if err != nil {
+		return err
+	}
+
+	fields := ParseAgentFields(issue.Description)
+	if fields.Runner == runner {
+		return nil
+	}
+	fields.Runner = runner
+
+	description := FormatAgentDescription(issue.Title, fields)
+	return b.Update(id, UpdateOptions{Description: &description})
+}
+
+// UpdateAgentContextUsage records the share of its context window an agent
+// reports using, in percent, for budget-aware cycling. Pass empty string to
+// clear it.
+func (b *Beads) UpdateAgentContextUsage(id string, usage string) error {
+	// First get current issue to preserve other fields
+	issue, err := b.Show(id)
+	if err != nil {
+		return err
+	}
+
+	fields := ParseAgentFields(issue.Description)
+	fields.ContextUsage = usage
+
+	description := FormatAgentDescription(issue.Title, fields)
+	return b.Update(id, UpdateOptions{Description: &description})
+}
+
+// UpdateAgentLifecycleState replaces the agent state record kept on an
+// agent bead (one line of JSON). Pass empty string to clear it.
+func (b *Beads) UpdateAgentLifecycleState(id string, record string) error {
+	if strings.Contains(record, "\n") {
+		return fmt.Errorf("agent state record for %s must be a single line", id)
+	}
+
+	// First get current issue to preserve other fields
+	issue, err := b.Show(id)
+	if err != nil {
+		return err
+	}
+
+	fields := ParseAgentFields(issue.Description)
+	if fields.LifecycleState == record {
+		return nil
+	}
+	fields.LifecycleState = record
+
+	description := FormatAgentDescription(issue.Title, fields)
+	return b.Update(id, UpdateOptions{Description: &description})
+}
+
 // UpdateAgentNotificationLevel updates the notification_level field in an agent bead.
 // Valid levels: verbose, normal, muted (DND mode).
 // Pass empty string to reset to default (normal).
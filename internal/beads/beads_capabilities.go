@@ -0,0 +1,184 @@
+package beads
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Capabilities. An agent bead declares what its agent can work on in a
+// capabilities line, as comma-separated tokens by kind:
+//
+//	capabilities: lang:go, lang:python, repo:gastown, tool:docker
+//
+// A work bead asks for capabilities with needs:<capability> labels
+// (needs:lang:go). Matching is exact and case-insensitive; a work bead
+// without needs labels can go to any agent.
+
+// NeedsLabelPrefix starts the labels naming a capability work requires.
+const NeedsLabelPrefix = "needs:"
+
+// ParseCapabilities parses a capabilities value: tokens separated by commas
+// or whitespace, lowercased, deduplicated, and sorted.
+func ParseCapabilities(s string) []string {
+	var caps []string
+	for _, token := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		caps = append(caps, strings.ToLower(token))
+	}
+	sort.Strings(caps)
+	return slices.Compact(caps)
+}
+
+// FormatCapabilities renders capabilities for a capabilities line.
+func FormatCapabilities(caps []string) string {
+	return strings.Join(ParseCapabilities(strings.Join(caps, ",")), ", ")
+}
+
+// RequiredCapabilities returns the capabilities an issue's needs: labels
+// ask for.
+func RequiredCapabilities(issue *Issue) []string {
+	var need []string
+	for _, label := range issue.Labels {
+		if capability, ok := strings.CutPrefix(label, NeedsLabelPrefix); ok && capability != "" {
+			need = append(need, capability)
+		}
+	}
+	return ParseCapabilities(strings.Join(need, ","))
+}
+
+// MissingCapabilities returns the capabilities in need that have lacks.
+func MissingCapabilities(have, need []string) []string {
+	var missing []string
+	for _, capability := range need {
+		if !slices.Contains(have, capability) {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+// UpdateAgentCapabilities replaces the capabilities declared on an agent
+// bead. Pass nil to clear them.
+func (b *Beads) UpdateAgentCapabilities(id string, caps []string) error {
+	issue, err := b.Show(id)
+	if err != nil {
+		return err
+	}
+
+	fields := ParseAgentFields(issue.Description)
+	value := FormatCapabilities(caps)
+	if fields.Capabilities == value {
+		return nil
+	}
+	fields.Capabilities = value
+
+	description := FormatAgentDescription(issue.Title, fields)
+	return b.Update(id, UpdateOptions{Description: &description})
+}
+
+// CapableAgent is an agent work can be routed to.
+type CapableAgent struct {
+	// Address is the assignee address, e.g. "gastown/crew/max".
+	Address string
+
+	// Capabilities are the agent's declared capabilities.
+	Capabilities []string
+
+	// Load is how much work the agent already has; routing prefers the
+	// least loaded capable agent.
+	Load int
+}
+
+// WorkRoute assigns an issue to an agent.
+type WorkRoute struct {
+	Issue *Issue
+	Agent string
+}
+
+// WorkGap is an issue no agent has the capabilities for.
+type WorkGap struct {
+	Issue *Issue
+
+	// Missing are the needed capabilities no agent declares. When every
+	// capability is declared by someone but no single agent has them all,
+	// it lists all the needed capabilities.
+	Missing []string
+}
+
+// RouteWork routes issues, in order, to agents that have every capability
+// each needs, spreading work to the least loaded agent (ties go to the
+// first by address). Issues with no needs: labels are skipped unless all
+// is set. Issues no agent can take are returned as gaps.
+func RouteWork(issues []*Issue, agents []CapableAgent, all bool) ([]WorkRoute, []WorkGap) {
+	pool := slices.Clone(agents)
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].Address < pool[j].Address })
+
+	var routes []WorkRoute
+	var gaps []WorkGap
+	for _, issue := range issues {
+		need := RequiredCapabilities(issue)
+		if len(need) == 0 && !all {
+			continue
+		}
+		best := -1
+		for i, agent := range pool {
+			if len(MissingCapabilities(agent.Capabilities, need)) > 0 {
+				continue
+			}
+			if best < 0 || agent.Load < pool[best].Load {
+				best = i
+			}
+		}
+		if best < 0 {
+			gaps = append(gaps, WorkGap{Issue: issue, Missing: undeclared(pool, need)})
+			continue
+		}
+		pool[best].Load++
+		routes = append(routes, WorkRoute{Issue: issue, Agent: pool[best].Address})
+	}
+	return routes, gaps
+}
+
+// undeclared returns the capabilities in need that no agent declares, or
+// all of need if each is declared by someone.
+func undeclared(agents []CapableAgent, need []string) []string {
+	var missing []string
+	for _, capability := range need {
+		declared := slices.ContainsFunc(agents, func(a CapableAgent) bool {
+			return slices.Contains(a.Capabilities, capability)
+		})
+		if !declared {
+			missing = append(missing, capability)
+		}
+	}
+	if len(missing) == 0 {
+		return need
+	}
+	return missing
+}
+
+// CapableCrew returns the crew of a rig as routing candidates: each crew
+// agent bead among agentBeads, with its declared capabilities and as load
+// the number of issues in active (in-progress work) assigned to it.
+func CapableCrew(rigName string, agentBeads, active []*Issue) []CapableAgent {
+	load := make(map[string]int)
+	for _, issue := range active {
+		if issue.Assignee != "" {
+			load[issue.Assignee]++
+		}
+	}
+	var crew []CapableAgent
+	for _, agent := range agentBeads {
+		r, role, name, ok := ParseAgentBeadID(agent.ID)
+		if !ok || role != "crew" || r != rigName || name == "" {
+			continue
+		}
+		address := rigName + "/crew/" + name
+		crew = append(crew, CapableAgent{
+			Address:      address,
+			Capabilities: ParseCapabilities(ParseAgentFields(agent.Description).Capabilities),
+			Load:         load[address],
+		})
+	}
+	return crew
+}
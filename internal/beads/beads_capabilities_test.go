@@ -0,0 +1,105 @@
+package beads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	got := ParseCapabilities("Lang:Go, repo:gastown  tool:docker,,lang:go")
+	want := []string{"lang:go", "repo:gastown", "tool:docker"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCapabilities = %v, want %v", got, want)
+	}
+	if got := FormatCapabilities([]string{"tool:docker", "lang:go"}); got != "lang:go, tool:docker" {
+		t.Errorf("FormatCapabilities = %q", got)
+	}
+	if got := ParseCapabilities(""); len(got) != 0 {
+		t.Errorf("ParseCapabilities(\"\") = %v, want none", got)
+	}
+}
+
+func TestAgentFieldsCapabilitiesRoundTrip(t *testing.T) {
+	desc := FormatAgentDescription("Crew max", &AgentFields{
+		RoleType:     "crew",
+		Rig:          "gastown",
+		Capabilities: "lang:go, repo:gastown",
+	})
+	if got := ParseAgentFields(desc).Capabilities; got != "lang:go, repo:gastown" {
+		t.Errorf("Capabilities = %q after round trip", got)
+	}
+}
+
+func TestRequiredCapabilities(t *testing.T) {
+	issue := &Issue{Labels: []string{"bug", "needs:lang:go", "needs:", "needs:Tool:Docker"}}
+	want := []string{"lang:go", "tool:docker"}
+	if got := RequiredCapabilities(issue); !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredCapabilities = %v, want %v", got, want)
+	}
+}
+
+func TestRouteWork(t *testing.T) {
+	agents := []CapableAgent{
+		{Address: "gastown/crew/zoe", Capabilities: []string{"lang:go", "lang:python"}},
+		{Address: "gastown/crew/max", Capabilities: []string{"lang:go", "tool:docker"}, Load: 1},
+		{Address: "gastown/crew/ann", Capabilities: []string{"lang:python"}},
+	}
+	issues := []*Issue{
+		{ID: "gt-1", Labels: []string{"needs:lang:go"}},
+		{ID: "gt-2", Labels: []string{"needs:lang:go"}},
+		{ID: "gt-3", Labels: []string{"needs:lang:rust"}},
+		{ID: "gt-4", Labels: []string{"needs:tool:docker", "needs:lang:python"}},
+		{ID: "gt-5"},
+	}
+
+	routes, gaps := RouteWork(issues, agents, false)
+
+	gotRoutes := map[string]string{}
+	for _, r := range routes {
+		gotRoutes[r.Issue.ID] = r.Agent
+	}
+	wantRoutes := map[string]string{
+		"gt-1": "gastown/crew/zoe", // least loaded
+		"gt-2": "gastown/crew/max", // tie at 1 goes to the first by address
+	}
+	if !reflect.DeepEqual(gotRoutes, wantRoutes) {
+		t.Errorf("routes = %v, want %v", gotRoutes, wantRoutes)
+	}
+
+	if len(gaps) != 2 {
+		t.Fatalf("got %d gaps, want 2: %+v", len(gaps), gaps)
+	}
+	if gaps[0].Issue.ID != "gt-3" || !reflect.DeepEqual(gaps[0].Missing, []string{"lang:rust"}) {
+		t.Errorf("gap[0] = %s %v, want gt-3 [lang:rust]", gaps[0].Issue.ID, gaps[0].Missing)
+	}
+	// Both capabilities exist, just not on one agent.
+	if gaps[1].Issue.ID != "gt-4" || !reflect.DeepEqual(gaps[1].Missing, []string{"lang:python", "tool:docker"}) {
+		t.Errorf("gap[1] = %s %v, want gt-4 [lang:python tool:docker]", gaps[1].Issue.ID, gaps[1].Missing)
+	}
+
+	if routes, _ := RouteWork(issues[4:], agents, true); len(routes) != 1 || routes[0].Agent != "gastown/crew/ann" {
+		t.Errorf("RouteWork(all) = %+v, want gt-5 to gastown/crew/ann", routes)
+	}
+}
+
+func TestCapableCrew(t *testing.T) {
+	agentBeads := []*Issue{
+		{ID: "gt-gastown-crew-max", Description: "Crew max\n\ncapabilities: lang:go"},
+		{ID: "gt-gastown-crew-joe"},
+		{ID: "gt-gastown-polecat-toast", Description: "capabilities: lang:go"},
+		{ID: "gt-beads-crew-emma", Description: "capabilities: lang:go"},
+	}
+	active := []*Issue{
+		{ID: "gt-1", Assignee: "gastown/crew/max"},
+		{ID: "gt-2", Assignee: "gastown/crew/max"},
+		{ID: "gt-3"},
+	}
+	got := CapableCrew("gastown", agentBeads, active)
+	want := []CapableAgent{
+		{Address: "gastown/crew/max", Capabilities: []string{"lang:go"}, Load: 2},
+		{Address: "gastown/crew/joe"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CapableCrew = %+v, want %+v", got, want)
+	}
+}
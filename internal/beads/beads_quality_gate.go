@@ -0,0 +1,212 @@
+// Package beads provides quality gate bead management.
+// Quality gates are per-rig completion requirements published by the witness.
+package beads
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// QualityGateLabel marks a bead as a rig's quality gate entry.
+const QualityGateLabel = "gt:quality-gate"
+
+// QualityGateFields holds structured fields for quality gate beads.
+// These are stored as "key: value" lines in the description.
+type QualityGateFields struct {
+	Rig            string   // Rig the gates apply to
+	RequiredChecks []string // Shell commands that must pass in the worktree before completion
+	BlockedPaths   []string // Path patterns completed work must not touch
+	UpdatedBy      string   // Who last published the gates (usually the rig's witness)
+	UpdatedAt      string   // ISO 8601 timestamp
+}
+
+// FormatQualityGateDescription creates a description string from quality gate fields.
+// Checks are separated by ";" since commands commonly contain commas.
+func FormatQualityGateDescription(title string, fields *QualityGateFields) string {
+	if fields == nil {
+		return title
+	}
+
+	var lines []string
+	lines = append(lines, title)
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("rig: %s", fields.Rig))
+
+	if len(fields.RequiredChecks) > 0 {
+		lines = append(lines, fmt.Sprintf("required_checks: %s", strings.Join(fields.RequiredChecks, "; ")))
+	} else {
+		lines = append(lines, "required_checks: null")
+	}
+
+	if len(fields.BlockedPaths) > 0 {
+		lines = append(lines, fmt.Sprintf("blocked_paths: %s", strings.Join(fields.BlockedPaths, ",")))
+	} else {
+		lines = append(lines, "blocked_paths: null")
+	}
+
+	if fields.UpdatedBy != "" {
+		lines = append(lines, fmt.Sprintf("updated_by: %s", fields.UpdatedBy))
+	} else {
+		lines = append(lines, "updated_by: null")
+	}
+
+	if fields.UpdatedAt != "" {
+		lines = append(lines, fmt.Sprintf("updated_at: %s", fields.UpdatedAt))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ParseQualityGateFields extracts quality gate fields from a description.
+func ParseQualityGateFields(description string) *QualityGateFields {
+	fields := &QualityGateFields{}
+
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		colonIdx := strings.Index(line, ":")
+		if colonIdx == -1 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:colonIdx])
+		value := strings.TrimSpace(line[colonIdx+1:])
+		if value == "null" || value == "" {
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "rig":
+			fields.Rig = value
+		case "required_checks":
+			for _, c := range strings.Split(value, ";") {
+				if c = strings.TrimSpace(c); c != "" {
+					fields.RequiredChecks = append(fields.RequiredChecks, c)
+				}
+			}
+		case "blocked_paths":
+			for _, p := range strings.Split(value, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					fields.BlockedPaths = append(fields.BlockedPaths, p)
+				}
+			}
+		case "updated_by":
+			fields.UpdatedBy = value
+		case "updated_at":
+			fields.UpdatedAt = value
+		}
+	}
+
+	return fields
+}
+
+// IsEmpty reports whether the gates impose no requirements.
+func (f *QualityGateFields) IsEmpty() bool {
+	return f == nil || (len(f.RequiredChecks) == 0 && len(f.BlockedPaths) == 0)
+}
+
+// BlockedPathViolations returns the changed files that match a blocked path.
+func (f *QualityGateFields) BlockedPathViolations(changed []string) []string {
+	if f == nil {
+		return nil
+	}
+	var violations []string
+	for _, file := range changed {
+		for _, pattern := range f.BlockedPaths {
+			if MatchBlockedPath(pattern, file) {
+				violations = append(violations, file)
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// MatchBlockedPath reports whether a repo-relative file path matches a blocked
+// path pattern. Patterns ending in "/" or "/**" block a whole directory tree;
+// patterns without a "/" match the file's base name anywhere in the tree;
+// anything else is matched against the full path with path.Match.
+func MatchBlockedPath(pattern, file string) bool {
+	file = strings.TrimPrefix(file, "./")
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return file == dir || strings.HasPrefix(file, dir+"/")
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(file, pattern)
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	ok, _ := path.Match(pattern, file)
+	return ok
+}
+
+// QualityGateBeadID returns the bead ID for a rig's quality gates.
+// Format: hq-quality-gate-<rig> (town-level, so dispatch and completion can
+// find it from any rig without routing).
+func QualityGateBeadID(rig string) string {
+	return "hq-quality-gate-" + rig
+}
+
+// GetQualityGates retrieves a rig's quality gates.
+// Returns nil, nil if the rig has no published gates.
+func (b *Beads) GetQualityGates(rig string) (*Issue, *QualityGateFields, error) {
+	id := QualityGateBeadID(rig)
+	issue, err := b.Show(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	if !HasLabel(issue, QualityGateLabel) {
+		return nil, nil, fmt.Errorf("bead %s is not a quality gate bead (missing %s label)", id, QualityGateLabel)
+	}
+
+	return issue, ParseQualityGateFields(issue.Description), nil
+}
+
+// SetQualityGates publishes a rig's quality gates, creating the gate bead on
+// first use and replacing its fields afterwards.
+func (b *Beads) SetQualityGates(fields *QualityGateFields) error {
+	if fields == nil || fields.Rig == "" {
+		return fmt.Errorf("quality gates require a rig")
+	}
+	fields.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	title := fmt.Sprintf("Quality gates: %s", fields.Rig)
+	description := FormatQualityGateDescription(title, fields)
+
+	existing, _, err := b.GetQualityGates(fields.Rig)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return b.Update(existing.ID, UpdateOptions{Description: &description})
+	}
+
+	args := []string{"create", "--json",
+		"--id=" + QualityGateBeadID(fields.Rig),
+		"--title=" + title,
+		"--description=" + description,
+		"--type=task", // Gates use task type with gt:quality-gate label
+		"--labels=" + QualityGateLabel,
+		"--force", // Override prefix check (town beads may have mixed prefixes)
+	}
+	if actor := b.getActor(); actor != "" {
+		args = append(args, "--actor="+actor)
+	}
+
+	_, err = b.run(args...)
+	return err
+}
@@ -0,0 +1,57 @@
+package beads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQualityGateRoundTrip(t *testing.T) {
+	original := &QualityGateFields{
+		Rig:            "gastown",
+		RequiredChecks: []string{"go test ./...", "golangci-lint run --enable=errcheck,govet"},
+		BlockedPaths:   []string{"internal/secrets/**", "go.sum"},
+		UpdatedBy:      "gastown/witness",
+		UpdatedAt:      "2026-01-15T10:00:00Z",
+	}
+
+	parsed := ParseQualityGateFields(FormatQualityGateDescription("Quality gates: gastown", original))
+	if !reflect.DeepEqual(parsed, original) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", parsed, original)
+	}
+
+	empty := ParseQualityGateFields(FormatQualityGateDescription("Quality gates: x", &QualityGateFields{Rig: "x"}))
+	if !empty.IsEmpty() {
+		t.Errorf("expected empty gates, got %+v", empty)
+	}
+}
+
+func TestMatchBlockedPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"internal/secrets/**", "internal/secrets/keys.go", true},
+		{"internal/secrets/**", "internal/secrets", true},
+		{"internal/secrets/**", "internal/secretsfoo/x.go", false},
+		{"migrations/", "migrations/001.sql", true},
+		{"go.sum", "go.sum", true},
+		{"go.sum", "tools/go.sum", true},
+		{"*.pem", "deploy/certs/server.pem", true},
+		{"deploy/*.yaml", "deploy/prod.yaml", true},
+		{"deploy/*.yaml", "deploy/prod/app.yaml", false},
+		{"./docs/**", "docs/README.md", true},
+	}
+
+	for _, tc := range tests {
+		if got := MatchBlockedPath(tc.pattern, tc.file); got != tc.want {
+			t.Errorf("MatchBlockedPath(%q, %q) = %v, want %v", tc.pattern, tc.file, got, tc.want)
+		}
+	}
+
+	gates := &QualityGateFields{BlockedPaths: []string{"internal/secrets/**"}}
+	got := gates.BlockedPathViolations([]string{"README.md", "internal/secrets/a.go"})
+	if !reflect.DeepEqual(got, []string{"internal/secrets/a.go"}) {
+		t.Errorf("BlockedPathViolations = %v", got)
+	}
+}
@@ -1388,6 +1388,35 @@ func TestRoleConfigRoundTrip(t *testing.T) {
 	}
 }
 
+// TestRoleConfigLayout tests parsing and formatting session layout fields.
+func TestRoleConfigLayout(t *testing.T) {
+	description := `layout: main-vertical
+layout_pane: tail -F {town}/daemon/daemon.log
+layout_pane: go test ./... -run . -v
+layout_window: feed=gt feed
+layout_window: no command`
+
+	config := ParseRoleConfig(description)
+	if config == nil {
+		t.Fatal("ParseRoleConfig returned nil")
+	}
+	if config.Layout != "main-vertical" {
+		t.Errorf("Layout = %q, want main-vertical", config.Layout)
+	}
+	if len(config.LayoutPanes) != 2 || config.LayoutPanes[1] != "go test ./... -run . -v" {
+		t.Errorf("LayoutPanes = %q", config.LayoutPanes)
+	}
+	if len(config.LayoutWindows) != 1 || config.LayoutWindows[0] != (RoleWindow{Name: "feed", Command: "gt feed"}) {
+		t.Errorf("LayoutWindows = %+v, want only feed (no name=command window skipped)", config.LayoutWindows)
+	}
+
+	reparsed := ParseRoleConfig(FormatRoleConfig(config))
+	if reparsed == nil || reparsed.Layout != config.Layout ||
+		len(reparsed.LayoutPanes) != 2 || len(reparsed.LayoutWindows) != 1 {
+		t.Errorf("round-trip = %+v", reparsed)
+	}
+}
+
 // TestRoleBeadID tests role bead ID generation.
 func TestRoleBeadID(t *testing.T) {
 	tests := []struct {
@@ -2380,3 +2409,33 @@ func TestCloseAndClearAgentBead_ReasonVariations(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentFieldsRunnerRoundTrip(t *testing.T) {
+	fields := &AgentFields{
+		RoleType:   "witness",
+		Rig:        "gastown",
+		AgentState: "running",
+		Runner:     "claude-haiku: claude --dangerously-skip-permissions --model haiku",
+	}
+	parsed := ParseAgentFields(FormatAgentDescription("Witness", fields))
+	if parsed.Runner != fields.Runner {
+		t.Errorf("Runner = %q, want %q", parsed.Runner, fields.Runner)
+	}
+
+	fields.Runner = ""
+	if desc := FormatAgentDescription("Witness", fields); strings.Contains(desc, "runner:") {
+		t.Errorf("empty runner should be omitted, got:\n%s", desc)
+	}
+}
+
+func TestAgentFieldsLifecycleStateRoundTrip(t *testing.T) {
+	fields := &AgentFields{
+		RoleType:       "crew",
+		Rig:            "gastown",
+		LifecycleState: `{"requesting_cycle":true,"requesting_time":"2026-01-02T03:04:05Z"}`,
+	}
+	parsed := ParseAgentFields(FormatAgentDescription("Crew max", fields))
+	if parsed.LifecycleState != fields.LifecycleState {
+		t.Errorf("LifecycleState = %q, want %q", parsed.LifecycleState, fields.LifecycleState)
+	}
+}
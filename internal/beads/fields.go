@@ -547,6 +547,28 @@ type RoleConfig struct {
 	// StuckThreshold is how long a wisp can be in_progress before considered stuck.
 	// Format: duration string (e.g., "1h", "30m"). Default: 1h.
 	StuckThreshold string
+
+	// Session layout: extra panes and windows around the agent, created when
+	// the daemon starts the session. Commands support the same placeholders
+	// as the patterns above.
+
+	// Layout is the tmux layout applied to the agent's window once its extra
+	// panes exist (e.g. "main-vertical", "even-horizontal", "tiled").
+	Layout string
+
+	// LayoutPanes are commands run in panes beside the agent, in order.
+	// Format: "layout_pane: tail -F {town}/daemon/daemon.log"
+	LayoutPanes []string
+
+	// LayoutWindows are extra windows after the agent's.
+	// Format: "layout_window: tests=go test ./... -count=1 -run . -v"
+	LayoutWindows []RoleWindow
+}
+
+// RoleWindow is an extra tmux window of a role's session layout.
+type RoleWindow struct {
+	Name    string
+	Command string
 }
 
 // ParseRoleConfig extracts RoleConfig from a role bead's description.
@@ -610,6 +632,21 @@ func ParseRoleConfig(description string) *RoleConfig {
 		case "stuck_threshold", "stuck-threshold", "stuckthreshold":
 			config.StuckThreshold = value
 			hasFields = true
+		case "layout":
+			config.Layout = value
+			hasFields = true
+		case "layout_pane", "layout-pane":
+			config.LayoutPanes = append(config.LayoutPanes, value)
+			hasFields = true
+		case "layout_window", "layout-window":
+			// Format: "layout_window: NAME=COMMAND"
+			if eqIdx := strings.Index(value, "="); eqIdx > 0 {
+				config.LayoutWindows = append(config.LayoutWindows, RoleWindow{
+					Name:    strings.TrimSpace(value[:eqIdx]),
+					Command: strings.TrimSpace(value[eqIdx+1:]),
+				})
+				hasFields = true
+			}
 		}
 	}
 
@@ -650,6 +687,15 @@ func FormatRoleConfig(config *RoleConfig) string {
 	for k, v := range config.EnvVars {
 		lines = append(lines, "env_var: "+k+"="+v)
 	}
+	if config.Layout != "" {
+		lines = append(lines, "layout: "+config.Layout)
+	}
+	for _, pane := range config.LayoutPanes {
+		lines = append(lines, "layout_pane: "+pane)
+	}
+	for _, w := range config.LayoutWindows {
+		lines = append(lines, "layout_window: "+w.Name+"="+w.Command)
+	}
 
 	return strings.Join(lines, "\n")
 }
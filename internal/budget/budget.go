@@ -0,0 +1,153 @@
+// Package budget enforces per-rig monthly spending budgets.
+//
+// Budgets are configured in the rig's settings (config.BudgetConfig). Budget
+// state - alerts already sent, hard-stop pauses and operator approvals - lives
+// in the rig's wisp layer, so it is local to this town and never synced.
+package budget
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/wisp"
+)
+
+// Wisp config keys for budget state.
+const (
+	// PausedKey holds the period (YYYY-MM) in which the rig's budget hard stop
+	// tripped. Non-critical agents are refused while it is set.
+	PausedKey = "budget_paused"
+
+	// ApprovedKey holds the period an operator approved overspending for.
+	// The hard stop won't trip again until the next period.
+	ApprovedKey = "budget_approved"
+
+	// AlertedKey holds "<period>:<pct>", the highest threshold already alerted.
+	AlertedKey = "budget_alerted"
+)
+
+// Period returns the budget period (calendar month, YYYY-MM) containing t.
+func Period(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// MonthStart returns midnight on the first day of t's month.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// LoadConfig returns the budget config for a rig, or nil if none is set.
+func LoadConfig(townRoot, rigName string) *config.BudgetConfig {
+	settings, err := config.LoadRigSettings(config.RigSettingsPath(filepath.Join(townRoot, rigName)))
+	if err != nil || settings.Budget == nil || settings.Budget.MonthlyUSD <= 0 {
+		return nil
+	}
+	return settings.Budget
+}
+
+// Thresholds returns the configured alert thresholds in ascending order.
+func Thresholds(cfg *config.BudgetConfig) []int {
+	thresholds := cfg.AlertThresholds
+	if len(thresholds) == 0 {
+		thresholds = config.DefaultBudgetAlertThresholds
+	}
+	sorted := slices.Clone(thresholds)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// IsCritical reports whether role keeps running when the budget is exhausted.
+func IsCritical(cfg *config.BudgetConfig, role string) bool {
+	roles := config.DefaultBudgetCriticalRoles
+	if cfg != nil && len(cfg.CriticalRoles) > 0 {
+		roles = cfg.CriticalRoles
+	}
+	return slices.Contains(roles, role)
+}
+
+// Evaluation is the outcome of comparing spend against a budget.
+type Evaluation struct {
+	Percent float64 // spend as a percentage of the budget
+
+	// Threshold is the highest alert threshold newly crossed, or 0 if no
+	// alert is due (nothing crossed, or already alerted this period).
+	Threshold int
+
+	// Exhausted is true once spend reaches the budget.
+	Exhausted bool
+}
+
+// Evaluate compares spent against cfg. lastAlerted is the highest threshold
+// already alerted this period.
+func Evaluate(cfg *config.BudgetConfig, spent float64, lastAlerted int) Evaluation {
+	eval := Evaluation{
+		Percent:   spent / cfg.MonthlyUSD * 100,
+		Exhausted: spent >= cfg.MonthlyUSD,
+	}
+	for _, pct := range Thresholds(cfg) {
+		if eval.Percent >= float64(pct) && pct > lastAlerted {
+			eval.Threshold = pct
+		}
+	}
+	return eval
+}
+
+// LastAlerted returns the highest threshold alerted for the rig in period.
+func LastAlerted(townRoot, rigName, period string) int {
+	value := wisp.NewConfig(townRoot, rigName).GetString(AlertedKey)
+	p, pct, ok := strings.Cut(value, ":")
+	if !ok || p != period {
+		return 0
+	}
+	n, _ := strconv.Atoi(pct)
+	return n
+}
+
+// RecordAlert records that threshold was alerted for the rig in period.
+func RecordAlert(townRoot, rigName, period string, threshold int) error {
+	return wisp.NewConfig(townRoot, rigName).Set(AlertedKey, fmt.Sprintf("%s:%d", period, threshold))
+}
+
+// IsPaused reports whether the rig's budget hard stop has tripped and not
+// yet been approved.
+func IsPaused(townRoot, rigName string) bool {
+	return wisp.NewConfig(townRoot, rigName).GetString(PausedKey) != ""
+}
+
+// IsApproved reports whether an operator approved overspending in period.
+func IsApproved(townRoot, rigName, period string) bool {
+	return wisp.NewConfig(townRoot, rigName).GetString(ApprovedKey) == period
+}
+
+// Pause trips the rig's hard stop for period.
+func Pause(townRoot, rigName, period string) error {
+	return wisp.NewConfig(townRoot, rigName).Set(PausedKey, period)
+}
+
+// Approve clears the hard stop and lets the rig overspend for the rest of period.
+func Approve(townRoot, rigName, period string) error {
+	cfg := wisp.NewConfig(townRoot, rigName)
+	if err := cfg.Unset(PausedKey); err != nil {
+		return err
+	}
+	return cfg.Set(ApprovedKey, period)
+}
+
+// CheckRole reports whether an agent of role may start in the rig.
+// Returns false with a reason when the hard stop has paused the rig and the
+// role isn't critical.
+func CheckRole(townRoot, rigName, role string) (bool, string) {
+	if !IsPaused(townRoot, rigName) {
+		return true, ""
+	}
+	if IsCritical(LoadConfig(townRoot, rigName), role) {
+		return true, ""
+	}
+	return false, fmt.Sprintf("rig budget exhausted (approve with 'gt costs budget approve %s')", rigName)
+}
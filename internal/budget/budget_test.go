@@ -0,0 +1,87 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestEvaluate(t *testing.T) {
+	cfg := &config.BudgetConfig{MonthlyUSD: 100}
+
+	tests := []struct {
+		name        string
+		spent       float64
+		lastAlerted int
+		threshold   int
+		exhausted   bool
+	}{
+		{"under all thresholds", 10, 0, 0, false},
+		{"crosses 50", 55, 0, 50, false},
+		{"50 already alerted", 60, 50, 0, false},
+		{"jumps past 50 and 80", 85, 0, 80, false},
+		{"exhausted", 100, 80, 100, true},
+		{"exhausted and alerted", 120, 100, 0, true},
+	}
+
+	for _, tc := range tests {
+		got := Evaluate(cfg, tc.spent, tc.lastAlerted)
+		if got.Threshold != tc.threshold || got.Exhausted != tc.exhausted {
+			t.Errorf("%s: Evaluate(%v, %d) = %+v, expected threshold %d exhausted %v",
+				tc.name, tc.spent, tc.lastAlerted, got, tc.threshold, tc.exhausted)
+		}
+	}
+}
+
+func TestIsCritical(t *testing.T) {
+	if !IsCritical(nil, "witness") || IsCritical(nil, "polecat") {
+		t.Error("default critical roles should be witness and refinery")
+	}
+	cfg := &config.BudgetConfig{MonthlyUSD: 10, CriticalRoles: []string{"crew"}}
+	if !IsCritical(cfg, "crew") || IsCritical(cfg, "witness") {
+		t.Errorf("IsCritical should use configured roles %v", cfg.CriticalRoles)
+	}
+}
+
+func TestPauseApprove(t *testing.T) {
+	townRoot := t.TempDir()
+	period := Period(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC))
+	if period != "2026-03" {
+		t.Fatalf("Period() = %q, expected 2026-03", period)
+	}
+
+	if ok, _ := CheckRole(townRoot, "gastown", "polecat"); !ok {
+		t.Error("polecat refused before pause")
+	}
+
+	if err := Pause(townRoot, "gastown", period); err != nil {
+		t.Fatal(err)
+	}
+	if ok, reason := CheckRole(townRoot, "gastown", "polecat"); ok || reason == "" {
+		t.Errorf("CheckRole(polecat) = %v, %q; expected refusal while paused", ok, reason)
+	}
+	if ok, _ := CheckRole(townRoot, "gastown", "witness"); !ok {
+		t.Error("witness refused while paused, expected critical role to run")
+	}
+
+	if err := Approve(townRoot, "gastown", period); err != nil {
+		t.Fatal(err)
+	}
+	if IsPaused(townRoot, "gastown") || !IsApproved(townRoot, "gastown", period) {
+		t.Error("Approve should clear the pause and record approval")
+	}
+	if IsApproved(townRoot, "gastown", "2026-04") {
+		t.Error("approval should not carry into the next period")
+	}
+
+	if err := RecordAlert(townRoot, "gastown", period, 80); err != nil {
+		t.Fatal(err)
+	}
+	if got := LastAlerted(townRoot, "gastown", period); got != 80 {
+		t.Errorf("LastAlerted() = %d, expected 80", got)
+	}
+	if got := LastAlerted(townRoot, "gastown", "2026-04"); got != 0 {
+		t.Errorf("LastAlerted(next period) = %d, expected 0", got)
+	}
+}
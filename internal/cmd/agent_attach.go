@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// attendedLabel marks an agent bead while a human is attached to its session.
+const attendedLabel = "attended"
+
+var agentAttachForce bool
+
+var agentAttachCmd = &cobra.Command{
+	Use:   "attach <agent>",
+	Short: "Attach to an agent's session with lifecycle safety checks",
+	Long: `Attach your terminal to a managed agent's tmux session.
+
+Before attaching, checks whether the daemon has a lifecycle action
+(cycle, restart, shutdown) queued for the agent - either as unread
+LIFECYCLE mail in the deacon inbox or as a requesting_* flag in the
+agent's state file. If one is pending the session may be killed while
+you're typing, so attach refuses unless --force is given.
+
+The attach is recorded in the event log, and the agent bead is labeled
+'attended' until you detach.
+
+Agent can be a role (mayor, deacon, witness, refinery, crew), a path
+(<rig>/crew/<name>, <rig>/polecats/<name>) or a raw session name.
+
+Examples:
+  gt agent attach mayor
+  gt agent attach gastown/crew/max
+  gt agent attach gastown/witness --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAgentAttach,
+}
+
+func init() {
+	agentAttachCmd.Flags().BoolVarP(&agentAttachForce, "force", "f", false,
+		"Attach even if a lifecycle action is pending")
+
+	agentsCmd.AddCommand(agentAttachCmd)
+}
+
+func runAgentAttach(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	sessionName, err := resolveRoleToSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	t := tmux.NewTmux()
+	running, err := t.HasSession(sessionName)
+	if err != nil {
+		return fmt.Errorf("checking session: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("session %s is not running", sessionName)
+	}
+
+	pending, err := daemon.PendingLifecycleActions(townRoot, sessionName)
+	if err != nil {
+		// Can't prove the session is safe - warn but don't block
+		fmt.Fprintf(os.Stderr, "%s Could not check pending lifecycle actions: %v\n",
+			style.Warning.Render("⚠"), err)
+	}
+	var pendingActions []string
+	for _, p := range pending {
+		pendingActions = append(pendingActions, string(p.Action))
+	}
+	if len(pending) > 0 {
+		fmt.Printf("%s Daemon has pending lifecycle action(s) for %s:\n",
+			style.Warning.Render("⚠"), sessionName)
+		for _, p := range pending {
+			fmt.Printf("  %s (from %s via %s)\n", p.Action, p.Identity, p.Source)
+		}
+		if !agentAttachForce {
+			return fmt.Errorf("session may be killed while attached; use --force to attach anyway")
+		}
+	}
+
+	agentID := sessionToAgentID(sessionName)
+	_ = events.LogFeed(events.TypeAttach, detectActor(),
+		events.AttachPayload(sessionName, agentID, pendingActions))
+
+	agentBeadID := agentIDToBeadID(agentID, townRoot)
+	if agentBeadID != "" {
+		bd := beads.New(beads.ResolveHookDir(townRoot, agentBeadID, ""))
+		if err := bd.Update(agentBeadID, beads.UpdateOptions{AddLabels: []string{attendedLabel}}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't mark %s attended: %v\n", agentBeadID, err)
+			agentBeadID = ""
+		}
+	}
+
+	attachErr := attachToTmuxSession(sessionName)
+
+	// Inside tmux, switch-client returns immediately, so the attended label
+	// stays until the agent is next cycled. Outside tmux we're back after detach.
+	if agentBeadID != "" && os.Getenv("TMUX") == "" {
+		bd := beads.New(beads.ResolveHookDir(townRoot, agentBeadID, ""))
+		if err := bd.Update(agentBeadID, beads.UpdateOptions{RemoveLabels: []string{attendedLabel}}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't clear attended label on %s: %v\n", agentBeadID, err)
+		}
+	}
+
+	return attachErr
+}
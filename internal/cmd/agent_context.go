@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var agentContextBead bool
+
+var agentContextCmd = &cobra.Command{
+	Use:   "context <percent> [agent]",
+	Short: "Report how much of its context window an agent has used",
+	Long: `Record an agent's context window usage, in percent, in its state.json
+(or with --bead, on its agent bead).
+
+When budget-aware cycling is on (lifecycle.context_budget in
+mayor/daemon.json), the daemon schedules a cycle for an agent that reports
+cycle_at percent or more and carries it out once the agent's session goes
+idle, so the agent is not cut off mid-task. At force_at percent it cycles
+the agent right away. A report is cleared when the session is cycled.
+
+Agent defaults to the agent of the current session (from GT_ROLE, GT_RIG,
+GT_CREW, GT_POLECAT). It is a daemon identity (gastown-crew-max) or a path
+(gastown/crew/max).
+
+Examples:
+  gt agents context 82
+  gt agents context 90% gastown/crew/max
+  gt agents context 85 --bead`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runAgentContext,
+}
+
+func init() {
+	agentContextCmd.Flags().BoolVar(&agentContextBead, "bead", false, "Record the usage on the agent bead instead of state.json")
+	agentsCmd.AddCommand(agentContextCmd)
+}
+
+func runAgentContext(cmd *cobra.Command, args []string) error {
+	usage, err := daemon.ParseContextUsage(args[0])
+	if err != nil {
+		return err
+	}
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	identity := daemon.IdentityFromEnv(os.Getenv)
+	if len(args) > 1 {
+		identity = args[1]
+	}
+	if identity == "" {
+		return fmt.Errorf("not in an agent session; name the agent")
+	}
+
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	return ctl.ReportContextUsage(identity, usage, agentContextBead, time.Now())
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	agentExecWindow  bool
+	agentExecTimeout time.Duration
+)
+
+var agentExecCmd = &cobra.Command{
+	Use:   "exec <agent> -- <command>...",
+	Short: "Run a command in an agent's workdir and environment",
+	Long: `Run a shell command in an agent's context and print its output,
+without disturbing the agent's pane - e.g. to run the tests in a crew
+member's workspace or check its git status.
+
+By default the command runs here, in the agent's workdir, with the
+environment of its tmux session (GT_ROLE, BD_ACTOR, ...) layered over
+yours. If the session isn't running, only the workdir is used.
+
+With --window the command runs in a new, detached window of the agent's
+session instead, so it sees exactly what the agent sees. The window closes
+when the command finishes.
+
+The command's exit status becomes gt's exit status. Everything after --
+is passed to sh -c, joined by spaces.
+
+Agent can be a role (mayor, deacon, witness, refinery, crew), a path
+(<rig>/crew/<name>, <rig>/polecats/<name>) or a raw session name.
+
+Examples:
+  gt agent exec gastown/crew/max -- git status --short
+  gt agent exec gastown/polecats/Toast -- go test ./...
+  gt agent exec gastown/witness --window -- 'env | grep ^GT_'`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runAgentExec,
+}
+
+func init() {
+	agentExecCmd.Flags().BoolVar(&agentExecWindow, "window", false, "Run in a new window of the agent's session")
+	agentExecCmd.Flags().DurationVar(&agentExecTimeout, "timeout", 10*time.Minute, "Give up on the command after this long")
+
+	agentsCmd.AddCommand(agentExecCmd)
+}
+
+func runAgentExec(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	sessionName, err := resolveRoleToSession(args[0])
+	if err != nil {
+		return err
+	}
+	command := strings.Join(args[1:], " ")
+
+	t := tmux.NewTmux()
+	running, err := t.HasSession(sessionName)
+	if err != nil {
+		return fmt.Errorf("checking session: %w", err)
+	}
+	workDir, err := sessionWorkDir(sessionName, townRoot)
+	if err != nil && running {
+		workDir, err = t.GetPaneWorkDir(sessionName)
+	}
+	if err != nil {
+		return fmt.Errorf("finding workdir of %s: %w", sessionName, err)
+	}
+
+	if agentExecWindow {
+		if !running {
+			return fmt.Errorf("session %s is not running", sessionName)
+		}
+		result, err := t.RunInWindow(sessionName, "gt-exec", workDir, command, agentExecTimeout)
+		if result != nil {
+			fmt.Print(result.Output)
+		}
+		if errors.Is(err, tmux.ErrCommandTimeout) {
+			return fmt.Errorf("command timed out after %s", agentExecTimeout)
+		}
+		if err != nil {
+			return err
+		}
+		if result.ExitCode != 0 {
+			return NewSilentExit(result.ExitCode)
+		}
+		return nil
+	}
+
+	env := os.Environ()
+	if running {
+		sessionEnv, err := t.GetAllEnvironment(sessionName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Could not read the environment of %s: %v\n",
+				style.Warning.Render("⚠"), sessionName, err)
+		}
+		env = append(env, agentExecEnv(sessionEnv)...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), agentExecTimeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Dir = workDir
+	c.Env = env
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("command timed out after %s", agentExecTimeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return NewSilentExit(exitErr.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
+
+// agentExecEnv formats a session environment as KEY=value entries, sorted.
+// Later entries win in exec, so these override the caller's own.
+func agentExecEnv(sessionEnv map[string]string) []string {
+	env := make([]string, 0, len(sessionEnv))
+	for key, value := range sessionEnv {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var agentHeartbeatCmd = &cobra.Command{
+	Use:   "heartbeat [agent]",
+	Short: "Report that an agent is alive",
+	Long: `Stamp last_heartbeat in an agent's state.json.
+
+When the daemon's heartbeat protocol is on (lifecycle.agent_heartbeats in
+mayor/daemon.json), covered agents run this every GT_HEARTBEAT_INTERVAL.
+An agent whose session is running but which stops reporting is marked
+stuck on its agent bead, and cycled if cycle_after is set. The mark is
+cleared at the next heartbeat.
+
+Agent defaults to the agent of the current session (from GT_ROLE, GT_RIG,
+GT_CREW, GT_POLECAT). It is a daemon identity (gastown-crew-max) or a path
+(gastown/crew/max).
+
+Examples:
+  gt agents heartbeat
+  gt agents heartbeat gastown/crew/max`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAgentHeartbeat,
+}
+
+func init() {
+	agentsCmd.AddCommand(agentHeartbeatCmd)
+}
+
+func runAgentHeartbeat(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	identity := daemon.IdentityFromEnv(os.Getenv)
+	if len(args) > 0 {
+		identity = args[0]
+	}
+	if identity == "" {
+		return fmt.Errorf("not in an agent session; name the agent")
+	}
+
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	return ctl.Heartbeat(identity, time.Now())
+}
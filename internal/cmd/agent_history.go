@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	agentHistorySince string
+	agentHistoryLimit int
+	agentHistoryJSON  bool
+)
+
+var agentHistoryCmd = &cobra.Command{
+	Use:   "history [agent]",
+	Short: "Show an agent's restart history",
+	Long: `Show every cycle and restart the daemon has carried out for an agent:
+when, who asked for it and why, how long it took, and whether it worked.
+
+Agents can give a reason, the bead they were working on, and an urgency
+with their lifecycle request ("reason", "task_bead", and "urgency" in the
+mail body); restarts the daemon decides on itself name the check that
+asked for them (daemon/heartbeat, daemon/escalation, ...).
+
+Agent defaults to the agent of the current session. It is a daemon
+identity (gastown-crew-max) or a path (gastown/crew/max).
+
+Examples:
+  gt agent history gastown/crew/max
+  gt agent history gastown/witness --since 24h
+  gt agent history gastown-crew-max -n 10 --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAgentHistory,
+}
+
+func init() {
+	agentHistoryCmd.Flags().StringVar(&agentHistorySince, "since", "", "Only restarts newer than this (e.g. 30m, 1h, 2d)")
+	agentHistoryCmd.Flags().IntVarP(&agentHistoryLimit, "limit", "n", 50, "Show at most this many of the newest restarts (0 for all)")
+	agentHistoryCmd.Flags().BoolVar(&agentHistoryJSON, "json", false, "Output as JSON")
+	agentsCmd.AddCommand(agentHistoryCmd)
+}
+
+func runAgentHistory(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	identity := daemon.IdentityFromEnv(os.Getenv)
+	if len(args) > 0 {
+		identity = args[0]
+	}
+	if identity == "" {
+		return fmt.Errorf("not in an agent session; name the agent")
+	}
+
+	q := daemon.RestartQuery{Identity: identity}
+	if agentHistorySince != "" {
+		d, err := parseDuration(agentHistorySince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		q.Since = time.Now().Add(-d)
+	}
+	all, err := daemon.RestartHistory(townRoot, q)
+	if err != nil {
+		return fmt.Errorf("reading restart history: %w", err)
+	}
+	records := all
+	if agentHistoryLimit > 0 && len(records) > agentHistoryLimit {
+		records = records[len(records)-agentHistoryLimit:]
+	}
+
+	if agentHistoryJSON {
+		if records == nil {
+			records = []daemon.RestartRecord{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	if len(all) == 0 {
+		fmt.Printf("No restarts recorded for %s\n", identity)
+		return nil
+	}
+	fmt.Printf("%s %s\n\n", style.Bold.Render(identity), restartHistorySummary(all, time.Now()))
+	for _, rec := range records {
+		icon := style.Success.Render("✓")
+		if rec.Outcome != daemon.AuditOutcomeOK {
+			icon = style.Error.Render("✗")
+		}
+		fmt.Printf("%s %s  %-7s by %s, took %v\n", icon, rec.Time.Local().Format("2006-01-02 15:04:05"),
+			rec.Action, rec.RequestedBy, rec.Duration.Round(100*time.Millisecond))
+		if rec.Reason != "" {
+			fmt.Printf("    %s\n", rec.Reason)
+		}
+		if context := restartContext(rec.TaskBead, rec.Urgency); context != "" {
+			fmt.Printf("    %s\n", style.Dim.Render(context))
+		}
+		if rec.Error != "" {
+			fmt.Printf("    %s\n", style.Error.Render(rec.Error))
+		}
+	}
+	if len(records) < len(all) {
+		fmt.Printf("\n%s\n", style.Dim.Render(fmt.Sprintf("(%d older restarts not shown; use -n 0 for all)", len(all)-len(records))))
+	}
+	return nil
+}
+
+// restartHistorySummary counts the restarts, failures, and restarts in the
+// last day: "12 restarts (2 failed), 5 in the last 24h".
+func restartHistorySummary(records []daemon.RestartRecord, now time.Time) string {
+	failed, recent := 0, 0
+	for _, rec := range records {
+		if rec.Outcome != daemon.AuditOutcomeOK {
+			failed++
+		}
+		if now.Sub(rec.Time) < 24*time.Hour {
+			recent++
+		}
+	}
+	summary := fmt.Sprintf("%d restarts", len(records))
+	if len(records) == 1 {
+		summary = "1 restart"
+	}
+	if failed > 0 {
+		summary += fmt.Sprintf(" (%d failed)", failed)
+	}
+	return summary + fmt.Sprintf(", %d in the last 24h", recent)
+}
+
+// restartContext describes a restart's task bead and urgency:
+// "task gt-abc, urgency high".
+func restartContext(taskBead, urgency string) string {
+	var parts []string
+	if taskBead != "" {
+		parts = append(parts, "task "+taskBead)
+	}
+	if urgency != "" {
+		parts = append(parts, "urgency "+urgency)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/session"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/transcript"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// daemonLogTimeLayout is the timestamp prefix log.LstdFlags writes.
+const daemonLogTimeLayout = "2006/01/02 15:04:05"
+
+// agentLogsPollInterval is how often --follow checks the files for growth.
+const agentLogsPollInterval = time.Second
+
+var (
+	agentLogsFollow bool
+	agentLogsSince  string
+	agentLogsLines  int
+)
+
+var agentLogsCmd = &cobra.Command{
+	Use:   "logs <agent>",
+	Short: "Show daemon log entries and captured output for an agent",
+	Long: `Show everything recorded about one agent in one place:
+
+  daemon   lines from daemon/daemon.log that mention the agent
+  audit    kill and state records from daemon/audit.jsonl for its session
+  pane     the tail of its captured session transcript (logs/transcripts/)
+
+Daemon and audit entries are merged in time order, followed by the
+transcript tail. With --follow, new entries from all three sources are
+streamed as they are written until Ctrl+C.
+
+Transcripts are only captured when enabled in mayor/daemon.json
+("transcripts": {"enabled": true}).
+
+Agent can be a role (mayor, deacon, witness, refinery, crew), a path
+(<rig>/crew/<name>, <rig>/polecats/<name>) or a raw session name.
+
+Examples:
+  gt agent logs gastown/crew/max
+  gt agent logs gastown/witness --since 1h
+  gt agent logs gt-gastown-Toast -f -n 20`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAgentLogs,
+}
+
+func init() {
+	agentLogsCmd.Flags().BoolVarP(&agentLogsFollow, "follow", "f", false, "Stream new entries as they are written")
+	agentLogsCmd.Flags().StringVar(&agentLogsSince, "since", "", "Only daemon entries newer than this (e.g. 30m, 1h, 2d)")
+	agentLogsCmd.Flags().IntVarP(&agentLogsLines, "lines", "n", 50, "Transcript lines to show")
+
+	agentsCmd.AddCommand(agentLogsCmd)
+}
+
+// agentLogEntry is one timestamped line from the daemon log or audit log.
+type agentLogEntry struct {
+	Time   time.Time
+	Source string // "daemon" or "audit"
+	Text   string
+}
+
+// agentLogSources locates an agent's log files and recognizes its entries.
+type agentLogSources struct {
+	townRoot      string
+	sessionName   string
+	daemonLog     string
+	auditLog      string
+	transcriptDir string
+	mention       *regexp.Regexp
+}
+
+func newAgentLogSources(townRoot, sessionName string) *agentLogSources {
+	return &agentLogSources{
+		townRoot:      townRoot,
+		sessionName:   sessionName,
+		daemonLog:     daemon.DefaultConfig(townRoot).LogFile,
+		auditLog:      daemon.AuditLogFile(townRoot),
+		transcriptDir: transcript.Dir(townRoot, sessionName),
+		mention:       agentMentionPattern(sessionName),
+	}
+}
+
+// agentMentionPattern matches the names the daemon logs an agent under: its
+// session name, its daemon identity (gastown-crew-max), and its mail address
+// (gastown/crew/max, gastown/max). Names must stand alone, so "max" doesn't
+// match "maxwell".
+func agentMentionPattern(sessionName string) *regexp.Regexp {
+	names := []string{sessionName}
+	if id, err := session.ParseSessionName(sessionName); err == nil {
+		names = append(names, strings.TrimSuffix(id.Address(), "/"))
+		switch id.Role {
+		case session.RoleMayor, session.RoleDeacon:
+			names = append(names, string(id.Role))
+		case session.RoleWitness, session.RoleRefinery:
+			names = append(names, id.Rig+"-"+string(id.Role))
+		case session.RoleCrew:
+			names = append(names, id.Rig+"-crew-"+id.Name, id.Rig+"/"+id.Name)
+		case session.RolePolecat:
+			names = append(names, id.Rig+"-polecat-"+id.Name, id.Rig+"/"+id.Name)
+		}
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return regexp.MustCompile(`(^|[^A-Za-z0-9_-])(` + strings.Join(quoted, "|") + `)($|[^A-Za-z0-9_-])`)
+}
+
+func runAgentLogs(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	sessionName, err := resolveRoleToSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if agentLogsSince != "" {
+		d, err := parseDuration(agentLogsSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = time.Now().Add(-d)
+	}
+
+	src := newAgentLogSources(townRoot, sessionName)
+
+	entries, err := src.entries(since)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s\n", style.Bold.Render("Daemon log for"), sessionName)
+	if len(entries) == 0 {
+		fmt.Printf("  %s\n", style.Dim.Render("(no entries)"))
+	}
+	for _, e := range entries {
+		printAgentLogEntry(e)
+	}
+
+	lines, err := transcript.Tail(src.transcriptDir, agentLogsLines)
+	if err != nil {
+		return fmt.Errorf("reading transcript: %w", err)
+	}
+	fmt.Printf("\n%s %s\n", style.Bold.Render("Transcript"), style.Dim.Render(src.transcriptDir))
+	if len(lines) == 0 {
+		fmt.Printf("  %s\n", style.Dim.Render("(none captured - is transcript capture enabled in mayor/daemon.json?)"))
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	if !agentLogsFollow {
+		return nil
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("\n%s\n", style.Dim.Render("Following (Ctrl+C to stop)..."))
+	return src.follow(ctx, agentLogsPollInterval)
+}
+
+// entries returns the agent's daemon and audit entries since the given
+// time (zero for all), oldest first.
+func (s *agentLogSources) entries(since time.Time) ([]agentLogEntry, error) {
+	var entries []agentLogEntry
+
+	f, err := os.Open(s.daemonLog)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading daemon log: %w", err)
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if e, ok := s.daemonEntry(scanner.Text()); ok && !e.Time.Before(since) {
+				entries = append(entries, e)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading daemon log: %w", err)
+		}
+	}
+
+	records, err := daemon.LoadAuditLog(s.townRoot)
+	if err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+	for _, rec := range records {
+		if e, ok := s.auditEntry(rec); ok && !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// daemonEntry parses a daemon log line, reporting whether it mentions the agent.
+func (s *agentLogSources) daemonEntry(line string) (agentLogEntry, bool) {
+	if len(line) < len(daemonLogTimeLayout)+1 || !s.mention.MatchString(line) {
+		return agentLogEntry{}, false
+	}
+	ts, err := time.ParseInLocation(daemonLogTimeLayout, line[:len(daemonLogTimeLayout)], time.Local)
+	if err != nil {
+		return agentLogEntry{}, false
+	}
+	return agentLogEntry{Time: ts, Source: "daemon", Text: line[len(daemonLogTimeLayout)+1:]}, true
+}
+
+// auditEntry formats an audit record, reporting whether it concerns the agent.
+func (s *agentLogSources) auditEntry(rec daemon.AuditRecord) (agentLogEntry, bool) {
+	if rec.Target != s.sessionName && !s.mention.MatchString(rec.Target) {
+		return agentLogEntry{}, false
+	}
+	text := fmt.Sprintf("%s %s (requested by %s): %s", rec.Op, rec.Target, rec.RequestedBy, rec.Outcome)
+	if rec.Error != "" {
+		text += " - " + rec.Error
+	}
+	return agentLogEntry{Time: rec.Time.Local(), Source: "audit", Text: text}, true
+}
+
+func printAgentLogEntry(e agentLogEntry) {
+	fmt.Printf("%s %s %s\n",
+		style.Dim.Render(e.Time.Format("2006-01-02 15:04:05")),
+		style.Dim.Render(fmt.Sprintf("[%-6s]", e.Source)),
+		e.Text)
+}
+
+// follow streams entries appended to the daemon log, audit log, and
+// transcript until ctx is done.
+func (s *agentLogSources) follow(ctx context.Context, interval time.Duration) error {
+	daemonTail := newLineTail(s.daemonLog)
+	auditTail := newLineTail(s.auditLog)
+	paneTail := newLineTail(filepath.Join(s.transcriptDir, "transcript.log"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for _, line := range daemonTail.next() {
+			if e, ok := s.daemonEntry(line); ok {
+				printAgentLogEntry(e)
+			}
+		}
+		for _, line := range auditTail.next() {
+			var rec daemon.AuditRecord
+			if json.Unmarshal([]byte(line), &rec) != nil {
+				continue
+			}
+			if e, ok := s.auditEntry(rec); ok {
+				printAgentLogEntry(e)
+			}
+		}
+		for _, line := range paneTail.next() {
+			fmt.Println(strings.TrimRight(line, "\r"))
+		}
+	}
+}
+
+// lineTail reads complete lines appended to a file since the last call.
+// A file that shrinks (rotated or truncated) is read again from the start.
+type lineTail struct {
+	path    string
+	offset  int64
+	partial string
+}
+
+// newLineTail starts tailing path at its current end.
+func newLineTail(path string) *lineTail {
+	t := &lineTail{path: path}
+	if info, err := os.Stat(path); err == nil {
+		t.offset = info.Size()
+	}
+	return t
+}
+
+// next returns the complete lines written since the last call.
+func (t *lineTail) next() []string {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	if info.Size() < t.offset {
+		t.offset, t.partial = 0, ""
+	}
+	if info.Size() == t.offset {
+		return nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	t.offset += int64(len(data))
+
+	text := t.partial + string(data)
+	lines := strings.Split(text, "\n")
+	t.partial = lines[len(lines)-1]
+	return lines[:len(lines)-1]
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+func TestAgentMentionPattern(t *testing.T) {
+	re := agentMentionPattern("gt-gastown-crew-max")
+	for line, want := range map[string]bool{
+		"Executing cycle for session gt-gastown-crew-max":       true,
+		"Processing lifecycle request from gastown-crew-max:":   true,
+		"Stored handoff for gastown/crew/max at /x":             true,
+		"Requesting handoff from gastown-crew-maxwell":          false,
+		"Killed session gt-gastown-crew-maxine for restart":     false,
+		"Processing lifecycle request from gastown-crew-joe: x": false,
+	} {
+		if got := re.MatchString(line); got != want {
+			t.Errorf("match(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestAgentLogEntries(t *testing.T) {
+	townRoot := t.TempDir()
+	src := newAgentLogSources(townRoot, "gt-gastown-witness")
+	if err := os.MkdirAll(filepath.Dir(src.daemonLog), 0755); err != nil {
+		t.Fatal(err)
+	}
+	log := "2026/01/02 10:00:00 Executing restart for session gt-gastown-witness\n" +
+		"2026/01/02 10:00:05 Executing restart for session gt-beads-witness\n" +
+		"2026/01/02 12:00:00 Lifecycle request from gastown-witness deferred\n"
+	if err := os.WriteFile(src.daemonLog, []byte(log), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := daemon.AppendAudit(townRoot, daemon.AuditRecord{
+		Time: time.Date(2026, 1, 2, 10, 0, 1, 0, time.Local), Op: daemon.AuditKillSession,
+		Target: "gt-gastown-witness", RequestedBy: "gastown-witness", Outcome: daemon.AuditOutcomeOK,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := src.entries(time.Time{})
+	if err != nil {
+		t.Fatalf("entries: %v", err)
+	}
+	var sources []string
+	for _, e := range entries {
+		sources = append(sources, e.Source)
+	}
+	if want := []string{"daemon", "audit", "daemon"}; !slices.Equal(sources, want) {
+		t.Errorf("sources = %v, want %v (entries %+v)", sources, want, entries)
+	}
+
+	entries, err = src.entries(time.Date(2026, 1, 2, 11, 0, 0, 0, time.Local))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("entries since 11:00 = %+v, %v", entries, err)
+	}
+}
+
+func TestLineTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tail := newLineTail(path)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("one\ntw")
+	if got := tail.next(); !slices.Equal(got, []string{"one"}) {
+		t.Errorf("next = %q, want [one]", got)
+	}
+	_, _ = f.WriteString("o\n")
+	f.Close()
+	if got := tail.next(); !slices.Equal(got, []string{"two"}) {
+		t.Errorf("next = %q, want [two]", got)
+	}
+
+	// Rotation replaces the file with a shorter one
+	if err := os.WriteFile(path, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := tail.next(); !slices.Equal(got, []string{"new"}) {
+		t.Errorf("next after rotation = %q, want [new]", got)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var agentRefreshCmd = &cobra.Command{
+	Use:   "refresh <agent>...",
+	Short: "Reload an agent's project docs and role context without restarting",
+	Long: `Ask running agents to re-prime in place: re-read CLAUDE.md (and AGENTS.md)
+and run 'gt prime', keeping their tmux session and conversation.
+
+Use after editing project docs or role templates when a full cycle would
+throw away useful context. Agents that aren't running are reported and
+skipped; start them normally and they pick up the new docs at startup.
+
+Agents can also request this for themselves by mailing the daemon a
+LIFECYCLE request with action "refresh".
+
+Agent is a daemon identity (gastown-crew-max) or a path (gastown/crew/max).
+
+Examples:
+  gt agents refresh gastown-witness
+  gt agents refresh gastown/crew/max gastown/crew/joe`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAgentRefresh,
+}
+
+func init() {
+	agentsCmd.AddCommand(agentRefreshCmd)
+}
+
+func runAgentRefresh(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	var failed []string
+	var firstErr error
+	for _, agent := range args {
+		if err := ctl.Refresh(agent); err != nil {
+			fmt.Printf("  %s %s: %v\n", style.Warning.Render("⚠"), agent, err)
+			failed = append(failed, agent)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fmt.Printf("  %s %s refreshing\n", style.Success.Render("✓"), agent)
+	}
+	if len(failed) > 0 {
+		// Wrap the first failure so the exit code reflects its class
+		return fmt.Errorf("failed to refresh: %s: %w", strings.Join(failed, ", "), firstErr)
+	}
+	return nil
+}
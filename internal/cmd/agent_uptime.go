@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	agentUptimeSince string
+	agentUptimeJSON  bool
+)
+
+var agentUptimeCmd = &cobra.Command{
+	Use:   "uptime",
+	Short: "Report agent session availability over time",
+	Long: `Report how much of the time each agent's session was running,
+how often it was restarted, and the mean time between cycles (uptime per
+restart), per agent, per rig, and for the whole town.
+
+The daemon records every agent session coming up or going down in its
+uptime history; restarts come from the restart history ('gt agent
+history'). Time while the daemon was down counts as the last state it
+recorded.
+
+Examples:
+  gt agent uptime
+  gt agent uptime --since 24h
+  gt agent uptime --since 30d --json`,
+	Args: cobra.NoArgs,
+	RunE: runAgentUptime,
+}
+
+func init() {
+	agentUptimeCmd.Flags().StringVar(&agentUptimeSince, "since", "7d", "Report on this much history (e.g. 24h, 7d)")
+	agentUptimeCmd.Flags().BoolVar(&agentUptimeJSON, "json", false, "Output as JSON")
+	agentsCmd.AddCommand(agentUptimeCmd)
+}
+
+func runAgentUptime(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	window, err := parseDuration(agentUptimeSince)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+
+	now := time.Now()
+	report, err := daemon.LoadUptimeReport(townRoot, now.Add(-window), now)
+	if err != nil {
+		return fmt.Errorf("reading uptime history: %w", err)
+	}
+
+	if agentUptimeJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Agents) == 0 {
+		fmt.Printf("No uptime recorded since %s\n", report.Since.Local().Format("2006-01-02 15:04"))
+		return nil
+	}
+	fmt.Printf("%s since %s\n\n", style.Bold.Render("Agent uptime"), report.Since.Local().Format("2006-01-02 15:04"))
+	fmt.Printf("  %-32s %8s %9s %10s\n", "AGENT", "AVAIL", "RESTARTS", "MTBC")
+	rig := ""
+	for i, u := range report.Agents {
+		if i == 0 || u.Rig != rig {
+			rig = u.Rig
+			fmt.Printf("  %s\n", style.Bold.Render(uptimeRigName(rig)))
+		}
+		fmt.Printf("    %-30s %s\n", u.Identity, formatUptime(u))
+	}
+
+	fmt.Println()
+	for _, r := range report.Rigs {
+		fmt.Printf("  %-32s %s\n", uptimeRigName(r.Rig), formatUptime(r))
+	}
+	fmt.Printf("  %-32s %s\n", "town", formatUptime(report.Town))
+	return nil
+}
+
+// uptimeRigName labels a rig group; town-level agents have no rig.
+func uptimeRigName(rig string) string {
+	if rig == "" {
+		return "(town)"
+	}
+	return rig
+}
+
+// formatUptime renders availability, restarts, and mean time between
+// cycles as table columns.
+func formatUptime(u *daemon.AgentUptime) string {
+	mtbc := "-"
+	if d := u.MeanTimeBetweenCycles(); d > 0 {
+		mtbc = d.Round(time.Minute).String()
+	}
+	return fmt.Sprintf("%7.1f%% %9d %10s", 100*u.Availability(), u.Restarts, mtbc)
+}
@@ -60,7 +60,7 @@ var AgentTypeIcons = map[AgentType]string{
 
 var agentsCmd = &cobra.Command{
 	Use:     "agents",
-	Aliases: []string{"ag"},
+	Aliases: []string{"ag", "agent"},
 	GroupID: GroupAgents,
 	Short:   "Switch between Gas Town agent sessions",
 	Long: `Display a popup menu of core Gas Town agent sessions.
@@ -330,12 +330,11 @@ func runAgents(cmd *cobra.Command, args []string) error {
 	}
 
 	// Execute tmux display-menu
-	tmuxPath, err := exec.LookPath("tmux")
-	if err != nil {
+	if _, err := exec.LookPath("tmux"); err != nil {
 		return fmt.Errorf("tmux not found: %w", err)
 	}
 
-	execCmd := exec.Command(tmuxPath, menuArgs...)
+	execCmd := tmux.Command(menuArgs...)
 	execCmd.Stdin = os.Stdin
 	execCmd.Stdout = os.Stdout
 	execCmd.Stderr = os.Stderr
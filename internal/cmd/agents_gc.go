@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/session"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// agentGCReason is recorded on agent beads archived by gt agents gc.
+const agentGCReason = "gc: no session or workdir"
+
+var (
+	agentsGCArchive      bool
+	agentsGCKillSessions bool
+	agentsGCJSON         bool
+)
+
+var agentsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Find and clean up orphaned agent beads and sessions",
+	Long: `Find agent beads and tmux sessions that no longer match each other.
+
+Towns accumulate orphans after rigs are renamed or crew members removed:
+
+  Orphaned beads     Open agent beads whose session isn't running and whose
+                     workdir (rig, crew, or polecat directory) no longer exists.
+  Orphaned sessions  Gas Town tmux sessions with no open agent bead.
+
+Mayor, deacon, and dog beads are never reported as orphans.
+
+By default this only reports. Use --archive to close orphaned beads (they are
+reopened if the agent is spawned again) and --kill-sessions to kill orphaned
+sessions.
+
+Examples:
+  gt agents gc                 # Report orphans
+  gt agents gc --archive       # Close orphaned agent beads
+  gt agents gc --json`,
+	RunE: runAgentsGC,
+}
+
+func init() {
+	agentsGCCmd.Flags().BoolVar(&agentsGCArchive, "archive", false, "Close orphaned agent beads")
+	agentsGCCmd.Flags().BoolVar(&agentsGCKillSessions, "kill-sessions", false, "Kill sessions that have no agent bead")
+	agentsGCCmd.Flags().BoolVar(&agentsGCJSON, "json", false, "Output as JSON")
+	agentsCmd.AddCommand(agentsGCCmd)
+}
+
+// agentBeadRef is an open agent bead and the beads database it lives in.
+type agentBeadRef struct {
+	ID       string
+	BeadsDir string
+}
+
+// OrphanedAgentBead is an agent bead with no session and no workdir.
+type OrphanedAgentBead struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	Session  string `json:"session"`
+	WorkDir  string `json:"workdir"`
+	Archived bool   `json:"archived,omitempty"`
+
+	beadsDir string
+}
+
+// OrphanedAgentSession is a Gas Town session with no agent bead.
+type OrphanedAgentSession struct {
+	Session string `json:"session"`
+	Address string `json:"address"`
+	Killed  bool   `json:"killed,omitempty"`
+}
+
+// AgentGCReport is the result of gt agents gc.
+type AgentGCReport struct {
+	Beads    []OrphanedAgentBead    `json:"orphaned_beads"`
+	Sessions []OrphanedAgentSession `json:"orphaned_sessions"`
+}
+
+func runAgentsGC(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	refs, err := listTownAgentBeads(townRoot)
+	if err != nil {
+		return err
+	}
+
+	t := tmux.NewTmux()
+	sessions, err := t.ListSessions()
+	if err != nil {
+		return fmt.Errorf("listing tmux sessions: %w", err)
+	}
+
+	report := findAgentOrphans(townRoot, refs, sessions, dirExists)
+
+	for i := range report.Beads {
+		orphan := &report.Beads[i]
+		if !agentsGCArchive {
+			continue
+		}
+		if err := beads.New(orphan.beadsDir).CloseAndClearAgentBead(orphan.ID, agentGCReason); err != nil {
+			fmt.Fprintf(os.Stderr, "%s archiving %s: %v\n", style.WarningPrefix, orphan.ID, err)
+			continue
+		}
+		orphan.Archived = true
+	}
+	for i := range report.Sessions {
+		orphan := &report.Sessions[i]
+		if !agentsGCKillSessions {
+			continue
+		}
+		if err := t.KillSessionWithProcesses(orphan.Session); err != nil {
+			fmt.Fprintf(os.Stderr, "%s killing %s: %v\n", style.WarningPrefix, orphan.Session, err)
+			continue
+		}
+		orphan.Killed = true
+	}
+
+	if agentsGCJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	printAgentGCReport(report)
+	return nil
+}
+
+func printAgentGCReport(report *AgentGCReport) {
+	if len(report.Beads) == 0 && len(report.Sessions) == 0 {
+		fmt.Printf("%s No orphaned agent beads or sessions\n", style.SuccessPrefix)
+		return
+	}
+
+	if len(report.Beads) > 0 {
+		fmt.Printf("%s\n", style.Bold.Render(fmt.Sprintf("Orphaned agent beads (%d):", len(report.Beads))))
+		for _, b := range report.Beads {
+			status := ""
+			if b.Archived {
+				status = style.Success.Render(" (archived)")
+			}
+			fmt.Printf("  %s  %s%s\n", b.ID, style.Dim.Render(b.WorkDir+" missing, "+b.Session+" not running"), status)
+		}
+	}
+	if len(report.Sessions) > 0 {
+		fmt.Printf("%s\n", style.Bold.Render(fmt.Sprintf("Sessions without agent beads (%d):", len(report.Sessions))))
+		for _, s := range report.Sessions {
+			status := ""
+			if s.Killed {
+				status = style.Success.Render(" (killed)")
+			}
+			fmt.Printf("  %s  %s%s\n", s.Session, style.Dim.Render(s.Address), status)
+		}
+	}
+
+	if !agentsGCArchive && !agentsGCKillSessions {
+		fmt.Printf("\n%s\n", style.Dim.Render("Run with --archive and/or --kill-sessions to clean up"))
+	}
+}
+
+// listTownAgentBeads returns the open agent beads in the town database and
+// every routed rig database.
+func listTownAgentBeads(townRoot string) ([]agentBeadRef, error) {
+	townBeadsDir := beads.GetTownBeadsPath(townRoot)
+	dirs := []string{townBeadsDir}
+	routes, err := beads.LoadRoutes(townBeadsDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading routes: %w", err)
+	}
+	for _, r := range routes {
+		if r.Path == "." || r.Path == "" {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(townRoot, r.Path))
+	}
+
+	seen := make(map[string]bool)
+	var refs []agentBeadRef
+	for _, dir := range dirs {
+		issues, err := beads.New(dir).ListAgentBeads()
+		if err != nil {
+			// A rig whose database is gone can't hold orphans we could close.
+			continue
+		}
+		for id := range issues {
+			if seen[id] {
+				continue // Routes may share a database
+			}
+			seen[id] = true
+			refs = append(refs, agentBeadRef{ID: id, BeadsDir: dir})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].ID < refs[j].ID })
+	return refs, nil
+}
+
+// agentBeadIdentity maps an agent bead ID to the agent it tracks. Returns nil
+// for beads that aren't garbage collected (mayor, deacon, dogs) or that don't
+// parse.
+func agentBeadIdentity(id string) *session.AgentIdentity {
+	rig, role, name, ok := beads.ParseAgentBeadID(id)
+	if !ok {
+		return nil
+	}
+	switch session.Role(role) {
+	case session.RoleWitness, session.RoleRefinery:
+		if rig == "" {
+			return nil
+		}
+		return &session.AgentIdentity{Role: session.Role(role), Rig: rig}
+	case session.RoleCrew, session.RolePolecat:
+		if rig == "" || name == "" {
+			return nil
+		}
+		return &session.AgentIdentity{Role: session.Role(role), Rig: rig, Name: name}
+	default:
+		return nil
+	}
+}
+
+// agentWorkDir is the directory whose existence shows the agent still exists.
+func agentWorkDir(townRoot string, id *session.AgentIdentity) string {
+	switch id.Role {
+	case session.RoleCrew:
+		return filepath.Join(townRoot, id.Rig, "crew", id.Name)
+	case session.RolePolecat:
+		return filepath.Join(townRoot, id.Rig, "polecats", id.Name)
+	default:
+		return filepath.Join(townRoot, id.Rig)
+	}
+}
+
+// findAgentOrphans matches agent beads against tmux sessions and workdirs.
+func findAgentOrphans(townRoot string, refs []agentBeadRef, sessions []string, exists func(string) bool) *AgentGCReport {
+	running := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		running[s] = true
+	}
+
+	report := &AgentGCReport{}
+	tracked := make(map[string]bool) // session names that have an agent bead
+	for _, ref := range refs {
+		rig, role, name, ok := beads.ParseAgentBeadID(ref.ID)
+		if ok {
+			tracked[(&session.AgentIdentity{Role: session.Role(role), Rig: rig, Name: name}).SessionName()] = true
+		}
+
+		id := agentBeadIdentity(ref.ID)
+		if id == nil {
+			continue
+		}
+		sessionName := id.SessionName()
+		workDir := agentWorkDir(townRoot, id)
+		if running[sessionName] || exists(workDir) {
+			continue
+		}
+		report.Beads = append(report.Beads, OrphanedAgentBead{
+			ID:       ref.ID,
+			Address:  id.Address(),
+			Session:  sessionName,
+			WorkDir:  workDir,
+			beadsDir: ref.BeadsDir,
+		})
+	}
+
+	for _, s := range sessions {
+		id, err := session.ParseSessionName(s)
+		if err != nil {
+			continue // Not a Gas Town agent session
+		}
+		if id.Role == session.RoleMayor || id.Role == session.RoleDeacon {
+			continue
+		}
+		if tracked[s] {
+			continue
+		}
+		report.Sessions = append(report.Sessions, OrphanedAgentSession{Session: s, Address: id.Address()})
+	}
+	return report
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFindAgentOrphans(t *testing.T) {
+	townRoot := "/town"
+	existing := map[string]bool{
+		filepath.Join(townRoot, "gastown"):                    true,
+		filepath.Join(townRoot, "gastown", "crew", "max"):     true,
+		filepath.Join(townRoot, "gastown", "polecats", "nux"): true,
+	}
+	exists := func(path string) bool { return existing[path] }
+
+	refs := []agentBeadRef{
+		{ID: "hq-mayor"},
+		{ID: "hq-dog-alpha"},
+		{ID: "gt-gastown-witness"},
+		{ID: "gt-gastown-crew-max"},
+		{ID: "gt-gastown-crew-joe"},      // workdir removed, not running
+		{ID: "gt-gastown-polecat-toast"}, // workdir removed but still running
+		{ID: "gt-oldrig-refinery"},       // rig renamed
+		{ID: "gt-gastown-polecat-nux"},
+	}
+	sessions := []string{
+		"hq-mayor",
+		"gt-gastown-witness",
+		"gt-gastown-toast",
+		"gt-gastown-crew-ghost", // no bead
+		"scratch",               // not a Gas Town session
+	}
+
+	report := findAgentOrphans(townRoot, refs, sessions, exists)
+
+	var beadIDs []string
+	for _, b := range report.Beads {
+		beadIDs = append(beadIDs, b.ID)
+	}
+	want := []string{"gt-gastown-crew-joe", "gt-oldrig-refinery"}
+	if len(beadIDs) != len(want) || beadIDs[0] != want[0] || beadIDs[1] != want[1] {
+		t.Errorf("orphaned beads = %v, want %v", beadIDs, want)
+	}
+	if report.Beads[0].Session != "gt-gastown-crew-joe" {
+		t.Errorf("session = %q, want gt-gastown-crew-joe", report.Beads[0].Session)
+	}
+
+	if len(report.Sessions) != 1 || report.Sessions[0].Session != "gt-gastown-crew-ghost" {
+		t.Errorf("orphaned sessions = %+v, want only gt-gastown-crew-ghost", report.Sessions)
+	}
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/storage"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Audit export flags
+var (
+	auditExportFormat string
+	auditExportSince  string
+	auditExportOutput string
+)
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the event audit trail for SIEM ingestion",
+	Long: `Export the town's raw event log (.events.jsonl) in a SIEM-friendly format.
+
+Formats:
+  json  Newline-delimited JSON with stable field names (default)
+  cef   ArcSight Common Event Format, one event per line
+
+Events are hash-chained when they are logged (seq/prev_hash/hash, or
+cs4/cs5 in CEF): each hash covers the event and the previous hash. Export
+verifies the whole log first and fails at the first broken link, so an
+altered, dropped, or reordered event is reported rather than exported.
+Records keep their stored hashes, so a --since export still links to the
+events before it. Verify a JSON export with 'gt audit verify'.
+
+Examples:
+  gt audit export > audit.jsonl
+  gt audit export --format cef --since 24h -o audit.cef
+  gt audit verify audit.jsonl`,
+	RunE: runAuditExport,
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Verify the hash chain of a JSON audit export",
+	Long: `Verify that a JSON audit export has not been altered.
+
+Recomputes every record's hash and checks it links to the previous record
+and that no sequence number is missing. Reports the first record that
+fails. Use "-" to read from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuditVerify,
+}
+
+func init() {
+	auditExportCmd.Flags().StringVar(&auditExportFormat, "format", "json", "Output format: json or cef")
+	auditExportCmd.Flags().StringVar(&auditExportSince, "since", "", "Export events since duration (e.g., 1h, 24h, 7d)")
+	auditExportCmd.Flags().StringVarP(&auditExportOutput, "output", "o", "", "Write to file instead of stdout")
+
+	auditCmd.AddCommand(auditExportCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+}
+
+func runAuditExport(cmd *cobra.Command, args []string) error {
+	if auditExportFormat != "json" && auditExportFormat != "cef" {
+		return fmt.Errorf("invalid --format %q (use json or cef)", auditExportFormat)
+	}
+
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	var filter events.ExportFilter
+	if auditExportSince != "" {
+		duration, err := parseDuration(auditExportSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since duration: %w", err)
+		}
+		filter.Since = time.Now().Add(-duration)
+	}
+
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("opening town store: %w", err)
+	}
+	defer store.Close()
+
+	data, err := store.Get(events.EventsFile)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("reading events: %w", err)
+	}
+	records, err := events.ReadExportRecords(bytes.NewReader(data), filter)
+	if err != nil {
+		return fmt.Errorf("reading events: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if auditExportOutput != "" {
+		f, err := os.Create(auditExportOutput)
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	for _, rec := range records {
+		if auditExportFormat == "cef" {
+			fmt.Fprintln(w, events.FormatCEF(rec, Version))
+			continue
+		}
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("encoding record %d: %w", rec.Seq, err)
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+
+	if auditExportOutput != "" {
+		fmt.Fprintf(os.Stderr, "%s Exported %d event(s) to %s\n", style.Bold.Render("✓"), len(records), auditExportOutput)
+	}
+	return nil
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("opening export: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	count, err := events.VerifyChain(in)
+	if err != nil {
+		return fmt.Errorf("verification failed after %d valid record(s): %w", count, err)
+	}
+	fmt.Printf("%s Hash chain intact: %d record(s) verified\n", style.Bold.Render("✓"), count)
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
 	"github.com/steveyegge/gastown/internal/tui/convoy"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
@@ -746,7 +747,7 @@ func isReadyIssue(t trackedIssueInfo, blockedIssues map[string]bool) bool {
 	}
 
 	// Check if tmux session exists
-	checkCmd := exec.Command("tmux", "has-session", "-t", sessionName)
+	checkCmd := tmux.Command("has-session", "-t", sessionName)
 	if err := checkCmd.Run(); err != nil {
 		return true // Session doesn't exist = ready
 	}
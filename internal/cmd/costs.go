@@ -913,7 +913,7 @@ func deriveSessionName() string {
 // Note: We don't check TMUX env var because it may not be inherited when Claude Code
 // runs bash commands, even though we are inside a tmux session.
 func detectCurrentTmuxSession() string {
-	cmd := exec.Command("tmux", "display-message", "-p", "#S")
+	cmd := tmux.Command("display-message", "-p", "#S")
 	output, err := cmd.Output()
 	if err != nil {
 		return ""
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/budget"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	budgetJSON   bool
+	budgetDryRun bool
+)
+
+var costsBudgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Show per-rig monthly budgets and month-to-date spend",
+	Long: `Show per-rig monthly budgets and month-to-date spend.
+
+Budgets are configured per rig in settings/config.json:
+
+  "budget": {
+    "monthly_usd": 200,
+    "alert_thresholds": [50, 80, 100],
+    "hard_stop": true,
+    "critical_roles": ["witness", "refinery"]
+  }
+
+'gt costs budget check' compares spend against each budget, alerts the
+mayor as thresholds are crossed, and - with hard_stop - pauses
+non-critical agents once the budget is exhausted. The daemon runs it
+every 15 minutes while any rig has a budget. A paused rig refuses new
+polecats, warm sessions, and daemon restarts of non-critical agents
+until an operator runs 'gt costs budget approve <rig>'.`,
+	RunE: runCostsBudget,
+}
+
+var costsBudgetCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Alert on budget thresholds and enforce hard stops",
+	Long: `Compare month-to-date spend against each rig's budget.
+
+Sends the mayor one alert per threshold per month. When a rig with
+hard_stop enabled exhausts its budget, stops its non-critical agent
+sessions, pauses the rig, and asks the overseer for approval.
+
+The daemon runs this every 15 minutes while any rig has a budget; run it
+by hand to check right away.`,
+	RunE: runCostsBudgetCheck,
+}
+
+var costsBudgetApproveCmd = &cobra.Command{
+	Use:   "approve <rig>",
+	Short: "Resume a rig paused by its budget hard stop",
+	Long: `Resume a rig paused by its budget hard stop.
+
+Approval lasts for the rest of the calendar month: the hard stop won't
+trip again until next month's budget is exhausted. Paused agents are
+not restarted automatically (use 'gt rig start <rig>' or 'gt sling').`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCostsBudgetApprove,
+}
+
+func init() {
+	costsCmd.AddCommand(costsBudgetCmd)
+	costsBudgetCmd.Flags().BoolVar(&budgetJSON, "json", false, "Output as JSON")
+
+	costsBudgetCmd.AddCommand(costsBudgetCheckCmd)
+	costsBudgetCheckCmd.Flags().BoolVar(&budgetDryRun, "dry-run", false, "Show alerts and pauses without sending or applying them")
+
+	costsBudgetCmd.AddCommand(costsBudgetApproveCmd)
+}
+
+// RigBudgetStatus is the month-to-date budget position of one rig.
+type RigBudgetStatus struct {
+	Rig       string  `json:"rig"`
+	Period    string  `json:"period"`
+	BudgetUSD float64 `json:"budget_usd"`
+	SpentUSD  float64 `json:"spent_usd"`
+	Percent   float64 `json:"percent"`
+	HardStop  bool    `json:"hard_stop"`
+	Paused    bool    `json:"paused"`
+	Approved  bool    `json:"approved"`
+
+	config     *config.BudgetConfig
+	evaluation budget.Evaluation
+}
+
+// loadBudgetStatuses evaluates every rig with a budget against month-to-date spend.
+func loadBudgetStatuses(townRoot string, now time.Time) ([]*RigBudgetStatus, error) {
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil {
+		return nil, fmt.Errorf("loading rigs config: %w", err)
+	}
+
+	spend, err := monthToDateSpendByRig(now)
+	if err != nil {
+		return nil, err
+	}
+
+	period := budget.Period(now)
+	var statuses []*RigBudgetStatus
+	for rigName := range rigsConfig.Rigs {
+		cfg := budget.LoadConfig(townRoot, rigName)
+		if cfg == nil {
+			continue
+		}
+		eval := budget.Evaluate(cfg, spend[rigName], budget.LastAlerted(townRoot, rigName, period))
+		statuses = append(statuses, &RigBudgetStatus{
+			Rig:        rigName,
+			Period:     period,
+			BudgetUSD:  cfg.MonthlyUSD,
+			SpentUSD:   spend[rigName],
+			Percent:    eval.Percent,
+			HardStop:   cfg.HardStop,
+			Paused:     budget.IsPaused(townRoot, rigName),
+			Approved:   budget.IsApproved(townRoot, rigName, period),
+			config:     cfg,
+			evaluation: eval,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Rig < statuses[j].Rig })
+	return statuses, nil
+}
+
+// monthToDateSpendByRig sums ledger costs per rig since the start of now's month:
+// daily digests plus today's not-yet-digested session wisps.
+func monthToDateSpendByRig(now time.Time) (map[string]float64, error) {
+	entries, err := queryDigestBeads(now.Day())
+	if err != nil {
+		return nil, fmt.Errorf("querying digest beads: %w", err)
+	}
+	todayWisps, _ := querySessionCostWisps(now)
+	entries = append(entries, todayWisps...)
+
+	monthStart := budget.MonthStart(now)
+	spend := make(map[string]float64)
+	for _, entry := range entries {
+		if entry.Rig == "" || (!entry.EndedAt.IsZero() && entry.EndedAt.Before(monthStart)) {
+			continue
+		}
+		spend[entry.Rig] += entry.CostUSD
+	}
+	return spend, nil
+}
+
+func runCostsBudget(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	statuses, err := loadBudgetStatuses(townRoot, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if budgetJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println(style.Dim.Render("No rig budgets configured."))
+		return nil
+	}
+
+	fmt.Printf("\n%s Budgets for %s\n\n", style.Bold.Render("💰"), budget.Period(time.Now()))
+	for _, s := range statuses {
+		marker := style.Success.Render("●")
+		switch {
+		case s.Paused:
+			marker = style.Error.Render("⏸")
+		case s.evaluation.Exhausted:
+			marker = style.Error.Render("●")
+		case s.Percent >= 80:
+			marker = style.Warning.Render("●")
+		}
+		fmt.Printf("  %s %-20s $%8.2f / $%8.2f  %5.1f%%", marker, s.Rig, s.SpentUSD, s.BudgetUSD, s.Percent)
+		switch {
+		case s.Paused:
+			fmt.Printf("  %s", style.Error.Render("paused - awaiting approval"))
+		case s.Approved:
+			fmt.Printf("  %s", style.Dim.Render("overspend approved"))
+		case s.HardStop:
+			fmt.Printf("  %s", style.Dim.Render("hard stop"))
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	return nil
+}
+
+func runCostsBudgetCheck(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	statuses, err := loadBudgetStatuses(townRoot, time.Now())
+	if err != nil {
+		return err
+	}
+
+	router := mail.NewRouter(townRoot)
+	sender := detectSender()
+	for _, s := range statuses {
+		if s.evaluation.Threshold > 0 {
+			if err := sendBudgetAlert(router, sender, s); err != nil {
+				fmt.Printf("%s %s: %v\n", style.Error.Render("✗"), s.Rig, err)
+			} else if !budgetDryRun {
+				if err := budget.RecordAlert(townRoot, s.Rig, s.Period, s.evaluation.Threshold); err != nil {
+					fmt.Printf("%s %s: recording alert: %v\n", style.Warning.Render("⚠"), s.Rig, err)
+				}
+			}
+		}
+
+		if s.evaluation.Exhausted && s.HardStop && !s.Paused && !s.Approved {
+			if err := enforceBudgetHardStop(townRoot, router, sender, s); err != nil {
+				fmt.Printf("%s %s: %v\n", style.Error.Render("✗"), s.Rig, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendBudgetAlert mails the mayor that a rig crossed a budget threshold.
+func sendBudgetAlert(router *mail.Router, sender string, s *RigBudgetStatus) error {
+	subject := fmt.Sprintf("BUDGET: %s at %d%% of monthly budget", s.Rig, s.evaluation.Threshold)
+	if budgetDryRun {
+		fmt.Printf("[dry-run] Would send: %s\n", subject)
+		return nil
+	}
+
+	body := fmt.Sprintf("Rig %s has spent $%.2f of its $%.2f budget for %s (%.1f%%).",
+		s.Rig, s.SpentUSD, s.BudgetUSD, s.Period, s.Percent)
+	if s.HardStop {
+		body += "\n\nHard stop is enabled: non-critical agents will be paused when the budget is exhausted."
+	}
+	msg := &mail.Message{
+		From:     sender,
+		To:       "mayor/",
+		Subject:  subject,
+		Body:     body,
+		Priority: mail.PriorityHigh,
+	}
+	if err := router.Send(msg); err != nil {
+		return fmt.Errorf("sending budget alert: %w", err)
+	}
+	fmt.Printf("%s %s\n", style.Warning.Render("⚠"), subject)
+	return nil
+}
+
+// enforceBudgetHardStop stops a rig's non-critical agent sessions, pauses the
+// rig so they aren't restarted, and asks the overseer for approval.
+func enforceBudgetHardStop(townRoot string, router *mail.Router, sender string, s *RigBudgetStatus) error {
+	t := tmux.NewTmux()
+	sessions, err := t.ListSessions()
+	if err != nil {
+		return fmt.Errorf("listing sessions: %w", err)
+	}
+
+	var targets []string
+	for _, session := range sessions {
+		role, rigName, _ := parseSessionName(session)
+		if rigName == s.Rig && !budget.IsCritical(s.config, role) {
+			targets = append(targets, session)
+		}
+	}
+
+	if budgetDryRun {
+		fmt.Printf("[dry-run] Would pause %s and stop %d session(s): %v\n", s.Rig, len(targets), targets)
+		return nil
+	}
+
+	// Pause first so the daemon doesn't restart what we're about to stop
+	if err := budget.Pause(townRoot, s.Rig, s.Period); err != nil {
+		return fmt.Errorf("pausing rig: %w", err)
+	}
+
+	var stopped []string
+	for _, session := range targets {
+		if err := t.KillSessionWithProcesses(session); err != nil {
+			fmt.Printf("%s %s: stopping %s: %v\n", style.Warning.Render("⚠"), s.Rig, session, err)
+			continue
+		}
+		stopped = append(stopped, session)
+		_ = events.LogFeed(events.TypeKill, sender, events.KillPayload(s.Rig, session, "budget exhausted"))
+	}
+
+	msg := &mail.Message{
+		From:    sender,
+		To:      "overseer",
+		Subject: fmt.Sprintf("BUDGET: %s paused - approval required", s.Rig),
+		Body: fmt.Sprintf(`Rig %s exhausted its $%.2f budget for %s ($%.2f spent).
+
+Stopped %d non-critical session(s): %v
+New polecats and restarts of non-critical agents are refused.
+
+To resume: gt costs budget approve %s`,
+			s.Rig, s.BudgetUSD, s.Period, s.SpentUSD, len(stopped), stopped, s.Rig),
+		Priority: mail.PriorityUrgent,
+	}
+	if err := router.Send(msg); err != nil {
+		fmt.Printf("%s %s: notifying overseer: %v\n", style.Warning.Render("⚠"), s.Rig, err)
+	}
+
+	fmt.Printf("%s Rig %s paused: budget exhausted, stopped %d session(s)\n",
+		style.Error.Render("⏸"), s.Rig, len(stopped))
+	return nil
+}
+
+func runCostsBudgetApprove(cmd *cobra.Command, args []string) error {
+	townRoot, _, err := getRig(args[0])
+	if err != nil {
+		return err
+	}
+	rigName := args[0]
+
+	period := budget.Period(time.Now())
+	if err := budget.Approve(townRoot, rigName, period); err != nil {
+		return fmt.Errorf("approving budget: %w", err)
+	}
+	_ = events.LogFeed(events.TypeBudgetApproved, detectSender(), map[string]interface{}{
+		"rig":    rigName,
+		"period": period,
+	})
+
+	fmt.Printf("%s Rig %s may exceed its budget for %s\n", style.Success.Render("✓"), rigName, period)
+	fmt.Printf("  Use '%s' to start agents\n", style.Dim.Render("gt rig start "+rigName))
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -10,6 +11,7 @@ import (
 var (
 	crewRig           string
 	crewBranch        bool
+	crewWorktree      bool
 	crewJSON          bool
 	crewForce         bool
 	crewPurge         bool
@@ -35,8 +37,9 @@ CREW VS POLECATS:
   Polecats: Ephemeral. Witness-managed. Auto-nuked after work.
   Crew:     Persistent. User-managed. Stays until you remove it.
 
-Crew workers are full git clones (not worktrees) for human developers
-who want persistent context and control over their workspace lifecycle.
+Crew workers are full git clones by default (or worktrees with --worktree)
+for human developers who want persistent context and control over their
+workspace lifecycle.
 Use crew workers for exploratory work, long-running tasks, or when you
 want to keep uncommitted changes around.
 
@@ -53,7 +56,9 @@ Commands:
   gt crew at <name>        Attach to session
   gt crew remove <name>    Remove workspace
   gt crew refresh <name>   Context cycle with handoff mail
-  gt crew restart <name>   Kill and restart session fresh`,
+  gt crew restart <name>   Kill and restart session fresh
+  gt crew repair <name>    Recreate a missing workspace as a worktree
+  gt crew prune            Garbage-collect stale crew worktrees`,
 }
 
 var crewAddCmd = &cobra.Command{
@@ -67,11 +72,17 @@ Each workspace is created at <rig>/crew/<name>/ with:
 - CLAUDE.md with crew worker prompting
 - Optional feature branch (crew/<name>)
 
+The crew member also gets an agent bead, and is declared under its rig in
+town.yaml when the town manifest lists the rig (otherwise the reconciler
+would stop its session). --start starts the session right away.
+
 Examples:
   gt crew add dave                       # Create single workspace
   gt crew add murgen croaker goblin      # Create multiple at once
   gt crew add emma --rig greenplace      # Create in specific rig
-  gt crew add fred --branch              # Create with feature branch`,
+  gt crew add fred --branch              # Create with feature branch
+  gt crew add gina --worktree            # Worktree on crew/gina instead of a clone
+  gt crew add hank --start               # Provision and start the session`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runCrewAdd,
 }
@@ -132,6 +143,12 @@ The agent bead is CLOSED by default (preserves CV history). Use --purge
 to DELETE the agent bead entirely (for accidental/test crew that should
 leave no trace in the ledger).
 
+Removal also clears the crew member's open mail and drops it from
+town.yaml. --drain asks a running session to commit, push, and hand off,
+and waits (up to --drain-timeout) for it to exit before removing.
+--archive moves the workspace to <rig>/crew/.archive/ instead of
+deleting it, keeping uncommitted work.
+
 --purge also:
   - Deletes the agent bead (not just closes it)
   - Unassigns any beads assigned to this crew member
@@ -143,6 +160,7 @@ Examples:
   gt crew remove dave emma fred             # Remove multiple
   gt crew remove beads/grip beads/fang      # Remove from specific rig
   gt crew remove dave --force               # Force remove (closes bead)
+  gt crew remove dave --drain --archive     # Let the session wrap up, keep files
   gt crew remove test-crew --purge          # Obliterate (deletes bead)`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runCrewRemove,
@@ -247,6 +265,43 @@ Examples:
 	RunE: runCrewPristine,
 }
 
+var crewRepairCmd = &cobra.Command{
+	Use:   "repair <name>",
+	Short: "Recreate a missing crew workspace as a worktree",
+	Long: `Recreate a crew workspace whose directory has gone missing.
+
+The workspace is rebuilt as a git worktree of the rig's shared repo on its
+crew/<name> branch, so committed work on that branch comes back. A workspace
+that still exists as a git checkout is left alone.
+
+The daemon does this automatically when it restarts a crew session whose
+workdir is missing.
+
+Examples:
+  gt crew repair dave             # Recreate dave's workspace
+  gt crew repair beads/emma       # Rig/name format`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCrewRepair,
+}
+
+var crewPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Garbage-collect stale crew worktrees",
+	Long: `Clean up crew worktrees and branches left behind by removed workspaces.
+
+Drops worktree registrations whose directories no longer exist, then deletes
+crew/<name> branches that have no workspace and are fully merged into the
+rig's default branch. Orphaned branches with unmerged commits are kept and
+listed so no work is lost.
+
+Examples:
+  gt crew prune                   # Prune in current rig
+  gt crew prune --rig beads       # Prune in specific rig
+  gt crew prune --json            # JSON output`,
+	Args: cobra.NoArgs,
+	RunE: runCrewPrune,
+}
+
 var crewNextCmd = &cobra.Command{
 	Use:    "next",
 	Short:  "Switch to next crew session in same rig",
@@ -330,6 +385,9 @@ func init() {
 	// Add flags
 	crewAddCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to create crew workspace in")
 	crewAddCmd.Flags().BoolVar(&crewBranch, "branch", false, "Create a feature branch (crew/<name>)")
+	crewAddCmd.Flags().BoolVar(&crewWorktree, "worktree", false, "Create a git worktree on crew/<name> instead of a full clone")
+	crewAddCmd.Flags().BoolVar(&crewStart, "start", false, "Start the crew session after provisioning")
+	crewAddCmd.Flags().BoolVar(&crewDebug, "debug", false, "Show session machinery output when starting")
 
 	crewListCmd.Flags().StringVar(&crewRig, "rig", "", "Filter by rig name")
 	crewListCmd.Flags().BoolVar(&crewListAll, "all", false, "List crew workspaces in all rigs")
@@ -345,6 +403,9 @@ func init() {
 	crewRemoveCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
 	crewRemoveCmd.Flags().BoolVar(&crewForce, "force", false, "Force remove (skip safety checks)")
 	crewRemoveCmd.Flags().BoolVar(&crewPurge, "purge", false, "Obliterate: delete agent bead, unassign work, clear mail")
+	crewRemoveCmd.Flags().BoolVar(&crewDrain, "drain", false, "Ask a running session to wrap up and exit before removing")
+	crewRemoveCmd.Flags().DurationVar(&crewDrainTimeout, "drain-timeout", 5*time.Minute, "How long --drain waits before stopping the session")
+	crewRemoveCmd.Flags().BoolVar(&crewArchive, "archive", false, "Move the workspace to <rig>/crew/.archive instead of deleting it")
 
 	crewRefreshCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
 	crewRefreshCmd.Flags().StringVarP(&crewMessage, "message", "m", "", "Custom handoff message")
@@ -357,6 +418,11 @@ func init() {
 	crewPristineCmd.Flags().StringVar(&crewRig, "rig", "", "Filter by rig name")
 	crewPristineCmd.Flags().BoolVar(&crewJSON, "json", false, "Output as JSON")
 
+	crewRepairCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
+
+	crewPruneCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
+	crewPruneCmd.Flags().BoolVar(&crewJSON, "json", false, "Output as JSON")
+
 	crewRestartCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use (filter when using --all)")
 	crewRestartCmd.Flags().BoolVar(&crewAll, "all", false, "Restart all running crew sessions")
 	crewRestartCmd.Flags().BoolVar(&crewDryRun, "dry-run", false, "Show what would be restarted without restarting")
@@ -380,6 +446,8 @@ func init() {
 	crewCmd.AddCommand(crewRenameCmd)
 	crewCmd.AddCommand(crewPristineCmd)
 	crewCmd.AddCommand(crewRestartCmd)
+	crewCmd.AddCommand(crewRepairCmd)
+	crewCmd.AddCommand(crewPruneCmd)
 
 	// Add --session flag to next/prev commands for tmux key binding support
 	// When run via run-shell, tmux session context may be wrong, so we pass it explicitly
@@ -80,7 +80,12 @@ func runCrewAdd(cmd *cobra.Command, args []string) error {
 		// Create crew workspace
 		fmt.Printf("Creating crew workspace %s in %s...\n", name, rigName)
 
-		worker, err := crewMgr.Add(name, crewBranch)
+		var worker *crew.CrewWorker
+		if crewWorktree {
+			worker, err = crewMgr.AddWorktree(name)
+		} else {
+			worker, err = crewMgr.Add(name, crewBranch)
+		}
 		if err != nil {
 			if err == crew.ErrCrewExists {
 				style.PrintWarning("crew workspace '%s' already exists, skipping", name)
@@ -98,21 +103,13 @@ func runCrewAdd(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Branch: %s\n", worker.Branch)
 
 		// Create agent bead for the crew worker
-		prefix := beads.GetPrefixForRig(townRoot, rigName)
-		crewID := beads.CrewBeadIDWithPrefix(prefix, rigName, name)
-		if _, err := bd.Show(crewID); err != nil {
-			// Agent bead doesn't exist, create it
-			fields := &beads.AgentFields{
-				RoleType:   "crew",
-				Rig:        rigName,
-				AgentState: "idle",
-				RoleBead:   beads.RoleBeadIDTown("crew"),
-			}
-			desc := fmt.Sprintf("Crew worker %s in %s - human-managed persistent workspace.", name, rigName)
-			if _, err := bd.CreateAgentBead(crewID, desc, fields); err != nil {
-				style.PrintWarning("could not create agent bead for %s: %v", name, err)
-			} else {
-				fmt.Printf("  Agent bead: %s\n", crewID)
+		ensureCrewAgentBead(bd, townRoot, rigName, name)
+
+		setManifestCrew(townRoot, rigName, name, true)
+
+		if crewStart {
+			if err := startCrewSession(townRoot, rigName, name); err != nil {
+				style.PrintWarning("could not start session for %s: %v", name, err)
 			}
 		}
 
@@ -141,3 +138,25 @@ func runCrewAdd(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// ensureCrewAgentBead creates the agent bead of crew worker rigName/name if
+// it doesn't exist. Failures are warnings: the workspace works without it.
+func ensureCrewAgentBead(bd *beads.Beads, townRoot, rigName, name string) {
+	prefix := beads.GetPrefixForRig(townRoot, rigName)
+	crewID := beads.CrewBeadIDWithPrefix(prefix, rigName, name)
+	if _, err := bd.Show(crewID); err == nil {
+		return
+	}
+	fields := &beads.AgentFields{
+		RoleType:   "crew",
+		Rig:        rigName,
+		AgentState: "idle",
+		RoleBead:   beads.RoleBeadIDTown("crew"),
+	}
+	desc := fmt.Sprintf("Crew worker %s in %s - human-managed persistent workspace.", name, rigName)
+	if _, err := bd.CreateAgentBead(crewID, desc, fields); err != nil {
+		style.PrintWarning("could not create agent bead for %s: %v", name, err)
+	} else {
+		fmt.Printf("  Agent bead: %s\n", crewID)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/steveyegge/gastown/internal/git"
 	"github.com/steveyegge/gastown/internal/rig"
 	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
 
@@ -203,7 +204,7 @@ func isInTmuxSession(targetSession string) bool {
 	}
 
 	// Get current session name
-	cmd := exec.Command("tmux", "display-message", "-p", "#{session_name}")
+	cmd := tmux.Command("display-message", "-p", "#{session_name}")
 	out, err := cmd.Output()
 	if err != nil {
 		return false
@@ -216,8 +217,7 @@ func isInTmuxSession(targetSession string) bool {
 // attachToTmuxSession attaches to a tmux session.
 // If already inside tmux, uses switch-client instead of attach-session.
 func attachToTmuxSession(sessionID string) error {
-	tmuxPath, err := exec.LookPath("tmux")
-	if err != nil {
+	if _, err := exec.LookPath("tmux"); err != nil {
 		return fmt.Errorf("tmux not found: %w", err)
 	}
 
@@ -225,10 +225,10 @@ func attachToTmuxSession(sessionID string) error {
 	var cmd *exec.Cmd
 	if os.Getenv("TMUX") != "" {
 		// Inside tmux: switch to the target session
-		cmd = exec.Command(tmuxPath, "switch-client", "-t", sessionID)
+		cmd = tmux.Command("switch-client", "-t", sessionID)
 	} else {
 		// Outside tmux: attach to the session
-		cmd = exec.Command(tmuxPath, "attach-session", "-t", sessionID)
+		cmd = tmux.Command("attach-session", "-t", sessionID)
 	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -317,7 +317,7 @@ func parseCrewSessionName(sessionName string) (rigName, crewName string, ok bool
 // findRigCrewSessions returns all crew sessions for a given rig, sorted alphabetically.
 // Uses tmux list-sessions to find sessions matching gt-<rig>-crew-* pattern.
 func findRigCrewSessions(rigName string) ([]string, error) { //nolint:unparam // error return kept for future use
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}")
+	cmd := tmux.Command("list-sessions", "-F", "#{session_name}")
 	out, err := cmd.Output()
 	if err != nil {
 		// No tmux server or no sessions
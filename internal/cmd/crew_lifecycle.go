@@ -48,18 +48,32 @@ func runCrewRemove(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		// Check for running session (unless forced)
-		if !forceRemove {
+		townRoot, _ := workspace.Find(r.Path)
+		if townRoot == "" {
+			townRoot = r.Path
+		}
+
+		// Check for running session (unless forced or draining)
+		if !forceRemove && !crewDrain {
 			t := tmux.NewTmux()
 			sessionID := crewSessionName(r.Name, name)
 			hasSession, _ := t.HasSession(sessionID)
 			if hasSession {
-				fmt.Printf("Error removing %s: session '%s' is running (use --force to kill and remove)\n", arg, sessionID)
+				fmt.Printf("Error removing %s: session '%s' is running (use --drain or --force)\n", arg, sessionID)
 				lastErr = fmt.Errorf("session running")
 				continue
 			}
 		}
 
+		// Let a running session wrap up before it is killed
+		if crewDrain {
+			if err := drainCrewSession(townRoot, r.Name, name); err != nil {
+				fmt.Printf("Error draining %s: %v\n", arg, err)
+				lastErr = err
+				continue
+			}
+		}
+
 		// Kill session if it exists (with proper process cleanup to avoid orphans)
 		t := tmux.NewTmux()
 		sessionID := crewSessionName(r.Name, name)
@@ -82,8 +96,17 @@ func runCrewRemove(cmd *cobra.Command, args []string) error {
 			isWorktree = true
 		}
 
-		// Remove the workspace
-		if isWorktree {
+		// Remove (or archive) the workspace
+		if crewArchive {
+			archived, err := crewMgr.Archive(name)
+			if err != nil {
+				fmt.Printf("Error archiving %s: %v\n", arg, err)
+				lastErr = err
+				continue
+			}
+			fmt.Printf("%s Archived crew workspace %s/%s to %s\n",
+				style.Bold.Render("✓"), r.Name, name, archived)
+		} else if isWorktree {
 			// For worktrees, use git worktree remove
 			mayorRigPath := constants.RigMayorPath(r.Path)
 			removeArgs := []string{"worktree", "remove", crewPath}
@@ -117,10 +140,6 @@ func runCrewRemove(cmd *cobra.Command, args []string) error {
 		}
 
 		// Handle agent bead
-		townRoot, _ := workspace.Find(r.Path)
-		if townRoot == "" {
-			townRoot = r.Path
-		}
 		prefix := beads.GetPrefixForRig(townRoot, r.Name)
 		agentBeadID := beads.CrewBeadIDWithPrefix(prefix, r.Name, name)
 
@@ -157,13 +176,6 @@ func runCrewRemove(cmd *cobra.Command, args []string) error {
 					}
 				}
 			}
-
-			// Clear mail directory if it exists
-			mailDir := filepath.Join(crewPath, "mail")
-			if _, err := os.Stat(mailDir); err == nil {
-				// Mail dir was removed with the workspace, so nothing to do
-				// But if we want to be extra thorough, we could look in town beads
-			}
 		} else {
 			// Default: CLOSE the agent bead (preserves CV history)
 			closeArgs := []string{"close", agentBeadID, "--reason=Crew workspace removed"}
@@ -182,6 +194,15 @@ func runCrewRemove(cmd *cobra.Command, args []string) error {
 				fmt.Printf("Closed agent bead: %s\n", agentBeadID)
 			}
 		}
+
+		// Mail to a removed crew member would never be read
+		if cleared, err := clearCrewMail(townRoot, r.Name, name); err != nil {
+			style.PrintWarning("could not clear mail for %s: %v", name, err)
+		} else if cleared > 0 {
+			fmt.Printf("Cleared %d message(s)\n", cleared)
+		}
+
+		setManifestCrew(townRoot, r.Name, name, false)
 	}
 
 	return lastErr
@@ -131,3 +131,66 @@ func runCrewPristine(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runCrewRepair(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	// Parse rig/name format (e.g., "beads/emma" -> rig=beads, name=emma)
+	if rig, crewName, ok := parseRigSlashName(name); ok {
+		if crewRig == "" {
+			crewRig = rig
+		}
+		name = crewName
+	}
+
+	crewMgr, r, err := getCrewManager(crewRig)
+	if err != nil {
+		return err
+	}
+
+	worker, repaired, err := crewMgr.Repair(name)
+	if err != nil {
+		return fmt.Errorf("repairing crew workspace: %w", err)
+	}
+	if !repaired {
+		fmt.Printf("%s Crew workspace %s/%s is intact, nothing to repair\n",
+			style.Bold.Render("✓"), r.Name, name)
+		return nil
+	}
+
+	fmt.Printf("%s Recreated crew workspace %s/%s as a worktree\n",
+		style.Bold.Render("✓"), r.Name, name)
+	fmt.Printf("  Path: %s\n", worker.ClonePath)
+	fmt.Printf("  Branch: %s\n", worker.Branch)
+	return nil
+}
+
+func runCrewPrune(cmd *cobra.Command, args []string) error {
+	crewMgr, r, err := getCrewManager(crewRig)
+	if err != nil {
+		return err
+	}
+
+	result, err := crewMgr.PruneWorktrees()
+	if err != nil {
+		return fmt.Errorf("pruning crew worktrees: %w", err)
+	}
+
+	if crewJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	if len(result.DeletedBranches) == 0 && len(result.KeptBranches) == 0 {
+		fmt.Printf("%s No stale crew worktrees in %s\n", style.Bold.Render("✓"), r.Name)
+		return nil
+	}
+	for _, branch := range result.DeletedBranches {
+		fmt.Printf("%s Deleted merged branch %s\n", style.Bold.Render("✓"), branch)
+	}
+	for _, branch := range result.KeptBranches {
+		fmt.Printf("%s Kept %s: no workspace, but has unmerged commits\n",
+			style.Warning.Render("!"), branch)
+	}
+	return nil
+}
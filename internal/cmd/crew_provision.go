@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Crew provisioning flags (gt crew add --start, gt crew remove --drain/--archive)
+var (
+	crewStart        bool
+	crewDrain        bool
+	crewDrainTimeout time.Duration
+	crewArchive      bool
+)
+
+// crewIdentity returns the daemon identity of a crew member.
+func crewIdentity(rigName, name string) string {
+	return rigName + "-crew-" + name
+}
+
+// newCrewSessionController creates a session controller for crew commands.
+// Its progress lines are shown with --debug.
+func newCrewSessionController(townRoot string) *daemon.SessionController {
+	var out io.Writer = io.Discard
+	if crewDebug {
+		out = os.Stderr
+	}
+	return daemon.NewSessionController(townRoot, log.New(out, "  ", 0))
+}
+
+// setManifestCrew declares (add) or undeclares a crew member in town.yaml.
+// The reconciler stops crew sessions a listed rig doesn't declare, so a new
+// crew member must be registered to stay up. Towns without a manifest, and
+// rigs it doesn't list, are left alone.
+func setManifestCrew(townRoot, rigName, name string, add bool) {
+	m, err := manifest.Load(townRoot)
+	if err != nil {
+		style.PrintWarning("could not update %s: %v", manifest.FileName, err)
+		return
+	}
+	if m == nil {
+		return
+	}
+
+	var changed bool
+	if add {
+		if _, listed := m.Rigs[rigName]; !listed {
+			fmt.Printf("  %s\n", style.Dim.Render(fmt.Sprintf("Rig %s isn't listed in %s; crew not registered", rigName, manifest.FileName)))
+			return
+		}
+		changed = m.AddCrew(rigName, name)
+	} else {
+		changed = m.RemoveCrew(rigName, name)
+	}
+	if !changed {
+		return
+	}
+	if err := manifest.Save(townRoot, m); err != nil {
+		style.PrintWarning("could not update %s: %v", manifest.FileName, err)
+		return
+	}
+	if add {
+		fmt.Printf("  Registered in %s\n", manifest.FileName)
+	} else {
+		fmt.Printf("Removed from %s\n", manifest.FileName)
+	}
+}
+
+// startCrewSession starts a freshly added crew member's session through the
+// daemon's session machinery.
+func startCrewSession(townRoot, rigName, name string) error {
+	ctl := newCrewSessionController(townRoot)
+	if _, err := ctl.Start(crewIdentity(rigName, name)); err != nil {
+		return err
+	}
+	fmt.Printf("  Session: %s\n", ctl.SessionName(crewIdentity(rigName, name)))
+	return nil
+}
+
+// drainCrewSession asks a crew member's running session to wrap up and
+// waits for it to exit, stopping it after crewDrainTimeout.
+func drainCrewSession(townRoot, rigName, name string) error {
+	ctl := newCrewSessionController(townRoot)
+	identity := crewIdentity(rigName, name)
+	fmt.Printf("Draining %s (up to %v)...\n", identity, crewDrainTimeout)
+	stopped, err := ctl.Drain(identity, "gt crew remove", crewDrainTimeout)
+	if err != nil {
+		return err
+	}
+	if stopped {
+		fmt.Printf("Session %s drained\n", ctl.SessionName(identity))
+	}
+	return nil
+}
+
+// clearCrewMail acknowledges the open mail addressed to a removed crew
+// member, so it doesn't linger in the town's beads. Returns how many
+// messages were cleared.
+func clearCrewMail(townRoot, rigName, name string) (int, error) {
+	mailbox := mail.NewMailboxFromAddress(fmt.Sprintf("%s/crew/%s", rigName, name), townRoot)
+	messages, err := mailbox.List()
+	if err != nil {
+		return 0, err
+	}
+	cleared := 0
+	for _, msg := range messages {
+		if err := mailbox.Delete(msg.ID); err != nil {
+			return cleared, err
+		}
+		cleared++
+	}
+	return cleared, nil
+}
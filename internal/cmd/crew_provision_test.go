@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/manifest"
+)
+
+func TestSetManifestCrew(t *testing.T) {
+	townRoot := t.TempDir()
+	src := "version: 1\nrigs:\n  gastown:\n    crew: [max]\n"
+	if err := os.WriteFile(manifest.Path(townRoot), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setManifestCrew(townRoot, "gastown", "ada", true)
+	setManifestCrew(townRoot, "sandbox", "ada", true) // unlisted rig: no-op
+	m, err := manifest.Load(townRoot)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if crew := m.Rigs["gastown"].Crew; !slices.Equal(crew, []string{"max", "ada"}) {
+		t.Errorf("gastown crew after add = %v", crew)
+	}
+	if _, ok := m.Rigs["sandbox"]; ok {
+		t.Error("adding to an unlisted rig should not list it")
+	}
+
+	setManifestCrew(townRoot, "gastown", "max", false)
+	m, _ = manifest.Load(townRoot)
+	if crew := m.Rigs["gastown"].Crew; !slices.Equal(crew, []string{"ada"}) {
+		t.Errorf("gastown crew after remove = %v", crew)
+	}
+}
+
+func TestSetManifestCrew_NoManifest(t *testing.T) {
+	townRoot := t.TempDir()
+	setManifestCrew(townRoot, "gastown", "ada", true)
+	if _, err := os.Stat(manifest.Path(townRoot)); !os.IsNotExist(err) {
+		t.Errorf("a town without a manifest should not get one: %v", err)
+	}
+}
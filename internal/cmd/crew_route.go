@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/crew"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Crew capability flags
+var (
+	crewCapsClear bool
+	crewRouteAll  bool
+)
+
+var crewCapabilitiesCmd = &cobra.Command{
+	Use:   "capabilities <name> [capability...]",
+	Short: "Show or set what a crew member can work on",
+	Long: `Show or set the capabilities a crew member declares on its agent bead.
+
+Capabilities are free-form tokens, by convention kind:value - languages,
+repos, and tools:
+
+  lang:go  lang:python  repo:gastown  tool:docker
+
+Work beads ask for capabilities with needs:<capability> labels
+(bd label add gt-abc needs:lang:go); 'gt crew route' and the daemon's
+capability routing assign such work only to crew that has them all.
+
+With capabilities, replaces the declared set; with --clear, removes it.
+
+Examples:
+  gt crew capabilities max                             # Show
+  gt crew capabilities max lang:go repo:gastown tool:docker
+  gt crew capabilities gastown/max --clear`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCrewCapabilities,
+}
+
+var crewRouteCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Assign ready work to crew with the needed capabilities",
+	Long: `Assign the rig's unassigned ready work to crew by capability.
+
+Each ready bead with needs:<capability> labels goes to the least loaded
+crew member (fewest in-progress beads) whose agent bead declares every
+needed capability. Work no crew member can take is listed with what is
+missing. Beads without needs: labels are left alone unless --all is given.
+
+Examples:
+  gt crew route --dry-run     # Show what would be assigned
+  gt crew route --rig gastown
+  gt crew route --all         # Also spread work that needs nothing special`,
+	RunE: runCrewRoute,
+}
+
+func init() {
+	crewCapabilitiesCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
+	crewCapabilitiesCmd.Flags().BoolVar(&crewCapsClear, "clear", false, "Remove all declared capabilities")
+
+	crewRouteCmd.Flags().StringVar(&crewRig, "rig", "", "Rig to use")
+	crewRouteCmd.Flags().BoolVar(&crewDryRun, "dry-run", false, "Show assignments without making them")
+	crewRouteCmd.Flags().BoolVar(&crewRouteAll, "all", false, "Also route work without needs: labels")
+
+	crewCmd.AddCommand(crewCapabilitiesCmd)
+	crewCmd.AddCommand(crewRouteCmd)
+}
+
+func runCrewCapabilities(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if rig, crewName, ok := parseRigSlashName(name); ok {
+		if crewRig == "" {
+			crewRig = rig
+		}
+		name = crewName
+	}
+	caps := args[1:]
+	if crewCapsClear && len(caps) > 0 {
+		return fmt.Errorf("--clear takes no capabilities")
+	}
+
+	crewMgr, r, err := getCrewManager(crewRig)
+	if err != nil {
+		return err
+	}
+	if _, err := crewMgr.Get(name); err != nil {
+		if err == crew.ErrCrewNotFound {
+			return fmt.Errorf("crew workspace '%s' not found", name)
+		}
+		return fmt.Errorf("getting crew worker: %w", err)
+	}
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	bd := beads.New(beads.ResolveBeadsDir(r.Path))
+	crewID := beads.CrewBeadIDWithPrefix(beads.GetPrefixForRig(townRoot, r.Name), r.Name, name)
+
+	if len(caps) == 0 && !crewCapsClear {
+		issue, err := bd.Show(crewID)
+		if err != nil {
+			return fmt.Errorf("reading agent bead %s: %w", crewID, err)
+		}
+		declared := beads.ParseCapabilities(beads.ParseAgentFields(issue.Description).Capabilities)
+		if len(declared) == 0 {
+			fmt.Printf("%s/%s declares no capabilities\n", r.Name, name)
+			return nil
+		}
+		fmt.Printf("%s/%s: %s\n", r.Name, name, strings.Join(declared, " "))
+		return nil
+	}
+
+	if err := bd.UpdateAgentCapabilities(crewID, caps); err != nil {
+		return fmt.Errorf("updating agent bead %s: %w", crewID, err)
+	}
+	if crewCapsClear {
+		fmt.Printf("%s Cleared capabilities of %s/%s\n", style.Bold.Render("✓"), r.Name, name)
+		return nil
+	}
+	fmt.Printf("%s %s/%s: %s\n", style.Bold.Render("✓"), r.Name, name, beads.FormatCapabilities(caps))
+	return nil
+}
+
+func runCrewRoute(cmd *cobra.Command, args []string) error {
+	_, r, err := getCrewManager(crewRig)
+	if err != nil {
+		return err
+	}
+	bd := beads.New(beads.ResolveBeadsDir(r.Path))
+
+	ready, err := bd.Ready()
+	if err != nil {
+		return fmt.Errorf("listing ready work: %w", err)
+	}
+	ready = slices.DeleteFunc(ready, func(issue *beads.Issue) bool { return issue.Assignee != "" })
+	agentBeads, err := bd.List(beads.ListOptions{Label: "gt:agent", Priority: -1})
+	if err != nil {
+		return fmt.Errorf("listing agent beads: %w", err)
+	}
+	active, err := bd.List(beads.ListOptions{Status: "in_progress", Priority: -1})
+	if err != nil {
+		return fmt.Errorf("listing work in progress: %w", err)
+	}
+
+	crewAgents := beads.CapableCrew(r.Name, agentBeads, active)
+	if len(crewAgents) == 0 {
+		return fmt.Errorf("rig %s has no crew agent beads", r.Name)
+	}
+	routes, gaps := beads.RouteWork(ready, crewAgents, crewRouteAll)
+	if len(routes) == 0 && len(gaps) == 0 {
+		fmt.Printf("No ready work to route in %s\n", r.Name)
+		return nil
+	}
+
+	for _, route := range routes {
+		if crewDryRun {
+			fmt.Printf("  Would assign %s to %s: %s\n", route.Issue.ID, route.Agent, route.Issue.Title)
+			continue
+		}
+		assignee := route.Agent
+		if err := bd.Update(route.Issue.ID, beads.UpdateOptions{Assignee: &assignee}); err != nil {
+			style.PrintWarning("could not assign %s to %s: %v", route.Issue.ID, route.Agent, err)
+			continue
+		}
+		fmt.Printf("  %s Assigned %s to %s: %s\n", style.Bold.Render("✓"), route.Issue.ID, route.Agent, route.Issue.Title)
+	}
+	for _, gap := range gaps {
+		fmt.Printf("  %s No crew for %s (needs %s): %s\n", style.Bold.Render("⚠"),
+			gap.Issue.ID, strings.Join(gap.Missing, ", "), gap.Issue.Title)
+	}
+	return nil
+}
@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/daemon"
 	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
 
@@ -22,10 +29,13 @@ var daemonCmd = &cobra.Command{
 
 The daemon is a simple Go process that:
 - Pokes agents periodically (heartbeat)
-- Processes lifecycle requests (cycle, restart, shutdown)
+- Processes lifecycle requests (cycle, restart, shutdown, refresh)
 - Restarts sessions when agents request cycling
 
-The daemon is a "dumb scheduler" - all intelligence is in agents.`,
+The daemon is a "dumb scheduler" - all intelligence is in agents.
+
+To keep it running across reboots, install it as a systemd or launchd user
+service with 'gt daemon install'.`,
 }
 
 var daemonStartCmd = &cobra.Command{
@@ -33,7 +43,16 @@ var daemonStartCmd = &cobra.Command{
 	Short: "Start the daemon",
 	Long: `Start the Gas Town daemon in the background.
 
-The daemon will run until stopped with 'gt daemon stop'.`,
+The daemon will run until stopped with 'gt daemon stop'.
+
+Only one daemon acts per town. With --standby, start a second daemon that
+waits and takes over if the running one dies (crash, kill -9, or its
+machine going away when the town is on a shared filesystem). Standbys
+exit when the leader is stopped cleanly with 'gt daemon stop'.
+
+With --record FILE, the daemon appends every lifecycle pass to FILE: the
+config and state it read, and each tmux, mail, and bd call with its answer.
+Replay a recording with 'gt daemon replay FILE' to see how it decided.`,
 	RunE: runDaemonStart,
 }
 
@@ -44,11 +63,29 @@ var daemonStopCmd = &cobra.Command{
 	RunE:  runDaemonStop,
 }
 
+var daemonReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the daemon's config without restarting it",
+	Long: `Validate mayor/daemon.json, mayor/rigs.json, and the role plugins in
+roles/, then have the running daemon reload them.
+
+The daemon also reloads on its own within seconds of one of these files
+changing, and on SIGHUP. A config that fails validation is rejected and
+the running config stays in effect; 'gt daemon status' shows the last
+reload's outcome. Session naming, the tmux server, the API listener, the
+agent state backend, and tracing only change when the daemon restarts.`,
+	RunE: runDaemonReload,
+}
+
 var daemonStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show daemon status",
-	Long:  `Show the current status of the Gas Town daemon.`,
-	RunE:  runDaemonStatus,
+	Long: `Show the current status of the Gas Town daemon.
+
+With --json, prints whether the daemon is running along with its saved
+state, including heartbeat counters and deacon inbox poll stats (current
+adaptive interval, last poll, unread count).`,
+	RunE: runDaemonStatus,
 }
 
 var daemonLogsCmd = &cobra.Command{
@@ -58,6 +95,67 @@ var daemonLogsCmd = &cobra.Command{
 	RunE:  runDaemonLogs,
 }
 
+var daemonSafeModeCmd = &cobra.Command{
+	Use:   "safe-mode",
+	Short: "Show or clear daemon safe mode",
+	Long: `Show why the daemon is in safe mode.
+
+If the daemon crashes repeatedly before completing its first heartbeat
+(bad config, corrupt store, a startup panic), the next start comes up in
+safe mode: it holds the PID file and reports status, but runs no heartbeat,
+lifecycle processing, or watchers. Safe mode persists across restarts until
+cleared.
+
+Examples:
+  gt daemon safe-mode          # Show reason and recent failed starts
+  gt daemon safe-mode clear    # Clear after fixing the cause, then restart`,
+	RunE: runDaemonSafeMode,
+}
+
+var daemonSafeModeClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear safe mode so the next start runs normally",
+	RunE:  runDaemonSafeModeClear,
+}
+
+var daemonBeadsSyncCmd = &cobra.Command{
+	Use:   "beads-sync",
+	Short: "Show or run the scheduled bd sync across all rigs",
+	Long: `Show the report from the daemon's last scheduled beads sync round.
+
+When "beads_sync" is enabled in mayor/daemon.json, the daemon syncs every
+beads database in the town on a schedule (default every 30m) instead of
+only at session restart. Each round checks every database once (worktrees
+that redirect to a shared database are not synced twice), syncs only the
+ones that are ahead or behind, and skips and reports any with conflicts.
+
+  "beads_sync": {"enabled": true, "interval": "15m"}
+
+Examples:
+  gt daemon beads-sync           # Show the last report
+  gt daemon beads-sync --now     # Run a round now and show its report`,
+	RunE: runDaemonBeadsSync,
+}
+
+var daemonRenameSessionsCmd = &cobra.Command{
+	Use:   "rename-sessions",
+	Short: "Rename running agent sessions to the configured naming scheme",
+	Long: `Rename running agent sessions after changing "session_naming" in
+mayor/daemon.json, so the daemon finds them under their new names instead
+of starting a second copy of every agent.
+
+Sessions are matched by the name they have without session_naming (the
+built-in gt-/hq- names, or a role bead's session_pattern). A rename is
+skipped if a session with the new name is already running.
+
+  "session_naming": {"prefix": "town1-", "hq_prefix": "town1-hq-"}
+
+Examples:
+  gt daemon rename-sessions --dry-run   # Show what would be renamed
+  gt daemon rename-sessions`,
+	RunE: runDaemonRenameSessions,
+}
+
 var daemonRunCmd = &cobra.Command{
 	Use:    "run",
 	Short:  "Run daemon in foreground (internal)",
@@ -66,19 +164,45 @@ var daemonRunCmd = &cobra.Command{
 }
 
 var (
-	daemonLogLines int
+	daemonLogLines  int
 	daemonLogFollow bool
+	daemonDryRun    bool
+	daemonStandby   bool
+	daemonRecord    string
+
+	daemonBeadsSyncNow  bool
+	daemonBeadsSyncJSON bool
+
+	daemonStatusJSON bool
+
+	daemonRenameDryRun bool
 )
 
 func init() {
 	daemonCmd.AddCommand(daemonStartCmd)
 	daemonCmd.AddCommand(daemonStopCmd)
 	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonReloadCmd)
 	daemonCmd.AddCommand(daemonLogsCmd)
 	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonSafeModeCmd)
+	daemonSafeModeCmd.AddCommand(daemonSafeModeClearCmd)
+	daemonCmd.AddCommand(daemonBeadsSyncCmd)
+	daemonCmd.AddCommand(daemonRenameSessionsCmd)
+
+	daemonStatusCmd.Flags().BoolVar(&daemonStatusJSON, "json", false, "Output as JSON")
+	daemonBeadsSyncCmd.Flags().BoolVar(&daemonBeadsSyncNow, "now", false, "Run a sync round now")
+	daemonBeadsSyncCmd.Flags().BoolVar(&daemonBeadsSyncJSON, "json", false, "Output as JSON")
+	daemonRenameSessionsCmd.Flags().BoolVar(&daemonRenameDryRun, "dry-run", false, "Show renames without performing them")
 
 	daemonLogsCmd.Flags().IntVarP(&daemonLogLines, "lines", "n", 50, "Number of lines to show")
 	daemonLogsCmd.Flags().BoolVarP(&daemonLogFollow, "follow", "f", false, "Follow log output")
+	daemonStartCmd.Flags().BoolVar(&daemonDryRun, "dry-run", false, "Verify and log the daemon's actions without executing them")
+	daemonRunCmd.Flags().BoolVar(&daemonDryRun, "dry-run", false, "Verify and log the daemon's actions without executing them")
+	daemonStartCmd.Flags().BoolVar(&daemonStandby, "standby", false, "Start a standby that takes over if the running daemon dies")
+	daemonRunCmd.Flags().BoolVar(&daemonStandby, "standby", false, "Wait for the running daemon to die, then take over")
+	daemonStartCmd.Flags().StringVar(&daemonRecord, "record", "", "Record lifecycle decisions to `FILE` for 'gt daemon replay'")
+	daemonRunCmd.Flags().StringVar(&daemonRecord, "record", "", "Record lifecycle decisions to `FILE` for 'gt daemon replay'")
 
 	rootCmd.AddCommand(daemonCmd)
 }
@@ -94,10 +218,17 @@ func runDaemonStart(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("checking daemon status: %w", err)
 	}
-	if running {
+	if running && !daemonStandby {
 		return fmt.Errorf("daemon already running (PID %d)", pid)
 	}
 
+	// An installed service starts the daemon so it stays supervised
+	if !daemonStandby && !daemonDryRun {
+		if service := townServiceStatus(townRoot); service != nil && service.Installed {
+			return startDaemonService(townRoot)
+		}
+	}
+
 	// Start daemon in background
 	// We use 'gt daemon run' as the actual daemon process
 	gtPath, err := os.Executable()
@@ -105,7 +236,22 @@ func runDaemonStart(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("finding executable: %w", err)
 	}
 
-	daemonCmd := exec.Command(gtPath, "daemon", "run")
+	runArgs := []string{"daemon", "run"}
+	if daemonDryRun {
+		runArgs = append(runArgs, "--dry-run")
+	}
+	if daemonStandby {
+		runArgs = append(runArgs, "--standby")
+	}
+	if daemonRecord != "" {
+		// The daemon runs in the town root
+		record, err := filepath.Abs(daemonRecord)
+		if err != nil {
+			return fmt.Errorf("resolving --record: %w", err)
+		}
+		runArgs = append(runArgs, "--record", record)
+	}
+	daemonCmd := exec.Command(gtPath, runArgs...)
 	daemonCmd.Dir = townRoot
 
 	// Detach from terminal
@@ -120,6 +266,16 @@ func runDaemonStart(cmd *cobra.Command, args []string) error {
 	// Wait a moment for the daemon to initialize and acquire the lock
 	time.Sleep(200 * time.Millisecond)
 
+	if daemonStandby && running {
+		// A standby never writes the PID file while the leader lives
+		if err := daemonCmd.Process.Signal(syscall.Signal(0)); err != nil {
+			return fmt.Errorf("standby daemon failed to start (check logs with 'gt daemon logs')")
+		}
+		fmt.Printf("%s Standby daemon started (PID %d), leader is PID %d\n",
+			style.Bold.Render("✓"), daemonCmd.Process.Pid, pid)
+		return nil
+	}
+
 	// Verify it started
 	running, pid, err = daemon.IsRunning(townRoot)
 	if err != nil {
@@ -138,6 +294,10 @@ func runDaemonStart(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if daemonDryRun {
+		fmt.Printf("%s Daemon started in dry-run mode (PID %d)\n", style.Bold.Render("✓"), pid)
+		return nil
+	}
 	fmt.Printf("%s Daemon started (PID %d)\n", style.Bold.Render("✓"), pid)
 	return nil
 }
@@ -164,6 +324,67 @@ func runDaemonStop(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runDaemonReload(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if err := daemon.CheckConfig(townRoot); err != nil {
+		return fmt.Errorf("config not reloaded: %w", err)
+	}
+
+	running, pid, err := daemon.IsRunning(townRoot)
+	if err != nil {
+		return fmt.Errorf("checking daemon status: %w", err)
+	}
+	if !running {
+		fmt.Printf("%s Config is valid; daemon is not running\n", style.Bold.Render("✓"))
+		return nil
+	}
+
+	sent := time.Now()
+	if err := daemon.ReloadDaemon(pid); err != nil {
+		return fmt.Errorf("signaling daemon: %w", err)
+	}
+	for i := 0; i < 30; i++ {
+		time.Sleep(100 * time.Millisecond)
+		state, err := daemon.LoadState(townRoot)
+		if err != nil || state.ConfigReload == nil || state.ConfigReload.At.Before(sent) {
+			continue
+		}
+		if state.ConfigReload.Error != "" {
+			return fmt.Errorf("daemon rejected the config: %s", state.ConfigReload.Error)
+		}
+		fmt.Printf("%s Daemon reloaded its config (PID %d)\n", style.Bold.Render("✓"), pid)
+		printConfigReloadRestart(state.ConfigReload)
+		return nil
+	}
+	fmt.Printf("%s Reload requested (PID %d); check '%s' for the outcome\n",
+		style.Bold.Render("✓"), pid, style.Dim.Render("gt daemon status"))
+	return nil
+}
+
+// printConfigReloadRestart lists changed sections a reload couldn't apply.
+func printConfigReloadRestart(reload *daemon.ConfigReload) {
+	if len(reload.NeedsRestart) > 0 {
+		fmt.Printf("  %s Restart the daemon to apply: %s\n", style.Bold.Render("⚠"), strings.Join(reload.NeedsRestart, ", "))
+	}
+}
+
+// printConfigReloadStatus shows the last config reload if it needs
+// attention.
+func printConfigReloadStatus(reload *daemon.ConfigReload) {
+	if reload == nil {
+		return
+	}
+	if reload.Error != "" {
+		fmt.Printf("  %s Config reload rejected at %s (running config kept): %s\n", style.Bold.Render("⚠"),
+			reload.At.Local().Format("2006-01-02 15:04"), reload.Error)
+		return
+	}
+	printConfigReloadRestart(reload)
+}
+
 func runDaemonStatus(cmd *cobra.Command, args []string) error {
 	townRoot, err := workspace.FindFromCwdOrError()
 	if err != nil {
@@ -175,6 +396,10 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("checking daemon status: %w", err)
 	}
 
+	if daemonStatusJSON {
+		return printDaemonStatusJSON(townRoot, running, pid)
+	}
+
 	if running {
 		fmt.Printf("%s Daemon is %s (PID %d)\n",
 			style.Bold.Render("●"),
@@ -190,6 +415,32 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 					state.LastHeartbeat.Format("15:04:05"),
 					state.HeartbeatCount)
 			}
+			if socket := daemon.TmuxSocket(townRoot); socket != "" {
+				fmt.Printf("  Tmux server: %s\n", style.Dim.Render("tmux -L "+socket))
+			}
+			if state.SafeMode {
+				fmt.Printf("  %s SAFE MODE: %s\n", style.Bold.Render("⚠"), state.SafeModeReason)
+				fmt.Printf("    Executors are disabled. Fix the cause, then '%s'\n",
+					style.Dim.Render("gt daemon safe-mode clear && gt daemon stop && gt daemon start"))
+			}
+			if stop, err := daemon.LoadEmergencyStop(townRoot); err == nil && stop != nil {
+				fmt.Printf("  %s EMERGENCY STOP since %s (%s): %s\n", style.Bold.Render("■"),
+					stop.Since.Local().Format("2006-01-02 15:04"), stop.RequestedBy, stop.Reason)
+				fmt.Printf("    No sessions will be started. Lift with '%s'\n", style.Dim.Render("gt town resume"))
+			}
+			printMailPollStatus(state.MailPoll)
+			printRateLimitStatus(state.RateLimited)
+			printEscalationStatus(state.Escalations)
+			printZombieStatus(state.Zombies)
+			printHeartbeatStatus(state.AgentHeartbeats)
+			printContextCycleStatus(state.ContextCycles)
+			printDiskUsageStatus(state.DiskUsage)
+			printCapabilityGapStatus(state.CapabilityGaps)
+			printHibernatingStatus(state.Hibernating)
+			printDetectedRigsStatus(state.DetectedRigs)
+			printConfigReloadStatus(state.ConfigReload)
+			printLeaderStatus(townRoot, pid)
+			printServiceStatus(townServiceStatus(townRoot))
 
 			// Check if binary is newer than process
 			if binaryModTime, err := getBinaryModTime(); err == nil {
@@ -205,12 +456,267 @@ func runDaemonStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%s Daemon is %s\n",
 			style.Dim.Render("○"),
 			"not running")
+		service := townServiceStatus(townRoot)
+		printServiceStatus(service)
+		if j, err := daemon.LoadStartupJournal(townRoot); err == nil {
+			if crashes := j.RecentCrashes(time.Now()); len(crashes) > 0 {
+				fmt.Printf("  %s %d failed start(s) recently (see '%s')\n",
+					style.Bold.Render("⚠"), len(crashes), style.Dim.Render("gt daemon safe-mode"))
+			}
+		}
 		fmt.Printf("\nStart with: %s\n", style.Dim.Render("gt daemon start"))
+		if service != nil && !service.Installed {
+			fmt.Printf("Keep it running across reboots: %s\n", style.Dim.Render("gt daemon install"))
+		}
 	}
 
 	return nil
 }
 
+// printLeaderStatus prints the leader lease when it names another machine
+// or has lapsed, which only matters for towns shared between machines.
+func printLeaderStatus(townRoot string, pid int) {
+	lease, err := daemon.LoadLease(townRoot)
+	if err != nil || lease == nil {
+		return
+	}
+	host, _ := os.Hostname()
+	switch {
+	case !lease.Live(time.Now()):
+		fmt.Printf("  %s Leader lease of %s lapsed at %s\n",
+			style.Bold.Render("⚠"), lease.Holder, lease.ExpiresAt.Format("15:04:05"))
+	case lease.Host != host || lease.PID != pid:
+		fmt.Printf("  Leader: %s (renewed %s)\n", lease.Holder, lease.RenewedAt.Format("15:04:05"))
+	}
+}
+
+// printMailPollStatus prints deacon inbox poll health from daemon state.
+func printMailPollStatus(poll *daemon.MailPollStats) {
+	if poll == nil || poll.LastPollAt.IsZero() {
+		return
+	}
+
+	if poll.ConsecutiveFailures > 0 {
+		lastSuccess := "never"
+		if !poll.LastSuccessAt.IsZero() {
+			lastSuccess = poll.LastSuccessAt.Format("15:04:05")
+		}
+		fmt.Printf("  %s Mail poll failing: %d consecutive failures (last success: %s)\n",
+			style.Bold.Render("⚠"), poll.ConsecutiveFailures, lastSuccess)
+		if poll.LastError != "" {
+			fmt.Printf("    %s\n", style.Dim.Render(poll.LastError))
+		}
+		return
+	}
+
+	fmt.Printf("  Last mail poll: %s (%d unread)\n", poll.LastSuccessAt.Format("15:04:05"), poll.LastUnread)
+	if poll.Interval > 0 {
+		fmt.Printf("  Mail poll interval: %s\n", poll.Interval)
+	}
+	if !poll.OldestUnreadAt.IsZero() {
+		fmt.Printf("  Oldest unread deacon mail: %s old\n",
+			time.Since(poll.OldestUnreadAt).Round(time.Second))
+	}
+	if poll.Duplicates > 0 {
+		fmt.Printf("  Duplicate messages collapsed: %d\n", poll.Duplicates)
+	}
+}
+
+// printRateLimitStatus lists senders whose lifecycle requests were rejected
+// for exceeding the rate limit.
+func printRateLimitStatus(limited map[string]*daemon.RateLimitStats) {
+	senders := make([]string, 0, len(limited))
+	for sender := range limited {
+		senders = append(senders, sender)
+	}
+	sort.Strings(senders)
+	for _, sender := range senders {
+		stats := limited[sender]
+		fmt.Printf("  %s Rate limited: %s (%d rejected, last %s",
+			style.Bold.Render("⚠"), sender, stats.Rejected, stats.LastRejectedAt.Format("15:04:05"))
+		if stats.Escalations > 0 {
+			fmt.Printf(", escalated %d×", stats.Escalations)
+		}
+		fmt.Println(")")
+	}
+}
+
+// printEscalationStatus lists agents witnesses have escalated, with the
+// most recent report.
+func printEscalationStatus(escalations map[string]*daemon.EscalationHistory) {
+	agents := make([]string, 0, len(escalations))
+	for agent := range escalations {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		history := escalations[agent]
+		if len(history.Reports) == 0 {
+			continue
+		}
+		last := history.Reports[len(history.Reports)-1]
+		fmt.Printf("  %s Escalated: %s (%d reports, last %s: %s",
+			style.Bold.Render("⚠"), agent, history.Total, last.At.Format("2006-01-02 15:04"), last.Kind)
+		if last.Bead != "" {
+			fmt.Printf(", %s", last.Bead)
+		}
+		fmt.Println(")")
+	}
+}
+
+// printZombieStatus prints what the zombie reaper found: agent processes
+// that outlived their session.
+func printZombieStatus(zombies *daemon.ZombieStats) {
+	if zombies == nil || (zombies.Found == 0 && zombies.Terminated == 0) {
+		return
+	}
+	marker := "  "
+	if zombies.Found > 0 || zombies.Unkillable > 0 {
+		marker = "  " + style.Bold.Render("⚠") + " "
+	}
+	fmt.Printf("%sZombie agent processes: %d found at %s (%d terminated, %d killed",
+		marker, zombies.Found, zombies.LastScanAt.Format("15:04:05"), zombies.Terminated, zombies.Killed)
+	if zombies.Unkillable > 0 {
+		fmt.Printf(", %d unkillable", zombies.Unkillable)
+	}
+	fmt.Println(")")
+
+	identities := make([]string, 0, len(zombies.ByIdentity))
+	for identity := range zombies.ByIdentity {
+		identities = append(identities, identity)
+	}
+	sort.Strings(identities)
+	for _, identity := range identities {
+		fmt.Printf("    %s %s\n", style.Dim.Render(fmt.Sprintf("%d×", zombies.ByIdentity[identity])), identity)
+	}
+}
+
+// printHeartbeatStatus lists agents marked stuck for missing heartbeats.
+func printHeartbeatStatus(heartbeats map[string]*daemon.AgentHeartbeat) {
+	agents := make([]string, 0, len(heartbeats))
+	for agent, h := range heartbeats {
+		if !h.StuckSince.IsZero() {
+			agents = append(agents, agent)
+		}
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		h := heartbeats[agent]
+		last := "never"
+		if !h.LastHeartbeat.IsZero() {
+			last = h.LastHeartbeat.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  %s Stuck: %s (no heartbeat since %s", style.Bold.Render("⚠"), agent, last)
+		if h.Cycles > 0 {
+			fmt.Printf(", cycled %d times", h.Cycles)
+		}
+		fmt.Println(")")
+	}
+}
+
+// printContextCycleStatus lists agents waiting for an idle point to be
+// cycled on their context budget.
+func printContextCycleStatus(cycles map[string]*daemon.ContextCycle) {
+	agents := make([]string, 0, len(cycles))
+	for agent := range cycles {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		c := cycles[agent]
+		fmt.Printf("  %s Cycle pending: %s at %d%% context (waiting for idle since %s)\n",
+			style.Bold.Render("↻"), agent, c.Usage, c.ScheduledAt.Local().Format("15:04"))
+	}
+}
+
+// printDiskUsageStatus lists workspaces over their disk quota.
+func printDiskUsageStatus(usage map[string]*daemon.WorkspaceUsage) {
+	agents := make([]string, 0, len(usage))
+	for agent, u := range usage {
+		if u.Over() {
+			agents = append(agents, agent)
+		}
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		u := usage[agent]
+		fmt.Printf("  %s Over disk quota: %s (%s of %s; '%s')\n", style.Bold.Render("⚠"), agent,
+			daemon.FormatSize(u.Bytes), daemon.FormatSize(u.Quota), style.Dim.Render("gt workspace clean "+agent))
+	}
+}
+
+// printCapabilityGapStatus lists ready work no crew member can take.
+func printCapabilityGapStatus(gaps []daemon.CapabilityGap) {
+	for _, gap := range gaps {
+		fmt.Printf("  %s No crew for %s in %s: needs %s\n", style.Bold.Render("⚠"),
+			gap.Issue, gap.Rig, strings.Join(gap.Missing, ", "))
+	}
+}
+
+// printHibernatingStatus lists agents hibernated for being idle.
+func printHibernatingStatus(hibernating map[string]time.Time) {
+	agents := make([]string, 0, len(hibernating))
+	for agent := range hibernating {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		fmt.Printf("  %s Hibernating: %s (since %s; wakes on work or mail)\n", style.Dim.Render("z"),
+			agent, hibernating[agent].Local().Format("2006-01-02 15:04"))
+	}
+}
+
+// printDetectedRigsStatus lists the git checkouts in the town root that
+// aren't rigs yet.
+func printDetectedRigsStatus(detected map[string]*daemon.DetectedRig) {
+	dirs := make([]string, 0, len(detected))
+	for dir := range detected {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		rec := detected[dir]
+		note := "run 'gt rig provision " + dir + "'"
+		switch {
+		case rec.Problem != "":
+			note = rec.Problem
+		case rec.Error != "":
+			note = "provisioning failed: " + rec.Error
+		}
+		fmt.Printf("  %s New rig: %s (%s)\n", style.Warning.Render("+"), dir, note)
+	}
+}
+
+// daemonStatus is the JSON form of gt daemon status.
+type daemonStatus struct {
+	Running bool          `json:"running"`
+	PID     int           `json:"pid,omitempty"`
+	Leader  *daemon.Lease `json:"leader,omitempty"`
+	State   *daemon.State `json:"state,omitempty"`
+
+	// Service is the daemon's systemd/launchd service, when installed.
+	Service *daemon.ServiceStatus `json:"service,omitempty"`
+}
+
+func printDaemonStatusJSON(townRoot string, running bool, pid int) error {
+	status := daemonStatus{Running: running}
+	if running {
+		status.PID = pid
+	}
+	if lease, err := daemon.LoadLease(townRoot); err == nil {
+		status.Leader = lease
+	}
+	if state, err := daemon.LoadState(townRoot); err == nil {
+		status.State = state
+	}
+	if service := townServiceStatus(townRoot); service != nil && service.Installed {
+		status.Service = service
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(status)
+}
+
 // getBinaryModTime returns the modification time of the current executable
 func getBinaryModTime() (time.Time, error) {
 	exePath, err := os.Executable()
@@ -258,10 +764,156 @@ func runDaemonRun(cmd *cobra.Command, args []string) error {
 	}
 
 	config := daemon.DefaultConfig(townRoot)
+	config.DryRun = daemonDryRun
+	config.Standby = daemonStandby
+	config.RecordFile = daemonRecord
 	d, err := daemon.New(config)
 	if err != nil {
+		daemon.RecordStartupFailure(townRoot, err)
 		return fmt.Errorf("creating daemon: %w", err)
 	}
 
 	return d.Run()
 }
+
+func runDaemonSafeMode(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	j, err := daemon.LoadStartupJournal(townRoot)
+	if err != nil {
+		return err
+	}
+
+	if j.SafeMode {
+		fmt.Printf("%s Daemon is in %s since %s\n", style.Bold.Render("⚠"),
+			style.Bold.Render("safe mode"), j.SafeModeSince.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Reason: %s\n", j.SafeModeReason)
+	} else {
+		fmt.Printf("%s Daemon is not in safe mode\n", style.Success.Render("✓"))
+	}
+
+	if len(j.Attempts) > 0 {
+		fmt.Printf("\nRecent starts:\n")
+		for _, a := range j.Attempts {
+			status := style.Success.Render("ok")
+			if !a.Stable {
+				status = style.Warning.Render("failed")
+			}
+			line := fmt.Sprintf("  %s  PID %-7d %s", a.StartedAt.Format("2006-01-02 15:04:05"), a.PID, status)
+			if a.Cause != "" {
+				line += "  " + style.Dim.Render(a.Cause)
+			}
+			fmt.Println(line)
+		}
+	}
+	if j.SafeMode {
+		fmt.Printf("\nAfter fixing the cause: %s\n", style.Dim.Render("gt daemon safe-mode clear && gt daemon stop && gt daemon start"))
+	}
+	return nil
+}
+
+func runDaemonSafeModeClear(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if err := daemon.ClearSafeMode(townRoot); err != nil {
+		return fmt.Errorf("clearing safe mode: %w", err)
+	}
+	fmt.Printf("%s Safe mode cleared; restart the daemon to resume normal operation\n", style.Success.Render("✓"))
+	return nil
+}
+
+func runDaemonBeadsSync(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	var report *daemon.BeadsSyncReport
+	if daemonBeadsSyncNow {
+		report = daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0)).RunBeadsSync()
+	} else if report, err = daemon.LoadBeadsSyncReport(townRoot); err != nil {
+		return fmt.Errorf("loading sync report: %w", err)
+	}
+	if report == nil {
+		fmt.Printf("No beads sync report yet. Run %s or enable beads_sync in mayor/daemon.json.\n",
+			style.Dim.Render("gt daemon beads-sync --now"))
+		return nil
+	}
+
+	if daemonBeadsSyncJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Printf("%s %s (took %s)\n\n", style.Bold.Render("Beads sync"),
+		report.StartedAt.Format("2006-01-02 15:04:05"), report.Duration)
+	for _, res := range report.Results {
+		var icon, detail string
+		switch res.Status {
+		case daemon.BeadsSyncSynced:
+			icon = style.Success.Render("✓")
+			detail = fmt.Sprintf("synced (%d ahead, %d behind)", res.Ahead, res.Behind)
+		case daemon.BeadsSyncCurrent:
+			icon = style.Dim.Render("•")
+			detail = "up to date"
+		case daemon.BeadsSyncConflict:
+			icon = style.Warning.Render("⚠")
+			detail = "conflicts: " + strings.Join(res.Conflicts, ", ")
+		default:
+			icon = style.Warning.Render("✗")
+			detail = "failed: " + res.Error
+		}
+		fmt.Printf("  %s %-30s %s\n", icon, res.Workspace, detail)
+	}
+	fmt.Printf("\n%d synced, %d up to date, %d conflicts, %d failed\n",
+		report.Count(daemon.BeadsSyncSynced), report.Count(daemon.BeadsSyncCurrent),
+		report.Count(daemon.BeadsSyncConflict), report.Count(daemon.BeadsSyncFailed))
+	return nil
+}
+
+func runDaemonRenameSessions(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	live, err := tmux.NewTmux().ListSessions()
+	if err != nil {
+		return fmt.Errorf("listing sessions: %w", err)
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	plan := ctl.PlanSessionRenames(live)
+	if len(plan) == 0 {
+		fmt.Println("All running sessions already follow the configured naming scheme.")
+		return nil
+	}
+
+	var failed int
+	for _, r := range plan {
+		switch {
+		case r.Skipped != "":
+			fmt.Printf("  %s %s: skipped, %s\n", style.Warning.Render("⚠"), r.From, r.Skipped)
+		case daemonRenameDryRun:
+			fmt.Printf("  %s %s → %s\n", style.Dim.Render("•"), r.From, r.To)
+		default:
+			if err := ctl.RenameSession(r); err != nil {
+				fmt.Printf("  %s %v\n", style.Warning.Render("✗"), err)
+				failed++
+				continue
+			}
+			fmt.Printf("  %s %s → %s\n", style.Success.Render("✓"), r.From, r.To)
+		}
+	}
+	if daemonRenameDryRun {
+		fmt.Printf("\n%s\n", style.Dim.Render("Dry run: nothing renamed"))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d session(s) could not be renamed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Daemon audit flags
+var (
+	daemonAuditSince  string
+	daemonAuditOp     string
+	daemonAuditBy     string
+	daemonAuditTarget string
+	daemonAuditLimit  int
+	daemonAuditJSON   bool
+	daemonAuditVerify bool
+)
+
+var daemonAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the daemon's audit log of destructive operations",
+	Long: `Query the tamper-evident audit log of destructive daemon operations.
+
+Every session kill, daemon state write, and mail deletion is recorded in
+daemon/audit.jsonl with who requested it, which checks passed first, and
+the outcome. Records are hash-chained: each includes the hash of the one
+before it, so editing or removing a line is detected by --verify.
+
+Operations: kill_session, state_write, mail_delete
+
+Examples:
+  gt daemon audit                           # Last 50 records
+  gt daemon audit --op kill_session --since 24h
+  gt daemon audit --by gastown/witness      # Requested by an agent
+  gt daemon audit --verify                  # Check the hash chain`,
+	Args: cobra.NoArgs,
+	RunE: runDaemonAudit,
+}
+
+func init() {
+	daemonAuditCmd.Flags().StringVar(&daemonAuditSince, "since", "", "Only records newer than this (e.g., 1h, 7d)")
+	daemonAuditCmd.Flags().StringVar(&daemonAuditOp, "op", "", "Only this operation (kill_session, state_write, mail_delete)")
+	daemonAuditCmd.Flags().StringVar(&daemonAuditBy, "by", "", "Only records requested by this identity")
+	daemonAuditCmd.Flags().StringVar(&daemonAuditTarget, "target", "", "Only records whose target contains this text")
+	daemonAuditCmd.Flags().IntVarP(&daemonAuditLimit, "limit", "n", 50, "Show at most this many records (0 for all)")
+	daemonAuditCmd.Flags().BoolVar(&daemonAuditJSON, "json", false, "Output as JSON")
+	daemonAuditCmd.Flags().BoolVar(&daemonAuditVerify, "verify", false, "Verify the hash chain and report the first break")
+
+	daemonCmd.AddCommand(daemonAuditCmd)
+}
+
+func runDaemonAudit(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	records, err := daemon.LoadAuditLog(townRoot)
+	if err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+
+	if daemonAuditVerify {
+		if err := daemon.VerifyAuditChain(records); err != nil {
+			var chainErr *daemon.AuditChainError
+			if errors.As(err, &chainErr) {
+				fmt.Printf("%s %v\n", style.Error.Render("✗"), err)
+				return NewSilentExit(1)
+			}
+			return err
+		}
+		fmt.Printf("%s Audit chain intact (%d records)\n", style.Success.Render("✓"), len(records))
+		return nil
+	}
+
+	var since time.Time
+	if daemonAuditSince != "" {
+		window, err := parseDuration(daemonAuditSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = time.Now().Add(-window)
+	}
+
+	var matched []daemon.AuditRecord
+	for _, rec := range records {
+		switch {
+		case !since.IsZero() && rec.Time.Before(since):
+		case daemonAuditOp != "" && rec.Op != daemonAuditOp:
+		case daemonAuditBy != "" && rec.RequestedBy != daemonAuditBy:
+		case daemonAuditTarget != "" && !strings.Contains(rec.Target, daemonAuditTarget):
+		default:
+			matched = append(matched, rec)
+		}
+	}
+	if daemonAuditLimit > 0 && len(matched) > daemonAuditLimit {
+		matched = matched[len(matched)-daemonAuditLimit:]
+	}
+
+	if daemonAuditJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matched)
+	}
+
+	if len(matched) == 0 {
+		fmt.Println(style.Dim.Render("No matching audit records."))
+		return nil
+	}
+	for _, rec := range matched {
+		icon := style.Success.Render("✓")
+		if rec.Outcome != daemon.AuditOutcomeOK {
+			icon = style.Error.Render("✗")
+		}
+		fmt.Printf("%s %s %-12s %s %s\n", icon,
+			style.Dim.Render(fmt.Sprintf("#%d %s", rec.Seq, rec.Time.Local().Format("2006-01-02 15:04:05"))),
+			rec.Op, rec.Target, style.Dim.Render("by "+rec.RequestedBy))
+		if rec.Reason != "" {
+			fmt.Printf("    %s %s\n", style.Dim.Render("reason:"), rec.Reason)
+		}
+		if context := restartContext(rec.TaskBead, rec.Urgency); context != "" {
+			fmt.Printf("    %s\n", style.Dim.Render(context))
+		}
+		for _, check := range rec.Verified {
+			fmt.Printf("    %s %s\n", style.Dim.Render("•"), check)
+		}
+		if rec.Error != "" {
+			fmt.Printf("    %s %s\n", style.Error.Render("error:"), rec.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Daemon replay flags
+var (
+	daemonReplayPass int
+	daemonReplayJSON bool
+	daemonReplayLog  bool
+)
+
+var daemonReplayCmd = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "Replay recorded lifecycle passes to see how the daemon decided",
+	Long: `Re-run the lifecycle passes of a daemon recording (made with
+'gt daemon start --record FILE') in a sandbox town.
+
+Each pass is replayed as a dry run against the config and state files it
+read, with tmux, mail, and bd answering as they did when it was recorded.
+The report lists what the daemon did in each pass, and the replay's log
+shows why.
+
+Queries the recording has no answer for are listed as missing: the replay
+took a different path than the recorded run, usually because the daemon
+code changed since.
+
+Examples:
+  gt daemon replay daemon.rec             # Replay every pass
+  gt daemon replay daemon.rec --pass 12   # Replay pass 12
+  gt daemon replay daemon.rec --log       # Include the replayed daemon log`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDaemonReplay,
+}
+
+func init() {
+	daemonReplayCmd.Flags().IntVar(&daemonReplayPass, "pass", 0, "Replay only this pass")
+	daemonReplayCmd.Flags().BoolVar(&daemonReplayJSON, "json", false, "Output as JSON")
+	daemonReplayCmd.Flags().BoolVarP(&daemonReplayLog, "log", "v", false, "Print the replayed daemon log")
+	daemonCmd.AddCommand(daemonReplayCmd)
+}
+
+func runDaemonReplay(cmd *cobra.Command, args []string) error {
+	entries, err := daemon.LoadRecording(args[0])
+	if err != nil {
+		return err
+	}
+	report, err := daemon.Replay(entries, daemonReplayPass)
+	if err != nil {
+		return err
+	}
+
+	if daemonReplayJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Passes) == 0 {
+		fmt.Printf("%s No lifecycle passes recorded in %s\n", style.Dim.Render("○"), args[0])
+		return nil
+	}
+	for i, pass := range report.Passes {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s %s\n", style.Bold.Render(fmt.Sprintf("Pass %d", pass.Pass)),
+			style.Dim.Render(pass.At.Local().Format("2006-01-02 15:04:05")))
+		if len(pass.Recorded) == 0 {
+			fmt.Printf("  %s\n", style.Dim.Render("(no actions)"))
+		}
+		for _, action := range pass.Recorded {
+			fmt.Printf("  %s %s\n", style.Success.Render("→"), action)
+		}
+		for _, query := range pass.Missing {
+			fmt.Printf("  %s no recorded answer: %s\n", style.Warning.Render("⚠"), query)
+		}
+		if daemonReplayLog && pass.Log != "" {
+			fmt.Printf("\n  %s\n", style.Bold.Render("Daemon log:"))
+			for _, line := range strings.Split(strings.TrimRight(pass.Log, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var daemonInstallPrint bool
+
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the daemon as a user service that survives reboots",
+	Long: `Install the daemon as a per-user service: a systemd user unit on Linux
+(~/.config/systemd/user/gt-daemon-<town>.service) or a launchd agent on
+macOS (~/Library/LaunchAgents/io.gastown.daemon.<town>.plist).
+
+The service runs 'gt daemon run' from the town root with the current PATH
+and HOME, starts at login, and is restarted if the daemon crashes (a clean
+'gt daemon stop' is not undone). Output before the daemon opens its own
+log goes to daemon/service.log.
+
+A daemon already running outside the service is stopped so the service's
+daemon takes over. Installing again rewrites the service, e.g. after
+moving the gt binary.
+
+On Linux, run 'loginctl enable-linger' once for the service to start at
+boot rather than at your first login.
+
+Examples:
+  gt daemon install           # Install, enable, and start the service
+  gt daemon install --print   # Show the unit/plist without installing
+  gt daemon uninstall         # Stop and remove the service`,
+	RunE: runDaemonInstall,
+}
+
+var daemonUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the daemon's user service",
+	Long: `Stop and disable the daemon's systemd or launchd service and remove its
+definition. The daemon no longer starts at login; start it by hand with
+'gt daemon start'.`,
+	RunE: runDaemonUninstall,
+}
+
+func init() {
+	daemonInstallCmd.Flags().BoolVar(&daemonInstallPrint, "print", false, "Print the service definition instead of installing it")
+	daemonCmd.AddCommand(daemonInstallCmd)
+	daemonCmd.AddCommand(daemonUninstallCmd)
+}
+
+// townServiceSpec returns the daemon service spec of the town at townRoot,
+// run by this gt binary.
+func townServiceSpec(townRoot string) (*daemon.ServiceSpec, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("finding executable: %w", err)
+	}
+	return daemon.NewServiceSpec(townRoot, exe)
+}
+
+func runDaemonInstall(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	spec, err := townServiceSpec(townRoot)
+	if err != nil {
+		return err
+	}
+
+	if daemonInstallPrint {
+		data, err := spec.Render()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	if running, pid, err := daemon.IsRunning(townRoot); err == nil && running {
+		if status, err := daemon.QueryService(spec); err != nil || !status.Active {
+			if err := daemon.StopDaemon(townRoot); err != nil {
+				return fmt.Errorf("stopping running daemon (PID %d): %w", pid, err)
+			}
+			fmt.Printf("%s Stopped daemon (was PID %d) for the service to take over\n", style.Bold.Render("✓"), pid)
+		}
+	}
+
+	path, err := daemon.InstallService(spec)
+	if err != nil {
+		return fmt.Errorf("installing %s service: %w", spec.Manager, err)
+	}
+	fmt.Printf("%s Installed %s service %s\n", style.Bold.Render("✓"), spec.Manager, spec.Name)
+	fmt.Printf("  Definition: %s\n", path)
+	fmt.Printf("  Service log: %s\n", spec.LogFile)
+	if spec.Manager == daemon.ServiceSystemd {
+		fmt.Printf("  %s\n", style.Dim.Render("To start at boot rather than login: loginctl enable-linger"))
+	}
+	return nil
+}
+
+func runDaemonUninstall(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	spec, err := townServiceSpec(townRoot)
+	if err != nil {
+		return err
+	}
+	if err := daemon.UninstallService(spec); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no %s service installed for this town", spec.Manager)
+		}
+		return fmt.Errorf("uninstalling %s service: %w", spec.Manager, err)
+	}
+	fmt.Printf("%s Removed %s service %s\n", style.Bold.Render("✓"), spec.Manager, spec.Name)
+	return nil
+}
+
+// townServiceStatus returns the status of the town's daemon service, or nil
+// if the platform has no supported service manager.
+func townServiceStatus(townRoot string) *daemon.ServiceStatus {
+	spec, err := townServiceSpec(townRoot)
+	if err != nil {
+		return nil
+	}
+	status, err := daemon.QueryService(spec)
+	if err != nil {
+		return nil
+	}
+	return status
+}
+
+// printServiceStatus prints the daemon service line of 'gt daemon status'.
+func printServiceStatus(status *daemon.ServiceStatus) {
+	if status == nil || !status.Installed {
+		return
+	}
+	enabled := "disabled"
+	if status.Enabled {
+		enabled = "enabled"
+	}
+	fmt.Printf("  Service: %s %s (%s, %s)\n", status.Manager, status.Name, enabled, status.State)
+}
+
+// startDaemonService starts the daemon through its installed service and
+// waits for it to come up.
+func startDaemonService(townRoot string) error {
+	spec, err := townServiceSpec(townRoot)
+	if err != nil {
+		return err
+	}
+	if err := daemon.StartService(spec); err != nil {
+		return fmt.Errorf("starting %s service: %w", spec.Manager, err)
+	}
+	for i := 0; i < 20; i++ {
+		if running, pid, err := daemon.IsRunning(townRoot); err == nil && running {
+			fmt.Printf("%s Daemon started by %s service %s (PID %d)\n", style.Bold.Render("✓"), spec.Manager, spec.Name, pid)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("daemon failed to start (check 'gt daemon logs' and %s)", spec.LogFile)
+}
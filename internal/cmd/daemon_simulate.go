@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/testharness"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Daemon simulate flags
+var (
+	daemonSimulateConfig string
+	daemonSimulateJSON   bool
+	daemonSimulateLog    bool
+)
+
+var daemonSimulateCmd = &cobra.Command{
+	Use:   "simulate <script.json>",
+	Short: "Replay lifecycle mail against a daemon config in a sandbox town",
+	Long: `Run a scripted sequence of lifecycle requests against a daemon in a
+temporary sandbox town and report whether its decisions match expectations.
+
+The sandbox has fake tmux sessions and fake agents that write state files,
+so nothing in a real town is touched. Use it to check a daemon.json change
+before applying it.
+
+The config used is, in order: the script's inline "config", --config, the
+current town's mayor/daemon.json, or the defaults.
+
+Script format:
+
+  {
+    "name": "crew cycle restarts the session",
+    "rigs": ["gastown"],
+    "crew": ["gastown/max"],
+    "sessions": {"gt-gastown-crew-max": "running"},
+    "steps": [
+      {"mail": {"from": "gastown/crew/max", "action": "cycle"}},
+      {"process": true},
+      {"expect": {"events": ["kill gt-gastown-crew-max", "new gt-gastown-crew-max"],
+                  "running": ["gt-gastown-crew-max"], "inbox_empty": ["deacon/"]}}
+    ]
+  }
+
+Expectations: events (in order), no_events, running, no_session,
+inbox_empty, mail_sent ({"to", "subject"}), log.
+
+Exits 1 if any expectation fails.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDaemonSimulate,
+}
+
+func init() {
+	daemonSimulateCmd.Flags().StringVar(&daemonSimulateConfig, "config", "", "daemon.json to simulate (default: the current town's)")
+	daemonSimulateCmd.Flags().BoolVar(&daemonSimulateJSON, "json", false, "Output as JSON")
+	daemonSimulateCmd.Flags().BoolVarP(&daemonSimulateLog, "log", "v", false, "Print the daemon log")
+	daemonCmd.AddCommand(daemonSimulateCmd)
+}
+
+func runDaemonSimulate(cmd *cobra.Command, args []string) error {
+	script, err := testharness.LoadScript(args[0])
+	if err != nil {
+		return err
+	}
+
+	var config []byte
+	switch {
+	case daemonSimulateConfig != "":
+		if config, err = os.ReadFile(daemonSimulateConfig); err != nil {
+			return fmt.Errorf("reading config: %w", err)
+		}
+	default:
+		if townRoot, err := workspace.FindFromCwd(); err == nil && townRoot != "" {
+			config, _ = os.ReadFile(daemon.PatrolConfigFile(townRoot))
+		}
+	}
+
+	report, err := testharness.Simulate(script, config)
+	if err != nil {
+		return err
+	}
+
+	if daemonSimulateJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		name := report.Name
+		if name == "" {
+			name = args[0]
+		}
+		fmt.Printf("Simulating %s\n", style.Bold.Render(name))
+		for _, step := range report.Steps {
+			if step.Error != "" {
+				fmt.Printf("  %s %s\n", style.Error.Render("✗"), step.Step)
+				fmt.Printf("      %s\n", step.Error)
+			} else {
+				fmt.Printf("  %s %s\n", style.Success.Render("✓"), step.Step)
+			}
+		}
+		if daemonSimulateLog {
+			fmt.Printf("\n%s\n%s", style.Bold.Render("Daemon log:"), report.Log)
+		}
+		fmt.Println()
+		if report.Failed == 0 {
+			fmt.Printf("%s All %d step(s) passed\n", style.Success.Render("✓"), len(report.Steps))
+		} else {
+			fmt.Printf("%s %d of %d step(s) failed\n", style.Error.Render("✗"), report.Failed, len(report.Steps))
+		}
+	}
+
+	if report.Failed > 0 {
+		return NewSilentExit(1)
+	}
+	return nil
+}
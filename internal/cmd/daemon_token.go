@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Daemon token flags
+var (
+	daemonTokenScopes []string
+	daemonTokenTTL    string
+	daemonTokenJSON   bool
+)
+
+var daemonTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage daemon API tokens",
+	Long: `Manage the tokens that authenticate daemon API calls and lifecycle mail.
+
+Each token belongs to one agent identity and carries scopes:
+
+  read            follow the daemon's event stream
+  lifecycle-self  request lifecycle actions on its own session
+  lifecycle-any   request lifecycle actions on any session
+
+Only a hash of each token is stored; the token itself is printed once,
+when it is minted. Lifecycle mail must carry a token (body "token") when
+lifecycle.require_token is set in mayor/daemon.json; the API's POST
+/lifecycle always requires one.`,
+	RunE: requireSubcommand,
+}
+
+var daemonTokenMintCmd = &cobra.Command{
+	Use:   "mint <identity>",
+	Short: "Create a token for an agent",
+	Long: `Create a token for an agent identity and print it.
+
+Examples:
+  gt daemon token mint mayor --scope lifecycle-any --scope read
+  gt daemon token mint gastown/crew/max --scope lifecycle-self
+  gt daemon token mint dashboard --scope read --ttl 30d`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDaemonTokenMint,
+}
+
+var daemonTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tokens (IDs, identities, scopes)",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonTokenList,
+}
+
+var daemonTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke a token",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDaemonTokenRevoke,
+}
+
+func init() {
+	daemonTokenMintCmd.Flags().StringSliceVar(&daemonTokenScopes, "scope", nil,
+		"Scope to grant (read, lifecycle-self, lifecycle-any); repeatable")
+	daemonTokenMintCmd.Flags().StringVar(&daemonTokenTTL, "ttl", "", "Expire the token after this long (e.g., 24h, 30d)")
+	daemonTokenListCmd.Flags().BoolVar(&daemonTokenJSON, "json", false, "Output as JSON")
+
+	daemonTokenCmd.AddCommand(daemonTokenMintCmd)
+	daemonTokenCmd.AddCommand(daemonTokenListCmd)
+	daemonTokenCmd.AddCommand(daemonTokenRevokeCmd)
+	daemonCmd.AddCommand(daemonTokenCmd)
+}
+
+func runDaemonTokenMint(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	var ttl time.Duration
+	if daemonTokenTTL != "" {
+		if ttl, err = parseDuration(daemonTokenTTL); err != nil {
+			return fmt.Errorf("invalid --ttl: %w", err)
+		}
+	}
+
+	secret, token, err := daemon.MintToken(townRoot, args[0], daemonTokenScopes, ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Minted token %s for %s (%s)\n", style.Success.Render("✓"),
+		token.ID, token.Identity, strings.Join(token.Scopes, ", "))
+	if !token.ExpiresAt.IsZero() {
+		fmt.Printf("  expires %s\n", token.ExpiresAt.Local().Format(time.RFC3339))
+	}
+	fmt.Printf("\n%s\n\n", secret)
+	fmt.Println(style.Dim.Render("This is the only time the token is shown. Store it in the agent's environment."))
+	return nil
+}
+
+func runDaemonTokenList(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	tokens, err := daemon.LoadTokens(townRoot)
+	if err != nil {
+		return err
+	}
+
+	if daemonTokenJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tokens)
+	}
+	if len(tokens) == 0 {
+		fmt.Println(style.Dim.Render("No API tokens."))
+		return nil
+	}
+	now := time.Now()
+	for _, t := range tokens {
+		line := fmt.Sprintf("%s  %-24s %s", style.Bold.Render(t.ID), t.Identity, strings.Join(t.Scopes, ", "))
+		switch {
+		case t.Expired(now):
+			line += style.Dim.Render("  (expired)")
+		case !t.ExpiresAt.IsZero():
+			line += style.Dim.Render("  expires " + t.ExpiresAt.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runDaemonTokenRevoke(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if err := daemon.RevokeToken(townRoot, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("%s Revoked token %s\n", style.Success.Render("✓"), args[0])
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var daemonUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Have the daemon install the latest release and restart in place",
+	Long: `Ask the running daemon to update its binary from the release location
+in mayor/daemon.json and restart in place, without stopping orchestration.
+
+The daemon downloads the release, verifies it against the published
+SHA-256, keeps the old binary as <binary>.prev, and execs the new one with
+the same PID. Pending lifecycle requests stay queued in its inbox and are
+processed by the new version. If the release matches the running binary,
+nothing happens.
+
+  "self_update": {
+    "source": "https://releases.example.com/gt/latest/gt-{os}-{arch}",
+    "check_interval": "6h"
+  }
+
+With check_interval set, the daemon also checks on its own. Watch
+'gt daemon logs' for the outcome.`,
+	RunE: runDaemonUpdate,
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonUpdateCmd)
+}
+
+func runDaemonUpdate(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if !daemon.SelfUpdateConfigured(townRoot) {
+		return fmt.Errorf("self_update is not configured in %s", daemon.PatrolConfigFile(townRoot))
+	}
+
+	running, pid, err := daemon.IsRunning(townRoot)
+	if err != nil {
+		return fmt.Errorf("checking daemon status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("daemon is not running")
+	}
+
+	if err := daemon.RequestUpdate(townRoot, "gt daemon update"); err != nil {
+		return fmt.Errorf("requesting update: %w", err)
+	}
+	if err := daemon.WakeDaemon(pid); err != nil {
+		fmt.Printf("%s Update requested; the daemon will apply it at its next heartbeat\n", style.Bold.Render("✓"))
+		return nil
+	}
+	fmt.Printf("%s Update requested from daemon (PID %d)\n", style.Bold.Render("✓"), pid)
+	fmt.Printf("  %s\n", style.Dim.Render("See 'gt daemon logs' for the result"))
+	return nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Daemon watchdog flags
+var (
+	daemonWatchdogInterval time.Duration
+	daemonWatchdogFailures int
+	daemonWatchdogGrace    time.Duration
+	daemonWatchdogOnce     bool
+	daemonWatchdogJSON     bool
+)
+
+var daemonWatchdogCmd = &cobra.Command{
+	Use:   "watchdog",
+	Short: "Restart the daemon when its health checks keep failing",
+	Long: `Watch the daemon's health and restart it if it stays unhealthy.
+
+Health comes from the daemon API's /healthz when api.listen is set in
+mayor/daemon.json, otherwise from the daemon state file. The daemon is
+unhealthy when it isn't running, /healthz doesn't answer, or its heartbeat
+loop is stuck or overdue. A degraded daemon (failing mail polls, safe
+mode, a long API queue) is reported but not restarted.
+
+After --failures consecutive failed checks the daemon is stopped (killed if
+it doesn't exit) and started again, then given --grace to come up before
+checks resume.
+
+Run it under a supervisor (systemd, launchd) or in a tmux window. With
+--once it checks a single time and exits 1 when unhealthy, for cron.
+
+Examples:
+  gt daemon watchdog
+  gt daemon watchdog --interval 1m --failures 5
+  gt daemon watchdog --once --json`,
+	Args: cobra.NoArgs,
+	RunE: runDaemonWatchdog,
+}
+
+func init() {
+	daemonWatchdogCmd.Flags().DurationVar(&daemonWatchdogInterval, "interval", 30*time.Second, "Time between health checks")
+	daemonWatchdogCmd.Flags().IntVar(&daemonWatchdogFailures, "failures", 3, "Consecutive failed checks before a restart")
+	daemonWatchdogCmd.Flags().DurationVar(&daemonWatchdogGrace, "grace", 2*time.Minute, "Time a restarted daemon gets before checks resume")
+	daemonWatchdogCmd.Flags().BoolVar(&daemonWatchdogOnce, "once", false, "Check once and exit (1 if unhealthy)")
+	daemonWatchdogCmd.Flags().BoolVar(&daemonWatchdogJSON, "json", false, "With --once, print the health report as JSON")
+	daemonCmd.AddCommand(daemonWatchdogCmd)
+}
+
+func runDaemonWatchdog(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if daemonWatchdogOnce {
+		return runDaemonWatchdogOnce(townRoot)
+	}
+	if daemonWatchdogFailures < 1 {
+		return fmt.Errorf("--failures must be at least 1")
+	}
+
+	fmt.Printf("%s Watching daemon health every %v (restart after %d failed checks)\n",
+		style.Bold.Render("●"), daemonWatchdogInterval, daemonWatchdogFailures)
+	failures := 0
+	for {
+		report, err := daemon.ProbeHealth(townRoot)
+		if problem := watchdogProblem(report, err); problem != "" {
+			failures++
+			watchdogLog(style.Warning.Render("⚠"), "unhealthy (%d/%d): %s", failures, daemonWatchdogFailures, problem)
+		} else {
+			if failures > 0 {
+				watchdogLog(style.Success.Render("✓"), "healthy again")
+			}
+			failures = 0
+		}
+
+		if failures >= daemonWatchdogFailures {
+			watchdogLog(style.Bold.Render("↻"), "restarting daemon")
+			if running, _, _ := daemon.IsRunning(townRoot); running {
+				if err := daemon.StopDaemon(townRoot); err != nil {
+					watchdogLog(style.Error.Render("✗"), "stopping daemon: %v", err)
+				}
+			}
+			if err := runDaemonStart(cmd, nil); err != nil {
+				watchdogLog(style.Error.Render("✗"), "starting daemon: %v", err)
+			}
+			failures = 0
+			time.Sleep(daemonWatchdogGrace)
+			continue
+		}
+		time.Sleep(daemonWatchdogInterval)
+	}
+}
+
+func runDaemonWatchdogOnce(townRoot string) error {
+	report, err := daemon.ProbeHealth(townRoot)
+	problem := watchdogProblem(report, err)
+
+	if daemonWatchdogJSON {
+		if report == nil {
+			report = &daemon.HealthReport{Status: daemon.HealthUnhealthy, Problems: []string{problem}}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else if report != nil {
+		printHealthReport(report)
+	} else {
+		fmt.Printf("%s Daemon unhealthy: %s\n", style.Error.Render("✗"), problem)
+	}
+
+	if problem != "" {
+		return NewSilentExit(1)
+	}
+	return nil
+}
+
+// watchdogProblem returns why a probe failed, or "" if the daemon doesn't
+// need a restart.
+func watchdogProblem(report *daemon.HealthReport, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if report.Status == daemon.HealthUnhealthy {
+		return strings.Join(report.Problems, "; ")
+	}
+	return ""
+}
+
+func watchdogLog(icon, format string, args ...any) {
+	fmt.Printf("%s %s %s\n", style.Dim.Render(time.Now().Format("15:04:05")), icon, fmt.Sprintf(format, args...))
+}
+
+func printHealthReport(r *daemon.HealthReport) {
+	icon := style.Success.Render("✓")
+	switch r.Status {
+	case daemon.HealthDegraded:
+		icon = style.Warning.Render("⚠")
+	case daemon.HealthUnhealthy:
+		icon = style.Error.Render("✗")
+	}
+	fmt.Printf("%s Daemon %s (PID %d, from %s)\n", icon, r.Status, r.PID, r.Source)
+	for _, p := range r.Problems {
+		fmt.Printf("  - %s\n", p)
+	}
+	if !r.LastHeartbeatAt.IsZero() {
+		line := fmt.Sprintf("  Last heartbeat: %s (#%d)", r.LastHeartbeatAt.Local().Format("15:04:05"), r.HeartbeatCount)
+		if r.LastHeartbeatDuration > 0 {
+			line += fmt.Sprintf(", took %v", r.LastHeartbeatDuration.Round(time.Millisecond))
+		}
+		fmt.Println(line)
+	}
+	if !r.HeartbeatRunningSince.IsZero() {
+		fmt.Printf("  Heartbeat running since %s\n", r.HeartbeatRunningSince.Local().Format("15:04:05"))
+	}
+	if !r.LastMailSuccessAt.IsZero() {
+		fmt.Printf("  Last mail poll: %s (%d unread)\n", r.LastMailSuccessAt.Local().Format("15:04:05"), r.UnreadLifecycleMail)
+	}
+	if r.Source == "api" {
+		fmt.Printf("  Goroutines: %d, API queue: %d, event subscribers: %d\n", r.Goroutines, r.APIQueue, r.EventSubscribers)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/doctor"
+	"github.com/steveyegge/gastown/internal/style"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
 
@@ -14,6 +15,7 @@ var (
 	doctorVerbose         bool
 	doctorRig             string
 	doctorRestartSessions bool
+	doctorInteractive     bool
 )
 
 var doctorCmd = &cobra.Command{
@@ -42,6 +44,13 @@ Infrastructure checks:
   - daemon                   Check if daemon is running (fixable)
   - repo-fingerprint         Check database has valid repo fingerprint (fixable)
   - boot-health              Check Boot watchdog health (vet mode)
+  - required-binaries        Check tmux, git, bd, and gt are on PATH
+
+Town health checks:
+  - state-files              Check daemon and town state files parse (fixable)
+  - agent-session-consistency Check agent beads agree with tmux (fixable)
+  - mail-backlog             Detect a deacon inbox that isn't being drained
+  - workspace-clean          Detect agent workspaces with uncommitted changes
 
 Cleanup checks (fixable):
   - orphan-sessions          Detect orphaned tmux sessions
@@ -81,6 +90,7 @@ Patrol checks:
   - patrol-roles-have-prompts Verify role prompts exist
 
 Use --fix to attempt automatic fixes for issues that support it.
+Use --interactive (-i) to confirm each fix before it is applied.
 Use --rig to check a specific rig instead of the entire workspace.`,
 	RunE: runDoctor,
 }
@@ -90,6 +100,7 @@ func init() {
 	doctorCmd.Flags().BoolVarP(&doctorVerbose, "verbose", "v", false, "Show detailed output")
 	doctorCmd.Flags().StringVar(&doctorRig, "rig", "", "Check specific rig only")
 	doctorCmd.Flags().BoolVar(&doctorRestartSessions, "restart-sessions", false, "Restart patrol sessions when fixing stale settings (use with --fix)")
+	doctorCmd.Flags().BoolVarP(&doctorInteractive, "interactive", "i", false, "Confirm each fix before applying it (implies --fix)")
 	rootCmd.AddCommand(doctorCmd)
 }
 
@@ -125,6 +136,11 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	d.Register(doctor.NewDaemonCheck())
 	d.Register(doctor.NewRepoFingerprintCheck())
 	d.Register(doctor.NewBootHealthCheck())
+	d.Register(doctor.NewRequiredBinariesCheck())
+	d.Register(doctor.NewStateFilesCheck())
+	d.Register(doctor.NewAgentSessionConsistencyCheck())
+	d.Register(doctor.NewAgentStateSyncCheck())
+	d.Register(doctor.NewMailBacklogCheck())
 	d.Register(doctor.NewBeadsDatabaseCheck())
 	d.Register(doctor.NewCustomTypesCheck())
 	d.Register(doctor.NewRoleLabelCheck())
@@ -181,6 +197,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	d.Register(doctor.NewHookAttachmentValidCheck())
 	d.Register(doctor.NewHookSingletonCheck())
 	d.Register(doctor.NewOrphanedAttachmentsCheck())
+	d.Register(doctor.NewWorkspaceCleanCheck())
 
 	// Rig-specific checks (only when --rig is specified)
 	if doctorRig != "" {
@@ -189,7 +206,9 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 
 	// Run checks
 	var report *doctor.Report
-	if doctorFix {
+	if doctorInteractive {
+		report = d.FixInteractive(ctx, confirmDoctorFix)
+	} else if doctorFix {
 		report = d.Fix(ctx)
 	} else {
 		report = d.Run(ctx)
@@ -205,3 +224,15 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// confirmDoctorFix shows a failed check and asks whether to apply its fix.
+func confirmDoctorFix(check doctor.Check, result *doctor.CheckResult) bool {
+	fmt.Printf("\n%s %s: %s\n", style.WarningPrefix, result.Name, result.Message)
+	for _, detail := range result.Details {
+		fmt.Printf("    %s\n", style.Dim.Render(detail))
+	}
+	if result.FixHint != "" {
+		fmt.Printf("    %s\n", style.Dim.Render(result.FixHint))
+	}
+	return promptYesNo(fmt.Sprintf("Fix %s?", check.Name()))
+}
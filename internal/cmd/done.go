@@ -274,6 +274,15 @@ func runDone(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("branch '%s' has 0 commits ahead of %s; nothing to merge\nMake and commit changes first, or use --status DEFERRED to exit without completing", branch, originDefault)
 		}
 
+		// Enforce witness-published quality gates before the work leaves the worktree
+		gateBase := originDefault
+		if _, err := g.Rev(originDefault); err != nil {
+			gateBase = defaultBranch
+		}
+		if err := enforceQualityGates(townRoot, rigName, g, gateBase, cwd); err != nil {
+			return err
+		}
+
 		// CRITICAL: Push branch BEFORE creating MR bead (hq-6dk53, hq-a4ksk)
 		// The MR bead triggers Refinery to process this branch. If the branch
 		// isn't pushed yet, Refinery finds nothing to merge. The worktree gets
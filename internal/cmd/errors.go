@@ -3,8 +3,35 @@ package cmd
 import (
 	"errors"
 	"fmt"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+// Exit codes for classified daemon errors, so scripts can tell a typo in an
+// agent name from a tmux failure without parsing messages. Other errors exit
+// with ExitFailure.
+const (
+	ExitFailure           = 1
+	ExitUnknownIdentity   = 3
+	ExitStateVerification = 4
+	ExitSessionBackend    = 5
+	ExitStaleRequest      = 6
+	ExitPolicyDenied      = 7
+	ExitTimeout           = 8
+	ExitSmokeTestFailed   = 9
 )
 
+// exitCodes maps daemon error codes to exit codes.
+var exitCodes = map[string]int{
+	daemon.ErrorCodeUnknownIdentity:         ExitUnknownIdentity,
+	daemon.ErrorCodeStateVerificationFailed: ExitStateVerification,
+	daemon.ErrorCodeSessionBackend:          ExitSessionBackend,
+	daemon.ErrorCodeStaleRequest:            ExitStaleRequest,
+	daemon.ErrorCodePolicyDenied:            ExitPolicyDenied,
+	daemon.ErrorCodeTimeout:                 ExitTimeout,
+	daemon.ErrorCodeSmokeTestFailed:         ExitSmokeTestFailed,
+}
+
 // SilentExitError signals that the command should exit with a specific code
 // without printing an error message. This is used for scripting purposes
 // where exit codes convey status (e.g., "no mail" = exit 1).
@@ -34,3 +61,19 @@ func IsSilentExit(err error) (int, bool) {
 	}
 	return 0, false
 }
+
+// ExitCode returns the process exit code for a command error: the code of a
+// SilentExitError, the class code of a daemon error, else ExitFailure.
+// Returns 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if code, ok := IsSilentExit(err); ok {
+		return code
+	}
+	if code, ok := exitCodes[daemon.ErrorCode(err)]; ok {
+		return code
+	}
+	return ExitFailure
+}
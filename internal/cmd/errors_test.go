@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+
+	"github.com/steveyegge/gastown/internal/daemon"
 )
 
 func TestSilentExitError_Error(t *testing.T) {
@@ -90,3 +92,26 @@ func TestSilentExitError_Is(t *testing.T) {
 		t.Errorf("errors.As extracted code = %d, want 1", target.Code)
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"plain error", errors.New("boom"), ExitFailure},
+		{"silent exit", NewSilentExit(2), 2},
+		{"unknown identity", fmt.Errorf("refreshing: %w", daemon.ErrUnknownIdentity), ExitUnknownIdentity},
+		{"state verification", daemon.ErrStateVerificationFailed, ExitStateVerification},
+		{"session backend", fmt.Errorf("a: %w", fmt.Errorf("b: %w", daemon.ErrSessionBackend)), ExitSessionBackend},
+		{"stale request", daemon.ErrStaleRequest, ExitStaleRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -306,7 +306,7 @@ func runFeedInWindow(workDir string, bdArgs []string) error {
 // windowExists checks if a window with the given name exists in the session.
 // Note: getCurrentTmuxSession is defined in handoff.go
 func windowExists(_ *tmux.Tmux, session, windowName string) (bool, error) { // t unused: direct exec for simplicity
-	cmd := exec.Command("tmux", "list-windows", "-t", session, "-F", "#{window_name}")
+	cmd := tmux.Command("list-windows", "-t", session, "-F", "#{window_name}")
 	out, err := cmd.Output()
 	if err != nil {
 		return false, err
@@ -323,12 +323,12 @@ func windowExists(_ *tmux.Tmux, session, windowName string) (bool, error) { // t
 // createWindow creates a new tmux window with the given name and command.
 func createWindow(_ *tmux.Tmux, session, windowName, workDir, command string) error { // t unused: direct exec for simplicity
 	args := []string{"new-window", "-t", session, "-n", windowName, "-c", workDir, command}
-	cmd := exec.Command("tmux", args...)
+	cmd := tmux.Command(args...)
 	return cmd.Run()
 }
 
 // selectWindow switches to the specified window.
 func selectWindow(_ *tmux.Tmux, target string) error { // t unused: direct exec for simplicity
-	cmd := exec.Command("tmux", "select-window", "-t", target)
+	cmd := tmux.Command("select-window", "-t", target)
 	return cmd.Run()
 }
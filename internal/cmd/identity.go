@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Identity command flags
+var (
+	identityExplainJSON    bool
+	identityExplainVerbose bool
+)
+
+var identityCmd = &cobra.Command{
+	Use:     "identity",
+	GroupID: GroupDiag,
+	Short:   "Debug agent identity mappings",
+	RunE:    requireSubcommand,
+}
+
+var identityExplainCmd = &cobra.Command{
+	Use:   "explain <identity>",
+	Short: "Show how an identity resolves to sessions, beads, and paths",
+	Long: `Show every name the daemon derives from an identity string, and flag
+anything ambiguous about the mapping.
+
+Accepts the daemon's dash form (gastown-crew-max), the BD_ACTOR slash form
+(gastown/crew/max), or the tmux session name of a managed agent
+(gt-gastown-crew-max).
+
+Shows:
+  - Parsed role, rig, and agent name
+  - Role bead consulted and whether its config, a legacy bead, or
+    built-in defaults decided the patterns
+  - tmux session name (and whether it is running)
+  - Work directory and agent state file
+  - Agent bead ID and BD_ACTOR
+
+Examples:
+  gt identity explain gastown-witness
+  gt identity explain gastown/crew/max --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runIdentityExplain,
+}
+
+func init() {
+	identityExplainCmd.Flags().BoolVar(&identityExplainJSON, "json", false, "Output as JSON")
+	identityExplainCmd.Flags().BoolVarP(&identityExplainVerbose, "verbose", "v", false, "Show role bead lookup warnings")
+	identityCmd.AddCommand(identityExplainCmd)
+	rootCmd.AddCommand(identityCmd)
+}
+
+func runIdentityExplain(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	var out io.Writer = io.Discard
+	if identityExplainVerbose {
+		out = os.Stderr
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(out, "  ", 0))
+	ex, err := ctl.Explain(args[0])
+	if err != nil {
+		return err
+	}
+
+	if identityExplainJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ex)
+	}
+
+	fmt.Printf("%s %s\n\n", style.Bold.Render("Identity:"), ex.Identity)
+	row := func(label, value string) {
+		if value == "" {
+			value = style.Dim.Render("(none)")
+		}
+		fmt.Printf("  %-14s %s\n", label, value)
+	}
+	row("Role:", ex.Role)
+	row("Rig:", ex.Rig)
+	row("Agent:", ex.Agent)
+	row("Role bead:", fmt.Sprintf("%s %s", ex.RoleBeadID, style.Dim.Render("("+ex.RoleConfigSource+")")))
+	if ex.SessionPattern != "" {
+		row("  session:", ex.SessionPattern)
+	}
+	if ex.WorkDirPattern != "" {
+		row("  work_dir:", ex.WorkDirPattern)
+	}
+	if ex.WorkDirTemplate != "" {
+		row("  template:", ex.WorkDirTemplate+" "+style.Dim.Render("(daemon.json)"))
+	}
+
+	session := ex.SessionName
+	if session != "" {
+		if ex.SessionRunning {
+			session += " " + style.Success.Render("(running)")
+		} else {
+			session += " " + style.Dim.Render("(not running)")
+		}
+	}
+	row("Session:", session)
+	workDir := ex.WorkDir
+	if workDir != "" && !ex.WorkDirExists {
+		workDir += " " + style.Warning.Render("(missing)")
+	}
+	row("Work dir:", workDir)
+	if ex.WorkDirError != "" && ex.WorkDirExists {
+		row("", style.Warning.Render(ex.WorkDirError))
+	}
+	row("State file:", ex.StateFile)
+	row("State backend:", ex.StateBackend)
+	row("Agent bead:", ex.AgentBeadID)
+	row("BD_ACTOR:", ex.BDActor)
+
+	if len(ex.Ambiguities) > 0 {
+		fmt.Printf("\n%s\n", style.Bold.Render("Ambiguities:"))
+		for _, a := range ex.Ambiguities {
+			fmt.Printf("  %s %s\n", style.Warning.Render("⚠"), a)
+		}
+	}
+	return nil
+}
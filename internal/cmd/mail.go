@@ -18,10 +18,26 @@ var (
 	mailNotify        bool
 	mailSendSelf      bool
 	mailCC            []string // CC recipients
+	mailAttach        []string // Files to attach
+	mailTemplate      string   // Structured message template
 	mailInboxJSON     bool
 	mailReadJSON      bool
 	mailInboxUnread   bool
 	mailInboxIdentity string
+	mailInboxWatch    bool
+	mailInboxJSONL    bool
+	mailInboxInterval int
+	mailInboxExisting bool
+	mailInboxBcasts   bool
+	mailInboxBcast    string
+	mailInboxFrom     string
+	mailInboxType     string
+	mailInboxPriority string
+	mailInboxSince    string
+	mailInboxUntil    string
+	mailInboxSubject  []string
+	mailInboxLimit    int
+	mailInboxOffset   int
 	mailCheckInject   bool
 	mailCheckJSON     bool
 	mailCheckIdentity string
@@ -96,6 +112,12 @@ Addresses:
   <rig>/<polecat>  - Send to a specific polecat
   <rig>/           - Broadcast to a rig
   list:<name>      - Send to a mailing list (fans out to all members)
+  all-agents       - Broadcast to every agent in the town
+  witness/*        - Broadcast to a role in every rig (also crew/*, polecats/*)
+  <rig>/crew/*     - Broadcast to a role in one rig ('*' matches one segment)
+
+Broadcasts fan out into one message per agent. Every copy shares a
+broadcast ID, shown on send; filter for it with 'gt mail inbox --broadcast'.
 
 Mailing lists are defined in ~/gt/config/messaging.json and allow
 sending to multiple recipients at once. Each recipient gets their
@@ -116,6 +138,15 @@ Priority levels:
 
 Use --urgent as shortcut for --priority 0.
 
+Templates (--template) build the subject and a JSON body for standard
+messages from their fields, given as flags (e.g. --action for lifecycle).
+A template may supply a default address, type, and priority. List them with
+'gt mail templates'.
+
+Attachments (--attach) are stored once in the town's mail store
+(.beads/mail-attachments/, content-addressed) and listed by 'gt mail read'.
+Bodies over 32KB are stored the same way as body.txt, with a preview inline.
+
 Examples:
   gt mail send greenplace/Toast -s "Status check" -m "How's that bug fix going?"
   gt mail send mayor/ -s "Work complete" -m "Finished gt-abc"
@@ -125,7 +156,11 @@ Examples:
   gt mail send mayor/ -s "Re: Status" -m "Done" --reply-to msg-abc123
   gt mail send --self -s "Handoff" -m "Context for next session"
   gt mail send greenplace/Toast -s "Update" -m "Progress report" --cc overseer
-  gt mail send list:oncall -s "Alert" -m "System down"`,
+  gt mail send list:oncall -s "Alert" -m "System down"
+  gt mail send witness/* -s "Freeze" -m "No merges until 14:00"
+  gt mail send deacon/ -s "Cycle failed" -m "See log" --attach /tmp/cycle.log
+  gt mail send --template lifecycle --action cycle
+  gt mail send --template escalation --severity high --summary "Tests hang on CI"`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runMailSend,
 }
@@ -142,7 +177,30 @@ Examples:
   gt mail inbox                       # Current context (auto-detected)
   gt mail inbox mayor/                # Mayor's inbox
   gt mail inbox greenplace/Toast         # Polecat's inbox
-  gt mail inbox --identity greenplace/Toast  # Explicit polecat identity`,
+  gt mail inbox --identity greenplace/Toast  # Explicit polecat identity
+  gt mail inbox --broadcasts          # Only messages sent to a group
+  gt mail inbox --broadcast all-agents  # Only town-wide broadcasts
+
+Filters narrow the listing and combine with each other:
+
+  gt mail inbox --from mayor/ --unread       # Unread mail from the mayor
+  gt mail inbox --from 'gastown/*' --since 2h  # From a rig's agents, last 2 hours
+  gt mail inbox --type task --priority high  # High or urgent tasks
+  gt mail inbox --subject LIFECYCLE: --subject ESCALATE:  # Subject prefixes
+  gt mail inbox --until 2026-01-31           # Sent before a date (or RFC3339 time)
+
+--limit and --offset page through the (newest first) result. With --json,
+paging prints an object with the page and the total match count instead of
+a bare message array:
+
+  gt mail inbox --limit 20 --offset 20 --json
+
+Watch mode streams new messages as they arrive instead of listing once.
+With --json-lines each message is printed as a single JSON object per
+line, so scripts can react to mail without polling:
+
+  gt mail inbox --watch                     # Follow the current inbox
+  gt mail inbox mayor/ --watch --json-lines # Machine-readable stream`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runMailInbox,
 }
@@ -186,6 +244,9 @@ var mailArchiveCmd = &cobra.Command{
 
 Removes the messages from your inbox by closing them in beads.
 
+Old mail is removed town-wide by the daemon's retention rules; see
+'gt mail archive apply' and 'gt mail archive search'.
+
 Examples:
   gt mail archive hq-abc123
   gt mail archive hq-abc123 hq-def456 hq-ghi789`,
@@ -382,6 +443,13 @@ Examples:
 	RunE: runMailSearch,
 }
 
+var mailTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List message templates for 'gt mail send --template'",
+	Args:  cobra.NoArgs,
+	RunE:  runMailTemplates,
+}
+
 var mailAnnouncesCmd = &cobra.Command{
 	Use:   "announces [channel]",
 	Short: "List or read announce channels",
@@ -416,7 +484,7 @@ Examples:
 
 func init() {
 	// Send flags
-	mailSendCmd.Flags().StringVarP(&mailSubject, "subject", "s", "", "Message subject (required)")
+	mailSendCmd.Flags().StringVarP(&mailSubject, "subject", "s", "", "Message subject (required unless --template)")
 	mailSendCmd.Flags().StringVarP(&mailBody, "message", "m", "", "Message body")
 	mailSendCmd.Flags().IntVar(&mailPriority, "priority", 2, "Message priority (0=urgent, 1=high, 2=normal, 3=low, 4=backlog)")
 	mailSendCmd.Flags().BoolVar(&mailUrgent, "urgent", false, "Set priority=0 (urgent)")
@@ -428,13 +496,29 @@ func init() {
 	mailSendCmd.Flags().BoolVar(&mailPermanent, "permanent", false, "Send as permanent (not ephemeral, synced to remote)")
 	mailSendCmd.Flags().BoolVar(&mailSendSelf, "self", false, "Send to self (auto-detect from cwd)")
 	mailSendCmd.Flags().StringArrayVar(&mailCC, "cc", nil, "CC recipients (can be used multiple times)")
-	_ = mailSendCmd.MarkFlagRequired("subject") // cobra flags: error only at runtime if missing
+	mailSendCmd.Flags().StringArrayVar(&mailAttach, "attach", nil, "Attach a file, e.g. a log or diff (can be used multiple times)")
+	mailSendCmd.Flags().StringVar(&mailTemplate, "template", "", "Build subject and body from a message template (see 'gt mail templates')")
+	registerMailTemplateFlags(mailSendCmd)
 
 	// Inbox flags
 	mailInboxCmd.Flags().BoolVar(&mailInboxJSON, "json", false, "Output as JSON")
 	mailInboxCmd.Flags().BoolVarP(&mailInboxUnread, "unread", "u", false, "Show only unread messages")
 	mailInboxCmd.Flags().StringVar(&mailInboxIdentity, "identity", "", "Explicit identity for inbox (e.g., greenplace/Toast)")
 	mailInboxCmd.Flags().StringVar(&mailInboxIdentity, "address", "", "Alias for --identity")
+	mailInboxCmd.Flags().BoolVarP(&mailInboxWatch, "watch", "w", false, "Stream new messages as they arrive")
+	mailInboxCmd.Flags().BoolVar(&mailInboxJSONL, "json-lines", false, "With --watch: print one JSON object per message")
+	mailInboxCmd.Flags().IntVarP(&mailInboxInterval, "interval", "n", 2, "With --watch: store check interval in seconds")
+	mailInboxCmd.Flags().BoolVar(&mailInboxExisting, "existing", false, "With --watch: emit messages already in the inbox first")
+	mailInboxCmd.Flags().BoolVar(&mailInboxBcasts, "broadcasts", false, "Show only messages sent to a group of agents")
+	mailInboxCmd.Flags().StringVar(&mailInboxBcast, "broadcast", "", "Show only messages from this broadcast (ID or group address, e.g. witness/*)")
+	mailInboxCmd.Flags().StringVar(&mailInboxFrom, "from", "", "Show only messages from this sender (address or glob, e.g. gastown/*)")
+	mailInboxCmd.Flags().StringVar(&mailInboxType, "type", "", "Show only messages of this type (task, scavenge, notification, reply)")
+	mailInboxCmd.Flags().StringVar(&mailInboxPriority, "priority", "", "Show only messages of at least this priority (low, normal, high, urgent)")
+	mailInboxCmd.Flags().StringVar(&mailInboxSince, "since", "", "Show only messages sent since a duration ago (e.g. 2h, 7d) or a date/RFC3339 time")
+	mailInboxCmd.Flags().StringVar(&mailInboxUntil, "until", "", "Show only messages sent until a duration ago or a date/RFC3339 time")
+	mailInboxCmd.Flags().StringArrayVar(&mailInboxSubject, "subject", nil, "Show only messages whose subject starts with this prefix, ignoring case (can be used multiple times)")
+	mailInboxCmd.Flags().IntVar(&mailInboxLimit, "limit", 0, "Show at most this many messages (0 for all)")
+	mailInboxCmd.Flags().IntVar(&mailInboxOffset, "offset", 0, "Skip this many matching messages (with --limit, for paging)")
 
 	// Read flags
 	mailReadCmd.Flags().BoolVar(&mailReadJSON, "json", false, "Output as JSON")
@@ -483,6 +567,7 @@ func init() {
 	mailCmd.AddCommand(mailClearCmd)
 	mailCmd.AddCommand(mailSearchCmd)
 	mailCmd.AddCommand(mailAnnouncesCmd)
+	mailCmd.AddCommand(mailTemplatesCmd)
 
 	rootCmd.AddCommand(mailCmd)
 }
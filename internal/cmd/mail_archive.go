@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Mail archive flags
+var (
+	mailArchiveFrom   string
+	mailArchiveTo     string
+	mailArchiveMonth  string
+	mailArchiveJSON   bool
+	mailArchiveDryRun bool
+)
+
+var mailArchiveSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search mail removed by the retention rules",
+	Long: `Search the town's mail archive.
+
+Messages archived by the mail_retention rules in mayor/daemon.json are kept
+in compressed monthly files under mail-archive/. The query matches the
+subject, body, sender, or recipient, ignoring case; without one, every
+archived message matching the filters is listed.
+
+Examples:
+  gt mail archive search deploy
+  gt mail archive search --from mayor/ --month 2026-03
+  gt mail archive search --to gastown/witness --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMailArchiveSearch,
+}
+
+var mailArchiveApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the mail retention rules now",
+	Long: `Apply the mail_retention rules from mayor/daemon.json immediately.
+
+The daemon applies them on its own once per interval (default 24h). The
+first rule a message matches decides whether it is archived or deleted;
+pinned messages and messages matching no rule are kept.
+
+  "mail_retention": {
+    "rules": [
+      {"older_than": "7d", "types": ["notification"], "status": "read", "action": "delete"},
+      {"older_than": "30d", "status": "read"},
+      {"older_than": "90d"}
+    ]
+  }
+
+Examples:
+  gt mail archive apply --dry-run
+  gt mail archive apply`,
+	Args: cobra.NoArgs,
+	RunE: runMailArchiveApply,
+}
+
+func init() {
+	mailArchiveSearchCmd.Flags().StringVar(&mailArchiveFrom, "from", "", "Filter by sender address")
+	mailArchiveSearchCmd.Flags().StringVar(&mailArchiveTo, "to", "", "Filter by recipient address")
+	mailArchiveSearchCmd.Flags().StringVar(&mailArchiveMonth, "month", "", "Only search this month (YYYY-MM)")
+	mailArchiveSearchCmd.Flags().BoolVar(&mailArchiveJSON, "json", false, "Output as JSON")
+
+	mailArchiveApplyCmd.Flags().BoolVar(&mailArchiveDryRun, "dry-run", false, "Report what would be archived or deleted")
+	mailArchiveApplyCmd.Flags().BoolVar(&mailArchiveJSON, "json", false, "Output as JSON")
+
+	mailArchiveCmd.AddCommand(mailArchiveSearchCmd)
+	mailArchiveCmd.AddCommand(mailArchiveApplyCmd)
+}
+
+func runMailArchiveSearch(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	q := mail.ArchiveQuery{From: mailArchiveFrom, To: mailArchiveTo}
+	if len(args) > 0 {
+		q.Text = args[0]
+	}
+	if mailArchiveMonth != "" {
+		month, err := time.Parse("2006-01", mailArchiveMonth)
+		if err != nil {
+			return fmt.Errorf("invalid --month %q (want YYYY-MM)", mailArchiveMonth)
+		}
+		q.Since, q.Until = month, month.AddDate(0, 1, 0)
+	}
+
+	messages, err := mail.SearchArchive(daemon.MailArchiveDir(townRoot), q)
+	if err != nil {
+		return fmt.Errorf("searching archive: %w", err)
+	}
+
+	if mailArchiveJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(messages)
+	}
+
+	fmt.Printf("%s Archived mail: %d message(s)\n\n", style.Bold.Render("🔍"), len(messages))
+	if len(messages) == 0 {
+		fmt.Printf("  %s\n", style.Dim.Render("(no matches)"))
+		return nil
+	}
+	for _, msg := range messages {
+		typeMarker := ""
+		if msg.Type != "" && msg.Type != mail.TypeNotification {
+			typeMarker = fmt.Sprintf(" [%s]", msg.Type)
+		}
+		fmt.Printf("  %s%s\n", msg.Subject, typeMarker)
+		fmt.Printf("    %s from %s to %s\n", style.Dim.Render(msg.ID), msg.From, msg.To)
+		fmt.Printf("    %s\n", style.Dim.Render(msg.Timestamp.Format("2006-01-02 15:04")))
+	}
+	return nil
+}
+
+func runMailArchiveApply(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	report, err := ctl.ApplyMailRetention(mailArchiveDryRun)
+	if err != nil {
+		return err
+	}
+
+	if mailArchiveJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	verb := "Applied"
+	if mailArchiveDryRun {
+		verb = "Would apply"
+	}
+	fmt.Printf("%s %s retention to %d message(s)\n", style.Bold.Render("✓"), verb, report.Scanned)
+	fmt.Printf("  Archived: %d\n", report.Archived)
+	fmt.Printf("  Deleted:  %d\n", report.Deleted)
+	fmt.Printf("  Kept:     %d\n", report.Kept)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/mail"
@@ -30,6 +31,10 @@ func getMailbox(address string) (*mail.Mailbox, error) {
 }
 
 func runMailInbox(cmd *cobra.Command, args []string) error {
+	if mailInboxJSONL && !mailInboxWatch {
+		return errors.New("--json-lines requires --watch")
+	}
+
 	// Determine which inbox to check (priority: --identity flag, positional arg, auto-detect)
 	address := ""
 	if mailInboxIdentity != "" {
@@ -45,22 +50,32 @@ func runMailInbox(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Get messages
-	var messages []*mail.Message
-	if mailInboxUnread {
-		messages, err = mailbox.ListUnread()
-	} else {
-		messages, err = mailbox.List()
+	filter, err := inboxFilterFromFlags(time.Now())
+	if err != nil {
+		return err
 	}
+
+	if mailInboxWatch {
+		return runMailInboxWatch(mailbox, address, filter)
+	}
+
+	// Get messages
+	messages, err := mailbox.List()
 	if err != nil {
 		return fmt.Errorf("listing messages: %w", err)
 	}
+	messages = filterBroadcasts(messages, mailInboxBcasts, mailInboxBcast)
+	page := mail.FilterMessages(messages, filter)
+	paged := filter.Offset > 0 || filter.Limit > 0
 
 	// JSON output
 	if mailInboxJSON {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		return enc.Encode(messages)
+		if paged {
+			return enc.Encode(page)
+		}
+		return enc.Encode(page.Messages)
 	}
 
 	// Human-readable output
@@ -68,12 +83,12 @@ func runMailInbox(cmd *cobra.Command, args []string) error {
 	fmt.Printf("%s Inbox: %s (%d messages, %d unread)\n\n",
 		style.Bold.Render("📬"), address, total, unread)
 
-	if len(messages) == 0 {
+	if len(page.Messages) == 0 {
 		fmt.Printf("  %s\n", style.Dim.Render("(no messages)"))
 		return nil
 	}
 
-	for _, msg := range messages {
+	for _, msg := range page.Messages {
 		readMarker := "●"
 		if msg.Read {
 			readMarker = "○"
@@ -90,6 +105,9 @@ func runMailInbox(cmd *cobra.Command, args []string) error {
 		if msg.Wisp {
 			wispMarker = " " + style.Dim.Render("(wisp)")
 		}
+		if msg.BroadcastID != "" {
+			wispMarker += " " + style.Dim.Render("(to "+msg.BroadcastTo+")")
+		}
 
 		fmt.Printf("  %s %s%s%s%s\n", readMarker, msg.Subject, typeMarker, priorityMarker, wispMarker)
 		fmt.Printf("    %s from %s\n",
@@ -99,9 +117,88 @@ func runMailInbox(cmd *cobra.Command, args []string) error {
 			style.Dim.Render(msg.Timestamp.Format("2006-01-02 15:04")))
 	}
 
+	if paged {
+		footer := fmt.Sprintf("Showing %d-%d of %d", page.Offset+1, page.Offset+len(page.Messages), page.Total)
+		if next := page.NextOffset(); next > 0 {
+			footer += fmt.Sprintf(" (next page: --offset %d)", next)
+		}
+		fmt.Printf("\n  %s\n", style.Dim.Render(footer))
+	}
+
 	return nil
 }
 
+// inboxFilterFromFlags builds the inbox filter from the inbox flags.
+func inboxFilterFromFlags(now time.Time) (mail.InboxFilter, error) {
+	filter := mail.InboxFilter{
+		From:            mailInboxFrom,
+		UnreadOnly:      mailInboxUnread,
+		SubjectPrefixes: mailInboxSubject,
+		Offset:          mailInboxOffset,
+		Limit:           mailInboxLimit,
+	}
+	if mailInboxOffset < 0 || mailInboxLimit < 0 {
+		return filter, errors.New("--offset and --limit can't be negative")
+	}
+	if mailInboxType != "" {
+		filter.Type = mail.MessageType(mailInboxType)
+		if mail.ParseMessageType(mailInboxType) != filter.Type {
+			return filter, fmt.Errorf("invalid --type %q (want task, scavenge, notification, or reply)", mailInboxType)
+		}
+	}
+	if mailInboxPriority != "" {
+		filter.Priority = mail.Priority(mailInboxPriority)
+		if mail.ParsePriority(mailInboxPriority) != filter.Priority {
+			return filter, fmt.Errorf("invalid --priority %q (want low, normal, high, or urgent)", mailInboxPriority)
+		}
+	}
+	var err error
+	if filter.Since, err = parseInboxTime(mailInboxSince, now); err != nil {
+		return filter, fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseInboxTime(mailInboxUntil, now); err != nil {
+		return filter, fmt.Errorf("invalid --until: %w", err)
+	}
+	return filter, nil
+}
+
+// parseInboxTime parses a --since/--until value: a duration before now
+// ("2h", "7d"), an RFC3339 time, or a date. Empty is the zero time.
+func parseInboxTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration (2h, 7d), date (2006-01-02), or RFC3339 time", s)
+}
+
+// filterBroadcasts keeps only broadcast messages (onlyBroadcasts), or only
+// those of one broadcast, by ID or group address.
+func filterBroadcasts(messages []*mail.Message, onlyBroadcasts bool, broadcast string) []*mail.Message {
+	if !onlyBroadcasts && broadcast == "" {
+		return messages
+	}
+	var kept []*mail.Message
+	for _, msg := range messages {
+		if broadcast != "" && !msg.InBroadcast(broadcast) {
+			continue
+		}
+		if msg.BroadcastID == "" {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
 func runMailRead(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return errors.New("msgID argument required")
@@ -162,6 +259,13 @@ func runMailRead(cmd *cobra.Command, args []string) error {
 		fmt.Printf("\n%s\n", msg.Body)
 	}
 
+	if len(msg.Attachments) > 0 {
+		fmt.Printf("\n%s\n", style.Bold.Render("Attachments:"))
+		for _, a := range msg.Attachments {
+			fmt.Printf("  %s  %s\n", a.Name, style.Dim.Render(mailbox.AttachmentPath(a)))
+		}
+	}
+
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -45,7 +46,30 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 		}
 	} else if len(args) > 0 {
 		to = args[0]
-	} else {
+	}
+
+	var tmpl *mail.Template
+	if mailTemplate != "" {
+		t, err := mail.LookupTemplate(mailTemplate)
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("subject") || cmd.Flags().Changed("message") {
+			return fmt.Errorf("--template builds the subject and body; don't combine it with --subject or --message")
+		}
+		tmpl = t
+		if to == "" {
+			to = tmpl.DefaultTo
+		}
+	} else if mailSubject == "" {
+		return fmt.Errorf("required flag(s) \"subject\" not set (or use --template)")
+	}
+	templateValues, err := mailTemplateValues(cmd, tmpl)
+	if err != nil {
+		return err
+	}
+
+	if to == "" {
 		return fmt.Errorf("address required (or use --self)")
 	}
 
@@ -58,26 +82,35 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 	// Determine sender
 	from := detectSender()
 
-	// Create message
+	// Create message, from a template if one was given
 	msg := &mail.Message{
 		From:    from,
 		To:      to,
 		Subject: mailSubject,
 		Body:    mailBody,
 	}
+	if tmpl != nil {
+		if msg, err = tmpl.Render(from, templateValues); err != nil {
+			return err
+		}
+		msg.To = to
+		mailSubject = msg.Subject
+	}
 
-	// Set priority (--urgent overrides --priority)
+	// Set priority (--urgent overrides --priority, which overrides the template)
 	if mailUrgent {
 		msg.Priority = mail.PriorityUrgent
-	} else {
+	} else if tmpl == nil || cmd.Flags().Changed("priority") {
 		msg.Priority = mail.PriorityFromInt(mailPriority)
 	}
 	if mailNotify && msg.Priority == mail.PriorityNormal {
 		msg.Priority = mail.PriorityHigh
 	}
 
-	// Set message type
-	msg.Type = mail.ParseMessageType(mailType)
+	// Set message type (--type overrides the template)
+	if tmpl == nil || cmd.Flags().Changed("type") {
+		msg.Type = mail.ParseMessageType(mailType)
+	}
 
 	// Set pinned flag
 	msg.Pinned = mailPinned
@@ -88,6 +121,15 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 	// Set CC recipients
 	msg.CC = mailCC
 
+	// Store attachments once; every recipient's copy references the same files
+	for _, path := range mailAttach {
+		a, err := mail.NewRouter(workDir).AttachFile(path)
+		if err != nil {
+			return err
+		}
+		msg.Attachments = append(msg.Attachments, a)
+	}
+
 	// Handle reply-to: auto-set type to reply and look up thread
 	if mailReplyTo != "" {
 		msg.ReplyTo = mailReplyTo
@@ -128,6 +170,11 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// A send to a group of agents is one broadcast across all the copies
+	if mail.IsBroadcastAddress(to) || len(recipients) > 1 {
+		msg.MarkBroadcast(to)
+	}
+
 	// Route based on recipient type
 	router := mail.NewRouter(workDir)
 	var recipientAddrs []string
@@ -171,6 +218,9 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 	if len(recipientAddrs) > 1 || (len(recipientAddrs) == 1 && recipientAddrs[0] != to) {
 		fmt.Printf("  Recipients: %s\n", strings.Join(recipientAddrs, ", "))
 	}
+	if msg.BroadcastID != "" {
+		fmt.Printf("  Broadcast: %s\n", msg.BroadcastID)
+	}
 
 	if len(msg.CC) > 0 {
 		fmt.Printf("  CC: %s\n", strings.Join(msg.CC, ", "))
@@ -178,10 +228,82 @@ func runMailSend(cmd *cobra.Command, args []string) error {
 	if msg.Type != mail.TypeNotification {
 		fmt.Printf("  Type: %s\n", msg.Type)
 	}
+	for _, a := range msg.Attachments {
+		fmt.Printf("  Attachment: %s (%d bytes)\n", a.Name, a.Size)
+	}
 
 	return nil
 }
 
+// runMailTemplates lists the message templates and their fields.
+func runMailTemplates(cmd *cobra.Command, args []string) error {
+	for _, t := range mail.Templates() {
+		fmt.Printf("%s  %s\n", style.Bold.Render(t.Name), t.Description)
+		if t.DefaultTo != "" {
+			fmt.Printf("  %s\n", style.Dim.Render("default address: "+t.DefaultTo))
+		}
+		for _, f := range t.Fields {
+			flag := "--" + f.Name
+			if f.Required {
+				flag += " (required)"
+			}
+			desc := f.Description
+			if len(f.Choices) > 0 {
+				desc += ": " + strings.Join(f.Choices, ", ")
+			}
+			fmt.Printf("  %-28s %s\n", flag, desc)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// registerMailTemplateFlags adds a --<field> flag to cmd for every field of
+// every message template. Fields shared by several templates share a flag.
+func registerMailTemplateFlags(cmd *cobra.Command) {
+	for _, t := range mail.Templates() {
+		for _, f := range t.Fields {
+			if cmd.Flags().Lookup(f.Name) != nil {
+				continue
+			}
+			usage := "Template field: " + f.Description
+			if len(f.Choices) > 0 {
+				usage += " (" + strings.Join(f.Choices, ", ") + ")"
+			}
+			if f.Bool {
+				cmd.Flags().Bool(f.Name, false, usage)
+			} else {
+				cmd.Flags().String(f.Name, "", usage)
+			}
+		}
+	}
+}
+
+// mailTemplateValues collects the template field flags that were set and
+// checks they belong to tmpl (nil when no template was chosen).
+func mailTemplateValues(cmd *cobra.Command, tmpl *mail.Template) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, t := range mail.Templates() {
+		for _, f := range t.Fields {
+			flag := cmd.Flags().Lookup(f.Name)
+			if flag == nil || !flag.Changed {
+				continue
+			}
+			if _, seen := values[f.Name]; seen {
+				continue
+			}
+			if tmpl == nil {
+				return nil, fmt.Errorf("--%s is a template field; use it with --template", f.Name)
+			}
+			if !slices.ContainsFunc(tmpl.Fields, func(tf mail.TemplateField) bool { return tf.Name == f.Name }) {
+				return nil, fmt.Errorf("--%s is not a field of template %s", f.Name, tmpl.Name)
+			}
+			values[f.Name] = flag.Value.String()
+		}
+	}
+	return values, nil
+}
+
 // generateThreadID creates a random thread ID for new message threads.
 func generateThreadID() string {
 	b := make([]byte, 6)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/config"
@@ -296,3 +297,29 @@ func TestAnnounceMessageParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestParseInboxTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2h", now.Add(-2 * time.Hour), false},
+		{"7d", now.Add(-7 * 24 * time.Hour), false},
+		{"2026-03-01T08:00:00Z", time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), false},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseInboxTime(tt.in, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInboxTime(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseInboxTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// runMailInboxWatch streams new messages for address until interrupted.
+func runMailInboxWatch(mailbox *mail.Mailbox, address string, filter mail.InboxFilter) error {
+	if mailInboxJSON {
+		return errors.New("--json and --watch cannot be used together (use --json-lines)")
+	}
+	if filter.Limit > 0 || filter.Offset > 0 {
+		return errors.New("--limit and --offset cannot be used with --watch")
+	}
+	if mailInboxInterval <= 0 {
+		return fmt.Errorf("interval must be positive, got %d", mailInboxInterval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := mail.WatchOptions{
+		Interval:        time.Duration(mailInboxInterval) * time.Second,
+		IncludeExisting: mailInboxExisting,
+		UnreadOnly:      mailInboxUnread,
+	}
+
+	// With a mail transport configured, messages are pushed as they are
+	// sent; the store is still polled every interval
+	if townRoot, err := workspace.FindFromCwd(); err == nil && townRoot != "" {
+		if transport, err := mail.TransportForTown(townRoot, os.Getenv); err != nil {
+			fmt.Fprintf(os.Stderr, "%s mail transport unavailable, polling only: %v\n", style.Warning.Render("⚠"), err)
+		} else if transport != nil {
+			defer transport.Close()
+			opts.Transport = transport
+		}
+	}
+
+	// --broadcasts, --broadcast, and the other filters apply to the stream too
+	skip := func(msg *mail.Message) bool {
+		return !filter.Matches(msg) ||
+			len(filterBroadcasts([]*mail.Message{msg}, mailInboxBcasts, mailInboxBcast)) == 0
+	}
+
+	if mailInboxJSONL {
+		enc := json.NewEncoder(os.Stdout)
+		return mailbox.Watch(ctx, opts, func(msg *mail.Message) error {
+			if skip(msg) {
+				return nil
+			}
+			return enc.Encode(msg)
+		})
+	}
+
+	fmt.Printf("%s Watching %s (every %ds, Ctrl+C to stop)\n\n",
+		style.Bold.Render("📬"), address, mailInboxInterval)
+	return mailbox.Watch(ctx, opts, func(msg *mail.Message) error {
+		if !skip(msg) {
+			printWatchedMessage(msg)
+		}
+		return nil
+	})
+}
+
+// printWatchedMessage prints a single-line summary of a streamed message.
+func printWatchedMessage(msg *mail.Message) {
+	typeMarker := ""
+	if msg.Type != "" && msg.Type != mail.TypeNotification {
+		typeMarker = fmt.Sprintf(" [%s]", msg.Type)
+	}
+	priorityMarker := ""
+	if msg.Priority == mail.PriorityHigh || msg.Priority == mail.PriorityUrgent {
+		priorityMarker = " " + style.Bold.Render("!")
+	}
+	fmt.Printf("  %s %s %s%s%s %s\n",
+		style.Dim.Render(msg.Timestamp.Format("15:04:05")),
+		msg.From,
+		msg.Subject, typeMarker, priorityMarker,
+		style.Dim.Render("("+msg.ID+")"))
+}
@@ -2,9 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
 	"sort"
 	"strings"
+
+	"github.com/steveyegge/gastown/internal/tmux"
 )
 
 // cyclePolecatSession switches to the next or previous polecat session in the same rig.
@@ -71,7 +72,7 @@ func cyclePolecatSession(direction int, sessionOverride string) error {
 	targetSession := sessions[targetIdx]
 
 	// Switch to target session
-	cmd := exec.Command("tmux", "switch-client", "-t", targetSession)
+	cmd := tmux.Command("switch-client", "-t", targetSession)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("switching to %s: %w", targetSession, err)
 	}
@@ -134,7 +135,7 @@ func parsePolecatSessionName(sessionName string) (rigName, polecatName string, o
 // Uses tmux list-sessions to find sessions matching gt-<rig>-<name> pattern,
 // excluding crew, witness, and refinery sessions.
 func findRigPolecatSessions(rigName string) ([]string, error) { //nolint:unparam // error return kept for future use
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}")
+	cmd := tmux.Command("list-sessions", "-F", "#{session_name}")
 	out, err := cmd.Output()
 	if err != nil {
 		// No tmux server or no sessions
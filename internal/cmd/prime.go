@@ -194,9 +194,11 @@ func runPrime(cmd *cobra.Command, args []string) error {
 		ensureBeadsRedirect(ctx)
 	}
 
-	// Emit session_start event for seance discovery
+	// Emit session_start event for seance discovery, and report that the
+	// agent is up
 	if !primeDryRun {
 		emitSessionEvent(ctx)
+		reportAgentStarted(townRoot)
 	}
 
 	// Output session metadata for seance discovery
@@ -212,6 +214,9 @@ func runPrime(cmd *cobra.Command, args []string) error {
 	// Output handoff content if present
 	outputHandoffContent(ctx)
 
+	// Tell the agent if the daemon cycled its previous session
+	outputLastKillContext(ctx)
+
 	// Output attachment status (for autonomous work detection)
 	outputAttachmentStatus(ctx)
 
@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/steveyegge/gastown/internal/daemon"
 	"github.com/steveyegge/gastown/internal/events"
 	"github.com/steveyegge/gastown/internal/runtime"
 	"github.com/steveyegge/gastown/internal/workspace"
@@ -175,6 +178,18 @@ func emitSessionEvent(ctx RoleContext) {
 	_ = events.LogFeed(events.TypeSessionStart, actor, payload)
 }
 
+// reportAgentStarted stamps last_heartbeat for the session's agent, so
+// waiters such as 'gt rig up --wait' see that the agent itself came up.
+// Sessions without agent env (GT_ROLE etc.) are skipped.
+func reportAgentStarted(townRoot string) {
+	identity := daemon.IdentityFromEnv(os.Getenv)
+	if identity == "" {
+		return
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	_ = ctl.Heartbeat(identity, time.Now())
+}
+
 // outputSessionMetadata prints a structured metadata line for seance discovery.
 // Format: [GAS TOWN] role:<role> pid:<pid> session:<session_id>
 // This enables gt seance to discover sessions from gt prime output.
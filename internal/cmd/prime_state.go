@@ -10,6 +10,7 @@ import (
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/checkpoint"
 	"github.com/steveyegge/gastown/internal/constants"
+	"github.com/steveyegge/gastown/internal/daemon"
 )
 
 // SessionState represents the detected session state for observability.
@@ -114,3 +115,24 @@ func checkHandoffMarkerDryRun(workDir string) {
 	// Output the warning but don't remove marker
 	outputHandoffWarning(prevSession)
 }
+
+// outputLastKillContext reports a recent daemon kill recorded in the agent's
+// state, so a restarted agent knows it was cycled and by whom rather than
+// assuming it crashed.
+func outputLastKillContext(ctx RoleContext) {
+	rec, err := daemon.ReadKillRecord(ctx.TownRoot, daemon.IdentityFromEnv(os.Getenv), ctx.WorkDir)
+	if err != nil || rec == nil {
+		explain(true, "Lifecycle: no daemon kill recorded in state file")
+		return
+	}
+
+	age := time.Since(rec.At)
+	if age > 24*time.Hour {
+		explain(true, "Lifecycle: last daemon kill is older than 24h, not shown")
+		return
+	}
+
+	explain(true, "Lifecycle: daemon kill recorded in state file")
+	fmt.Printf("\n[lifecycle] Previous session was ended by the daemon %s ago (action: %s, requested by: %s)\n",
+		age.Round(time.Minute), rec.Action, rec.By)
+}
@@ -91,8 +91,14 @@ var refineryQueueCmd = &cobra.Command{
 	Short: "Show merge queue",
 	Long: `Show the merge queue for a rig.
 
-Lists all pending merge requests waiting to be processed.
-If rig is not specified, infers it from the current directory.`,
+Lists all pending merge requests waiting to be processed, in the order
+they will be merged. Use the subcommands to reorder the queue or work it.
+If rig is not specified, infers it from the current directory.
+
+Examples:
+  gt refinery queue
+  gt refinery queue top gt-abc123
+  gt refinery queue process`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runRefineryQueue,
 }
@@ -472,6 +478,9 @@ func runRefineryQueue(cmd *cobra.Command, args []string) error {
 		if item.MR.IssueID != "" {
 			issueInfo = fmt.Sprintf(" (%s)", item.MR.IssueID)
 		}
+		if item.Pinned {
+			status += " " + style.Dim.Render("[pinned]")
+		}
 
 		fmt.Printf("%s %s %s/%s%s %s\n",
 			prefix,
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/refinery"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Refinery queue subcommand flags
+var (
+	refineryQueueProcessMax  int
+	refineryQueueProcessJSON bool
+)
+
+var refineryQueueMoveCmd = &cobra.Command{
+	Use:   "move <mr-id> <position> [rig]",
+	Short: "Move an MR to a position in the merge queue",
+	Long: `Move a pending merge request to a position in the merge queue.
+
+The MR and every MR ahead of it are pinned in that order: they stay at the
+head of the queue, ahead of MRs that arrive later with a higher score. Use
+'gt refinery queue reset' to return to score order.
+
+Examples:
+  gt refinery queue move gt-abc123 1
+  gt refinery queue move gt-abc123 3 greenplace`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runRefineryQueueMove,
+}
+
+var refineryQueueTopCmd = &cobra.Command{
+	Use:   "top <mr-id> [rig]",
+	Short: "Move an MR to the head of the merge queue",
+	Long: `Move a pending merge request to the head of the merge queue.
+
+Same as 'gt refinery queue move <mr-id> 1'.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runRefineryQueueTop,
+}
+
+var refineryQueueResetCmd = &cobra.Command{
+	Use:   "reset [rig]",
+	Short: "Unpin all MRs, returning the queue to score order",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runRefineryQueueReset,
+}
+
+var refineryQueueProcessCmd = &cobra.Command{
+	Use:   "process [rig]",
+	Short: "Merge ready MRs one at a time",
+	Long: `Work the merge queue: take the MR at the head, sync the refinery
+workspace, merge it (with the rig's merge_queue checks), and close or
+reopen its bead. Repeats until the queue is empty or --max MRs are done.
+
+Merges are serialized per rig: if the daemon or another 'process' is
+already working the queue, this exits with an error.
+
+Examples:
+  gt refinery queue process
+  gt refinery queue process greenplace --max 1`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRefineryQueueProcess,
+}
+
+func init() {
+	refineryQueueProcessCmd.Flags().IntVar(&refineryQueueProcessMax, "max", 0, "Stop after this many MRs (0: until the queue is empty)")
+	refineryQueueProcessCmd.Flags().BoolVar(&refineryQueueProcessJSON, "json", false, "Output results as JSON")
+
+	refineryQueueCmd.AddCommand(refineryQueueMoveCmd)
+	refineryQueueCmd.AddCommand(refineryQueueTopCmd)
+	refineryQueueCmd.AddCommand(refineryQueueResetCmd)
+	refineryQueueCmd.AddCommand(refineryQueueProcessCmd)
+}
+
+func runRefineryQueueMove(cmd *cobra.Command, args []string) error {
+	position, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid position %q: %w", args[1], err)
+	}
+	rigName := ""
+	if len(args) > 2 {
+		rigName = args[2]
+	}
+	return moveQueuedMR(args[0], position, rigName)
+}
+
+func runRefineryQueueTop(cmd *cobra.Command, args []string) error {
+	rigName := ""
+	if len(args) > 1 {
+		rigName = args[1]
+	}
+	return moveQueuedMR(args[0], 1, rigName)
+}
+
+func moveQueuedMR(id string, position int, rigName string) error {
+	mgr, _, rigName, err := getRefineryManager(rigName)
+	if err != nil {
+		return err
+	}
+	queue, err := mgr.MoveMR(id, position)
+	if err != nil {
+		return err
+	}
+	for _, item := range queue {
+		if item.MR.ID == id {
+			position = item.Position
+		}
+	}
+	fmt.Printf("%s Moved %s to position %d in the %s merge queue\n", style.Bold.Render("✓"), id, position, rigName)
+	return nil
+}
+
+func runRefineryQueueReset(cmd *cobra.Command, args []string) error {
+	rigName := ""
+	if len(args) > 0 {
+		rigName = args[0]
+	}
+	mgr, _, rigName, err := getRefineryManager(rigName)
+	if err != nil {
+		return err
+	}
+	if err := mgr.ResetQueueOrder(); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s merge queue back in score order\n", style.Bold.Render("✓"), rigName)
+	return nil
+}
+
+func runRefineryQueueProcess(cmd *cobra.Command, args []string) error {
+	rigName := ""
+	if len(args) > 0 {
+		rigName = args[0]
+	}
+	_, r, rigName, err := getRefineryManager(rigName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := refinery.NewQueueRunner(r)
+	runner.WorkerID = rigName + "/refinery"
+	if refineryQueueProcessJSON {
+		runner.SetOutput(os.Stderr)
+	}
+	results, err := runner.Run(ctx, refineryQueueProcessMax)
+	if errors.Is(err, refinery.ErrQueueBusy) {
+		return fmt.Errorf("%s: %w", rigName, err)
+	}
+
+	if refineryQueueProcessJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(results); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+
+	if len(results) == 0 && err == nil {
+		fmt.Printf("  %s\n", style.Dim.Render("(no ready MRs)"))
+		return nil
+	}
+	merged := 0
+	for _, res := range results {
+		if res.Result.Success {
+			merged++
+		}
+	}
+	fmt.Printf("\n%s Merged %d of %d MR(s) from the %s merge queue\n", style.Bold.Render("📋"), merged, len(results), rigName)
+	for _, res := range results {
+		if !res.Result.Success {
+			fmt.Printf("  %s %s: %s\n", style.Dim.Render("✗"), res.MR.ID, res.Result.Error)
+		}
+	}
+	return err
+}
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/remote"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var (
+	remoteAddTown     string
+	remoteAddGT       string
+	remoteListJSON    bool
+	remoteLifeDryRun  bool
+	remoteAttachForce bool
+)
+
+var remoteCmd = &cobra.Command{
+	Use:     "remote",
+	GroupID: GroupServices,
+	Short:   "Manage towns on other machines over SSH",
+	Long: `Manage Gas Town towns running on other machines (e.g. cloud VMs) from
+your laptop.
+
+A remote is a name for an SSH destination - a Host entry from ~/.ssh/config
+or user@host - plus the town root on that machine. Commands ssh in, change
+to the town root, and run the remote gt, so the remote daemon is reached
+through its normal lifecycle mail and state files. Put ports, keys, and jump
+hosts in ~/.ssh/config.
+
+Remotes are stored in ~/.config/gastown/remotes.json.
+
+Examples:
+  gt remote add prod gt-prod --town /srv/gt
+  gt remote status prod
+  gt remote lifecycle prod restart gastown-witness
+  gt remote attach prod gastown/crew/max
+  gt remote run prod -- mail inbox`,
+	RunE: requireSubcommand,
+}
+
+var remoteAddCmd = &cobra.Command{
+	Use:   "add <name> <ssh-host>",
+	Short: "Add or update a remote town",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runRemoteAdd,
+}
+
+var remoteRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a remote town",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runRemoteRemove,
+}
+
+var remoteListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List remote towns",
+	Args:    cobra.NoArgs,
+	RunE:    runRemoteList,
+}
+
+var remoteStatusCmd = &cobra.Command{
+	Use:   "status <name> [gt status flags...]",
+	Short: "Show town and daemon status of a remote town",
+	Long: `Run 'gt status' and 'gt daemon status' in a remote town.
+
+Extra arguments are passed to 'gt status', e.g. 'gt remote status prod --json'.`,
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	RunE:               runRemoteStatus,
+}
+
+var remoteRunCmd = &cobra.Command{
+	Use:   "run <name> -- <gt args...>",
+	Short: "Run any gt command in a remote town",
+	Long: `Run a gt command in a remote town. Arguments after -- are passed to the
+remote gt unchanged.
+
+Examples:
+  gt remote run prod -- mail inbox --identity deacon/
+  gt remote run prod -- daemon status --json`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runRemoteRun,
+}
+
+var remoteLifecycleCmd = &cobra.Command{
+	Use:   "lifecycle <name> <action> <target>...",
+	Short: "Send a lifecycle request to a remote town's daemon",
+	Long: `Ask a remote daemon to cycle, restart, shutdown, or refresh agents.
+
+The request is mailed to the remote deacon inbox as a batch LIFECYCLE
+request sent as mayor/, the same path a local mayor uses, so it gets the
+daemon's usual validation, throttling, and audit record.
+
+Targets are daemon identities (gastown-witness, gastown-crew-max).
+
+Examples:
+  gt remote lifecycle prod restart gastown-witness
+  gt remote lifecycle prod cycle gastown-crew-max gastown-crew-joe --dry-run`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: runRemoteLifecycle,
+}
+
+var remoteAttachCmd = &cobra.Command{
+	Use:   "attach <name> <agent>",
+	Short: "Attach to an agent's session in a remote town",
+	Long: `Attach your terminal to an agent's tmux session in a remote town.
+
+Runs 'gt agents attach' on the remote machine over an interactive SSH
+session, so the usual pending-lifecycle safety checks apply. Detach with
+the tmux prefix + d as usual; the SSH session ends with it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRemoteAttach,
+}
+
+func init() {
+	remoteAddCmd.Flags().StringVar(&remoteAddTown, "town", remote.DefaultTown, "Town root on the remote machine")
+	remoteAddCmd.Flags().StringVar(&remoteAddGT, "gt", "", "Path to gt on the remote machine (default: gt on PATH)")
+	remoteListCmd.Flags().BoolVar(&remoteListJSON, "json", false, "Output as JSON")
+	remoteLifecycleCmd.Flags().BoolVar(&remoteLifeDryRun, "dry-run", false, "Have the daemon validate and log the actions without executing them")
+	remoteAttachCmd.Flags().BoolVarP(&remoteAttachForce, "force", "f", false, "Attach even if a lifecycle action is pending")
+
+	remoteCmd.AddCommand(remoteAddCmd)
+	remoteCmd.AddCommand(remoteRemoveCmd)
+	remoteCmd.AddCommand(remoteListCmd)
+	remoteCmd.AddCommand(remoteStatusCmd)
+	remoteCmd.AddCommand(remoteRunCmd)
+	remoteCmd.AddCommand(remoteLifecycleCmd)
+	remoteCmd.AddCommand(remoteAttachCmd)
+	rootCmd.AddCommand(remoteCmd)
+}
+
+func runRemoteAdd(cmd *cobra.Command, args []string) error {
+	cfg, err := remote.Load()
+	if err != nil {
+		return err
+	}
+	r := &remote.Remote{Name: args[0], Host: args[1], Town: remoteAddTown, GT: remoteAddGT}
+	if err := cfg.Add(r); err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving remotes: %w", err)
+	}
+	fmt.Printf("%s Added remote %s → %s:%s\n", style.Success.Render("✓"), r.Name, r.Host, r.TownRoot())
+	fmt.Printf("  Check it with: %s\n", style.Dim.Render("gt remote status "+r.Name))
+	return nil
+}
+
+func runRemoteRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := remote.Load()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.Get(args[0]); err != nil {
+		return err
+	}
+	delete(cfg.Remotes, args[0])
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("saving remotes: %w", err)
+	}
+	fmt.Printf("%s Removed remote %s\n", style.Success.Render("✓"), args[0])
+	return nil
+}
+
+func runRemoteList(cmd *cobra.Command, args []string) error {
+	cfg, err := remote.Load()
+	if err != nil {
+		return err
+	}
+
+	if remoteListJSON {
+		remotes := make([]*remote.Remote, 0, len(cfg.Remotes))
+		for _, name := range cfg.Names() {
+			remotes = append(remotes, cfg.Remotes[name])
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(remotes)
+	}
+
+	if len(cfg.Remotes) == 0 {
+		fmt.Println("No remotes configured. Add one with: gt remote add <name> <ssh-host>")
+		return nil
+	}
+	for _, name := range cfg.Names() {
+		r := cfg.Remotes[name]
+		fmt.Printf("  %s  %s\n", style.Bold.Render(name), style.Dim.Render(r.Host+":"+r.TownRoot()))
+	}
+	return nil
+}
+
+func runRemoteStatus(cmd *cobra.Command, args []string) error {
+	if args[0] == "-h" || args[0] == "--help" {
+		return cmd.Help()
+	}
+	r, err := loadRemote(args[0])
+	if err != nil {
+		return err
+	}
+	if err := runRemoteGT(r, false, nil, append([]string{"status"}, args[1:]...)...); err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return nil // Caller asked for a specific status format
+	}
+	fmt.Println()
+	return runRemoteGT(r, false, nil, "daemon", "status")
+}
+
+func runRemoteRun(cmd *cobra.Command, args []string) error {
+	r, err := loadRemote(args[0])
+	if err != nil {
+		return err
+	}
+	return runRemoteGT(r, false, nil, args[1:]...)
+}
+
+func runRemoteLifecycle(cmd *cobra.Command, args []string) error {
+	r, err := loadRemote(args[0])
+	if err != nil {
+		return err
+	}
+	action, targets := args[1], args[2:]
+	switch strings.ToLower(action) {
+	case "cycle", "restart", "shutdown", "stop", "refresh":
+	default:
+		return fmt.Errorf("unknown lifecycle action %q (want cycle, restart, shutdown, or refresh)", action)
+	}
+
+	body, err := remoteLifecycleBody(action, targets, remoteLifeDryRun)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("LIFECYCLE: %s %s", action, strings.Join(targets, " "))
+	if err := runRemoteGT(r, false, map[string]string{"GT_ROLE": "mayor"},
+		"mail", "send", "deacon/", "-s", subject, "-m", body); err != nil {
+		return err
+	}
+	fmt.Printf("%s Requested %s of %s on %s\n", style.Success.Render("✓"), action, strings.Join(targets, ", "), r.Name)
+	fmt.Printf("  The daemon acts on its next inbox poll; results are mailed to mayor/.\n")
+	return nil
+}
+
+// remoteLifecycleBody builds a batch lifecycle body for the remote daemon.
+func remoteLifecycleBody(action string, targets []string, dryRun bool) (string, error) {
+	type item struct {
+		Target string `json:"target"`
+		Action string `json:"action"`
+	}
+	body := struct {
+		Actions []item   `json:"actions"`
+		DryRun  bool     `json:"dry_run,omitempty"`
+		Notify  []string `json:"notify,omitempty"`
+	}{DryRun: dryRun, Notify: []string{"mayor"}}
+	for _, target := range targets {
+		body.Actions = append(body.Actions, item{Target: target, Action: strings.ToLower(action)})
+	}
+	data, err := json.Marshal(body)
+	return string(data), err
+}
+
+func runRemoteAttach(cmd *cobra.Command, args []string) error {
+	r, err := loadRemote(args[0])
+	if err != nil {
+		return err
+	}
+	gtArgs := []string{"agents", "attach", args[1]}
+	if remoteAttachForce {
+		gtArgs = append(gtArgs, "--force")
+	}
+	return runRemoteGT(r, true, nil, gtArgs...)
+}
+
+func loadRemote(name string) (*remote.Remote, error) {
+	cfg, err := remote.Load()
+	if err != nil {
+		return nil, err
+	}
+	r, err := cfg.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w (see 'gt remote list')", err)
+	}
+	return r, nil
+}
+
+// runRemoteGT runs gt in the remote town with the terminal attached, passing
+// the remote exit code through.
+func runRemoteGT(r *remote.Remote, tty bool, env map[string]string, args ...string) error {
+	c := r.Command(tty, env, args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return NewSilentExit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("running ssh %s: %w", r.Host, err)
+	}
+	return nil
+}
@@ -320,6 +320,56 @@ func runRigAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("not in a Gas Town workspace: %w", err)
 	}
 
+	fmt.Printf("Creating rig %s...\n", style.Bold.Render(name))
+	fmt.Printf("  Repository: %s\n", gitURL)
+	if rigAddLocalRepo != "" {
+		fmt.Printf("  Local repo: %s\n", rigAddLocalRepo)
+	}
+
+	startTime := time.Now()
+
+	newRig, err := addRigToTown(townRoot, rig.AddRigOptions{
+		Name:          name,
+		GitURL:        gitURL,
+		BeadsPrefix:   rigAddPrefix,
+		LocalRepo:     rigAddLocalRepo,
+		DefaultBranch: rigAddBranch,
+	})
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(startTime)
+
+	// Read default branch from rig config
+	defaultBranch := "main"
+	if rigCfg, err := rig.LoadRigConfig(filepath.Join(townRoot, name)); err == nil && rigCfg.DefaultBranch != "" {
+		defaultBranch = rigCfg.DefaultBranch
+	}
+
+	fmt.Printf("\n%s Rig created in %.1fs\n", style.Success.Render("✓"), elapsed.Seconds())
+	fmt.Printf("\nStructure:\n")
+	fmt.Printf("  %s/\n", name)
+	fmt.Printf("  ├── config.json\n")
+	fmt.Printf("  ├── .repo.git/        (shared bare repo for refinery+polecats)\n")
+	fmt.Printf("  ├── .beads/           (prefix: %s)\n", newRig.Config.Prefix)
+	fmt.Printf("  ├── plugins/          (rig-level plugins)\n")
+	fmt.Printf("  ├── mayor/rig/        (clone: %s)\n", defaultBranch)
+	fmt.Printf("  ├── refinery/rig/     (worktree: %s, sees polecat branches)\n", defaultBranch)
+	fmt.Printf("  ├── crew/             (empty - add crew with 'gt crew add')\n")
+	fmt.Printf("  ├── witness/\n")
+	fmt.Printf("  └── polecats/\n")
+
+	fmt.Printf("\nNext steps:\n")
+	fmt.Printf("  gt crew add <name> --rig %s   # Create your personal workspace\n", name)
+	fmt.Printf("  cd %s/crew/<name>              # Start working\n", filepath.Join(townRoot, name))
+
+	return nil
+}
+
+// addRigToTown adds a rig to the town at townRoot: creates it, registers it
+// in rigs.json, routes its bead prefix, and creates its identity bead.
+func addRigToTown(townRoot string, opts rig.AddRigOptions) (*rig.Rig, error) {
 	// Load rigs config
 	rigsPath := filepath.Join(townRoot, "mayor", "rigs.json")
 	rigsConfig, err := config.LoadRigsConfig(rigsPath)
@@ -335,29 +385,15 @@ func runRigAdd(cmd *cobra.Command, args []string) error {
 	g := git.NewGit(townRoot)
 	mgr := rig.NewManager(townRoot, rigsConfig, g)
 
-	fmt.Printf("Creating rig %s...\n", style.Bold.Render(name))
-	fmt.Printf("  Repository: %s\n", gitURL)
-	if rigAddLocalRepo != "" {
-		fmt.Printf("  Local repo: %s\n", rigAddLocalRepo)
-	}
-
-	startTime := time.Now()
-
 	// Add the rig
-	newRig, err := mgr.AddRig(rig.AddRigOptions{
-		Name:          name,
-		GitURL:        gitURL,
-		BeadsPrefix:   rigAddPrefix,
-		LocalRepo:     rigAddLocalRepo,
-		DefaultBranch: rigAddBranch,
-	})
+	newRig, err := mgr.AddRig(opts)
 	if err != nil {
-		return fmt.Errorf("adding rig: %w", err)
+		return nil, fmt.Errorf("adding rig: %w", err)
 	}
 
 	// Save updated rigs config
 	if err := config.SaveRigsConfig(rigsPath, rigsConfig); err != nil {
-		return fmt.Errorf("saving rigs config: %w", err)
+		return nil, fmt.Errorf("saving rigs config: %w", err)
 	}
 
 	// Add route to town-level routes.jsonl for prefix-based routing.
@@ -368,14 +404,14 @@ func runRigAdd(cmd *cobra.Command, args []string) error {
 	// "<rig>/.beads", while repos with tracked beads have their database at mayor/rig/.beads.
 	var beadsWorkDir string
 	if newRig.Config.Prefix != "" {
-		routePath := name
-		mayorRigBeads := filepath.Join(townRoot, name, "mayor", "rig", ".beads")
+		routePath := opts.Name
+		mayorRigBeads := filepath.Join(townRoot, opts.Name, "mayor", "rig", ".beads")
 		if _, err := os.Stat(mayorRigBeads); err == nil {
 			// Source repo has .beads/ tracked - route to mayor/rig
-			routePath = name + "/mayor/rig"
-			beadsWorkDir = filepath.Join(townRoot, name, "mayor", "rig")
+			routePath = opts.Name + "/mayor/rig"
+			beadsWorkDir = filepath.Join(townRoot, opts.Name, "mayor", "rig")
 		} else {
-			beadsWorkDir = filepath.Join(townRoot, name)
+			beadsWorkDir = filepath.Join(townRoot, opts.Name)
 		}
 		route := beads.Route{
 			Prefix: newRig.Config.Prefix + "-",
@@ -390,13 +426,13 @@ func runRigAdd(cmd *cobra.Command, args []string) error {
 	// Create rig identity bead
 	if newRig.Config.Prefix != "" && beadsWorkDir != "" {
 		bd := beads.New(beadsWorkDir)
-		rigBeadID := beads.RigBeadIDWithPrefix(newRig.Config.Prefix, name)
+		rigBeadID := beads.RigBeadIDWithPrefix(newRig.Config.Prefix, opts.Name)
 		fields := &beads.RigFields{
-			Repo:   gitURL,
+			Repo:   opts.GitURL,
 			Prefix: newRig.Config.Prefix,
 			State:  "active",
 		}
-		if _, err := bd.CreateRigBead(rigBeadID, name, fields); err != nil {
+		if _, err := bd.CreateRigBead(rigBeadID, opts.Name, fields); err != nil {
 			// Non-fatal: rig is functional without the identity bead
 			fmt.Printf("  %s Could not create rig identity bead: %v\n", style.Warning.Render("!"), err)
 		} else {
@@ -404,32 +440,7 @@ func runRigAdd(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	elapsed := time.Since(startTime)
-
-	// Read default branch from rig config
-	defaultBranch := "main"
-	if rigCfg, err := rig.LoadRigConfig(filepath.Join(townRoot, name)); err == nil && rigCfg.DefaultBranch != "" {
-		defaultBranch = rigCfg.DefaultBranch
-	}
-
-	fmt.Printf("\n%s Rig created in %.1fs\n", style.Success.Render("✓"), elapsed.Seconds())
-	fmt.Printf("\nStructure:\n")
-	fmt.Printf("  %s/\n", name)
-	fmt.Printf("  ├── config.json\n")
-	fmt.Printf("  ├── .repo.git/        (shared bare repo for refinery+polecats)\n")
-	fmt.Printf("  ├── .beads/           (prefix: %s)\n", newRig.Config.Prefix)
-	fmt.Printf("  ├── plugins/          (rig-level plugins)\n")
-	fmt.Printf("  ├── mayor/rig/        (clone: %s)\n", defaultBranch)
-	fmt.Printf("  ├── refinery/rig/     (worktree: %s, sees polecat branches)\n", defaultBranch)
-	fmt.Printf("  ├── crew/             (empty - add crew with 'gt crew add')\n")
-	fmt.Printf("  ├── witness/\n")
-	fmt.Printf("  └── polecats/\n")
-
-	fmt.Printf("\nNext steps:\n")
-	fmt.Printf("  gt crew add <name> --rig %s   # Create your personal workspace\n", name)
-	fmt.Printf("  cd %s/crew/<name>              # Start working\n", filepath.Join(townRoot, name))
-
-	return nil
+	return newRig, nil
 }
 
 func runRigList(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/deps"
+	"github.com/steveyegge/gastown/internal/rig"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var rigProvisionPrefix string
+
+var rigProvisionCmd = &cobra.Command{
+	Use:   "provision [dir]",
+	Short: "Turn a git checkout in the town root into a rig",
+	Long: `Provision a git checkout that was cloned straight into the town root
+(~/gt/myproject) as a rig of the same name.
+
+The checkout is moved to daemon/provision/<dir>, where it stays as the
+rig's local reference repo, and the rig is created from its origin (or from
+the checkout itself if it has no origin), as 'gt rig add' would: refinery
+and mayor clones, witness and polecat directories, rig beads, and agent
+beads. The daemon starts the witness and refinery on its next heartbeat.
+
+With no argument, lists the checkouts that could be provisioned. The daemon
+offers these to the mayor, or provisions them itself with
+"rig_provisioning": {"mode": "auto"} in mayor/daemon.json.
+
+Examples:
+  gt rig provision
+  gt rig provision myproject
+  gt rig provision myproject --prefix mp`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRigProvision,
+}
+
+func init() {
+	rigCmd.AddCommand(rigProvisionCmd)
+	rigProvisionCmd.Flags().StringVar(&rigProvisionPrefix, "prefix", "", "Beads issue prefix (default: derived from name)")
+}
+
+func runRigProvision(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	var ignore []string
+	if cfg := daemon.LoadPatrolConfig(townRoot); cfg != nil && cfg.RigProvisioning != nil {
+		ignore = cfg.RigProvisioning.Ignore
+	}
+	found, err := daemon.DetectNewRigs(townRoot, ignore)
+	if err != nil {
+		return fmt.Errorf("looking for new rigs: %w", err)
+	}
+
+	if len(args) == 0 {
+		if len(found) == 0 {
+			fmt.Println(style.Dim.Render("No git checkouts in the town root to provision."))
+			return nil
+		}
+		for _, r := range found {
+			origin := r.GitURL
+			if origin == "" {
+				origin = "no origin"
+			}
+			fmt.Printf("  %s %s\n", style.Bold.Render(r.Dir), style.Dim.Render("("+origin+")"))
+			if r.Problem != "" {
+				fmt.Printf("    %s %s\n", style.Warning.Render("⚠"), r.Problem)
+			}
+		}
+		return nil
+	}
+
+	dir := args[0]
+	var candidate *daemon.NewRig
+	for i := range found {
+		if found[i].Dir == dir {
+			candidate = &found[i]
+		}
+	}
+	if candidate == nil {
+		return fmt.Errorf("%s is not a git checkout in the town root that could be a rig", dir)
+	}
+	if candidate.Problem != "" {
+		return fmt.Errorf("can't provision %s: %s", dir, candidate.Problem)
+	}
+	if err := deps.EnsureBeads(true); err != nil {
+		return fmt.Errorf("beads dependency check failed: %w", err)
+	}
+	return provisionRig(townRoot, candidate)
+}
+
+// provisionRig moves a checkout out of the way and adds a rig in its place
+// that references it. If adding the rig fails, the checkout is put back.
+func provisionRig(townRoot string, candidate *daemon.NewRig) error {
+	checkout := filepath.Join(townRoot, candidate.Dir)
+	staged := daemon.RigProvisionStagingDir(townRoot, candidate.Dir)
+	if _, err := os.Stat(staged); err == nil {
+		return fmt.Errorf("%s already exists; move it away first", staged)
+	}
+	if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(staged), err)
+	}
+	if err := os.Rename(checkout, staged); err != nil {
+		return fmt.Errorf("moving %s aside: %w", checkout, err)
+	}
+
+	gitURL := candidate.GitURL
+	if gitURL == "" {
+		gitURL = staged
+	}
+	fmt.Printf("Provisioning rig %s...\n", style.Bold.Render(candidate.Dir))
+	fmt.Printf("  Repository: %s\n", gitURL)
+	fmt.Printf("  Checkout kept at: %s\n", staged)
+
+	newRig, err := addRigToTown(townRoot, rig.AddRigOptions{
+		Name:        candidate.Dir,
+		GitURL:      gitURL,
+		BeadsPrefix: rigProvisionPrefix,
+		LocalRepo:   staged,
+	})
+	if err != nil {
+		// Whatever is at the checkout's path now is a partial rig
+		if rmErr := os.RemoveAll(checkout); rmErr != nil {
+			return fmt.Errorf("%w (and removing the partial rig failed: %v; the checkout is at %s)", err, rmErr, staged)
+		}
+		if mvErr := os.Rename(staged, checkout); mvErr != nil {
+			return fmt.Errorf("%w (and restoring the checkout failed: %v; it is at %s)", err, mvErr, staged)
+		}
+		return err
+	}
+
+	fmt.Printf("\n%s Rig %s provisioned (prefix %s)\n", style.Success.Render("✓"), candidate.Dir, newRig.Config.Prefix)
+	fmt.Printf("  The daemon starts its witness and refinery on the next heartbeat.\n")
+	return nil
+}
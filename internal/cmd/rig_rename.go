@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/constants"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/rig"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var rigRenameDryRun bool
+
+var rigRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a rig and migrate its sessions, beads, and mail",
+	Long: `Rename a rig and migrate everything that carries its name:
+
+  1. the rig directory, its config.json, and the town's beads routes
+     (git worktrees are repaired after the move)
+  2. the mayor/rigs.json registry entry
+  3. running tmux sessions
+  4. agent beads (new beads are created from the old ones)
+  5. open mail addressed to the rig's agents
+
+The old name is kept as an alias in mayor/rigs.json, so identities,
+addresses, and lifecycle requests that still use it resolve to the new rig.
+
+If a step fails, the steps already done are undone in reverse order.
+Old agent beads are closed only after everything else succeeded.
+
+Examples:
+  gt rig rename gastown gt_core
+  gt rig rename gastown gt_core --dry-run`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRigRename,
+}
+
+func init() {
+	rigRenameCmd.Flags().BoolVar(&rigRenameDryRun, "dry-run", false, "Show what would be migrated without changing anything")
+	rigCmd.AddCommand(rigRenameCmd)
+}
+
+// renameAgent is one agent of a rig being renamed.
+type renameAgent struct {
+	oldIdentity, newIdentity string
+	oldSession, newSession   string
+	oldBead, newBead         string
+}
+
+// renameStep is one undoable step of a rig rename.
+type renameStep struct {
+	name string
+	do   func() error
+	undo func() error
+}
+
+func runRigRename(cmd *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	rigsPath := constants.MayorRigsPath(townRoot)
+	rigsConfig, err := config.LoadRigsConfig(rigsPath)
+	if err != nil {
+		return fmt.Errorf("loading rigs config: %w", err)
+	}
+	if _, ok := rigsConfig.Rigs[oldName]; !ok {
+		return fmt.Errorf("rig '%s' not found", oldName)
+	}
+	if _, ok := rigsConfig.Rigs[newName]; ok {
+		return fmt.Errorf("rig '%s' already exists", newName)
+	}
+
+	// Session names and bead IDs are computed before the rename, while the
+	// old name still resolves to itself.
+	agents := rigRenameAgents(townRoot, oldName, newName)
+
+	if rigRenameDryRun {
+		fmt.Printf("Would rename rig %s to %s:\n", style.Bold.Render(oldName), style.Bold.Render(newName))
+		fmt.Printf("  move %s -> %s\n", filepath.Join(townRoot, oldName), filepath.Join(townRoot, newName))
+		fmt.Printf("  alias %s -> %s in %s\n", oldName, newName, rigsPath)
+		for _, a := range agents {
+			fmt.Printf("  %s -> %s", a.oldIdentity, a.newIdentity)
+			if a.oldSession != a.newSession {
+				fmt.Printf("  session %s -> %s", a.oldSession, a.newSession)
+			}
+			if a.oldBead != "" {
+				fmt.Printf("  bead %s -> %s", a.oldBead, a.newBead)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("  open mail to %s/... -> %s/...\n", oldName, newName)
+		return nil
+	}
+
+	t := tmux.NewTmux()
+	bd := beads.New(townRoot)
+	beadsDir := filepath.Join(townRoot, ".beads")
+	var movedMail []string
+
+	steps := []renameStep{
+		{
+			name: "move rig",
+			do: func() error {
+				mgr := rig.NewManager(townRoot, rigsConfig, git.NewGit(townRoot))
+				warnings, err := mgr.RenameRig(oldName, newName)
+				if err != nil {
+					return err
+				}
+				for _, w := range warnings {
+					fmt.Printf("  %s %s\n", style.Warning.Render("⚠"), w)
+				}
+				return config.SaveRigsConfig(rigsPath, rigsConfig)
+			},
+			undo: func() error {
+				restored, err := config.LoadRigsConfig(rigsPath)
+				if err != nil {
+					return err
+				}
+				if _, ok := restored.Rigs[newName]; !ok {
+					// rigs.json wasn't saved; the registry still has the old name
+					return os.Rename(filepath.Join(townRoot, newName), filepath.Join(townRoot, oldName))
+				}
+				mgr := rig.NewManager(townRoot, restored, git.NewGit(townRoot))
+				if _, err := mgr.RenameRig(newName, oldName); err != nil {
+					return err
+				}
+				delete(restored.Aliases, oldName)
+				delete(restored.Aliases, newName)
+				return config.SaveRigsConfig(rigsPath, restored)
+			},
+		},
+		{
+			name: "rename sessions",
+			do: func() error {
+				for _, a := range agents {
+					if a.oldSession == a.newSession {
+						continue
+					}
+					if running, _ := t.HasSession(a.oldSession); !running {
+						continue
+					}
+					if err := t.RenameSession(a.oldSession, a.newSession); err != nil {
+						return fmt.Errorf("renaming session %s: %w", a.oldSession, err)
+					}
+				}
+				return nil
+			},
+			undo: func() error {
+				for _, a := range agents {
+					if running, _ := t.HasSession(a.newSession); running && a.oldSession != a.newSession {
+						_ = t.RenameSession(a.newSession, a.oldSession)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "migrate agent beads",
+			do: func() error {
+				for _, a := range agents {
+					if a.oldBead == "" || a.oldBead == a.newBead {
+						continue
+					}
+					issue, fields, err := bd.GetAgentBead(a.oldBead)
+					if err != nil {
+						return fmt.Errorf("reading %s: %w", a.oldBead, err)
+					}
+					if issue == nil {
+						continue // Agent never had a bead
+					}
+					fields.Rig = newName
+					title := strings.ReplaceAll(issue.Title, oldName, newName)
+					if _, err := bd.CreateOrReopenAgentBead(a.newBead, title, fields); err != nil {
+						return fmt.Errorf("creating %s: %w", a.newBead, err)
+					}
+				}
+				return nil
+			},
+			undo: func() error {
+				for _, a := range agents {
+					if a.newBead != "" && a.oldBead != a.newBead {
+						_ = bd.CloseAndClearAgentBead(a.newBead, "rig rename rolled back")
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "readdress mail",
+			do: func() error {
+				var err error
+				movedMail, err = mail.ReassignRig(townRoot, beadsDir, oldName, newName)
+				return err
+			},
+			undo: func() error {
+				_, err := mail.ReassignRig(townRoot, beadsDir, newName, oldName)
+				return err
+			},
+		},
+	}
+
+	fmt.Printf("Renaming rig %s to %s...\n", style.Bold.Render(oldName), style.Bold.Render(newName))
+	for i, step := range steps {
+		if err := step.do(); err != nil {
+			fmt.Printf("  %s %s: %v\n", style.Error.Render("✗"), step.name, err)
+			for j := i; j >= 0; j-- {
+				if undoErr := steps[j].undo(); undoErr != nil {
+					fmt.Printf("  %s undo %s: %v\n", style.Warning.Render("⚠"), steps[j].name, undoErr)
+				}
+			}
+			return fmt.Errorf("renaming rig: %s: %w (rolled back)", step.name, err)
+		}
+		fmt.Printf("  %s %s\n", style.Success.Render("✓"), step.name)
+	}
+	if len(movedMail) > 0 {
+		fmt.Printf("  %s readdressed %d open message(s)\n", style.Dim.Render("•"), len(movedMail))
+	}
+
+	// Past the point of no return: retire the old agent beads.
+	for _, a := range agents {
+		if a.oldBead == "" || a.oldBead == a.newBead {
+			continue
+		}
+		if issue, _, err := bd.GetAgentBead(a.oldBead); err != nil || issue == nil {
+			continue
+		}
+		if err := bd.CloseAndClearAgentBead(a.oldBead, "rig renamed to "+newName); err != nil {
+			fmt.Printf("  %s closing %s: %v\n", style.Warning.Render("⚠"), a.oldBead, err)
+		}
+	}
+
+	fmt.Printf("%s Rig %s renamed to %s (old name kept as an alias)\n", style.Success.Render("✓"), oldName, newName)
+	return nil
+}
+
+// rigRenameAgents lists the agents of oldName with their session names and
+// agent bead IDs under both names.
+func rigRenameAgents(townRoot, oldName, newName string) []renameAgent {
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	identities := daemon.RigIdentities(townRoot, oldName)
+	if entries, err := os.ReadDir(filepath.Join(townRoot, oldName, "polecats")); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				identities = append(identities, oldName+"-polecat-"+entry.Name())
+			}
+		}
+	}
+
+	prefix := config.GetRigPrefix(townRoot, oldName)
+	agents := make([]renameAgent, 0, len(identities))
+	for _, identity := range identities {
+		rest := strings.TrimPrefix(identity, oldName+"-")
+		a := renameAgent{
+			oldIdentity: identity,
+			newIdentity: newName + "-" + rest,
+			oldSession:  ctl.SessionName(identity),
+			oldBead:     ctl.AgentBeadID(identity),
+		}
+		// Session names and bead IDs embed the rig name; swap it in place
+		// rather than re-resolving, since the new rig doesn't exist yet.
+		a.newSession = strings.Replace(a.oldSession, "-"+oldName+"-", "-"+newName+"-", 1)
+		a.newBead = renamedAgentBeadID(prefix, newName, rest, a.oldBead)
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+// renamedAgentBeadID returns the agent bead ID of the agent "rest" (witness,
+// crew-max, polecat-toast, ...) in the renamed rig, or "" if oldBead is "".
+func renamedAgentBeadID(prefix, newName, rest, oldBead string) string {
+	if oldBead == "" {
+		return ""
+	}
+	role, name, _ := strings.Cut(rest, "-")
+	switch role {
+	case "witness":
+		return beads.WitnessBeadIDWithPrefix(prefix, newName)
+	case "refinery":
+		return beads.RefineryBeadIDWithPrefix(prefix, newName)
+	case "crew":
+		return beads.CrewBeadIDWithPrefix(prefix, newName, name)
+	case "polecat":
+		return beads.PolecatBeadIDWithPrefix(prefix, newName, name)
+	default:
+		return ""
+	}
+}
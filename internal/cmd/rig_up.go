@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/wisp"
+)
+
+// Rig up/down flags
+var (
+	rigUpWait        bool
+	rigUpWaitTimeout time.Duration
+	rigUpVerbose     bool
+)
+
+var rigUpCmd = &cobra.Command{
+	Use:   "up <rig>",
+	Short: "Start every persistent agent in a rig (witness, refinery, crew)",
+	Long: `Bring up all persistent agents of a rig in dependency order:
+
+  1. witness
+  2. refinery
+  3. every crew member
+
+A rig parked by 'gt rig down' (or 'gt rig park') is unparked first; a
+docked rig stays down. Sessions are started with the same machinery the
+daemon uses for lifecycle restarts, so role config, budgets, and per-agent
+lifecycle hooks all apply. Agents that are already running are left alone.
+Polecats are not started; they are spawned on demand when work is assigned.
+
+With --wait, blocks until every agent it started has reported in itself
+(gt prime stamps last_heartbeat at session start), not just until its
+session exists.
+
+Examples:
+  gt rig up gastown
+  gt rig up gastown --wait --timeout 5m`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRigUp,
+}
+
+var rigDownCmd = &cobra.Command{
+	Use:   "down <rig>",
+	Short: "Stop every persistent agent in a rig (crew, refinery, witness)",
+	Long: `Tear down all persistent agents of a rig in reverse dependency order:
+
+  1. every crew member
+  2. refinery
+  3. witness
+
+The rig is parked first, so the daemon doesn't restart what is being
+stopped; it stays down until 'gt rig up' or 'gt rig unpark'. Each agent's
+pre-shutdown lifecycle hook runs before its session is killed. Polecat
+sessions are not touched; use 'gt rig shutdown' to stop those too.
+
+With --wait, blocks until every agent's session is gone.
+
+Examples:
+  gt rig down gastown
+  gt rig down gastown --wait`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRigDown,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{rigUpCmd, rigDownCmd} {
+		c.Flags().BoolVar(&rigUpWait, "wait", false, "Block until all agents are up (or down)")
+		c.Flags().DurationVar(&rigUpWaitTimeout, "timeout", 2*time.Minute, "How long --wait blocks before giving up")
+		c.Flags().BoolVarP(&rigUpVerbose, "verbose", "v", false, "Show session machinery output (hooks, warnings)")
+		rigCmd.AddCommand(c)
+	}
+}
+
+// newRigSessionController creates a session controller that logs to stderr
+// only in verbose mode.
+func newRigSessionController(townRoot string) *daemon.SessionController {
+	var out io.Writer = io.Discard
+	if rigUpVerbose {
+		out = os.Stderr
+	}
+	return daemon.NewSessionController(townRoot, log.New(out, "  ", 0))
+}
+
+func runRigUp(cmd *cobra.Command, args []string) error {
+	rigName := args[0]
+	townRoot, _, err := getRig(rigName)
+	if err != nil {
+		return err
+	}
+
+	// Undo the park from gt rig down, or the starts below are refused
+	if IsRigParked(townRoot, rigName) {
+		if err := wisp.NewConfig(townRoot, rigName).Unset(RigStatusKey); err != nil {
+			return fmt.Errorf("clearing parked status: %w", err)
+		}
+		fmt.Printf("  %s Unparked %s\n", style.Dim.Render("•"), rigName)
+	}
+
+	ctl := newRigSessionController(townRoot)
+	identities := daemon.RigIdentities(townRoot, rigName)
+
+	fmt.Printf("Bringing up rig %s (%d agents)...\n", style.Bold.Render(rigName), len(identities))
+	startedAt := make(map[string]time.Time)
+	var failed []string
+	for _, identity := range identities {
+		at := time.Now()
+		started, err := ctl.Start(identity)
+		switch {
+		case err != nil:
+			fmt.Printf("  %s %s: %v\n", style.Warning.Render("⚠"), identity, err)
+			failed = append(failed, identity)
+		case started:
+			fmt.Printf("  %s %s started\n", style.Success.Render("✓"), identity)
+			startedAt[identity] = at
+		default:
+			fmt.Printf("  %s %s already running\n", style.Dim.Render("•"), identity)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to start: %s", strings.Join(failed, ", "))
+	}
+	if rigUpWait {
+		return waitForRigAgents(identities, "up", rigUpWaitTimeout, func(identity string) bool {
+			running, err := ctl.IsRunning(identity)
+			if err != nil || !running {
+				return false
+			}
+			// Agents that were already running came up before this run
+			at, started := startedAt[identity]
+			return !started || ctl.ReportedSince(identity, at)
+		})
+	}
+	fmt.Printf("%s Rig %s is up\n", style.Success.Render("✓"), rigName)
+	return nil
+}
+
+func runRigDown(cmd *cobra.Command, args []string) error {
+	rigName := args[0]
+	townRoot, _, err := getRig(rigName)
+	if err != nil {
+		return err
+	}
+
+	// Park first so the daemon doesn't restart what we're about to stop
+	if err := wisp.NewConfig(townRoot, rigName).Set(RigStatusKey, RigStatusParked); err != nil {
+		return fmt.Errorf("parking rig: %w", err)
+	}
+
+	ctl := newRigSessionController(townRoot)
+	identities := daemon.RigIdentities(townRoot, rigName)
+	// Tear down in reverse: crew first, witness last so it can observe the rest
+	for i, j := 0, len(identities)-1; i < j; i, j = i+1, j-1 {
+		identities[i], identities[j] = identities[j], identities[i]
+	}
+
+	fmt.Printf("Tearing down rig %s (%d agents)...\n", style.Bold.Render(rigName), len(identities))
+	requestedBy := detectSender()
+	var failed []string
+	for _, identity := range identities {
+		stopped, err := ctl.Stop(identity, requestedBy)
+		switch {
+		case err != nil:
+			fmt.Printf("  %s %s: %v\n", style.Warning.Render("⚠"), identity, err)
+			failed = append(failed, identity)
+		case stopped:
+			fmt.Printf("  %s %s stopped\n", style.Success.Render("✓"), identity)
+		default:
+			fmt.Printf("  %s %s not running\n", style.Dim.Render("•"), identity)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stop: %s", strings.Join(failed, ", "))
+	}
+	if rigUpWait {
+		if err := waitForRigAgents(identities, "down", rigUpWaitTimeout, func(identity string) bool {
+			running, err := ctl.IsRunning(identity)
+			return err == nil && !running
+		}); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s Rig %s is down (parked; 'gt rig up %s' brings it back)\n", style.Success.Render("✓"), rigName, rigName)
+	return nil
+}
+
+// waitForRigAgents polls until done reports true for every agent.
+func waitForRigAgents(identities []string, want string, timeout time.Duration, done func(identity string) bool) error {
+	fmt.Printf("Waiting for %d agents to be %s...\n", len(identities), want)
+	deadline := time.Now().Add(timeout)
+	pending := identities
+
+	for {
+		var still []string
+		for _, identity := range pending {
+			if !done(identity) {
+				still = append(still, identity)
+				continue
+			}
+			fmt.Printf("  %s %s %s\n", style.Success.Render("✓"), identity, want)
+		}
+		if len(still) == 0 {
+			fmt.Printf("%s All agents %s\n", style.Success.Render("✓"), want)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for agents to be %s: %s", timeout, want, strings.Join(still, ", "))
+		}
+		pending = still
+		time.Sleep(2 * time.Second)
+	}
+}
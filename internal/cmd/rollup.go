@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/rollup"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// rollupWindow is the throughput lookback for each snapshot.
+const rollupWindow = 24 * time.Hour
+
+// Rollup flags
+var (
+	rollupPushDryRun  bool
+	rollupSummaryJSON bool
+)
+
+var rollupCmd = &cobra.Command{
+	Use:     "rollup",
+	GroupID: GroupDiag,
+	Short:   "Export anonymized town metrics to a central dashboard",
+	Long: `Push anonymized summary metrics to an organization-wide aggregation service.
+
+Rollup is opt-in. Enable it in the "rollup" section of mayor/daemon.json:
+
+  "rollup": {
+    "enabled": true,
+    "endpoint": "https://metrics.example.com",
+    "interval": "1h",
+    "token_env": "GT_ROLLUP_TOKEN"
+  }
+
+Once enabled, the daemon runs 'gt rollup push' every interval. Snapshots
+contain counts only: agents by role, availability, month-to-date cost, and
+work throughput over the last 24h. The town is identified by a random ID;
+no town, rig, agent, or bead names are sent.`,
+	RunE: requireSubcommand,
+}
+
+var rollupPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Collect a snapshot and push it to the aggregation service",
+	Long: `Collect an anonymized snapshot of this town and push it.
+
+Use --dry-run to print the snapshot that would be sent without pushing.
+--dry-run works even when rollup is not enabled.`,
+	RunE: runRollupPush,
+}
+
+var rollupSummaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Show the organization-wide rollup from the aggregation service",
+	RunE:  runRollupSummary,
+}
+
+func init() {
+	rollupPushCmd.Flags().BoolVar(&rollupPushDryRun, "dry-run", false, "Print the snapshot instead of pushing it")
+	rollupSummaryCmd.Flags().BoolVar(&rollupSummaryJSON, "json", false, "Output as JSON")
+
+	rollupCmd.AddCommand(rollupPushCmd)
+	rollupCmd.AddCommand(rollupSummaryCmd)
+	rootCmd.AddCommand(rollupCmd)
+}
+
+// loadRollupConfig returns the town's rollup config, failing unless it is
+// enabled and valid.
+func loadRollupConfig(townRoot string) (*rollup.Config, error) {
+	var cfg *rollup.Config
+	if patrol := daemon.LoadPatrolConfig(townRoot); patrol != nil {
+		cfg = patrol.Rollup
+	}
+	if cfg == nil || !cfg.Enabled {
+		return nil, errors.New("rollup is not enabled (set rollup.enabled in mayor/daemon.json)")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func runRollupPush(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	var cfg *rollup.Config
+	if !rollupPushDryRun {
+		if cfg, err = loadRollupConfig(townRoot); err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := collectRollupSnapshot(townRoot, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if rollupPushDryRun {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot)
+	}
+
+	client := rollup.NewClient(cfg.Endpoint, cfg.Token())
+	if err := client.Push(context.Background(), snapshot); err != nil {
+		return fmt.Errorf("pushing snapshot: %w", err)
+	}
+	fmt.Printf("%s Pushed snapshot to %s\n", style.Success.Render("✓"), cfg.Endpoint)
+	return nil
+}
+
+// collectRollupSnapshot gathers the anonymized metrics for one snapshot.
+// Cost and throughput are best-effort: a town without bd or an event log
+// still reports its agents.
+func collectRollupSnapshot(townRoot string, now time.Time) (*rollup.Snapshot, error) {
+	townID, err := rollup.TownID(townRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &rollup.Snapshot{
+		SchemaVersion: rollup.SchemaVersion,
+		TownID:        townID,
+		CollectedAt:   now.UTC(),
+		WindowHours:   int(rollupWindow.Hours()),
+		Agents:        rollup.AgentMetrics{ByRole: make(map[string]int)},
+	}
+
+	// Availability covers persistent agents only; polecats come and go with work
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+	persistent, persistentUp := 0, 0
+	for _, identity := range ctl.Identities() {
+		role := daemon.IdentityRole(identity)
+		running, err := ctl.IsRunning(identity)
+		if err != nil {
+			continue
+		}
+		snapshot.Agents.Total++
+		snapshot.Agents.ByRole[role]++
+		if running {
+			snapshot.Agents.Running++
+		}
+		if role != "polecat" {
+			persistent++
+			if running {
+				persistentUp++
+			}
+		}
+	}
+	if persistent > 0 {
+		snapshot.Availability = float64(persistentUp) / float64(persistent)
+	}
+
+	if spend, err := monthToDateSpendByRig(now); err == nil {
+		for _, usd := range spend {
+			snapshot.CostMonthUSD += usd
+		}
+	} else {
+		style.PrintWarning("could not read month-to-date cost: %v", err)
+	}
+
+	if throughput, err := rollup.CountThroughput(townRoot, now.Add(-rollupWindow)); err == nil {
+		snapshot.Throughput = throughput
+	} else {
+		style.PrintWarning("could not read event log: %v", err)
+	}
+
+	return snapshot, nil
+}
+
+func runRollupSummary(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	cfg, err := loadRollupConfig(townRoot)
+	if err != nil {
+		return err
+	}
+
+	summary, err := rollup.NewClient(cfg.Endpoint, cfg.Token()).Summary(context.Background())
+	if err != nil {
+		return fmt.Errorf("fetching summary: %w", err)
+	}
+
+	if rollupSummaryJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summary)
+	}
+
+	fmt.Printf("%s\n\n", style.Bold.Render("Organization rollup"))
+	fmt.Printf("  Towns:         %d\n", summary.Towns)
+	fmt.Printf("  Agents:        %d (%d running)\n", summary.Agents.Total, summary.Agents.Running)
+	fmt.Printf("  Availability:  %.1f%%\n", summary.Availability*100)
+	fmt.Printf("  Cost (month):  $%.2f\n", summary.CostMonthUSD)
+	fmt.Printf("  Throughput:    %d slung, %d done, %d merged, %d merge failures\n",
+		summary.Throughput.Slung, summary.Throughput.Done, summary.Throughput.Merged, summary.Throughput.MergeFailed)
+	if !summary.UpdatedAt.IsZero() {
+		fmt.Printf("  %s\n", style.Dim.Render("Updated "+summary.UpdatedAt.Local().Format(time.RFC822)))
+	}
+	return nil
+}
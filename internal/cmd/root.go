@@ -8,7 +8,9 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
 	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/tmux"
 	"github.com/steveyegge/gastown/internal/version"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
@@ -20,7 +22,11 @@ var rootCmd = &cobra.Command{
 	Long: `Gas Town (gt) manages multi-agent workspaces called rigs.
 
 It coordinates agent spawning, work distribution, and communication
-across distributed teams of AI agents working on shared codebases.`,
+across distributed teams of AI agents working on shared codebases.
+
+Exit codes: 0 success, 1 error, 3 unknown agent identity, 4 state
+verification failed (rig parked, workspace missing, ...), 5 session
+backend (tmux) error, 6 stale lifecycle request.`,
 	PersistentPreRunE: persistentPreRun,
 }
 
@@ -48,6 +54,8 @@ func persistentPreRun(cmd *cobra.Command, args []string) error {
 	// Get the root command name being run
 	cmdName := cmd.Name()
 
+	useTownTmuxSocket()
+
 	// Check town root branch (warning only, non-blocking)
 	if !branchCheckExemptCommands[cmdName] {
 		warnIfTownRootOffMain()
@@ -62,6 +70,16 @@ func persistentPreRun(cmd *cobra.Command, args []string) error {
 	return CheckBeadsVersion()
 }
 
+// useTownTmuxSocket points every tmux call at the town's dedicated tmux
+// server, when mayor/daemon.json (or GT_TMUX_SOCKET) gives it one.
+func useTownTmuxSocket() {
+	socket := tmux.SocketFromEnv()
+	if townRoot, err := workspace.FindFromCwd(); err == nil && townRoot != "" {
+		socket = daemon.TmuxSocket(townRoot)
+	}
+	tmux.SetDefaultSocket(socket)
+}
+
 // warnIfTownRootOffMain prints a warning if the town root is not on main branch.
 // This is a non-blocking warning to help catch accidental branch switches.
 func warnIfTownRootOffMain() {
@@ -158,15 +176,9 @@ func checkStaleBinaryWarning() {
 // Execute runs the root command and returns an exit code.
 // The caller (main) should call os.Exit with this code.
 func Execute() int {
-	if err := rootCmd.Execute(); err != nil {
-		// Check for silent exit (scripting commands that signal status via exit code)
-		if code, ok := IsSilentExit(err); ok {
-			return code
-		}
-		// Other errors already printed by cobra
-		return 1
-	}
-	return 0
+	// Errors were already printed by cobra; silent exits and classified
+	// daemon errors pick the code
+	return ExitCode(rootCmd.Execute())
 }
 
 // Command group IDs - used by subcommands to organize help output
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/scorecard"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Scorecard flags
+var (
+	scorecardSince  string
+	scorecardRole   string
+	scorecardJSON   bool
+	scorecardByRole bool
+)
+
+var scorecardCmd = &cobra.Command{
+	Use:     "scorecard",
+	GroupID: GroupDiag,
+	Short:   "Show per-agent performance scorecards",
+	Long: `Show performance scorecards for each agent over a window.
+
+Metrics come from the event log and the cost ledger:
+
+  Done      beads the agent completed (gt done)
+  Rework    share of completed beads that were slung again afterwards
+  Cycles    average slings per completed bead (1.0 = done first time)
+  $/bead    session spend divided by completed beads
+
+Use --by-role to compare roles (e.g. crew vs polecats) instead of
+individual agents. --json output is stable for dashboards and scripts.
+
+Examples:
+  gt scorecard                  # Last 7 days
+  gt scorecard --since 30d      # Last 30 days
+  gt scorecard --role polecat   # Polecats only
+  gt scorecard --by-role --json`,
+	Args: cobra.NoArgs,
+	RunE: runScorecard,
+}
+
+func init() {
+	scorecardCmd.Flags().StringVar(&scorecardSince, "since", "7d", "Window to score (e.g., 24h, 7d, 30d)")
+	scorecardCmd.Flags().StringVar(&scorecardRole, "role", "", "Only show agents with this role")
+	scorecardCmd.Flags().BoolVar(&scorecardByRole, "by-role", false, "Aggregate scorecards by role")
+	scorecardCmd.Flags().BoolVar(&scorecardJSON, "json", false, "Output as JSON")
+
+	rootCmd.AddCommand(scorecardCmd)
+}
+
+func runScorecard(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	window, err := parseDuration(scorecardSince)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	now := time.Now()
+	since := now.Add(-window)
+
+	evts, err := scorecard.LoadEvents(townRoot)
+	if err != nil {
+		return fmt.Errorf("reading event log: %w", err)
+	}
+
+	// Cost is best-effort: scorecards without bd still show work metrics.
+	costs, err := spendByAgentSince(now, since)
+	if err != nil {
+		style.PrintWarning("could not read cost ledger: %v", err)
+	}
+
+	report := scorecard.Build(evts, costs, since, now)
+	if scorecardRole != "" {
+		var filtered []scorecard.Scorecard
+		for _, c := range report.Agents {
+			if c.Role == scorecardRole {
+				filtered = append(filtered, c)
+			}
+		}
+		report.Agents = filtered
+	}
+
+	if scorecardJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Printf("\n%s Scorecards since %s\n\n", style.Bold.Render("📊"), since.Format("2006-01-02 15:04"))
+
+	cards, label := report.Agents, "AGENT"
+	if scorecardByRole {
+		cards, label = report.ByRole, "ROLE"
+	}
+	if len(cards) == 0 {
+		fmt.Printf("  %s\n\n", style.Dim.Render("(no activity in window)"))
+		return nil
+	}
+
+	fmt.Printf("  %-28s %8s %6s %8s %7s %10s %9s\n", label, "ASSIGNED", "DONE", "REWORK", "CYCLES", "COST", "$/BEAD")
+	for _, c := range cards {
+		name := c.Agent
+		if scorecardByRole {
+			name = c.Role
+		}
+		fmt.Printf("  %-28s %8d %6d %7.0f%% %7.1f %10s %9s\n",
+			name, c.Assigned, c.Completed, c.ReworkRate*100, c.AvgCycles,
+			formatScorecardUSD(c.CostUSD), formatScorecardUSD(c.CostPerBead))
+	}
+	fmt.Println()
+	return nil
+}
+
+// spendByAgentSince sums ledger costs per agent path for sessions that ended
+// after since: daily digests plus today's not-yet-digested session wisps.
+func spendByAgentSince(now, since time.Time) (map[string]float64, error) {
+	days := int(math.Ceil(now.Sub(since).Hours()/24)) + 1
+	entries, err := queryDigestBeads(days)
+	if err != nil {
+		return nil, fmt.Errorf("querying digest beads: %w", err)
+	}
+	todayWisps, _ := querySessionCostWisps(now)
+	entries = append(entries, todayWisps...)
+
+	spend := make(map[string]float64)
+	for _, entry := range entries {
+		if !entry.EndedAt.IsZero() && entry.EndedAt.Before(since) {
+			continue
+		}
+		spend[buildAgentPath(entry.Role, entry.Rig, entry.Worker)] += entry.CostUSD
+	}
+	return spend, nil
+}
+
+func formatScorecardUSD(usd float64) string {
+	if usd == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
@@ -478,6 +478,9 @@ func runSling(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%s Could not store dispatcher in bead: %v\n", style.Dim.Render("Warning:"), err)
 	}
 
+	// Show the dispatcher which quality gates the work will be held to
+	announceQualityGates(townRoot, targetAgent)
+
 	// Store args in bead description (no-tmux mode: beads as data plane)
 	if slingArgs != "" {
 		if err := storeArgsInBead(beadID, slingArgs); err != nil {
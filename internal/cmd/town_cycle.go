@@ -2,10 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
 	"sort"
 
 	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/tmux"
 )
 
 // townCycleSession is the --session flag for town next/prev commands.
@@ -43,7 +43,9 @@ func init() {
 var townCmd = &cobra.Command{
 	Use:   "town",
 	Short: "Town-level operations",
-	Long:  `Commands for town-level operations including session cycling.`,
+	Long:  `Commands for town-level operations including scaffolding a new town,
+session cycling, reconciling agents against the town manifest (town.yaml), and snapshotting
+the town's control state to move it between hosts.`,
 }
 
 var townNextCmd = &cobra.Command{
@@ -135,7 +137,7 @@ func cycleTownSession(direction int, sessionOverride string) error {
 	targetSession := sessions[targetIdx]
 
 	// Switch to target session
-	cmd := exec.Command("tmux", "switch-client", "-t", targetSession)
+	cmd := tmux.Command("switch-client", "-t", targetSession)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("switching to %s: %w", targetSession, err)
 	}
@@ -146,7 +148,7 @@ func cycleTownSession(direction int, sessionOverride string) error {
 // findRunningTownSessions returns a list of currently running town-level sessions.
 func findRunningTownSessions() ([]string, error) {
 	// Get all tmux sessions
-	out, err := exec.Command("tmux", "list-sessions", "-F", "#{session_name}").Output()
+	out, err := tmux.Command("list-sessions", "-F", "#{session_name}").Output()
 	if err != nil {
 		return nil, fmt.Errorf("listing tmux sessions: %w", err)
 	}
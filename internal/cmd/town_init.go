@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/crew"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/rig"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Town init flags
+var (
+	townInitName    string
+	townInitRigs    []string
+	townInitCrew    []string
+	townInitNoBeads bool
+	townInitService bool
+)
+
+var townInitCmd = &cobra.Command{
+	Use:   "init [path]",
+	Short: "Scaffold a complete town: HQ, rigs, crew, and daemon",
+	Long: `Set up a town in one step.
+
+Creates the HQ as 'gt install' does (mayor/, deacon/, town beads holding
+the mail store, mayor and deacon agent beads) if path isn't one yet, then:
+  - adds each --rig (witness/, refinery/, crew/, polecats/ and the witness
+    and refinery agent beads), skipping rigs already registered
+  - creates each --crew workspace and its agent bead
+  - writes mayor/daemon.json with the default patrols if it is missing
+  - creates an empty agent state record for every agent, in the state
+    backend daemon.json selects (state.json files by default)
+  - with --service, installs the daemon as a user service (systemd on
+    Linux, launchd on macOS) so it starts at login
+
+Running it again on an existing town only adds what is missing.
+
+Examples:
+  gt town init ~/gt --rig gastown=https://github.com/steveyegge/gastown
+  gt town init ~/gt --rig gastown=git@github.com:me/gastown.git --crew gastown/max
+  gt town init . --service`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTownInit,
+}
+
+func init() {
+	townInitCmd.Flags().StringVarP(&townInitName, "name", "n", "", "Town name (defaults to directory name)")
+	townInitCmd.Flags().StringArrayVar(&townInitRigs, "rig", nil, "Rig to add, as name=git-url (repeatable)")
+	townInitCmd.Flags().StringArrayVar(&townInitCrew, "crew", nil, "Crew workspace to create, as rig/name (repeatable)")
+	townInitCmd.Flags().BoolVar(&townInitNoBeads, "no-beads", false, "Skip town beads initialization")
+	townInitCmd.Flags().BoolVar(&townInitService, "service", false, "Install the daemon as a user service")
+	townCmd.AddCommand(townInitCmd)
+}
+
+func runTownInit(cmd *cobra.Command, args []string) error {
+	target := "."
+	if len(args) > 0 {
+		target = args[0]
+	}
+	if strings.HasPrefix(target, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("getting home directory: %w", err)
+		}
+		target = filepath.Join(home, target[1:])
+	}
+	townRoot, err := filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+
+	// Validate everything before creating anything
+	rigs, err := parseTownInitRigs(townInitRigs)
+	if err != nil {
+		return err
+	}
+	for _, spec := range townInitCrew {
+		if r, name, ok := parseRigSlashName(spec); !ok || r == "" || name == "" {
+			return fmt.Errorf("invalid --crew %q (want rig/name)", spec)
+		}
+	}
+
+	if isWS, _ := workspace.IsWorkspace(townRoot); isWS {
+		fmt.Printf("%s Using existing HQ at %s\n", style.Bold.Render("🏭"), style.Dim.Render(townRoot))
+	} else {
+		installName = townInitName
+		installNoBeads = townInitNoBeads
+		if err := runInstall(cmd, []string{townRoot}); err != nil {
+			return err
+		}
+	}
+	fmt.Println()
+
+	for _, opts := range rigs {
+		if err := initTownRig(townRoot, opts); err != nil {
+			return err
+		}
+	}
+	for _, spec := range townInitCrew {
+		rigName, name, _ := parseRigSlashName(spec)
+		if err := initTownCrew(townRoot, rigName, name); err != nil {
+			return err
+		}
+	}
+
+	if err := initTownDaemon(townRoot); err != nil {
+		return err
+	}
+	initTownAgentStates(townRoot)
+
+	if townInitService {
+		spec, err := townServiceSpec(townRoot)
+		if err != nil {
+			return err
+		}
+		path, err := daemon.InstallService(spec)
+		if err != nil {
+			return fmt.Errorf("installing daemon service: %w", err)
+		}
+		fmt.Printf("   ✓ Installed %s service %s (%s)\n", spec.Manager, spec.Name, path)
+	}
+
+	fmt.Printf("\n%s Town ready at %s\n", style.Bold.Render("✓"), townRoot)
+	if !townInitService {
+		fmt.Printf("  Start the daemon: %s\n", style.Dim.Render("gt daemon start"))
+	}
+	return nil
+}
+
+// parseTownInitRigs parses --rig name=git-url flags.
+func parseTownInitRigs(specs []string) ([]rig.AddRigOptions, error) {
+	var out []rig.AddRigOptions
+	for _, spec := range specs {
+		name, url, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid --rig %q (want name=git-url)", spec)
+		}
+		out = append(out, rig.AddRigOptions{Name: name, GitURL: url})
+	}
+	return out, nil
+}
+
+// initTownRig adds a rig unless it is already registered, in which case it
+// only makes sure the agent directories exist.
+func initTownRig(townRoot string, opts rig.AddRigOptions) error {
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err == nil {
+		if _, ok := rigsConfig.Rigs[opts.Name]; ok {
+			for _, dir := range []string{"witness", "refinery", "crew", "polecats"} {
+				if err := os.MkdirAll(filepath.Join(townRoot, opts.Name, dir), 0755); err != nil {
+					return fmt.Errorf("creating %s/%s: %w", opts.Name, dir, err)
+				}
+			}
+			fmt.Printf("   ✓ Rig %s already registered\n", opts.Name)
+			return nil
+		}
+	}
+
+	fmt.Printf("Creating rig %s from %s...\n", style.Bold.Render(opts.Name), opts.GitURL)
+	if _, err := addRigToTown(townRoot, opts); err != nil {
+		return fmt.Errorf("rig %s: %w", opts.Name, err)
+	}
+	fmt.Printf("   ✓ Created rig %s\n", opts.Name)
+	return nil
+}
+
+// initTownCrew creates a crew workspace unless it exists, and its agent bead.
+func initTownCrew(townRoot, rigName, name string) error {
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil {
+		return fmt.Errorf("loading rigs config: %w", err)
+	}
+	r, err := rig.NewManager(townRoot, rigsConfig, git.NewGit(townRoot)).GetRig(rigName)
+	if err != nil {
+		return fmt.Errorf("crew %s/%s: rig '%s' not found", rigName, name, rigName)
+	}
+
+	worker, err := crew.NewManager(r, git.NewGit(r.Path)).Add(name, false)
+	switch {
+	case err == crew.ErrCrewExists:
+		fmt.Printf("   ✓ Crew %s/%s already exists\n", rigName, name)
+	case err != nil:
+		return fmt.Errorf("creating crew workspace %s/%s: %w", rigName, name, err)
+	default:
+		fmt.Printf("   ✓ Created crew workspace %s/%s (%s)\n", rigName, name, worker.ClonePath)
+	}
+	ensureCrewAgentBead(beads.New(beads.ResolveBeadsDir(r.Path)), townRoot, rigName, name)
+	setManifestCrew(townRoot, rigName, name, true)
+	return nil
+}
+
+// initTownDaemon writes the default mayor/daemon.json if there is none and
+// creates the daemon's runtime directory.
+func initTownDaemon(townRoot string) error {
+	path := config.DaemonPatrolConfigPath(townRoot)
+	_, statErr := os.Stat(path)
+	if err := config.EnsureDaemonPatrolConfig(townRoot); err != nil {
+		return fmt.Errorf("writing daemon config: %w", err)
+	}
+	if os.IsNotExist(statErr) {
+		fmt.Printf("   ✓ Created mayor/daemon.json\n")
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "daemon"), 0755); err != nil {
+		return fmt.Errorf("creating daemon directory: %w", err)
+	}
+	return nil
+}
+
+// initTownAgentStates creates an empty state record for the town agents and
+// each rig's witness, refinery, and crew. Failures are warnings: agents
+// create their state on first use anyway.
+func initTownAgentStates(townRoot string) {
+	identities := []string{"mayor", "deacon"}
+	if rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json")); err == nil {
+		mgr := rig.NewManager(townRoot, rigsConfig, git.NewGit(townRoot))
+		for name := range rigsConfig.Rigs {
+			identities = append(identities, name+"-witness", name+"-refinery")
+			r, err := mgr.GetRig(name)
+			if err != nil {
+				continue
+			}
+			for _, member := range r.Crew {
+				identities = append(identities, crewIdentity(name, member))
+			}
+		}
+	}
+
+	initialized := 0
+	for _, identity := range identities {
+		if err := daemon.InitAgentState(townRoot, identity); err != nil {
+			style.PrintWarning("could not create agent state for %s: %v", identity, err)
+			continue
+		}
+		initialized++
+	}
+	fmt.Printf("   ✓ Agent state ready for %d agent(s)\n", initialized)
+}
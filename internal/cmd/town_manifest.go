@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var townReconcileDryRun bool
+
+var townDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show drift between town.yaml and running agents",
+	Long: `Compare the town manifest (town.yaml at the town root) with the agent
+sessions and agent beads actually present, and show what the reconciler
+would change.
+
+The daemon reconciles on every heartbeat; this shows the pending plan.`,
+	RunE: runTownDiff,
+}
+
+var townReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Converge running agents toward town.yaml now",
+	Long: `Apply the reconciler plan immediately instead of waiting for the next
+daemon heartbeat: start declared agents that aren't running, stop sessions
+the manifest doesn't want, and correct agent beads that disagree with tmux.
+
+Parked and docked rigs are never started. Polecats over a rig's cap are
+reported, not killed.`,
+	RunE: runTownReconcile,
+}
+
+func init() {
+	townReconcileCmd.Flags().BoolVar(&townReconcileDryRun, "dry-run", false, "Show the plan without applying it")
+	townCmd.AddCommand(townDiffCmd)
+	townCmd.AddCommand(townReconcileCmd)
+}
+
+// loadTownManifest finds the town and reads its manifest.
+func loadTownManifest() (string, *manifest.Manifest, error) {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return "", nil, fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	m, err := manifest.Load(townRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	if m == nil {
+		return "", nil, fmt.Errorf("no %s in %s", manifest.FileName, townRoot)
+	}
+	return townRoot, m, nil
+}
+
+func runTownDiff(cmd *cobra.Command, args []string) error {
+	townRoot, m, err := loadTownManifest()
+	if err != nil {
+		return err
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(os.Stderr, "  ", 0))
+	changes := ctl.ReconcilePlan(m)
+	if len(changes) == 0 {
+		fmt.Printf("%s Town matches %s\n", style.Success.Render("✓"), manifest.FileName)
+		return nil
+	}
+	for _, change := range changes {
+		printManifestChange(change)
+	}
+	return nil
+}
+
+func runTownReconcile(cmd *cobra.Command, args []string) error {
+	townRoot, m, err := loadTownManifest()
+	if err != nil {
+		return err
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(os.Stderr, "  ", 0))
+	changes := ctl.ReconcilePlan(m)
+	if len(changes) == 0 {
+		fmt.Printf("%s Town matches %s\n", style.Success.Render("✓"), manifest.FileName)
+		return nil
+	}
+
+	failed := 0
+	for _, change := range changes {
+		printManifestChange(change)
+		if townReconcileDryRun || change.Action == manifest.ActionWarn {
+			continue
+		}
+		if err := ctl.Apply(change); err != nil {
+			fmt.Printf("    %s %v\n", style.Warning.Render("⚠"), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes failed", failed, len(changes))
+	}
+	if townReconcileDryRun {
+		fmt.Printf("%s\n", style.Dim.Render("(dry run: no changes applied)"))
+	}
+	return nil
+}
+
+func printManifestChange(change manifest.Change) {
+	var icon string
+	switch change.Action {
+	case manifest.ActionStart:
+		icon = style.Success.Render("+")
+	case manifest.ActionStop:
+		icon = style.Warning.Render("-")
+	case manifest.ActionSyncBead:
+		icon = style.Dim.Render("~")
+	default:
+		icon = style.Warning.Render("⚠")
+	}
+	fmt.Printf("  %s %-10s %s %s\n", icon, change.Action, change.Identity, style.Dim.Render("("+change.Reason+")"))
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/snapshot"
+	"github.com/steveyegge/gastown/internal/state"
+	"github.com/steveyegge/gastown/internal/storage"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	townSnapshotOutput string
+	townRestoreForce   bool
+	townRestoreStart   bool
+)
+
+var townSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Archive the town's control state for migration",
+	Long: `Write the town's control state to a single archive:
+
+  - daemon state, runtime state, and journals (startup, audit)
+  - daemon config, rig registry, and town.yaml
+  - every agent's state.json
+  - a bead export of each beads database (agent beads, mail queues, work)
+  - the agent sessions and whether each was running
+
+Rig clones and worktrees are not included. Restore the archive with
+'gt town restore' on the new host.`,
+	RunE: runTownSnapshot,
+}
+
+var townRestoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Restore town control state from a snapshot",
+	Long: `Restore a snapshot written by 'gt town snapshot' into the current town.
+
+To move a town: install a town on the new host (gt install), add its rigs
+and crew again so their workspaces exist, stop the daemon, then restore.
+Agent state for workspaces that don't exist yet is skipped and reported;
+restore again once they are cloned.
+
+Nothing is written if the restore would overwrite existing state, unless
+--force is given. With --start, agents whose session was running at
+snapshot time are started afterwards.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTownRestore,
+}
+
+func init() {
+	townSnapshotCmd.Flags().StringVarP(&townSnapshotOutput, "output", "o", "", "Archive path (default town-snapshot-<time>.tar.gz)")
+	townRestoreCmd.Flags().BoolVar(&townRestoreForce, "force", false, "Overwrite existing state")
+	townRestoreCmd.Flags().BoolVar(&townRestoreStart, "start", false, "Start agents that were running at snapshot time")
+	townCmd.AddCommand(townSnapshotCmd)
+	townCmd.AddCommand(townRestoreCmd)
+}
+
+// snapshotSkip are store keys and files never archived: they belong to the
+// running daemon process, not the town.
+var snapshotSkip = []string{"*.pid", "*.lock", "*.log", "leader.json"}
+
+func runTownSnapshot(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctl := daemon.NewSessionController(townRoot, log.New(os.Stderr, "  ", 0))
+	opts := snapshot.CollectOptions{
+		StorePrefixes: []string{"daemon/"},
+		SkipKeys:      snapshotSkip,
+		Files: []string{
+			townRel(townRoot, daemon.StartupJournalFile(townRoot)),
+			townRel(townRoot, daemon.AuditLogFile(townRoot)),
+			townRel(townRoot, daemon.PatrolConfigFile(townRoot)),
+			filepath.Join("mayor", "rigs.json"),
+			manifest.FileName,
+		},
+		ExportBeads: exportBeads,
+	}
+	for _, identity := range ctl.Identities() {
+		running, _ := ctl.IsRunning(identity)
+		opts.Agents = append(opts.Agents, snapshot.Agent{
+			Identity: identity,
+			Session:  ctl.SessionName(identity),
+			Running:  running,
+		})
+		if workDir := ctl.WorkDir(identity); workDir != "" {
+			if rel := townRel(townRoot, state.AgentStatePath(workDir)); rel != "" {
+				opts.Files = append(opts.Files, rel)
+			}
+		}
+	}
+	for _, workDir := range ctl.BeadsWorkspaces() {
+		if rel := townRel(townRoot, workDir); rel != "" {
+			opts.BeadsWorkspaces = append(opts.BeadsWorkspaces, rel)
+		}
+	}
+
+	now := time.Now()
+	snap, warnings, err := snapshot.Collect(townRoot, store, opts, now)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Printf("  %s %s\n", style.Warning.Render("⚠"), w)
+	}
+
+	output := townSnapshotOutput
+	if output == "" {
+		output = "town-snapshot-" + now.Format("20060102-150405") + ".tar.gz"
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := snap.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", output, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	running := 0
+	for _, agent := range snap.Agents {
+		if agent.Running {
+			running++
+		}
+	}
+	fmt.Printf("%s Wrote %s (%d entries, %d agents, %d running)\n",
+		style.Success.Render("✓"), output, len(snap.Entries), len(snap.Agents), running)
+	return nil
+}
+
+func runTownRestore(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	if running, pid, _ := daemon.IsRunning(townRoot); running {
+		return fmt.Errorf("daemon is running (PID %d); stop it with 'gt daemon stop' before restoring", pid)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	snap, err := snapshot.Read(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot of %s on %s, taken %s\n", snap.TownRoot, snap.Host, snap.CreatedAt.Local().Format("2006-01-02 15:04"))
+
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	result, err := snap.Restore(townRoot, snapshot.RestoreOptions{
+		Store:       store,
+		Force:       townRestoreForce,
+		CreateDirs:  []string{"daemon", "mayor"},
+		ImportBeads: importBeads,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Restored %d of %d entries\n", style.Success.Render("✓"), len(result.Restored), len(snap.Entries))
+	for _, skipped := range result.Skipped {
+		fmt.Printf("  %s skipped %s\n", style.Warning.Render("⚠"), skipped)
+	}
+
+	var wasRunning []string
+	for _, agent := range snap.Agents {
+		if agent.Running {
+			wasRunning = append(wasRunning, agent.Identity)
+		}
+	}
+	if len(wasRunning) == 0 {
+		return nil
+	}
+	if !townRestoreStart {
+		fmt.Printf("\n%d agents were running: %s\n", len(wasRunning), strings.Join(wasRunning, ", "))
+		fmt.Printf("Start them with %s, or start the daemon.\n", style.Dim.Render("gt town restore --start"))
+		return nil
+	}
+
+	ctl := daemon.NewSessionController(townRoot, log.New(os.Stderr, "  ", 0))
+	failed := 0
+	for _, identity := range wasRunning {
+		started, err := ctl.Start(identity)
+		switch {
+		case err != nil:
+			fmt.Printf("  %s %s: %v\n", style.Warning.Render("⚠"), identity, err)
+			failed++
+		case started:
+			fmt.Printf("  %s started %s\n", style.Success.Render("+"), identity)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d agents failed to start", failed, len(wasRunning))
+	}
+	return nil
+}
+
+// townRel returns p relative to the town root in slash form, or "" if p is
+// outside the town.
+func townRel(townRoot, p string) string {
+	rel, err := filepath.Rel(townRoot, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// exportBeads exports the beads database of a workspace as JSONL, falling
+// back to the database's committed issues.jsonl when bd can't export.
+func exportBeads(workDir string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.Command("bd", "export")
+	c.Dir = workDir
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	exportErr := c.Run()
+	if exportErr == nil {
+		return stdout.Bytes(), nil
+	}
+	data, err := os.ReadFile(filepath.Join(beads.ResolveBeadsDir(workDir), "issues.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("bd export: %v %s", exportErr, strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
+}
+
+// importBeads loads a JSONL export into a workspace's beads database.
+func importBeads(workDir, jsonlPath string) error {
+	c := exec.Command("bd", "import", "-i", jsonlPath)
+	c.Dir = workDir
+	if out, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("bd import: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
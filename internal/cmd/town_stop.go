@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// emergencyStopWait is how long 'gt town stop --force' waits for a running
+// daemon to carry out the stop before killing the sessions itself.
+const emergencyStopWait = 10 * time.Second
+
+var (
+	townStopForce  bool
+	townStopReason string
+)
+
+var townStopCmd = &cobra.Command{
+	Use:   "stop --force",
+	Short: "Emergency stop: kill every agent session now",
+	Long: `Kill every managed agent session immediately and keep the town down.
+
+This is the emergency brake, not an orderly shutdown (use 'gt down' for
+that): sessions are killed without pre-shutdown hooks or state checks, and
+the daemon refuses to start or restart any session - heartbeat recovery,
+lifecycle requests, warm pool, prewarming - until 'gt town resume'.
+
+The stop is recorded with its reason. If the daemon is running it carries
+out the stop; otherwise this command kills the sessions itself.
+
+The mayor or the overseer can also trigger an emergency stop by mail: an
+urgent or high priority message to deacon/ with subject
+"EMERGENCY_STOP: <reason>".
+
+Examples:
+  gt town stop --force --reason "runaway API spend"`,
+	Args: cobra.NoArgs,
+	RunE: runTownStop,
+}
+
+var townResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Lift an emergency stop",
+	Long: `Lift the emergency stop set by 'gt town stop --force', so the daemon
+starts and restarts sessions again. Agents come back on the daemon's next
+heartbeat; use 'gt up' to start them now.`,
+	Args: cobra.NoArgs,
+	RunE: runTownResume,
+}
+
+func init() {
+	townStopCmd.Flags().BoolVar(&townStopForce, "force", false, "Required: confirm the emergency stop")
+	townStopCmd.Flags().StringVar(&townStopReason, "reason", "", "Why the town is being stopped (recorded)")
+	townCmd.AddCommand(townStopCmd)
+	townCmd.AddCommand(townResumeCmd)
+}
+
+func runTownStop(cmd *cobra.Command, args []string) error {
+	if !townStopForce {
+		return fmt.Errorf("'gt town stop' is an emergency stop and requires --force (for an orderly shutdown use 'gt down')")
+	}
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	requestedBy := detectSender()
+	reason := townStopReason
+	if reason == "" {
+		reason = "gt town stop --force"
+	}
+	stop, err := daemon.RequestEmergencyStop(townRoot, reason, requestedBy)
+	if err != nil {
+		return fmt.Errorf("recording emergency stop: %w", err)
+	}
+	fmt.Printf("%s Emergency stop recorded: %s\n", style.Bold.Render("■"), stop.Reason)
+
+	// Let the daemon do it, so no heartbeat in flight races the kill
+	if running, pid, _ := daemon.IsRunning(townRoot); running && daemon.WakeDaemon(pid) == nil {
+		for waited := time.Duration(0); waited < emergencyStopWait; waited += 500 * time.Millisecond {
+			time.Sleep(500 * time.Millisecond)
+			if current, err := daemon.LoadEmergencyStop(townRoot); err == nil && current != nil && !current.ExecutedAt.IsZero() {
+				stop = current
+				break
+			}
+		}
+	}
+	if stop.ExecutedAt.IsZero() {
+		ctl := daemon.NewSessionController(townRoot, log.New(os.Stderr, "", 0))
+		if stop, err = ctl.EnforceEmergencyStop(); err != nil {
+			return err
+		}
+		if stop == nil {
+			return fmt.Errorf("emergency stop was lifted before it was carried out")
+		}
+	}
+
+	fmt.Printf("%s Killed %d session(s)\n", style.Success.Render("✓"), len(stop.Killed))
+	for _, name := range stop.Killed {
+		fmt.Printf("  %s\n", style.Dim.Render(name))
+	}
+	fmt.Printf("Nothing will be started until %s\n", style.Bold.Render("gt town resume"))
+	return nil
+}
+
+func runTownResume(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	stop, err := daemon.ResumeFromEmergencyStop(townRoot)
+	if err != nil {
+		return fmt.Errorf("lifting emergency stop: %w", err)
+	}
+	if stop == nil {
+		fmt.Println(style.Dim.Render("No emergency stop in effect."))
+		return nil
+	}
+	fmt.Printf("%s Emergency stop lifted (in effect since %s, requested by %s: %s)\n",
+		style.Success.Render("✓"), stop.Since.Local().Format("2006-01-02 15:04"), stop.RequestedBy, stop.Reason)
+	if running, pid, _ := daemon.IsRunning(townRoot); running {
+		_ = daemon.WakeDaemon(pid)
+		fmt.Println(style.Dim.Render("The daemon restarts agents at its next heartbeat; run 'gt up' to start them now."))
+	}
+	return nil
+}
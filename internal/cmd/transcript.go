@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/transcript"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Transcript command flags
+var (
+	transcriptLines     int
+	transcriptDir       string
+	transcriptMaxSizeMB int
+	transcriptKeep      int
+)
+
+var transcriptCmd = &cobra.Command{
+	Use:     "transcript <agent>",
+	GroupID: GroupDiag,
+	Short:   "Show captured session output for an agent",
+	Long: `Show the last lines of an agent's persisted session transcript.
+
+Transcripts are captured by the daemon via tmux pipe-pane when enabled in
+mayor/daemon.json ("transcripts": {"enabled": true}) and are kept under
+<town>/logs/transcripts/<session>/ with size-based rotation. They outlive
+the session, so they're available after an agent dies or is cycled.
+
+The agent can be a role shortcut, a path, or a session name.
+
+Examples:
+  gt transcript mayor                    # Mayor's transcript
+  gt transcript gastown/witness          # Rig witness
+  gt transcript gastown/crew/max -n 200  # Last 200 lines
+  gt transcript gt-gastown-Toast         # By session name`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTranscript,
+}
+
+var transcriptRecordCmd = &cobra.Command{
+	Use:    "record",
+	Short:  "Record stdin to a rotating transcript (used by tmux pipe-pane)",
+	Hidden: true,
+	RunE:   runTranscriptRecord,
+}
+
+func init() {
+	transcriptCmd.Flags().IntVarP(&transcriptLines, "lines", "n", 50, "Number of lines to show")
+
+	transcriptRecordCmd.Flags().StringVar(&transcriptDir, "dir", "", "Transcript directory")
+	transcriptRecordCmd.Flags().IntVar(&transcriptMaxSizeMB, "max-size-mb", 0, "Rotate after this many MiB (default 10)")
+	transcriptRecordCmd.Flags().IntVar(&transcriptKeep, "keep", 0, "Rotated files to keep (default 5)")
+	_ = transcriptRecordCmd.MarkFlagRequired("dir")
+
+	transcriptCmd.AddCommand(transcriptRecordCmd)
+	rootCmd.AddCommand(transcriptCmd)
+}
+
+func runTranscript(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+
+	sessionName, err := resolveRoleToSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	lines, err := transcript.Tail(transcript.Dir(townRoot, sessionName), transcriptLines)
+	if err != nil {
+		return fmt.Errorf("reading transcript: %w", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("no transcript for %s (is transcript capture enabled in mayor/daemon.json?)", sessionName)
+	}
+
+	fmt.Println(strings.Join(lines, "\n"))
+	return nil
+}
+
+func runTranscriptRecord(cmd *cobra.Command, args []string) error {
+	maxSize := int64(transcriptMaxSizeMB) * 1024 * 1024
+	return transcript.Record(transcriptDir, os.Stdin, maxSize, transcriptKeep)
+}
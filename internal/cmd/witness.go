@@ -323,12 +323,11 @@ func runWitnessAttach(cmd *cobra.Command, args []string) error {
 	}
 
 	// Attach to the session
-	tmuxPath, err := exec.LookPath("tmux")
-	if err != nil {
+	if _, err := exec.LookPath("tmux"); err != nil {
 		return fmt.Errorf("tmux not found: %w", err)
 	}
 
-	attachCmd := exec.Command(tmuxPath, "attach-session", "-t", sessionName)
+	attachCmd := tmux.Command("attach-session", "-t", sessionName)
 	attachCmd.Stdin = os.Stdin
 	attachCmd.Stdout = os.Stdout
 	attachCmd.Stderr = os.Stderr
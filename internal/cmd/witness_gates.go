@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Witness gates command flags
+var (
+	witnessGatesJSON   bool
+	witnessGatesChecks []string
+	witnessGatesBlock  []string
+)
+
+var witnessGatesCmd = &cobra.Command{
+	Use:   "gates <rig>",
+	Short: "Show the rig's quality gates",
+	Long: `Show the quality gates the Witness has published for a rig.
+
+Quality gates are per-rig completion requirements, stored in a town-level
+bead (hq-quality-gate-<rig>) so every tool sees the same rules:
+  - Required checks: shell commands that must pass in the polecat's worktree
+  - Blocked paths: files completed work must not touch
+
+'gt sling' shows the gates when dispatching work to the rig, 'gt done'
+refuses to complete work that fails them, and the Refinery rejects merge
+requests that touch blocked paths.
+
+Examples:
+  gt witness gates greenplace
+  gt witness gates set greenplace --check "go test ./..." --block "internal/secrets/**"
+  gt witness gates clear greenplace`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWitnessGatesShow,
+}
+
+var witnessGatesSetCmd = &cobra.Command{
+	Use:   "set <rig>",
+	Short: "Publish quality gates for a rig",
+	Long: `Publish quality gates for a rig, replacing any existing gates.
+
+Blocked path patterns:
+  dir/** or dir/   Block a directory tree
+  *.pem            No "/": match the file name anywhere
+  deploy/*.yaml    Match the full path (path.Match syntax)
+
+Examples:
+  gt witness gates set greenplace --check "go test ./..." --check "go vet ./..."
+  gt witness gates set greenplace --block "migrations/**" --block "*.pem"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWitnessGatesSet,
+}
+
+var witnessGatesClearCmd = &cobra.Command{
+	Use:   "clear <rig>",
+	Short: "Remove all quality gates for a rig",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWitnessGatesClear,
+}
+
+func init() {
+	witnessGatesCmd.Flags().BoolVar(&witnessGatesJSON, "json", false, "Output as JSON")
+	witnessGatesSetCmd.Flags().StringArrayVar(&witnessGatesChecks, "check", nil, "Required check command (can be repeated)")
+	witnessGatesSetCmd.Flags().StringArrayVar(&witnessGatesBlock, "block", nil, "Blocked path pattern (can be repeated)")
+
+	witnessGatesCmd.AddCommand(witnessGatesSetCmd)
+	witnessGatesCmd.AddCommand(witnessGatesClearCmd)
+	witnessCmd.AddCommand(witnessGatesCmd)
+}
+
+func runWitnessGatesShow(cmd *cobra.Command, args []string) error {
+	townRoot, _, err := getRig(args[0])
+	if err != nil {
+		return err
+	}
+
+	_, gates, err := beads.New(townRoot).GetQualityGates(args[0])
+	if err != nil {
+		return fmt.Errorf("loading quality gates: %w", err)
+	}
+
+	if witnessGatesJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(gates)
+	}
+
+	if gates.IsEmpty() {
+		fmt.Printf("%s No quality gates published for %s\n", style.Dim.Render("○"), args[0])
+		return nil
+	}
+
+	fmt.Printf("%s Quality gates for %s\n", style.Bold.Render("⚑"), args[0])
+	printQualityGates(gates)
+	if gates.UpdatedBy != "" {
+		fmt.Printf("  %s\n", style.Dim.Render(fmt.Sprintf("published by %s at %s", gates.UpdatedBy, gates.UpdatedAt)))
+	}
+	return nil
+}
+
+func runWitnessGatesSet(cmd *cobra.Command, args []string) error {
+	if len(witnessGatesChecks) == 0 && len(witnessGatesBlock) == 0 {
+		return fmt.Errorf("nothing to publish: use --check and/or --block (or 'gt witness gates clear')")
+	}
+	return publishQualityGates(args[0], witnessGatesChecks, witnessGatesBlock)
+}
+
+func runWitnessGatesClear(cmd *cobra.Command, args []string) error {
+	return publishQualityGates(args[0], nil, nil)
+}
+
+// publishQualityGates writes the rig's gate bead.
+func publishQualityGates(rigName string, checks, blocked []string) error {
+	townRoot, _, err := getRig(rigName)
+	if err != nil {
+		return err
+	}
+
+	gates := &beads.QualityGateFields{
+		Rig:            rigName,
+		RequiredChecks: checks,
+		BlockedPaths:   blocked,
+		UpdatedBy:      detectSender(),
+	}
+	if err := beads.New(townRoot).SetQualityGates(gates); err != nil {
+		return fmt.Errorf("publishing quality gates: %w", err)
+	}
+
+	if gates.IsEmpty() {
+		fmt.Printf("%s Cleared quality gates for %s\n", style.Bold.Render("✓"), rigName)
+		return nil
+	}
+	fmt.Printf("%s Published quality gates for %s\n", style.Bold.Render("✓"), rigName)
+	printQualityGates(gates)
+	return nil
+}
+
+func printQualityGates(gates *beads.QualityGateFields) {
+	for _, check := range gates.RequiredChecks {
+		fmt.Printf("  check: %s\n", check)
+	}
+	for _, pattern := range gates.BlockedPaths {
+		fmt.Printf("  blocked: %s\n", pattern)
+	}
+}
+
+// loadQualityGates returns the rig's published gates, or nil if it has none
+// or they can't be read (gates are advisory when the town beads are unavailable).
+func loadQualityGates(townRoot, rigName string) *beads.QualityGateFields {
+	_, gates, err := beads.New(townRoot).GetQualityGates(rigName)
+	if err != nil {
+		style.PrintWarning("could not load quality gates for %s: %v", rigName, err)
+		return nil
+	}
+	if gates.IsEmpty() {
+		return nil
+	}
+	return gates
+}
+
+// announceQualityGates tells the dispatcher which gates the assignee's work
+// will be held to when it runs 'gt done'.
+func announceQualityGates(townRoot, targetAgent string) {
+	rigName, _, ok := strings.Cut(targetAgent, "/")
+	if !ok {
+		return
+	}
+	gates := loadQualityGates(townRoot, rigName)
+	if gates == nil {
+		return
+	}
+	fmt.Printf("%s Quality gates for %s apply (enforced at gt done):\n", style.Bold.Render("⚑"), rigName)
+	printQualityGates(gates)
+}
+
+// enforceQualityGates verifies completed work against the rig's quality gates:
+// the branch must not change blocked paths relative to base, and every
+// required check must pass in workDir. Returns nil if the rig has no gates.
+func enforceQualityGates(townRoot, rigName string, g *git.Git, base, workDir string) error {
+	gates := loadQualityGates(townRoot, rigName)
+	if gates == nil {
+		return nil
+	}
+
+	if len(gates.BlockedPaths) > 0 {
+		changed, err := g.ChangedFiles(base, "HEAD")
+		if err != nil {
+			return fmt.Errorf("listing changed files against %s: %w", base, err)
+		}
+		if violations := gates.BlockedPathViolations(changed); len(violations) > 0 {
+			return fmt.Errorf("cannot complete: changes touch paths blocked by the %s quality gates:\n  %s\nRevert those changes, or use --status ESCALATED to ask the witness for an exception",
+				rigName, strings.Join(violations, "\n  "))
+		}
+	}
+
+	for _, check := range gates.RequiredChecks {
+		fmt.Printf("Running quality gate check: %s\n", check)
+		c := exec.Command("sh", "-c", check)
+		c.Dir = workDir
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("cannot complete: quality gate check %q failed: %w\nFix the failure and retry, or use --status ESCALATED", check, err)
+		}
+	}
+
+	fmt.Printf("%s Quality gates passed\n", style.Bold.Render("✓"))
+	return nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Workspace command flags
+var (
+	workspaceJSON   bool
+	workspaceDryRun bool
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:     "workspace",
+	GroupID: GroupWorkspace,
+	Short:   "Track agent workspace disk usage and clean workspaces",
+	RunE:    requireSubcommand,
+	Long: `Track agent workspace disk usage against quotas and clean workspaces.
+
+Crew and refinery workspaces accumulate build artifacts and dependencies.
+Quotas and cleanup commands are configured under "disk_quota" in
+mayor/daemon.json:
+
+  "disk_quota": {
+    "roles": {"crew": "5GB", "refinery": "10GB"},
+    "workspaces": {"gastown-crew-max": "20GB"},
+    "clean": ["rm -rf node_modules dist", "go clean -cache"],
+    "clean_before_restart": true
+  }
+
+The daemon measures workspaces with a quota hourly (check_interval) and
+lists those over quota in 'gt daemon status'. With clean_before_restart,
+an over-quota workspace is cleaned when its session is restarted or
+cycled.`,
+}
+
+var workspaceUsageCmd = &cobra.Command{
+	Use:   "usage [identity...]",
+	Short: "Show workspace disk usage against quotas",
+	Long: `Measure the disk usage of agent workspaces.
+
+Without arguments, every workspace with a quota is measured.
+
+Examples:
+  gt workspace usage
+  gt workspace usage gastown-crew-max --json`,
+	RunE: runWorkspaceUsage,
+}
+
+var workspaceCleanCmd = &cobra.Command{
+	Use:   "clean <identity>...",
+	Short: "Run the configured cleanup commands in workspaces",
+	Long: `Run the disk_quota.clean commands from mayor/daemon.json in each agent's
+workspace, in order, and report the space reclaimed. A failing command
+doesn't stop the ones after it.
+
+Cleanup runs while the agent's session keeps running; stop or cycle the
+agent first if the commands remove files it is using.
+
+Examples:
+  gt workspace clean gastown-crew-max
+  gt workspace clean gastown-refinery --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runWorkspaceClean,
+}
+
+func init() {
+	workspaceUsageCmd.Flags().BoolVar(&workspaceJSON, "json", false, "Output as JSON")
+	workspaceCleanCmd.Flags().BoolVar(&workspaceDryRun, "dry-run", false, "Show the commands without running them")
+	workspaceCleanCmd.Flags().BoolVar(&workspaceJSON, "json", false, "Output as JSON")
+
+	workspaceCmd.AddCommand(workspaceUsageCmd)
+	workspaceCmd.AddCommand(workspaceCleanCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+func runWorkspaceUsage(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+
+	identities := args
+	if len(identities) == 0 {
+		identities = ctl.QuotaIdentities()
+		if len(identities) == 0 {
+			return fmt.Errorf("no disk_quota configured in %s; name workspaces to measure", daemon.PatrolConfigFile(townRoot))
+		}
+	}
+
+	usage := make(map[string]*daemon.WorkspaceUsage)
+	for _, identity := range identities {
+		u, err := ctl.WorkspaceUsage(identity)
+		if err != nil {
+			if len(args) > 0 {
+				return err
+			}
+			continue // Workspace not created yet
+		}
+		usage[identity] = u
+	}
+
+	if workspaceJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(usage)
+	}
+
+	for _, identity := range identities {
+		u := usage[identity]
+		if u == nil {
+			continue
+		}
+		quota := style.Dim.Render("no quota")
+		marker := " "
+		if u.Quota > 0 {
+			quota = "of " + daemon.FormatSize(u.Quota)
+			if u.Over() {
+				marker = style.Bold.Render("⚠")
+			}
+		}
+		fmt.Printf("%s %-28s %8s %s\n", marker, identity, daemon.FormatSize(u.Bytes), quota)
+	}
+	return nil
+}
+
+func runWorkspaceClean(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+	}
+	ctl := daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+
+	var reports []*daemon.CleanReport
+	var failed int
+	for _, identity := range args {
+		report, err := ctl.CleanWorkspace(identity, workspaceDryRun)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		failed += report.Failed()
+		if workspaceJSON {
+			continue
+		}
+
+		if workspaceDryRun {
+			fmt.Printf("Would clean %s (%s, %s):\n", identity, report.Dir, daemon.FormatSize(report.Before))
+			for _, c := range report.Commands {
+				fmt.Printf("  %s\n", c.Command)
+			}
+			continue
+		}
+		fmt.Printf("%s Cleaned %s: %s -> %s\n", style.Bold.Render("✓"), identity,
+			daemon.FormatSize(report.Before), daemon.FormatSize(report.After))
+		for _, c := range report.Commands {
+			if c.Error == "" {
+				continue
+			}
+			fmt.Printf("  %s %s: %s\n", style.Bold.Render("⚠"), c.Command, c.Error)
+			if c.Output != "" {
+				fmt.Printf("    %s\n", style.Dim.Render(c.Output))
+			}
+		}
+	}
+
+	if workspaceJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d cleanup command(s) failed", failed)
+	}
+	return nil
+}
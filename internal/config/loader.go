@@ -212,6 +212,24 @@ func validateRigSettings(c *RigSettings) error {
 			return err
 		}
 	}
+	if c.Budget != nil {
+		if err := validateBudgetConfig(c.Budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateBudgetConfig validates a BudgetConfig.
+func validateBudgetConfig(c *BudgetConfig) error {
+	if c.MonthlyUSD < 0 {
+		return fmt.Errorf("%w: budget.monthly_usd must be non-negative", ErrMissingField)
+	}
+	for _, pct := range c.AlertThresholds {
+		if pct <= 0 {
+			return fmt.Errorf("%w: budget.alert_thresholds must be positive percentages", ErrMissingField)
+		}
+	}
 	return nil
 }
 
@@ -1487,6 +1505,16 @@ func GetDefaultFormula(rigPath string) string {
 	return settings.Workflow.DefaultFormula
 }
 
+// ResolveRigName returns the current name of a rig from rigs.json, following
+// the aliases of renamed rigs. Returns rigName if it isn't an alias.
+func ResolveRigName(townRoot, rigName string) string {
+	rigsConfig, err := LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil {
+		return rigName
+	}
+	return rigsConfig.ResolveRig(rigName)
+}
+
 // GetRigPrefix returns the beads prefix for a rig from rigs.json.
 // Falls back to "gt" if the rig isn't found or has no prefix configured.
 // townRoot is the path to the town directory (e.g., ~/gt).
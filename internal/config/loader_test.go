@@ -863,6 +863,21 @@ func TestRuntimeConfigBuildCommand(t *testing.T) {
 			rc:   &RuntimeConfig{Command: "", Args: nil},
 			want: "claude --dangerously-skip-permissions",
 		},
+		{
+			name: "model",
+			rc:   &RuntimeConfig{Model: "haiku"},
+			want: "claude --dangerously-skip-permissions --model haiku",
+		},
+		{
+			name: "permission mode replaces skip-permissions",
+			rc:   &RuntimeConfig{Model: "opus", PermissionMode: "acceptEdits"},
+			want: "claude --model opus --permission-mode acceptEdits",
+		},
+		{
+			name: "local model wrapper",
+			rc:   &RuntimeConfig{Provider: "generic", Command: "/opt/llm/run", Args: []string{}, Model: "qwen", PermissionMode: "plan"},
+			want: "/opt/llm/run --model qwen",
+		},
 	}
 
 	for _, tt := range tests {
@@ -2603,3 +2618,33 @@ func TestBuildStartupCommandWithAgentOverride_IncludesGTRoot(t *testing.T) {
 		t.Errorf("expected GT_ROOT=%s in command, got: %q", townRoot, cmd)
 	}
 }
+
+func TestRigsConfigAliases(t *testing.T) {
+	t.Parallel()
+	c := &RigsConfig{Rigs: map[string]RigEntry{"core": {}}}
+
+	c.AddAlias("gastown", "gt_core")
+	c.AddAlias("gt_core", "core")
+	for _, name := range []string{"gastown", "gt_core", "core"} {
+		if got := c.ResolveRig(name); got != "core" {
+			t.Errorf("ResolveRig(%q) = %q, want core", name, got)
+		}
+	}
+	if got := c.ResolveRig("other"); got != "other" {
+		t.Errorf("ResolveRig(other) = %q, want other", got)
+	}
+
+	// Reusing an aliased name drops the alias
+	c.AddAlias("core", "gastown")
+	if _, ok := c.Aliases["gastown"]; ok {
+		t.Error("alias gastown should be dropped once a rig is named gastown again")
+	}
+	if got := c.ResolveRig("gt_core"); got != "gastown" {
+		t.Errorf("ResolveRig(gt_core) = %q, want gastown", got)
+	}
+
+	var nilConfig *RigsConfig
+	if got := nilConfig.ResolveRig("gastown"); got != "gastown" {
+		t.Errorf("nil ResolveRig = %q, want gastown", got)
+	}
+}
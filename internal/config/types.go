@@ -48,6 +48,8 @@ type TownSettings struct {
 	// Keys are agent names that can be referenced by DefaultAgent or rig settings.
 	// Values override or extend the built-in presets.
 	// Example: {"gemini": {"command": "/custom/path/to/gemini"}}
+	// Per-role runners are custom agents referenced from RoleAgents, e.g.
+	// {"claude-haiku": {"command": "claude", "model": "haiku", "permission_mode": "acceptEdits"}}
 	Agents map[string]*RuntimeConfig `json:"agents,omitempty"`
 
 	// RoleAgents maps role names to agent aliases for per-role model selection.
@@ -152,6 +154,44 @@ const DefaultCrewName = "max"
 type RigsConfig struct {
 	Version int                 `json:"version"`
 	Rigs    map[string]RigEntry `json:"rigs"`
+
+	// Aliases maps the old names of renamed rigs to their current names, so
+	// identities, session names, and mail addresses using an old name still
+	// resolve. Written by 'gt rig rename'.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// ResolveRig returns the current name of a rig, following the aliases left
+// by renames. Names that aren't aliases are returned as-is.
+func (c *RigsConfig) ResolveRig(name string) string {
+	if c == nil {
+		return name
+	}
+	// Bounded by the alias count, so a cycle can't loop forever
+	for i := 0; i < len(c.Aliases); i++ {
+		next, ok := c.Aliases[name]
+		if !ok {
+			break
+		}
+		name = next
+	}
+	return name
+}
+
+// AddAlias records that rig oldName is now called newName. Existing aliases
+// to oldName are pointed at newName, and any alias named newName is dropped
+// since that name is in use again.
+func (c *RigsConfig) AddAlias(oldName, newName string) {
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	delete(c.Aliases, newName)
+	for alias, target := range c.Aliases {
+		if target == oldName {
+			c.Aliases[alias] = newName
+		}
+	}
+	c.Aliases[oldName] = newName
 }
 
 // RigEntry represents a single rig in the registry.
@@ -210,6 +250,7 @@ type RigSettings struct {
 	Crew       *CrewConfig       `json:"crew,omitempty"`        // crew startup settings
 	Workflow   *WorkflowConfig   `json:"workflow,omitempty"`    // workflow settings
 	Runtime    *RuntimeConfig    `json:"runtime,omitempty"`     // LLM runtime settings (deprecated: use Agent)
+	Budget     *BudgetConfig     `json:"budget,omitempty"`      // monthly spending budget
 
 	// Agent selects which agent preset to use for this rig.
 	// Can be a built-in preset ("claude", "gemini", "codex", "cursor", "auggie", "amp")
@@ -231,6 +272,31 @@ type RigSettings struct {
 	RoleAgents map[string]string `json:"role_agents,omitempty"`
 }
 
+// BudgetConfig represents a rig's monthly spending budget.
+// Spend is measured from the cost ledger (gt costs) for the current calendar month.
+type BudgetConfig struct {
+	// MonthlyUSD is the budget for the calendar month. Zero disables budgeting.
+	MonthlyUSD float64 `json:"monthly_usd"`
+
+	// AlertThresholds are percentages of MonthlyUSD at which the mayor is alerted.
+	// Each threshold alerts once per month. Default: 50, 80, 100.
+	AlertThresholds []int `json:"alert_thresholds,omitempty"`
+
+	// HardStop pauses non-critical agents once the budget is exhausted.
+	// They stay paused until an operator runs 'gt costs budget approve <rig>'.
+	HardStop bool `json:"hard_stop,omitempty"`
+
+	// CriticalRoles keep running when the budget is exhausted.
+	// Default: witness, refinery.
+	CriticalRoles []string `json:"critical_roles,omitempty"`
+}
+
+// DefaultBudgetAlertThresholds are the alert percentages used when none are configured.
+var DefaultBudgetAlertThresholds = []int{50, 80, 100}
+
+// DefaultBudgetCriticalRoles are the roles exempt from a budget hard stop.
+var DefaultBudgetCriticalRoles = []string{"witness", "refinery"}
+
 // CrewConfig represents crew workspace settings for a rig.
 type CrewConfig struct {
 	// Startup is a natural language instruction for which crew to start on boot.
@@ -267,6 +333,15 @@ type RuntimeConfig struct {
 	// Empty by default (hooks handle context).
 	InitialPrompt string `json:"initial_prompt,omitempty"`
 
+	// Model selects the model the runtime should use (e.g., "opus", "haiku").
+	// Rendered as "--model <model>". Empty uses the runtime's own default.
+	Model string `json:"model,omitempty"`
+
+	// PermissionMode sets the runtime's permission mode (claude only), e.g.
+	// "acceptEdits" or "plan". Rendered as "--permission-mode <mode>" in place
+	// of the default --dangerously-skip-permissions.
+	PermissionMode string `json:"permission_mode,omitempty"`
+
 	// PromptMode controls how prompts are passed to the runtime.
 	// Supported values: "arg" (append prompt arg), "none" (ignore prompt).
 	// Default: "arg" for claude/generic, "none" for codex.
@@ -337,7 +412,7 @@ func (rc *RuntimeConfig) BuildCommand() string {
 	resolved := normalizeRuntimeConfig(rc)
 
 	cmd := resolved.Command
-	args := resolved.Args
+	args := resolved.commandArgs()
 
 	// Combine command and args
 	if len(args) > 0 {
@@ -346,6 +421,30 @@ func (rc *RuntimeConfig) BuildCommand() string {
 	return cmd
 }
 
+// commandArgs returns Args with the model and permission mode flags applied.
+// A configured permission mode replaces --dangerously-skip-permissions, since
+// claude refuses both together.
+func (rc *RuntimeConfig) commandArgs() []string {
+	if rc.Model == "" && rc.PermissionMode == "" {
+		return rc.Args
+	}
+
+	args := make([]string, 0, len(rc.Args)+4)
+	for _, arg := range rc.Args {
+		if rc.PermissionMode != "" && rc.Provider == "claude" && arg == "--dangerously-skip-permissions" {
+			continue
+		}
+		args = append(args, arg)
+	}
+	if rc.Model != "" {
+		args = append(args, "--model", rc.Model)
+	}
+	if rc.PermissionMode != "" && rc.Provider == "claude" {
+		args = append(args, "--permission-mode", rc.PermissionMode)
+	}
+	return args
+}
+
 // BuildCommandWithPrompt returns the full command line with an initial prompt.
 // If the config has an InitialPrompt, it's appended as a quoted argument.
 // If prompt is provided, it overrides the config's InitialPrompt.
@@ -370,7 +469,7 @@ func (rc *RuntimeConfig) BuildCommandWithPrompt(prompt string) string {
 // BuildArgsWithPrompt returns the runtime command and args suitable for exec.
 func (rc *RuntimeConfig) BuildArgsWithPrompt(prompt string) []string {
 	resolved := normalizeRuntimeConfig(rc)
-	args := append([]string{resolved.Command}, resolved.Args...)
+	args := append([]string{resolved.Command}, resolved.commandArgs()...)
 
 	p := prompt
 	if p == "" {
@@ -757,6 +856,12 @@ type MessagingConfig struct {
 	// Like mailing lists but for tmux send-keys instead of durable mail.
 	// Example: {"workers": ["gastown/polecats/*", "gastown/crew/*"], "witnesses": ["*/witness"]}
 	NudgeChannels map[string][]string `json:"nudge_channels,omitempty"`
+
+	// Transport is the URL of a low-latency mail transport that pushes new
+	// mail to watching agents (nats://host:4222, redis://host:6379).
+	// Messages are still stored in beads; the transport only speeds up
+	// delivery. GT_MAIL_TRANSPORT overrides it.
+	Transport string `json:"transport,omitempty"`
 }
 
 // QueueConfig represents a work queue configuration.
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,8 +17,8 @@ import (
 	"github.com/steveyegge/gastown/internal/git"
 	"github.com/steveyegge/gastown/internal/rig"
 	"github.com/steveyegge/gastown/internal/session"
+	"github.com/steveyegge/gastown/internal/state"
 	"github.com/steveyegge/gastown/internal/tmux"
-	"github.com/steveyegge/gastown/internal/util"
 )
 
 // Common errors
@@ -82,16 +83,28 @@ func validateCrewName(name string) error {
 type Manager struct {
 	rig *rig.Rig
 	git *git.Git
+	out io.Writer // non-fatal warnings
 }
 
-// NewManager creates a new crew manager.
+// NewManager creates a new crew manager. Warnings go to stdout.
 func NewManager(r *rig.Rig, g *git.Git) *Manager {
 	return &Manager{
 		rig: r,
 		git: g,
+		out: os.Stdout,
 	}
 }
 
+// SetOutput sets where the manager writes non-fatal warnings.
+func (m *Manager) SetOutput(w io.Writer) {
+	m.out = w
+}
+
+// warnf writes a non-fatal warning.
+func (m *Manager) warnf(format string, args ...interface{}) {
+	fmt.Fprintf(m.out, "Warning: "+format+"\n", args...)
+}
+
 // crewDir returns the directory for a crew worker.
 func (m *Manager) crewDir(name string) string {
 	return filepath.Join(m.rig.Path, "crew", name)
@@ -133,7 +146,7 @@ func (m *Manager) Add(name string, createBranch bool) (*CrewWorker, error) {
 	// Clone the rig repo
 	if m.rig.LocalRepo != "" {
 		if err := m.git.CloneWithReference(m.rig.GitURL, crewPath, m.rig.LocalRepo); err != nil {
-			fmt.Printf("Warning: could not clone with local repo reference: %v\n", err)
+			m.warnf("could not clone with local repo reference: %v", err)
 			if err := m.git.Clone(m.rig.GitURL, crewPath); err != nil {
 				return nil, fmt.Errorf("cloning rig: %w", err)
 			}
@@ -149,7 +162,7 @@ func (m *Manager) Add(name string, createBranch bool) (*CrewWorker, error) {
 
 	// Optionally create a working branch
 	if createBranch {
-		branchName = fmt.Sprintf("crew/%s", name)
+		branchName = BranchName(name)
 		if err := crewGit.CreateBranch(branchName); err != nil {
 			_ = os.RemoveAll(crewPath) // best-effort cleanup
 			return nil, fmt.Errorf("creating branch: %w", err)
@@ -160,17 +173,29 @@ func (m *Manager) Add(name string, createBranch bool) (*CrewWorker, error) {
 		}
 	}
 
+	crew, err := m.provisionWorkspace(name, branchName, false)
+	if err != nil {
+		_ = os.RemoveAll(crewPath) // best-effort cleanup
+		return nil, err
+	}
+	return crew, nil
+}
+
+// provisionWorkspace sets up a freshly checked-out crew workspace (clone or
+// worktree) and writes its state file. The caller cleans up on error.
+func (m *Manager) provisionWorkspace(name, branchName string, worktree bool) (*CrewWorker, error) {
+	crewPath := m.crewDir(name)
+
 	// Create mail directory for mail delivery
 	mailPath := m.mailDir(name)
 	if err := os.MkdirAll(mailPath, 0755); err != nil {
-		_ = os.RemoveAll(crewPath) // best-effort cleanup
 		return nil, fmt.Errorf("creating mail dir: %w", err)
 	}
 
 	// Set up shared beads: crew uses rig's shared beads via redirect file
 	if err := m.setupSharedBeads(crewPath); err != nil {
 		// Non-fatal - crew can still work, warn but don't fail
-		fmt.Printf("Warning: could not set up shared beads: %v\n", err)
+		m.warnf("could not set up shared beads: %v", err)
 	}
 
 	// Provision PRIME.md with Gas Town context for this worker.
@@ -178,14 +203,14 @@ func (m *Manager) Add(name string, createBranch bool) (*CrewWorker, error) {
 	// always have GUPP and essential Gas Town context.
 	if err := beads.ProvisionPrimeMDForWorktree(crewPath); err != nil {
 		// Non-fatal - crew can still work via hook, warn but don't fail
-		fmt.Printf("Warning: could not provision PRIME.md: %v\n", err)
+		m.warnf("could not provision PRIME.md: %v", err)
 	}
 
 	// Copy overlay files from .runtime/overlay/ to crew root.
 	// This allows services to have .env and other config files at their root.
 	if err := rig.CopyOverlay(m.rig.Path, crewPath); err != nil {
 		// Non-fatal - log warning but continue
-		fmt.Printf("Warning: could not copy overlay files: %v\n", err)
+		m.warnf("could not copy overlay files: %v", err)
 	}
 
 	// NOTE: Slash commands (.claude/commands/) are provisioned at town level by gt install.
@@ -203,13 +228,13 @@ func (m *Manager) Add(name string, createBranch bool) (*CrewWorker, error) {
 		Rig:       m.rig.Name,
 		ClonePath: crewPath,
 		Branch:    branchName,
+		Worktree:  worktree,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
 	// Save state
 	if err := m.saveState(crew); err != nil {
-		_ = os.RemoveAll(crewPath) // best-effort cleanup
 		return nil, fmt.Errorf("saving state: %w", err)
 	}
 
@@ -240,9 +265,47 @@ func (m *Manager) Remove(name string, force bool) error {
 		return fmt.Errorf("removing crew dir: %w", err)
 	}
 
+	// Drop the worktree registration if this workspace was a worktree.
+	// Harmless for clones: prune only removes entries whose paths are gone.
+	if repoGit, err := m.repoBase(); err == nil {
+		_ = repoGit.WorktreePrune()
+	}
+
 	return nil
 }
 
+// archiveDir holds archived crew workspaces, under <rig>/crew. Dot-dirs
+// aren't valid crew names, so it never shows up as a crew member.
+const archiveDir = ".archive"
+
+// Archive moves a crew worker's workspace to
+// <rig>/crew/.archive/<name>-<timestamp> instead of deleting it, and returns
+// the archive path. Uncommitted work is kept as-is. An archived worktree's
+// registration is pruned, so its .git link no longer resolves; the files
+// and the crew/<name> branch remain.
+func (m *Manager) Archive(name string) (string, error) {
+	if err := validateCrewName(name); err != nil {
+		return "", err
+	}
+	if !m.exists(name) {
+		return "", ErrCrewNotFound
+	}
+
+	dir := filepath.Join(m.rig.Path, "crew", archiveDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating archive dir: %w", err)
+	}
+	dest := filepath.Join(dir, name+"-"+time.Now().UTC().Format("20060102-150405"))
+	if err := os.Rename(m.crewDir(name), dest); err != nil {
+		return "", fmt.Errorf("archiving crew dir: %w", err)
+	}
+
+	if repoGit, err := m.repoBase(); err == nil {
+		_ = repoGit.WorktreePrune()
+	}
+	return dest, nil
+}
+
 // List returns all crew workers in the rig.
 func (m *Manager) List() ([]*CrewWorker, error) {
 	crewBaseDir := filepath.Join(m.rig.Path, "crew")
@@ -283,10 +346,12 @@ func (m *Manager) Get(name string) (*CrewWorker, error) {
 	return m.loadState(name)
 }
 
-// saveState persists crew worker state to disk using atomic write.
+// saveState persists crew worker state to disk using an atomic read-modify-write.
 func (m *Manager) saveState(crew *CrewWorker) error {
+	// Merge rather than overwrite: state.json also carries the daemon's
+	// lifecycle fields (request flags, last kill).
 	stateFile := m.stateFile(crew.Name)
-	if err := util.AtomicWriteJSON(stateFile, crew); err != nil {
+	if err := state.MergeAgentStateFields(stateFile, crew); err != nil {
 		return fmt.Errorf("writing state: %w", err)
 	}
 
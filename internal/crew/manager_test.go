@@ -342,6 +342,50 @@ func TestManagerRemove(t *testing.T) {
 	}
 }
 
+func TestManagerArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	rigPath := filepath.Join(tmpDir, "test-rig")
+	if err := os.MkdirAll(rigPath, 0755); err != nil {
+		t.Fatalf("failed to create rig dir: %v", err)
+	}
+	bareRepoPath := filepath.Join(tmpDir, "bare-repo.git")
+	if err := runCmd("git", "init", "--bare", bareRepoPath); err != nil {
+		t.Fatalf("failed to create bare repo: %v", err)
+	}
+	mgr := NewManager(&rig.Rig{Name: "test-rig", Path: rigPath, GitURL: bareRepoPath}, git.NewGit(rigPath))
+
+	if _, err := mgr.Add("dana", false); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	notes := filepath.Join(mgr.crewDir("dana"), "notes.txt")
+	if err := os.WriteFile(notes, []byte("uncommitted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := mgr.Archive("dana")
+	if err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if _, err := mgr.Get("dana"); err != ErrCrewNotFound {
+		t.Errorf("expected ErrCrewNotFound after archive, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "notes.txt")); err != nil || string(data) != "uncommitted" {
+		t.Errorf("archived notes = %q, %v", data, err)
+	}
+	if filepath.Dir(dest) != filepath.Join(rigPath, "crew", archiveDir) {
+		t.Errorf("archive path = %s", dest)
+	}
+
+	// The archive dir is not a crew member
+	workers, err := mgr.List()
+	if err != nil || len(workers) != 0 {
+		t.Errorf("List() = %v, %v; want no workers", workers, err)
+	}
+	if _, err := mgr.Archive("dana"); err != ErrCrewNotFound {
+		t.Errorf("archiving twice: expected ErrCrewNotFound, got %v", err)
+	}
+}
+
 // Helper to run commands
 func runCmd(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
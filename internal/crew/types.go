@@ -17,6 +17,10 @@ type CrewWorker struct {
 	// Branch is the current git branch.
 	Branch string `json:"branch"`
 
+	// Worktree is true if the workspace is a git worktree of the rig's
+	// shared repo rather than a full clone.
+	Worktree bool `json:"worktree,omitempty"`
+
 	// CreatedAt is when the crew worker was created.
 	CreatedAt time.Time `json:"created_at"`
 
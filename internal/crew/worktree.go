@@ -0,0 +1,201 @@
+package crew
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/rig"
+)
+
+// branchPrefix is the namespace for crew working branches.
+const branchPrefix = "crew/"
+
+// BranchName returns the working branch for a crew member (crew/<name>).
+func BranchName(name string) string {
+	return branchPrefix + name
+}
+
+// repoBase returns the Git object to use for worktree operations.
+// Prefers the shared bare repo (.repo.git) if it exists, otherwise falls back
+// to mayor/rig - the same base polecat worktrees hang off.
+func (m *Manager) repoBase() (*git.Git, error) {
+	bareRepoPath := filepath.Join(m.rig.Path, ".repo.git")
+	if info, err := os.Stat(bareRepoPath); err == nil && info.IsDir() {
+		return git.NewGitWithDir(bareRepoPath, ""), nil
+	}
+
+	mayorPath := filepath.Join(m.rig.Path, "mayor", "rig")
+	if _, err := os.Stat(mayorPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no repo base found (neither .repo.git nor mayor/rig exists)")
+	}
+	return git.NewGit(mayorPath), nil
+}
+
+// defaultStartPoint returns origin/<default-branch> for new crew branches.
+func (m *Manager) defaultStartPoint() string {
+	defaultBranch := "main"
+	if rigCfg, err := rig.LoadRigConfig(m.rig.Path); err == nil && rigCfg.DefaultBranch != "" {
+		defaultBranch = rigCfg.DefaultBranch
+	}
+	return "origin/" + defaultBranch
+}
+
+// addWorktree checks out the crew member's branch as a worktree at its crew
+// dir, reusing the branch if it already exists (e.g. after the directory was
+// lost) and creating it from origin/<default> otherwise.
+func (m *Manager) addWorktree(repoGit *git.Git, name string) error {
+	// Clear registrations for deleted paths so git doesn't refuse the path
+	_ = repoGit.WorktreePrune()
+
+	crewPath := m.crewDir(name)
+	branchName := BranchName(name)
+
+	exists, err := repoGit.BranchExists(branchName)
+	if err != nil {
+		return fmt.Errorf("checking branch %s: %w", branchName, err)
+	}
+	if exists {
+		if err := repoGit.WorktreeAddExisting(crewPath, branchName); err != nil {
+			return fmt.Errorf("creating worktree for %s: %w", branchName, err)
+		}
+		return nil
+	}
+
+	if err := repoGit.Fetch("origin"); err != nil {
+		// Non-fatal - proceed with potentially stale code
+		m.warnf("could not fetch origin: %v", err)
+	}
+	startPoint := m.defaultStartPoint()
+	if err := repoGit.WorktreeAddFromRef(crewPath, branchName, startPoint); err != nil {
+		return fmt.Errorf("creating worktree from %s: %w", startPoint, err)
+	}
+	return nil
+}
+
+// AddWorktree creates a crew workspace as a git worktree of the rig's shared
+// repo on branch crew/<name>, instead of a full clone.
+func (m *Manager) AddWorktree(name string) (*CrewWorker, error) {
+	if err := validateCrewName(name); err != nil {
+		return nil, err
+	}
+	if m.exists(name) {
+		return nil, ErrCrewExists
+	}
+
+	repoGit, err := m.repoBase()
+	if err != nil {
+		return nil, fmt.Errorf("finding repo base: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(m.rig.Path, "crew"), 0755); err != nil {
+		return nil, fmt.Errorf("creating crew dir: %w", err)
+	}
+
+	if err := m.addWorktree(repoGit, name); err != nil {
+		return nil, err
+	}
+
+	crew, err := m.provisionWorkspace(name, BranchName(name), true)
+	if err != nil {
+		_ = repoGit.WorktreeRemove(m.crewDir(name), true) // best-effort cleanup
+		return nil, err
+	}
+	return crew, nil
+}
+
+// Repair recreates a crew workspace whose directory is missing, as a worktree
+// on its crew/<name> branch. An existing git checkout is left untouched.
+// Returns the worker and whether anything was repaired.
+func (m *Manager) Repair(name string) (*CrewWorker, bool, error) {
+	if err := validateCrewName(name); err != nil {
+		return nil, false, err
+	}
+
+	crewPath := m.crewDir(name)
+	if m.exists(name) {
+		if !git.NewGit(crewPath).IsRepo() {
+			return nil, false, fmt.Errorf("%s exists but is not a git checkout; remove it or move it aside", crewPath)
+		}
+		worker, err := m.loadState(name)
+		return worker, false, err
+	}
+
+	repoGit, err := m.repoBase()
+	if err != nil {
+		return nil, false, fmt.Errorf("finding repo base: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(m.rig.Path, "crew"), 0755); err != nil {
+		return nil, false, fmt.Errorf("creating crew dir: %w", err)
+	}
+	if err := m.addWorktree(repoGit, name); err != nil {
+		return nil, false, err
+	}
+
+	crew, err := m.provisionWorkspace(name, BranchName(name), true)
+	if err != nil {
+		_ = repoGit.WorktreeRemove(crewPath, true) // best-effort cleanup
+		return nil, false, err
+	}
+	return crew, true, nil
+}
+
+// WorktreeGCResult reports what PruneWorktrees cleaned up.
+type WorktreeGCResult struct {
+	// DeletedBranches are crew branches removed because their workspace was
+	// gone and the branch was fully merged into the default branch.
+	DeletedBranches []string `json:"deleted_branches,omitempty"`
+
+	// KeptBranches are orphaned crew branches left alone because they hold
+	// unmerged commits.
+	KeptBranches []string `json:"kept_branches,omitempty"`
+}
+
+// PruneWorktrees garbage-collects stale crew worktrees: it drops worktree
+// registrations whose directories are gone, then deletes crew/<name> branches
+// that no longer have a workspace and are fully merged. Unmerged orphaned
+// branches are kept and reported so no work is lost.
+func (m *Manager) PruneWorktrees() (*WorktreeGCResult, error) {
+	repoGit, err := m.repoBase()
+	if err != nil {
+		return nil, fmt.Errorf("finding repo base: %w", err)
+	}
+	if err := repoGit.WorktreePrune(); err != nil {
+		return nil, fmt.Errorf("pruning worktrees: %w", err)
+	}
+
+	branches, err := repoGit.ListBranches(branchPrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("listing crew branches: %w", err)
+	}
+
+	// Branches still checked out somewhere can't be deleted anyway
+	checkedOut := make(map[string]bool)
+	if worktrees, err := repoGit.WorktreeList(); err == nil {
+		for _, wt := range worktrees {
+			checkedOut[wt.Branch] = true
+		}
+	}
+
+	startPoint := m.defaultStartPoint()
+	result := &WorktreeGCResult{}
+	for _, branch := range branches {
+		name := strings.TrimPrefix(branch, branchPrefix)
+		if checkedOut[branch] || m.exists(name) {
+			continue
+		}
+
+		merged, err := repoGit.IsAncestor(branch, startPoint)
+		if err != nil || !merged {
+			result.KeptBranches = append(result.KeptBranches, branch)
+			continue
+		}
+		if err := repoGit.DeleteBranch(branch, true); err != nil {
+			result.KeptBranches = append(result.KeptBranches, branch)
+			continue
+		}
+		result.DeletedBranches = append(result.DeletedBranches, branch)
+	}
+	return result, nil
+}
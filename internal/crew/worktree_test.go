@@ -0,0 +1,139 @@
+package crew
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/rig"
+)
+
+// setupWorktreeRig creates a rig whose mayor/rig is a clone of a source repo
+// with one commit on main, so origin/main exists for worktree start points.
+func setupWorktreeRig(t *testing.T) *Manager {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	sourceRepoPath := filepath.Join(tmpDir, "source-repo")
+	rigPath := filepath.Join(tmpDir, "test-rig")
+	cmds := [][]string{
+		{"git", "init", "-b", "main", sourceRepoPath},
+		{"git", "-C", sourceRepoPath, "config", "user.email", "test@test.com"},
+		{"git", "-C", sourceRepoPath, "config", "user.name", "Test"},
+		{"git", "-C", sourceRepoPath, "commit", "--allow-empty", "-m", "Initial commit"},
+		{"git", "clone", sourceRepoPath, filepath.Join(rigPath, "mayor", "rig")},
+	}
+	for _, cmd := range cmds {
+		if err := runCmd(cmd[0], cmd[1:]...); err != nil {
+			t.Fatalf("failed to run %v: %v", cmd, err)
+		}
+	}
+
+	r := &rig.Rig{
+		Name:   "test-rig",
+		Path:   rigPath,
+		GitURL: sourceRepoPath,
+	}
+	return NewManager(r, git.NewGit(rigPath))
+}
+
+func TestManagerAddWorktree(t *testing.T) {
+	mgr := setupWorktreeRig(t)
+
+	worker, err := mgr.AddWorktree("gina")
+	if err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if worker.Branch != "crew/gina" {
+		t.Errorf("expected branch 'crew/gina', got '%s'", worker.Branch)
+	}
+	if !worker.Worktree {
+		t.Error("expected worker to be marked as a worktree")
+	}
+
+	branch, err := git.NewGit(worker.ClonePath).CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch failed: %v", err)
+	}
+	if branch != "crew/gina" {
+		t.Errorf("expected checkout on 'crew/gina', got '%s'", branch)
+	}
+
+	if _, err := mgr.AddWorktree("gina"); err != ErrCrewExists {
+		t.Errorf("expected ErrCrewExists, got %v", err)
+	}
+}
+
+func TestManagerWarningsGoToOutput(t *testing.T) {
+	mgr := setupWorktreeRig(t)
+	if err := runCmd("git", "-C", filepath.Join(mgr.rig.Path, "mayor", "rig"), "remote", "set-url", "origin", filepath.Join(t.TempDir(), "gone")); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	mgr.SetOutput(&out)
+
+	if _, err := mgr.AddWorktree("gina"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: could not fetch origin") {
+		t.Errorf("output = %q, want the fetch warning", out.String())
+	}
+}
+
+func TestManagerRepair(t *testing.T) {
+	mgr := setupWorktreeRig(t)
+
+	worker, err := mgr.AddWorktree("hank")
+	if err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	// Intact workspace is left alone
+	if _, repaired, err := mgr.Repair("hank"); err != nil || repaired {
+		t.Fatalf("expected no repair for intact workspace, got repaired=%v err=%v", repaired, err)
+	}
+
+	// Lose the directory, then repair it from the existing branch
+	if err := os.RemoveAll(worker.ClonePath); err != nil {
+		t.Fatalf("failed to remove workspace: %v", err)
+	}
+	repairedWorker, repaired, err := mgr.Repair("hank")
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if !repaired {
+		t.Error("expected missing workspace to be repaired")
+	}
+	if _, err := os.Stat(filepath.Join(repairedWorker.ClonePath, "state.json")); err != nil {
+		t.Errorf("expected state.json after repair: %v", err)
+	}
+}
+
+func TestManagerPruneWorktrees(t *testing.T) {
+	mgr := setupWorktreeRig(t)
+
+	if _, err := mgr.AddWorktree("ivy"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if _, err := mgr.AddWorktree("jack"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+	if err := mgr.Remove("ivy", true); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	result, err := mgr.PruneWorktrees()
+	if err != nil {
+		t.Fatalf("PruneWorktrees failed: %v", err)
+	}
+	if !slices.Contains(result.DeletedBranches, "crew/ivy") {
+		t.Errorf("expected crew/ivy to be deleted, got %+v", result)
+	}
+	if slices.Contains(result.DeletedBranches, "crew/jack") {
+		t.Errorf("crew/jack still has a workspace and must be kept, got %+v", result)
+	}
+}
@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Agent lifecycle hook names. Hooks live in <workdir>/.runtime/lifecycle-hooks/
+// and let a single agent specialize role-generic lifecycle behavior (flush a
+// scratch file before a cycle, warm a cache after start) without editing
+// central config.
+const (
+	HookPreCycle    = "pre-cycle.sh"    // before the session is killed for a cycle or restart
+	HookPreShutdown = "pre-shutdown.sh" // before the session is killed for a shutdown
+	HookPostStart   = "post-start.sh"   // after the session has been started
+	HookSmokeTest   = "smoke-test.sh"   // after a restart; failing it fails the restart
+)
+
+const (
+	// defaultAgentHookTimeout bounds how long a hook may run before it is killed.
+	defaultAgentHookTimeout = 30 * time.Second
+
+	// maxHookOutputLog caps how much hook output is copied into the daemon log.
+	maxHookOutputLog = 2048
+)
+
+// AgentHooksDir returns the lifecycle hook directory for an agent working directory.
+func AgentHooksDir(workDir string) string {
+	return filepath.Join(workDir, ".runtime", "lifecycle-hooks")
+}
+
+// preKillHook returns the hook to run before killing a session for action,
+// or "" if the action doesn't kill sessions.
+func preKillHook(action LifecycleAction) string {
+	switch action {
+	case ActionShutdown:
+		return HookPreShutdown
+	case ActionCycle, ActionRestart:
+		return HookPreCycle
+	}
+	return ""
+}
+
+// agentHookTimeout returns the configured hook timeout.
+func (d *Daemon) agentHookTimeout() time.Duration {
+	cfg := d.patrolConfig.lifecycleConfig()
+	if cfg.HookTimeout == "" {
+		return defaultAgentHookTimeout
+	}
+	timeout, err := time.ParseDuration(cfg.HookTimeout)
+	if err != nil || timeout <= 0 {
+		d.logger.Printf("Warning: invalid lifecycle.hook_timeout %q, using %v", cfg.HookTimeout, defaultAgentHookTimeout)
+		return defaultAgentHookTimeout
+	}
+	return timeout
+}
+
+// runAgentHook runs one of an agent's lifecycle hooks, if present. Hook
+// failures are logged with the hook's output but never block the lifecycle
+// action: a broken hook must not leave an agent unable to cycle.
+func (d *Daemon) runAgentHook(identity, sessionName, hook string, action LifecycleAction) {
+	config, parsed, err := d.getRoleConfigForIdentity(identity)
+	if err != nil {
+		return
+	}
+	workDir := d.getWorkDir(config, parsed)
+	if workDir == "" {
+		return
+	}
+
+	env := []string{
+		"GT_AGENT=" + identity,
+		"GT_SESSION=" + sessionName,
+		"GT_LIFECYCLE_ACTION=" + string(action),
+		"GT_TOWN_ROOT=" + d.config.TownRoot,
+	}
+
+	// A role plugin's hooks apply to every agent of the role and run first
+	if p := lookupRolePlugin(parsed.RoleType); p != nil {
+		output, err := runHookScript(filepath.Join(p.HooksDir(), hook), workDir, env, d.agentHookTimeout())
+		d.logHookResult(identity, p.Name+" role hook "+hook, output, err)
+	}
+	output, err := runAgentHookScript(workDir, hook, env, d.agentHookTimeout())
+	d.logHookResult(identity, "hook "+hook, output, err)
+}
+
+// logHookResult logs the outcome of a hook run; missing hooks are silent.
+func (d *Daemon) logHookResult(identity, what, output string, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if output != "" {
+		d.logger.Printf("Output of %s for %s:\n%s", what, identity, truncateHookOutput(output))
+	}
+	if err != nil {
+		d.logger.Printf("Warning: %s for %s failed: %v", what, identity, err)
+		return
+	}
+	d.logger.Printf("Ran %s for %s", what, identity)
+}
+
+// runAgentHookScript executes <workDir>/.runtime/lifecycle-hooks/<hook> with
+// workDir as its working directory, returning its combined output. Returns an
+// error wrapping os.ErrNotExist if the hook isn't there, and refuses hooks that
+// aren't executable.
+func runAgentHookScript(workDir, hook string, env []string, timeout time.Duration) (string, error) {
+	return runHookScript(filepath.Join(AgentHooksDir(workDir), hook), workDir, env, timeout)
+}
+
+// runHookScript executes the hook at hookPath in workDir; see runAgentHookScript.
+func runHookScript(hookPath, workDir string, env []string, timeout time.Duration) (string, error) {
+	info, err := os.Stat(hookPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory: %w", hookPath, os.ErrNotExist)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%s is not executable (use chmod +x)", hookPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hookPath)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), env...)
+	// Don't wait on grandchildren holding the output pipe after a timeout kill
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err = cmd.Run()
+	output := strings.TrimSpace(out.String())
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("timed out after %v", timeout)
+	}
+	return output, err
+}
+
+// truncateHookOutput keeps the tail of long hook output, where errors usually are.
+func truncateHookOutput(output string) string {
+	if len(output) <= maxHookOutputLog {
+		return output
+	}
+	return "..." + output[len(output)-maxHookOutputLog:]
+}
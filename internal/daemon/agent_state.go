@@ -0,0 +1,347 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/state"
+	"github.com/steveyegge/gastown/pkg/eventstream"
+)
+
+// Agent state files (<workdir>/state.json, see state.AgentState) carry
+// lifecycle request flags: an agent sets requesting_cycle/_shutdown plus
+// requesting_time before mailing the daemon, so a request whose mail never
+// arrived can still be recovered instead of leaving the agent waiting forever.
+// After killing a session the daemon records last_killed_* there so the next
+// session's startup hook can tell it was cycled and why. Towns can keep this
+// state on agent beads or in a sqlite database instead (agent_state in
+// mayor/daemon.json, see state.Backend); everything here goes through the
+// configured backend.
+
+// defaultStaleFlagThreshold is how long a requesting flag may go unanswered
+// before the reaper treats it as orphaned.
+const defaultStaleFlagThreshold = 10 * time.Minute
+
+// Stale flag reaper actions (LifecycleConfig.StaleFlagAction).
+const (
+	staleFlagActionClear   = "clear"
+	staleFlagActionExecute = "execute"
+)
+
+// agentStateFile returns the path to an agent's state file.
+func agentStateFile(workDir string) string {
+	return state.AgentStatePath(workDir)
+}
+
+// agentStates returns the town's agent state backend. An invalid config is
+// logged and falls back to state files, which agents always have.
+func (d *Daemon) agentStates() state.Backend {
+	var cfg *state.BackendConfig
+	if d.patrolConfig != nil {
+		cfg = d.patrolConfig.AgentState
+	}
+	backend, err := state.OpenBackend(d.config.TownRoot, cfg)
+	if err != nil {
+		d.logger.Printf("Warning: %v, using state files", err)
+		return state.FileBackend{}
+	}
+	return backend
+}
+
+// agentRef locates identity's state for any backend. workDir is the
+// identity's working directory if the caller already resolved it.
+func (d *Daemon) agentRef(identity, workDir string) state.AgentRef {
+	if workDir == "" {
+		workDir = d.agentWorkDir(identity)
+	}
+	return state.AgentRef{Identity: identity, WorkDir: workDir, BeadID: d.identityToAgentBeadID(identity)}
+}
+
+// readAgentState reads identity's state from the configured backend.
+// Returns nil with no error if it has none.
+func (d *Daemon) readAgentState(identity string) (*state.AgentState, error) {
+	return d.agentStates().Read(d.agentRef(identity, ""))
+}
+
+// requestedAction returns the lifecycle action an agent state file is
+// requesting, if any. Shutdown wins over cycle when both are set.
+func requestedAction(s *state.AgentState) (LifecycleAction, bool) {
+	switch {
+	case s == nil:
+		return "", false
+	case s.RequestingShutdown:
+		return ActionShutdown, true
+	case s.RequestingCycle:
+		return ActionCycle, true
+	}
+	return "", false
+}
+
+// staleRequest reports whether the state holds a requesting flag older than
+// threshold. A flag with no requesting_time is treated as stale, since
+// nothing else will ever clear it.
+func staleRequest(s *state.AgentState, now time.Time, threshold time.Duration) (LifecycleAction, bool) {
+	action, ok := requestedAction(s)
+	if !ok {
+		return "", false
+	}
+	if s.RequestingTime.IsZero() {
+		return action, true
+	}
+	return action, now.Sub(s.RequestingTime) > threshold
+}
+
+// managedIdentities lists the daemon identities of every agent the town layout
+// implies: mayor, deacon, each rig's witness, refinery, crew, and polecats,
+// and the agents of role plugins.
+func (d *Daemon) managedIdentities() []string {
+	identities := []string{"mayor", "deacon"}
+	for _, rigName := range d.getKnownRigs() {
+		identities = append(identities, rigName+"-witness", rigName+"-refinery")
+
+		crewNames, _ := listPolecatWorktrees(filepath.Join(d.config.TownRoot, rigName, "crew"))
+		for _, name := range crewNames {
+			identities = append(identities, rigName+"-crew-"+name)
+		}
+
+		polecatNames, _ := listPolecatWorktrees(filepath.Join(d.config.TownRoot, rigName, "polecats"))
+		for _, name := range polecatNames {
+			identities = append(identities, rigName+"-polecat-"+name)
+		}
+	}
+	return append(identities, d.pluginIdentities()...)
+}
+
+// reapStaleRequestFlags finds requesting_* flags that were never answered.
+// It runs after lifecycle mail processing, which clears the flag of every
+// request it claims. A flag still set past the threshold means the agent's
+// mail send failed, unless its mail is still waiting in the deacon inbox
+// (throttled, rate limited, or held for a handoff), so agents with pending
+// lifecycle mail are skipped, and the reaper does nothing when the inbox
+// can't be read. Depending on config the reaper either clears the flag and
+// tells the agent to re-request, or executes the request itself.
+func (d *Daemon) reapStaleRequestFlags() {
+	cfg := d.patrolConfig.lifecycleConfig()
+
+	threshold := defaultStaleFlagThreshold
+	if cfg.StaleFlagThreshold != "" {
+		if parsed, err := time.ParseDuration(cfg.StaleFlagThreshold); err == nil {
+			threshold = parsed
+		} else {
+			d.logger.Printf("Warning: invalid lifecycle.stale_flag_threshold %q, using %v", cfg.StaleFlagThreshold, threshold)
+		}
+	}
+
+	// Role config is looked up per role type, so resolve it once per pass.
+	roleConfigs := make(map[string]*beads.RoleConfig)
+	states := d.agentStates()
+	now := time.Now()
+	var pending map[string]bool // fetched once a stale flag is found
+
+	for _, identity := range d.managedIdentities() {
+		parsed, err := parseIdentity(identity)
+		if err != nil {
+			continue
+		}
+		roleConfig, cached := roleConfigs[parsed.RoleType]
+		if !cached {
+			roleConfig, _, _ = d.getRoleConfigForIdentity(identity)
+			roleConfigs[parsed.RoleType] = roleConfig
+		}
+
+		workDir := d.getWorkDir(roleConfig, parsed)
+		if workDir == "" {
+			continue
+		}
+		ref := d.agentRef(identity, workDir)
+		agentState, err := states.Read(ref)
+		if err != nil {
+			d.logger.Printf("Warning: cannot read agent state for %s: %v", identity, err)
+			continue
+		}
+
+		action, stale := staleRequest(agentState, now, threshold)
+		if !stale {
+			continue
+		}
+		if pending == nil {
+			if pending, err = d.pendingLifecycleSenders(); err != nil {
+				d.logger.Printf("Warning: not reaping stale request flags, deacon inbox unreadable: %v", err)
+				return
+			}
+		}
+		if pending[identity] || pending[identityToBDActor(identity)] {
+			continue // Its mail is waiting to be processed
+		}
+
+		d.logger.Printf("Stale %s request flag for %s (no lifecycle mail within %v)", action, identity, threshold)
+		if d.config.DryRun {
+			if cfg.StaleFlagAction == staleFlagActionExecute {
+				d.logger.Printf("[dry-run] Would clear the flag and execute %s for %s", action, identity)
+			} else {
+				d.logger.Printf("[dry-run] Would clear the flag and notify %s", identity)
+			}
+			continue
+		}
+
+		var request *LifecycleRequest
+		if cfg.StaleFlagAction == staleFlagActionExecute {
+			request = &LifecycleRequest{
+				From:        identity,
+				Action:      action,
+				Timestamp:   now,
+				Reason:      fmt.Sprintf("unanswered requesting_%s flag", action),
+				RequestedBy: requestedByStaleFlag,
+			}
+			if !d.admitRestart(request) {
+				continue // Leave the flag for a later heartbeat
+			}
+		}
+
+		// Claim then execute, as with mail: clear the flag first so a failed
+		// action isn't retried on every heartbeat.
+		if err := states.Update(ref, func(s *state.AgentState) error {
+			s.ClearRequest()
+			return nil
+		}); err != nil {
+			d.logger.Printf("Warning: failed to clear stale request flag for %s: %v", identity, err)
+			continue
+		}
+
+		if request != nil {
+			if err := d.executeLifecycleAction(request); err != nil {
+				d.logger.Printf("Error executing reaped %s for %s: %v", action, identity, err)
+			}
+			continue
+		}
+		d.notifyAgentOfClearedFlag(identity, action, threshold)
+	}
+}
+
+// pendingLifecycleSenders returns the senders of the unread lifecycle mail
+// in the deacon inbox.
+func (d *Daemon) pendingLifecycleSenders() (map[string]bool, error) {
+	messages, err := d.deaconInbox()
+	if err != nil {
+		return nil, err
+	}
+	senders := make(map[string]bool)
+	for _, msg := range messages {
+		if !msg.Read && strings.HasPrefix(strings.ToLower(msg.Subject), lifecycleSubjectPrefix) {
+			senders[msg.From] = true
+		}
+	}
+	return senders, nil
+}
+
+// agentWorkDir resolves the working directory for an identity, or "".
+func (d *Daemon) agentWorkDir(identity string) string {
+	roleConfig, parsed, err := d.getRoleConfigForIdentity(identity)
+	if err != nil {
+		return ""
+	}
+	return d.getWorkDir(roleConfig, parsed)
+}
+
+// clearAgentRequestFlags clears any requesting flags for an identity once the
+// daemon has claimed its lifecycle request. Missing state is fine.
+func (d *Daemon) clearAgentRequestFlags(identity string) {
+	states := d.agentStates()
+	ref := d.agentRef(identity, "")
+	agentState, err := states.Read(ref)
+	if err != nil {
+		return
+	}
+	if _, ok := requestedAction(agentState); !ok {
+		return
+	}
+
+	if err := states.Update(ref, func(s *state.AgentState) error {
+		s.ClearRequest()
+		return nil
+	}); err != nil {
+		d.logger.Printf("Warning: failed to clear request flags for %s: %v", identity, err)
+	}
+}
+
+// notifyAgentOfClearedFlag tells an agent its stale lifecycle request was dropped.
+func (d *Daemon) notifyAgentOfClearedFlag(identity string, action LifecycleAction, threshold time.Duration) {
+	addr := identityToBDActor(identity)
+	subject := fmt.Sprintf("LIFECYCLE_FLAG_CLEARED: stale %s request", action)
+	body := fmt.Sprintf(`Your state file had requesting_%s set for more than %v,
+but the daemon never received a matching lifecycle mail.
+
+The flag has been cleared. If you still need this action, request it again.`,
+		action, threshold)
+
+	if err := d.mailClient().Send(addr, subject, body); err != nil {
+		d.logger.Printf("Warning: failed to notify %s of cleared request flag: %v", addr, err)
+	} else {
+		d.logger.Printf("Cleared stale %s request flag for %s and notified agent", action, identity)
+	}
+}
+
+// KillRecord describes the daemon's most recent kill of an agent's session.
+type KillRecord struct {
+	At     time.Time
+	By     string
+	Action LifecycleAction
+}
+
+// ReadKillRecord returns the last kill the daemon recorded in the agent
+// state of identity (working in workDir), or nil if none was recorded.
+// identity may be "" when it is unknown; only the file backend finds the
+// state then.
+func ReadKillRecord(townRoot, identity, workDir string) (*KillRecord, error) {
+	d := NewWithBackends(&Config{TownRoot: townRoot}, Backends{}, log.New(io.Discard, "", 0))
+	ref := state.AgentRef{Identity: identity, WorkDir: workDir}
+	if identity != "" {
+		ref.BeadID = d.identityToAgentBeadID(identity)
+	}
+	s, err := d.agentStates().Read(ref)
+	if err != nil || s == nil || s.LastKilledAt.IsZero() {
+		return nil, err
+	}
+	return &KillRecord{At: s.LastKilledAt, By: s.LastKilledBy, Action: LifecycleAction(s.LastKillAction)}, nil
+}
+
+// InitAgentState creates an empty state record for identity in the town's
+// configured backend if it has none, so agents and tooling find one from
+// the first session. Existing state is left as it is.
+func InitAgentState(townRoot, identity string) error {
+	d := NewWithBackends(&Config{TownRoot: townRoot}, Backends{}, log.New(io.Discard, "", 0))
+	return d.agentStates().Update(d.agentRef(identity, ""), func(*state.AgentState) error { return nil })
+}
+
+// recordKill notes in the agent's state that the daemon killed its session,
+// who asked for it, and why. The write is an atomic read-modify-write so
+// agent-owned fields are preserved. Failures are logged, not fatal: the kill
+// has already happened.
+func (d *Daemon) recordKill(identity string, action LifecycleAction, requestedBy string) {
+	d.publish(eventstream.TypeAgentState, identity, map[string]any{
+		"to":           "stopped",
+		"reason":       string(action),
+		"requested_by": requestedBy,
+	})
+
+	err := d.agentStates().Update(d.agentRef(identity, ""), func(s *state.AgentState) error {
+		s.LastKilledAt = time.Now().UTC().Truncate(time.Second)
+		s.LastKilledBy = requestedBy
+		s.LastKillAction = string(action)
+		s.ContextUsage, s.ContextReportedAt = 0, time.Time{} // The next session starts empty
+		return nil
+	})
+	if errors.Is(err, state.ErrNoLocation) || errors.Is(err, fs.ErrNotExist) {
+		return // No workdir or agent bead - nowhere for the next session to look
+	}
+	if err != nil {
+		d.logger.Printf("Warning: failed to record kill for %s: %v", identity, err)
+	}
+}
@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+func TestStaleRequest(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	threshold := 10 * time.Minute
+	old := now.Add(-time.Hour)
+	recent := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		state      *state.AgentState
+		wantAction LifecycleAction
+		wantStale  bool
+	}{
+		{"no flags", &state.AgentState{}, "", false},
+		{"nil state", nil, "", false},
+		{"recent cycle", &state.AgentState{RequestingCycle: true, RequestingTime: recent}, ActionCycle, false},
+		{"old cycle", &state.AgentState{RequestingCycle: true, RequestingTime: old}, ActionCycle, true},
+		{"old shutdown", &state.AgentState{RequestingShutdown: true, RequestingTime: old}, ActionShutdown, true},
+		{"flag without time", &state.AgentState{RequestingCycle: true}, ActionCycle, true},
+		{"shutdown wins", &state.AgentState{RequestingCycle: true, RequestingShutdown: true, RequestingTime: old}, ActionShutdown, true},
+	}
+
+	for _, tc := range tests {
+		action, stale := staleRequest(tc.state, now, threshold)
+		if stale != tc.wantStale {
+			t.Errorf("%s: stale = %v, want %v", tc.name, stale, tc.wantStale)
+		}
+		if tc.wantStale && action != tc.wantAction {
+			t.Errorf("%s: action = %q, want %q", tc.name, action, tc.wantAction)
+		}
+	}
+}
+
+func TestManagedIdentities(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"gastown/crew/max", "gastown/polecats/Toast", "gastown/crew/.hidden"} {
+		if err := os.MkdirAll(filepath.Join(townRoot, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := d.managedIdentities()
+	for _, want := range []string{"mayor", "deacon", "gastown-witness", "gastown-refinery", "gastown-crew-max", "gastown-polecat-Toast"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("managedIdentities() missing %q: %v", want, got)
+		}
+	}
+	if slices.Contains(got, "gastown-crew-.hidden") {
+		t.Errorf("managedIdentities() should skip hidden dirs: %v", got)
+	}
+}
+
+func TestReadKillRecord(t *testing.T) {
+	townRoot, workDir := t.TempDir(), t.TempDir()
+
+	rec, err := ReadKillRecord(townRoot, "", workDir)
+	if err != nil || rec != nil {
+		t.Fatalf("expected nil record for missing state, got %v, %v", rec, err)
+	}
+
+	content := `{"name":"max","last_killed_at":"2026-01-02T03:04:05Z","last_killed_by":"gastown-crew-max","last_kill_action":"cycle"}`
+	if err := os.WriteFile(agentStateFile(workDir), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err = ReadKillRecord(townRoot, "", workDir)
+	if err != nil {
+		t.Fatalf("ReadKillRecord: %v", err)
+	}
+	if rec == nil {
+		t.Fatal("expected kill record")
+	}
+	if rec.By != "gastown-crew-max" || rec.Action != ActionCycle {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+	if !rec.At.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected time: %v", rec.At)
+	}
+}
+
+func TestPendingLifecycleActions_StateFlag(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.UpdateAgentState(agentStateFile(workDir), func(s *state.AgentState) error {
+		s.RequestingCycle = true
+		s.RequestingTime = time.Now()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The inbox fetch may fail without gt installed; state flags are still reported.
+	pending, _ := PendingLifecycleActions(townRoot, "gt-gastown-crew-max")
+	if len(pending) != 1 || pending[0].Action != ActionCycle || pending[0].Source != "state_flag" {
+		t.Errorf("PendingLifecycleActions() = %+v, expected one state_flag cycle", pending)
+	}
+
+	pending, _ = PendingLifecycleActions(townRoot, "gt-gastown-witness")
+	if len(pending) != 0 {
+		t.Errorf("PendingLifecycleActions(witness) = %+v, expected none", pending)
+	}
+}
+
+func TestReapStaleRequestFlags(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.UpdateAgentState(agentStateFile(workDir), func(s *state.AgentState) error {
+		s.RequestingCycle = true
+		s.RequestingTime = time.Now().Add(-time.Hour)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d.beads = &hibernateBeads{}
+	mail := &inboxMail{messages: []BeadsMessage{{ID: "msg-1", From: "gastown-crew-max", Subject: "LIFECYCLE: cycle"}}}
+	d.mail = mail
+	flagSet := func() bool {
+		s, err := state.ReadAgentState(agentStateFile(workDir))
+		return err == nil && s.RequestingCycle
+	}
+
+	// Its lifecycle mail is still waiting in the deacon inbox
+	d.reapStaleRequestFlags()
+	if !flagSet() || len(mail.sent) != 0 {
+		t.Fatalf("flag reaped with its mail pending (sent %v)", mail.sent)
+	}
+
+	// A dry run only logs
+	mail.messages = nil
+	d.config.DryRun = true
+	d.reapStaleRequestFlags()
+	if !flagSet() || len(mail.sent) != 0 {
+		t.Fatalf("flag reaped in a dry run (sent %v)", mail.sent)
+	}
+
+	d.config.DryRun = false
+	d.reapStaleRequestFlags()
+	if flagSet() || len(mail.sent) != 1 {
+		t.Errorf("flag not cleared and agent not notified (sent %v)", mail.sent)
+	}
+}
@@ -0,0 +1,414 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/pkg/eventstream"
+)
+
+// APIConfig enables the daemon's HTTP API, configured under "api" in
+// mayor/daemon.json:
+//
+//	"api": {"listen": "127.0.0.1:7474", "require_auth": true}
+//
+// The API is off unless listen is set. Requests authenticate with an API
+// token (see api_tokens.go) as "Authorization: Bearer <token>". POST
+// /lifecycle always requires one; the event stream requires a token with
+// the read scope only with require_auth, so without it bind the API to
+// loopback or a trusted network. GET /healthz (see health.go) is open.
+type APIConfig struct {
+	// Listen is the host:port to serve on.
+	Listen string `json:"listen,omitempty"`
+
+	// RequireAuth requires a read-scoped token for the event stream.
+	RequireAuth bool `json:"require_auth,omitempty"`
+
+	// EventBuffer is how many recent events are kept for subscribers that
+	// reconnect (default 256).
+	EventBuffer int `json:"event_buffer,omitempty"`
+}
+
+const (
+	defaultEventBuffer = 256
+
+	// subscriberQueue is how many events a slow subscriber may fall behind
+	// before it is disconnected (it then resumes from the buffer).
+	subscriberQueue = 64
+
+	// eventKeepalive is how often an idle stream gets a comment line, so
+	// proxies and clients can tell a quiet daemon from a dead connection.
+	eventKeepalive = 15 * time.Second
+)
+
+// eventBus fans daemon events out to stream subscribers and keeps the most
+// recent ones for replay. Publishing never blocks the daemon.
+type eventBus struct {
+	mu     sync.Mutex
+	seq    int64
+	recent []eventstream.Event // oldest first, at most size
+	size   int
+	subs   map[chan eventstream.Event]bool
+}
+
+func newEventBus(size int) *eventBus {
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+	return &eventBus{size: size, subs: make(map[chan eventstream.Event]bool)}
+}
+
+// publish records an event and hands it to every subscriber. A subscriber
+// whose queue is full is dropped.
+func (b *eventBus) publish(ev eventstream.Event) eventstream.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	ev.Seq = b.seq
+	b.recent = append(b.recent, ev)
+	if len(b.recent) > b.size {
+		b.recent = b.recent[len(b.recent)-b.size:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// subscribe returns the buffered events after lastSeq and a channel for the
+// events that follow. A lastSeq beyond the current sequence comes from a
+// previous daemon process; everything buffered is replayed then.
+func (b *eventBus) subscribe(lastSeq int64) ([]eventstream.Event, chan eventstream.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastSeq > b.seq {
+		lastSeq = 0
+	}
+	var backlog []eventstream.Event
+	for _, ev := range b.recent {
+		if ev.Seq > lastSeq {
+			backlog = append(backlog, ev)
+		}
+	}
+	ch := make(chan eventstream.Event, subscriberQueue)
+	b.subs[ch] = true
+	return backlog, ch
+}
+
+func (b *eventBus) unsubscribe(ch chan eventstream.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[ch] {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// subscribers returns how many streams are following the bus.
+func (b *eventBus) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// events returns the daemon's event bus, creating it on first use.
+func (d *Daemon) events() *eventBus {
+	d.eventsOnce.Do(func() {
+		size := 0
+		if d.patrolConfig != nil && d.patrolConfig.API != nil {
+			size = d.patrolConfig.API.EventBuffer
+		}
+		d.eventBus = newEventBus(size)
+	})
+	return d.eventBus
+}
+
+// publish emits a structured event to dashboards following /events. Events
+// published during a traced operation carry its trace_id.
+func (d *Daemon) publish(eventType, identity string, data map[string]any) {
+	if traceID, _ := d.traceFields(); traceID != "" {
+		if data == nil {
+			data = make(map[string]any)
+		}
+		data["trace_id"] = traceID
+	}
+	d.events().publish(eventstream.Event{
+		Time:     time.Now().UTC(),
+		Type:     eventType,
+		Identity: identity,
+		Data:     data,
+	})
+}
+
+// apiHandler returns the daemon API's routes.
+func (d *Daemon) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", d.serveHealth)
+	mux.HandleFunc("GET /events", d.serveEvents)
+	mux.HandleFunc("POST /lifecycle", d.serveLifecycle)
+	return mux
+}
+
+// apiToken authenticates the request's bearer token. Returns nil after
+// writing the error response if there is no valid token.
+func (d *Daemon) apiToken(w http.ResponseWriter, r *http.Request) *APIToken {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return nil
+	}
+	token, err := AuthenticateToken(d.config.TownRoot, strings.TrimSpace(secret))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil
+	}
+	return token
+}
+
+// apiLifecycleBody is the body of POST /lifecycle.
+type apiLifecycleBody struct {
+	// Target is the agent to act on (default: the token's identity).
+	Target string   `json:"target,omitempty"`
+	Action string   `json:"action"`
+	DryRun bool     `json:"dry_run,omitempty"`
+	Notify []string `json:"notify,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+
+	TaskBead string `json:"task_bead,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+}
+
+// serveLifecycle queues a lifecycle action for the next heartbeat. The
+// token must allow the action on the target. The result is mailed to the
+// token's identity, the target, and the notify list, as for lifecycle mail.
+func (d *Daemon) serveLifecycle(w http.ResponseWriter, r *http.Request) {
+	token := d.apiToken(w, r)
+	if token == nil {
+		return
+	}
+	var body apiLifecycleBody
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&body); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	action, ok := parseLifecycleAction(body.Action)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown action %q", body.Action), http.StatusBadRequest)
+		return
+	}
+	target := body.Target
+	if target == "" {
+		target = token.Identity
+	}
+	target = normalizeIdentity(strings.TrimSuffix(target, "/"))
+	if err := token.AuthorizeLifecycle(target); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if d.identityToSession(target) == "" {
+		http.Error(w, fmt.Sprintf("%v: %s", ErrUnknownIdentity, target), http.StatusBadRequest)
+		return
+	}
+
+	request := &LifecycleRequest{
+		RequestID:   fmt.Sprintf("api-%s-%d", token.ID, time.Now().UnixNano()),
+		From:        target,
+		Action:      action,
+		Timestamp:   time.Now(),
+		DryRun:      body.DryRun,
+		Notify:      d.parseNotifyList(token.Identity, append([]string{token.Identity}, body.Notify...)),
+		Reason:      body.Reason,
+		TaskBead:    body.TaskBead,
+		Urgency:     d.parseUrgency(token.Identity, body.Urgency),
+		RequestedBy: token.Identity,
+	}
+	d.apiMu.Lock()
+	d.apiQueue = append(d.apiQueue, queuedLifecycle{request: request, requestedBy: token.Identity})
+	d.apiMu.Unlock()
+	d.logger.Printf("Queued %s of %s from API (token %s, %s)", action, target, token.ID, token.Identity)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"request_id": request.RequestID,
+		"target":     target,
+		"action":     string(action),
+	})
+}
+
+// queuedLifecycle is a lifecycle request accepted by the API.
+type queuedLifecycle struct {
+	request     *LifecycleRequest
+	requestedBy string // identity of the token it was sent with
+}
+
+// processAPILifecycleRequests runs the lifecycle actions queued through the
+// API. Throttled restarts stay queued for the next heartbeat.
+func (d *Daemon) processAPILifecycleRequests() {
+	d.apiMu.Lock()
+	queue := d.apiQueue
+	d.apiQueue = nil
+	d.apiMu.Unlock()
+
+	var deferred []queuedLifecycle
+	for _, queued := range queue {
+		request := queued.request
+		if !d.admitRestart(request) {
+			deferred = append(deferred, queued)
+			continue
+		}
+		d.logger.Printf("Processing API lifecycle request %s: %s %s", request.RequestID, request.Action, request.From)
+		err := d.executeLifecycleAction(request)
+		if err != nil {
+			d.logger.Printf("Error executing lifecycle action: %v", err)
+		}
+		d.replyLifecycleResult(request, queued.requestedBy, err)
+	}
+	if len(deferred) > 0 {
+		d.apiMu.Lock()
+		d.apiQueue = append(deferred, d.apiQueue...)
+		d.apiMu.Unlock()
+	}
+}
+
+// serveEvents streams events as server-sent events. ?types=a,b filters by
+// type; Last-Event-ID resumes after an event still in the buffer.
+func (d *Daemon) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if d.patrolConfig != nil && d.patrolConfig.API != nil && d.patrolConfig.API.RequireAuth {
+		token := d.apiToken(w, r)
+		if token == nil {
+			return
+		}
+		if !token.HasScope(ScopeRead) {
+			http.Error(w, fmt.Sprintf("token %s lacks the %s scope", token.ID, ScopeRead), http.StatusForbidden)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var types []string
+	if t := r.URL.Query().Get("types"); t != "" {
+		types = strings.Split(t, ",")
+	}
+	var lastSeq int64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+			lastSeq = n
+		}
+	}
+
+	backlog, ch := d.events().subscribe(lastSeq)
+	defer d.events().unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev eventstream.Event) error {
+		if len(types) > 0 && !slices.Contains(types, ev.Type) {
+			return nil
+		}
+		return eventstream.WriteEvent(w, ev)
+	}
+	for _, ev := range backlog {
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return // Fell behind; the client resumes from the buffer
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// startAPI serves the daemon API if one is configured.
+func (d *Daemon) startAPI() {
+	if d.patrolConfig == nil || d.patrolConfig.API == nil || d.patrolConfig.API.Listen == "" {
+		return
+	}
+	ln, err := net.Listen("tcp", d.patrolConfig.API.Listen)
+	if err != nil {
+		d.logger.Printf("Warning: daemon API disabled: %v", err)
+		return
+	}
+	d.apiServer = &http.Server{
+		Handler:           d.apiHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return d.ctx },
+	}
+	go func() {
+		if err := d.apiServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			d.logger.Printf("Warning: daemon API stopped: %v", err)
+		}
+	}()
+	d.logger.Printf("Daemon API listening on %s", ln.Addr())
+}
+
+// stopAPI closes the API listener and any open streams.
+func (d *Daemon) stopAPI() {
+	if d.apiServer == nil {
+		return
+	}
+	_ = d.apiServer.Close()
+	d.logger.Println("Daemon API stopped")
+}
+
+// publishAgentState emits an agent state transition.
+func (d *Daemon) publishAgentState(identity, session, to, reason string) {
+	d.publish(eventstream.TypeAgentState, identity, map[string]any{
+		"to":      to,
+		"session": session,
+		"reason":  reason,
+	})
+}
+
+// publishSyncWarning emits a sync warning for a workspace.
+func (d *Daemon) publishSyncWarning(workDir, message string) {
+	d.publish(eventstream.TypeSyncWarning, "", map[string]any{"workdir": workDir, "message": message})
+}
+
+// syncWarning logs a sync warning and publishes it to the event stream.
+func (d *Daemon) syncWarning(workDir, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	d.logger.Printf("Warning: %s", message)
+	d.publishSyncWarning(workDir, message)
+}
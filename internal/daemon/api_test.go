@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/pkg/eventstream"
+)
+
+func TestEventBusReplayAndFanout(t *testing.T) {
+	b := newEventBus(3)
+	for i := 0; i < 5; i++ {
+		b.publish(eventstream.Event{Type: eventstream.TypeMailProcessed})
+	}
+
+	backlog, ch := b.subscribe(0)
+	if len(backlog) != 3 || backlog[0].Seq != 3 || backlog[2].Seq != 5 {
+		t.Errorf("backlog = %+v, want seq 3..5 (buffer of 3)", backlog)
+	}
+	if backlog, _ := b.subscribe(4); len(backlog) != 1 || backlog[0].Seq != 5 {
+		t.Errorf("resume after 4 = %+v", backlog)
+	}
+	// An id from a previous daemon process replays the whole buffer
+	if backlog, _ := b.subscribe(900); len(backlog) != 3 {
+		t.Errorf("resume after foreign id = %d events, want 3", len(backlog))
+	}
+
+	b.publish(eventstream.Event{Type: eventstream.TypeSyncWarning})
+	if ev := <-ch; ev.Seq != 6 || ev.Type != eventstream.TypeSyncWarning {
+		t.Errorf("fanned out %+v", ev)
+	}
+
+	// A subscriber that stops reading is dropped instead of blocking
+	for i := 0; i < subscriberQueue+1; i++ {
+		b.publish(eventstream.Event{Type: eventstream.TypeMailProcessed})
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != subscriberQueue {
+		t.Errorf("slow subscriber got %d events before being dropped, want %d", n, subscriberQueue)
+	}
+}
+
+func TestEventsEndpoint(t *testing.T) {
+	d := testDaemon()
+	srv := httptest.NewServer(d.apiHandler())
+	defer srv.Close()
+
+	d.publish(eventstream.TypeLifecycleStarted, "gastown-crew-max", map[string]any{"action": "restart"})
+	d.publish(eventstream.TypeMailProcessed, "gastown-crew-max", map[string]any{"message_id": "hq-1"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := &eventstream.Client{URL: srv.URL, Types: []string{eventstream.TypeAgentState, eventstream.TypeLifecycleStarted}}
+
+	var got []eventstream.Event
+	err := c.Stream(ctx, func(ev eventstream.Event) error {
+		got = append(got, ev)
+		if len(got) == 1 {
+			// Live events follow the buffered ones
+			d.publishAgentState("gastown-crew-max", "gt-gastown-crew-max", "running", "started")
+			return nil
+		}
+		return eventstream.ErrStop
+	})
+	if err != nil {
+		t.Fatalf("Stream = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	if got[0].Type != eventstream.TypeLifecycleStarted || got[0].Data["action"] != "restart" {
+		t.Errorf("first event = %+v", got[0])
+	}
+	if got[1].Type != eventstream.TypeAgentState || got[1].Seq != 3 || got[1].Data["to"] != "running" {
+		t.Errorf("second event = %+v (mail_processed should be filtered out)", got[1])
+	}
+}
+
+func TestLifecycleAndMailEvents(t *testing.T) {
+	d := testDaemon()
+	_ = d.executeLifecycleAction(&LifecycleRequest{From: "not-an-identity", Action: ActionRestart})
+	backlog, _ := d.events().subscribe(0)
+	if len(backlog) != 0 {
+		t.Errorf("unresolvable identity published %+v", backlog)
+	}
+
+	if err := d.closeMessage("hq-msg-1", "gastown-crew-max", "stale"); err == nil {
+		t.Skip("gt available; mail delete succeeded")
+	}
+	backlog, _ = d.events().subscribe(0)
+	if len(backlog) != 1 || backlog[0].Type != eventstream.TypeMailProcessed || backlog[0].Data["error"] == nil {
+		t.Errorf("closeMessage published %+v", backlog)
+	}
+}
@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// API tokens authenticate callers of the daemon API and, when
+// lifecycle.require_token is set, of lifecycle mail. Each token belongs to
+// one identity and carries scopes:
+//
+//	read            follow the event stream
+//	lifecycle-self  request lifecycle actions on its own session
+//	lifecycle-any   request lifecycle actions on any session (mayor, deacon)
+//
+// Only a SHA-256 hash of each token is stored, so reading the token store
+// doesn't reveal usable tokens. A crew agent holding a lifecycle-self token
+// can cycle itself but can't shut down the mayor.
+
+// Token scopes.
+const (
+	ScopeRead          = "read"
+	ScopeLifecycleSelf = "lifecycle-self"
+	ScopeLifecycleAny  = "lifecycle-any"
+)
+
+// TokenScopes lists the valid scopes.
+var TokenScopes = []string{ScopeRead, ScopeLifecycleSelf, ScopeLifecycleAny}
+
+// tokensKey is the town store key of the token store.
+const tokensKey = "daemon/api-tokens.json"
+
+// tokenPrefix starts every token, so leaked tokens are easy to grep for.
+const tokenPrefix = "gtk_"
+
+// APIToken is a stored token. The secret itself is never stored.
+type APIToken struct {
+	ID        string    `json:"id"`
+	Identity  string    `json:"identity"`
+	Scopes    []string  `json:"scopes"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// Expired reports whether the token has expired at now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
+}
+
+// HasScope reports whether the token carries scope. lifecycle-any implies
+// lifecycle-self.
+func (t *APIToken) HasScope(scope string) bool {
+	if scope == ScopeLifecycleSelf && slices.Contains(t.Scopes, ScopeLifecycleAny) {
+		return true
+	}
+	return slices.Contains(t.Scopes, scope)
+}
+
+// AuthorizeLifecycle checks that the token may request a lifecycle action
+// on target: lifecycle-self for its own identity, lifecycle-any otherwise.
+func (t *APIToken) AuthorizeLifecycle(target string) error {
+	scope := ScopeLifecycleAny
+	if sameIdentity(t.Identity, target) {
+		scope = ScopeLifecycleSelf
+	}
+	if !t.HasScope(scope) {
+		return fmt.Errorf("%w: token %s (%s) lacks %s for %s", ErrUnauthorized, t.ID, t.Identity, scope, target)
+	}
+	return nil
+}
+
+// canonicalIdentity maps the daemon and mail forms of an identity
+// ("gastown-witness", "gastown/witness", "mayor", "mayor/") to one form.
+func canonicalIdentity(identity string) string {
+	return identityToMailAddress(normalizeIdentity(strings.TrimSuffix(identity, "/")))
+}
+
+// sameIdentity reports whether a and b name the same agent.
+func sameIdentity(a, b string) bool {
+	return canonicalIdentity(a) == canonicalIdentity(b)
+}
+
+// hashToken returns the stored hash of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadTokens returns the town's API tokens, sorted by creation time.
+func LoadTokens(townRoot string) ([]APIToken, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(tokensKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var tokens []APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing token store: %w", err)
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// saveTokens replaces the town's API tokens.
+func saveTokens(townRoot string, tokens []APIToken) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(tokensKey, data)
+}
+
+// MintToken creates a token for identity with the given scopes. ttl of 0
+// means the token doesn't expire. The returned secret is shown once; only
+// its hash is stored.
+func MintToken(townRoot, identity string, scopes []string, ttl time.Duration) (string, *APIToken, error) {
+	if identity == "" {
+		return "", nil, fmt.Errorf("token identity is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(TokenScopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope %q (valid: %s)", scope, strings.Join(TokenScopes, ", "))
+		}
+	}
+
+	idBytes := make([]byte, 4)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+	secret := tokenPrefix + id + "_" + hex.EncodeToString(secretBytes)
+
+	now := time.Now().UTC()
+	token := APIToken{
+		ID:        id,
+		Identity:  canonicalIdentity(identity),
+		Scopes:    scopes,
+		Hash:      hashToken(secret),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl)
+	}
+
+	tokens, err := LoadTokens(townRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := saveTokens(townRoot, append(tokens, token)); err != nil {
+		return "", nil, err
+	}
+	return secret, &token, nil
+}
+
+// RevokeToken deletes the token with the given ID.
+func RevokeToken(townRoot, id string) error {
+	tokens, err := LoadTokens(townRoot)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(tokens, func(t APIToken) bool { return t.ID == id })
+	if i < 0 {
+		return fmt.Errorf("no token with ID %s", id)
+	}
+	return saveTokens(townRoot, slices.Delete(tokens, i, i+1))
+}
+
+// AuthenticateToken returns the stored token matching secret. Unknown,
+// malformed, and expired tokens are ErrUnauthorized.
+func AuthenticateToken(townRoot, secret string) (*APIToken, error) {
+	rest, ok := strings.CutPrefix(secret, tokenPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+	id, _, _ := strings.Cut(rest, "_")
+
+	tokens, err := LoadTokens(townRoot)
+	if err != nil {
+		return nil, fmt.Errorf("loading tokens: %w", err)
+	}
+	hash := hashToken(secret)
+	for i := range tokens {
+		t := &tokens[i]
+		if t.ID != id || subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if t.Expired(time.Now()) {
+			return nil, fmt.Errorf("%w: token %s expired", ErrUnauthorized, t.ID)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: unknown token", ErrUnauthorized)
+}
+
+// authorizeLifecycle checks a lifecycle request's token when
+// lifecycle.require_token is set. Every target of a batch must be allowed.
+func (d *Daemon) authorizeLifecycle(request *LifecycleRequest) error {
+	if !d.patrolConfig.lifecycleConfig().RequireToken {
+		return nil
+	}
+	if request.Token == "" {
+		return fmt.Errorf("%w: lifecycle.require_token is set and the request has no token", ErrUnauthorized)
+	}
+	token, err := AuthenticateToken(d.config.TownRoot, request.Token)
+	if err != nil {
+		return err
+	}
+	if request.Action != ActionBatch {
+		return token.AuthorizeLifecycle(request.From)
+	}
+	for _, item := range request.Batch {
+		if err := token.AuthorizeLifecycle(item.From); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMintAuthenticateRevokeToken(t *testing.T) {
+	townRoot := t.TempDir()
+
+	secret, token, err := MintToken(townRoot, "gastown-crew-max", []string{ScopeLifecycleSelf}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, tokenPrefix+token.ID+"_") {
+		t.Errorf("secret %q doesn't carry the token ID %s", secret, token.ID)
+	}
+	if token.Identity != "gastown/crew/max" {
+		t.Errorf("Identity = %q, want gastown/crew/max", token.Identity)
+	}
+
+	stored, err := LoadTokens(townRoot)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("LoadTokens = %v, %v", stored, err)
+	}
+	if strings.Contains(stored[0].Hash, secret) || stored[0].Hash != hashToken(secret) {
+		t.Error("token store must hold only the hash")
+	}
+
+	got, err := AuthenticateToken(townRoot, secret)
+	if err != nil || got.ID != token.ID {
+		t.Fatalf("AuthenticateToken = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "nope", tokenPrefix + token.ID + "_wrong", secret + "x"} {
+		if _, err := AuthenticateToken(townRoot, bad); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("AuthenticateToken(%q) = %v, want ErrUnauthorized", bad, err)
+		}
+	}
+
+	if err := RevokeToken(townRoot, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticateToken(townRoot, secret); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("revoked token authenticated: %v", err)
+	}
+}
+
+func TestMintTokenValidation(t *testing.T) {
+	townRoot := t.TempDir()
+	if _, _, err := MintToken(townRoot, "mayor", nil, 0); err == nil {
+		t.Error("expected error for no scopes")
+	}
+	if _, _, err := MintToken(townRoot, "mayor", []string{"admin"}, 0); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+
+	secret, _, err := MintToken(townRoot, "mayor", []string{ScopeRead}, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := AuthenticateToken(townRoot, secret); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expired token authenticated: %v", err)
+	}
+}
+
+func TestAuthorizeLifecycleScopes(t *testing.T) {
+	crew := &APIToken{ID: "a", Identity: "gastown/crew/max", Scopes: []string{ScopeLifecycleSelf}}
+	mayor := &APIToken{ID: "b", Identity: "mayor/", Scopes: []string{ScopeLifecycleAny}}
+	reader := &APIToken{ID: "c", Identity: "gastown/witness", Scopes: []string{ScopeRead}}
+
+	tests := []struct {
+		token  *APIToken
+		target string
+		ok     bool
+	}{
+		{crew, "gastown-crew-max", true},
+		{crew, "gastown/crew/max", true},
+		{crew, "mayor", false},
+		{crew, "gastown-crew-joe", false},
+		{mayor, "mayor/", true},
+		{mayor, "gastown-crew-max", true},
+		{reader, "gastown-witness", false},
+	}
+	for _, tt := range tests {
+		err := tt.token.AuthorizeLifecycle(tt.target)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s.AuthorizeLifecycle(%s) = %v, want ok=%v", tt.token.Identity, tt.target, err, tt.ok)
+		}
+		if err != nil && ErrorCode(err) != ErrorCodeUnauthorized {
+			t.Errorf("ErrorCode = %q, want %q", ErrorCode(err), ErrorCodeUnauthorized)
+		}
+	}
+}
+
+func TestAuthorizeLifecycleMail(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	crewSecret, _, err := MintToken(d.config.TownRoot, "gastown-crew-max", []string{ScopeLifecycleSelf}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shutdownMayor := &LifecycleRequest{From: "mayor/", Action: ActionShutdown, Token: crewSecret}
+	if err := d.authorizeLifecycle(shutdownMayor); err != nil {
+		t.Errorf("tokens not required: authorizeLifecycle = %v", err)
+	}
+
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{RequireToken: true}}
+	if err := d.authorizeLifecycle(shutdownMayor); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("crew token shutting down the mayor: %v, want ErrUnauthorized", err)
+	}
+	if err := d.authorizeLifecycle(&LifecycleRequest{From: "gastown/crew/max", Action: ActionCycle}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("request without token: %v, want ErrUnauthorized", err)
+	}
+	if err := d.authorizeLifecycle(&LifecycleRequest{From: "gastown/crew/max", Action: ActionCycle, Token: crewSecret}); err != nil {
+		t.Errorf("crew cycling itself: %v", err)
+	}
+
+	batch := &LifecycleRequest{From: "mayor/", Action: ActionBatch, Token: crewSecret,
+		Batch: []LifecycleRequest{{From: "gastown-crew-max", Action: ActionCycle}, {From: "gastown-witness", Action: ActionRestart}}}
+	if err := d.authorizeLifecycle(batch); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("batch touching another agent with a self token: %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestLifecycleEndpointAuth(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	crewSecret, _, err := MintToken(d.config.TownRoot, "gastown-crew-max", []string{ScopeLifecycleSelf}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(d.apiHandler())
+	defer srv.Close()
+
+	post := func(token, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/lifecycle", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("", `{"action": "cycle"}`); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	if code := post(crewSecret, `{"target": "mayor", "action": "shutdown"}`); code != http.StatusForbidden {
+		t.Errorf("crew shutting down mayor: status %d, want 403", code)
+	}
+	if code := post(crewSecret, `{"action": "explode"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown action: status %d, want 400", code)
+	}
+	if code := post(crewSecret, `{"action": "cycle"}`); code != http.StatusAccepted {
+		t.Errorf("crew cycling itself: status %d, want 202", code)
+	}
+
+	d.apiMu.Lock()
+	defer d.apiMu.Unlock()
+	if len(d.apiQueue) != 1 {
+		t.Fatalf("queued %d requests, want 1", len(d.apiQueue))
+	}
+	if q := d.apiQueue[0]; q.request.From != "gastown-crew-max" || q.request.Action != ActionCycle || q.requestedBy != "gastown/crew/max" {
+		t.Errorf("queued %+v by %s", q.request, q.requestedBy)
+	}
+}
+
+func TestEventsRequireAuth(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	d.patrolConfig = &DaemonPatrolConfig{API: &APIConfig{RequireAuth: true}}
+	selfSecret, _, err := MintToken(d.config.TownRoot, "gastown-crew-max", []string{ScopeLifecycleSelf}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(d.apiHandler())
+	defer srv.Close()
+
+	for token, want := range map[string]int{"": http.StatusUnauthorized, selfSecret: http.StatusForbidden} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("token %q: status %d, want %d", token, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// The audit log records every destructive operation the daemon performs:
+// session kills, daemon state writes, and mail deletions. Each record names
+// who asked for it, what was verified first, and the outcome.
+//
+// The log is tamper-evident: each record carries the SHA-256 of its own
+// content chained to the previous record's hash, so editing, deleting, or
+// reordering a line breaks verification from that point on. It is a plain
+// append-only file (not a town store key) so that external tools can ship it
+// somewhere write-once.
+
+// Audited operations.
+const (
+	AuditKillSession = "kill_session"
+	AuditStateWrite  = "state_write"
+	AuditMailDelete  = "mail_delete"
+)
+
+// Audit outcomes.
+const (
+	AuditOutcomeOK     = "ok"
+	AuditOutcomeFailed = "failed"
+)
+
+// auditGenesisHash is the PrevHash of the first record.
+const auditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// auditTailWindow is how much of the end of the log is read to find the last
+// record when appending.
+const auditTailWindow = 64 * 1024
+
+// AuditRecord is one audited operation.
+type AuditRecord struct {
+	Seq         int64     `json:"seq"`
+	Time        time.Time `json:"ts"`
+	Op          string    `json:"op"`
+	Target      string    `json:"target"`
+	RequestedBy string    `json:"requested_by"`
+
+	// Verified lists the checks that passed before the operation ran.
+	Verified []string `json:"verified,omitempty"`
+
+	// Reason, TaskBead, and Urgency are the context given with the
+	// lifecycle request the operation was done for, if any.
+	Reason   string `json:"reason,omitempty"`
+	TaskBead string `json:"task_bead,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+
+	// TraceID and SpanID link the record to the operation's trace when the
+	// daemon traces lifecycle operations.
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash returns the chained hash of the record: SHA-256 over PrevHash
+// and the JSON encoding of the record with Hash cleared.
+func (r AuditRecord) computeHash() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r) // AuditRecord always marshals
+	sum := sha256.Sum256(append([]byte(r.PrevHash), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditLogFile returns the path to the audit log.
+func AuditLogFile(townRoot string) string {
+	return filepath.Join(townRoot, "daemon", "audit.jsonl")
+}
+
+// AppendAudit chains rec onto the audit log and writes it. Seq, PrevHash, and
+// Hash are filled in; Time defaults to now. A lock file serializes the daemon
+// and CLI commands that act through SessionController.
+func AppendAudit(townRoot string, rec AuditRecord) (*AuditRecord, error) {
+	path := AuditLogFile(townRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating audit dir: %w", err)
+	}
+
+	lock := flock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("locking audit log: %w", err)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	last, err := lastAuditRecord(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Seq = 1
+	rec.PrevHash = auditGenesisHash
+	if last != nil {
+		rec.Seq = last.Seq + 1
+		rec.PrevHash = last.Hash
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Time = rec.Time.UTC()
+	rec.Hash = rec.computeHash()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("writing audit log: %w", err)
+	}
+	return &rec, nil
+}
+
+// lastAuditRecord reads the final record of the log, or nil if it is empty.
+func lastAuditRecord(path string) (*AuditRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - auditTailWindow
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return nil, nil
+	}
+	if i := bytes.LastIndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	var rec AuditRecord
+	if err := json.Unmarshal(tail, &rec); err != nil {
+		return nil, fmt.Errorf("audit log tail is corrupt: %w", err)
+	}
+	return &rec, nil
+}
+
+// LoadAuditLog reads every record in the audit log.
+func LoadAuditLog(townRoot string) ([]AuditRecord, error) {
+	f, err := os.Open(AuditLogFile(townRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return records, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// AuditChainError reports where the audit chain breaks.
+type AuditChainError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at seq %d: %s", e.Seq, e.Reason)
+}
+
+// VerifyAuditChain checks that records form an unbroken hash chain starting
+// at the genesis hash. It returns an *AuditChainError for the first bad record.
+func VerifyAuditChain(records []AuditRecord) error {
+	prev := auditGenesisHash
+	var seq int64
+	for _, rec := range records {
+		seq++
+		switch {
+		case rec.Seq != seq:
+			return &AuditChainError{Seq: rec.Seq, Reason: fmt.Sprintf("expected seq %d", seq)}
+		case rec.PrevHash != prev:
+			return &AuditChainError{Seq: rec.Seq, Reason: "previous hash does not match"}
+		case rec.computeHash() != rec.Hash:
+			return &AuditChainError{Seq: rec.Seq, Reason: "record hash does not match its content"}
+		}
+		prev = rec.Hash
+	}
+	return nil
+}
+
+// audit records a destructive operation. Failures to write the audit log are
+// logged, never fatal: the operation has already happened.
+func (d *Daemon) audit(op, target, requestedBy string, verified []string, opErr error) {
+	d.appendAudit(AuditRecord{Op: op, Target: target, RequestedBy: requestedBy, Verified: verified}, opErr)
+}
+
+// auditRequest records a destructive operation done for a lifecycle
+// request, with the request's reason, task bead, and urgency.
+func (d *Daemon) auditRequest(op, target string, request *LifecycleRequest, verified []string, opErr error) {
+	d.appendAudit(AuditRecord{
+		Op:          op,
+		Target:      target,
+		RequestedBy: request.From,
+		Verified:    verified,
+		Reason:      request.Reason,
+		TaskBead:    request.TaskBead,
+		Urgency:     request.Urgency,
+	}, opErr)
+}
+
+// appendAudit fills in rec's outcome and trace and writes it.
+func (d *Daemon) appendAudit(rec AuditRecord, opErr error) {
+	rec.Outcome = AuditOutcomeOK
+	rec.TraceID, rec.SpanID = d.traceFields()
+	if opErr != nil {
+		rec.Outcome = AuditOutcomeFailed
+		rec.Error = opErr.Error()
+	}
+	if _, err := AppendAudit(d.config.TownRoot, rec); err != nil {
+		d.logger.Printf("Warning: failed to write audit record for %s %s: %v", rec.Op, rec.Target, err)
+	}
+}
+
+// saveState writes daemon state and audits the write. Only the process
+// holding the daemon lock writes state, which is the check recorded.
+func (d *Daemon) saveState(state *State, requestedBy string) error {
+	err := SaveState(d.config.TownRoot, state)
+	d.audit(AuditStateWrite, stateKey, requestedBy,
+		[]string{fmt.Sprintf("daemon lock held by pid %d", os.Getpid())}, err)
+	return err
+}
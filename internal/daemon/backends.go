@@ -0,0 +1,290 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+// SessionBackend is the terminal multiplexer the daemon drives agents
+// through. *tmux.Tmux implements it; internal/testharness provides an
+// in-memory fake.
+type SessionBackend interface {
+	IsAvailable() bool
+	HasSession(name string) (bool, error)
+	ListSessions() ([]string, error)
+	EnsureSessionFresh(name, workDir string) error
+	KillSession(name string) error
+	KillSessionWithProcesses(name string) error
+	GetPanePID(session string) (string, error)
+	SessionActivity(session string) (time.Time, error)
+	RenameSession(oldName, newName string) error
+	SetEnvironment(session, key, value string) error
+	ConfigureGasTownSession(session string, theme tmux.Theme, rig, worker, role string) error
+	ApplyLayout(session, workDir string, layout tmux.SessionLayout) error
+	SendKeys(session, keys string) error
+	NudgeSession(session, message string) error
+	WaitForCommand(session string, excludeCommands []string, timeout time.Duration) error
+	AcceptBypassPermissionsWarning(session string) error
+	IsClaudeRunning(session string) bool
+	IsPanePiped(session string) (bool, error)
+	PipePane(session, command string) error
+	SetPaneDiedHook(session, agentID string) error
+}
+
+var _ SessionBackend = (*tmux.Tmux)(nil)
+
+// sessionsFor returns the session backend with its tmux commands killed
+// when ctx is done. Other backends are returned as they are.
+func (d *Daemon) sessionsFor(ctx context.Context) SessionBackend {
+	if t, ok := d.tmux.(*tmux.Tmux); ok {
+		return t.WithContext(ctx)
+	}
+	return d.tmux
+}
+
+// safeSender is implemented by session backends that can type a command
+// without it interleaving with other pane input (see tmux.SafeSendKeys).
+type safeSender interface {
+	SafeSendKeys(target, text string, timeout time.Duration) error
+}
+
+// sendStartCommand types a startup command into a fresh session's shell,
+// through the backend's safe send when it has one. A prompt the safe send
+// doesn't recognize (an unusual shell theme) falls back to plain send-keys:
+// nobody is typing into a session created moments ago.
+func (d *Daemon) sendStartCommand(sessions SessionBackend, session, command string) error {
+	sender, ok := sessions.(safeSender)
+	if !ok {
+		return sessions.SendKeys(session, command)
+	}
+	err := sender.SafeSendKeys(session, command, tmux.DefaultSafeSendTimeout)
+	if errors.Is(err, tmux.ErrNotAtPrompt) {
+		d.logger.Printf("No shell prompt recognized in %s, sending start command unguarded", session)
+		return sessions.SendKeys(session, command)
+	}
+	return err
+}
+
+// MailClient reads and sends the mail the daemon exchanges with agents.
+type MailClient interface {
+	// Inbox returns the messages in identity's inbox.
+	Inbox(identity string) ([]BeadsMessage, error)
+
+	// Delete removes a message.
+	Delete(id string) error
+
+	// Send mails subject and body to an address.
+	Send(to, subject, body string) error
+}
+
+// unreadInbox is implemented by mail clients that can return just the
+// unread messages whose subject starts with one of a set of prefixes, so
+// polling an inbox doesn't fetch and decode mail the daemon never acts on.
+type unreadInbox interface {
+	UnreadInbox(identity string, subjectPrefixes ...string) ([]BeadsMessage, error)
+}
+
+// BeadsClient answers the daemon's bd queries.
+type BeadsClient interface {
+	// RoleConfig returns the config of a role bead, or nil if it has none.
+	RoleConfig(roleBeadID string) (*beads.RoleConfig, error)
+
+	// Run runs bd with args in dir and returns its stdout.
+	Run(dir string, args ...string) ([]byte, error)
+}
+
+// ContainerClient manages the containers of containerized agents (see
+// container.go).
+type ContainerClient interface {
+	// Remove stops and deletes a container. A missing container is not an
+	// error.
+	Remove(name string) error
+
+	// List returns the names of the containers carrying label ("key=value").
+	List(label string) ([]string, error)
+}
+
+// RigProvisioner turns a git checkout in the town root into a rig (see
+// rig_provision.go).
+type RigProvisioner interface {
+	Provision(townRoot, dir string) error
+}
+
+// Backends replaces the daemon's external dependencies. Nil fields use the
+// real implementation (tmux, gt mail, bd, the docker or podman CLI, gt rig
+// provision).
+type Backends struct {
+	Sessions    SessionBackend
+	Mail        MailClient
+	Beads       BeadsClient
+	Containers  ContainerClient
+	Provisioner RigProvisioner
+
+	// Sleep replaces the fixed pauses of session start-up (waiting for an
+	// agent to settle before nudging it).
+	Sleep func(time.Duration)
+}
+
+// NewWithBackends creates a daemon for the town at config.TownRoot that uses
+// the given backends and logs to logger. Unlike New it opens no log file and
+// takes no lock: it is meant for tests and for driving daemon logic (e.g.
+// ProcessLifecycleRequests) from other tools.
+func NewWithBackends(config *Config, backends Backends, logger *log.Logger) *Daemon {
+	registerRolePlugins(config.TownRoot, logger)
+	registerRigAliases(config.TownRoot)
+	d := &Daemon{
+		config:       config,
+		patrolConfig: LoadPatrolConfig(config.TownRoot),
+		tmux:         backends.Sessions,
+		mail:         backends.Mail,
+		beads:        backends.Beads,
+		containers:   backends.Containers,
+		provisioner:  backends.Provisioner,
+		sleep:        backends.Sleep,
+		logger:       logger,
+		crashHistory: make(map[string][]time.Time),
+	}
+	if d.tmux == nil {
+		d.tmux = tmux.NewTmuxWithSocket(TmuxSocket(config.TownRoot))
+	}
+	d.restarts = d.newRestartThrottle()
+	if d.sleep != nil {
+		d.restarts.sleep = d.sleep
+	}
+	return d
+}
+
+// mailClient returns the daemon's mail backend.
+func (d *Daemon) mailClient() MailClient {
+	if d.mail == nil {
+		return gtMail{townRoot: d.config.TownRoot}
+	}
+	return d.mail
+}
+
+// beadsClient returns the daemon's bd backend.
+func (d *Daemon) beadsClient() BeadsClient {
+	if d.beads == nil {
+		return bdClient{townRoot: d.config.TownRoot}
+	}
+	return d.beads
+}
+
+// beadsFor returns the beads backend with its bd runs killed when ctx is
+// done. A replaced backend is returned as it is.
+func (d *Daemon) beadsFor(ctx context.Context) BeadsClient {
+	if d.beads == nil {
+		return bdClient{townRoot: d.config.TownRoot, ctx: ctx}
+	}
+	return d.beads
+}
+
+// pause waits out a fixed start-up delay.
+func (d *Daemon) pause(delay time.Duration) {
+	if d.sleep != nil {
+		d.sleep(delay)
+		return
+	}
+	time.Sleep(delay)
+}
+
+// gtMail is the MailClient backed by the gt mail command.
+type gtMail struct {
+	townRoot string
+}
+
+func (m gtMail) Inbox(identity string) ([]BeadsMessage, error) {
+	return m.inbox(gtMailInboxArgs(identity, false, nil))
+}
+
+// UnreadInbox returns identity's unread messages with one of the subject
+// prefixes (any subject if none), filtered by gt mail inbox.
+func (m gtMail) UnreadInbox(identity string, subjectPrefixes ...string) ([]BeadsMessage, error) {
+	return m.inbox(gtMailInboxArgs(identity, true, subjectPrefixes))
+}
+
+// gtMailInboxArgs returns the gt arguments listing identity's inbox as JSON.
+func gtMailInboxArgs(identity string, unreadOnly bool, subjectPrefixes []string) []string {
+	args := []string{"mail", "inbox", "--identity", identity, "--json"}
+	if unreadOnly {
+		args = append(args, "--unread")
+	}
+	for _, prefix := range subjectPrefixes {
+		args = append(args, "--subject", prefix)
+	}
+	return args
+}
+
+func (m gtMail) inbox(args []string) ([]BeadsMessage, error) {
+	cmd := exec.Command("gt", args...) //nolint:gosec // G204: args are constructed internally
+	cmd.Dir = m.townRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output) == 0 || string(output) == "[]" || string(output) == "[]\n" {
+		return nil, nil
+	}
+
+	var messages []BeadsMessage
+	if err := json.Unmarshal(output, &messages); err != nil {
+		return nil, fmt.Errorf("parsing inbox: %w", err)
+	}
+	return messages, nil
+}
+
+func (m gtMail) Delete(id string) error {
+	cmd := exec.Command("gt", "mail", "delete", id)
+	cmd.Dir = m.townRoot
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gt mail delete %s: %v (output: %s)", id, err, string(output))
+	}
+	return nil
+}
+
+func (m gtMail) Send(to, subject, body string) error {
+	cmd := exec.Command("gt", "mail", "send", to, "-s", subject, "-m", body) //nolint:gosec // G204: args are constructed internally
+	cmd.Dir = m.townRoot
+	return cmd.Run()
+}
+
+// bdClient is the BeadsClient backed by the bd command.
+type bdClient struct {
+	townRoot string
+	ctx      context.Context // bounds Run; nil for none
+}
+
+func (b bdClient) RoleConfig(roleBeadID string) (*beads.RoleConfig, error) {
+	return beads.New(b.townRoot).GetRoleConfig(roleBeadID)
+}
+
+func (b bdClient) Run(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("bd", args...)
+	if b.ctx != nil {
+		cmd = exec.CommandContext(b.ctx, "bd", args...)
+		cmd.WaitDelay = time.Second
+	}
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return output, fmt.Errorf("bd %s: %s", strings.Join(args, " "), msg)
+		}
+		return output, fmt.Errorf("bd %s: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
@@ -0,0 +1,275 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// defaultBeadsSyncInterval is how often the coordinator syncs when enabled.
+const defaultBeadsSyncInterval = 30 * time.Minute
+
+// beadsSyncKey is the town storage key for the last sync report.
+const beadsSyncKey = "daemon/beads-sync.json"
+
+// Beads sync outcomes, per workspace.
+const (
+	BeadsSyncSynced   = "synced"
+	BeadsSyncCurrent  = "up-to-date"
+	BeadsSyncConflict = "conflict"
+	BeadsSyncFailed   = "failed"
+)
+
+// BeadsSyncConfig configures the scheduled bd sync coordinator. When enabled,
+// the coordinator replaces the best-effort bd sync done at session restart.
+type BeadsSyncConfig struct {
+	// Enabled turns on scheduled syncs. Default: false.
+	Enabled bool `json:"enabled"`
+
+	// Interval between sync rounds (Go duration string, default "30m").
+	Interval string `json:"interval,omitempty"`
+}
+
+// BeadsSyncResult is the outcome for one beads database.
+type BeadsSyncResult struct {
+	// Workspace is the first workspace (relative to the town root) found
+	// using this database; worktrees that redirect to it are not synced again.
+	Workspace string   `json:"workspace"`
+	BeadsDir  string   `json:"beads_dir"`
+	Status    string   `json:"status"`
+	Ahead     int      `json:"ahead,omitempty"`
+	Behind    int      `json:"behind,omitempty"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// BeadsSyncReport is the consolidated outcome of one sync round.
+type BeadsSyncReport struct {
+	StartedAt time.Time         `json:"started_at"`
+	Duration  string            `json:"duration"`
+	Results   []BeadsSyncResult `json:"results"`
+}
+
+// Count returns how many results have the given status.
+func (r *BeadsSyncReport) Count(status string) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// beadsSyncEnabled reports whether the scheduled coordinator owns bd sync.
+func (d *Daemon) beadsSyncEnabled() bool {
+	return d.patrolConfig != nil && d.patrolConfig.BeadsSync != nil && d.patrolConfig.BeadsSync.Enabled
+}
+
+// beadsSyncInterval returns the configured interval.
+func (d *Daemon) beadsSyncInterval() time.Duration {
+	if interval := d.patrolConfig.BeadsSync.Interval; interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil && parsed > 0 {
+			return parsed
+		}
+		d.logger.Printf("Warning: invalid beads_sync.interval %q, using %v", interval, defaultBeadsSyncInterval)
+	}
+	return defaultBeadsSyncInterval
+}
+
+// syncBeadsIfDue runs a sync round once per configured interval.
+func (d *Daemon) syncBeadsIfDue(state *State, now time.Time) {
+	if !d.beadsSyncEnabled() {
+		return
+	}
+	if !state.LastBeadsSync.IsZero() && now.Sub(state.LastBeadsSync) < d.beadsSyncInterval() {
+		return
+	}
+	state.LastBeadsSync = now
+
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Beads sync: would sync %d beads databases", len(d.beadsSyncTargets()))
+		return
+	}
+	d.syncBeads(now)
+}
+
+// syncBeads runs one sync round over every beads database in the town and
+// saves the consolidated report.
+func (d *Daemon) syncBeads(now time.Time) *BeadsSyncReport {
+	report := &BeadsSyncReport{StartedAt: now}
+	span := d.startSpan("beads.sync")
+	defer d.endSpan(span, nil)
+	for _, workDir := range d.beadsSyncTargets() {
+		wsSpan := d.startSpan("beads.sync.workspace", "gt.workdir", workDir)
+		res := d.syncBeadsWorkspace(workDir)
+		wsSpan.SetAttributes("gt.status", res.Status)
+		var wsErr error
+		if res.Status == BeadsSyncFailed {
+			wsErr = errors.New(res.Error)
+		}
+		d.endSpan(wsSpan, wsErr)
+		switch res.Status {
+		case BeadsSyncConflict:
+			d.publishSyncWarning(workDir, "beads sync conflict: "+strings.Join(res.Conflicts, ", "))
+		case BeadsSyncFailed:
+			d.publishSyncWarning(workDir, "beads sync failed: "+res.Error)
+		}
+		report.Results = append(report.Results, res)
+	}
+	report.Duration = time.Since(now).Round(time.Second).String()
+
+	if err := saveBeadsSyncReport(d.config.TownRoot, report); err != nil {
+		d.logger.Printf("Warning: failed to save beads sync report: %v", err)
+	}
+	d.logger.Printf("Beads sync: %d synced, %d up to date, %d conflicts, %d failed (%s)",
+		report.Count(BeadsSyncSynced), report.Count(BeadsSyncCurrent),
+		report.Count(BeadsSyncConflict), report.Count(BeadsSyncFailed), report.Duration)
+
+	if conflicts := report.Count(BeadsSyncConflict); conflicts > 0 {
+		var where []string
+		for _, res := range report.Results {
+			if res.Status == BeadsSyncConflict {
+				where = append(where, res.Workspace)
+			}
+		}
+		d.notify(notifier.EventBeadsSyncConflict, map[string]string{
+			"count":      fmt.Sprintf("%d", conflicts),
+			"workspaces": strings.Join(where, ", "),
+		})
+	}
+	return report
+}
+
+// syncBeadsWorkspace syncs one database if it is behind or ahead. Workspaces
+// with conflicts are left alone and reported: syncing over a conflict would
+// bury it under another merge.
+func (d *Daemon) syncBeadsWorkspace(workDir string) BeadsSyncResult {
+	rel, err := filepath.Rel(d.config.TownRoot, workDir)
+	if err != nil {
+		rel = workDir
+	}
+	result := BeadsSyncResult{Workspace: rel, BeadsDir: beads.ResolveBeadsDir(workDir)}
+
+	b := beads.New(workDir)
+	status, err := b.GetSyncStatus()
+	if err != nil {
+		result.Status = BeadsSyncFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Ahead, result.Behind = status.Ahead, status.Behind
+
+	switch {
+	case len(status.Conflicts) > 0:
+		result.Status = BeadsSyncConflict
+		result.Conflicts = status.Conflicts
+	case status.Ahead == 0 && status.Behind == 0:
+		result.Status = BeadsSyncCurrent
+	default:
+		if err := b.Sync(); err != nil {
+			result.Status = BeadsSyncFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = BeadsSyncSynced
+		}
+	}
+	return result
+}
+
+// beadsSyncTargets returns one workspace per distinct beads database: the
+// town, then each rig and its agent workspaces. Worktrees whose .beads
+// redirects to an already-listed database are skipped.
+func (d *Daemon) beadsSyncTargets() []string {
+	townRoot := d.config.TownRoot
+	candidates := []string{townRoot}
+	for _, rigName := range d.getKnownRigs() {
+		rigPath := filepath.Join(townRoot, rigName)
+		candidates = append(candidates,
+			rigPath,
+			filepath.Join(rigPath, "mayor", "rig"),
+			filepath.Join(rigPath, "refinery", "rig"),
+		)
+		for _, sub := range []string{"crew", "polecats"} {
+			names, _ := listPolecatWorktrees(filepath.Join(rigPath, sub))
+			for _, name := range names {
+				candidates = append(candidates, filepath.Join(rigPath, sub, name))
+			}
+		}
+	}
+	return dedupeBeadsWorkspaces(candidates)
+}
+
+// dedupeBeadsWorkspaces keeps the first workspace for each resolved beads
+// directory, dropping workspaces with no beads directory at all.
+func dedupeBeadsWorkspaces(candidates []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, workDir := range candidates {
+		beadsDir := beads.ResolveBeadsDir(workDir)
+		if info, err := os.Stat(beadsDir); err != nil || !info.IsDir() {
+			continue
+		}
+		if seen[beadsDir] {
+			continue
+		}
+		seen[beadsDir] = true
+		out = append(out, workDir)
+	}
+	return out
+}
+
+// RunBeadsSync runs one sync round immediately and returns the report.
+func (c *SessionController) RunBeadsSync() *BeadsSyncReport {
+	return c.d.syncBeads(time.Now())
+}
+
+// LoadBeadsSyncReport returns the last sync report, or nil if none was saved.
+func LoadBeadsSyncReport(townRoot string) (*BeadsSyncReport, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(beadsSyncKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var report BeadsSyncReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func saveBeadsSyncReport(townRoot string, report *BeadsSyncReport) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(beadsSyncKey, data)
+}
+
+// BeadsWorkspaces returns one workspace per beads database in the town,
+// town first.
+func (c *SessionController) BeadsWorkspaces() []string {
+	return c.d.beadsSyncTargets()
+}
@@ -0,0 +1,245 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lifecycle mail bodies are meant to be JSON (LifecycleBody), but agents
+// sometimes write YAML or "key: value" lines instead. The body is offered to
+// a chain of parsers in order and the first that recognizes it wins; the
+// parser's name is recorded with the request in the audit log.
+//
+// lifecycle.body_strictness picks the chain:
+//
+//	strict    json
+//	standard  json, yaml, kv (default)
+//	lenient   json, yaml, kv, keyword
+//
+// lifecycle.body_parsers lists the chain explicitly instead, and may name
+// parsers added with RegisterBodyParser.
+
+// Built-in body parsers.
+const (
+	BodyParserJSON     = "json"
+	BodyParserYAML     = "yaml"
+	BodyParserKeyValue = "kv"
+	BodyParserKeyword  = "keyword"
+)
+
+// Body strictness levels.
+const (
+	BodyStrict   = "strict"
+	BodyStandard = "standard"
+	BodyLenient  = "lenient"
+)
+
+// BodyParser reads a lifecycle mail body. ok is false if the body isn't in
+// the parser's format, so the next parser gets a turn.
+type BodyParser func(body string) (parsed *LifecycleBody, ok bool)
+
+var (
+	bodyParsersMu sync.RWMutex
+	bodyParsers   = map[string]BodyParser{
+		BodyParserJSON:     parseJSONBody,
+		BodyParserYAML:     parseYAMLBody,
+		BodyParserKeyValue: parseKeyValueBody,
+		BodyParserKeyword:  parseKeywordBody,
+	}
+)
+
+// bodyStrictnessChains maps each strictness level to its parser chain.
+var bodyStrictnessChains = map[string][]string{
+	BodyStrict:   {BodyParserJSON},
+	BodyStandard: {BodyParserJSON, BodyParserYAML, BodyParserKeyValue},
+	BodyLenient:  {BodyParserJSON, BodyParserYAML, BodyParserKeyValue, BodyParserKeyword},
+}
+
+// RegisterBodyParser adds (or replaces) a named body parser, which
+// lifecycle.body_parsers can then include in the chain.
+func RegisterBodyParser(name string, parser BodyParser) {
+	bodyParsersMu.Lock()
+	defer bodyParsersMu.Unlock()
+	bodyParsers[name] = parser
+}
+
+// BodyParserNames returns the names of all registered body parsers, sorted.
+func BodyParserNames() []string {
+	bodyParsersMu.RLock()
+	defer bodyParsersMu.RUnlock()
+	names := make([]string, 0, len(bodyParsers))
+	for name := range bodyParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupBodyParser(name string) (BodyParser, bool) {
+	bodyParsersMu.RLock()
+	defer bodyParsersMu.RUnlock()
+	parser, ok := bodyParsers[name]
+	return parser, ok
+}
+
+// bodyParserChain returns the configured parser chain, falling back to the
+// standard chain (with a warning) when the config names something unknown.
+func (d *Daemon) bodyParserChain() []string {
+	cfg := d.patrolConfig.lifecycleConfig()
+	if len(cfg.BodyParsers) > 0 {
+		for _, name := range cfg.BodyParsers {
+			if _, ok := lookupBodyParser(name); !ok {
+				d.logger.Printf("Warning: unknown lifecycle.body_parsers entry %q (known: %s), using the %s chain",
+					name, strings.Join(BodyParserNames(), ", "), BodyStandard)
+				return bodyStrictnessChains[BodyStandard]
+			}
+		}
+		return cfg.BodyParsers
+	}
+	if cfg.BodyStrictness == "" {
+		return bodyStrictnessChains[BodyStandard]
+	}
+	chain, ok := bodyStrictnessChains[cfg.BodyStrictness]
+	if !ok {
+		d.logger.Printf("Warning: invalid lifecycle.body_strictness %q, using %s", cfg.BodyStrictness, BodyStandard)
+		return bodyStrictnessChains[BodyStandard]
+	}
+	return chain
+}
+
+// parseLifecycleBody runs body through the parser chain. Returns the parsed
+// body and the name of the parser that matched, or an error naming the
+// parsers tried.
+func (d *Daemon) parseLifecycleBody(body string) (*LifecycleBody, string, error) {
+	chain := d.bodyParserChain()
+	for _, name := range chain {
+		parser, ok := lookupBodyParser(name)
+		if !ok {
+			continue
+		}
+		if parsed, ok := parser(body); ok {
+			return parsed, name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no body parser matched (tried %s)", strings.Join(chain, ", "))
+}
+
+// parseJSONBody accepts a JSON object.
+func parseJSONBody(body string) (*LifecycleBody, bool) {
+	var parsed LifecycleBody
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// parseYAMLBody accepts a YAML mapping with an action key.
+func parseYAMLBody(body string) (*LifecycleBody, bool) {
+	var m map[string]any
+	if err := yaml.Unmarshal([]byte(body), &m); err != nil {
+		return nil, false
+	}
+	if _, ok := m["action"]; !ok {
+		return nil, false
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, false
+	}
+	return parseJSONBody(string(data))
+}
+
+// parseKeyValueBody accepts "key: value" or "key=value" pairs, one per line
+// or separated by semicolons, with an action key; or a body that is just an
+// action word ("restart"). Keys are case-insensitive and may use - or
+// spaces for _. Notify takes a comma-separated list.
+func parseKeyValueBody(body string) (*LifecycleBody, bool) {
+	trimmed := strings.TrimSpace(body)
+	if _, ok := parseLifecycleAction(trimmed); ok {
+		return &LifecycleBody{Action: strings.ToLower(trimmed)}, true
+	}
+
+	var parsed LifecycleBody
+	hasAction := false
+	for _, line := range strings.FieldsFunc(body, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if eq := strings.Index(line, "="); eq >= 0 && (!ok || eq < len(key)) {
+			key, value, ok = line[:eq], line[eq+1:], true
+		}
+		if !ok {
+			return nil, false // Not a key-value line
+		}
+		key = strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(key)))
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch key {
+		case "action":
+			parsed.Action = strings.ToLower(value)
+			hasAction = true
+		case "dry_run":
+			switch strings.ToLower(value) {
+			case "true", "yes", "1":
+				parsed.DryRun = true
+			}
+		case "notify":
+			for _, id := range strings.Split(value, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					parsed.Notify = append(parsed.Notify, id)
+				}
+			}
+		case "handoff":
+			parsed.Handoff = value
+		case "token":
+			parsed.Token = value
+		case "reason":
+			parsed.Reason = value
+		case "task_bead", "task":
+			parsed.TaskBead = value
+		case "urgency":
+			parsed.Urgency = value
+		}
+	}
+	if !hasAction {
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// parseKeywordBody accepts free text that names exactly one lifecycle
+// action ("please cycle me, context is full"). A mention of a dry run makes
+// the request a dry run, so a misread never does more than was asked. The
+// text itself becomes the request's reason.
+func parseKeywordBody(body string) (*LifecycleBody, bool) {
+	words := strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_' && r != '-'
+	})
+	var action LifecycleAction
+	dryRun := false
+	for i, word := range words {
+		if word == "dry-run" || word == "dry_run" || (word == "dry" && i+1 < len(words) && words[i+1] == "run") {
+			dryRun = true
+			continue
+		}
+		found, ok := parseLifecycleAction(word)
+		if !ok {
+			continue
+		}
+		if action != "" && found != action {
+			return nil, false // Ambiguous
+		}
+		action = found
+	}
+	if action == "" {
+		return nil, false
+	}
+	return &LifecycleBody{Action: string(action), DryRun: dryRun, Reason: strings.TrimSpace(body)}, true
+}
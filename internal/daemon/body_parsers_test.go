@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLifecycleRequest_BodyParsers(t *testing.T) {
+	tests := []struct {
+		strictness string
+		body       string
+		action     LifecycleAction // "" = rejected
+		parser     string
+		dryRun     bool
+	}{
+		{"", `{"action": "cycle"}`, ActionCycle, BodyParserJSON, false},
+		{"", "action: restart\ndry_run: true\nnotify: [mayor]", ActionRestart, BodyParserYAML, true},
+		{"", "action=refresh; dry-run=yes", ActionRefresh, BodyParserKeyValue, true},
+		{"", "Action: Shutdown\nnotify = mayor, gastown/witness", ActionShutdown, BodyParserKeyValue, false},
+		{"", "stop", ActionShutdown, BodyParserKeyValue, false},
+		{"", "please cycle me, context is full", "", "", false},
+
+		{BodyStrict, `{"action": "cycle"}`, ActionCycle, BodyParserJSON, false},
+		{BodyStrict, "action: cycle", "", "", false},
+		{BodyStrict, "cycle", "", "", false},
+
+		{BodyLenient, "please cycle me, context is full", ActionCycle, BodyParserKeyword, false},
+		{BodyLenient, "dry run a restart please", ActionRestart, BodyParserKeyword, true},
+		{BodyLenient, "don't stop, just restart", "", "", false},
+		{BodyLenient, "hello there", "", "", false},
+	}
+
+	for _, tc := range tests {
+		d := testDaemon()
+		d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{BodyStrictness: tc.strictness}}
+		got := d.parseLifecycleRequest(&BeadsMessage{Subject: "LIFECYCLE: request", Body: tc.body, From: "mayor"})
+		name := tc.strictness + "/" + strings.ReplaceAll(tc.body, "\n", `\n`)
+		if tc.action == "" {
+			if got != nil {
+				t.Errorf("%s: got %s via %s, want rejected", name, got.Action, got.Parser)
+			}
+			continue
+		}
+		if got == nil {
+			t.Errorf("%s: rejected, want %s", name, tc.action)
+			continue
+		}
+		if got.Action != tc.action || got.Parser != tc.parser || got.DryRun != tc.dryRun {
+			t.Errorf("%s: got %s via %s (dry_run %v), want %s via %s (dry_run %v)",
+				name, got.Action, got.Parser, got.DryRun, tc.action, tc.parser, tc.dryRun)
+		}
+	}
+}
+
+func TestBodyParserChain(t *testing.T) {
+	RegisterBodyParser("always-refresh", func(string) (*LifecycleBody, bool) {
+		return &LifecycleBody{Action: "refresh"}, true
+	})
+	defer func() {
+		bodyParsersMu.Lock()
+		delete(bodyParsers, "always-refresh")
+		bodyParsersMu.Unlock()
+	}()
+
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{BodyParsers: []string{"json", "always-refresh"}}}
+	got := d.parseLifecycleRequest(&BeadsMessage{Subject: "LIFECYCLE: x", Body: "anything", From: "mayor"})
+	if got == nil || got.Action != ActionRefresh || got.Parser != "always-refresh" {
+		t.Fatalf("custom parser: got %+v", got)
+	}
+
+	// An unknown parser name falls back to the standard chain
+	d.patrolConfig.Lifecycle.BodyParsers = []string{"json", "toml"}
+	if chain := d.bodyParserChain(); strings.Join(chain, ",") != "json,yaml,kv" {
+		t.Errorf("chain = %v, want the standard chain", chain)
+	}
+}
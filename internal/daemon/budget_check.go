@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/budget"
+)
+
+const (
+	// budgetCheckInterval is how often rig budgets are checked.
+	budgetCheckInterval = 15 * time.Minute
+
+	// budgetCheckTimeout bounds a single gt costs budget check run.
+	budgetCheckTimeout = 2 * time.Minute
+)
+
+// checkBudgets runs 'gt costs budget check' every budgetCheckInterval while
+// any rig has a budget, so alerts go out and hard stops trip without anyone
+// running the check by hand. The command owns the cost ledger queries, the
+// alerts, and stopping a rig's non-critical sessions; once it pauses a rig,
+// the daemon refuses to restart or warm agents there (see budget.CheckRole).
+func (d *Daemon) checkBudgets(state *State, now time.Time) {
+	if !d.anyRigBudgeted() {
+		return
+	}
+	if !state.LastBudgetCheck.IsZero() && now.Sub(state.LastBudgetCheck) < budgetCheckInterval {
+		return
+	}
+	state.LastBudgetCheck = now
+
+	args := []string{"costs", "budget", "check"}
+	if d.config.DryRun {
+		args = append(args, "--dry-run")
+	}
+	cmd := exec.Command("gt", args...) //nolint:gosec // G204: args are constructed internally
+	cmd.Dir = d.config.TownRoot
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		d.logger.Printf("Warning: budget check failed to start: %v", err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			d.logger.Printf("Warning: budget check failed: %v: %s", err, strings.TrimSpace(out.String()))
+			return
+		}
+		if report := strings.TrimSpace(out.String()); report != "" {
+			d.logger.Printf("Budget check: %s", report)
+		}
+	case <-time.After(budgetCheckTimeout):
+		_ = cmd.Process.Kill()
+		d.logger.Printf("Warning: budget check timed out after %v", budgetCheckTimeout)
+	}
+}
+
+// anyRigBudgeted reports whether any known rig has a monthly budget.
+func (d *Daemon) anyRigBudgeted() bool {
+	for _, rigName := range d.getKnownRigs() {
+		if budget.LoadConfig(d.config.TownRoot, rigName) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/budget"
+)
+
+func TestCheckBudgets_Schedule(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	t.Setenv("PATH", t.TempDir()) // no gt: runs fail to start and are only logged
+
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	state := &State{}
+
+	// No rig has a budget
+	d.checkBudgets(state, now)
+	if !state.LastBudgetCheck.IsZero() {
+		t.Fatal("budget check ran with no budgets configured")
+	}
+
+	settings := filepath.Join(townRoot, "gastown", "settings")
+	if err := os.MkdirAll(settings, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(settings, "config.json"), []byte(`{"type":"rig-settings","version":1,"budget":{"monthly_usd":100,"hard_stop":true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d.checkBudgets(state, now)
+	if !state.LastBudgetCheck.Equal(now) {
+		t.Fatalf("budget check not run: last = %v", state.LastBudgetCheck)
+	}
+
+	// Not due again until the interval has passed
+	d.checkBudgets(state, now.Add(budgetCheckInterval/2))
+	if !state.LastBudgetCheck.Equal(now) {
+		t.Error("budget check ran again before the interval")
+	}
+	d.checkBudgets(state, now.Add(budgetCheckInterval))
+	if !state.LastBudgetCheck.Equal(now.Add(budgetCheckInterval)) {
+		t.Error("budget check not run after the interval")
+	}
+}
+
+func TestMaintainWarmPool_SkipsBudgetPausedRig(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	tm := &hibernateTmux{running: map[string]bool{}}
+	d.tmux = tm
+	d.config.DryRun = true
+	d.patrolConfig = &DaemonPatrolConfig{WarmPool: &WarmPoolConfig{Enabled: true, Rigs: []string{"gastown"}}}
+
+	if err := budget.Pause(d.config.TownRoot, "gastown", budget.Period(time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	var logged strings.Builder
+	d.logger.SetOutput(&logged)
+	d.maintainWarmPool()
+	if strings.Contains(logged.String(), "Would start warm session") {
+		t.Errorf("warm session started in a budget-paused rig: %s", logged.String())
+	}
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// defaultCapabilityRoutingInterval is how often ready work is matched
+// against crew capabilities.
+const defaultCapabilityRoutingInterval = 10 * time.Minute
+
+// CapabilityRoutingConfig matches ready work to crew by capability, under
+// "capability_routing" in mayor/daemon.json:
+//
+//	"capability_routing": {"enabled": true, "interval": "5m", "assign": true}
+//
+// Work beads name what they need with needs:<capability> labels; crew agent
+// beads declare what they have on a capabilities line (see 'gt crew
+// capabilities'). Each pass finds the unassigned ready work of every rig
+// that needs capabilities and, with assign set, assigns it to the least
+// loaded crew member that has them all. Work no crew member can take is
+// reported by 'gt daemon status'.
+type CapabilityRoutingConfig struct {
+	// Enabled turns on the routing pass.
+	Enabled bool `json:"enabled"`
+
+	// Interval is how often the pass runs (Go duration string, default
+	// "10m").
+	Interval string `json:"interval,omitempty"`
+
+	// Assign assigns matched work; without it the pass only reports gaps.
+	Assign bool `json:"assign,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *CapabilityRoutingConfig) Validate() error {
+	if c == nil || c.Interval == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+		return fmt.Errorf("capability_routing: invalid interval %q", c.Interval)
+	}
+	return nil
+}
+
+// capabilityRoutingConfig returns the capability routing config, or nil if
+// it is unset, disabled, or invalid.
+func (c *DaemonPatrolConfig) capabilityRoutingConfig() *CapabilityRoutingConfig {
+	if c == nil || c.CapabilityRouting == nil || !c.CapabilityRouting.Enabled || c.CapabilityRouting.Validate() != nil {
+		return nil
+	}
+	return c.CapabilityRouting
+}
+
+// CapabilityGap is ready work no crew member has the capabilities for.
+type CapabilityGap struct {
+	Rig   string `json:"rig"`
+	Issue string `json:"issue"`
+	Title string `json:"title,omitempty"`
+
+	// Missing are the needed capabilities no crew member declares, or all
+	// of them if no single member has the combination.
+	Missing []string `json:"missing"`
+}
+
+// routeWorkByCapability runs the routing pass once per interval.
+func (d *Daemon) routeWorkByCapability(state *State, now time.Time) {
+	cfg := d.patrolConfig.capabilityRoutingConfig()
+	if cfg == nil {
+		return
+	}
+	interval := defaultCapabilityRoutingInterval
+	if cfg.Interval != "" {
+		interval, _ = time.ParseDuration(cfg.Interval) // checked by Validate
+	}
+	if !d.lastCapabilityRouting.IsZero() && now.Sub(d.lastCapabilityRouting) < interval {
+		state.CapabilityGaps = d.capabilityGaps
+		return
+	}
+	d.lastCapabilityRouting = now
+
+	span := d.startSpan("capability.routing")
+	defer d.endSpan(span, nil)
+	var gaps []CapabilityGap
+	for _, rigName := range d.getKnownRigs() {
+		gaps = append(gaps, d.routeRigWork(rigName, cfg.Assign)...)
+	}
+	for _, gap := range gaps {
+		known := slices.ContainsFunc(d.capabilityGaps, func(g CapabilityGap) bool { return g.Issue == gap.Issue })
+		if !known {
+			d.logger.Printf("No crew in %s can take %s: missing %s",
+				gap.Rig, gap.Issue, strings.Join(gap.Missing, ", "))
+		}
+	}
+	d.capabilityGaps = gaps
+	state.CapabilityGaps = gaps
+}
+
+// routeRigWork routes one rig's unassigned ready work to its crew and
+// returns the work it could not place.
+func (d *Daemon) routeRigWork(rigName string, assign bool) []CapabilityGap {
+	rigDir := filepath.Join(d.config.TownRoot, rigName)
+	ready, err := d.listIssues(rigDir, "ready", "--json")
+	if err != nil {
+		d.logger.Printf("Warning: capability routing for %s: %v", rigName, err)
+		return nil
+	}
+	ready = slices.DeleteFunc(ready, func(issue *beads.Issue) bool { return issue.Assignee != "" })
+	if len(ready) == 0 {
+		return nil
+	}
+	agentBeads, err := d.listIssues(rigDir, "list", "--label=gt:agent", "--json")
+	if err != nil {
+		d.logger.Printf("Warning: capability routing for %s: %v", rigName, err)
+		return nil
+	}
+	active, _ := d.listIssues(rigDir, "list", "--status=in_progress", "--json")
+
+	routes, workGaps := beads.RouteWork(ready, beads.CapableCrew(rigName, agentBeads, active), false)
+	if assign {
+		for _, route := range routes {
+			if d.config.DryRun {
+				d.logger.Printf("[dry-run] Would assign %s to %s", route.Issue.ID, route.Agent)
+				continue
+			}
+			if _, err := d.beadsClient().Run(rigDir, "update", route.Issue.ID, "--assignee="+route.Agent); err != nil {
+				d.logger.Printf("Warning: assigning %s to %s: %v", route.Issue.ID, route.Agent, err)
+				continue
+			}
+			d.logger.Printf("Assigned %s to %s by capability", route.Issue.ID, route.Agent)
+		}
+	}
+
+	gaps := make([]CapabilityGap, 0, len(workGaps))
+	for _, gap := range workGaps {
+		gaps = append(gaps, CapabilityGap{Rig: rigName, Issue: gap.Issue.ID, Title: gap.Issue.Title, Missing: gap.Missing})
+	}
+	return gaps
+}
+
+// listIssues runs a bd query that prints issues as JSON.
+func (d *Daemon) listIssues(dir string, args ...string) ([]*beads.Issue, error) {
+	out, err := d.beadsClient().Run(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var issues []*beads.Issue
+	if err := json.Unmarshal(out, &issues); err != nil {
+		return nil, fmt.Errorf("parsing bd %s output: %w", args[0], err)
+	}
+	return issues, nil
+}
@@ -0,0 +1,89 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// routingBeads answers the bd queries of the capability routing pass.
+type routingBeads struct {
+	ready   []*beads.Issue
+	agents  []*beads.Issue
+	updates []string
+}
+
+func (b *routingBeads) RoleConfig(string) (*beads.RoleConfig, error) { return nil, nil }
+
+func (b *routingBeads) Run(dir string, args ...string) ([]byte, error) {
+	switch {
+	case args[0] == "ready":
+		return json.Marshal(b.ready)
+	case args[0] == "list" && args[1] == "--label=gt:agent":
+		return json.Marshal(b.agents)
+	case args[0] == "list":
+		return []byte("[]"), nil
+	case args[0] == "update":
+		b.updates = append(b.updates, strings.Join(args[1:], " "))
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unexpected bd %v", args)
+}
+
+func TestRouteWorkByCapability(t *testing.T) {
+	d, _ := testDaemonWithTown(t, "test")
+	rigsJSON := `{"rigs": {"gastown": {}}}`
+	if err := os.WriteFile(filepath.Join(d.config.TownRoot, "mayor", "rigs.json"), []byte(rigsJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := &routingBeads{
+		ready: []*beads.Issue{
+			{ID: "gt-1", Labels: []string{"needs:lang:go"}},
+			{ID: "gt-2", Labels: []string{"needs:lang:rust"}, Title: "Port the parser"},
+			{ID: "gt-3", Labels: []string{"needs:lang:go"}, Assignee: "gastown/crew/joe"},
+			{ID: "gt-4"},
+		},
+		agents: []*beads.Issue{
+			{ID: "gt-gastown-crew-max", Description: "capabilities: lang:go"},
+		},
+	}
+	d.beads = fake
+	d.patrolConfig = &DaemonPatrolConfig{CapabilityRouting: &CapabilityRoutingConfig{Enabled: true, Assign: true}}
+
+	state := &State{}
+	now := time.Now()
+	d.routeWorkByCapability(state, now)
+
+	if want := []string{"gt-1 --assignee=gastown/crew/max"}; !reflect.DeepEqual(fake.updates, want) {
+		t.Errorf("updates = %v, want %v", fake.updates, want)
+	}
+	want := []CapabilityGap{{Rig: "gastown", Issue: "gt-2", Title: "Port the parser", Missing: []string{"lang:rust"}}}
+	if !reflect.DeepEqual(state.CapabilityGaps, want) {
+		t.Errorf("gaps = %+v, want %+v", state.CapabilityGaps, want)
+	}
+
+	// Within the interval the last result is reported without a new pass.
+	fake.updates = nil
+	state = &State{}
+	d.routeWorkByCapability(state, now.Add(time.Minute))
+	if len(fake.updates) != 0 || len(state.CapabilityGaps) != 1 {
+		t.Errorf("second pass within interval: updates %v, gaps %v", fake.updates, state.CapabilityGaps)
+	}
+}
+
+func TestCapabilityRoutingConfigValidate(t *testing.T) {
+	if err := (&CapabilityRoutingConfig{Enabled: true, Interval: "soon"}).Validate(); err == nil {
+		t.Error("expected error for invalid interval")
+	}
+	cfg := &DaemonPatrolConfig{CapabilityRouting: &CapabilityRoutingConfig{Enabled: true, Interval: "-1m"}}
+	if cfg.capabilityRoutingConfig() != nil {
+		t.Error("invalid config should disable routing")
+	}
+}
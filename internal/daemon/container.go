@@ -0,0 +1,270 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Containerized agent sessions. Agents matched by a profile under
+// "containers" in mayor/daemon.json run inside a docker or podman container
+// instead of directly on the host:
+//
+//	"containers": {
+//	  "runtime": "podman",
+//	  "profiles": [
+//	    {"roles": ["crew"], "image": "ghcr.io/acme/agent:latest", "cpus": "2", "memory": "4g"}
+//	  ]
+//	}
+//
+// The agent keeps its tmux session, so nudges, attach, and transcripts work
+// as before, but the session's command is a 'run -it' of the profile's image
+// with the agent's workdir mounted at the same path, so the agent gets its
+// own CPU and memory limits and sees only its own workspace (plus any extra
+// mounts). Each container is named after its session and labeled with the
+// town. Killing the session removes its container, and containers whose
+// session is gone are removed every heartbeat.
+
+// Container runtimes.
+const (
+	ContainerRuntimeDocker = "docker"
+	ContainerRuntimePodman = "podman"
+)
+
+// containerTownLabel labels every agent container with its town root.
+const containerTownLabel = "gt.town"
+
+// ContainerConfig runs agents in containers.
+type ContainerConfig struct {
+	// Runtime is the container CLI: "docker" (default) or "podman".
+	Runtime string `json:"runtime,omitempty"`
+
+	// Profiles pick the container settings per agent; the first match wins.
+	// Agents no profile matches run on the host.
+	Profiles []*ContainerProfile `json:"profiles,omitempty"`
+}
+
+// ContainerProfile is the container settings of a set of agents.
+type ContainerProfile struct {
+	// Roles matches agents by role type (crew, polecat, witness, ...).
+	Roles []string `json:"roles,omitempty"`
+
+	// Agents matches identities, exact or path.Match globs
+	// (e.g. "gastown-crew-*"). With neither Roles nor Agents the profile
+	// matches every agent.
+	Agents []string `json:"agents,omitempty"`
+
+	// Image is the container image; it must provide the agent runtime
+	// (claude, gt, bd, git).
+	Image string `json:"image"`
+
+	// CPUs and Memory limit the container ("2", "4g"), as the runtime's
+	// --cpus and --memory flags take them.
+	CPUs   string `json:"cpus,omitempty"`
+	Memory string `json:"memory,omitempty"`
+
+	// Mounts are extra volumes ("src:dst[:ro]").
+	Mounts []string `json:"mounts,omitempty"`
+
+	// Args are extra arguments to the runtime's run command.
+	Args []string `json:"args,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *ContainerConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Runtime {
+	case "", ContainerRuntimeDocker, ContainerRuntimePodman:
+	default:
+		return fmt.Errorf("containers: unknown runtime %q (want docker or podman)", c.Runtime)
+	}
+	for i, p := range c.Profiles {
+		if p == nil || p.Image == "" {
+			return fmt.Errorf("containers: profiles[%d]: image is required", i)
+		}
+		for _, pattern := range p.Agents {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("containers: profiles[%d]: invalid agent pattern %q", i, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// containerConfig returns the container config, or nil if it is unset,
+// has no profiles, or is invalid.
+func (c *DaemonPatrolConfig) containerConfig() *ContainerConfig {
+	if c == nil || c.Containers == nil || len(c.Containers.Profiles) == 0 || c.Containers.Validate() != nil {
+		return nil
+	}
+	return c.Containers
+}
+
+// runtime returns the container CLI.
+func (c *ContainerConfig) runtime() string {
+	if c.Runtime == "" {
+		return ContainerRuntimeDocker
+	}
+	return c.Runtime
+}
+
+// profileFor returns the profile identity runs under, or nil to run it on
+// the host.
+func (c *ContainerConfig) profileFor(identity string, parsed *ParsedIdentity) *ContainerProfile {
+	if c == nil {
+		return nil
+	}
+	for _, p := range c.Profiles {
+		if len(p.Roles) == 0 && len(p.Agents) == 0 {
+			return p
+		}
+		for _, role := range p.Roles {
+			if parsed != nil && role == parsed.RoleType {
+				return p
+			}
+		}
+		if matchesPrewarmAgents(identity, p.Agents) {
+			return p
+		}
+	}
+	return nil
+}
+
+// containerStartCommand returns the command that starts identity's agent:
+// startCmd itself, or startCmd run in a container if a profile matches.
+func (d *Daemon) containerStartCommand(identity, sessionName, workDir string, parsed *ParsedIdentity, startCmd string) string {
+	cfg := d.patrolConfig.containerConfig()
+	profile := cfg.profileFor(identity, parsed)
+	if profile == nil {
+		return startCmd
+	}
+	return d.containerRunCommand(cfg, profile, sessionName, workDir, startCmd)
+}
+
+// agentRunning reports whether identity's agent is running in sessionName.
+// A containerized agent's pane runs the container CLI rather than Claude,
+// so for those a live session is enough.
+func (d *Daemon) agentRunning(identity, sessionName string, parsed *ParsedIdentity) bool {
+	if d.patrolConfig.containerConfig().profileFor(identity, parsed) == nil {
+		return d.tmux.IsClaudeRunning(sessionName)
+	}
+	has, err := d.tmux.HasSession(sessionName)
+	return err == nil && has
+}
+
+// containerRunCommand wraps an agent's start command in a run of the
+// profile's image, named after its session.
+func (d *Daemon) containerRunCommand(cfg *ContainerConfig, p *ContainerProfile, sessionName, workDir, startCmd string) string {
+	args := []string{"exec", cfg.runtime(), "run", "--rm", "-it",
+		"--name", sessionName,
+		"--label", containerTownLabel + "=" + d.config.TownRoot,
+		"-v", workDir + ":" + workDir,
+		"-w", workDir,
+	}
+	if p.CPUs != "" {
+		args = append(args, "--cpus", p.CPUs)
+	}
+	if p.Memory != "" {
+		args = append(args, "--memory", p.Memory)
+	}
+	for _, mount := range p.Mounts {
+		args = append(args, "-v", mount)
+	}
+	args = append(args, p.Args...)
+	args = append(args, p.Image, "sh", "-c", startCmd)
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if i == 0 {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// removeAgentContainer removes the container of a containerized agent's
+// session, if it has one.
+func (d *Daemon) removeAgentContainer(sessionName string) {
+	if d.patrolConfig.containerConfig() == nil {
+		return
+	}
+	if err := d.containerClient().Remove(sessionName); err != nil {
+		d.logger.Printf("Warning: failed to remove container %s: %v", sessionName, err)
+	}
+}
+
+// reapContainers removes the town's agent containers whose tmux session is
+// gone, so a container never outlives its agent.
+func (d *Daemon) reapContainers() {
+	if d.patrolConfig.containerConfig() == nil {
+		return
+	}
+	names, err := d.containerClient().List(containerTownLabel + "=" + d.config.TownRoot)
+	if err != nil {
+		d.logger.Printf("Warning: listing agent containers: %v", err)
+		return
+	}
+	for _, name := range names {
+		if running, err := d.tmux.HasSession(name); err != nil || running {
+			continue
+		}
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Would remove orphaned container %s", name)
+			continue
+		}
+		if err := d.containerClient().Remove(name); err != nil {
+			d.logger.Printf("Warning: failed to remove orphaned container %s: %v", name, err)
+			continue
+		}
+		d.logger.Printf("Removed container %s: its session is gone", name)
+	}
+}
+
+// containerClient returns the daemon's container backend.
+func (d *Daemon) containerClient() ContainerClient {
+	if d.containers == nil {
+		runtime := ContainerRuntimeDocker
+		if cfg := d.patrolConfig.containerConfig(); cfg != nil {
+			runtime = cfg.runtime()
+		}
+		return containerCLI{runtime: runtime}
+	}
+	return d.containers
+}
+
+// containerCLI is the ContainerClient backed by the docker or podman CLI.
+type containerCLI struct {
+	runtime string
+}
+
+func (c containerCLI) Remove(name string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.runtime, "rm", "-f", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(strings.ToLower(msg), "no such container") {
+			return nil
+		}
+		if msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func (c containerCLI) List(label string) ([]string, error) {
+	out, err := exec.Command(c.runtime, "ps", "-a", "--filter", "label="+label, "--format", "{{.Names}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s ps: %w", c.runtime, err)
+	}
+	return strings.Fields(string(out)), nil
+}
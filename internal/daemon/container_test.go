@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeContainers lists containers and records removals.
+type fakeContainers struct {
+	names   []string
+	removed []string
+}
+
+func (f *fakeContainers) Remove(name string) error {
+	f.removed = append(f.removed, name)
+	return nil
+}
+
+func (f *fakeContainers) List(string) ([]string, error) { return f.names, nil }
+
+func TestContainerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *ContainerConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"default runtime", &ContainerConfig{Profiles: []*ContainerProfile{{Image: "agent"}}}, false},
+		{"podman", &ContainerConfig{Runtime: "podman", Profiles: []*ContainerProfile{{Image: "agent"}}}, false},
+		{"unknown runtime", &ContainerConfig{Runtime: "lxc"}, true},
+		{"missing image", &ContainerConfig{Profiles: []*ContainerProfile{{Roles: []string{"crew"}}}}, true},
+		{"bad pattern", &ContainerConfig{Profiles: []*ContainerProfile{{Image: "agent", Agents: []string{"["}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContainerProfileFor(t *testing.T) {
+	crew := &ContainerProfile{Roles: []string{"crew"}, Image: "crew"}
+	named := &ContainerProfile{Agents: []string{"gastown-polecat-*"}, Image: "polecat"}
+	cfg := &ContainerConfig{Profiles: []*ContainerProfile{crew, named}}
+
+	tests := []struct {
+		identity string
+		want     *ContainerProfile
+	}{
+		{"gastown-crew-max", crew},
+		{"gastown-polecat-toast", named},
+		{"beads-polecat-toast", nil},
+		{"deacon", nil},
+	}
+	for _, tt := range tests {
+		parsed, err := parseIdentity(tt.identity)
+		if err != nil {
+			t.Fatalf("parseIdentity(%q): %v", tt.identity, err)
+		}
+		if got := cfg.profileFor(tt.identity, parsed); got != tt.want {
+			t.Errorf("profileFor(%q) = %v, want %v", tt.identity, got, tt.want)
+		}
+	}
+
+	catchAll := &ContainerConfig{Profiles: []*ContainerProfile{{Image: "all"}}}
+	if catchAll.profileFor("deacon", &ParsedIdentity{RoleType: "deacon"}) == nil {
+		t.Error("profile with no roles or agents should match every agent")
+	}
+	var none *ContainerConfig
+	if none.profileFor("deacon", nil) != nil {
+		t.Error("nil config should match no agent")
+	}
+}
+
+func TestContainerStartCommand(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{Containers: &ContainerConfig{
+		Runtime: "podman",
+		Profiles: []*ContainerProfile{{
+			Roles:  []string{"crew"},
+			Image:  "ghcr.io/acme/agent:latest",
+			CPUs:   "2",
+			Memory: "4g",
+			Mounts: []string{"/cache:/cache:ro"},
+		}},
+	}}
+
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+	got := d.containerStartCommand("gastown-crew-max", "gt-gastown-crew-max", "/town/gastown/crew/max", crew, "exec claude --resume")
+	for _, want := range []string{
+		"exec 'podman' 'run' '--rm' '-it'",
+		"'--name' 'gt-gastown-crew-max'",
+		"'--label' 'gt.town=/tmp/test'",
+		"'-v' '/town/gastown/crew/max:/town/gastown/crew/max' '-w' '/town/gastown/crew/max'",
+		"'--cpus' '2' '--memory' '4g'",
+		"'-v' '/cache:/cache:ro'",
+		"'ghcr.io/acme/agent:latest' 'sh' '-c' 'exec claude --resume'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("start command %q missing %q", got, want)
+		}
+	}
+
+	witness := &ParsedIdentity{RoleType: "witness", RigName: "gastown"}
+	if got := d.containerStartCommand("gastown-witness", "gt-gastown-witness", "/town/gastown/witness", witness, "exec claude"); got != "exec claude" {
+		t.Errorf("unmatched agent start command = %q, want it unchanged", got)
+	}
+}
+
+func TestReapContainers(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{Containers: &ContainerConfig{
+		Profiles: []*ContainerProfile{{Image: "agent"}},
+	}}
+	d.tmux = &hibernateTmux{running: map[string]bool{"gt-gastown-crew-max": true}}
+	containers := &fakeContainers{names: []string{"gt-gastown-crew-max", "gt-gastown-crew-joe"}}
+	d.containers = containers
+
+	d.reapContainers()
+	if len(containers.removed) != 1 || containers.removed[0] != "gt-gastown-crew-joe" {
+		t.Errorf("removed = %v, want only the container whose session is gone", containers.removed)
+	}
+
+	// Dry run removes nothing
+	containers.removed = nil
+	d.config.DryRun = true
+	d.reapContainers()
+	if len(containers.removed) != 0 {
+		t.Errorf("dry run removed %v", containers.removed)
+	}
+}
+
+func TestAgentRunningContainerized(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{Containers: &ContainerConfig{
+		Profiles: []*ContainerProfile{{Roles: []string{"polecat"}, Image: "agent"}},
+	}}
+	d.tmux = &hibernateTmux{running: map[string]bool{"gt-gastown-toast": true}}
+
+	parsed := &ParsedIdentity{RoleType: "polecat", RigName: "gastown", AgentName: "toast"}
+	if !d.agentRunning("gastown-polecat-toast", "gt-gastown-toast", parsed) {
+		t.Error("containerized agent with a live session should count as running")
+	}
+	if d.agentRunning("gastown-polecat-nux", "gt-gastown-nux", parsed) {
+		t.Error("containerized agent without a session should not count as running")
+	}
+}
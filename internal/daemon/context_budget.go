@@ -0,0 +1,268 @@
+package daemon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+// Context budget defaults
+const (
+	defaultContextCycleAt   = 80
+	defaultContextForceAt   = 95
+	defaultContextIdleFor   = 2 * time.Minute
+	defaultContextReportAge = 30 * time.Minute
+)
+
+// Context usage report sources.
+const (
+	ContextSourceState = "state"
+	ContextSourceBead  = "bead"
+)
+
+// ContextBudgetConfig turns on budget-aware cycling, under
+// "lifecycle.context_budget" in mayor/daemon.json:
+//
+//	"context_budget": {
+//	  "cycle_at": 80,
+//	  "force_at": 95,
+//	  "idle_for": "2m",
+//	  "agents": ["*-crew-*", "*-polecat-*"]
+//	}
+//
+// Agents report how much of their context window they have used with 'gt
+// agents context <percent>', which records it in their state.json (or, with
+// --bead, on their agent bead). Once an agent reports CycleAt or more, the
+// daemon schedules a cycle and carries it out at the agent's next idle
+// point: when its session has produced no output for IdleFor. At ForceAt
+// it cycles the agent without waiting, before it runs out mid-task.
+type ContextBudgetConfig struct {
+	// CycleAt is the usage, in percent, at which a cycle is scheduled
+	// (default 80).
+	CycleAt int `json:"cycle_at,omitempty"`
+
+	// ForceAt is the usage at which the agent is cycled even if it is busy
+	// (default 95; above 100 never forces).
+	ForceAt int `json:"force_at,omitempty"`
+
+	// IdleFor is how long a session must be quiet to count as idle (Go
+	// duration string, default "2m").
+	IdleFor string `json:"idle_for,omitempty"`
+
+	// MaxReportAge ignores reports older than this (Go duration string,
+	// default "30m"), so a stale report can't cycle a fresh session.
+	MaxReportAge string `json:"max_report_age,omitempty"`
+
+	// Agents are the identities cycled on their budget, exact or
+	// path.Match globs (default: all managed agents).
+	Agents []string `json:"agents,omitempty"`
+}
+
+// covers reports whether identity is cycled on its context budget.
+func (c *ContextBudgetConfig) covers(identity string) bool {
+	return c != nil && (len(c.Agents) == 0 || matchesAnyPattern(c.Agents, identity))
+}
+
+// ContextCycle is a cycle scheduled for an agent near its context limit.
+// Copied into State each heartbeat for gt daemon status.
+type ContextCycle struct {
+	// Usage is the agent's last reported context usage, in percent.
+	Usage int `json:"usage"`
+
+	// Source is where the report came from: "state" or "bead".
+	Source string `json:"source"`
+
+	// ReportedAt is when the agent reported Usage.
+	ReportedAt time.Time `json:"reported_at"`
+
+	// ScheduledAt is when the daemon first saw the agent over budget.
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// contextBudgetMonitor tracks scheduled context cycles between heartbeats.
+type contextBudgetMonitor struct {
+	pending map[string]*ContextCycle
+
+	// cycle carries out a context budget cycle; replaced in tests.
+	cycle func(request *LifecycleRequest) error
+}
+
+func (d *Daemon) contextBudgetState() *contextBudgetMonitor {
+	if d.contextBudgets == nil {
+		d.contextBudgets = &contextBudgetMonitor{
+			pending: make(map[string]*ContextCycle),
+			cycle:   d.executeLifecycleAction,
+		}
+	}
+	return d.contextBudgets
+}
+
+// snapshot returns a copy of the scheduled cycles, or nil if none.
+func (m *contextBudgetMonitor) snapshot() map[string]*ContextCycle {
+	if m == nil || len(m.pending) == 0 {
+		return nil
+	}
+	out := make(map[string]*ContextCycle, len(m.pending))
+	for identity, c := range m.pending {
+		cc := *c
+		out[identity] = &cc
+	}
+	return out
+}
+
+// contextThresholds resolves the cycle and force percentages, idle time,
+// and report age limit.
+func (d *Daemon) contextThresholds(cfg *ContextBudgetConfig) (cycleAt, forceAt int, idleFor, maxAge time.Duration) {
+	cycleAt, forceAt = cfg.CycleAt, cfg.ForceAt
+	if cycleAt <= 0 || cycleAt > 100 {
+		cycleAt = defaultContextCycleAt
+	}
+	if forceAt <= 0 {
+		forceAt = defaultContextForceAt
+	}
+	idleFor = d.lifecycleDuration("context_budget.idle_for", cfg.IdleFor, defaultContextIdleFor)
+	maxAge = d.lifecycleDuration("context_budget.max_report_age", cfg.MaxReportAge, defaultContextReportAge)
+	return cycleAt, forceAt, idleFor, maxAge
+}
+
+// checkContextBudgets schedules a cycle for every covered agent whose
+// reported context usage is over budget, and cycles those whose session has
+// gone idle (or which are about to run out).
+func (d *Daemon) checkContextBudgets(now time.Time) {
+	cfg := d.patrolConfig.lifecycleConfig().ContextBudget
+	if cfg == nil {
+		return
+	}
+	cycleAt, forceAt, idleFor, maxAge := d.contextThresholds(cfg)
+	m := d.contextBudgetState()
+
+	over := make(map[string]bool)
+	for _, identity := range d.managedIdentities() {
+		if !cfg.covers(identity) {
+			continue
+		}
+		session := d.identityToSession(identity)
+		if session == "" {
+			continue
+		}
+		if ok, _ := d.tmux.HasSession(session); !ok {
+			continue
+		}
+		usage, reportedAt, source := d.readContextUsage(identity)
+		if usage < cycleAt || now.Sub(reportedAt) > maxAge {
+			continue
+		}
+		over[identity] = true
+
+		c := m.pending[identity]
+		if c == nil {
+			c = &ContextCycle{ScheduledAt: now}
+			m.pending[identity] = c
+			d.logger.Printf("Context budget: %s at %d%% (from %s), cycling at next idle point", identity, usage, source)
+		}
+		c.Usage, c.ReportedAt, c.Source = usage, reportedAt, source
+
+		why := ""
+		if last, err := d.tmux.SessionActivity(session); err == nil && !last.IsZero() && now.Sub(last) >= idleFor {
+			why = fmt.Sprintf("idle for %v", now.Sub(last).Round(time.Second))
+		} else if usage >= forceAt {
+			why = fmt.Sprintf("at %d%%, not waiting for an idle point", usage)
+		} else {
+			continue
+		}
+
+		request := &LifecycleRequest{
+			From:        identity,
+			Action:      ActionCycle,
+			Timestamp:   now,
+			Reason:      fmt.Sprintf("context at %d%% (%s)", usage, why),
+			RequestedBy: requestedByContextBudget,
+		}
+		if !d.admitRestart(request) {
+			continue // Try again next heartbeat
+		}
+		d.logger.Printf("Context budget: cycling %s at %d%% (%s)", identity, usage, why)
+		if err := m.cycle(request); err != nil {
+			d.logger.Printf("Warning: context budget cycle of %s failed: %v", identity, err)
+			continue
+		}
+		delete(m.pending, identity)
+		delete(over, identity)
+	}
+
+	// Forget cycles that are no longer due (agent gone, report cleared)
+	for identity := range m.pending {
+		if !over[identity] {
+			delete(m.pending, identity)
+		}
+	}
+}
+
+// readContextUsage returns identity's last context usage report: from its
+// state file, else from its agent bead. Reports from before the agent's
+// session was last killed belong to an old session and are ignored.
+func (d *Daemon) readContextUsage(identity string) (usage int, reportedAt time.Time, source string) {
+	var lastKilled time.Time
+	if s, err := d.readAgentState(identity); err == nil && s != nil {
+		lastKilled = s.LastKilledAt
+		if s.ContextUsage > 0 && s.ContextReportedAt.After(lastKilled) {
+			return s.ContextUsage, s.ContextReportedAt, ContextSourceState
+		}
+	}
+
+	agentBeadID := d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return 0, time.Time{}, ""
+	}
+	info, err := d.getAgentBeadInfo(agentBeadID)
+	if err != nil || info.ContextUsage == "" {
+		return 0, time.Time{}, ""
+	}
+	usage, err = ParseContextUsage(info.ContextUsage)
+	if err != nil {
+		return 0, time.Time{}, ""
+	}
+	reportedAt, err = time.Parse(time.RFC3339, info.LastUpdate)
+	if err != nil || !reportedAt.After(lastKilled) {
+		return 0, time.Time{}, ""
+	}
+	return usage, reportedAt, ContextSourceBead
+}
+
+// ParseContextUsage parses a context usage report: a percentage, with or
+// without a "%" ("82", "82%").
+func ParseContextUsage(s string) (int, error) {
+	usage, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if err != nil || usage < 0 || usage > 100 {
+		return 0, fmt.Errorf("invalid context usage %q: want a percentage from 0 to 100", s)
+	}
+	return usage, nil
+}
+
+// ReportContextUsage records how much of its context window identity has
+// used, in its state file or (onBead) on its agent bead. Agents call this
+// via 'gt agents context'.
+func (c *SessionController) ReportContextUsage(identity string, usage int, onBead bool, now time.Time) error {
+	identity = normalizeIdentity(identity)
+	if onBead {
+		agentBeadID := c.d.identityToAgentBeadID(identity)
+		if agentBeadID == "" {
+			return fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+		}
+		return beads.New(c.d.config.TownRoot).UpdateAgentContextUsage(agentBeadID, strconv.Itoa(usage))
+	}
+
+	workDir := c.d.agentWorkDir(identity)
+	if workDir == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+	}
+	return c.d.agentStates().Update(c.d.agentRef(identity, workDir), func(s *state.AgentState) error {
+		s.ContextUsage = usage
+		s.ContextReportedAt = now.UTC()
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// activityTmux reports session activity on top of paneTmux.
+type activityTmux struct {
+	paneTmux
+	activity map[string]time.Time
+}
+
+func (a *activityTmux) SessionActivity(session string) (time.Time, error) {
+	return a.activity[session], nil
+}
+
+func TestCheckContextBudgets(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", "max"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{ContextBudget: &ContextBudgetConfig{
+		Agents: []string{"*-crew-*"},
+	}}}
+	session := d.identityToSession("gastown-crew-max")
+	tm := &activityTmux{paneTmux: paneTmux{panes: map[string]int{session: 100}}, activity: map[string]time.Time{}}
+	d.tmux = tm
+
+	var cycled []string
+	d.contextBudgetState().cycle = func(request *LifecycleRequest) error {
+		cycled = append(cycled, request.From)
+		d.recordKill(request.From, ActionCycle, request.RequestedBy)
+		return nil
+	}
+	ctl := &SessionController{d: d}
+	// Kills are stamped with the wall clock, so the test runs on it too
+	start := time.Now().Truncate(time.Second)
+
+	// Under budget: nothing scheduled
+	if err := ctl.ReportContextUsage("gastown/crew/max", 70, false, start); err != nil {
+		t.Fatalf("ReportContextUsage: %v", err)
+	}
+	d.checkContextBudgets(start)
+	if len(d.contextBudgets.pending) != 0 {
+		t.Fatalf("pending %+v at 70%%", d.contextBudgets.pending)
+	}
+
+	// Over budget while busy: scheduled, not cycled
+	if err := ctl.ReportContextUsage("gastown/crew/max", 85, false, start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	tm.activity[session] = start.Add(90 * time.Second)
+	d.checkContextBudgets(start.Add(2 * time.Minute))
+	c := d.contextBudgets.pending["gastown-crew-max"]
+	if c == nil || c.Usage != 85 || c.Source != ContextSourceState || len(cycled) != 0 {
+		t.Fatalf("busy at 85%%: pending %+v, cycled %v", c, cycled)
+	}
+	if snap := d.contextBudgets.snapshot(); snap["gastown-crew-max"].Usage != 85 {
+		t.Errorf("snapshot = %+v", snap)
+	}
+
+	// Idle: cycled, and the report is cleared with the old session
+	d.checkContextBudgets(start.Add(4 * time.Minute))
+	if len(cycled) != 1 || len(d.contextBudgets.pending) != 0 {
+		t.Fatalf("idle: cycled %v, pending %+v", cycled, d.contextBudgets.pending)
+	}
+	s, err := d.readAgentState("gastown-crew-max")
+	if err != nil || s.ContextUsage != 0 {
+		t.Errorf("state after cycle = %+v, %v; want usage cleared", s, err)
+	}
+	d.checkContextBudgets(start.Add(5 * time.Minute))
+	if len(cycled) != 1 {
+		t.Errorf("cycled again without a new report: %v", cycled)
+	}
+
+	// Nearly out: cycled even while busy
+	now := start.Add(10 * time.Minute)
+	if err := ctl.ReportContextUsage("gastown/crew/max", 97, false, now); err != nil {
+		t.Fatal(err)
+	}
+	tm.activity[session] = now
+	d.checkContextBudgets(now)
+	if len(cycled) != 2 {
+		t.Errorf("at 97%%: cycled %v, want a forced cycle", cycled)
+	}
+
+	// Stale reports are ignored
+	if err := ctl.ReportContextUsage("gastown/crew/max", 90, false, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	d.checkContextBudgets(now.Add(time.Hour))
+	if len(d.contextBudgets.pending) != 0 || len(cycled) != 2 {
+		t.Errorf("stale report: pending %+v, cycled %v", d.contextBudgets.pending, cycled)
+	}
+}
+
+func TestParseContextUsage(t *testing.T) {
+	for in, want := range map[string]int{"82": 82, "90%": 90, " 0 ": 0, "100": 100} {
+		if got, err := ParseContextUsage(in); err != nil || got != want {
+			t.Errorf("ParseContextUsage(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "101", "-1", "0.8", "lots"} {
+		if _, err := ParseContextUsage(in); err == nil {
+			t.Errorf("ParseContextUsage(%q) succeeded, want error", in)
+		}
+	}
+}
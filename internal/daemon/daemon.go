@@ -3,34 +3,41 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/gofrs/flock"
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/boot"
+	"github.com/steveyegge/gastown/internal/budget"
 	"github.com/steveyegge/gastown/internal/config"
 	"github.com/steveyegge/gastown/internal/constants"
 	"github.com/steveyegge/gastown/internal/deacon"
 	"github.com/steveyegge/gastown/internal/events"
 	"github.com/steveyegge/gastown/internal/feed"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/notifier"
 	"github.com/steveyegge/gastown/internal/polecat"
 	"github.com/steveyegge/gastown/internal/refinery"
 	"github.com/steveyegge/gastown/internal/rig"
-	"github.com/steveyegge/gastown/internal/session"
 	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/tracing"
 	"github.com/steveyegge/gastown/internal/util"
 	"github.com/steveyegge/gastown/internal/wisp"
 	"github.com/steveyegge/gastown/internal/witness"
+	"github.com/steveyegge/gastown/internal/workspace"
+	"github.com/steveyegge/gastown/pkg/eventstream"
 )
 
 // Daemon is the town-level background service.
@@ -38,24 +45,144 @@ import (
 // This is recovery-focused: normal wake is handled by feed subscription (bd activity --follow).
 // The daemon is the safety net for dead sessions, GUPP violations, and orphaned work.
 type Daemon struct {
-	config       *Config
-	patrolConfig *DaemonPatrolConfig
-	tmux         *tmux.Tmux
-	logger       *log.Logger
-	ctx          context.Context
-	cancel       context.CancelFunc
-	curator      *feed.Curator
+	config        *Config
+	patrolConfig  *DaemonPatrolConfig
+	tmux          SessionBackend
+	mail          MailClient      // nil: gt mail (see backends.go)
+	beads         BeadsClient     // nil: bd
+	containers    ContainerClient // nil: docker/podman CLI (see container.go)
+	provisioner   RigProvisioner  // nil: gt rig provision (see rig_provision.go)
+	sleep         func(time.Duration)
+	recorder      *recorder // nil unless recording (see recording.go)
+	logger        *log.Logger
+	ctx           context.Context
+	cancel        context.CancelFunc
+	curator       *feed.Curator
 	convoyWatcher *ConvoyWatcher
+	notifier      *notifier.Notifier
 
 	// Mass death detection: track recent session deaths
 	deathsMu     sync.Mutex
 	recentDeaths []sessionDeath
 
+	// Crash loop detection: deaths per session over a longer window.
+	// Guarded by deathsMu.
+	crashHistory map[string][]time.Time
+
+	// Town manifest (town.yaml), reloaded each heartbeat; nil if absent.
+	manifest *manifest.Manifest
+
+	// Deacon inbox poll health, copied into State each heartbeat.
+	mailPoll MailPollStats
+
+	// Signalled when the mail transport pushes deacon mail (see
+	// mail_poll.go); nil without a transport.
+	mailWake      chan struct{}
+	mailTransport mail.Transport
+
 	// Deacon startup tracking: prevents race condition where newly started
 	// sessions are immediately killed by the heartbeat check.
 	// See: https://github.com/steveyegge/gastown/issues/567
 	// Note: Only accessed from heartbeat loop goroutine - no sync needed.
 	deaconLastStarted time.Time
+
+	// The trackers from here to contextBudgets take no locks: heartbeats
+	// and lifecycle processing both run on the main loop goroutine, and a
+	// lifecycle step returns before the loop moves on (see runStep).
+
+	// Restart pacing for lifecycle requests (see restart_throttle.go).
+	restarts *restartThrottle
+
+	// Cycle handoffs requested and awaiting injection (see handoff.go).
+	handoffs *handoffTracker
+
+	// Agent state conflicts already logged, by "<identity>/<field>" (see
+	// state_sync.go).
+	stateConflicts map[string]bool
+
+	// Whether each agent's session was up at the last heartbeat (see
+	// uptime.go).
+	uptimeSeen map[string]bool
+
+	// Recently seen deacon messages, for collapsing duplicates (see
+	// mail_dedup.go).
+	mailDedup *mailDeduper
+
+	// Per-sender lifecycle request counts (see rate_limit.go).
+	rateLimits *rateLimiter
+
+	// Witness escalation history per agent (see escalation.go).
+	escalations *escalationTracker
+
+	// Agent processes tracked per session for zombie reaping (see zombies.go).
+	zombies *zombieReaper
+
+	// Agent self-report heartbeats (see heartbeats.go).
+	heartbeats *heartbeatMonitor
+
+	// Cycles scheduled on agents' context usage (see context_budget.go).
+	contextBudgets *contextBudgetMonitor
+
+	// Event stream for dashboards and the optional HTTP API serving it
+	// (see api.go). The bus is shared with API handler goroutines.
+	eventsOnce sync.Once
+	eventBus   *eventBus
+	apiServer  *http.Server
+
+	// Set while a background merge queue pass runs (see merge_queue.go).
+	mergeQueueBusy atomic.Bool
+
+	// Lifecycle requests accepted by the API, run by the heartbeat loop.
+	apiMu    sync.Mutex
+	apiQueue []queuedLifecycle
+
+	// Heartbeat loop timing for /healthz (see health.go).
+	healthOnce sync.Once
+	health     *healthMonitor
+
+	// Spans of lifecycle operations, nil when tracing is off (see tracing.go).
+	// span is the one in progress. Only the heartbeat loop goroutine traces.
+	tracer *tracing.Tracer
+	span   *tracing.Span
+
+	// lastUpdateCheck is when self-update last checked for a release.
+	lastUpdateCheck time.Time
+
+	// selfBinary and execSelf locate and exec the daemon's binary for a
+	// restart in place (default os.Executable and syscall.Exec); replaced
+	// in tests.
+	selfBinary func() (string, error)
+	execSelf   func(exe string) error
+
+	// lastDiskCheck is when workspace disk usage was last measured;
+	// diskUsage holds the measurements, by identity.
+	lastDiskCheck time.Time
+	diskUsage     map[string]*WorkspaceUsage
+
+	// lastCapabilityRouting is when ready work was last routed by
+	// capability; capabilityGaps is the work that pass could not place.
+	lastCapabilityRouting time.Time
+	capabilityGaps        []CapabilityGap
+
+	// configFingerprint summarizes the config files as of the last load,
+	// to notice when they change (see reload.go).
+	configFingerprint string
+
+	// retentionStore replaces the town beads as the mail retention store
+	// (tests).
+	retentionStore mail.RetentionStore
+
+	// problemStore replaces the town beads as the workspace-problem store
+	// (tests).
+	problemStore WorkspaceProblemStore
+
+	// Leader lease held by this daemon (see leader.go). lostLeadership is
+	// set when another daemon took the lease; this one then stops acting.
+	lease          *Lease
+	lostLeadership bool
+
+	// startupStable is set once this start is journaled as stable (see safemode.go).
+	startupStable bool
 }
 
 // sessionDeath records a detected session death for mass death analysis.
@@ -70,6 +197,16 @@ const (
 	massDeathThreshold = 3                // Number of deaths to trigger alert
 )
 
+// Crash loop detection parameters (single session dying repeatedly)
+const (
+	crashLoopWindow    = 30 * time.Minute
+	crashLoopThreshold = 3
+)
+
+// staleLifecycleSurgeThreshold is how many stale lifecycle messages in a
+// single inbox poll count as a surge worth alerting on.
+const staleLifecycleSurgeThreshold = 5
+
 // New creates a new daemon instance.
 func New(config *Config) (*Daemon, error) {
 	// Ensure daemon directory exists
@@ -93,34 +230,138 @@ func New(config *Config) (*Daemon, error) {
 		logger.Printf("Loaded patrol config from %s", PatrolConfigFile(config.TownRoot))
 	}
 
-	return &Daemon{
+	// Role plugins add identity patterns and session templates, so they
+	// must be registered before anything parses an identity.
+	registerRolePlugins(config.TownRoot, logger)
+	registerRigAliases(config.TownRoot)
+
+	// A broken naming scheme would have the daemon start duplicates of
+	// every agent it can no longer find, so refuse to run with one.
+	if err := patrolConfig.sessionNaming().Validate(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid session_naming in %s: %w", PatrolConfigFile(config.TownRoot), err)
+	}
+
+	// Notification sinks are optional; a broken config disables them rather
+	// than keeping the daemon from starting.
+	var notifyConfig *notifier.Config
+	if patrolConfig != nil && patrolConfig.Notifications != nil {
+		if err := patrolConfig.Notifications.Validate(); err != nil {
+			logger.Printf("Warning: invalid notifications config, alerts disabled: %v", err)
+		} else {
+			notifyConfig = patrolConfig.Notifications
+		}
+	}
+	townName, _ := workspace.GetTownName(config.TownRoot)
+
+	var tracer *tracing.Tracer
+	if patrolConfig != nil && patrolConfig.Tracing != nil {
+		if err := patrolConfig.Tracing.Validate(); err != nil {
+			logger.Printf("Warning: invalid tracing config, tracing disabled: %v", err)
+		} else {
+			tracer = tracing.New(patrolConfig.Tracing)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.MailRetention != nil {
+		if err := patrolConfig.MailRetention.Validate(); err != nil {
+			logger.Printf("Warning: invalid mail_retention config, retention disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.DiskQuota != nil {
+		if err := patrolConfig.DiskQuota.Validate(); err != nil {
+			logger.Printf("Warning: invalid disk_quota config, quotas disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.CapabilityRouting != nil {
+		if err := patrolConfig.CapabilityRouting.Validate(); err != nil {
+			logger.Printf("Warning: invalid capability_routing config, routing disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.Hibernate != nil {
+		if err := patrolConfig.Hibernate.Validate(); err != nil {
+			logger.Printf("Warning: invalid hibernate config, hibernation disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.Containers != nil {
+		if err := patrolConfig.Containers.Validate(); err != nil {
+			logger.Printf("Warning: invalid containers config, agents run on the host: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.RigProvisioning != nil {
+		if err := patrolConfig.RigProvisioning.Validate(); err != nil {
+			logger.Printf("Warning: invalid rig_provisioning config, rig provisioning disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.SessionGC != nil {
+		if err := patrolConfig.SessionGC.Validate(); err != nil {
+			logger.Printf("Warning: invalid session_gc config, stale session collection disabled: %v", err)
+		}
+	}
+	if patrolConfig != nil && patrolConfig.SelfUpdate != nil {
+		if err := patrolConfig.SelfUpdate.Validate(); err != nil {
+			logger.Printf("Warning: invalid self_update config, self-update disabled: %v", err)
+		}
+	}
+
+	// Everything the daemon runs - its own tmux calls, gt subprocesses and
+	// the agents in the sessions it starts - must use the town's server.
+	tmuxSocket := TmuxSocket(config.TownRoot)
+	if tmuxSocket != "" {
+		tmux.SetDefaultSocket(tmuxSocket)
+		_ = os.Setenv(tmux.SocketEnv, tmuxSocket)
+		logger.Printf("Using dedicated tmux server (socket %s)", tmuxSocket)
+	}
+
+	d := &Daemon{
 		config:       config,
 		patrolConfig: patrolConfig,
-		tmux:         tmux.NewTmux(),
+		tmux:         tmux.NewTmuxWithSocket(tmuxSocket),
 		logger:       logger,
 		ctx:          ctx,
 		cancel:       cancel,
-	}, nil
+		notifier:     notifier.New(notifyConfig, townName),
+		crashHistory: make(map[string][]time.Time),
+		tracer:       tracer,
+	}
+	d.restarts = d.newRestartThrottle()
+	if config.RecordFile != "" {
+		rec, err := openRecorder(config.RecordFile)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("opening recording: %w", err)
+		}
+		d.startRecording(rec)
+		logger.Printf("Recording decision inputs to %s", config.RecordFile)
+	}
+	return d, nil
+}
+
+// notify fires an alert event. Delivery failures are logged, never fatal.
+func (d *Daemon) notify(event string, fields map[string]string) {
+	if err := d.notifier.Notify(event, fields); err != nil {
+		d.logger.Printf("Warning: %s notification failed: %v", event, err)
+	}
 }
 
 // Run starts the daemon main loop.
 func (d *Daemon) Run() error {
 	d.logger.Printf("Daemon starting (PID %d)", os.Getpid())
+	if d.config.DryRun {
+		d.logger.Println("Dry-run mode: actions will be verified and logged, not executed")
+	}
 
 	// Acquire exclusive lock to prevent multiple daemons from running.
 	// This prevents the TOCTOU race condition where multiple concurrent starts
 	// can all pass the IsRunning() check before any writes the PID file.
 	// Uses gofrs/flock for cross-platform compatibility (Unix + Windows).
-	lockFile := filepath.Join(d.config.TownRoot, "daemon", "daemon.lock")
-	fileLock := flock.New(lockFile)
-
-	// Try to acquire exclusive lock (non-blocking)
-	locked, err := fileLock.TryLock()
+	// A standby daemon blocks here until the leader dies (see leader.go).
+	fileLock, err := d.acquireLeadership()
 	if err != nil {
-		return fmt.Errorf("acquiring lock: %w", err)
-	}
-	if !locked {
-		return fmt.Errorf("daemon already running (lock held by another process)")
+		if errors.Is(err, errLeaderStopped) || errors.Is(err, context.Canceled) {
+			d.logger.Printf("Standby exiting: %v", err)
+			return nil
+		}
+		return err
 	}
 	defer func() { _ = fileLock.Unlock() }()
 
@@ -128,7 +369,22 @@ func (d *Daemon) Run() error {
 	if err := os.WriteFile(d.config.PidFile, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
 		return fmt.Errorf("writing PID file: %w", err)
 	}
-	defer func() { _ = os.Remove(d.config.PidFile) }() // best-effort cleanup
+	defer func() {
+		// Best-effort cleanup, unless a new leader has already replaced it
+		if data, err := os.ReadFile(d.config.PidFile); err == nil && string(data) == strconv.Itoa(os.Getpid()) {
+			_ = os.Remove(d.config.PidFile)
+		}
+	}()
+
+	// Journal this start; repeated crashes before a first heartbeat put the
+	// daemon in safe mode instead of a silent crash loop
+	if safe, reason := d.beginStartup(time.Now()); safe {
+		return d.runSafeMode(reason)
+	}
+	defer d.recordStartupPanic()
+
+	// Pick up crash counts and retry timers from the previous daemon
+	d.loadRuntimeState()
 
 	// Update state
 	state := &State{
@@ -136,9 +392,23 @@ func (d *Daemon) Run() error {
 		PID:       os.Getpid(),
 		StartedAt: time.Now(),
 	}
-	if err := SaveState(d.config.TownRoot, state); err != nil {
+	// Carry over prewarm records so sessions prewarmed before a restart
+	// are still stopped on time, agents paused by their schedule or
+	// hibernated so they are still resumed, and the rollup, beads sync, mail retention,
+	// and budget check schedules so restarts don't trigger extra rounds.
+	if prev, err := LoadState(d.config.TownRoot); err == nil {
+		state.Prewarmed = prev.Prewarmed
+		state.SchedulePaused = prev.SchedulePaused
+		state.Hibernating = prev.Hibernating
+		state.LastRollupPush = prev.LastRollupPush
+		state.LastBeadsSync = prev.LastBeadsSync
+		state.LastMailRetention = prev.LastMailRetention
+		state.LastBudgetCheck = prev.LastBudgetCheck
+	}
+	if err := d.saveState(state, "daemon/startup"); err != nil {
 		d.logger.Printf("Warning: failed to save state: %v", err)
 	}
+	d.notify(notifier.EventDaemonStart, map[string]string{"pid": strconv.Itoa(os.Getpid())})
 
 	// Handle signals
 	sigChan := make(chan os.Signal, 1)
@@ -151,24 +421,28 @@ func (d *Daemon) Run() error {
 
 	d.logger.Printf("Daemon running, recovery heartbeat interval %v", recoveryHeartbeatInterval)
 
-	// Start feed curator goroutine
-	d.curator = feed.NewCurator(d.config.TownRoot)
-	if err := d.curator.Start(); err != nil {
-		d.logger.Printf("Warning: failed to start feed curator: %v", err)
-	} else {
-		d.logger.Println("Feed curator started")
-	}
-
-	// Start convoy watcher for event-driven convoy completion
-	d.convoyWatcher = NewConvoyWatcher(d.config.TownRoot, d.logger.Printf)
-	if err := d.convoyWatcher.Start(); err != nil {
-		d.logger.Printf("Warning: failed to start convoy watcher: %v", err)
-	} else {
-		d.logger.Println("Convoy watcher started")
-	}
+	// Start the feed curator, the convoy watcher for event-driven convoy
+	// completion, and the event stream API (before the first heartbeat
+	// publishes to it)
+	d.startBackground()
 
 	// Initial heartbeat
 	d.heartbeat(state)
+	d.markStartupStable()
+
+	// Poll the deacon inbox between heartbeats on an adaptive interval:
+	// quickly while lifecycle mail is arriving, backing off when idle.
+	mailTimer := time.NewTimer(d.mailPollInterval())
+	defer mailTimer.Stop()
+
+	// Keep the leader lease fresh even when nothing else is happening
+	leaseTicker := time.NewTicker(leaseRenewInterval)
+	defer leaseTicker.Stop()
+
+	// Watch the config files for changes to reload
+	d.configFingerprint = configFingerprint(d.config.TownRoot)
+	configTicker := time.NewTicker(configWatchInterval)
+	defer configTicker.Stop()
 
 	for {
 		select {
@@ -176,11 +450,35 @@ func (d *Daemon) Run() error {
 			d.logger.Println("Daemon context canceled, shutting down")
 			return d.shutdown(state)
 
+		case <-mailTimer.C:
+			d.processLifecycleRequests()
+			mailTimer.Reset(d.mailPollInterval())
+
+		case <-d.mailWake:
+			d.processLifecycleRequests()
+			if !mailTimer.Stop() {
+				select {
+				case <-mailTimer.C:
+				default:
+				}
+			}
+			mailTimer.Reset(d.mailPollInterval())
+
+		case <-leaseTicker.C:
+			d.confirmLeadership(time.Now())
+
+		case <-configTicker.C:
+			d.checkConfigFiles(state)
+
 		case sig := <-sigChan:
 			if isLifecycleSignal(sig) {
 				// Lifecycle signal: immediate lifecycle processing (from gt handoff)
 				d.logger.Println("Received lifecycle signal, processing lifecycle requests immediately")
 				d.processLifecycleRequests()
+				d.checkSelfUpdate(state, time.Now())
+			} else if isReloadSignal(sig) {
+				d.logger.Println("Received reload signal, reloading config")
+				_ = d.reloadConfig(state, "signal")
 			} else {
 				d.logger.Printf("Received signal %v, shutting down", sig)
 				return d.shutdown(state)
@@ -188,6 +486,7 @@ func (d *Daemon) Run() error {
 
 		case <-timer.C:
 			d.heartbeat(state)
+			d.checkSelfUpdate(state, time.Now())
 
 			// Fixed recovery interval (no activity-based backoff)
 			timer.Reset(recoveryHeartbeatInterval)
@@ -209,7 +508,27 @@ const recoveryHeartbeatInterval = 3 * time.Minute
 // - Agents with work-on-hook not progressing (GUPP violation)
 // - Orphaned work (assigned to dead agents)
 func (d *Daemon) heartbeat(state *State) {
+	if !d.confirmLeadership(time.Now()) {
+		return
+	}
 	d.logger.Println("Heartbeat starting (recovery-focused)")
+	d.healthState().heartbeatStarted(time.Now())
+
+	// 0. Reload the town manifest so the auto-start checks below and the
+	// reconciler (step 16) agree on what should be running
+	d.manifest = d.loadManifest()
+	// and pick up rig aliases left by 'gt rig rename'
+	registerRigAliases(d.config.TownRoot)
+
+	// During an emergency stop nothing is checked or restarted; lifecycle
+	// mail is still answered (restarts are refused) until 'gt town resume'
+	state.EmergencyStop = d.emergencyStop()
+	if state.EmergencyStop != nil {
+		d.logger.Printf("Emergency stop in effect (%s), skipping heartbeat checks", state.EmergencyStop.Reason)
+		d.processLifecycleRequests()
+		d.saveHeartbeatState(state)
+		return
+	}
 
 	// 1. Ensure Deacon is running (restart if dead)
 	// Check patrol config - can be disabled in mayor/daemon.json
@@ -233,6 +552,11 @@ func (d *Daemon) heartbeat(state *State) {
 		d.checkDeaconHeartbeat()
 	}
 
+	// 3b. Offer or perform provisioning of git checkouts that appeared in
+	// the town root, before the rig patrols below so a new rig's witness
+	// and refinery start in the same heartbeat
+	d.provisionNewRigs(state, time.Now())
+
 	// 4. Ensure Witnesses are running for all rigs (restart if dead)
 	// Check patrol config - can be disabled in mayor/daemon.json
 	if IsPatrolEnabled(d.patrolConfig, "witness") {
@@ -255,8 +579,13 @@ func (d *Daemon) heartbeat(state *State) {
 	d.triggerPendingSpawns()
 
 	// 7. Process lifecycle requests
+	d.restarts.beginPass()
 	d.processLifecycleRequests()
 
+	// 7b. Reap requesting_* flags whose lifecycle mail never arrived.
+	// Must run after step 7, which clears the flags of claimed requests.
+	d.reapStaleRequestFlags()
+
 	// 8. (Removed) Stale agent check - violated "discover, don't track"
 
 	// 9. Check for GUPP violations (agents with work-on-hook not progressing)
@@ -274,12 +603,91 @@ func (d *Daemon) heartbeat(state *State) {
 	// This is a safety net - Deacon patrol also does this more frequently.
 	d.cleanupOrphanedProcesses()
 
-	// Update state
+	// 12b. Reap agent processes that outlived their session (zombies).
+	// Unlike step 12, these are found by session tracking and env markers.
+	d.reapZombies(time.Now())
+
+	// 12c. Mark agents that stopped reporting heartbeats stuck (if enabled)
+	d.checkAgentHeartbeats(time.Now())
+
+	// 12d. Cycle agents near the end of their context window when idle (if enabled)
+	d.checkContextBudgets(time.Now())
+
+	// 13. Pipe session output into rotated transcript files (if enabled)
+	d.ensureTranscriptCapture()
+
+	// 14. Prewarm idle agents during the off-peak window (if enabled)
+	d.prewarmAgents(state, time.Now())
+
+	// 15. Push anonymized metrics to the org rollup service (if enabled)
+	d.pushRollup(state, time.Now())
+
+	// 16. Converge sessions and agent beads toward town.yaml (if present),
+	// leaving agents hibernated by step 25 down
+	d.reconcileManifest(state, d.manifest)
+
+	// 17. Sync beads databases that are ahead or behind (if enabled)
+	d.syncBeadsIfDue(state, time.Now())
+
+	// 18. Keep the warm shell pool topped up (if enabled)
+	d.maintainWarmPool()
+
+	// 19. Ensure auto_start role plugin agents are running
+	d.ensureRolePluginsRunning()
+
+	// 20. Archive or delete old mail per the retention rules (if configured)
+	d.applyMailRetentionIfDue(state, time.Now())
+
+	// 21. Measure workspace disk usage against quotas (if configured)
+	d.checkDiskQuotas(state, time.Now())
+
+	// 22. Pause and resume agents per their working hours (if configured)
+	d.enforceSchedules(state, time.Now())
+
+	// 23. Merge ready MRs from rig merge queues in the background (if enabled)
+	d.processMergeQueues()
+
+	// 24. Route ready work to crew with the needed capabilities (if enabled)
+	d.routeWorkByCapability(state, time.Now())
+
+	// 25. Hibernate idle crew and wake it for new work or mail (if enabled)
+	d.hibernateIdleAgents(state, time.Now())
+
+	// 26. Record agent sessions that came up or went down (uptime history)
+	d.trackUptime(time.Now())
+
+	// 27. Bring agent state files in line with their agent beads
+	d.syncAgentStates()
+
+	// 28. Remove agent containers whose session is gone (if configured)
+	d.reapContainers()
+
+	// 29. Report or kill sessions that belong to no registered agent
+	d.collectStaleSessions(state, time.Now())
+
+	// 30. Alert on rig budgets and enforce hard stops (if any rig has a budget)
+	d.checkBudgets(state, time.Now())
+
+	d.saveHeartbeatState(state)
+}
+
+// saveHeartbeatState records the end of a heartbeat in daemon state.
+func (d *Daemon) saveHeartbeatState(state *State) {
+	mailPoll := d.mailPoll
+	state.MailPoll = &mailPoll
+	state.RateLimited = d.rateLimits.snapshot()
+	state.Escalations = d.escalations.snapshot()
+	state.Zombies = d.zombieStats()
+	state.AgentHeartbeats = d.heartbeats.snapshot()
+	state.ContextCycles = d.contextBudgets.snapshot()
 	state.LastHeartbeat = time.Now()
 	state.HeartbeatCount++
-	if err := SaveState(d.config.TownRoot, state); err != nil {
+	if err := d.saveState(state, "daemon/heartbeat"); err != nil {
 		d.logger.Printf("Warning: failed to save state: %v", err)
 	}
+	d.saveRuntimeState()
+	d.flushTraces()
+	d.healthState().heartbeatDone(time.Now())
 
 	d.logger.Printf("Heartbeat complete (#%d)", state.HeartbeatCount)
 }
@@ -289,7 +697,7 @@ const DeaconRole = "deacon"
 
 // getDeaconSessionName returns the Deacon session name for the daemon's town.
 func (d *Daemon) getDeaconSessionName() string {
-	return session.DeaconSessionName()
+	return d.defaultSessionName(&ParsedIdentity{RoleType: "deacon"})
 }
 
 // ensureBootRunning spawns Boot to triage the Deacon.
@@ -305,6 +713,11 @@ func (d *Daemon) ensureBootRunning() {
 		return
 	}
 
+	if d.config.DryRun {
+		d.logger.Println("[dry-run] Would spawn Boot for triage")
+		return
+	}
+
 	// Check for degraded mode
 	degraded := os.Getenv("GT_DEGRADED") == "true"
 	if degraded || !d.tmux.IsAvailable() {
@@ -361,6 +774,18 @@ func (d *Daemon) runDegradedBootTriage(b *boot.Boot) {
 // ensureDeaconRunning ensures the Deacon is running.
 // Uses deacon.Manager for consistent startup behavior (WaitForShellReady, GUPP, etc.).
 func (d *Daemon) ensureDeaconRunning() {
+	if d.manifest.RoleDisabled("", "deacon") {
+		d.logger.Printf("Deacon disabled in town manifest, skipping")
+		return
+	}
+
+	if d.config.DryRun {
+		if running, err := d.tmux.HasSession(d.getDeaconSessionName()); err == nil && !running {
+			d.logger.Println("[dry-run] Would start the Deacon")
+		}
+		return
+	}
+
 	mgr := deacon.NewManager(d.config.TownRoot)
 
 	if err := mgr.Start(""); err != nil {
@@ -431,13 +856,25 @@ func (d *Daemon) checkDeaconHeartbeat() {
 	// Session exists but heartbeat is stale - Deacon is stuck
 	if age > 30*time.Minute {
 		// Very stuck - restart the session
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Deacon stuck for %s - would kill session %s", age.Round(time.Minute), sessionName)
+			return
+		}
 		d.logger.Printf("Deacon stuck for %s - restarting session", age.Round(time.Minute))
-		if err := d.tmux.KillSession(sessionName); err != nil {
+		d.trackSessionProcesses("deacon", sessionName)
+		err := d.tmux.KillSession(sessionName)
+		d.audit(AuditKillSession, sessionName, "daemon/deacon-heartbeat",
+			[]string{"session exists", fmt.Sprintf("deacon heartbeat stale for %s (> 30m)", age.Round(time.Minute))}, err)
+		if err != nil {
 			d.logger.Printf("Error killing stuck Deacon: %v", err)
 		}
 		// ensureDeaconRunning will restart on next heartbeat
 	} else {
 		// Stuck but not critically - nudge to wake up
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Deacon stuck for %s - would nudge session %s", age.Round(time.Minute), sessionName)
+			return
+		}
 		d.logger.Printf("Deacon stuck for %s - nudging session", age.Round(time.Minute))
 		if err := d.tmux.NudgeSession(sessionName, "HEALTH_CHECK: heartbeat stale, respond to confirm responsiveness"); err != nil {
 			d.logger.Printf("Error nudging stuck Deacon: %v", err)
@@ -462,10 +899,25 @@ func (d *Daemon) ensureWitnessRunning(rigName string) {
 		d.logger.Printf("Skipping witness auto-start for %s: %s", rigName, reason)
 		return
 	}
+	if d.manifest.RoleDisabled(rigName, "witness") {
+		d.logger.Printf("Skipping witness auto-start for %s: disabled in town manifest", rigName)
+		return
+	}
+	if outside, reason := d.outsideSchedule(rigName+"-witness", time.Now()); outside {
+		d.logger.Printf("Skipping witness auto-start for %s: %s", rigName, reason)
+		return
+	}
 
 	// Manager.Start() handles: zombie detection, session creation, env vars, theming,
 	// startup readiness waits, and crucially - startup/propulsion nudges (GUPP).
 	// It returns ErrAlreadyRunning if Claude is already running in tmux.
+	if d.config.DryRun {
+		if running, err := d.tmux.HasSession(d.identityToSession(rigName + "-witness")); err == nil && !running {
+			d.logger.Printf("[dry-run] Would start the witness for %s", rigName)
+		}
+		return
+	}
+
 	r := &rig.Rig{
 		Name: rigName,
 		Path: filepath.Join(d.config.TownRoot, rigName),
@@ -502,10 +954,25 @@ func (d *Daemon) ensureRefineryRunning(rigName string) {
 		d.logger.Printf("Skipping refinery auto-start for %s: %s", rigName, reason)
 		return
 	}
+	if d.manifest.RoleDisabled(rigName, "refinery") {
+		d.logger.Printf("Skipping refinery auto-start for %s: disabled in town manifest", rigName)
+		return
+	}
+	if outside, reason := d.outsideSchedule(rigName+"-refinery", time.Now()); outside {
+		d.logger.Printf("Skipping refinery auto-start for %s: %s", rigName, reason)
+		return
+	}
 
 	// Manager.Start() handles: zombie detection, session creation, env vars, theming,
 	// WaitForClaudeReady, and crucially - startup/propulsion nudges (GUPP).
 	// It returns ErrAlreadyRunning if Claude is already running in tmux.
+	if d.config.DryRun {
+		if running, err := d.tmux.HasSession(d.identityToSession(rigName + "-refinery")); err == nil && !running {
+			d.logger.Printf("[dry-run] Would start the refinery for %s", rigName)
+		}
+		return
+	}
+
 	r := &rig.Rig{
 		Name: rigName,
 		Path: filepath.Join(d.config.TownRoot, rigName),
@@ -603,6 +1070,10 @@ func (d *Daemon) triggerPendingSpawns() {
 		return
 	}
 
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would trigger %d pending spawn(s)", len(pending))
+		return
+	}
 	d.logger.Printf("Found %d pending spawn(s), attempting to trigger...", len(pending))
 
 	// Trigger pending spawns (uses WaitForRuntimeReady with short timeout)
@@ -636,7 +1107,14 @@ func (d *Daemon) triggerPendingSpawns() {
 
 // processLifecycleRequests checks for and processes lifecycle requests.
 func (d *Daemon) processLifecycleRequests() {
+	if !d.confirmLeadership(time.Now()) {
+		return
+	}
+	if stop := d.emergencyStop(); stop != nil {
+		d.enforceEmergencyStop(stop)
+	}
 	d.ProcessLifecycleRequests()
+	d.processAPILifecycleRequests()
 }
 
 // shutdown performs graceful shutdown.
@@ -655,10 +1133,29 @@ func (d *Daemon) shutdown(state *State) error { //nolint:unparam // error return
 		d.logger.Println("Convoy watcher stopped")
 	}
 
+	d.stopAPI()
+	if d.mailTransport != nil {
+		_ = d.mailTransport.Close()
+	}
+	d.flushTraces()
+	if d.recorder != nil {
+		d.recorder.close()
+	}
+
+	// A daemon that lost leadership leaves state to the new leader
+	if d.lostLeadership {
+		d.logger.Println("Daemon stopped (no longer leader)")
+		return nil
+	}
+
 	state.Running = false
-	if err := SaveState(d.config.TownRoot, state); err != nil {
+	if err := d.saveState(state, "daemon/shutdown"); err != nil {
 		d.logger.Printf("Warning: failed to save final state: %v", err)
 	}
+	d.saveRuntimeState()
+	d.releaseLeadership()
+	d.markStartupStable()
+	d.notify(notifier.EventDaemonStop, map[string]string{"pid": strconv.Itoa(os.Getpid())})
 
 	d.logger.Println("Daemon stopped")
 	return nil
@@ -797,7 +1294,7 @@ func listPolecatWorktrees(polecatsDir string) ([]string, error) {
 // If the polecat has work-on-hook but the tmux session is dead, it's restarted.
 func (d *Daemon) checkPolecatHealth(rigName, polecatName string) {
 	// Build the expected tmux session name
-	sessionName := fmt.Sprintf("gt-%s-%s", rigName, polecatName)
+	sessionName := d.defaultSessionName(&ParsedIdentity{RoleType: "polecat", RigName: rigName, AgentName: polecatName})
 
 	// Check if tmux session exists
 	sessionAlive, err := d.tmux.HasSession(sessionName)
@@ -836,9 +1333,19 @@ func (d *Daemon) checkPolecatHealth(rigName, polecatName string) {
 	// Track this death for mass death detection
 	d.recordSessionDeath(sessionName)
 
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would restart crashed polecat %s/%s", rigName, polecatName)
+		return
+	}
+
 	// Auto-restart the polecat
 	if err := d.restartPolecatSession(rigName, polecatName, sessionName); err != nil {
 		d.logger.Printf("Error restarting polecat %s/%s: %v", rigName, polecatName, err)
+		d.notify(notifier.EventRestartFailed, map[string]string{
+			"agent":   rigName + "/polecats/" + polecatName,
+			"session": sessionName,
+			"error":   err.Error(),
+		})
 		// Notify witness as fallback
 		d.notifyWitnessOfCrashedPolecat(rigName, polecatName, info.HookBead, err)
 	} else {
@@ -848,6 +1355,8 @@ func (d *Daemon) checkPolecatHealth(rigName, polecatName string) {
 
 // recordSessionDeath records a session death and checks for mass death pattern.
 func (d *Daemon) recordSessionDeath(sessionName string) {
+	d.publish(eventstream.TypeAgentState, "", map[string]any{"to": "dead", "session": sessionName})
+
 	d.deathsMu.Lock()
 	defer d.deathsMu.Unlock()
 
@@ -873,6 +1382,31 @@ func (d *Daemon) recordSessionDeath(sessionName string) {
 	if len(d.recentDeaths) >= massDeathThreshold {
 		d.emitMassDeathEvent()
 	}
+
+	// Check for a crash loop on this session
+	if d.crashHistory == nil {
+		d.crashHistory = make(map[string][]time.Time)
+	}
+	loopCutoff := now.Add(-crashLoopWindow)
+	var crashes []time.Time
+	for _, ts := range d.crashHistory[sessionName] {
+		if ts.After(loopCutoff) {
+			crashes = append(crashes, ts)
+		}
+	}
+	crashes = append(crashes, now)
+	d.crashHistory[sessionName] = crashes
+
+	if len(crashes) >= crashLoopThreshold {
+		d.logger.Printf("CRASH LOOP DETECTED: session %s died %d times in %s", sessionName, len(crashes), crashLoopWindow)
+		d.notify(notifier.EventCrashLoop, map[string]string{
+			"session": sessionName,
+			"count":   strconv.Itoa(len(crashes)),
+			"window":  crashLoopWindow.String(),
+		})
+		// Reset so we alert once per loop, not on every further death
+		delete(d.crashHistory, sessionName)
+	}
 }
 
 // emitMassDeathEvent logs a mass death event when multiple sessions die in a short window.
@@ -898,10 +1432,20 @@ func (d *Daemon) emitMassDeathEvent() {
 
 // restartPolecatSession restarts a crashed polecat session.
 func (d *Daemon) restartPolecatSession(rigName, polecatName, sessionName string) error {
+	if err := d.checkNotStopped(rigName + "-polecat-" + polecatName); err != nil {
+		return err
+	}
+
 	// Check rig operational state before auto-restarting
 	if operational, reason := d.isRigOperational(rigName); !operational {
 		return fmt.Errorf("cannot restart polecat: %s", reason)
 	}
+	if ok, reason := budget.CheckRole(d.config.TownRoot, rigName, "polecat"); !ok {
+		return fmt.Errorf("cannot restart polecat: %s", reason)
+	}
+	if outside, reason := d.outsideSchedule(rigName+"-polecat-"+polecatName, time.Now()); outside {
+		return fmt.Errorf("cannot restart polecat: %s", reason)
+	}
 
 	// Calculate rig path for agent config resolution
 	rigPath := filepath.Join(d.config.TownRoot, rigName)
@@ -921,7 +1465,9 @@ func (d *Daemon) restartPolecatSession(rigName, polecatName, sessionName string)
 	}
 
 	// Pre-sync workspace (ensure beads are current)
-	d.syncWorkspace(workDir)
+	if err := d.syncWorkspace(d.context(), workDir); err != nil {
+		return err
+	}
 
 	// Create new tmux session
 	// Use EnsureSessionFresh to handle zombie sessions that exist but have dead Claude
@@ -954,7 +1500,7 @@ func (d *Daemon) restartPolecatSession(rigName, polecatName, sessionName string)
 	// Launch Claude with environment exported inline
 	// Pass rigPath so rig agent settings are honored (not town-level defaults)
 	startCmd := config.BuildStartupCommand(envVars, rigPath, "")
-	if err := d.tmux.SendKeys(sessionName, startCmd); err != nil {
+	if err := d.sendStartCommand(d.tmux, sessionName, startCmd); err != nil {
 		return fmt.Errorf("sending startup command: %w", err)
 	}
 
@@ -980,9 +1526,7 @@ restart_error: %v
 Manual intervention may be required.`,
 		polecatName, hookBead, restartErr)
 
-	cmd := exec.Command("gt", "mail", "send", witnessAddr, "-s", subject, "-m", body) //nolint:gosec // G204: args are constructed internally
-	cmd.Dir = d.config.TownRoot
-	if err := cmd.Run(); err != nil {
+	if err := d.mailClient().Send(witnessAddr, subject, body); err != nil {
 		d.logger.Printf("Warning: failed to notify witness of crashed polecat: %v", err)
 	}
 }
@@ -992,6 +1536,18 @@ Manual intervention may be required.`,
 // Detection uses TTY column: processes with TTY "?" have no controlling terminal.
 // This is a safety net fallback - Deacon patrol also runs this more frequently.
 func (d *Daemon) cleanupOrphanedProcesses() {
+	if d.config.DryRun {
+		orphans, err := util.FindOrphanedClaudeProcesses()
+		if err != nil {
+			d.logger.Printf("Warning: orphan process scan failed: %v", err)
+			return
+		}
+		for _, p := range orphans {
+			d.logger.Printf("[dry-run] Would signal orphaned PID %d (%s)", p.PID, p.Cmd)
+		}
+		return
+	}
+
 	results, err := util.CleanupOrphanedClaudeProcesses()
 	if err != nil {
 		d.logger.Printf("Warning: orphan process cleanup failed: %v", err)
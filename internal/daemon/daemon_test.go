@@ -2,11 +2,17 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/steveyegge/gastown/internal/deacon"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -274,3 +280,260 @@ func TestLifecycleRequest_Serialization(t *testing.T) {
 		t.Errorf("Action mismatch: got %q, want %q", loaded.Action, request.Action)
 	}
 }
+
+func TestRecordSessionDeath_CrashLoop(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	// The crash loop alert logs an event to the town found from the
+	// working directory; keep it in the test town.
+	t.Chdir(d.config.TownRoot)
+
+	d.recordSessionDeath("gt-gastown-witness")
+	d.recordSessionDeath("gt-gastown-witness")
+	if got := len(d.crashHistory["gt-gastown-witness"]); got != 2 {
+		t.Fatalf("expected 2 recorded crashes, got %d", got)
+	}
+
+	// Third death within the window is a crash loop; history resets after alerting
+	d.recordSessionDeath("gt-gastown-witness")
+	if got := len(d.crashHistory["gt-gastown-witness"]); got != 0 {
+		t.Errorf("expected crash history reset after crash loop, got %d", got)
+	}
+}
+
+func TestTranscriptRecordCommand(t *testing.T) {
+	got := transcriptRecordCommand("/town/logs/transcripts/gt-x", &TranscriptConfig{Enabled: true, MaxSizeMB: 20, Keep: 3})
+	expected := "gt transcript record --dir '/town/logs/transcripts/gt-x' --max-size-mb 20 --keep 3"
+	if got != expected {
+		t.Errorf("transcriptRecordCommand() = %q, expected %q", got, expected)
+	}
+
+	got = transcriptRecordCommand("/it's/here", &TranscriptConfig{Enabled: true})
+	expected = `gt transcript record --dir '/it'\''s/here'`
+	if got != expected {
+		t.Errorf("transcriptRecordCommand() = %q, expected %q", got, expected)
+	}
+}
+
+func TestPrewarmWindow(t *testing.T) {
+	at := func(hh, mm int) time.Time { return time.Date(2026, 5, 10, hh, mm, 0, 0, time.UTC) }
+
+	tests := []struct {
+		window   string
+		now      time.Time
+		inWindow bool
+		opened   time.Time
+	}{
+		{"02:00-05:00", at(3, 0), true, at(2, 0)},
+		{"02:00-05:00", at(5, 0), false, time.Time{}},
+		{"23:00-04:00", at(23, 30), true, at(23, 0)},
+		{"23:00-04:00", at(1, 0), true, at(23, 0).AddDate(0, 0, -1)},
+		{"23:00-04:00", at(12, 0), false, time.Time{}},
+	}
+
+	for _, tc := range tests {
+		w, err := parsePrewarmWindow(tc.window)
+		if err != nil {
+			t.Fatalf("parsePrewarmWindow(%q): %v", tc.window, err)
+		}
+		opened, in := w.opened(tc.now)
+		if in != tc.inWindow || (in && !opened.Equal(tc.opened)) {
+			t.Errorf("%s at %s: opened=%v in=%v, expected %v %v", tc.window, tc.now.Format("15:04"), opened, in, tc.opened, tc.inWindow)
+		}
+	}
+
+	for _, bad := range []string{"", "2am-5am", "02:00-02:00", "25:00-03:00"} {
+		if _, err := parsePrewarmWindow(bad); err == nil {
+			t.Errorf("parsePrewarmWindow(%q) should fail", bad)
+		}
+	}
+
+	if !matchesPrewarmAgents("gastown-crew-max", defaultPrewarmAgents) || matchesPrewarmAgents("gastown-witness", defaultPrewarmAgents) {
+		t.Error("default prewarm agents should match crew only")
+	}
+}
+
+func TestRigIdentities(t *testing.T) {
+	townRoot := t.TempDir()
+	for _, name := range []string{"max", "joe", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := RigIdentities(townRoot, "gastown")
+	want := []string{"gastown-witness", "gastown-refinery", "gastown-crew-joe", "gastown-crew-max"}
+	if !slices.Equal(got, want) {
+		t.Errorf("RigIdentities() = %v, expected %v", got, want)
+	}
+}
+
+func TestNormalizeIdentity(t *testing.T) {
+	tests := map[string]string{
+		"gastown/witness":        "gastown-witness",
+		"gastown/refinery":       "gastown-refinery",
+		"gastown/crew/max":       "gastown-crew-max",
+		"gastown/polecats/toast": "gastown/polecats/toast",
+		"gastown-crew-max":       "gastown-crew-max",
+		"mayor":                  "mayor",
+	}
+	for in, want := range tests {
+		if got := normalizeIdentity(in); got != want {
+			t.Errorf("normalizeIdentity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBeginStartupSafeMode(t *testing.T) {
+	townRoot := t.TempDir()
+	d := &Daemon{config: &Config{TownRoot: townRoot}, logger: log.New(io.Discard, "", 0)}
+	now := time.Now()
+
+	// Two recent crashes and one old one: not enough for safe mode
+	j := &StartupJournal{Attempts: []StartupRecord{
+		{StartedAt: now.Add(-time.Hour), PID: 1},
+		{StartedAt: now.Add(-2 * time.Minute), PID: 2},
+		{StartedAt: now.Add(-time.Minute), PID: 3, Cause: "panic: nil map"},
+	}}
+	if err := saveStartupJournal(townRoot, j); err != nil {
+		t.Fatal(err)
+	}
+	if safe, _ := d.beginStartup(now); safe {
+		t.Fatal("safe mode after 2 recent crashes")
+	}
+
+	// This start never became stable, so the next one is the third crash
+	safe, reason := d.beginStartup(now.Add(time.Second))
+	if !safe {
+		t.Fatal("expected safe mode after 3 recent crashes")
+	}
+	if !strings.Contains(reason, "panic: nil map") {
+		t.Errorf("reason = %q, want last crash cause", reason)
+	}
+
+	// Safe mode is sticky until cleared
+	if safe, _ := d.beginStartup(now.Add(time.Hour)); !safe {
+		t.Error("safe mode should persist until cleared")
+	}
+	if err := ClearSafeMode(townRoot); err != nil {
+		t.Fatal(err)
+	}
+	if safe, _ := d.beginStartup(now.Add(time.Hour)); safe {
+		t.Error("safe mode should be off after ClearSafeMode")
+	}
+}
+
+func TestDedupeBeadsWorkspaces(t *testing.T) {
+	townRoot := t.TempDir()
+	rigBeads := filepath.Join(townRoot, "gastown", "mayor", "rig", ".beads")
+	crewBeads := filepath.Join(townRoot, "gastown", "crew", "max", ".beads")
+	for _, dir := range []string{filepath.Join(townRoot, ".beads"), rigBeads, crewBeads} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Crew worktree redirects to the rig database
+	if err := os.WriteFile(filepath.Join(crewBeads, "redirect"), []byte("../../mayor/rig/.beads\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := dedupeBeadsWorkspaces([]string{
+		townRoot,
+		filepath.Join(townRoot, "gastown"), // no .beads
+		filepath.Join(townRoot, "gastown", "mayor", "rig"),
+		filepath.Join(townRoot, "gastown", "crew", "max"),
+	})
+	want := []string{townRoot, filepath.Join(townRoot, "gastown", "mayor", "rig")}
+	if !slices.Equal(got, want) {
+		t.Errorf("dedupeBeadsWorkspaces = %v, want %v", got, want)
+	}
+}
+
+func TestAuditChain(t *testing.T) {
+	townRoot := t.TempDir()
+	for _, target := range []string{"gt-mayor", "gt-deacon", "hq-123"} {
+		if _, err := AppendAudit(townRoot, AuditRecord{Op: AuditKillSession, Target: target, RequestedBy: "mayor/", Outcome: AuditOutcomeOK}); err != nil {
+			t.Fatalf("AppendAudit: %v", err)
+		}
+	}
+
+	records, err := LoadAuditLog(townRoot)
+	if err != nil {
+		t.Fatalf("LoadAuditLog: %v", err)
+	}
+	if len(records) != 3 || records[2].Seq != 3 || records[2].PrevHash != records[1].Hash {
+		t.Fatalf("records not chained: %+v", records)
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		t.Fatalf("VerifyAuditChain on intact log: %v", err)
+	}
+
+	// Rewriting who asked for a kill must be detected.
+	records[1].RequestedBy = "deacon/"
+	var chainErr *AuditChainError
+	if err := VerifyAuditChain(records); !errors.As(err, &chainErr) || chainErr.Seq != 2 {
+		t.Errorf("tampered record: err = %v, want chain break at seq 2", err)
+	}
+
+	// Dropping a record breaks the chain too.
+	if err := VerifyAuditChain([]AuditRecord{records[0], records[2]}); !errors.As(err, &chainErr) {
+		t.Errorf("deleted record: err = %v, want chain break", err)
+	}
+}
+
+func TestAuditRequestContext(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	d.auditRequest(AuditKillSession, "gt-gastown-crew-max",
+		&LifecycleRequest{From: "gastown-crew-max", Action: ActionCycle, Reason: "context full", TaskBead: "gt-abc", Urgency: UrgencyHigh},
+		[]string{"session running"}, nil)
+
+	records, err := LoadAuditLog(d.config.TownRoot)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("LoadAuditLog = %+v, %v", records, err)
+	}
+	rec := records[0]
+	if rec.RequestedBy != "gastown-crew-max" || rec.Reason != "context full" || rec.TaskBead != "gt-abc" || rec.Urgency != UrgencyHigh {
+		t.Errorf("record = %+v, want the request's context", rec)
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		t.Errorf("VerifyAuditChain: %v", err)
+	}
+}
+
+func TestCheckDeaconHeartbeatDryRun(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	var logs strings.Builder
+	d.logger = log.New(&logs, "", 0)
+	d.config.DryRun = true
+	if err := deacon.WriteHeartbeat(d.config.TownRoot, &deacon.Heartbeat{Timestamp: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	session := d.getDeaconSessionName()
+	sessions := &hibernateTmux{running: map[string]bool{session: true}}
+	d.tmux = sessions
+
+	d.checkDeaconHeartbeat()
+
+	if !sessions.running[session] {
+		t.Error("dry run killed the stuck deacon")
+	}
+	if !strings.Contains(logs.String(), "[dry-run] Deacon stuck") {
+		t.Errorf("dry run didn't log the kill it skipped:\n%s", logs.String())
+	}
+}
+
+func TestRestartPolecatSessionHonorsEmergencyStop(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	d.tmux = &hibernateTmux{running: map[string]bool{}}
+	if _, err := RequestEmergencyStop(d.config.TownRoot, "test", "overseer"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := d.restartPolecatSession("gastown", "toast", "gt-gastown-toast")
+	if !errors.Is(err, ErrEmergencyStop) {
+		t.Errorf("restartPolecatSession during an emergency stop = %v, want ErrEmergencyStop", err)
+	}
+}
@@ -0,0 +1,367 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/notifier"
+)
+
+// defaultDiskQuotaInterval is how often workspace disk usage is measured.
+const defaultDiskQuotaInterval = time.Hour
+
+// cleanCommandTimeout bounds each workspace cleanup command.
+const cleanCommandTimeout = 10 * time.Minute
+
+// DiskQuotaConfig tracks workspace disk usage against quotas, under
+// "disk_quota" in mayor/daemon.json:
+//
+//	"disk_quota": {
+//	  "roles": {"crew": "5GB", "refinery": "10GB"},
+//	  "workspaces": {"gastown-crew-max": "20GB"},
+//	  "clean": ["rm -rf node_modules dist", "go clean -cache"],
+//	  "clean_before_restart": true
+//	}
+//
+// Only workspaces with a quota are measured. Over-quota workspaces are
+// reported by 'gt daemon status' and fire a disk_quota notification;
+// 'gt workspace clean' runs the clean commands in a workspace.
+type DiskQuotaConfig struct {
+	// CheckInterval is how often usage is measured (Go duration string,
+	// default "1h").
+	CheckInterval string `json:"check_interval,omitempty"`
+
+	// Roles sets the quota of every workspace of a role (crew, refinery,
+	// witness, polecat, mayor, deacon), as a size such as "5GB".
+	Roles map[string]string `json:"roles,omitempty"`
+
+	// Workspaces overrides the quota of individual agents by identity.
+	Workspaces map[string]string `json:"workspaces,omitempty"`
+
+	// Clean lists shell commands run in a workspace to clean it, in order.
+	Clean []string `json:"clean,omitempty"`
+
+	// CleanBeforeRestart cleans an over-quota workspace when its session
+	// is restarted or cycled, before the new session starts.
+	CleanBeforeRestart bool `json:"clean_before_restart,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *DiskQuotaConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.CheckInterval != "" {
+		if d, err := time.ParseDuration(c.CheckInterval); err != nil || d <= 0 {
+			return fmt.Errorf("disk_quota: invalid check_interval %q", c.CheckInterval)
+		}
+	}
+	for _, quotas := range []map[string]string{c.Roles, c.Workspaces} {
+		for key, size := range quotas {
+			if _, err := ParseSize(size); err != nil {
+				return fmt.Errorf("disk_quota: %s: %w", key, err)
+			}
+		}
+	}
+	for _, cmd := range c.Clean {
+		if strings.TrimSpace(cmd) == "" {
+			return fmt.Errorf("disk_quota: empty clean command")
+		}
+	}
+	return nil
+}
+
+// sizeUnits are the accepted size suffixes, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "500MB", "1.5G", or "2048" (bytes).
+// Units are binary: 1KB is 1024 bytes.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if rest, ok := strings.CutSuffix(str, u.suffix); ok {
+			str, mult = strings.TrimSpace(rest), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize formats a byte count for display ("1.5GB").
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits[:4] {
+		if n >= u.bytes {
+			return strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+// WorkspaceUsage is the measured disk usage of an agent's workspace.
+type WorkspaceUsage struct {
+	Dir       string    `json:"dir"`
+	Bytes     int64     `json:"bytes"`
+	Quota     int64     `json:"quota,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Over reports whether the workspace exceeds its quota.
+func (u *WorkspaceUsage) Over() bool {
+	return u.Quota > 0 && u.Bytes > u.Quota
+}
+
+// DirSize returns the total size of the regular files under dir. Symlinks
+// are not followed.
+func DirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // Unreadable entries don't stop the walk
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// diskQuotaConfig returns the disk quota config, or nil if it is unset or
+// invalid.
+func (c *DaemonPatrolConfig) diskQuotaConfig() *DiskQuotaConfig {
+	if c == nil || c.DiskQuota.Validate() != nil {
+		return nil
+	}
+	return c.DiskQuota
+}
+
+// quotaFor returns the identity's quota in bytes, or 0 if it has none.
+func (c *DiskQuotaConfig) quotaFor(identity string) int64 {
+	size, ok := c.Workspaces[identity]
+	if !ok {
+		parsed, err := parseIdentity(identity)
+		if err != nil {
+			return 0
+		}
+		if size, ok = c.Roles[parsed.RoleType]; !ok {
+			return 0
+		}
+	}
+	n, _ := ParseSize(size)
+	return n
+}
+
+// checkDiskQuotas measures every workspace with a quota once per check
+// interval and warns about workspaces that newly exceed theirs.
+func (d *Daemon) checkDiskQuotas(state *State, now time.Time) {
+	cfg := d.patrolConfig.diskQuotaConfig()
+	if cfg == nil {
+		return
+	}
+	interval := defaultDiskQuotaInterval
+	if cfg.CheckInterval != "" {
+		interval, _ = time.ParseDuration(cfg.CheckInterval) // checked by Validate
+	}
+	if !d.lastDiskCheck.IsZero() && now.Sub(d.lastDiskCheck) < interval {
+		state.DiskUsage = d.diskUsage
+		return
+	}
+	d.lastDiskCheck = now
+
+	span := d.startSpan("disk.quota")
+	defer d.endSpan(span, nil)
+	usage := make(map[string]*WorkspaceUsage)
+	for _, identity := range d.managedIdentities() {
+		quota := cfg.quotaFor(identity)
+		if quota == 0 {
+			continue
+		}
+		workDir := d.agentWorkDir(identity)
+		if workDir == "" {
+			continue
+		}
+		size, err := DirSize(workDir)
+		if err != nil {
+			continue // Workspace not created yet
+		}
+		u := &WorkspaceUsage{Dir: workDir, Bytes: size, Quota: quota, CheckedAt: now}
+		usage[identity] = u
+		if u.Over() && (d.diskUsage[identity] == nil || !d.diskUsage[identity].Over()) {
+			d.logger.Printf("Warning: workspace of %s is over quota: %s of %s (%s)",
+				identity, FormatSize(u.Bytes), FormatSize(u.Quota), workDir)
+			d.notify(notifier.EventDiskQuota, map[string]string{
+				"agent": identity,
+				"usage": FormatSize(u.Bytes),
+				"quota": FormatSize(u.Quota),
+			})
+		}
+	}
+	d.diskUsage = usage
+	state.DiskUsage = usage
+}
+
+// cleanBeforeRestart runs the clean commands in an over-quota workspace
+// before its session is recreated, when configured to.
+func (d *Daemon) cleanBeforeRestart(identity, workDir string) {
+	cfg := d.patrolConfig.diskQuotaConfig()
+	if cfg == nil || !cfg.CleanBeforeRestart || len(cfg.Clean) == 0 {
+		return
+	}
+	if u := d.diskUsage[identity]; u == nil || !u.Over() {
+		return
+	}
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would clean over-quota workspace %s before restart", workDir)
+		return
+	}
+	span := d.startSpan("workspace.clean", "gt.workdir", workDir)
+	report, err := CleanWorkspace(workDir, cfg.Clean)
+	d.endSpan(span, err)
+	if err != nil {
+		d.logger.Printf("Warning: cleaning workspace of %s: %v", identity, err)
+		return
+	}
+	d.logger.Printf("Cleaned workspace of %s before restart: %s -> %s",
+		identity, FormatSize(report.Before), FormatSize(report.After))
+	d.diskUsage[identity].Bytes = report.After
+}
+
+// CleanCommandResult is the outcome of one clean command.
+type CleanCommandResult struct {
+	Command string `json:"command"`
+	Error   string `json:"error,omitempty"`
+	Output  string `json:"output,omitempty"` // on failure
+}
+
+// CleanReport is the outcome of cleaning a workspace.
+type CleanReport struct {
+	Dir      string               `json:"dir"`
+	Before   int64                `json:"before"`
+	After    int64                `json:"after"`
+	Commands []CleanCommandResult `json:"commands"`
+}
+
+// Failed returns how many clean commands failed.
+func (r *CleanReport) Failed() int {
+	n := 0
+	for _, c := range r.Commands {
+		if c.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// CleanWorkspace runs the clean commands in dir with sh -c, in order. A
+// failing command doesn't stop the ones after it. Returns an error only if
+// dir can't be measured.
+func CleanWorkspace(dir string, commands []string) (*CleanReport, error) {
+	before, err := DirSize(dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &CleanReport{Dir: dir, Before: before}
+	for _, command := range commands {
+		result := CleanCommandResult{Command: command}
+		ctx, cancel := context.WithTimeout(context.Background(), cleanCommandTimeout)
+		cmd := exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // G204: commands come from the town's daemon.json
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			result.Error = err.Error()
+			result.Output = strings.TrimSpace(string(out))
+		}
+		cancel()
+		report.Commands = append(report.Commands, result)
+	}
+	report.After, _ = DirSize(dir)
+	return report, nil
+}
+
+// ErrNoCleanCommands is returned when a clean is requested but daemon.json
+// configures no clean commands.
+var ErrNoCleanCommands = errors.New("no disk_quota.clean commands configured")
+
+// WorkspaceUsage measures the identity's workspace now.
+func (c *SessionController) WorkspaceUsage(identity string) (*WorkspaceUsage, error) {
+	workDir := c.WorkDir(identity)
+	if workDir == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+	}
+	size, err := DirSize(workDir)
+	if err != nil {
+		return nil, classify(ErrStateVerificationFailed, fmt.Errorf("measuring %s: %w", workDir, err))
+	}
+	u := &WorkspaceUsage{Dir: workDir, Bytes: size, CheckedAt: time.Now()}
+	if cfg := c.d.patrolConfig.diskQuotaConfig(); cfg != nil {
+		u.Quota = cfg.quotaFor(identity)
+	}
+	return u, nil
+}
+
+// QuotaIdentities returns the managed identities that have a disk quota,
+// sorted.
+func (c *SessionController) QuotaIdentities() []string {
+	cfg := c.d.patrolConfig.diskQuotaConfig()
+	if cfg == nil {
+		return nil
+	}
+	var out []string
+	for _, identity := range c.d.managedIdentities() {
+		if cfg.quotaFor(identity) > 0 {
+			out = append(out, identity)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CleanWorkspace runs the configured clean commands in the identity's
+// workspace. With dryRun it returns the plan without running anything.
+func (c *SessionController) CleanWorkspace(identity string, dryRun bool) (*CleanReport, error) {
+	workDir := c.WorkDir(identity)
+	if workDir == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+	}
+	cfg := c.d.patrolConfig.diskQuotaConfig()
+	if cfg == nil || len(cfg.Clean) == 0 {
+		return nil, ErrNoCleanCommands
+	}
+	if dryRun {
+		report := &CleanReport{Dir: workDir}
+		report.Before, _ = DirSize(workDir)
+		for _, command := range cfg.Clean {
+			report.Commands = append(report.Commands, CleanCommandResult{Command: command})
+		}
+		return report, nil
+	}
+	report, err := CleanWorkspace(workDir, cfg.Clean)
+	if err != nil {
+		return nil, classify(ErrStateVerificationFailed, fmt.Errorf("cleaning %s: %w", workDir, err))
+	}
+	return report, nil
+}
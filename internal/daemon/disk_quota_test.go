@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"2048":   2048,
+		"512B":   512,
+		"4KB":    4 << 10,
+		"500mb":  500 << 20,
+		"1.5G":   3 << 29,
+		" 2 TB ": 2 << 40,
+	}
+	for in, want := range tests {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "-1GB", "lots"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) should fail", bad)
+		}
+	}
+	if got := FormatSize(3 << 29); got != "1.5GB" {
+		t.Errorf("FormatSize = %q, want 1.5GB", got)
+	}
+	if got := FormatSize(100); got != "100B" {
+		t.Errorf("FormatSize = %q, want 100B", got)
+	}
+}
+
+func TestDiskQuotaConfigQuotaFor(t *testing.T) {
+	cfg := &DiskQuotaConfig{
+		Roles:      map[string]string{"crew": "1KB"},
+		Workspaces: map[string]string{"gastown-crew-max": "2KB"},
+	}
+	for identity, want := range map[string]int64{
+		"gastown-crew-max":  2 << 10,
+		"gastown-crew-joe":  1 << 10,
+		"gastown-refinery":  0,
+		"not an identity!!": 0,
+	} {
+		if got := cfg.quotaFor(identity); got != want {
+			t.Errorf("quotaFor(%q) = %d, want %d", identity, got, want)
+		}
+	}
+	if err := (&DiskQuotaConfig{Roles: map[string]string{"crew": "huge"}}).Validate(); err == nil {
+		t.Error("invalid size accepted")
+	}
+}
+
+// writeSized creates a file of n bytes.
+func writeSized(t *testing.T, path string, n int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, n), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeSized(t, filepath.Join(dir, "node_modules", "pkg", "index.js"), 4000)
+	writeSized(t, filepath.Join(dir, "main.go"), 100)
+
+	report, err := CleanWorkspace(dir, []string{"rm -rf node_modules", "echo broken >&2; exit 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Before != 4100 || report.After != 100 {
+		t.Errorf("before/after = %d/%d, want 4100/100", report.Before, report.After)
+	}
+	if report.Failed() != 1 || report.Commands[1].Output != "broken" {
+		t.Errorf("commands = %+v, want the second to fail", report.Commands)
+	}
+}
+
+func TestCheckDiskQuotas(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	maxDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	writeSized(t, filepath.Join(maxDir, "dist", "bundle.js"), 3000)
+	writeSized(t, filepath.Join(townRoot, "gastown", "crew", "joe", "README"), 10)
+	d.patrolConfig = &DaemonPatrolConfig{DiskQuota: &DiskQuotaConfig{
+		Roles:              map[string]string{"crew": "1KB"},
+		Clean:              []string{"rm -rf dist"},
+		CleanBeforeRestart: true,
+	}}
+	state := &State{}
+	now := time.Now()
+
+	d.checkDiskQuotas(state, now)
+	if u := state.DiskUsage["gastown-crew-max"]; u == nil || !u.Over() || u.Bytes != 3000 {
+		t.Fatalf("max usage = %+v, want over quota", u)
+	}
+	if u := state.DiskUsage["gastown-crew-joe"]; u == nil || u.Over() {
+		t.Errorf("joe usage = %+v, want under quota", u)
+	}
+	if _, tracked := state.DiskUsage["gastown-refinery"]; tracked {
+		t.Error("workspaces without a quota should not be measured")
+	}
+
+	// An over-quota workspace is cleaned before its session restarts
+	d.cleanBeforeRestart("gastown-crew-max", maxDir)
+	if _, err := os.Stat(filepath.Join(maxDir, "dist")); !os.IsNotExist(err) {
+		t.Error("clean commands did not run before restart")
+	}
+	if d.diskUsage["gastown-crew-max"].Over() {
+		t.Error("usage should be updated after cleaning")
+	}
+
+	// Not measured again until the interval passes
+	writeSized(t, filepath.Join(maxDir, "dist", "bundle.js"), 3000)
+	d.checkDiskQuotas(state, now.Add(time.Minute))
+	if state.DiskUsage["gastown-crew-max"].Over() {
+		t.Error("measured again before the check interval")
+	}
+}
+
+func TestSessionControllerCleanWorkspace(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	ctl := &SessionController{d: d}
+
+	if _, err := ctl.CleanWorkspace("gastown-crew-max", true); err != ErrNoCleanCommands {
+		t.Errorf("err = %v, want ErrNoCleanCommands", err)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{DiskQuota: &DiskQuotaConfig{Clean: []string{"true"}}}
+	if _, err := ctl.CleanWorkspace("bogus!", false); err == nil || !strings.Contains(err.Error(), "unknown agent identity") {
+		t.Errorf("err = %v, want unknown identity", err)
+	}
+}
@@ -0,0 +1,298 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// Emergency stop: 'gt town stop --force' or an urgent EMERGENCY_STOP mail
+// records a stop in the town store. The daemon then kills every managed
+// session at once (no pre-shutdown hooks, no state verification), and while
+// the record exists it skips its heartbeat checks and refuses to start any
+// session. Only 'gt town resume' removes the record.
+
+// emergencyStopKey is the town storage key of the emergency stop record.
+const emergencyStopKey = "daemon/emergency-stop.json"
+
+// emergencyStopSubjectPrefix marks emergency stop mail (compared lowercased).
+const emergencyStopSubjectPrefix = "emergency_stop"
+
+// defaultEmergencyStopSenders may send emergency stop mail unless
+// lifecycle.emergency_stop_senders says otherwise.
+var defaultEmergencyStopSenders = []string{"mayor", "overseer"}
+
+// EmergencyStop is a town-wide stop in effect.
+type EmergencyStop struct {
+	Since       time.Time `json:"since"`
+	Reason      string    `json:"reason"`
+	RequestedBy string    `json:"requested_by"`
+
+	// ExecutedAt is when the sessions were killed; zero until then.
+	ExecutedAt time.Time `json:"executed_at,omitzero"`
+
+	// Killed lists the sessions the stop killed.
+	Killed []string `json:"killed,omitempty"`
+}
+
+// EmergencyStopBody is the optional JSON body of emergency stop mail. A
+// plain-text body is taken as the reason.
+type EmergencyStopBody struct {
+	Reason string `json:"reason,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// LoadEmergencyStop returns the emergency stop in effect, or nil.
+func LoadEmergencyStop(townRoot string) (*EmergencyStop, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(emergencyStopKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var stop EmergencyStop
+	if err := json.Unmarshal(data, &stop); err != nil {
+		return nil, fmt.Errorf("parsing emergency stop record: %w", err)
+	}
+	return &stop, nil
+}
+
+func saveEmergencyStop(townRoot string, stop *EmergencyStop) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := json.MarshalIndent(stop, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(emergencyStopKey, data)
+}
+
+// RequestEmergencyStop records an emergency stop for the daemon to carry
+// out. If a stop is already in effect it keeps its reason and start time,
+// but the daemon sweeps the sessions again.
+func RequestEmergencyStop(townRoot, reason, requestedBy string) (*EmergencyStop, error) {
+	stop, err := LoadEmergencyStop(townRoot)
+	if err != nil {
+		return nil, err
+	}
+	if stop == nil {
+		stop = &EmergencyStop{Since: time.Now().UTC(), Reason: reason, RequestedBy: requestedBy}
+	}
+	stop.ExecutedAt = time.Time{}
+	if err := saveEmergencyStop(townRoot, stop); err != nil {
+		return nil, err
+	}
+	return stop, nil
+}
+
+// ResumeFromEmergencyStop lifts the emergency stop. Returns the stop that
+// was lifted, or nil if none was in effect.
+func ResumeFromEmergencyStop(townRoot string) (*EmergencyStop, error) {
+	stop, err := LoadEmergencyStop(townRoot)
+	if err != nil || stop == nil {
+		return nil, err
+	}
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return stop, store.Delete(emergencyStopKey)
+}
+
+// emergencyStop returns the stop in effect, or nil. An unreadable record
+// counts as a stop: failing closed beats restarting sessions an operator
+// just killed.
+func (d *Daemon) emergencyStop() *EmergencyStop {
+	stop, err := LoadEmergencyStop(d.config.TownRoot)
+	if err != nil {
+		d.logger.Printf("Warning: cannot read emergency stop record, assuming stopped: %v", err)
+		return &EmergencyStop{Reason: "emergency stop record unreadable: " + err.Error()}
+	}
+	return stop
+}
+
+// checkNotStopped returns an error if an emergency stop forbids starting
+// identity's session.
+func (d *Daemon) checkNotStopped(identity string) error {
+	stop := d.emergencyStop()
+	if stop == nil {
+		return nil
+	}
+	return classify(ErrEmergencyStop, fmt.Errorf("not starting %s: emergency stop in effect (%s)", identity, stop.Reason))
+}
+
+// enforceEmergencyStop kills every managed session, once per stop request.
+func (d *Daemon) enforceEmergencyStop(stop *EmergencyStop) {
+	if !stop.ExecutedAt.IsZero() || stop.Since.IsZero() {
+		return // Already carried out, or an unreadable record
+	}
+	d.logger.Printf("EMERGENCY STOP requested by %s: %s", stop.RequestedBy, stop.Reason)
+
+	sessions := make(map[string]string) // session -> identity ("" for warm sessions)
+	for _, identity := range d.managedIdentities() {
+		if name := d.identityToSession(identity); name != "" {
+			sessions[name] = identity
+		}
+	}
+	if cfg := d.warmPoolConfig(); cfg != nil {
+		for _, rigName := range d.warmPoolRigs(cfg) {
+			for _, name := range d.warmSessionNames(rigName, cfg.size()) {
+				sessions[name] = ""
+			}
+		}
+	}
+
+	var killed []string
+	for name, identity := range sessions {
+		if exists, err := d.tmux.HasSession(name); err != nil || !exists {
+			continue
+		}
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Would kill %s", name)
+			continue
+		}
+		err := d.tmux.KillSessionWithProcesses(name)
+		d.audit(AuditKillSession, name, stop.RequestedBy, []string{"emergency stop"}, err)
+		if err != nil {
+			d.logger.Printf("Emergency stop: failed to kill %s: %v", name, err)
+			continue
+		}
+		killed = append(killed, name)
+		if identity != "" {
+			d.recordKill(identity, ActionShutdown, stop.RequestedBy)
+		}
+	}
+	if d.config.DryRun {
+		return
+	}
+
+	stop.ExecutedAt = time.Now().UTC()
+	stop.Killed = killed
+	if err := saveEmergencyStop(d.config.TownRoot, stop); err != nil {
+		d.logger.Printf("Warning: failed to record emergency stop: %v", err)
+	}
+	d.logger.Printf("Emergency stop: killed %d session(s); nothing will be started until 'gt town resume'", len(killed))
+	d.notify(notifier.EventEmergencyStop, map[string]string{
+		"reason":       stop.Reason,
+		"requested_by": stop.RequestedBy,
+		"killed":       strconv.Itoa(len(killed)),
+	})
+}
+
+// processEmergencyStopMail handles an EMERGENCY_STOP message in the deacon
+// inbox. It must be urgent or high priority and come from an allowed
+// sender (and carry a lifecycle-any token when lifecycle.require_token is
+// set). Returns false if msg isn't emergency stop mail.
+func (d *Daemon) processEmergencyStopMail(msg *BeadsMessage) bool {
+	subject := strings.ToLower(msg.Subject)
+	if !strings.HasPrefix(subject, emergencyStopSubjectPrefix) {
+		return false
+	}
+
+	var body EmergencyStopBody
+	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
+		body.Reason = strings.TrimSpace(msg.Body)
+	}
+	if rest := strings.TrimSpace(msg.Subject[len(emergencyStopSubjectPrefix):]); body.Reason == "" {
+		body.Reason = strings.TrimSpace(strings.TrimPrefix(rest, ":"))
+	}
+	if body.Reason == "" {
+		body.Reason = "no reason given"
+	}
+
+	if err := d.authorizeEmergencyStop(msg, body.Token); err != nil {
+		d.logger.Printf("Rejecting emergency stop from %s: %v", msg.From, err)
+		if !d.config.DryRun {
+			if err := d.closeMessage(msg.ID, msg.From, "rejected emergency stop: "+err.Error()); err != nil {
+				d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+			}
+		}
+		return true
+	}
+
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would record emergency stop from %s: %s", msg.From, body.Reason)
+		return true
+	}
+	// Claim first, as with lifecycle requests
+	if err := d.closeMessage(msg.ID, msg.From, "emergency stop"); err != nil {
+		d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+	}
+	stop, err := RequestEmergencyStop(d.config.TownRoot, body.Reason, msg.From)
+	if err != nil {
+		// Still kill everything; the stop just won't outlive this pass
+		d.logger.Printf("Warning: failed to record emergency stop: %v", err)
+		stop = &EmergencyStop{Since: time.Now().UTC(), Reason: body.Reason, RequestedBy: msg.From}
+	}
+	d.enforceEmergencyStop(stop)
+	return true
+}
+
+// authorizeEmergencyStop checks the priority and sender of emergency stop mail.
+func (d *Daemon) authorizeEmergencyStop(msg *BeadsMessage, token string) error {
+	if msg.Priority != "urgent" && msg.Priority != "high" {
+		return fmt.Errorf("priority %q: emergency stop mail must be urgent or high priority", msg.Priority)
+	}
+
+	cfg := d.patrolConfig.lifecycleConfig()
+	senders := cfg.EmergencyStopSenders
+	if len(senders) == 0 {
+		senders = defaultEmergencyStopSenders
+	}
+	allowed := false
+	for _, sender := range senders {
+		if sameIdentity(sender, msg.From) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s may not request an emergency stop", ErrUnauthorized, msg.From)
+	}
+
+	if !cfg.RequireToken {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("%w: lifecycle.require_token is set and the request has no token", ErrUnauthorized)
+	}
+	t, err := AuthenticateToken(d.config.TownRoot, token)
+	if err != nil {
+		return err
+	}
+	if !t.HasScope(ScopeLifecycleAny) {
+		return fmt.Errorf("%w: token %s lacks scope %s", ErrUnauthorized, t.ID, ScopeLifecycleAny)
+	}
+	return nil
+}
+
+// EnforceEmergencyStop kills every managed session for the emergency stop
+// in effect, if the daemon hasn't already. For use when no daemon is
+// running to do it. Returns the stop, or nil if none is in effect.
+func (c *SessionController) EnforceEmergencyStop() (*EmergencyStop, error) {
+	stop, err := LoadEmergencyStop(c.d.config.TownRoot)
+	if err != nil || stop == nil {
+		return nil, err
+	}
+	c.d.enforceEmergencyStop(stop)
+	return stop, nil
+}
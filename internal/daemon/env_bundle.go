@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// EnvFileName is the file in an agent's working directory holding the
+// environment its session was started with, for tooling that doesn't run
+// inside the tmux session (editors, cron jobs, CI hooks). It is written in
+// shell syntax, so `. ./.gt-env` works as well as a dotenv parser. It may
+// hold credentials, so it is 0600 and excluded from the workspace's git.
+const EnvFileName = ".gt-env"
+
+// EnvBundleConfig declares extra environment for a role's sessions.
+// Configured per role under "env" in mayor/daemon.json, with "*" applying
+// to every role:
+//
+//	"env": {
+//	  "*":    {"vars": {"GT_TOWN": "{town}"}},
+//	  "crew": {"vars": {"GOPATH": "{workdir}/.go", "AGENT_LOG": "{town}/logs/{rig}-{name}.log"}}
+//	}
+//
+// Placeholders: {town}, {rig}, {name}, {role}, {identity}, {session},
+// {workdir}. Role entries override "*", and both override role bead
+// env_vars.
+type EnvBundleConfig struct {
+	// Vars are the variables to set, as templates.
+	Vars map[string]string `json:"vars,omitempty"`
+
+	// WriteFile writes the bundle to <workdir>/.gt-env at session start
+	// (default true). A "*" setting applies unless the role overrides it.
+	WriteFile *bool `json:"write_file,omitempty"`
+}
+
+// envBundleConfigs returns the "*" and role entries of the env config, in
+// override order. Missing entries are skipped.
+func (d *Daemon) envBundleConfigs(role string) []*EnvBundleConfig {
+	if d.patrolConfig == nil {
+		return nil
+	}
+	var cfgs []*EnvBundleConfig
+	for _, key := range []string{"*", role} {
+		if cfg := d.patrolConfig.Env[key]; cfg != nil {
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	return cfgs
+}
+
+// writesEnvFile reports whether a role's sessions get a .gt-env file.
+func (d *Daemon) writesEnvFile(role string) bool {
+	write := true
+	for _, cfg := range d.envBundleConfigs(role) {
+		if cfg.WriteFile != nil {
+			write = *cfg.WriteFile
+		}
+	}
+	return write
+}
+
+// envBundle renders the full environment of an agent session: the base
+// Gas Town variables (GT_ROLE, GT_RIG, BD_ACTOR, ...), GT_TOWN_ROOT and
+// GT_WORKDIR, role bead env_vars, then the role's bundle from daemon.json.
+func (d *Daemon) envBundle(sessionName, workDir string, roleConfig *beads.RoleConfig, parsed *ParsedIdentity) map[string]string {
+	env := config.AgentEnv(config.AgentEnvConfig{
+		Role:      parsed.RoleType,
+		Rig:       parsed.RigName,
+		AgentName: parsed.AgentName,
+		TownRoot:  d.config.TownRoot,
+	})
+	// AgentEnv doesn't know plugin roles; give their rig agents GT_RIG
+	if parsed.RigName != "" && lookupRolePlugin(parsed.RoleType) != nil {
+		env["GT_RIG"] = parsed.RigName
+	}
+	identity := envIdentity(env)
+	env["GT_TOWN_ROOT"] = d.config.TownRoot
+	if workDir != "" {
+		env["GT_WORKDIR"] = workDir
+	}
+	if interval := d.heartbeatEnv(identity); interval != "" {
+		env["GT_HEARTBEAT_INTERVAL"] = interval
+	}
+
+	expand := func(v string) string {
+		v = beads.ExpandRolePattern(v, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
+		return strings.NewReplacer("{identity}", identity, "{session}", sessionName, "{workdir}", workDir).Replace(v)
+	}
+	if roleConfig != nil {
+		for k, v := range roleConfig.EnvVars {
+			env[k] = expand(v)
+		}
+	}
+	for _, cfg := range d.envBundleConfigs(parsed.RoleType) {
+		for k, v := range cfg.Vars {
+			env[k] = expand(v)
+		}
+	}
+	return env
+}
+
+// writeEnvFile writes env to <workDir>/.gt-env, one KEY='value' per line
+// in key order. In a git workspace the file is excluded first (see
+// excludeRuntimeFiles), so the agent can't commit it with its work.
+func writeEnvFile(workDir string, env map[string]string) error {
+	if err := excludeRuntimeFiles(workDir); err != nil {
+		return fmt.Errorf("excluding %s from git: %w", EnvFileName, err)
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString("# Environment of this agent's Gas Town session, written by the daemon\n")
+	b.WriteString("# at session start. Do not edit; configure \"env\" in mayor/daemon.json.\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, shellQuote(env[k]))
+	}
+	return util.AtomicWriteFile(filepath.Join(workDir, EnvFileName), b.Bytes(), 0600)
+}
+
+// shellQuote single-quotes v for sh.
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// ReadEnvFile reads the .gt-env file of an agent working directory.
+// Returns nil with no error if the file doesn't exist.
+func ReadEnvFile(workDir string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(workDir, EnvFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: malformed line %q", EnvFileName, line)
+		}
+		env[k] = shellUnquote(v)
+	}
+	return env, scanner.Err()
+}
+
+// shellUnquote reverses shellQuote. Unquoted values are returned as-is.
+func shellUnquote(v string) string {
+	if len(v) < 2 || v[0] != '\'' || v[len(v)-1] != '\'' {
+		return v
+	}
+	return strings.ReplaceAll(v[1:len(v)-1], `'\''`, "'")
+}
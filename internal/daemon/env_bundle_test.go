@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// envTmux records the environment set on sessions.
+type envTmux struct {
+	SessionBackend
+	env map[string]string
+}
+
+func (e *envTmux) SetEnvironment(session, key, value string) error {
+	e.env[key] = value
+	return nil
+}
+
+func TestEnvBundle(t *testing.T) {
+	d := testDaemon()
+	noFile := false
+	d.patrolConfig = &DaemonPatrolConfig{Env: map[string]*EnvBundleConfig{
+		"*":       {Vars: map[string]string{"GT_TOWN": "{town}", "EDITOR": "vi"}},
+		"crew":    {Vars: map[string]string{"EDITOR": "nano", "AGENT_LOG": "{town}/logs/{identity}.log", "SCRATCH": "{workdir}/.scratch"}},
+		"witness": {WriteFile: &noFile},
+	}}
+	roleConfig := &beads.RoleConfig{EnvVars: map[string]string{"EDITOR": "emacs", "CREW_HOME": "{town}/{rig}/crew/{name}"}}
+	parsed := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+
+	env := d.envBundle("gt-gastown-crew-max", "/tmp/test/gastown/crew/max", roleConfig, parsed)
+	want := map[string]string{
+		"GT_ROLE":      "crew",
+		"GT_RIG":       "gastown",
+		"GT_CREW":      "max",
+		"GT_TOWN_ROOT": "/tmp/test",
+		"GT_WORKDIR":   "/tmp/test/gastown/crew/max",
+		"GT_TOWN":      "/tmp/test",
+		"EDITOR":       "nano", // role entry beats "*", which beats the role bead
+		"CREW_HOME":    "/tmp/test/gastown/crew/max",
+		"AGENT_LOG":    "/tmp/test/logs/gastown-crew-max.log",
+		"SCRATCH":      "/tmp/test/gastown/crew/max/.scratch",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+
+	if !d.writesEnvFile("crew") || d.writesEnvFile("witness") {
+		t.Error("write_file override not applied")
+	}
+}
+
+func TestSetSessionEnvironmentWritesEnvFile(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	tm := &envTmux{env: make(map[string]string)}
+	d.tmux = tm
+	d.patrolConfig = &DaemonPatrolConfig{Env: map[string]*EnvBundleConfig{
+		"mayor": {Vars: map[string]string{"GREETING": "it's {role} o'clock"}},
+	}}
+	workDir := filepath.Join(d.config.TownRoot, "mayor")
+
+	d.setSessionEnvironment(d.tmux, "hq-mayor", workDir, nil, &ParsedIdentity{RoleType: "mayor"})
+	if tm.env["GREETING"] != "it's mayor o'clock" || tm.env["BD_ACTOR"] != "mayor" {
+		t.Errorf("session env = %v", tm.env)
+	}
+
+	fromFile, err := ReadEnvFile(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromFile) != len(tm.env) {
+		t.Errorf("%s has %d vars, session has %d", EnvFileName, len(fromFile), len(tm.env))
+	}
+	for k, v := range tm.env {
+		if fromFile[k] != v {
+			t.Errorf("%s: %s = %q, session has %q", EnvFileName, k, fromFile[k], v)
+		}
+	}
+
+	// The file is valid shell
+	data, _ := os.ReadFile(filepath.Join(workDir, EnvFileName))
+	if want := `GREETING='it'\''s mayor o'\''clock'`; !strings.Contains(string(data), want) {
+		t.Errorf("%s missing %s:\n%s", EnvFileName, want, data)
+	}
+
+	if env, err := ReadEnvFile(t.TempDir()); env != nil || err != nil {
+		t.Errorf("ReadEnvFile of empty dir = %v, %v", env, err)
+	}
+}
+
+func TestWriteEnvFileExcludedFromGit(t *testing.T) {
+	work := newSyncedClone(t)
+	if err := writeEnvFile(work, map[string]string{"API_TOKEN": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if status := gitIn(t, work, "status", "--porcelain"); status != "" {
+		t.Errorf("%s shows up in git status:\n%s", EnvFileName, status)
+	}
+	gitIn(t, work, "add", "-A")
+	if staged := gitIn(t, work, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("git add -A staged %s", staged)
+	}
+}
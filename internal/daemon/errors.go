@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"errors"
+)
+
+// Error classes of lifecycle operations. Errors returned by the daemon and
+// SessionController match at most one of these with errors.Is; the message
+// stays the specific one ("killing session: ..."). ErrorCode names the class
+// for mail, events, and the CLI's exit code.
+var (
+	// ErrUnknownIdentity: the identity doesn't name an agent of this town.
+	ErrUnknownIdentity = errors.New("unknown agent identity")
+
+	// ErrStateVerificationFailed: a precondition checked before acting on a
+	// session failed (rig parked, budget spent, workdir missing, handoff
+	// required, session not running).
+	ErrStateVerificationFailed = errors.New("state verification failed")
+
+	// ErrSessionBackend: the session backend (tmux) failed a call.
+	ErrSessionBackend = errors.New("session backend error")
+
+	// ErrStaleRequest: a lifecycle request expired before it could run.
+	ErrStaleRequest = errors.New("stale lifecycle request")
+
+	// ErrRateLimited: the sender exceeded its lifecycle request rate limit.
+	ErrRateLimited = errors.New("lifecycle request rate limited")
+
+	// ErrUnauthorized: the request had no valid API token, or its token
+	// doesn't allow the operation.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrEmergencyStop: an emergency stop is in effect; nothing may start
+	// until 'gt town resume'.
+	ErrEmergencyStop = errors.New("emergency stop in effect")
+
+	// ErrPolicyDenied: the town's policy hook denied the action, or failed
+	// and the policy doesn't fail open.
+	ErrPolicyDenied = errors.New("denied by policy")
+
+	// ErrTimeout: a lifecycle step (kill, sync, create, ...) missed its
+	// deadline and was abandoned.
+	ErrTimeout = errors.New("lifecycle step timed out")
+
+	// ErrSmokeTestFailed: a restarted session failed its smoke test, and
+	// failed it again after one more restart.
+	ErrSmokeTestFailed = errors.New("smoke test failed")
+)
+
+// Error codes, as reported by ErrorCode.
+const (
+	ErrorCodeUnknownIdentity         = "unknown_identity"
+	ErrorCodeStateVerificationFailed = "state_verification_failed"
+	ErrorCodeSessionBackend          = "session_backend"
+	ErrorCodeStaleRequest            = "stale_request"
+	ErrorCodeRateLimited             = "rate_limited"
+	ErrorCodeUnauthorized            = "unauthorized"
+	ErrorCodeEmergencyStop           = "emergency_stop"
+	ErrorCodePolicyDenied            = "policy_denied"
+	ErrorCodeTimeout                 = "timeout"
+	ErrorCodeSmokeTestFailed         = "smoke_test_failed"
+
+	// ErrorCodeInternal is reported in lifecycle results for unclassified
+	// failures; ErrorCode itself returns "" for them.
+	ErrorCodeInternal = "internal"
+)
+
+// errorCodes maps each error class to its code, in match order.
+var errorCodes = []struct {
+	class error
+	code  string
+}{
+	{ErrUnknownIdentity, ErrorCodeUnknownIdentity},
+	{ErrStaleRequest, ErrorCodeStaleRequest},
+	{ErrRateLimited, ErrorCodeRateLimited},
+	{ErrUnauthorized, ErrorCodeUnauthorized},
+	{ErrEmergencyStop, ErrorCodeEmergencyStop},
+	{ErrPolicyDenied, ErrorCodePolicyDenied},
+	{ErrTimeout, ErrorCodeTimeout},
+	{ErrSmokeTestFailed, ErrorCodeSmokeTestFailed},
+	{ErrStateVerificationFailed, ErrorCodeStateVerificationFailed},
+	{ErrSessionBackend, ErrorCodeSessionBackend},
+}
+
+// ErrorCode returns the code of err's class, or "" for nil and
+// unclassified errors.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.class) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// classifiedError tags an error with its class without changing its message.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.class, e.err} }
+
+// classify tags err with class. A nil err stays nil.
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
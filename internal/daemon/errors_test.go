@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unclassified", errors.New("boom"), ""},
+		{"sentinel", ErrStaleRequest, ErrorCodeStaleRequest},
+		{"wrapped sentinel", fmt.Errorf("%w: deacon", ErrUnknownIdentity), ErrorCodeUnknownIdentity},
+		{"classified", classify(ErrSessionBackend, errors.New("tmux died")), ErrorCodeSessionBackend},
+		{"classified and wrapped", fmt.Errorf("restart: %w", classify(ErrStateVerificationFailed, errors.New("parked"))), ErrorCodeStateVerificationFailed},
+		{"timed out backend call", classify(ErrSessionBackend, classify(ErrTimeout, errors.New("kill step timed out"))), ErrorCodeTimeout},
+		{"smoke test", classify(ErrSmokeTestFailed, errors.New("bd list failed")), ErrorCodeSmokeTestFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	if classify(ErrSessionBackend, nil) != nil {
+		t.Error("classify(nil) should stay nil")
+	}
+
+	inner := errors.New("killing session: no server running")
+	err := classify(ErrSessionBackend, inner)
+	if err.Error() != inner.Error() {
+		t.Errorf("message = %q, want %q", err.Error(), inner.Error())
+	}
+	if !errors.Is(err, ErrSessionBackend) || !errors.Is(err, inner) {
+		t.Error("classified error should match both its class and the original error")
+	}
+}
+
+func TestExecuteLifecycleAction_UnknownIdentity(t *testing.T) {
+	d := testDaemon()
+	err := d.executeLifecycleAction(&LifecycleRequest{From: "nonsense", Action: ActionRestart, Timestamp: time.Now()})
+	if !errors.Is(err, ErrUnknownIdentity) {
+		t.Fatalf("err = %v, want ErrUnknownIdentity", err)
+	}
+	if !strings.Contains(err.Error(), "nonsense") {
+		t.Errorf("error %q should name the identity", err)
+	}
+}
+
+func TestParseIdentity_UnknownFormat(t *testing.T) {
+	_, err := parseIdentity("not/a/valid/identity/at/all")
+	if ErrorCode(err) != ErrorCodeUnknownIdentity {
+		t.Errorf("ErrorCode(%v) = %q, want %q", err, ErrorCode(err), ErrorCodeUnknownIdentity)
+	}
+}
@@ -0,0 +1,472 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// Witness escalations. A witness that keeps seeing the same problem with an
+// agent (stuck, tests failing again, ...) mails the deacon an ESCALATE:
+// report instead of nudging forever. The daemon records each report in the
+// agent's escalation history and applies the configured policies once a
+// problem repeats: open an escalation bead, tell the mayor, cycle the agent.
+const (
+	// escalationSubjectPrefix marks an escalation report (case-insensitive).
+	escalationSubjectPrefix = "escalate:"
+
+	// defaultEscalationWindow is how far back reports are counted toward a
+	// policy's threshold.
+	defaultEscalationWindow = 24 * time.Hour
+
+	// maxEscalationHistory caps the reports kept per agent. Policy
+	// thresholds are counted from the kept reports, so they can't exceed it.
+	maxEscalationHistory = 50
+
+	// escalationBeadSource is the source recorded on escalation beads.
+	escalationBeadSource = "daemon:escalation"
+)
+
+// Escalation policy actions, applied in this order when a policy fires.
+const (
+	// EscalationActionBead opens an escalation bead for the problem.
+	EscalationActionBead = "bead"
+
+	// EscalationActionNotify mails the policy's notify list (default: mayor).
+	EscalationActionNotify = "notify"
+
+	// EscalationActionCycle cycles the agent's session.
+	EscalationActionCycle = "cycle"
+)
+
+var escalationActions = []string{EscalationActionBead, EscalationActionNotify, EscalationActionCycle}
+
+// EscalationConfig configures witness escalation handling, under
+// "lifecycle.escalation" in mayor/daemon.json:
+//
+//	"escalation": {
+//	  "window": "12h",
+//	  "policies": [
+//	    {"name": "stuck-crew", "kinds": ["stuck"], "agents": ["*-crew-*"], "after": 2, "actions": ["cycle"]},
+//	    {"name": "flaky", "kinds": ["tests-failing"], "after": 3, "actions": ["bead", "notify"], "severity": "high"}
+//	  ]
+//	}
+type EscalationConfig struct {
+	// Window is how far back reports count toward a policy's threshold
+	// (Go duration string, default "24h").
+	Window string `json:"window,omitempty"`
+
+	// Policies are checked against every report; each one that matches and
+	// reaches its threshold fires. Default: defaultEscalationPolicies.
+	Policies []EscalationPolicy `json:"policies,omitempty"`
+}
+
+// EscalationPolicy says what to do once an agent has been reported for a
+// problem often enough.
+type EscalationPolicy struct {
+	// Name identifies the policy in escalation history (default "policy-<n>",
+	// numbered from 1).
+	Name string `json:"name,omitempty"`
+
+	// Kinds are the report kinds the policy counts, exact or path.Match
+	// globs. Default: all kinds.
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Agents are the identities the policy applies to, exact or globs
+	// (e.g. "*-crew-*"). Default: all agents.
+	Agents []string `json:"agents,omitempty"`
+
+	// After is how many matching reports within the window fire the policy
+	// (default 1). The count restarts each time it fires.
+	After int `json:"after,omitempty"`
+
+	// Actions to take: "bead", "notify", "cycle".
+	Actions []string `json:"actions"`
+
+	// Notify lists the identities mailed by the notify action (default: mayor).
+	Notify []string `json:"notify,omitempty"`
+
+	// Severity is the severity of beads opened by the bead action
+	// (critical, high, medium, low; default medium).
+	Severity string `json:"severity,omitempty"`
+}
+
+// defaultEscalationPolicies apply when none are configured: a problem
+// reported three times within the window gets a bead and the mayor's
+// attention. Nothing is cycled automatically unless configured.
+var defaultEscalationPolicies = []EscalationPolicy{
+	{Name: "repeated", After: 3, Actions: []string{EscalationActionBead, EscalationActionNotify}},
+}
+
+// EscalationBody is the JSON body of an ESCALATE: report.
+type EscalationBody struct {
+	// Agent is the identity the report is about (daemon or BD_ACTOR form).
+	Agent string `json:"agent"`
+
+	// Kind names the problem, e.g. "stuck" or "tests-failing". Reports of
+	// the same kind count toward the same threshold.
+	Kind string `json:"kind"`
+
+	// Detail is free-form context for the mayor or the bead.
+	Detail string `json:"detail,omitempty"`
+}
+
+// EscalationReport is one recorded report.
+type EscalationReport struct {
+	At        time.Time `json:"at"`
+	Kind      string    `json:"kind"`
+	From      string    `json:"from"`
+	Detail    string    `json:"detail,omitempty"`
+	MessageID string    `json:"message_id,omitempty"`
+
+	// Fired names the policies this report triggered.
+	Fired []string `json:"fired,omitempty"`
+
+	// Bead is the escalation bead opened for it, if any.
+	Bead string `json:"bead,omitempty"`
+}
+
+// EscalationHistory is one agent's escalation history, kept in daemon state
+// and runtime state.
+type EscalationHistory struct {
+	// Total counts every report since the history began.
+	Total int `json:"total"`
+
+	// ByKind counts reports per kind.
+	ByKind map[string]int `json:"by_kind,omitempty"`
+
+	// Reports are the most recent reports, oldest first, at most
+	// maxEscalationHistory.
+	Reports []EscalationReport `json:"reports,omitempty"`
+}
+
+// escalationTracker keeps every agent's escalation history.
+type escalationTracker struct {
+	history map[string]*EscalationHistory
+
+	// seen holds reports already recorded by a dry-run daemon, which leaves
+	// the mail in place.
+	seen map[string]bool
+
+	// openBead creates an escalation bead and returns its ID.
+	openBead func(title string, fields *beads.EscalationFields) (string, error)
+}
+
+// escalationState returns the daemon's escalation tracker, creating it on
+// first use.
+func (d *Daemon) escalationState() *escalationTracker {
+	if d.escalations == nil {
+		d.escalations = &escalationTracker{
+			history: make(map[string]*EscalationHistory),
+			seen:    make(map[string]bool),
+			openBead: func(title string, fields *beads.EscalationFields) (string, error) {
+				issue, err := beads.New(d.config.TownRoot).CreateEscalationBead(title, fields)
+				if err != nil {
+					return "", err
+				}
+				return issue.ID, nil
+			},
+		}
+	}
+	return d.escalations
+}
+
+// escalationConfig returns the configured policies and counting window.
+func (d *Daemon) escalationConfig() ([]EscalationPolicy, time.Duration) {
+	cfg := d.patrolConfig.lifecycleConfig().Escalation
+	if cfg == nil {
+		return defaultEscalationPolicies, defaultEscalationWindow
+	}
+	policies := cfg.Policies
+	if len(policies) == 0 {
+		policies = defaultEscalationPolicies
+	}
+	return policies, d.lifecycleDuration("escalation.window", cfg.Window, defaultEscalationWindow)
+}
+
+func (p *EscalationPolicy) name(i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("policy-%d", i+1)
+}
+
+func (p *EscalationPolicy) after() int {
+	if p.After > 0 {
+		return p.After
+	}
+	return 1
+}
+
+func (p *EscalationPolicy) severity() string {
+	if p.Severity != "" {
+		return p.Severity
+	}
+	return "medium"
+}
+
+func (p *EscalationPolicy) notify() []string {
+	if len(p.Notify) > 0 {
+		return p.Notify
+	}
+	return []string{"mayor"}
+}
+
+// matchesKind reports whether the policy counts reports of kind.
+func (p *EscalationPolicy) matchesKind(kind string) bool {
+	return len(p.Kinds) == 0 || matchesAnyPattern(p.Kinds, kind)
+}
+
+// appliesTo reports whether the policy covers agent.
+func (p *EscalationPolicy) appliesTo(agent string) bool {
+	return len(p.Agents) == 0 || matchesAnyPattern(p.Agents, agent)
+}
+
+func matchesAnyPattern(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, s); matched || pattern == s {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a report to agent's history and returns the policies it
+// fires, with their names filled in. A policy fires when the matching reports since it last fired, and
+// within window, reach its threshold.
+func (t *escalationTracker) record(agent string, report EscalationReport, policies []EscalationPolicy, window time.Duration) []EscalationPolicy {
+	h := t.history[agent]
+	if h == nil {
+		h = &EscalationHistory{ByKind: make(map[string]int)}
+		t.history[agent] = h
+	}
+	h.Total++
+	h.ByKind[report.Kind]++
+
+	var fired []EscalationPolicy
+	since := report.At.Add(-window)
+	for i := range policies {
+		p := &policies[i]
+		if !p.appliesTo(agent) || !p.matchesKind(report.Kind) {
+			continue
+		}
+		name := p.name(i)
+		count := 1 // This report
+		for j := len(h.Reports) - 1; j >= 0; j-- {
+			prev := h.Reports[j]
+			if !prev.At.After(since) || slices.Contains(prev.Fired, name) {
+				break
+			}
+			if p.matchesKind(prev.Kind) {
+				count++
+			}
+		}
+		if count >= p.after() {
+			report.Fired = append(report.Fired, name)
+			named := *p
+			named.Name = name
+			fired = append(fired, named)
+		}
+	}
+
+	h.Reports = append(h.Reports, report)
+	if len(h.Reports) > maxEscalationHistory {
+		h.Reports = h.Reports[len(h.Reports)-maxEscalationHistory:]
+	}
+	return fired
+}
+
+// latest returns agent's most recent report, for the actions to annotate.
+func (t *escalationTracker) latest(agent string) *EscalationReport {
+	h := t.history[agent]
+	if h == nil || len(h.Reports) == 0 {
+		return nil
+	}
+	return &h.Reports[len(h.Reports)-1]
+}
+
+// snapshot copies the escalation histories for daemon state.
+func (t *escalationTracker) snapshot() map[string]*EscalationHistory {
+	if t == nil || len(t.history) == 0 {
+		return nil
+	}
+	out := make(map[string]*EscalationHistory, len(t.history))
+	for agent, h := range t.history {
+		copied := &EscalationHistory{Total: h.Total, Reports: slices.Clone(h.Reports)}
+		if len(h.ByKind) > 0 {
+			copied.ByKind = make(map[string]int, len(h.ByKind))
+			for kind, n := range h.ByKind {
+				copied.ByKind[kind] = n
+			}
+		}
+		out[agent] = copied
+	}
+	return out
+}
+
+// parseEscalation validates an ESCALATE: message and returns the agent it is
+// about and the report to record. Only witnesses and the town-level
+// supervisors (mayor, deacon) may escalate.
+func (d *Daemon) parseEscalation(msg *BeadsMessage, now time.Time) (string, EscalationReport, error) {
+	from := normalizeIdentity(strings.TrimSuffix(msg.From, "/"))
+	sender, err := parseIdentity(from)
+	if err != nil || (sender.RoleType != "witness" && sender.RoleType != "mayor" && sender.RoleType != "deacon") {
+		return "", EscalationReport{}, fmt.Errorf("sender %q may not escalate", msg.From)
+	}
+
+	var body EscalationBody
+	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
+		return "", EscalationReport{}, fmt.Errorf("body is not an escalation report: %v", err)
+	}
+	agent := normalizeIdentity(strings.TrimSpace(body.Agent))
+	if _, err := parseIdentity(agent); err != nil {
+		return "", EscalationReport{}, fmt.Errorf("unknown agent %q", body.Agent)
+	}
+	kind := strings.ToLower(strings.TrimSpace(body.Kind))
+	if kind == "" {
+		// "ESCALATE: stuck ..." names the kind in the subject
+		if fields := strings.Fields(msg.Subject[len(escalationSubjectPrefix):]); len(fields) > 0 {
+			kind = strings.ToLower(fields[0])
+		}
+	}
+	if kind == "" {
+		return "", EscalationReport{}, errors.New("no kind given")
+	}
+
+	return agent, EscalationReport{
+		At:        now,
+		Kind:      kind,
+		From:      from,
+		Detail:    body.Detail,
+		MessageID: msg.ID,
+	}, nil
+}
+
+// processEscalation handles msg if it is an escalation report and reports
+// whether it was, so the caller can move on to the next message.
+func (d *Daemon) processEscalation(msg *BeadsMessage, now time.Time) bool {
+	if !strings.HasPrefix(strings.ToLower(msg.Subject), escalationSubjectPrefix) {
+		return false
+	}
+	t := d.escalationState()
+	if t.seen[msg.ID] {
+		return true
+	}
+
+	agent, report, err := d.parseEscalation(msg, now)
+	if err != nil {
+		d.logger.Printf("Rejecting escalation %s from %s: %v", msg.ID, msg.From, err)
+		if d.config.DryRun {
+			t.seen[msg.ID] = true
+		} else if err := d.closeMessage(msg.ID, msg.From, "rejected escalation: "+err.Error()); err != nil {
+			d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+		}
+		return true
+	}
+
+	if d.config.DryRun {
+		t.seen[msg.ID] = true
+	} else if err := d.closeMessage(msg.ID, report.From, "escalation recorded"); err != nil {
+		d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+	}
+
+	policies, window := d.escalationConfig()
+	fired := t.record(agent, report, policies, window)
+	d.logger.Printf("Escalation from %s: %s is %s (%d %s reports)", report.From, agent, report.Kind,
+		t.history[agent].ByKind[report.Kind], report.Kind)
+	for _, policy := range fired {
+		d.applyEscalationPolicy(agent, &policy)
+	}
+	return true
+}
+
+// applyEscalationPolicy takes a fired policy's actions for agent's latest
+// report.
+func (d *Daemon) applyEscalationPolicy(agent string, policy *EscalationPolicy) {
+	t := d.escalationState()
+	report := t.latest(agent)
+	if report == nil {
+		return
+	}
+	h := t.history[agent]
+	d.logger.Printf("Escalation policy %s fired for %s (%s): %s",
+		policy.Name, agent, report.Kind, strings.Join(policy.Actions, ", "))
+
+	for _, action := range escalationActions {
+		if !slices.Contains(policy.Actions, action) {
+			continue
+		}
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Would %s for escalation of %s", action, agent)
+			continue
+		}
+		switch action {
+		case EscalationActionBead:
+			title := fmt.Sprintf("%s: %s (reported %d times)", agent, report.Kind, h.ByKind[report.Kind])
+			id, err := t.openBead(title, &beads.EscalationFields{
+				Severity:    policy.severity(),
+				Reason:      escalationSummary(agent, report, h),
+				Source:      escalationBeadSource,
+				EscalatedBy: identityToBDActor(report.From),
+				EscalatedAt: report.At.UTC().Format(time.RFC3339),
+			})
+			if err != nil {
+				d.logger.Printf("Warning: failed to open escalation bead for %s: %v", agent, err)
+				continue
+			}
+			report.Bead = id
+			d.logger.Printf("Opened escalation bead %s for %s", id, agent)
+
+		case EscalationActionNotify:
+			body := escalationSummary(agent, report, h)
+			if report.Bead != "" {
+				body += "\n\nTracked in " + report.Bead + "."
+			}
+			d.sendLifecycleNotify(policy.notify(),
+				fmt.Sprintf("ESCALATION: %s %s (reported %d times)", agent, report.Kind, h.ByKind[report.Kind]), body)
+
+		case EscalationActionCycle:
+			request := &LifecycleRequest{
+				From:        agent,
+				Action:      ActionCycle,
+				Timestamp:   report.At,
+				Reason:      fmt.Sprintf("escalation: %s (reported %d times)", report.Kind, h.ByKind[report.Kind]),
+				RequestedBy: requestedByEscalation,
+			}
+			if !d.admitRestart(request) {
+				continue
+			}
+			if err := d.executeLifecycleAction(request); err != nil {
+				d.logger.Printf("Warning: escalation cycle of %s failed: %v", agent, err)
+			}
+		}
+	}
+}
+
+// escalationSummary describes an agent's escalation history for a bead or
+// the mayor.
+func escalationSummary(agent string, report *EscalationReport, h *EscalationHistory) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s reported %s as %s", report.From, agent, report.Kind)
+	if report.Detail != "" {
+		fmt.Fprintf(&b, ": %s", report.Detail)
+	}
+	kinds := make([]string, 0, len(h.ByKind))
+	for kind := range h.ByKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Fprintf(&b, "\n\nEscalation history of %s (%d reports):", agent, h.Total)
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "\n  %s: %d", kind, h.ByKind[kind])
+	}
+	fmt.Fprintf(&b, "\n\nCheck it with: gt agent logs %s", identityToMailAddress(agent))
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// sentMail records what the daemon mailed and deleted.
+type sentMail struct {
+	deleted []string
+	sent    []string // "to|subject|body"
+}
+
+func (m *sentMail) Inbox(string) ([]BeadsMessage, error) { return nil, nil }
+func (m *sentMail) Delete(id string) error               { m.deleted = append(m.deleted, id); return nil }
+func (m *sentMail) Send(to, subject, body string) error {
+	m.sent = append(m.sent, to+"|"+subject+"|"+body)
+	return nil
+}
+
+func TestEscalationPolicyThreshold(t *testing.T) {
+	tr := &escalationTracker{history: make(map[string]*EscalationHistory)}
+	policies := []EscalationPolicy{
+		{Kinds: []string{"stuck"}, After: 2, Actions: []string{EscalationActionCycle}},
+		{Name: "crew-tests", Kinds: []string{"tests-*"}, Agents: []string{"*-crew-*"}, Actions: []string{EscalationActionBead}},
+	}
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	report := func(agent, kind string, at time.Duration) []string {
+		var names []string
+		for _, p := range tr.record(agent, EscalationReport{At: start.Add(at), Kind: kind}, policies, time.Hour) {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	if fired := report("gastown-crew-max", "stuck", 0); fired != nil {
+		t.Errorf("first stuck report fired %v", fired)
+	}
+	if fired := report("gastown-crew-max", "stuck", time.Minute); len(fired) != 1 || fired[0] != "policy-1" {
+		t.Errorf("second stuck report fired %v, want [policy-1]", fired)
+	}
+	// The count restarts after the policy fires
+	if fired := report("gastown-crew-max", "stuck", 2*time.Minute); fired != nil {
+		t.Errorf("third stuck report fired %v", fired)
+	}
+	// Reports outside the window don't count
+	if fired := report("gastown-crew-max", "stuck", 2*time.Hour); fired != nil {
+		t.Errorf("stuck report after the window fired %v", fired)
+	}
+
+	if fired := report("gastown-crew-max", "tests-failing", 3*time.Hour); len(fired) != 1 || fired[0] != "crew-tests" {
+		t.Errorf("crew tests report fired %v", fired)
+	}
+	if fired := report("gastown-refinery", "tests-failing", 3*time.Hour); fired != nil {
+		t.Errorf("policy for crew fired for the refinery: %v", fired)
+	}
+
+	h := tr.history["gastown-crew-max"]
+	if h.Total != 5 || h.ByKind["stuck"] != 4 || len(h.Reports[1].Fired) != 1 {
+		t.Errorf("history = %+v", h)
+	}
+}
+
+func TestProcessEscalation(t *testing.T) {
+	d := testDaemon()
+	mail := &sentMail{}
+	d.mail = mail
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{Escalation: &EscalationConfig{
+		Policies: []EscalationPolicy{{Name: "now", Actions: []string{EscalationActionNotify, EscalationActionBead}, Severity: "high"}},
+	}}}
+	var opened *beads.EscalationFields
+	d.escalationState().openBead = func(title string, fields *beads.EscalationFields) (string, error) {
+		opened = fields
+		return "hq-esc1", nil
+	}
+	now := time.Now()
+
+	if d.processEscalation(&BeadsMessage{ID: "m0", Subject: "LIFECYCLE: cycle"}, now) {
+		t.Error("lifecycle mail taken for an escalation")
+	}
+
+	msg := &BeadsMessage{
+		ID:      "m1",
+		From:    "gastown/witness",
+		Subject: "ESCALATE: stuck gastown/crew/max",
+		Body:    `{"agent": "gastown/crew/max", "detail": "no commits in 2h"}`,
+	}
+	if !d.processEscalation(msg, now) {
+		t.Fatal("escalation not recognized")
+	}
+	if len(mail.deleted) != 1 || mail.deleted[0] != "m1" {
+		t.Errorf("deleted %v, want the report", mail.deleted)
+	}
+	if opened == nil || opened.Severity != "high" || opened.EscalatedBy != "gastown/witness" ||
+		!strings.Contains(opened.Reason, "no commits in 2h") {
+		t.Errorf("bead fields = %+v", opened)
+	}
+	// The bead is opened first, so the mayor's mail can point at it
+	if len(mail.sent) != 1 || !strings.HasPrefix(mail.sent[0], "mayor/|ESCALATION: gastown-crew-max stuck") ||
+		!strings.Contains(mail.sent[0], "Tracked in hq-esc1") {
+		t.Errorf("sent %v", mail.sent)
+	}
+	if last := d.escalations.latest("gastown-crew-max"); last == nil || last.Kind != "stuck" || last.Bead != "hq-esc1" {
+		t.Errorf("latest report = %+v", last)
+	}
+
+	// Only supervisors may escalate
+	forged := &BeadsMessage{ID: "m2", From: "gastown/crew/joe", Subject: "ESCALATE: stuck", Body: `{"agent": "gastown-crew-max"}`}
+	if !d.processEscalation(forged, now) {
+		t.Fatal("forged escalation not recognized")
+	}
+	if len(mail.deleted) != 2 || d.escalations.history["gastown-crew-max"].Total != 1 {
+		t.Errorf("forged escalation recorded: deleted %v, history %+v", mail.deleted, d.escalations.history["gastown-crew-max"])
+	}
+}
+
+func TestEscalationHistorySurvivesRestart(t *testing.T) {
+	d := testDaemon()
+	d.escalationState().record("gastown-crew-max", EscalationReport{At: time.Now(), Kind: "stuck"}, nil, time.Hour)
+
+	rs := d.captureRuntimeState(time.Now())
+	restarted := testDaemon()
+	restarted.restoreRuntimeState(rs, time.Now())
+	h := restarted.escalationState().history["gastown-crew-max"]
+	if h == nil || h.Total != 1 || h.ByKind["stuck"] != 1 {
+		t.Errorf("restored history = %+v", h)
+	}
+}
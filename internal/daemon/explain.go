@@ -0,0 +1,177 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// Role config sources reported by Explain.
+const (
+	RoleConfigTown     = "town role bead"
+	RoleConfigLegacy   = "legacy role bead"
+	RoleConfigDefaults = "built-in defaults"
+)
+
+// IdentityExplanation shows how the daemon resolves one identity string.
+type IdentityExplanation struct {
+	Input    string `json:"input"`
+	Identity string `json:"identity"`
+
+	Role  string `json:"role"`
+	Rig   string `json:"rig,omitempty"`
+	Agent string `json:"agent,omitempty"`
+
+	RoleBeadID       string `json:"role_bead_id"`
+	RoleConfigSource string `json:"role_config_source"`
+	SessionPattern   string `json:"session_pattern,omitempty"`
+	WorkDirPattern   string `json:"work_dir_pattern,omitempty"`
+	WorkDirTemplate  string `json:"work_dir_template,omitempty"`
+
+	SessionName    string `json:"session_name"`
+	SessionRunning bool   `json:"session_running"`
+	WorkDir        string `json:"work_dir"`
+	WorkDirExists  bool   `json:"work_dir_exists"`
+	WorkDirError   string `json:"work_dir_error,omitempty"`
+	StateFile      string `json:"state_file"`
+	StateBackend   string `json:"state_backend"`
+	AgentBeadID    string `json:"agent_bead_id"`
+	BDActor        string `json:"bd_actor"`
+
+	// Ambiguities lists ways this resolution could be surprising.
+	Ambiguities []string `json:"ambiguities,omitempty"`
+}
+
+// normalizeIdentity converts BD_ACTOR-style slash identities
+// (gastown/witness, gastown/crew/max) to the daemon's dash format.
+// Polecat slash identities are understood by parseIdentity directly.
+func normalizeIdentity(s string) string {
+	parts := strings.Split(s, "/")
+	switch {
+	case len(parts) == 2 && (parts[1] == "witness" || parts[1] == "refinery"):
+		return parts[0] + "-" + parts[1]
+	case len(parts) == 3 && parts[1] == "crew":
+		return parts[0] + "-crew-" + parts[2]
+	}
+	return s
+}
+
+// Explain resolves an identity (daemon dash form, BD_ACTOR slash form, or a
+// tmux session name of a managed agent) and reports every derived name.
+func (c *SessionController) Explain(input string) (*IdentityExplanation, error) {
+	d := c.d
+	ex := &IdentityExplanation{Input: input, Identity: normalizeIdentity(input)}
+	if ex.Identity != input {
+		ex.Ambiguities = append(ex.Ambiguities, fmt.Sprintf("input is in BD_ACTOR form; daemon identity is %s", ex.Identity))
+	}
+
+	parsed, err := parseIdentity(ex.Identity)
+	if err != nil {
+		// Maybe it's a session name
+		for _, identity := range d.managedIdentities() {
+			if d.identityToSession(identity) == input {
+				ex.Identity = identity
+				ex.Ambiguities = append(ex.Ambiguities, fmt.Sprintf("input is a tmux session name; daemon identity is %s", identity))
+				parsed, err = parseIdentity(identity)
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	ex.Role, ex.Rig, ex.Agent = parsed.RoleType, parsed.RigName, parsed.AgentName
+
+	// Role config: town bead, then legacy bead, then defaults
+	b := beads.New(d.config.TownRoot)
+	ex.RoleBeadID = beads.RoleBeadIDTown(parsed.RoleType)
+	ex.RoleConfigSource = RoleConfigDefaults
+	roleConfig, _ := b.GetRoleConfig(ex.RoleBeadID)
+	if roleConfig != nil {
+		ex.RoleConfigSource = RoleConfigTown
+	} else if legacyID := beads.RoleBeadID(parsed.RoleType); legacyID != ex.RoleBeadID {
+		if roleConfig, _ = b.GetRoleConfig(legacyID); roleConfig != nil {
+			ex.RoleBeadID = legacyID
+			ex.RoleConfigSource = RoleConfigLegacy
+		}
+	}
+	if roleConfig != nil {
+		ex.SessionPattern = roleConfig.SessionPattern
+		ex.WorkDirPattern = roleConfig.WorkDirPattern
+	}
+
+	ex.SessionName = d.identityToSession(ex.Identity)
+	if ex.SessionName != "" {
+		ex.SessionRunning, _ = d.tmux.HasSession(ex.SessionName)
+	}
+	if cfg := d.workDirConfig(parsed.RoleType); cfg != nil {
+		ex.WorkDirTemplate = cfg.Template
+	}
+	ex.WorkDir = d.getWorkDir(roleConfig, parsed)
+	if ex.WorkDir != "" {
+		if info, err := os.Stat(ex.WorkDir); err == nil && info.IsDir() {
+			ex.WorkDirExists = true
+		}
+		if err := d.validateWorkDir(parsed, ex.WorkDir); err != nil {
+			ex.WorkDirError = err.Error()
+		}
+		ex.StateFile = agentStateFile(ex.WorkDir)
+	}
+	ex.StateBackend = d.agentStates().Name()
+	ex.AgentBeadID = d.identityToAgentBeadID(ex.Identity)
+	ex.BDActor = identityToBDActor(ex.Identity)
+
+	ex.Ambiguities = append(ex.Ambiguities, d.identityAmbiguities(ex)...)
+	return ex, nil
+}
+
+// identityAmbiguities flags the mapping pitfalls that usually send people
+// grepping: unregistered rigs, role words inside names, and names that
+// exist under more than one role.
+func (d *Daemon) identityAmbiguities(ex *IdentityExplanation) []string {
+	var out []string
+
+	if ex.Rig != "" {
+		if !slices.Contains(d.getKnownRigs(), ex.Rig) {
+			out = append(out, fmt.Sprintf("rig %q is not registered in mayor/rigs.json", ex.Rig))
+		}
+		for _, word := range []string{"-crew-", "-polecat-", "-witness", "-refinery"} {
+			if strings.Contains(ex.Rig, word) {
+				out = append(out, fmt.Sprintf("rig name %q contains %q; the identity may belong to a different rig", ex.Rig, strings.Trim(word, "-")))
+			}
+		}
+	}
+
+	if ex.Agent != "" {
+		if ex.Agent == "witness" || ex.Agent == "refinery" {
+			out = append(out, fmt.Sprintf("agent name %q is a role name; parsing matched the %s role first", ex.Agent, ex.Role))
+		}
+		// Same name as crew and polecat in one rig resolves differently by role
+		crew, _ := listPolecatWorktrees(filepath.Join(d.config.TownRoot, ex.Rig, "crew"))
+		polecats, _ := listPolecatWorktrees(filepath.Join(d.config.TownRoot, ex.Rig, "polecats"))
+		if slices.Contains(crew, ex.Agent) && slices.Contains(polecats, ex.Agent) {
+			out = append(out, fmt.Sprintf("%q exists as both crew and polecat in %s", ex.Agent, ex.Rig))
+		}
+	}
+
+	if ex.WorkDir != "" && !ex.WorkDirExists {
+		out = append(out, fmt.Sprintf("work dir %s does not exist", ex.WorkDir))
+	}
+	if ex.SessionPattern != "" && ex.RoleConfigSource == RoleConfigLegacy {
+		out = append(out, "session pattern comes from a legacy role bead; migrate it to "+beads.RoleBeadIDTown(ex.Role))
+	}
+
+	// Another managed identity mapping to the same session is a real collision
+	if ex.SessionName != "" {
+		for _, identity := range d.managedIdentities() {
+			if identity != ex.Identity && d.identityToSession(identity) == ex.SessionName {
+				out = append(out, fmt.Sprintf("session %s is also the session of %s", ex.SessionName, identity))
+			}
+		}
+	}
+	return out
+}
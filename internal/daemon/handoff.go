@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Handoff policies for cycle requests (lifecycle.handoff).
+const (
+	// HandoffOff cycles immediately; agents improvise handoff mail if at all.
+	HandoffOff = "off"
+
+	// HandoffRequest asks the agent for a handoff document and waits up to
+	// lifecycle.handoff_timeout for it, then cycles either way.
+	HandoffRequest = "request"
+
+	// HandoffRequire asks for a handoff document and rejects the cycle if
+	// none arrives within lifecycle.handoff_timeout.
+	HandoffRequire = "require"
+)
+
+const (
+	// defaultHandoffTimeout is how long an agent has to write its handoff.
+	defaultHandoffTimeout = 5 * time.Minute
+
+	// handoffEnvVar carries the stored handoff path into the new session.
+	handoffEnvVar = "GT_HANDOFF"
+
+	// maxStoredHandoffs is how many handoffs are kept per agent.
+	maxStoredHandoffs = 10
+)
+
+// handoffDecision is what to do with a cycle request this poll.
+type handoffDecision int
+
+const (
+	handoffProceed handoffDecision = iota // cycle now
+	handoffWait                           // leave the request unclaimed
+	handoffReject                         // claim the request and fail it
+)
+
+// handoffTracker remembers which agents have been asked for a handoff and
+// which stored handoffs are waiting to be injected into a new session.
+// Only accessed from the heartbeat loop goroutine.
+type handoffTracker struct {
+	requested map[string]time.Time // identity -> when the handoff was requested
+	ready     map[string]string    // identity -> stored handoff path
+}
+
+// handoffState returns the daemon's handoff tracker, creating it on first use.
+func (d *Daemon) handoffState() *handoffTracker {
+	if d.handoffs == nil {
+		d.handoffs = &handoffTracker{requested: map[string]time.Time{}, ready: map[string]string{}}
+	}
+	return d.handoffs
+}
+
+// handoffPolicy returns the configured handoff policy for cycle requests.
+func (d *Daemon) handoffPolicy() string {
+	switch policy := strings.ToLower(d.patrolConfig.lifecycleConfig().Handoff); policy {
+	case "", HandoffOff:
+		return HandoffOff
+	case HandoffRequest, HandoffRequire:
+		return policy
+	default:
+		d.logger.Printf("Warning: invalid lifecycle.handoff %q, using %q", policy, HandoffOff)
+		return HandoffOff
+	}
+}
+
+func (d *Daemon) handoffTimeout() time.Duration {
+	return d.lifecycleDuration("handoff_timeout", d.patrolConfig.lifecycleConfig().HandoffTimeout, defaultHandoffTimeout)
+}
+
+// handoffFile is where an agent writes its handoff document.
+func handoffFile(workDir string) string {
+	return filepath.Join(workDir, ".runtime", "handoff.md")
+}
+
+// handoffDir returns the directory holding an agent's stored handoffs.
+func handoffDir(townRoot, identity string) string {
+	return filepath.Join(townRoot, "daemon", "handoffs", identity)
+}
+
+// checkHandoff decides whether a cycle request may run yet. The first time
+// a request is seen the agent is mailed a "compose handoff" request; the
+// request then waits until the handoff file appears or the timeout passes.
+// The returned reason explains a wait, a rejection, or a cycle without one.
+func (d *Daemon) checkHandoff(request *LifecycleRequest, now time.Time) (handoffDecision, string) {
+	if request.Action != ActionCycle || request.DryRun || d.config.DryRun {
+		return handoffProceed, ""
+	}
+	policy := d.handoffPolicy()
+	if policy == HandoffOff {
+		return handoffProceed, ""
+	}
+
+	state := d.handoffState()
+	workDir := d.agentWorkDir(request.From)
+	if workDir == "" {
+		return handoffProceed, "" // Unknown identity; executeLifecycleAction reports it
+	}
+	if d.handoffSource(request, workDir) != "" {
+		delete(state.requested, request.From)
+		return handoffProceed, ""
+	}
+
+	requestedAt, asked := state.requested[request.From]
+	if !asked {
+		state.requested[request.From] = now
+		d.requestHandoff(request.From, workDir)
+		return handoffWait, "handoff requested"
+	}
+
+	timeout := d.handoffTimeout()
+	if waited := now.Sub(requestedAt); waited < timeout {
+		return handoffWait, fmt.Sprintf("waiting for handoff (%v left)", (timeout - waited).Round(time.Second))
+	}
+
+	delete(state.requested, request.From)
+	if policy == HandoffRequire {
+		return handoffReject, fmt.Sprintf("no handoff written to %s within %v", handoffFile(workDir), timeout)
+	}
+	return handoffProceed, fmt.Sprintf("no handoff within %v, cycling without one", timeout)
+}
+
+// handoffSource returns the handoff document to store for a cycle request:
+// the path named in the request, else the conventional handoff file. Paths
+// outside the agent's working directory are refused. Returns "" if there
+// is no non-empty document.
+func (d *Daemon) handoffSource(request *LifecycleRequest, workDir string) string {
+	path := handoffFile(workDir)
+	if request.Handoff != "" {
+		path = request.Handoff
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
+		}
+		rel, err := filepath.Rel(workDir, filepath.Clean(path))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			d.logger.Printf("Warning: ignoring handoff %s for %s: outside %s", request.Handoff, request.From, workDir)
+			return ""
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return ""
+	}
+	return path
+}
+
+// requestHandoff mails the agent asking it to write its handoff document.
+func (d *Daemon) requestHandoff(identity, workDir string) {
+	path := handoffFile(workDir)
+	d.logger.Printf("Requesting handoff from %s before cycle (%s)", identity, path)
+	body := fmt.Sprintf(`Your session will be cycled. Before it is, write a handoff document for
+your successor to:
+
+  %s
+
+Cover what you were doing, what is done, what is next, and anything
+surprising. The daemon cycles your session as soon as the file exists
+(within %v), and the new session finds it via $%s.`, path, d.handoffTimeout(), handoffEnvVar)
+	d.sendLifecycleNotify([]string{identity}, "HANDOFF: compose handoff before cycle", body)
+}
+
+// collectHandoff stores the agent's handoff document under the town's
+// daemon directory and queues it for the next session's environment. The
+// conventional handoff file is removed so a later cycle doesn't reuse it.
+func (d *Daemon) collectHandoff(request *LifecycleRequest, now time.Time) {
+	workDir := d.agentWorkDir(request.From)
+	if workDir == "" {
+		return
+	}
+	src := d.handoffSource(request, workDir)
+	if src == "" {
+		return
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		d.logger.Printf("Warning: reading handoff %s for %s: %v", src, request.From, err)
+		return
+	}
+
+	dir := handoffDir(d.config.TownRoot, request.From)
+	stored := filepath.Join(dir, now.UTC().Format("20060102T150405Z")+".md")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		d.logger.Printf("Warning: storing handoff for %s: %v", request.From, err)
+		return
+	}
+	if err := os.WriteFile(stored, data, 0644); err != nil {
+		d.logger.Printf("Warning: storing handoff for %s: %v", request.From, err)
+		return
+	}
+	if src == handoffFile(workDir) {
+		_ = os.Remove(src)
+	}
+	pruneHandoffs(dir, maxStoredHandoffs)
+
+	d.handoffState().ready[request.From] = stored
+	d.logger.Printf("Stored handoff for %s at %s", request.From, stored)
+}
+
+// takeHandoff returns and forgets the stored handoff queued for identity.
+func (d *Daemon) takeHandoff(identity string) string {
+	state := d.handoffState()
+	path := state.ready[identity]
+	delete(state.ready, identity)
+	return path
+}
+
+// pruneHandoffs keeps the newest keep handoffs in dir.
+func pruneHandoffs(dir string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".md") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return
+	}
+	sort.Strings(names) // timestamp names sort chronologically
+	for _, name := range names[:len(names)-keep] {
+		_ = os.Remove(filepath.Join(dir, name))
+	}
+}
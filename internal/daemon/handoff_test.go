@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckHandoff(t *testing.T) {
+	townRoot := t.TempDir()
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.patrolConfig = &DaemonPatrolConfig{
+		Lifecycle: &LifecycleConfig{Handoff: HandoffRequire, HandoffTimeout: "2m"},
+	}
+	workDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	request := &LifecycleRequest{From: "gastown-crew-max", Action: ActionCycle}
+	now := time.Now()
+
+	if decision, _ := d.checkHandoff(request, now); decision != handoffWait {
+		t.Fatalf("first check = %v, want wait", decision)
+	}
+	if decision, _ := d.checkHandoff(request, now.Add(time.Minute)); decision != handoffWait {
+		t.Fatalf("check before timeout = %v, want wait", decision)
+	}
+	if decision, _ := d.checkHandoff(request, now.Add(3*time.Minute)); decision != handoffReject {
+		t.Fatalf("check after timeout = %v, want reject", decision)
+	}
+
+	// Restarts never wait for a handoff
+	restart := &LifecycleRequest{From: "gastown-crew-max", Action: ActionRestart}
+	if decision, _ := d.checkHandoff(restart, now); decision != handoffProceed {
+		t.Errorf("restart = %v, want proceed", decision)
+	}
+
+	// Once the agent writes its handoff, the cycle proceeds and the document
+	// is stored and queued for the new session.
+	if err := os.MkdirAll(filepath.Join(workDir, ".runtime"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(handoffFile(workDir), []byte("# Handoff\nfinish gt-123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if decision, _ := d.checkHandoff(request, now.Add(4*time.Minute)); decision != handoffProceed {
+		t.Fatalf("check with handoff = %v, want proceed", decision)
+	}
+
+	d.collectHandoff(request, now)
+	stored := d.takeHandoff(request.From)
+	if stored == "" || filepath.Dir(stored) != handoffDir(townRoot, request.From) {
+		t.Fatalf("stored handoff = %q", stored)
+	}
+	if data, err := os.ReadFile(stored); err != nil || string(data) != "# Handoff\nfinish gt-123\n" {
+		t.Errorf("stored content = %q, %v", data, err)
+	}
+	if _, err := os.Stat(handoffFile(workDir)); !os.IsNotExist(err) {
+		t.Errorf("handoff file not removed after storing: %v", err)
+	}
+	if again := d.takeHandoff(request.From); again != "" {
+		t.Errorf("handoff injected twice: %q", again)
+	}
+
+	// Handoff paths outside the workdir are refused
+	request.Handoff = "../../../../etc/passwd"
+	if src := d.handoffSource(request, workDir); src != "" {
+		t.Errorf("handoffSource outside workdir = %q, want empty", src)
+	}
+}
+
+func TestPruneHandoffs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20260101T000000Z.md", "20260102T000000Z.md", "20260103T000000Z.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneHandoffs(dir, 2)
+	if _, err := os.Stat(filepath.Join(dir, "20260101T000000Z.md")); !os.IsNotExist(err) {
+		t.Error("oldest handoff not pruned")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20260103T000000Z.md")); err != nil {
+		t.Error("newest handoff pruned")
+	}
+}
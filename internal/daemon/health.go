@@ -0,0 +1,252 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Daemon self-health, served as GET /healthz on the API and read by
+// 'gt daemon watchdog'. The heartbeat loop records its timing here; API
+// handlers read it, so it is guarded by its own lock.
+
+// Health statuses. Only HealthUnhealthy makes /healthz answer 503.
+const (
+	HealthOK        = "ok"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+const (
+	// heartbeatStallAfter is how long one heartbeat may run, or how long
+	// the loop may go without completing one, before the daemon counts as
+	// unhealthy. Generous: a heartbeat can legitimately stagger restarts.
+	heartbeatStallAfter = 2*recoveryHeartbeatInterval + 4*time.Minute
+
+	// healthMaxGoroutines and healthMaxAPIQueue mark a daemon degraded
+	// (leaking goroutines, or not draining API requests).
+	healthMaxGoroutines = 5000
+	healthMaxAPIQueue   = 100
+
+	// healthProbeTimeout bounds a /healthz request from ProbeHealth.
+	healthProbeTimeout = 5 * time.Second
+)
+
+// HealthReport is the daemon's view of its own health.
+type HealthReport struct {
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at,omitzero"`
+
+	// Heartbeat loop timing. HeartbeatRunningSince is set while a
+	// heartbeat is in progress.
+	LastHeartbeatAt       time.Time     `json:"last_heartbeat_at,omitzero"`
+	LastHeartbeatDuration time.Duration `json:"last_heartbeat_duration,omitempty"`
+	HeartbeatRunningSince time.Time     `json:"heartbeat_running_since,omitzero"`
+	HeartbeatCount        int64         `json:"heartbeat_count"`
+
+	// Deacon inbox polling.
+	LastMailPollAt      time.Time `json:"last_mail_poll_at,omitzero"`
+	LastMailSuccessAt   time.Time `json:"last_mail_success_at,omitzero"`
+	MailPollFailures    int       `json:"mail_poll_failures,omitempty"`
+	UnreadLifecycleMail int       `json:"unread_lifecycle_mail"`
+	DuplicateMail       int       `json:"duplicate_mail,omitempty"`
+
+	Goroutines       int `json:"goroutines,omitempty"`
+	APIQueue         int `json:"api_queue"`
+	EventSubscribers int `json:"event_subscribers"`
+
+	SafeMode bool `json:"safe_mode,omitempty"`
+
+	// Source is where the report came from: "api" (/healthz) or "state"
+	// (daemon state file, when the API is off).
+	Source string `json:"source,omitempty"`
+}
+
+// healthMonitor holds the heartbeat loop's timing for /healthz.
+type healthMonitor struct {
+	mu           sync.Mutex
+	startedAt    time.Time
+	beatStarted  time.Time // zero when no heartbeat is running
+	lastBeat     time.Time
+	lastBeatTook time.Duration
+	beats        int64
+	mailPoll     MailPollStats
+}
+
+// healthState returns the daemon's health monitor, creating it on first use.
+func (d *Daemon) healthState() *healthMonitor {
+	d.healthOnce.Do(func() { d.health = &healthMonitor{startedAt: time.Now()} })
+	return d.health
+}
+
+func (h *healthMonitor) heartbeatStarted(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beatStarted = now
+}
+
+func (h *healthMonitor) heartbeatDone(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.beatStarted.IsZero() {
+		h.lastBeatTook = now.Sub(h.beatStarted)
+	}
+	h.beatStarted = time.Time{}
+	h.lastBeat = now
+	h.beats++
+}
+
+func (h *healthMonitor) mailPolled(stats MailPollStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mailPoll = stats
+}
+
+// healthReport assembles the daemon's health report.
+func (d *Daemon) healthReport(now time.Time) *HealthReport {
+	h := d.healthState()
+	h.mu.Lock()
+	r := &HealthReport{
+		PID:                   os.Getpid(),
+		StartedAt:             h.startedAt,
+		LastHeartbeatAt:       h.lastBeat,
+		LastHeartbeatDuration: h.lastBeatTook,
+		HeartbeatRunningSince: h.beatStarted,
+		HeartbeatCount:        h.beats,
+		LastMailPollAt:        h.mailPoll.LastPollAt,
+		LastMailSuccessAt:     h.mailPoll.LastSuccessAt,
+		MailPollFailures:      h.mailPoll.ConsecutiveFailures,
+		UnreadLifecycleMail:   h.mailPoll.LastUnread,
+		DuplicateMail:         h.mailPoll.Duplicates,
+		Goroutines:            runtime.NumGoroutine(),
+		Source:                "api",
+	}
+	h.mu.Unlock()
+
+	d.apiMu.Lock()
+	r.APIQueue = len(d.apiQueue)
+	d.apiMu.Unlock()
+	r.EventSubscribers = d.events().subscribers()
+
+	r.evaluate(now)
+	return r
+}
+
+// evaluate sets Status and Problems from the report's measurements.
+func (r *HealthReport) evaluate(now time.Time) {
+	r.Status = HealthOK
+	r.Problems = nil
+	problem := func(status, format string, args ...any) {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+		if status == HealthUnhealthy || r.Status == HealthOK {
+			r.Status = status
+		}
+	}
+
+	if r.SafeMode {
+		problem(HealthDegraded, "daemon is in safe mode")
+		return // Safe mode runs no heartbeat; restarting won't help
+	}
+	switch {
+	case !r.HeartbeatRunningSince.IsZero() && now.Sub(r.HeartbeatRunningSince) > heartbeatStallAfter:
+		problem(HealthUnhealthy, "heartbeat stuck for %v", now.Sub(r.HeartbeatRunningSince).Round(time.Second))
+	case r.HeartbeatRunningSince.IsZero() && !r.LastHeartbeatAt.IsZero() && now.Sub(r.LastHeartbeatAt) > heartbeatStallAfter:
+		problem(HealthUnhealthy, "no heartbeat for %v", now.Sub(r.LastHeartbeatAt).Round(time.Second))
+	case r.LastHeartbeatAt.IsZero() && r.HeartbeatRunningSince.IsZero() && !r.StartedAt.IsZero() && now.Sub(r.StartedAt) > heartbeatStallAfter:
+		problem(HealthUnhealthy, "no heartbeat since start %v ago", now.Sub(r.StartedAt).Round(time.Second))
+	}
+	if r.MailPollFailures >= mailPollFailureThreshold {
+		problem(HealthDegraded, "%d consecutive mail poll failures", r.MailPollFailures)
+	}
+	if r.Goroutines > healthMaxGoroutines {
+		problem(HealthDegraded, "%d goroutines", r.Goroutines)
+	}
+	if r.APIQueue > healthMaxAPIQueue {
+		problem(HealthDegraded, "%d API lifecycle requests queued", r.APIQueue)
+	}
+}
+
+// serveHealth answers GET /healthz: the health report, with status 503
+// when the daemon is unhealthy. It needs no token, so it reports timings
+// and counts only.
+func (d *Daemon) serveHealth(w http.ResponseWriter, r *http.Request) {
+	report := d.healthReport(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if report.Status == HealthUnhealthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ProbeHealth checks the health of the town's daemon from outside: through
+// /healthz when the API is configured, otherwise from the daemon state
+// file. An error means no report could be had (daemon down, API
+// unreachable).
+func ProbeHealth(townRoot string) (*HealthReport, error) {
+	running, pid, err := IsRunning(townRoot)
+	if err != nil {
+		return nil, fmt.Errorf("checking daemon: %w", err)
+	}
+	if !running {
+		return nil, fmt.Errorf("daemon is not running")
+	}
+
+	// A safe-mode daemon serves no API, so its state file is all there is
+	state, stateErr := LoadState(townRoot)
+	inSafeMode := stateErr == nil && state.SafeMode && state.PID == pid
+	if cfg := LoadPatrolConfig(townRoot); !inSafeMode && cfg != nil && cfg.API != nil && cfg.API.Listen != "" {
+		return probeHealthAPI(cfg.API.Listen)
+	}
+	if stateErr != nil {
+		return nil, fmt.Errorf("reading daemon state: %w", stateErr)
+	}
+	r := &HealthReport{
+		PID:             pid,
+		StartedAt:       state.StartedAt,
+		LastHeartbeatAt: state.LastHeartbeat,
+		HeartbeatCount:  state.HeartbeatCount,
+		SafeMode:        state.SafeMode,
+		Source:          "state",
+	}
+	if state.MailPoll != nil {
+		r.LastMailPollAt = state.MailPoll.LastPollAt
+		r.LastMailSuccessAt = state.MailPoll.LastSuccessAt
+		r.MailPollFailures = state.MailPoll.ConsecutiveFailures
+		r.UnreadLifecycleMail = state.MailPoll.LastUnread
+		r.DuplicateMail = state.MailPoll.Duplicates
+	}
+	r.evaluate(time.Now())
+	return r, nil
+}
+
+// probeHealthAPI fetches /healthz from the API at listen.
+func probeHealthAPI(listen string) (*HealthReport, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api.listen %q: %w", listen, err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	client := &http.Client{Timeout: healthProbeTimeout}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, port) + "/healthz")
+	if err != nil {
+		return nil, fmt.Errorf("probing /healthz: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var r HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("reading /healthz (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthReportEvaluate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		report HealthReport
+		status string
+	}{
+		{"fresh start", HealthReport{StartedAt: now.Add(-time.Minute)}, HealthOK},
+		{"recent heartbeat", HealthReport{LastHeartbeatAt: now.Add(-2 * time.Minute)}, HealthOK},
+		{"overdue", HealthReport{LastHeartbeatAt: now.Add(-time.Hour)}, HealthUnhealthy},
+		{"stuck", HealthReport{LastHeartbeatAt: now.Add(-time.Hour), HeartbeatRunningSince: now.Add(-time.Hour)}, HealthUnhealthy},
+		{"running normally", HealthReport{LastHeartbeatAt: now.Add(-time.Hour), HeartbeatRunningSince: now.Add(-time.Minute)}, HealthOK},
+		{"never beat", HealthReport{StartedAt: now.Add(-time.Hour)}, HealthUnhealthy},
+		{"mail failing", HealthReport{LastHeartbeatAt: now, MailPollFailures: mailPollFailureThreshold}, HealthDegraded},
+		{"mail failing and stuck", HealthReport{HeartbeatRunningSince: now.Add(-time.Hour), MailPollFailures: 5}, HealthUnhealthy},
+		{"safe mode", HealthReport{SafeMode: true, StartedAt: now.Add(-time.Hour)}, HealthDegraded},
+	}
+	for _, tc := range tests {
+		r := tc.report
+		r.evaluate(now)
+		if r.Status != tc.status {
+			t.Errorf("%s: status = %s (%v), want %s", tc.name, r.Status, r.Problems, tc.status)
+		}
+	}
+}
+
+func TestHealthzEndpoint(t *testing.T) {
+	d := testDaemon()
+	srv := httptest.NewServer(d.apiHandler())
+	defer srv.Close()
+
+	d.healthState().heartbeatStarted(time.Now().Add(-time.Second))
+	d.healthState().heartbeatDone(time.Now())
+	d.healthState().mailPolled(MailPollStats{LastSuccessAt: time.Now(), LastUnread: 2})
+
+	get := func() (int, HealthReport) {
+		resp, err := http.Get(srv.URL + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var r HealthReport
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, r
+	}
+
+	code, r := get()
+	if code != http.StatusOK || r.Status != HealthOK || r.HeartbeatCount != 1 || r.UnreadLifecycleMail != 2 || r.Goroutines == 0 {
+		t.Errorf("healthy daemon: HTTP %d %+v", code, r)
+	}
+
+	d.healthState().heartbeatStarted(time.Now().Add(-time.Hour))
+	code, r = get()
+	if code != http.StatusServiceUnavailable || r.Status != HealthUnhealthy || !strings.Contains(strings.Join(r.Problems, ";"), "stuck") {
+		t.Errorf("stuck heartbeat: HTTP %d %+v", code, r)
+	}
+}
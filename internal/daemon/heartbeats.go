@@ -0,0 +1,278 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+// AgentBeadStateStuck marks an agent whose session is running but which has
+// stopped reporting heartbeats. It is cleared back to running when the
+// agent reports again.
+const AgentBeadStateStuck = "stuck"
+
+// Agent heartbeat defaults
+const (
+	defaultAgentHeartbeatInterval = 5 * time.Minute
+
+	// defaultStuckHeartbeats is how many intervals an agent may miss before
+	// it is marked stuck, when StuckAfter isn't set.
+	defaultStuckHeartbeats = 3
+)
+
+// AgentHeartbeatConfig turns on the agent self-report heartbeat protocol,
+// under "lifecycle.agent_heartbeats" in mayor/daemon.json:
+//
+//	"agent_heartbeats": {
+//	  "interval": "5m",
+//	  "stuck_after": "20m",
+//	  "cycle_after": "45m",
+//	  "agents": ["*-crew-*", "*-polecat-*"]
+//	}
+//
+// Covered agents run 'gt agents heartbeat' every interval, which stamps
+// last_heartbeat in their state.json. The daemon marks an agent whose
+// session is running but which has been silent for StuckAfter as stuck on
+// its agent bead, and cycles it after CycleAfter.
+type AgentHeartbeatConfig struct {
+	// Interval is how often agents are expected to report (Go duration
+	// string, default "5m"). Sessions get it as GT_HEARTBEAT_INTERVAL.
+	Interval string `json:"interval,omitempty"`
+
+	// StuckAfter is the silence after which an agent is marked stuck
+	// (Go duration string, default three intervals).
+	StuckAfter string `json:"stuck_after,omitempty"`
+
+	// CycleAfter is the silence after which a stuck agent's session is
+	// cycled (Go duration string, default: never).
+	CycleAfter string `json:"cycle_after,omitempty"`
+
+	// Agents are the identities held to the protocol, exact or path.Match
+	// globs (default: all managed agents).
+	Agents []string `json:"agents,omitempty"`
+}
+
+// covers reports whether identity is held to the heartbeat protocol.
+func (c *AgentHeartbeatConfig) covers(identity string) bool {
+	return c != nil && (len(c.Agents) == 0 || matchesAnyPattern(c.Agents, identity))
+}
+
+// AgentHeartbeat is what the daemon knows about one agent's heartbeats.
+// Copied into State each heartbeat for gt daemon status.
+type AgentHeartbeat struct {
+	// LastHeartbeat is when the agent last reported, zero if it never has
+	// this session.
+	LastHeartbeat time.Time `json:"last_heartbeat,omitzero"`
+
+	// RunningSince is when the daemon first saw the current session
+	// running. Silence is measured from here until the first report.
+	RunningSince time.Time `json:"running_since"`
+
+	// StuckSince is when the agent was marked stuck, zero while healthy.
+	StuckSince time.Time `json:"stuck_since,omitzero"`
+
+	// Cycles counts sessions cycled for silence since the daemon started.
+	Cycles int `json:"cycles,omitempty"`
+}
+
+// silentSince is when the agent last showed a sign of life.
+func (h *AgentHeartbeat) silentSince() time.Time {
+	if h.LastHeartbeat.After(h.RunningSince) {
+		return h.LastHeartbeat
+	}
+	return h.RunningSince
+}
+
+// heartbeatMonitor tracks agent heartbeats between daemon heartbeats.
+type heartbeatMonitor struct {
+	agents map[string]*AgentHeartbeat
+
+	// setBeadState records an agent_state on the identity's agent bead;
+	// replaced in tests.
+	setBeadState func(identity, agentState string) error
+}
+
+func (d *Daemon) heartbeatState() *heartbeatMonitor {
+	if d.heartbeats == nil {
+		d.heartbeats = &heartbeatMonitor{
+			agents:       make(map[string]*AgentHeartbeat),
+			setBeadState: d.setAgentBeadState,
+		}
+	}
+	return d.heartbeats
+}
+
+// setAgentBeadState records agentState on identity's agent bead.
+func (d *Daemon) setAgentBeadState(identity, agentState string) error {
+	agentBeadID := d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return fmt.Errorf("no agent bead for %s", identity)
+	}
+	return beads.New(d.config.TownRoot).UpdateAgentState(agentBeadID, agentState, nil)
+}
+
+// snapshot returns a copy of the tracked heartbeats, or nil if none.
+func (m *heartbeatMonitor) snapshot() map[string]*AgentHeartbeat {
+	if m == nil || len(m.agents) == 0 {
+		return nil
+	}
+	out := make(map[string]*AgentHeartbeat, len(m.agents))
+	for identity, h := range m.agents {
+		hb := *h
+		out[identity] = &hb
+	}
+	return out
+}
+
+// heartbeatDurations resolves the interval, stuck, and cycle thresholds.
+// A zero cycle threshold means silent agents are never cycled.
+func (d *Daemon) heartbeatDurations(cfg *AgentHeartbeatConfig) (interval, stuckAfter, cycleAfter time.Duration) {
+	interval = d.lifecycleDuration("agent_heartbeats.interval", cfg.Interval, defaultAgentHeartbeatInterval)
+	if interval <= 0 {
+		interval = defaultAgentHeartbeatInterval
+	}
+	stuckAfter = d.lifecycleDuration("agent_heartbeats.stuck_after", cfg.StuckAfter, defaultStuckHeartbeats*interval)
+	cycleAfter = d.lifecycleDuration("agent_heartbeats.cycle_after", cfg.CycleAfter, 0)
+	return interval, stuckAfter, cycleAfter
+}
+
+// checkAgentHeartbeats reads the last heartbeat of every covered agent with
+// a running session, marks agents silent for too long as stuck (and cycles
+// them if configured), and clears the mark once they report again.
+func (d *Daemon) checkAgentHeartbeats(now time.Time) {
+	cfg := d.patrolConfig.lifecycleConfig().AgentHeartbeats
+	if cfg == nil {
+		return
+	}
+	_, stuckAfter, cycleAfter := d.heartbeatDurations(cfg)
+	m := d.heartbeatState()
+
+	running := make(map[string]bool)
+	for _, identity := range d.managedIdentities() {
+		if !cfg.covers(identity) {
+			continue
+		}
+		session := d.identityToSession(identity)
+		if session == "" {
+			continue
+		}
+		if ok, _ := d.tmux.HasSession(session); !ok {
+			continue
+		}
+		running[identity] = true
+
+		h := m.agents[identity]
+		if h == nil {
+			h = &AgentHeartbeat{RunningSince: now}
+			m.agents[identity] = h
+		}
+		if last := d.readAgentHeartbeat(identity); last.After(h.LastHeartbeat) {
+			h.LastHeartbeat = last
+		}
+
+		silence := now.Sub(h.silentSince())
+		switch {
+		case silence < stuckAfter:
+			if !h.StuckSince.IsZero() {
+				d.logger.Printf("Agent heartbeat: %s reported again after %v stuck", identity, now.Sub(h.StuckSince).Round(time.Second))
+				h.StuckSince = time.Time{}
+				d.reportHeartbeatState(m, identity, AgentBeadStateRunning)
+			}
+
+		case h.StuckSince.IsZero():
+			h.StuckSince = now
+			d.logger.Printf("Agent heartbeat: %s silent for %v, marking stuck", identity, silence.Round(time.Second))
+			d.reportHeartbeatState(m, identity, AgentBeadStateStuck)
+
+		case cycleAfter > 0 && silence >= cycleAfter:
+			request := &LifecycleRequest{
+				From:        identity,
+				Action:      ActionCycle,
+				Timestamp:   now,
+				Reason:      fmt.Sprintf("no heartbeat for %v", silence.Round(time.Second)),
+				RequestedBy: requestedByHeartbeat,
+			}
+			if !d.admitRestart(request) {
+				continue // Try again next heartbeat
+			}
+			d.logger.Printf("Agent heartbeat: cycling %s after %v of silence", identity, silence.Round(time.Second))
+			if err := d.executeLifecycleAction(request); err != nil {
+				d.logger.Printf("Warning: heartbeat cycle of %s failed: %v", identity, err)
+				continue
+			}
+			// The new session starts its own silence clock
+			h.Cycles++
+			h.RunningSince, h.StuckSince = now, time.Time{}
+			d.reportHeartbeatState(m, identity, AgentBeadStateRunning)
+		}
+	}
+
+	// Agents whose session is gone start fresh when it comes back
+	for identity := range m.agents {
+		if !running[identity] {
+			delete(m.agents, identity)
+		}
+	}
+}
+
+// reportHeartbeatState records a stuck/running transition on the agent
+// bead and the event stream.
+func (d *Daemon) reportHeartbeatState(m *heartbeatMonitor, identity, agentState string) {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would set %s agent_state=%s", identity, agentState)
+	} else if err := m.setBeadState(identity, agentState); err != nil {
+		d.logger.Printf("Warning: could not set %s agent_state=%s: %v", identity, agentState, err)
+	}
+	d.publishAgentState(identity, d.identityToSession(identity), agentState, "heartbeat")
+}
+
+// readAgentHeartbeat returns the last_heartbeat an agent recorded in its
+// state, zero if it has none.
+func (d *Daemon) readAgentHeartbeat(identity string) time.Time {
+	s, err := d.readAgentState(identity)
+	if err != nil || s == nil {
+		return time.Time{}
+	}
+	return s.LastHeartbeat
+}
+
+// heartbeatEnv returns the heartbeat interval to export to identity's
+// session, or "" if it isn't held to the protocol.
+func (d *Daemon) heartbeatEnv(identity string) string {
+	cfg := d.patrolConfig.lifecycleConfig().AgentHeartbeats
+	if !cfg.covers(identity) {
+		return ""
+	}
+	interval, _, _ := d.heartbeatDurations(cfg)
+	return interval.String()
+}
+
+// Heartbeat records that identity is alive by stamping last_heartbeat in
+// its state file. Agents held to the heartbeat protocol call this (via
+// 'gt agents heartbeat') every GT_HEARTBEAT_INTERVAL.
+func (c *SessionController) Heartbeat(identity string, now time.Time) error {
+	identity = normalizeIdentity(identity)
+	workDir := c.d.agentWorkDir(identity)
+	if workDir == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+	}
+	return c.d.agentStates().Update(c.d.agentRef(identity, workDir), func(s *state.AgentState) error {
+		s.LastHeartbeat = now.UTC()
+		return nil
+	})
+}
+
+// IdentityFromEnv derives the daemon identity of the agent whose session
+// set the given environment (GT_ROLE, GT_RIG, GT_CREW, GT_POLECAT), or "".
+func IdentityFromEnv(getenv func(string) string) string {
+	env := make(map[string]string)
+	for _, key := range []string{"GT_ROLE", "GT_RIG", "GT_CREW", "GT_POLECAT"} {
+		if v := strings.TrimSpace(getenv(key)); v != "" {
+			env[key] = v
+		}
+	}
+	return envIdentity(env)
+}
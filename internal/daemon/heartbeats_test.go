@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckAgentHeartbeats(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", "max"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{AgentHeartbeats: &AgentHeartbeatConfig{
+		StuckAfter: "10m",
+		Agents:     []string{"*-crew-*"},
+	}}}
+	session := d.identityToSession("gastown-crew-max")
+	tm := &paneTmux{panes: map[string]int{session: 100}}
+	d.tmux = tm
+
+	var reported []string
+	d.heartbeatState().setBeadState = func(identity, agentState string) error {
+		reported = append(reported, identity+"="+agentState)
+		return nil
+	}
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	d.checkAgentHeartbeats(start)
+	h := d.heartbeats.agents["gastown-crew-max"]
+	if h == nil || !h.RunningSince.Equal(start) || len(d.heartbeats.agents) != 1 {
+		t.Fatalf("tracked %+v, want only gastown-crew-max running since start", d.heartbeats.agents)
+	}
+
+	// A session that never reports is stuck once the threshold passes
+	d.checkAgentHeartbeats(start.Add(11 * time.Minute))
+	if h.StuckSince.IsZero() || len(reported) != 1 || reported[0] != "gastown-crew-max=stuck" {
+		t.Errorf("after 11m silence: %+v, reported %v", h, reported)
+	}
+	d.checkAgentHeartbeats(start.Add(12 * time.Minute))
+	if len(reported) != 1 {
+		t.Errorf("stuck reported again: %v", reported)
+	}
+
+	ctl := &SessionController{d: d}
+	if err := ctl.Heartbeat("gastown/crew/max", start.Add(13*time.Minute)); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if !ctl.ReportedSince("gastown-crew-max", start.Add(13*time.Minute)) || ctl.ReportedSince("gastown-crew-max", start.Add(14*time.Minute)) {
+		t.Error("ReportedSince doesn't match the heartbeat")
+	}
+	d.checkAgentHeartbeats(start.Add(14 * time.Minute))
+	if !h.StuckSince.IsZero() || len(reported) != 2 || reported[1] != "gastown-crew-max=running" {
+		t.Errorf("after heartbeat: %+v, reported %v", h, reported)
+	}
+	if snap := d.heartbeats.snapshot(); !snap["gastown-crew-max"].LastHeartbeat.Equal(start.Add(13 * time.Minute)) {
+		t.Errorf("snapshot = %+v", snap["gastown-crew-max"])
+	}
+
+	// Sessions that are gone are forgotten
+	delete(tm.panes, session)
+	d.checkAgentHeartbeats(start.Add(15 * time.Minute))
+	if len(d.heartbeats.agents) != 0 {
+		t.Errorf("tracked %+v after session ended", d.heartbeats.agents)
+	}
+}
+
+func TestIdentityFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"GT_ROLE": "crew", "GT_RIG": "gastown", "GT_CREW": "max"}, "gastown-crew-max"},
+		{map[string]string{"GT_ROLE": "witness", "GT_RIG": "gastown"}, "gastown-witness"},
+		{map[string]string{"GT_ROLE": "mayor"}, "mayor"},
+		{map[string]string{"GT_ROLE": "crew", "GT_RIG": "gastown"}, ""},
+		{map[string]string{}, ""},
+	}
+	for _, tc := range tests {
+		if got := IdentityFromEnv(func(k string) string { return tc.env[k] }); got != tc.want {
+			t.Errorf("IdentityFromEnv(%v) = %q, want %q", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestStuckAgentsSurviveRestart(t *testing.T) {
+	d := testDaemon()
+	now := time.Now()
+	d.heartbeatState().agents["gastown-crew-max"] = &AgentHeartbeat{RunningSince: now, StuckSince: now}
+	d.heartbeats.agents["gastown-crew-joe"] = &AgentHeartbeat{RunningSince: now}
+
+	restarted := testDaemon()
+	restarted.restoreRuntimeState(d.captureRuntimeState(now), now)
+	if h := restarted.heartbeatState().agents["gastown-crew-max"]; h == nil || h.StuckSince.IsZero() {
+		t.Errorf("stuck mark not restored: %+v", h)
+	}
+	if _, ok := restarted.heartbeats.agents["gastown-crew-joe"]; ok {
+		t.Error("healthy agent restored; its silence clock should restart")
+	}
+}
@@ -0,0 +1,208 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// requestedByHibernate marks shutdowns made to hibernate idle agents in
+// audit entries and restart history.
+const requestedByHibernate = "daemon/hibernate"
+
+// defaultHibernateIdleAfter is how long a crew member must sit idle before
+// its session is hibernated.
+const defaultHibernateIdleAfter = 2 * time.Hour
+
+// HibernateConfig stops the sessions of idle crew and wakes them when there
+// is something for them to do, under "hibernate" in mayor/daemon.json:
+//
+//	"hibernate": {"enabled": true, "idle_after": "90m", "agents": ["gastown-crew-*"]}
+//
+// A crew member is idle when its agent bead reports agent_state "idle",
+// nothing is hooked, and the bead hasn't changed for idle_after. If it also
+// has no unread mail and no open work assigned, its session is shut down
+// like a shutdown request and remembered as hibernating. A hibernating
+// session is started again as soon as work is hooked or assigned to it or
+// mail arrives for it. Starting it by hand ends the hibernation too.
+type HibernateConfig struct {
+	// Enabled turns on hibernation.
+	Enabled bool `json:"enabled"`
+
+	// IdleAfter is how long an agent must be idle before it is hibernated
+	// (Go duration string, default "2h").
+	IdleAfter string `json:"idle_after,omitempty"`
+
+	// Agents limits hibernation to these crew identities, exact or
+	// path.Match globs (default: every crew member).
+	Agents []string `json:"agents,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *HibernateConfig) Validate() error {
+	if c == nil || c.IdleAfter == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(c.IdleAfter); err != nil || d <= 0 {
+		return fmt.Errorf("hibernate: invalid idle_after %q", c.IdleAfter)
+	}
+	return nil
+}
+
+// hibernateConfig returns the hibernate config, or nil if it is unset,
+// disabled, or invalid.
+func (c *DaemonPatrolConfig) hibernateConfig() *HibernateConfig {
+	if c == nil || c.Hibernate == nil || !c.Hibernate.Enabled || c.Hibernate.Validate() != nil {
+		return nil
+	}
+	return c.Hibernate
+}
+
+// idleAfter returns the configured idle threshold.
+func (c *HibernateConfig) idleAfter() time.Duration {
+	if c.IdleAfter == "" {
+		return defaultHibernateIdleAfter
+	}
+	d, _ := time.ParseDuration(c.IdleAfter) // checked by Validate
+	return d
+}
+
+// hibernateIdleAgents hibernates idle crew and wakes hibernating crew that
+// has work or mail. Hibernating agents are remembered in state.
+func (d *Daemon) hibernateIdleAgents(state *State, now time.Time) {
+	cfg := d.patrolConfig.hibernateConfig()
+	if cfg == nil {
+		if len(state.Hibernating) > 0 {
+			// Hibernation was turned off: wake everyone it put to sleep.
+			cfg = &HibernateConfig{}
+		} else {
+			return
+		}
+	}
+	for _, identity := range d.managedIdentities() {
+		parsed, err := parseIdentity(identity)
+		if err != nil || parsed.RoleType != "crew" {
+			continue
+		}
+		if len(cfg.Agents) > 0 && !matchesPrewarmAgents(identity, cfg.Agents) {
+			continue
+		}
+		sessionName := d.identityToSession(identity)
+		if sessionName == "" {
+			continue
+		}
+		running, err := d.tmux.HasSession(sessionName)
+		if err != nil {
+			continue
+		}
+
+		if since, hibernating := state.Hibernating[identity]; hibernating {
+			if running {
+				delete(state.Hibernating, identity) // Started by hand
+				continue
+			}
+			reason := d.wakeReason(identity, parsed)
+			if reason == "" && cfg.Enabled {
+				continue
+			}
+			if reason == "" {
+				reason = "hibernation disabled"
+			}
+			d.wakeAgent(state, identity, sessionName, since, reason)
+			continue
+		}
+
+		if running && cfg.Enabled && d.idleFor(identity, now) >= cfg.idleAfter() && d.wakeReason(identity, parsed) == "" {
+			d.hibernateAgent(state, identity, now, cfg.idleAfter())
+		}
+	}
+}
+
+// idleFor returns how long identity's agent bead has reported idle with
+// nothing hooked, or 0 if it is busy or its bead can't be read.
+func (d *Daemon) idleFor(identity string, now time.Time) time.Duration {
+	beadID := d.identityToAgentBeadID(identity)
+	if beadID == "" {
+		return 0
+	}
+	info, err := d.getAgentBeadInfo(beadID)
+	if err != nil || info.State != "idle" || info.HookBead != "" {
+		return 0
+	}
+	updated, err := time.Parse(time.RFC3339, info.LastUpdate)
+	if err != nil {
+		return 0
+	}
+	return now.Sub(updated)
+}
+
+// wakeReason returns why identity should be awake - hooked work, open
+// assigned work, or unread mail - or "" if there is nothing for it to do.
+func (d *Daemon) wakeReason(identity string, parsed *ParsedIdentity) string {
+	if beadID := d.identityToAgentBeadID(identity); beadID != "" {
+		if info, err := d.getAgentBeadInfo(beadID); err == nil && info.HookBead != "" {
+			return "work hooked: " + info.HookBead
+		}
+	}
+
+	address := parsed.RigName + "/crew/" + parsed.AgentName
+	rigDir := filepath.Join(d.config.TownRoot, parsed.RigName)
+	for _, status := range []string{"open", "in_progress"} {
+		out, err := d.beadsClient().Run(rigDir, "list", "--assignee="+address, "--status="+status, "--json")
+		if err != nil {
+			continue
+		}
+		var issues []struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(out, &issues) == nil && len(issues) > 0 {
+			return "work assigned: " + issues[0].ID
+		}
+	}
+
+	if messages, err := d.mailClient().Inbox(address); err == nil {
+		if unread := countUnread(messages); unread > 0 {
+			return fmt.Sprintf("%d unread message(s)", unread)
+		}
+	}
+	return ""
+}
+
+// hibernateAgent shuts down an idle agent's session and records it.
+func (d *Daemon) hibernateAgent(state *State, identity string, now time.Time, idleAfter time.Duration) {
+	request := &LifecycleRequest{
+		From:        identity,
+		Action:      ActionShutdown,
+		Timestamp:   now,
+		DryRun:      d.config.DryRun,
+		Reason:      fmt.Sprintf("idle for over %s", idleAfter),
+		RequestedBy: requestedByHibernate,
+	}
+	if err := d.executeLifecycleAction(request); err != nil {
+		d.logger.Printf("Warning: hibernate: failed to stop %s: %v", identity, err)
+		return
+	}
+	if request.DryRun {
+		return
+	}
+	d.logger.Printf("Hibernate: stopped %s, idle for over %s", identity, idleAfter)
+	if state.Hibernating == nil {
+		state.Hibernating = make(map[string]time.Time)
+	}
+	state.Hibernating[identity] = now
+}
+
+// wakeAgent starts a hibernating agent's session again.
+func (d *Daemon) wakeAgent(state *State, identity, sessionName string, since time.Time, reason string) {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Hibernate: would wake %s (%s)", identity, reason)
+		return
+	}
+	if err := d.restartSession(sessionName, identity); err != nil {
+		d.logger.Printf("Warning: hibernate: failed to wake %s: %v", identity, err)
+		return // Try again next heartbeat
+	}
+	d.logger.Printf("Hibernate: woke %s after %s (%s)", identity, time.Since(since).Round(time.Minute), reason)
+	delete(state.Hibernating, identity)
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// hibernateTmux has one running session per entry in running.
+type hibernateTmux struct {
+	SessionBackend
+	running map[string]bool
+}
+
+func (f *hibernateTmux) HasSession(name string) (bool, error) { return f.running[name], nil }
+
+func (f *hibernateTmux) KillSession(name string) error {
+	delete(f.running, name)
+	return nil
+}
+
+func (f *hibernateTmux) GetPanePID(string) (string, error) { return "", fmt.Errorf("no pane") }
+
+// hibernateBeads serves one crew agent bead and the work assigned to it.
+type hibernateBeads struct {
+	state     string
+	hook      string
+	updatedAt time.Time
+	assigned  []string
+}
+
+func (b *hibernateBeads) RoleConfig(string) (*beads.RoleConfig, error) { return nil, nil }
+
+func (b *hibernateBeads) Run(dir string, args ...string) ([]byte, error) {
+	switch args[0] {
+	case "show":
+		return json.Marshal([]map[string]string{{
+			"id":          args[1],
+			"issue_type":  "agent",
+			"description": "agent_state: " + b.state,
+			"hook_bead":   b.hook,
+			"updated_at":  b.updatedAt.Format(time.RFC3339),
+		}})
+	case "list":
+		var issues []map[string]string
+		if strings.Contains(strings.Join(args, " "), "--status=open") {
+			for _, id := range b.assigned {
+				issues = append(issues, map[string]string{"id": id})
+			}
+		}
+		return json.Marshal(issues)
+	}
+	return nil, fmt.Errorf("unexpected bd %v", args)
+}
+
+// hibernateMail holds the unread mail of every inbox.
+type hibernateMail struct {
+	MailClient
+	unread int
+}
+
+func (m *hibernateMail) Inbox(string) ([]BeadsMessage, error) {
+	messages := make([]BeadsMessage, m.unread)
+	return messages, nil
+}
+
+func TestHibernateIdleCrew(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs": {"gastown": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", "max"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	session := d.identityToSession("gastown-crew-max")
+	fakeTmux := &hibernateTmux{running: map[string]bool{session: true}}
+	fakeBeads := &hibernateBeads{state: "idle", updatedAt: now.Add(-30 * time.Minute)}
+	fakeMail := &hibernateMail{}
+	d.tmux, d.beads, d.mail = fakeTmux, fakeBeads, fakeMail
+	d.patrolConfig = &DaemonPatrolConfig{Hibernate: &HibernateConfig{Enabled: true, IdleAfter: "1h"}}
+	state := &State{}
+
+	// Not idle long enough
+	d.hibernateIdleAgents(state, now)
+	if !fakeTmux.running[session] || len(state.Hibernating) != 0 {
+		t.Fatal("crew idle for 30m hibernated with idle_after 1h")
+	}
+
+	// Idle, but has unread mail
+	fakeMail.unread = 1
+	d.hibernateIdleAgents(state, now.Add(time.Hour))
+	if !fakeTmux.running[session] {
+		t.Fatal("crew with unread mail hibernated")
+	}
+
+	// Idle with nothing to do
+	fakeMail.unread = 0
+	d.hibernateIdleAgents(state, now.Add(time.Hour))
+	if fakeTmux.running[session] {
+		t.Error("idle crew session not stopped")
+	}
+	if _, ok := state.Hibernating["gastown-crew-max"]; !ok {
+		t.Fatalf("idle crew not recorded as hibernating: %v", state.Hibernating)
+	}
+
+	// Still nothing to do: stays asleep
+	d.hibernateIdleAgents(state, now.Add(2*time.Hour))
+	if _, ok := state.Hibernating["gastown-crew-max"]; !ok {
+		t.Error("hibernating crew woken with nothing to do")
+	}
+
+	// Started by hand: no longer hibernating
+	fakeTmux.running[session] = true
+	d.hibernateIdleAgents(state, now.Add(2*time.Hour))
+	if _, ok := state.Hibernating["gastown-crew-max"]; ok {
+		t.Error("crew started by hand still recorded as hibernating")
+	}
+}
+
+func TestWakeReason(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	fakeBeads := &hibernateBeads{state: "idle"}
+	fakeMail := &hibernateMail{}
+	d.beads, d.mail = fakeBeads, fakeMail
+	parsed := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+
+	if got := d.wakeReason("gastown-crew-max", parsed); got != "" {
+		t.Errorf("wakeReason with nothing to do = %q", got)
+	}
+	fakeMail.unread = 2
+	if got := d.wakeReason("gastown-crew-max", parsed); !strings.Contains(got, "2 unread") {
+		t.Errorf("wakeReason with mail = %q", got)
+	}
+	fakeBeads.assigned = []string{"gt-42"}
+	if got := d.wakeReason("gastown-crew-max", parsed); got != "work assigned: gt-42" {
+		t.Errorf("wakeReason with assigned work = %q", got)
+	}
+	fakeBeads.hook = "gt-7"
+	if got := d.wakeReason("gastown-crew-max", parsed); got != "work hooked: gt-7" {
+		t.Errorf("wakeReason with hooked work = %q", got)
+	}
+}
+
+func TestHibernateConfigValidate(t *testing.T) {
+	if err := (&HibernateConfig{Enabled: true, IdleAfter: "a while"}).Validate(); err == nil {
+		t.Error("expected error for invalid idle_after")
+	}
+	if got := (&HibernateConfig{}).idleAfter(); got != defaultHibernateIdleAfter {
+		t.Errorf("default idle_after = %v", got)
+	}
+}
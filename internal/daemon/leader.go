@@ -0,0 +1,206 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// Only one daemon per town may act: two would race each other killing and
+// restarting the same sessions. The daemon.lock flock decides this between
+// processes on one machine, and the kernel drops it the moment the holder
+// dies. Towns on a shared filesystem can run daemons on several machines,
+// where flock is not reliable, so the leader also holds a lease in
+// daemon/leader.json that it renews while running. A standby daemon
+// (gt daemon start --standby) waits for the lock and a lapsed lease, then
+// takes over.
+//
+// A leader that stops cleanly releases the lease, and standbys exit with it:
+// 'gt daemon stop' stops the town's daemons, it doesn't hand over to one.
+
+const (
+	// leaseTTL is how long a lease is honored without renewal.
+	leaseTTL = 90 * time.Second
+
+	// leaseRenewInterval is how often the leader renews its lease.
+	leaseRenewInterval = 30 * time.Second
+
+	// standbyPollInterval is how often a standby checks for a dead leader.
+	standbyPollInterval = 10 * time.Second
+)
+
+// errLeaderStopped is returned to a standby whose leader stopped cleanly.
+var errLeaderStopped = errors.New("leader stopped cleanly")
+
+// Lease records which daemon is the town's leader.
+type Lease struct {
+	Holder     string    `json:"holder"` // host:pid
+	Host       string    `json:"host"`
+	PID        int       `json:"pid"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Released is set when the leader stops cleanly.
+	Released bool `json:"released,omitempty"`
+}
+
+// LeaseFile returns the path to the leader lease.
+func LeaseFile(townRoot string) string {
+	return filepath.Join(townRoot, "daemon", "leader.json")
+}
+
+// LoadLease reads the leader lease. Returns nil, nil if there is none.
+func LoadLease(townRoot string) (*Lease, error) {
+	data, err := os.ReadFile(LeaseFile(townRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var lease Lease
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", LeaseFile(townRoot), err)
+	}
+	return &lease, nil
+}
+
+// Live reports whether the lease is held: not released and not expired.
+func (l *Lease) Live(now time.Time) bool {
+	return l != nil && !l.Released && now.Before(l.ExpiresAt)
+}
+
+// leaseHolder identifies this daemon process across machines.
+func leaseHolder() (host, holder string) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return host, host + ":" + strconv.Itoa(os.Getpid())
+}
+
+// acquireLeadership takes the daemon lock and lease, returning the held
+// lock. Without Standby it fails at once if another daemon leads; with
+// Standby it waits until the leader dies.
+func (d *Daemon) acquireLeadership() (*flock.Flock, error) {
+	fileLock := flock.New(filepath.Join(d.config.TownRoot, "daemon", "daemon.lock"))
+	standbySince := time.Now()
+	announced := false
+
+	for {
+		locked, err := fileLock.TryLock()
+		if err != nil {
+			return nil, fmt.Errorf("acquiring lock: %w", err)
+		}
+		var reason string
+		if locked {
+			reason, err = d.claimLease(time.Now(), standbySince)
+			if reason == "" && err == nil {
+				return fileLock, nil
+			}
+			_ = fileLock.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			reason = "lock held by another process"
+		}
+
+		if !d.config.Standby {
+			return nil, fmt.Errorf("daemon already running (%s)", reason)
+		}
+		if !announced {
+			d.logger.Printf("Standby: another daemon leads (%s); taking over if it dies", reason)
+			announced = true
+		}
+		select {
+		case <-d.ctx.Done():
+			return nil, d.ctx.Err()
+		case <-time.After(standbyPollInterval):
+		}
+	}
+}
+
+// claimLease writes this daemon's lease once the lock is held. A non-empty
+// reason means another daemon's lease is still live (it runs on another
+// machine sharing this town). A standby gets errLeaderStopped if the leader
+// released its lease after the standby started.
+func (d *Daemon) claimLease(now, standbySince time.Time) (string, error) {
+	host, holder := leaseHolder()
+	prev, err := LoadLease(d.config.TownRoot)
+	if err != nil {
+		d.logger.Printf("Warning: ignoring unreadable lease: %v", err)
+		prev = nil
+	}
+
+	switch {
+	case prev == nil || prev.Holder == holder:
+	case prev.Released:
+		if d.config.Standby && prev.RenewedAt.After(standbySince) {
+			return "", errLeaderStopped
+		}
+	case prev.Host == host:
+		// Same machine and we hold the flock: the previous holder is dead.
+		d.logger.Printf("Previous leader %s is gone, taking over", prev.Holder)
+	case prev.Live(now):
+		return fmt.Sprintf("lease held by %s until %s", prev.Holder, prev.ExpiresAt.Format(time.RFC3339)), nil
+	default:
+		d.logger.Printf("Lease of %s expired at %s, taking over", prev.Holder, prev.ExpiresAt.Format(time.RFC3339))
+	}
+
+	lease := &Lease{Holder: holder, Host: host, PID: os.Getpid(), AcquiredAt: now, RenewedAt: now, ExpiresAt: now.Add(leaseTTL)}
+	if err := util.AtomicWriteJSON(LeaseFile(d.config.TownRoot), lease); err != nil {
+		return "", fmt.Errorf("writing lease: %w", err)
+	}
+	d.lease = lease
+	d.logger.Printf("Acquired leadership as %s", holder)
+	return "", nil
+}
+
+// confirmLeadership renews the lease before the daemon acts. If another
+// daemon has taken the lease (this one stalled past the TTL), it steps down
+// and returns false so nothing is killed or restarted twice.
+func (d *Daemon) confirmLeadership(now time.Time) bool {
+	if d.lease == nil {
+		return true // Not running under Run (tests, one-shot commands)
+	}
+	if d.lostLeadership {
+		return false
+	}
+	current, err := LoadLease(d.config.TownRoot)
+	if err == nil && current != nil && current.Holder != d.lease.Holder {
+		d.logger.Printf("Lost leadership to %s, stepping down", current.Holder)
+		d.lostLeadership = true
+		d.cancel()
+		return false
+	}
+	if now.Sub(d.lease.RenewedAt) < leaseRenewInterval && now.Before(d.lease.ExpiresAt) {
+		return true
+	}
+	d.lease.RenewedAt = now
+	d.lease.ExpiresAt = now.Add(leaseTTL)
+	if err := util.AtomicWriteJSON(LeaseFile(d.config.TownRoot), d.lease); err != nil {
+		d.logger.Printf("Warning: renewing lease: %v", err)
+	}
+	return true
+}
+
+// releaseLeadership marks the lease released on clean shutdown.
+func (d *Daemon) releaseLeadership() {
+	if d.lease == nil || d.lostLeadership {
+		return
+	}
+	d.lease.Released = true
+	d.lease.RenewedAt = time.Now()
+	if err := util.AtomicWriteJSON(LeaseFile(d.config.TownRoot), d.lease); err != nil {
+		d.logger.Printf("Warning: releasing lease: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+func testLeaderDaemon(t *testing.T, townRoot string) *Daemon {
+	t.Helper()
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	t.Cleanup(d.cancel)
+	return d
+}
+
+func writeTestLease(t *testing.T, townRoot string, lease *Lease) {
+	t.Helper()
+	if err := util.AtomicWriteJSON(LeaseFile(townRoot), lease); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimLease(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "daemon"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	d := testLeaderDaemon(t, townRoot)
+
+	// A live lease from another machine blocks takeover
+	writeTestLease(t, townRoot, &Lease{Holder: "other-host:42", Host: "other-host", PID: 42, ExpiresAt: now.Add(time.Minute)})
+	if reason, err := d.claimLease(now, now); err != nil || reason == "" {
+		t.Fatalf("claim over live remote lease = %q, %v; want blocked", reason, err)
+	}
+
+	// Once it lapses, the lease is taken
+	if reason, err := d.claimLease(now.Add(2*time.Minute), now); err != nil || reason != "" {
+		t.Fatalf("claim over expired lease = %q, %v; want taken", reason, err)
+	}
+	lease, err := LoadLease(townRoot)
+	if err != nil || lease.PID != os.Getpid() || !lease.Live(now.Add(2*time.Minute)) {
+		t.Fatalf("lease after claim = %+v, %v", lease, err)
+	}
+
+	// A standby exits if the leader stops cleanly after the standby started
+	writeTestLease(t, townRoot, &Lease{Holder: "other-host:42", Host: "other-host", RenewedAt: now, Released: true})
+	standby := testLeaderDaemon(t, townRoot)
+	standby.config.Standby = true
+	if _, err := standby.claimLease(now, now.Add(-time.Minute)); !errors.Is(err, errLeaderStopped) {
+		t.Errorf("standby after clean stop: err = %v, want errLeaderStopped", err)
+	}
+}
+
+func TestConfirmLeadership(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "daemon"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	d := testLeaderDaemon(t, townRoot)
+	if _, err := d.claimLease(now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Renewal extends the lease
+	if !d.confirmLeadership(now.Add(leaseRenewInterval)) {
+		t.Fatal("leader should still lead")
+	}
+	lease, _ := LoadLease(townRoot)
+	if !lease.ExpiresAt.Equal(now.Add(leaseRenewInterval + leaseTTL)) {
+		t.Errorf("ExpiresAt = %v, want renewed", lease.ExpiresAt)
+	}
+
+	// Another daemon took over while this one stalled: step down
+	writeTestLease(t, townRoot, &Lease{Holder: "other-host:42", Host: "other-host", ExpiresAt: now.Add(time.Hour)})
+	if d.confirmLeadership(now.Add(5 * time.Minute)) {
+		t.Fatal("daemon should step down after losing the lease")
+	}
+	if d.ctx.Err() == nil {
+		t.Error("stepping down should cancel the daemon context")
+	}
+	if lease, _ := LoadLease(townRoot); lease.Holder != "other-host:42" {
+		t.Errorf("stepped-down daemon overwrote the lease: %+v", lease)
+	}
+}
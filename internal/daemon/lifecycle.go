@@ -1,21 +1,32 @@
 package daemon
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/budget"
 	"github.com/steveyegge/gastown/internal/config"
 	"github.com/steveyegge/gastown/internal/constants"
+	"github.com/steveyegge/gastown/internal/crew"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/notifier"
 	"github.com/steveyegge/gastown/internal/rig"
 	"github.com/steveyegge/gastown/internal/session"
 	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/vcs"
+	"github.com/steveyegge/gastown/pkg/eventstream"
 )
 
 // BeadsMessage represents a message from gt mail inbox --json.
@@ -31,183 +42,680 @@ type BeadsMessage struct {
 	Type      string `json:"type"`
 }
 
-// MaxLifecycleMessageAge is the maximum age of a lifecycle message before it's ignored.
+// MaxLifecycleMessageAge is the default maximum age of a lifecycle message before it's ignored.
 // Messages older than this are considered stale and deleted without execution.
+// Override per action or sender with lifecycle.max_age / lifecycle.sender_max_age.
 const MaxLifecycleMessageAge = 6 * time.Hour
 
+// lifecycleSubjectPrefix marks a lifecycle request (case-insensitive).
+const lifecycleSubjectPrefix = "lifecycle:"
+
+// deaconMailSubjects are the subject prefixes of the deacon mail the daemon
+// acts on: lifecycle requests, escalation reports, and emergency stops.
+var deaconMailSubjects = []string{lifecycleSubjectPrefix, escalationSubjectPrefix, emergencyStopSubjectPrefix}
+
+// deaconInbox returns the deacon mail to process. A mail client that can
+// filter returns only the unread mail with a subject in deaconMailSubjects;
+// otherwise the whole inbox is returned and read mail is skipped later.
+func (d *Daemon) deaconInbox() ([]BeadsMessage, error) {
+	client := d.mailClient()
+	if filtered, ok := client.(unreadInbox); ok {
+		return filtered.UnreadInbox("deacon/", deaconMailSubjects...)
+	}
+	return client.Inbox("deacon/")
+}
+
 // ProcessLifecycleRequests checks for and processes lifecycle requests from the deacon inbox.
 func (d *Daemon) ProcessLifecycleRequests() {
-	// Get mail for deacon identity (using gt mail, not bd mail)
-	cmd := exec.Command("gt", "mail", "inbox", "--identity", "deacon/", "--json")
-	cmd.Dir = d.config.TownRoot
+	defer d.beginRecordedPass()()
 
-	output, err := cmd.Output()
+	span := d.startSpan("mail.poll", "gt.mailbox", "deacon/")
+	messages, err := d.deaconInbox()
 	if err != nil {
+		d.endSpan(span, err)
 		d.logger.Printf("Warning: failed to fetch deacon inbox: %v", err)
+		d.recordMailPollFailure(err, time.Now())
 		return
 	}
-
-	if len(output) == 0 || string(output) == "[]" || string(output) == "[]\n" {
+	span.SetAttributes("gt.messages", strconv.Itoa(len(messages)))
+	d.endSpan(span, nil)
+	d.recordMailPollSuccess(messages, time.Now())
+	if len(messages) == 0 {
 		return
 	}
 
-	var messages []BeadsMessage
-	if err := json.Unmarshal(output, &messages); err != nil {
-		d.logger.Printf("Error parsing mail: %v", err)
-		return
+	staleCount := 0
+	defer func() {
+		if staleCount >= staleLifecycleSurgeThreshold {
+			d.notify(notifier.EventStaleLifecycleSurge, map[string]string{
+				"count": strconv.Itoa(staleCount),
+			})
+		}
+	}()
+
+	// Emergency stops go first, so no request in the same batch restarts
+	// a session the stop is about to kill.
+	for i := range messages {
+		if !messages[i].Read && d.processEmergencyStopMail(&messages[i]) {
+			messages[i].Read = true
+		}
 	}
 
-	for _, msg := range messages {
-		if msg.Read {
+	for i := range messages {
+		if messages[i].Read {
 			continue // Already processed
 		}
+		if d.collapseDuplicateMail(&messages[i], time.Now()) {
+			continue
+		}
+		if d.processLifecycleMessage(&messages[i]) {
+			staleCount++
+		}
+	}
+}
 
-		request := d.parseLifecycleRequest(&msg)
-		if request == nil {
-			continue // Not a lifecycle request
-		}
-
-		// Check message age - ignore stale lifecycle requests
-		if msgTime, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
-			age := time.Since(msgTime)
-			if age > MaxLifecycleMessageAge {
-				d.logger.Printf("Ignoring stale lifecycle request from %s (age: %v, max: %v) - deleting",
-					request.From, age.Round(time.Minute), MaxLifecycleMessageAge)
-				if err := d.closeMessage(msg.ID); err != nil {
-					d.logger.Printf("Warning: failed to delete stale message %s: %v", msg.ID, err)
-				}
-				continue
+// processLifecycleMessage handles one unread deacon message, tracing it as
+// its own trace. Returns true if it was a stale lifecycle request.
+func (d *Daemon) processLifecycleMessage(msg *BeadsMessage) (stale bool) {
+	span := d.startSpan("lifecycle.message", "gt.message_id", msg.ID, "gt.from", msg.From)
+	var actionErr error
+	defer func() {
+		d.endSpan(span, actionErr)
+		if actionErr != nil {
+			d.forgetDuplicates(msg)
+		}
+	}()
+
+	// Witness escalation reports are recorded, not executed.
+	if d.processEscalation(msg, time.Now()) {
+		span.SetAttributes("gt.kind", "escalation")
+		return false
+	}
+
+	parseSpan := d.startSpan("lifecycle.parse")
+	request := d.parseLifecycleRequest(msg)
+	d.endSpan(parseSpan, nil)
+	if request == nil {
+		return false // Not a lifecycle request
+	}
+	span.SetAttributes("gt.kind", "lifecycle", "gt.action", string(request.Action), "gt.parser", request.Parser)
+
+	// Check message age - ignore stale lifecycle requests
+	if msgTime, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+		age := time.Since(msgTime)
+		maxAge := d.lifecycleMessageMaxAge(request)
+		if age > maxAge {
+			d.logger.Printf("Ignoring stale lifecycle request from %s (age: %v, max: %v) - deleting",
+				request.From, age.Round(time.Minute), maxAge)
+			if err := d.closeMessage(msg.ID, request.From, fmt.Sprintf("stale: age %v exceeds max %v", age.Round(time.Minute), maxAge)); err != nil {
+				d.logger.Printf("Warning: failed to delete stale message %s: %v", msg.ID, err)
 			}
+			actionErr = fmt.Errorf("%w: expired unexecuted (age %v, max %v)", ErrStaleRequest, age.Round(time.Minute), maxAge)
+			d.replyLifecycleResult(request, request.From, actionErr)
+			return true
 		}
+	}
 
-		d.logger.Printf("Processing lifecycle request from %s: %s", request.From, request.Action)
+	// Without a token allowing the action, the request is rejected.
+	if err := d.authorizeLifecycle(request); err != nil {
+		d.logger.Printf("Rejecting %s from %s: %v", request.Action, request.From, err)
+		span.SetAttributes("gt.outcome", "unauthorized")
+		if !d.config.DryRun {
+			if err := d.closeMessage(msg.ID, request.From, "rejected: unauthorized"); err != nil {
+				d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+			}
+		}
+		actionErr = err
+		d.replyLifecycleResult(request, request.From, actionErr)
+		return false
+	}
+
+	// A sender over its rate limit gets a rejection instead.
+	if !d.checkRateLimit(msg, request, time.Now()) {
+		span.SetAttributes("gt.outcome", "rate_limited")
+		return false
+	}
 
-		// CRITICAL: Delete message FIRST, before executing action.
-		// This prevents stale messages from being reprocessed on every heartbeat.
-		// "Claim then execute" pattern: claim by deleting, then execute.
-		// Even if action fails, the message is gone - sender must re-request.
-		if err := d.closeMessage(msg.ID); err != nil {
-			d.logger.Printf("Warning: failed to delete message %s before execution: %v", msg.ID, err)
-			// Continue anyway - better to attempt action than leave stale message
+	// A cycle waiting on the agent's handoff document stays unclaimed too.
+	switch decision, reason := d.checkHandoff(request, time.Now()); decision {
+	case handoffWait:
+		d.logger.Printf("Deferring cycle of %s: %s", request.From, reason)
+		span.SetAttributes("gt.outcome", "awaiting_handoff")
+		return false
+	case handoffReject:
+		d.logger.Printf("Rejecting cycle of %s: %s", request.From, reason)
+		if !d.config.DryRun {
+			if err := d.closeMessage(msg.ID, request.From, "rejected: "+reason); err != nil {
+				d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+			}
+			d.clearAgentRequestFlags(request.From)
+		}
+		actionErr = classify(ErrStateVerificationFailed, fmt.Errorf("cycle rejected: %s", reason))
+		d.replyLifecycleResult(request, request.From, actionErr)
+		return false
+	default:
+		if reason != "" {
+			d.logger.Printf("Cycle of %s: %s", request.From, reason)
+		}
+	}
+
+	// Throttled restarts stay unclaimed and are retried next heartbeat.
+	if !d.admitRestart(request) {
+		span.SetAttributes("gt.outcome", "throttled")
+		return false
+	}
+
+	d.logger.Printf("Processing lifecycle request from %s: %s (%s body)", request.From, request.Action, request.Parser)
+
+	// CRITICAL: Delete message FIRST, before executing action.
+	// This prevents stale messages from being reprocessed on every heartbeat.
+	// "Claim then execute" pattern: claim by deleting, then execute.
+	// Even if action fails, the message is gone - sender must re-request.
+	// A dry-run daemon leaves mail alone so a real daemon started after it
+	// can still act on it.
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would delete message %s before execution", msg.ID)
+	} else if err := d.closeMessage(msg.ID, request.From,
+		fmt.Sprintf("lifecycle request claimed for %s (body parsed as %s)", request.Action, request.Parser)); err != nil {
+		d.logger.Printf("Warning: failed to delete message %s before execution: %v", msg.ID, err)
+		// Continue anyway - better to attempt action than leave stale message
+	}
+
+	if request.Action == ActionBatch {
+		d.executeLifecycleBatch(request)
+		return false
+	}
+
+	// The request has been claimed, so any requesting flag the agent set
+	// in its state file is answered and must not be reaped later.
+	if !d.config.DryRun && !request.DryRun {
+		d.clearAgentRequestFlags(request.From)
+	}
+
+	actionErr = d.executeLifecycleAction(request)
+	d.replyLifecycleResult(request, request.From, actionErr)
+	if actionErr != nil {
+		d.logger.Printf("Error executing lifecycle action: %v", actionErr)
+		if request.Action.restartsSession() {
+			d.notify(notifier.EventRestartFailed, request.withContext(map[string]string{
+				"agent":  request.From,
+				"action": string(request.Action),
+				"error":  actionErr.Error(),
+			}))
+		}
+	}
+	return false
+}
+
+// fetchDeaconInbox returns the deacon's unread lifecycle requests (using gt
+// mail, not bd mail).
+func fetchDeaconInbox(townRoot string) ([]BeadsMessage, error) {
+	return gtMail{townRoot: townRoot}.UnreadInbox("deacon/", lifecycleSubjectPrefix)
+}
+
+// PendingLifecycle is a lifecycle action the daemon has not yet executed.
+type PendingLifecycle struct {
+	Identity string          // daemon identity of the requesting agent
+	Action   LifecycleAction // requested action
+	Source   string          // "mail" or "state_flag"
+}
+
+// PendingLifecycleActions returns lifecycle actions queued for the agent
+// running in sessionName: unread LIFECYCLE mail in the deacon inbox, plus
+// requesting_* flags in the agent's state that the daemon hasn't
+// answered yet. On inbox errors the state-flag results are still returned
+// alongside the error. Used to warn humans before they attach to a session the
+// daemon is about to kill.
+func PendingLifecycleActions(townRoot, sessionName string) ([]PendingLifecycle, error) {
+	d := NewWithBackends(&Config{TownRoot: townRoot}, Backends{}, log.New(io.Discard, "", 0))
+
+	var pending []PendingLifecycle
+
+	for _, identity := range d.managedIdentities() {
+		if d.identityToSession(identity) != sessionName {
+			continue
+		}
+		agentState, err := d.readAgentState(identity)
+		if err != nil {
+			continue
+		}
+		if action, ok := requestedAction(agentState); ok {
+			pending = append(pending, PendingLifecycle{Identity: identity, Action: action, Source: "state_flag"})
+		}
+	}
+
+	// State flags are still reported if the inbox can't be read
+	messages, err := fetchDeaconInbox(townRoot)
+	if err != nil {
+		return pending, fmt.Errorf("fetching deacon inbox: %w", err)
+	}
+	for _, msg := range messages {
+		if msg.Read {
+			continue
+		}
+		request := d.parseLifecycleRequest(&msg)
+		if request == nil || d.identityToSession(request.From) != sessionName {
+			continue
 		}
+		pending = append(pending, PendingLifecycle{Identity: request.From, Action: request.Action, Source: "mail"})
+	}
+
+	return pending, nil
+}
 
-		if err := d.executeLifecycleAction(request); err != nil {
-			d.logger.Printf("Error executing lifecycle action: %v", err)
+// lifecycleMessageMaxAge returns how old a lifecycle request may be before it
+// is discarded as stale. A matching sender override wins, then the per-action
+// override, then MaxLifecycleMessageAge. Invalid durations are logged and skipped.
+func (d *Daemon) lifecycleMessageMaxAge(request *LifecycleRequest) time.Duration {
+	cfg := d.patrolConfig.lifecycleConfig()
+
+	// Exact sender match first, then glob patterns in sorted order so the
+	// result doesn't depend on map iteration.
+	if value, ok := cfg.SenderMaxAge[request.From]; ok {
+		if maxAge, ok := d.parseMaxAge("sender_max_age", request.From, value); ok {
+			return maxAge
+		}
+	}
+	patterns := make([]string, 0, len(cfg.SenderMaxAge))
+	for pattern := range cfg.SenderMaxAge {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, request.From); !matched || pattern == request.From {
 			continue
 		}
+		if maxAge, ok := d.parseMaxAge("sender_max_age", pattern, cfg.SenderMaxAge[pattern]); ok {
+			return maxAge
+		}
+	}
+
+	if value, ok := cfg.MaxAge[string(request.Action)]; ok {
+		if maxAge, ok := d.parseMaxAge("max_age", string(request.Action), value); ok {
+			return maxAge
+		}
+	}
+	return MaxLifecycleMessageAge
+}
+
+// parseMaxAge parses a lifecycle max age override, logging invalid values.
+func (d *Daemon) parseMaxAge(section, key, value string) (time.Duration, bool) {
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge <= 0 {
+		d.logger.Printf("Warning: invalid lifecycle.%s[%q] %q, ignoring", section, key, value)
+		return 0, false
 	}
+	return maxAge, true
 }
 
 // LifecycleBody is the structured body format for lifecycle requests.
 // Claude should send mail with JSON body: {"action": "cycle"} or {"action": "shutdown"}
+// Add "dry_run": true to verify the request without executing it.
+//
+// The mayor (or deacon) can instead send a batch for coordinated multi-agent
+// operations: {"actions": [{"target": "gastown-crew-max", "action": "cycle"}, ...]}
 type LifecycleBody struct {
-	Action string `json:"action"`
+	Action  string               `json:"action"`
+	Actions []LifecycleBatchItem `json:"actions,omitempty"`
+	DryRun  bool                 `json:"dry_run,omitempty"`
+
+	// Notify lists identities to mail the result to once the action has
+	// run (e.g. ["mayor", "gastown-witness"]).
+	Notify []string `json:"notify,omitempty"`
+
+	// Handoff is the path of an already-written handoff document for a
+	// cycle (default: .runtime/handoff.md in the agent's workdir).
+	Handoff string `json:"handoff,omitempty"`
+
+	// Token is an API token authorizing the request, required when
+	// lifecycle.require_token is set.
+	Token string `json:"token,omitempty"`
+
+	// Reason says why the action is wanted ("context full", "stuck on a
+	// merge"). Recorded in the restart history.
+	Reason string `json:"reason,omitempty"`
+
+	// TaskBead is the bead the agent was working on when it asked.
+	TaskBead string `json:"task_bead,omitempty"`
+
+	// Urgency is "low", "normal", or "high".
+	Urgency string `json:"urgency,omitempty"`
 }
 
 // parseLifecycleRequest extracts a lifecycle request from a message.
-// Uses structured body parsing instead of keyword matching on subject.
+// Uses structured body parsing instead of keyword matching on subject; the
+// body goes through the configured parser chain (see body_parsers.go).
 func (d *Daemon) parseLifecycleRequest(msg *BeadsMessage) *LifecycleRequest {
 	// Gate: subject must start with "LIFECYCLE:"
 	subject := strings.ToLower(msg.Subject)
-	if !strings.HasPrefix(subject, "lifecycle:") {
+	if !strings.HasPrefix(subject, lifecycleSubjectPrefix) {
 		return nil
 	}
 
-	// Parse structured body for action
-	var body LifecycleBody
-	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
-		// Fallback: check for simple action strings in body
-		bodyLower := strings.ToLower(strings.TrimSpace(msg.Body))
-		switch {
-		case bodyLower == "restart" || bodyLower == "action: restart":
-			body.Action = "restart"
-		case bodyLower == "shutdown" || bodyLower == "action: shutdown" || bodyLower == "stop":
-			body.Action = "shutdown"
-		case bodyLower == "cycle" || bodyLower == "action: cycle":
-			body.Action = "cycle"
-		default:
-			d.logger.Printf("Lifecycle request with unparseable body: %q", msg.Body)
-			return nil
+	body, parser, err := d.parseLifecycleBody(msg.Body)
+	if err != nil {
+		d.logger.Printf("Lifecycle request with unparseable body: %q: %v", msg.Body, err)
+		return nil
+	}
+	if len(body.Actions) > 0 {
+		request := d.parseLifecycleBatch(msg, body)
+		if request != nil {
+			request.Parser = parser
 		}
+		return request
 	}
 
-	// Map action string to enum
-	var action LifecycleAction
-	switch strings.ToLower(body.Action) {
-	case "restart":
-		action = ActionRestart
-	case "shutdown", "stop":
-		action = ActionShutdown
-	case "cycle":
-		action = ActionCycle
-	default:
+	action, ok := parseLifecycleAction(body.Action)
+	if !ok {
 		d.logger.Printf("Unknown lifecycle action: %q", body.Action)
 		return nil
 	}
 
 	return &LifecycleRequest{
+		RequestID: msg.ID,
 		From:      msg.From,
 		Action:    action,
 		Timestamp: time.Now(),
+		DryRun:    body.DryRun,
+		Notify:    d.parseNotifyList(msg.From, body.Notify),
+		Handoff:   body.Handoff,
+		Token:     body.Token,
+		Parser:    parser,
+		Reason:    body.Reason,
+		TaskBead:  body.TaskBead,
+		Urgency:   d.parseUrgency(msg.From, body.Urgency),
 	}
 }
 
+// parseLifecycleAction maps an action string to its enum.
+func parseLifecycleAction(s string) (LifecycleAction, bool) {
+	switch strings.ToLower(s) {
+	case "restart":
+		return ActionRestart, true
+	case "shutdown", "stop":
+		return ActionShutdown, true
+	case "cycle":
+		return ActionCycle, true
+	case "refresh":
+		return ActionRefresh, true
+	default:
+		if action := LifecycleAction(strings.ToLower(s)); lookupLifecycleAction(action) != nil {
+			return action, true
+		}
+		return "", false
+	}
+}
+
+// Lifecycle request urgencies.
+const (
+	UrgencyLow    = "low"
+	UrgencyNormal = "normal"
+	UrgencyHigh   = "high"
+)
+
+// parseUrgency normalizes a request's urgency. Unknown values are logged
+// and dropped rather than rejecting the request.
+func (d *Daemon) parseUrgency(from, urgency string) string {
+	switch u := strings.ToLower(strings.TrimSpace(urgency)); u {
+	case "":
+		return ""
+	case UrgencyLow, UrgencyNormal, UrgencyHigh:
+		return u
+	default:
+		d.logger.Printf("Lifecycle request from %s: unknown urgency %q, ignoring", from, urgency)
+		return ""
+	}
+}
+
+// defaultRefreshPrompt is typed into a session for ActionRefresh. The agent
+// re-reads its project docs in place; nothing about the session is restarted.
+const defaultRefreshPrompt = "LIFECYCLE_REFRESH: project docs or role context changed. " +
+	"Re-read CLAUDE.md (and AGENTS.md if present) and run `gt prime` to reload your role context, " +
+	"then continue your current work where you left off."
+
+// refreshPrompt returns the configured refresh instruction.
+func (d *Daemon) refreshPrompt() string {
+	if prompt := d.patrolConfig.lifecycleConfig().RefreshPrompt; prompt != "" {
+		return prompt
+	}
+	return defaultRefreshPrompt
+}
+
 // executeLifecycleAction performs the requested lifecycle action.
-func (d *Daemon) executeLifecycleAction(request *LifecycleRequest) error {
+func (d *Daemon) executeLifecycleAction(request *LifecycleRequest) (err error) {
 	// Determine session name from sender identity
 	sessionName := d.identityToSession(request.From)
 	if sessionName == "" {
-		return fmt.Errorf("unknown agent identity: %s", request.From)
+		return fmt.Errorf("%w: %s", ErrUnknownIdentity, request.From)
 	}
 
+	span := d.startSpan("lifecycle."+string(request.Action), "gt.identity", request.From, "gt.session", sessionName)
+	defer func() { d.endSpan(span, err) }()
+
 	d.logger.Printf("Executing %s for session %s", request.Action, sessionName)
+	dryRun := request.DryRun || d.config.DryRun
+	if request.Action.restartsSession() && !dryRun {
+		started := time.Now()
+		defer func() { d.recordRestart(request, sessionName, started, err) }()
+	}
+	d.publish(eventstream.TypeLifecycleStarted, request.From, map[string]any{
+		"action":  string(request.Action),
+		"session": sessionName,
+		"dry_run": dryRun,
+	})
+	defer func() {
+		data := map[string]any{"action": string(request.Action), "session": sessionName, "dry_run": dryRun, "status": BatchStatusOK}
+		if err != nil {
+			data["status"], data["error"] = BatchStatusFailed, err.Error()
+			if code := ErrorCode(err); code != "" {
+				data["error_code"] = code
+			}
+		}
+		d.publish(eventstream.TypeLifecycleCompleted, request.From, data)
+	}()
+
+	// verified collects the checks that passed, for the audit log.
+	verified := []string{fmt.Sprintf("identity %s resolves to session %s", request.From, sessionName)}
+	verifySpan := d.startSpan("lifecycle.verify")
 
 	// Check agent bead state (ZFC: trust what agent reports) - gt-39ttg
 	agentBeadID := d.identityToAgentBeadID(request.From)
 	if agentBeadID != "" {
 		if beadState, err := d.getAgentBeadState(agentBeadID); err == nil {
 			d.logger.Printf("Agent bead %s reports state: %s", agentBeadID, beadState)
+			verified = append(verified, fmt.Sprintf("agent bead %s reports %s", agentBeadID, beadState))
 		}
 	}
 
 	// Check if session exists (tmux detection still needed for lifecycle actions)
 	running, err := d.tmux.HasSession(sessionName)
+	d.endSpan(verifySpan, err)
 	if err != nil {
-		return fmt.Errorf("checking session: %w", err)
+		return classify(ErrSessionBackend, fmt.Errorf("checking session: %w", err))
+	}
+
+	// The town's policy hook may veto the action (a dry run asks it too)
+	if running || request.Action.restartsSession() {
+		if err := d.checkPolicy(&PolicyProposal{
+			Identity:    request.From,
+			Session:     sessionName,
+			Action:      request.Action,
+			RequestedBy: request.requester(),
+			Reason:      request.Reason,
+			RequestID:   request.RequestID,
+			DryRun:      dryRun,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Dry run: all verification above has passed, report the plan and stop.
+	if request.DryRun || d.config.DryRun {
+		return d.dryRunLifecycleAction(request, sessionName, running)
 	}
 
 	switch request.Action {
 	case ActionShutdown:
 		if running {
-			if err := d.tmux.KillSession(sessionName); err != nil {
-				return fmt.Errorf("killing session: %w", err)
-			}
-			d.logger.Printf("Killed session %s", sessionName)
+			return d.killLifecycleSession(request, sessionName, verified)
 		}
 		return nil
 
 	case ActionCycle, ActionRestart:
-		if running {
-			// Kill the session first
-			if err := d.tmux.KillSession(sessionName); err != nil {
-				return fmt.Errorf("killing session: %w", err)
-			}
-			d.logger.Printf("Killed session %s for restart", sessionName)
+		if request.Action == ActionCycle {
+			d.collectHandoff(request, time.Now())
+		}
+		return d.restartLifecycleSession(request, sessionName, running, verified)
 
-			// Wait a moment
-			time.Sleep(constants.ShutdownNotifyDelay)
+	case ActionRefresh:
+		if !running {
+			return classify(ErrStateVerificationFailed, fmt.Errorf("session %s not running, nothing to refresh", sessionName))
+		}
+		if err := d.tmux.NudgeSession(sessionName, d.refreshPrompt()); err != nil {
+			return classify(ErrSessionBackend, fmt.Errorf("sending refresh prompt: %w", err))
 		}
+		d.logger.Printf("Sent refresh prompt to session %s", sessionName)
+		return nil
 
-		// Restart the session
-		if err := d.restartSession(sessionName, request.From); err != nil {
-			return fmt.Errorf("restarting session: %w", err)
+	default:
+		handler := lookupLifecycleAction(request.Action)
+		if handler == nil {
+			return fmt.Errorf("unknown action: %s", request.Action)
 		}
-		d.logger.Printf("Restarted session %s", sessionName)
+		return handler(&LifecycleActionContext{
+			Request:  request,
+			Session:  sessionName,
+			Running:  running,
+			Logger:   d.logger,
+			d:        d,
+			verified: verified,
+		})
+	}
+}
+
+// killLifecycleSession kills a running session for a lifecycle request,
+// running its pre-kill hook first and recording the kill.
+func (d *Daemon) killLifecycleSession(request *LifecycleRequest, sessionName string, verified []string) error {
+	d.runAgentHook(request.From, sessionName, preKillHook(request.Action), request.Action)
+	d.trackSessionProcesses(request.From, sessionName)
+	killSpan := d.startSpan("session.kill")
+	err := d.runStep(StepKill, func(ctx context.Context) error { return d.sessionsFor(ctx).KillSession(sessionName) })
+	d.auditRequest(AuditKillSession, sessionName, request, append(verified, "session running", "action "+string(request.Action)), err)
+	d.endSpan(killSpan, err)
+	if err != nil {
+		return classify(ErrSessionBackend, fmt.Errorf("killing session: %w", err))
+	}
+	d.logger.Printf("Killed session %s", sessionName)
+	d.removeAgentContainer(sessionName)
+	d.recordKill(request.From, request.Action, request.From)
+	return nil
+}
+
+// restartLifecycleSession kills the session if it is running, waits for
+// tmux to tear it down, and starts it again.
+func (d *Daemon) restartLifecycleSession(request *LifecycleRequest, sessionName string, running bool, verified []string) error {
+	if running {
+		if err := d.killLifecycleSession(request, sessionName, verified); err != nil {
+			return err
+		}
+
+		// Let tmux finish tearing the session down before reusing its name
+		teardownSpan := d.startSpan("session.teardown")
+		d.awaitTeardown(request.From, sessionName)
+		d.endSpan(teardownSpan, nil)
+	}
+
+	startSpan := d.startSpan("session.start")
+	err := d.restartSession(sessionName, request.From)
+	d.endSpan(startSpan, err)
+	if err != nil {
+		return fmt.Errorf("restarting session: %w", err)
+	}
+	if err := d.smokeTestRestart(request, sessionName); err != nil {
+		return err
+	}
+	d.logger.Printf("Restarted session %s", sessionName)
+	return nil
+}
+
+// dryRunLifecycleAction logs what executeLifecycleAction would do for a request
+// without touching any sessions. Restart prerequisites (rig state, working
+// directory, start command) are resolved exactly as restartSession would, so a
+// dry run surfaces the same configuration errors a real run would hit.
+func (d *Daemon) dryRunLifecycleAction(request *LifecycleRequest, sessionName string, running bool) error {
+	for _, step := range lifecyclePlan(request.Action, sessionName, running) {
+		d.logger.Printf("[dry-run] %s: %s", request.From, step)
+	}
+	if hook := preKillHook(request.Action); running && hook != "" {
+		d.logger.Printf("[dry-run] %s: would run hook %s if present", request.From, hook)
+	}
+
+	if !request.Action.restartsSession() {
 		return nil
+	}
 
+	config, parsed, err := d.getRoleConfigForIdentity(request.From)
+	if err != nil {
+		return fmt.Errorf("parsing identity: %w", err)
+	}
+	if parsed.RigName != "" {
+		if operational, reason := d.isRigOperational(parsed.RigName); !operational {
+			return classify(ErrStateVerificationFailed, fmt.Errorf("[dry-run] restart would be refused: %s", reason))
+		}
+		if outside, reason := d.outsideSchedule(request.From, time.Now()); outside {
+			return classify(ErrStateVerificationFailed, fmt.Errorf("[dry-run] restart would be refused: %s", reason))
+		}
+	}
+	workDir := d.getWorkDir(config, parsed)
+	if workDir == "" {
+		return classify(ErrStateVerificationFailed, fmt.Errorf("[dry-run] cannot determine working directory for %s", request.From))
+	}
+	if _, err := os.Stat(workDir); err != nil {
+		d.logger.Printf("[dry-run] %s: warning: working directory %s is not accessible: %v", request.From, workDir, err)
+	}
+	d.logger.Printf("[dry-run] %s: workdir=%s pre-sync=%v", request.From, workDir, d.getNeedsPreSync(config, parsed))
+	d.logger.Printf("[dry-run] %s: start command: %s", request.From,
+		d.containerStartCommand(request.From, sessionName, workDir, parsed, readOnlyStartCommand(parsed, d.getStartCommand(config, parsed))))
+	if isReadOnlyRole(parsed.RoleType) {
+		d.logger.Printf("[dry-run] %s: would lock %s as a read-only checkout", request.From, workDir)
+	}
+	for _, hook := range []string{HookPostStart, HookSmokeTest} {
+		if _, err := os.Stat(filepath.Join(AgentHooksDir(workDir), hook)); err == nil {
+			d.logger.Printf("[dry-run] %s: would run hook %s", request.From, hook)
+		}
+	}
+	return nil
+}
+
+// lifecyclePlan describes the session operations for an action, in order.
+func lifecyclePlan(action LifecycleAction, sessionName string, running bool) []string {
+	var steps []string
+	switch action {
+	case ActionShutdown:
+		if running {
+			steps = append(steps, fmt.Sprintf("would kill session %s", sessionName))
+		} else {
+			steps = append(steps, fmt.Sprintf("session %s not running, nothing to kill", sessionName))
+		}
+	case ActionCycle, ActionRestart:
+		if running {
+			steps = append(steps, fmt.Sprintf("would kill session %s", sessionName))
+		}
+		steps = append(steps, fmt.Sprintf("would start session %s", sessionName))
+	case ActionRefresh:
+		if running {
+			steps = append(steps, fmt.Sprintf("would send refresh prompt to session %s", sessionName))
+		} else {
+			steps = append(steps, fmt.Sprintf("session %s not running, nothing to refresh", sessionName))
+		}
 	default:
-		return fmt.Errorf("unknown action: %s", request.Action)
+		if lookupLifecycleAction(action) != nil {
+			steps = append(steps, fmt.Sprintf("would run registered action %q on session %s", action, sessionName))
+			break
+		}
+		steps = append(steps, fmt.Sprintf("unknown action %q, nothing would be done", action))
 	}
+	return steps
 }
 
 // ParsedIdentity holds the components extracted from an agent identity string.
@@ -221,7 +729,25 @@ type ParsedIdentity struct {
 // parseIdentity extracts role type, rig name, and agent name from an identity string.
 // This is the ONLY place where identity string patterns are parsed.
 // All other functions should use the extracted components to look up role beads.
+// Identities of the built-in roles are tried first, then the patterns of the
+// registered role plugins (see role_plugins.go).
 func parseIdentity(identity string) (*ParsedIdentity, error) {
+	parsed, err := parseBuiltinIdentity(identity)
+	if err != nil {
+		var ok bool
+		if parsed, ok = parsePluginIdentity(identity); !ok {
+			return nil, classify(ErrUnknownIdentity, fmt.Errorf("unknown identity format: %s", identity))
+		}
+	}
+	// Identities naming a renamed rig resolve to its current name
+	if parsed.RigName != "" {
+		parsed.RigName = resolveRigAlias(parsed.RigName)
+	}
+	return parsed, nil
+}
+
+// parseBuiltinIdentity parses the identities of the built-in roles.
+func parseBuiltinIdentity(identity string) (*ParsedIdentity, error) {
 	switch identity {
 	case "mayor":
 		return &ParsedIdentity{RoleType: "mayor"}, nil
@@ -265,7 +791,7 @@ func parseIdentity(identity string) (*ParsedIdentity, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("unknown identity format: %s", identity)
+	return nil, classify(ErrUnknownIdentity, fmt.Errorf("unknown identity format: %s", identity))
 }
 
 // getRoleConfigForIdentity looks up the role bead for an identity and returns its config.
@@ -277,10 +803,10 @@ func (d *Daemon) getRoleConfigForIdentity(identity string) (*beads.RoleConfig, *
 	}
 
 	// Look up role bead
-	b := beads.New(d.config.TownRoot)
+	b := d.beadsClient()
 
 	roleBeadID := beads.RoleBeadIDTown(parsed.RoleType)
-	roleConfig, err := b.GetRoleConfig(roleBeadID)
+	roleConfig, err := b.RoleConfig(roleBeadID)
 	if err != nil {
 		d.logger.Printf("Warning: failed to get role config for %s: %v", roleBeadID, err)
 	}
@@ -289,7 +815,7 @@ func (d *Daemon) getRoleConfigForIdentity(identity string) (*beads.RoleConfig, *
 	if roleConfig == nil {
 		legacyRoleBeadID := beads.RoleBeadID(parsed.RoleType) // gt-<role>-role
 		if legacyRoleBeadID != roleBeadID {
-			legacyCfg, legacyErr := b.GetRoleConfig(legacyRoleBeadID)
+			legacyCfg, legacyErr := b.RoleConfig(legacyRoleBeadID)
 			if legacyErr != nil {
 				d.logger.Printf("Warning: failed to get legacy role config for %s: %v", legacyRoleBeadID, legacyErr)
 			} else if legacyCfg != nil {
@@ -298,43 +824,41 @@ func (d *Daemon) getRoleConfigForIdentity(identity string) (*beads.RoleConfig, *
 		}
 	}
 
+	// Plugin roles without a role bead use their role.json definition
+	if roleConfig == nil {
+		if p := lookupRolePlugin(parsed.RoleType); p != nil {
+			roleConfig = p.roleConfig()
+		}
+	}
+
 	// Return parsed identity even if config is nil (caller can use defaults)
 	return roleConfig, parsed, nil
 }
 
 // identityToSession converts a beads identity to a tmux session name.
-// Uses role bead config if available, falls back to hardcoded patterns.
+// Uses the daemon.json session_naming scheme and role bead config if
+// available, falls back to the built-in patterns.
 func (d *Daemon) identityToSession(identity string) string {
 	config, parsed, err := d.getRoleConfigForIdentity(identity)
 	if err != nil {
 		return ""
 	}
 
-	// If role bead has session_pattern, use it
-	if config != nil && config.SessionPattern != "" {
-		return beads.ExpandRolePattern(config.SessionPattern, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
-	}
-
-	// Fallback: use default patterns based on role type
-	switch parsed.RoleType {
-	case "mayor":
-		return session.MayorSessionName()
-	case "deacon":
-		return session.DeaconSessionName()
-	case "witness", "refinery":
-		return fmt.Sprintf("gt-%s-%s", parsed.RigName, parsed.RoleType)
-	case "crew":
-		return fmt.Sprintf("gt-%s-crew-%s", parsed.RigName, parsed.AgentName)
-	case "polecat":
-		return fmt.Sprintf("gt-%s-%s", parsed.RigName, parsed.AgentName)
-	default:
-		return ""
+	var rolePattern string
+	if config != nil {
+		rolePattern = config.SessionPattern
 	}
+	return d.patrolConfig.sessionNaming().sessionName(parsed, d.config.TownRoot, rolePattern)
 }
 
 // restartSession starts a new session for the given agent.
 // Uses role bead config if available, falls back to hardcoded defaults.
 func (d *Daemon) restartSession(sessionName, identity string) error {
+	if err := d.checkNotStopped(identity); err != nil {
+		d.logger.Printf("Skipping session restart for %s: %v", identity, err)
+		return err
+	}
+
 	// Get role config for this identity
 	config, parsed, err := d.getRoleConfigForIdentity(identity)
 	if err != nil {
@@ -346,41 +870,91 @@ func (d *Daemon) restartSession(sessionName, identity string) error {
 	if parsed.RigName != "" {
 		if operational, reason := d.isRigOperational(parsed.RigName); !operational {
 			d.logger.Printf("Skipping session restart for %s: %s", identity, reason)
-			return fmt.Errorf("cannot restart session: %s", reason)
+			return classify(ErrStateVerificationFailed, fmt.Errorf("cannot restart session: %s", reason))
+		}
+		if ok, reason := budget.CheckRole(d.config.TownRoot, parsed.RigName, parsed.RoleType); !ok {
+			d.logger.Printf("Skipping session restart for %s: %s", identity, reason)
+			return classify(ErrStateVerificationFailed, fmt.Errorf("cannot restart session: %s", reason))
+		}
+		if outside, reason := d.outsideSchedule(identity, time.Now()); outside {
+			d.logger.Printf("Skipping session restart for %s: %s", identity, reason)
+			return classify(ErrStateVerificationFailed, fmt.Errorf("cannot restart session: %s", reason))
 		}
 	}
 
 	// Determine working directory
 	workDir := d.getWorkDir(config, parsed)
 	if workDir == "" {
-		return fmt.Errorf("cannot determine working directory for %s", identity)
+		return classify(ErrStateVerificationFailed, fmt.Errorf("cannot determine working directory for %s", identity))
 	}
 
+	// Crew workdirs can go missing (deleted by hand, disk cleanup); recreate
+	// them as worktrees rather than starting a session in a void.
+	if parsed.RoleType == "crew" {
+		if err := d.ensureCrewWorkspace(parsed, workDir); err != nil {
+			return err
+		}
+	}
+	if isReadOnlyRole(parsed.RoleType) {
+		if err := d.ensureReadOnlyCheckout(parsed, workDir); err != nil {
+			d.logger.Printf("Refusing to start %s: %v", identity, err)
+			return err
+		}
+	}
+	if err := d.validateWorkDir(parsed, workDir); err != nil {
+		d.logger.Printf("Refusing to start %s: %v", identity, err)
+		return err
+	}
+	d.cleanBeforeRestart(identity, workDir)
+
 	// Determine if pre-sync is needed
 	needsPreSync := d.getNeedsPreSync(config, parsed)
 
 	// Pre-sync workspace for agents with git worktrees
 	if needsPreSync {
 		d.logger.Printf("Pre-syncing workspace for %s at %s", identity, workDir)
-		d.syncWorkspace(workDir)
+		syncSpan := d.startSpan("workspace.sync", "gt.workdir", workDir)
+		err := d.runStep(StepSync, func(ctx context.Context) error { return d.syncWorkspace(ctx, workDir) })
+		d.endSpan(syncSpan, err)
+		if err != nil {
+			d.logger.Printf("Refusing to start %s: %v", identity, err)
+			return err
+		}
 	}
 
-	// Create session
-	// Use EnsureSessionFresh to handle zombie sessions that exist but have dead Claude
-	if err := d.tmux.EnsureSessionFresh(sessionName, workDir); err != nil {
-		return fmt.Errorf("creating session: %w", err)
+	// Create session, or take over a crew warm session (a shell already
+	// created in the rig). Use EnsureSessionFresh to handle zombie sessions
+	// that exist but have dead Claude.
+	if !d.claimWarmSession(identity, sessionName, workDir, parsed) {
+		if err := d.runStep(StepCreate, func(ctx context.Context) error {
+			return d.sessionsFor(ctx).EnsureSessionFresh(sessionName, workDir)
+		}); err != nil {
+			return classify(ErrSessionBackend, fmt.Errorf("creating session: %w", err))
+		}
 	}
 
-	// Set environment variables
-	d.setSessionEnvironment(sessionName, config, parsed)
-
-	// Apply theme (non-fatal: theming failure doesn't affect operation)
-	d.applySessionTheme(sessionName, parsed)
+	// Set environment variables, then apply theme and layout (non-fatal:
+	// neither affects operation)
+	handoff := d.takeHandoff(identity)
+	if err := d.runStep(StepConfigure, func(ctx context.Context) error {
+		sessions := d.sessionsFor(ctx)
+		d.setSessionEnvironment(sessions, sessionName, workDir, config, parsed)
+		if handoff != "" {
+			_ = sessions.SetEnvironment(sessionName, handoffEnvVar, handoff)
+		}
+		d.applySessionTheme(sessions, sessionName, parsed)
+		d.applySessionLayout(sessions, sessionName, workDir, config, parsed)
+		return nil
+	}); err != nil {
+		return classify(ErrSessionBackend, fmt.Errorf("configuring session: %w", err))
+	}
 
 	// Get and send startup command
-	startCmd := d.getStartCommand(config, parsed)
-	if err := d.tmux.SendKeys(sessionName, startCmd); err != nil {
-		return fmt.Errorf("sending startup command: %w", err)
+	startCmd := d.containerStartCommand(identity, sessionName, workDir, parsed, readOnlyStartCommand(parsed, d.getStartCommand(config, parsed)))
+	if err := d.runStep(StepSendKeys, func(ctx context.Context) error {
+		return d.sendStartCommand(d.sessionsFor(ctx), sessionName, startCmd)
+	}); err != nil {
+		return classify(ErrSessionBackend, fmt.Errorf("sending startup command: %w", err))
 	}
 
 	// Wait for Claude to start, then accept bypass permissions warning if it appears.
@@ -389,28 +963,41 @@ func (d *Daemon) restartSession(sessionName, identity string) error {
 		// Non-fatal - Claude might still start
 	}
 	_ = d.tmux.AcceptBypassPermissionsWarning(sessionName)
-	time.Sleep(constants.ShutdownNotifyDelay)
+	d.pause(constants.ShutdownNotifyDelay)
 
 	// GUPP: Gas Town Universal Propulsion Principle
 	// Send startup nudge for predecessor discovery via /resume
 	recipient := identityToBDActor(identity)
-	_ = session.StartupNudge(d.tmux, sessionName, session.StartupNudgeConfig{
-		Recipient: recipient,
-		Sender:    "deacon",
-		Topic:     "lifecycle-restart",
+	_ = d.runStep(StepSendKeys, func(ctx context.Context) error {
+		return d.sessionsFor(ctx).NudgeSession(sessionName, session.FormatStartupNudge(session.StartupNudgeConfig{
+			Recipient: recipient,
+			Sender:    "deacon",
+			Topic:     "lifecycle-restart",
+		}))
 	}) // Non-fatal
 
 	// Send propulsion nudge to trigger autonomous execution.
 	// Wait for beacon to be fully processed (needs to be separate prompt)
-	time.Sleep(2 * time.Second)
-	_ = d.tmux.NudgeSession(sessionName, session.PropulsionNudgeForRole(parsed.RoleType, workDir)) // Non-fatal
+	d.pause(2 * time.Second)
+	_ = d.runStep(StepSendKeys, func(ctx context.Context) error {
+		return d.sessionsFor(ctx).NudgeSession(sessionName, session.PropulsionNudgeForRole(parsed.RoleType, workDir))
+	}) // Non-fatal
+
+	d.recordRunner(identity, config, parsed)
+	d.runAgentHook(identity, sessionName, HookPostStart, ActionRestart)
+	d.publishAgentState(identity, sessionName, "running", "started")
 
 	return nil
 }
 
 // getWorkDir determines the working directory for an agent.
-// Uses role bead config if available, falls back to hardcoded defaults.
+// Uses the daemon.json workdir template, then role bead config, then
+// hardcoded defaults.
 func (d *Daemon) getWorkDir(config *beads.RoleConfig, parsed *ParsedIdentity) string {
+	if workDir := d.workDirTemplate(parsed); workDir != "" {
+		return workDir
+	}
+
 	// If role bead has work_dir_pattern, use it
 	if config != nil && config.WorkDirPattern != "" {
 		return beads.ExpandRolePattern(config.WorkDirPattern, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
@@ -515,117 +1102,232 @@ func (d *Daemon) getStartCommand(roleConfig *beads.RoleConfig, parsed *ParsedIde
 	return defaultCmd
 }
 
-// setSessionEnvironment sets environment variables for the tmux session.
-// Uses centralized AgentEnv for consistency, plus role bead custom env vars if available.
-func (d *Daemon) setSessionEnvironment(sessionName string, roleConfig *beads.RoleConfig, parsed *ParsedIdentity) {
-	// Use centralized AgentEnv for base environment variables
-	envVars := config.AgentEnv(config.AgentEnvConfig{
-		Role:      parsed.RoleType,
-		Rig:       parsed.RigName,
-		AgentName: parsed.AgentName,
-		TownRoot:  d.config.TownRoot,
-	})
-	for k, v := range envVars {
-		_ = d.tmux.SetEnvironment(sessionName, k, v)
+// runnerDescription describes the runner getStartCommand selects for an agent:
+// the agent name (or "role-bead" for a role bead start_command) and the
+// command line without environment, e.g. "claude-haiku: claude --model haiku".
+func (d *Daemon) runnerDescription(roleConfig *beads.RoleConfig, parsed *ParsedIdentity) string {
+	if roleConfig != nil && roleConfig.StartCommand != "" {
+		return "role-bead: " + beads.ExpandRolePattern(roleConfig.StartCommand, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
+	}
+
+	rigPath := ""
+	if parsed.RigName != "" {
+		rigPath = filepath.Join(d.config.TownRoot, parsed.RigName)
+	}
+	agentName, _ := config.ResolveRoleAgentName(parsed.RoleType, d.config.TownRoot, rigPath)
+	runtimeConfig := config.ResolveRoleAgentConfig(parsed.RoleType, d.config.TownRoot, rigPath)
+	return agentName + ": " + runtimeConfig.BuildCommand()
+}
+
+// recordRunner writes the agent's runner to its agent bead for auditing
+// which model and flags each session ran with. Non-fatal.
+func (d *Daemon) recordRunner(identity string, roleConfig *beads.RoleConfig, parsed *ParsedIdentity) {
+	agentBeadID := d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return
+	}
+	runner := d.runnerDescription(roleConfig, parsed)
+	if err := beads.New(d.config.TownRoot).UpdateAgentRunner(agentBeadID, runner); err != nil {
+		d.logger.Printf("Warning: could not record runner for %s: %v", identity, err)
+		return
 	}
+	d.logger.Printf("Recorded runner for %s: %s", identity, runner)
+}
 
-	// Set any custom env vars from role config (bead-defined overrides)
-	if roleConfig != nil {
-		for k, v := range roleConfig.EnvVars {
-			expanded := beads.ExpandRolePattern(v, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
-			_ = d.tmux.SetEnvironment(sessionName, k, expanded)
+// setSessionEnvironment sets the session's environment bundle (see
+// env_bundle.go) and writes it to the workdir's .gt-env for tooling outside
+// tmux. A failed file write is logged; the session still starts.
+func (d *Daemon) setSessionEnvironment(sessions SessionBackend, sessionName, workDir string, roleConfig *beads.RoleConfig, parsed *ParsedIdentity) {
+	env := d.envBundle(sessionName, workDir, roleConfig, parsed)
+	for k, v := range env {
+		_ = sessions.SetEnvironment(sessionName, k, v)
+	}
+	if workDir != "" && d.writesEnvFile(parsed.RoleType) {
+		if err := writeEnvFile(workDir, env); err != nil {
+			d.logger.Printf("Warning: failed to write %s for %s: %v", EnvFileName, sessionName, err)
 		}
 	}
 }
 
 // applySessionTheme applies tmux theming to the session.
-func (d *Daemon) applySessionTheme(sessionName string, parsed *ParsedIdentity) {
+func (d *Daemon) applySessionTheme(sessions SessionBackend, sessionName string, parsed *ParsedIdentity) {
 	if parsed.RoleType == "mayor" {
 		theme := tmux.MayorTheme()
-		_ = d.tmux.ConfigureGasTownSession(sessionName, theme, "", "Mayor", "coordinator")
+		_ = sessions.ConfigureGasTownSession(sessionName, theme, "", "Mayor", "coordinator")
 	} else if parsed.RigName != "" {
 		theme := tmux.AssignTheme(parsed.RigName)
-		_ = d.tmux.ConfigureGasTownSession(sessionName, theme, parsed.RigName, parsed.RoleType, parsed.RoleType)
+		_ = sessions.ConfigureGasTownSession(sessionName, theme, parsed.RigName, parsed.RoleType, parsed.RoleType)
+	}
+}
+
+// sessionLayout builds the tmux layout of a role config, with placeholders
+// in its commands expanded.
+func (d *Daemon) sessionLayout(roleConfig *beads.RoleConfig, parsed *ParsedIdentity) tmux.SessionLayout {
+	if roleConfig == nil {
+		return tmux.SessionLayout{}
+	}
+	expand := func(command string) string {
+		return beads.ExpandRolePattern(command, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
+	}
+	layout := tmux.SessionLayout{Layout: roleConfig.Layout}
+	for _, command := range roleConfig.LayoutPanes {
+		layout.Panes = append(layout.Panes, expand(command))
+	}
+	for _, w := range roleConfig.LayoutWindows {
+		layout.Windows = append(layout.Windows, tmux.LayoutWindow{Name: w.Name, Command: expand(w.Command)})
+	}
+	return layout
+}
+
+// applySessionLayout adds the role's extra panes and windows to a new
+// session. A failed layout is logged; the agent's own pane still works.
+func (d *Daemon) applySessionLayout(sessions SessionBackend, sessionName, workDir string, roleConfig *beads.RoleConfig, parsed *ParsedIdentity) {
+	layout := d.sessionLayout(roleConfig, parsed)
+	if layout.IsEmpty() {
+		return
+	}
+	if err := sessions.ApplyLayout(sessionName, workDir, layout); err != nil {
+		d.logger.Printf("Warning: applying %s layout to %s: %v", parsed.RoleType, sessionName, err)
+	}
+}
+
+// ensureCrewWorkspace recreates a missing crew workdir as a git worktree on
+// the crew member's branch. Only the conventional <rig>/crew/<name> layout is
+// repaired; a custom workdir from role config must be fixed by hand.
+func (d *Daemon) ensureCrewWorkspace(parsed *ParsedIdentity, workDir string) error {
+	if _, err := os.Stat(workDir); err == nil {
+		return nil
+	}
+
+	rigPath := filepath.Join(d.config.TownRoot, parsed.RigName)
+	if workDir != filepath.Join(rigPath, "crew", parsed.AgentName) {
+		return classify(ErrStateVerificationFailed, fmt.Errorf("working directory %s does not exist (only %s/crew/<name> workspaces are recreated automatically)",
+			workDir, rigPath))
 	}
+
+	d.logger.Printf("Crew workspace %s missing, recreating as worktree", workDir)
+	r := &rig.Rig{Name: parsed.RigName, Path: rigPath}
+	mgr := crew.NewManager(r, git.NewGit(rigPath))
+	mgr.SetOutput(d.logger.Writer())
+	if _, _, err := mgr.Repair(parsed.AgentName); err != nil {
+		return fmt.Errorf("repairing crew workspace %s: %w", workDir, err)
+	}
+	if err := d.applyWorkDirSkeleton(parsed, workDir); err != nil {
+		d.logger.Printf("Warning: copying crew skeleton into %s: %v", workDir, err)
+	}
+	return nil
 }
 
 // syncWorkspace syncs a git workspace before starting a new session.
 // This ensures agents with persistent clones (like refinery) start with current code.
-func (d *Daemon) syncWorkspace(workDir string) {
-	// Determine default branch from rig config
-	// workDir is like <townRoot>/<rigName>/<role>/rig or <townRoot>/<rigName>/crew/<name>
-	defaultBranch := "main" // fallback
-	rel, err := filepath.Rel(d.config.TownRoot, workDir)
-	if err == nil {
-		parts := strings.Split(rel, string(filepath.Separator))
-		if len(parts) > 0 {
-			rigPath := filepath.Join(d.config.TownRoot, parts[0])
-			if rigCfg, err := rig.LoadRigConfig(rigPath); err == nil && rigCfg.DefaultBranch != "" {
-				defaultBranch = rigCfg.DefaultBranch
-			}
-		}
+// Failures are recorded in the workspace's problem bead (see
+// workspace_problems.go); the returned error means the workspace was left
+// with unresolved conflicts and the agent must not be started on it. The
+// vcs and bd commands are killed when ctx is done.
+func (d *Daemon) syncWorkspace(ctx context.Context, workDir string) error {
+	spec := d.workspaceVCSSpec(workDir)
+	backend, err := vcs.For(spec.Kind)
+	if err != nil {
+		d.logger.Printf("Error: %v in %s", err, workDir)
+		return nil
 	}
+	backend = vcs.WithContext(ctx, backend)
 
-	// Capture stderr for debuggability
-	var stderr bytes.Buffer
+	var problems []string
+	problem := func(format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		d.syncWarning(workDir, "%s", message)
+		problems = append(problems, message)
+	}
 
-	// Fetch latest from origin
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = workDir
-	fetchCmd.Stderr = &stderr
-	if err := fetchCmd.Run(); err != nil {
-		errMsg := strings.TrimSpace(stderr.String())
-		if errMsg == "" {
-			errMsg = err.Error()
+	// Fetch every configured remote. The remote we update from must fetch,
+	// or the agent would start on stale code; extra remotes are best-effort.
+	for _, remote := range spec.Fetch {
+		if err := backend.Fetch(workDir, remote); err != nil {
+			if remote == spec.Remote {
+				problem("%s fetch failed in %s: %v", spec.Kind, workDir, err)
+				d.recordWorkspaceProblems(workDir, problems, nil, time.Now())
+				return nil // Fail fast - don't start agent with stale code
+			}
+			problem("%s fetch of %s failed in %s: %v", spec.Kind, remote, workDir, err)
 		}
-		d.logger.Printf("Error: git fetch failed in %s: %s", workDir, errMsg)
-		return // Fail fast - don't start agent with stale code
 	}
 
-	// Reset stderr buffer
-	stderr.Reset()
+	// Rebase local work onto the tracked branch, unless that would rewrite
+	// unpushed work on a protected branch. Uncommitted work is auto-stashed.
+	if err := d.prepareWorkspaceUpdate(backend, spec, workDir); err != nil {
+		problem("%v", err)
+	} else if err := backend.Update(workDir, spec.Remote, spec.Branch); err != nil {
+		problem("%s update to %s/%s failed in %s: %v (agent may have conflicts)",
+			spec.Kind, spec.Remote, spec.Branch, workDir, err)
+		// Don't fail yet - only conflicts left behind block the agent
+	}
 
-	// Pull with rebase to incorporate changes
-	pullCmd := exec.Command("git", "pull", "--rebase", "origin", defaultBranch)
-	pullCmd.Dir = workDir
-	pullCmd.Stderr = &stderr
-	if err := pullCmd.Run(); err != nil {
-		errMsg := strings.TrimSpace(stderr.String())
-		if errMsg == "" {
-			errMsg = err.Error()
+	// A conflicted workspace, whether this update or an earlier one left
+	// it so, is broken code to start an agent on.
+	conflicts, err := backend.Conflicted(workDir)
+	if err != nil && !errors.Is(err, vcs.ErrUnsupported) {
+		d.logger.Printf("Warning: cannot check %s for conflicts: %v", workDir, err)
+	}
+
+	// The scheduled coordinator keeps beads current when enabled.
+	// Otherwise sync beads; errors carry bd's stderr for debuggability.
+	if !d.beadsSyncEnabled() {
+		if _, err := d.beadsFor(ctx).Run(workDir, "sync"); err != nil {
+			problem("bd sync failed in %s: %v", workDir, err)
+			// Don't fail - sync issues may be recoverable
 		}
-		d.logger.Printf("Warning: git pull failed in %s: %s (agent may have conflicts)", workDir, errMsg)
-		// Don't fail - agent can handle conflicts
 	}
 
-	// Reset stderr buffer
-	stderr.Reset()
+	d.recordWorkspaceProblems(workDir, problems, conflicts, time.Now())
+	if len(conflicts) > 0 {
+		if _, block := d.workspaceProblemConfig(); block {
+			return classify(ErrStateVerificationFailed, fmt.Errorf("workspace %s has unresolved conflicts in %s",
+				workDir, strings.Join(conflicts, ", ")))
+		}
+		d.syncWarning(workDir, "starting agent in %s despite unresolved conflicts in %s", workDir, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
 
-	// Sync beads
-	bdCmd := exec.Command("bd", "sync")
-	bdCmd.Dir = workDir
-	bdCmd.Stderr = &stderr
-	if err := bdCmd.Run(); err != nil {
-		errMsg := strings.TrimSpace(stderr.String())
-		if errMsg == "" {
-			errMsg = err.Error()
+// workspaceVCSSpec resolves what syncWorkspace fetches and updates to from
+// the rig's config.json. workDir is like <townRoot>/<rigName>/<role>/rig or
+// <townRoot>/<rigName>/crew/<name>.
+func (d *Daemon) workspaceVCSSpec(workDir string) vcs.Spec {
+	var cfg *vcs.Config
+	rigBranch := ""
+	if rel, err := filepath.Rel(d.config.TownRoot, workDir); err == nil {
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) > 0 {
+			rigPath := filepath.Join(d.config.TownRoot, parts[0])
+			if rigCfg, err := rig.LoadRigConfig(rigPath); err == nil {
+				rigBranch = rigCfg.DefaultBranch
+				if err := rigCfg.VCS.Validate(); err != nil {
+					d.logger.Printf("Warning: ignoring vcs config for rig %s: %v", parts[0], err)
+				} else {
+					cfg = rigCfg.VCS
+				}
+			}
 		}
-		d.logger.Printf("Warning: bd sync failed in %s: %s", workDir, errMsg)
-		// Don't fail - sync issues may be recoverable
 	}
+	return cfg.Resolve(vcs.Detect(workDir), rigBranch)
 }
 
 // closeMessage removes a lifecycle mail message after processing.
 // We use delete instead of read because gt mail read intentionally
 // doesn't mark messages as read (to preserve handoff messages).
-func (d *Daemon) closeMessage(id string) error {
+// The deletion is audited under the message's sender with the given reason.
+func (d *Daemon) closeMessage(id, from, reason string) error {
 	// Use gt mail delete to actually remove the message
-	cmd := exec.Command("gt", "mail", "delete", id)
-	cmd.Dir = d.config.TownRoot
-
-	output, err := cmd.CombinedOutput()
+	err := d.mailClient().Delete(id)
+	d.audit(AuditMailDelete, id, from, []string{"sender parsed as " + from, reason}, err)
+	data := map[string]any{"message_id": id, "reason": reason}
 	if err != nil {
-		return fmt.Errorf("gt mail delete %s: %v (output: %s)", id, err, string(output))
+		data["error"] = err.Error()
+	}
+	d.publish(eventstream.TypeMailProcessed, from, data)
+	if err != nil {
+		return err
 	}
 	d.logger.Printf("Deleted lifecycle message: %s", id)
 	return nil
@@ -641,6 +1343,8 @@ type AgentBeadInfo struct {
 	RoleType   string // Parsed from description: role_type
 	Rig        string // Parsed from description: rig
 	LastUpdate string `json:"updated_at"`
+
+	ContextUsage string // Parsed from description: context_usage
 }
 
 // getAgentBeadState reads non-observable agent state from an agent bead.
@@ -657,10 +1361,7 @@ func (d *Daemon) getAgentBeadState(agentBeadID string) (string, error) {
 
 // getAgentBeadInfo fetches and parses an agent bead by ID.
 func (d *Daemon) getAgentBeadInfo(agentBeadID string) (*AgentBeadInfo, error) {
-	cmd := exec.Command("bd", "show", agentBeadID, "--json")
-	cmd.Dir = d.config.TownRoot
-
-	output, err := cmd.Output()
+	output, err := d.beadsClient().Run(d.config.TownRoot, "show", agentBeadID, "--json")
 	if err != nil {
 		return nil, fmt.Errorf("bd show %s: %w", agentBeadID, err)
 	}
@@ -702,6 +1403,7 @@ func (d *Daemon) getAgentBeadInfo(agentBeadID string) (*AgentBeadInfo, error) {
 		info.RoleBead = fields.RoleBead
 		info.RoleType = fields.RoleType
 		info.Rig = fields.Rig
+		info.ContextUsage = fields.ContextUsage
 	}
 
 	// Use HookBead from database column directly (not from description)
@@ -795,10 +1497,7 @@ func (d *Daemon) checkGUPPViolations() {
 func (d *Daemon) checkRigGUPPViolations(rigName string) {
 	// List polecat agent beads for this rig
 	// Pattern: <prefix>-<rig>-polecat-<name> (e.g., gt-gastown-polecat-Toast)
-	cmd := exec.Command("bd", "list", "--type=agent", "--json")
-	cmd.Dir = d.config.TownRoot
-
-	output, err := cmd.Output()
+	output, err := d.beadsClient().Run(d.config.TownRoot, "list", "--type=agent", "--json")
 	if err != nil {
 		d.logger.Printf("Warning: bd list failed for GUPP check: %v", err)
 		return
@@ -836,10 +1535,11 @@ func (d *Daemon) checkRigGUPPViolations(rigName string) {
 		// Per gt-zecmc: derive running state from tmux, not agent_state
 		// Extract polecat name from agent ID (<prefix>-<rig>-polecat-<name> -> <name>)
 		polecatName := strings.TrimPrefix(agent.ID, prefix)
-		sessionName := fmt.Sprintf("gt-%s-%s", rigName, polecatName)
+		parsed := &ParsedIdentity{RoleType: "polecat", RigName: rigName, AgentName: polecatName}
+		sessionName := d.defaultSessionName(parsed)
 
 		// Check if tmux session exists and Claude is running
-		if d.tmux.IsClaudeRunning(sessionName) {
+		if d.agentRunning(rigName+"-polecat-"+polecatName, sessionName, parsed) {
 			// Session is alive - check if it's been stuck too long
 			updatedAt, err := time.Parse(time.RFC3339, agent.UpdatedAt)
 			if err != nil {
@@ -870,10 +1570,11 @@ stuck_duration: %v
 Action needed: Check if agent is alive and responsive. Consider restarting if stuck.`,
 		agentID, hookBead, stuckDuration.Round(time.Minute))
 
-	cmd := exec.Command("gt", "mail", "send", witnessAddr, "-s", subject, "-m", body)
-	cmd.Dir = d.config.TownRoot
-
-	if err := cmd.Run(); err != nil {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would notify %s of GUPP violation for %s", witnessAddr, agentID)
+		return
+	}
+	if err := d.mailClient().Send(witnessAddr, subject, body); err != nil {
 		d.logger.Printf("Warning: failed to notify witness of GUPP violation: %v", err)
 	} else {
 		d.logger.Printf("Notified %s of GUPP violation for %s", witnessAddr, agentID)
@@ -893,10 +1594,7 @@ func (d *Daemon) checkOrphanedWork() {
 
 // checkRigOrphanedWork checks polecats in a specific rig for orphaned work.
 func (d *Daemon) checkRigOrphanedWork(rigName string) {
-	cmd := exec.Command("bd", "list", "--type=agent", "--json")
-	cmd.Dir = d.config.TownRoot
-
-	output, err := cmd.Output()
+	output, err := d.beadsClient().Run(d.config.TownRoot, "list", "--type=agent", "--json")
 	if err != nil {
 		d.logger.Printf("Warning: bd list failed for orphaned work check: %v", err)
 		return
@@ -928,10 +1626,11 @@ func (d *Daemon) checkRigOrphanedWork(rigName string) {
 
 		// Check if tmux session is alive (derive state from tmux, not bead)
 		polecatName := strings.TrimPrefix(agent.ID, prefix)
-		sessionName := fmt.Sprintf("gt-%s-%s", rigName, polecatName)
+		parsed := &ParsedIdentity{RoleType: "polecat", RigName: rigName, AgentName: polecatName}
+		sessionName := d.defaultSessionName(parsed)
 
 		// Session running = not orphaned (work is being processed)
-		if d.tmux.IsClaudeRunning(sessionName) {
+		if d.agentRunning(rigName+"-polecat-"+polecatName, sessionName, parsed) {
 			continue
 		}
 
@@ -966,10 +1665,11 @@ hook_bead: %s
 Action needed: Either restart the agent or reassign the work.`,
 		agentID, hookBead)
 
-	cmd := exec.Command("gt", "mail", "send", witnessAddr, "-s", subject, "-m", body)
-	cmd.Dir = d.config.TownRoot
-
-	if err := cmd.Run(); err != nil {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would notify %s of orphaned work for %s", witnessAddr, agentID)
+		return
+	}
+	if err := d.mailClient().Send(witnessAddr, subject, body); err != nil {
 		d.logger.Printf("Warning: failed to notify witness of orphaned work: %v", err)
 	} else {
 		d.logger.Printf("Notified %s of orphaned work for %s", witnessAddr, agentID)
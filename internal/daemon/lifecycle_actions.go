@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registered lifecycle actions. Beyond the built-in cycle, restart,
+// shutdown, and refresh, subsystems and plugins can add actions such as
+// "compact" or "run-diagnostics" with RegisterLifecycleAction. A registered
+// action is accepted wherever a built-in one is (LIFECYCLE mail, the API,
+// batches) and runs after the same verification: identity resolution,
+// agent bead check, session check, policy hook for running sessions, and
+// dry runs, which only report that the handler would run.
+
+// LifecycleActionHandler performs a registered lifecycle action.
+type LifecycleActionHandler func(ctx *LifecycleActionContext) error
+
+// LifecycleActionContext is what a registered handler gets to work with.
+type LifecycleActionContext struct {
+	// Request is the request being executed; Request.From is the target.
+	Request *LifecycleRequest
+
+	// Session is the target's tmux session and Running whether it exists.
+	Session string
+	Running bool
+
+	// Logger is the daemon log.
+	Logger *log.Logger
+
+	d        *Daemon
+	verified []string
+}
+
+// WorkDir returns the target's working directory.
+func (c *LifecycleActionContext) WorkDir() string {
+	return c.d.agentWorkDir(c.Request.From)
+}
+
+// Nudge types text into the running session and submits it.
+func (c *LifecycleActionContext) Nudge(text string) error {
+	if !c.Running {
+		return classify(ErrStateVerificationFailed, fmt.Errorf("session %s not running", c.Session))
+	}
+	if err := c.d.tmux.NudgeSession(c.Session, text); err != nil {
+		return classify(ErrSessionBackend, fmt.Errorf("nudging session: %w", err))
+	}
+	return nil
+}
+
+// Shutdown kills the session, as the shutdown action does.
+func (c *LifecycleActionContext) Shutdown() error {
+	if !c.Running {
+		return nil
+	}
+	if err := c.d.killLifecycleSession(c.Request, c.Session, c.verified); err != nil {
+		return err
+	}
+	c.Running = false
+	return nil
+}
+
+// Restart kills the session if it is running and starts it again, as the
+// restart action does.
+func (c *LifecycleActionContext) Restart() error {
+	if err := c.d.restartLifecycleSession(c.Request, c.Session, c.Running, c.verified); err != nil {
+		return err
+	}
+	c.Running = true
+	return nil
+}
+
+// builtinLifecycleActions cannot be replaced by a registered handler.
+var builtinLifecycleActions = []LifecycleAction{ActionCycle, ActionRestart, ActionShutdown, ActionRefresh, ActionBatch, "stop"}
+
+var (
+	lifecycleActionsMu sync.RWMutex
+	lifecycleActions   = map[LifecycleAction]LifecycleActionHandler{}
+)
+
+// RegisterLifecycleAction adds (or replaces) a named lifecycle action.
+// Names are case-insensitive. It panics if name is empty or a built-in
+// action, since that is a programming error.
+func RegisterLifecycleAction(name string, handler LifecycleActionHandler) {
+	action := LifecycleAction(strings.ToLower(strings.TrimSpace(name)))
+	if action == "" || handler == nil {
+		panic("daemon: RegisterLifecycleAction needs a name and a handler")
+	}
+	for _, builtin := range builtinLifecycleActions {
+		if action == builtin {
+			panic(fmt.Sprintf("daemon: lifecycle action %q is built in", action))
+		}
+	}
+	lifecycleActionsMu.Lock()
+	defer lifecycleActionsMu.Unlock()
+	lifecycleActions[action] = handler
+}
+
+// LifecycleActionNames returns the names of the registered lifecycle
+// actions, sorted. Built-in actions are not included.
+func LifecycleActionNames() []string {
+	lifecycleActionsMu.RLock()
+	defer lifecycleActionsMu.RUnlock()
+	names := make([]string, 0, len(lifecycleActions))
+	for action := range lifecycleActions {
+		names = append(names, string(action))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupLifecycleAction returns the handler registered for action, or nil.
+func lookupLifecycleAction(action LifecycleAction) LifecycleActionHandler {
+	lifecycleActionsMu.RLock()
+	defer lifecycleActionsMu.RUnlock()
+	return lifecycleActions[action]
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func unregisterLifecycleAction(name string) {
+	lifecycleActionsMu.Lock()
+	defer lifecycleActionsMu.Unlock()
+	delete(lifecycleActions, LifecycleAction(name))
+}
+
+func TestRegisteredLifecycleAction(t *testing.T) {
+	var got *LifecycleActionContext
+	RegisterLifecycleAction("Run-Diagnostics", func(ctx *LifecycleActionContext) error {
+		got = ctx
+		return ctx.Nudge("run diagnostics")
+	})
+	defer unregisterLifecycleAction("run-diagnostics")
+
+	if !slices.Contains(LifecycleActionNames(), "run-diagnostics") {
+		t.Errorf("LifecycleActionNames() = %v, want run-diagnostics", LifecycleActionNames())
+	}
+	action, ok := parseLifecycleAction("run-diagnostics")
+	if !ok || action != "run-diagnostics" {
+		t.Fatalf("parseLifecycleAction = %q, %v", action, ok)
+	}
+
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	fake := &drainTmux{exitAfter: -1}
+	d.tmux = fake
+
+	if err := d.executeLifecycleAction(&LifecycleRequest{From: "ai-crew-max", Action: action, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("executeLifecycleAction: %v", err)
+	}
+	if got == nil || got.Request.From != "ai-crew-max" || !got.Running || got.Session == "" {
+		t.Fatalf("handler context = %+v", got)
+	}
+	if len(fake.nudges) != 1 || fake.nudges[0] != "run diagnostics" {
+		t.Errorf("nudges = %q, want the handler's nudge", fake.nudges)
+	}
+
+	// Dry runs only report the handler would run.
+	got = nil
+	if err := d.executeLifecycleAction(&LifecycleRequest{From: "ai-crew-max", Action: action, DryRun: true}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got != nil {
+		t.Error("handler ran during a dry run")
+	}
+}
+
+func TestRegisterLifecycleActionRejectsBuiltins(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a built-in action should panic")
+		}
+	}()
+	RegisterLifecycleAction("cycle", func(*LifecycleActionContext) error { return nil })
+}
+
+func TestUnknownLifecycleAction(t *testing.T) {
+	if _, ok := parseLifecycleAction("compact"); ok {
+		t.Error("unregistered action parsed")
+	}
+}
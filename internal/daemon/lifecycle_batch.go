@@ -0,0 +1,217 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/notifier"
+)
+
+// LifecycleBatchItem is one target/action pair in a batch lifecycle request.
+type LifecycleBatchItem struct {
+	Target string `json:"target"`
+	Action string `json:"action"`
+
+	// Reason and TaskBead override the batch's for this target.
+	Reason   string `json:"reason,omitempty"`
+	TaskBead string `json:"task_bead,omitempty"`
+}
+
+// Batch result statuses.
+const (
+	BatchStatusOK       = "ok"
+	BatchStatusFailed   = "failed"
+	BatchStatusSkipped  = "skipped"  // an earlier item failed
+	BatchStatusRejected = "rejected" // batch failed validation, nothing ran
+)
+
+// LifecycleBatchResult is the outcome for one target of a batch request.
+// The daemon replies to the sender with {"request_id": ..., "results": [...]}
+// in the mail body.
+type LifecycleBatchResult struct {
+	Target string          `json:"target"`
+	Action LifecycleAction `json:"action"`
+	Status string          `json:"status"`
+	Error  string          `json:"error,omitempty"`
+
+	// ErrorCode classifies Error (see ErrorCode), empty if unclassified.
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// parseLifecycleBatch builds an ActionBatch request from a body with an
+// "actions" list. Any unknown action rejects the whole batch, so a typo can't
+// leave a coordinated operation half-applied.
+func (d *Daemon) parseLifecycleBatch(msg *BeadsMessage, body *LifecycleBody) *LifecycleRequest {
+	request := &LifecycleRequest{
+		RequestID: msg.ID,
+		From:      msg.From,
+		Action:    ActionBatch,
+		Timestamp: time.Now(),
+		DryRun:    body.DryRun,
+		Notify:    d.parseNotifyList(msg.From, body.Notify),
+		Token:     body.Token,
+		Reason:    body.Reason,
+		TaskBead:  body.TaskBead,
+		Urgency:   d.parseUrgency(msg.From, body.Urgency),
+	}
+	for i, item := range body.Actions {
+		action, ok := parseLifecycleAction(item.Action)
+		if !ok {
+			d.logger.Printf("Batch lifecycle request from %s: unknown action %q at index %d", msg.From, item.Action, i)
+			return nil
+		}
+		if item.Target == "" {
+			d.logger.Printf("Batch lifecycle request from %s: missing target at index %d", msg.From, i)
+			return nil
+		}
+		reason, taskBead := item.Reason, item.TaskBead
+		if reason == "" {
+			reason = body.Reason
+		}
+		if taskBead == "" {
+			taskBead = body.TaskBead
+		}
+		request.Batch = append(request.Batch, LifecycleRequest{
+			RequestID:   msg.ID,
+			From:        item.Target,
+			Action:      action,
+			Timestamp:   request.Timestamp,
+			DryRun:      body.DryRun,
+			Reason:      reason,
+			TaskBead:    taskBead,
+			Urgency:     request.Urgency,
+			RequestedBy: msg.From,
+		})
+	}
+	return request
+}
+
+// canSendLifecycleBatch reports whether sender may act on other agents.
+// Only town-level coordinators (mayor, deacon) can; other agents may only
+// request actions for themselves.
+func canSendLifecycleBatch(sender string) bool {
+	parsed, err := parseIdentity(strings.TrimSuffix(sender, "/"))
+	if err != nil {
+		return false
+	}
+	return parsed.RoleType == "mayor" || parsed.RoleType == "deacon"
+}
+
+// validateLifecycleBatch checks the sender and every target before anything
+// runs. Returns per-target rejections, or nil if the batch may execute.
+func (d *Daemon) validateLifecycleBatch(request *LifecycleRequest) []LifecycleBatchResult {
+	var reason string
+	if !canSendLifecycleBatch(request.From) {
+		reason = fmt.Sprintf("sender %s may not request batch lifecycle actions", request.From)
+	}
+
+	results := make([]LifecycleBatchResult, len(request.Batch))
+	rejected := reason != ""
+	for i, item := range request.Batch {
+		results[i] = LifecycleBatchResult{Target: item.From, Action: item.Action, Status: BatchStatusRejected, Error: reason}
+		if reason == "" && d.identityToSession(item.From) == "" {
+			results[i].Error = fmt.Sprintf("%v: %s", ErrUnknownIdentity, item.From)
+			results[i].ErrorCode = ErrorCodeUnknownIdentity
+			rejected = true
+		}
+	}
+	if !rejected {
+		return nil
+	}
+	return results
+}
+
+// executeLifecycleBatch runs a batch request's actions in order and replies to
+// the sender with per-target results. The batch is validated up front; at run
+// time the first failure stops the batch and the remaining targets are
+// reported as skipped.
+func (d *Daemon) executeLifecycleBatch(request *LifecycleRequest) []LifecycleBatchResult {
+	results := d.validateLifecycleBatch(request)
+	if results != nil {
+		d.logger.Printf("Rejected batch lifecycle request from %s", request.From)
+		d.replyLifecycleBatch(request, results)
+		return results
+	}
+
+	d.logger.Printf("Executing batch lifecycle request from %s (%d actions)", request.From, len(request.Batch))
+	failed := false
+	for i := range request.Batch {
+		item := &request.Batch[i]
+		result := LifecycleBatchResult{Target: item.From, Action: item.Action, Status: BatchStatusOK}
+		if failed {
+			result.Status = BatchStatusSkipped
+			results = append(results, result)
+			continue
+		}
+
+		// A batch is all-or-nothing, so its restarts are staggered but never
+		// deferred by the throttle.
+		if item.Action.restartsSession() && !item.DryRun && !d.config.DryRun {
+			d.restarts.wait()
+			d.restarts.record(item.From)
+		}
+		if err := d.executeLifecycleAction(item); err != nil {
+			d.logger.Printf("Batch: %s %s failed: %v", item.Action, item.From, err)
+			result.Status = BatchStatusFailed
+			result.Error = err.Error()
+			result.ErrorCode = ErrorCode(err)
+			failed = true
+			if item.Action.restartsSession() {
+				d.notify(notifier.EventRestartFailed, item.withContext(map[string]string{
+					"agent":  item.From,
+					"action": string(item.Action),
+					"error":  err.Error(),
+				}))
+			}
+		}
+		results = append(results, result)
+	}
+
+	d.replyLifecycleBatch(request, results)
+	return results
+}
+
+// replyLifecycleBatch mails the per-target results back to the batch sender
+// and to any identities on the request's notify list.
+func (d *Daemon) replyLifecycleBatch(request *LifecycleRequest, results []LifecycleBatchResult) {
+	subject, body, err := formatLifecycleBatchReply(request.RequestID, results)
+	if err != nil {
+		d.logger.Printf("Warning: failed to encode batch results: %v", err)
+		return
+	}
+	if request.DryRun || d.config.DryRun {
+		subject = "[dry-run] " + subject
+	}
+
+	if err := d.mailClient().Send(request.From, subject, body); err != nil {
+		d.logger.Printf("Warning: failed to send batch results to %s: %v", request.From, err)
+	}
+	d.sendLifecycleNotify(request.Notify, subject, body)
+}
+
+// formatLifecycleBatchReply builds the reply subject and JSON body.
+func formatLifecycleBatchReply(requestID string, results []LifecycleBatchResult) (string, string, error) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	var subject string
+	switch {
+	case counts[BatchStatusRejected] > 0:
+		subject = fmt.Sprintf("LIFECYCLE_RESULT: batch rejected (%d actions)", len(results))
+	case counts[BatchStatusFailed] > 0:
+		subject = fmt.Sprintf("LIFECYCLE_RESULT: batch failed (%d ok, %d failed, %d skipped)",
+			counts[BatchStatusOK], counts[BatchStatusFailed], counts[BatchStatusSkipped])
+	default:
+		subject = fmt.Sprintf("LIFECYCLE_RESULT: batch ok (%d actions)", len(results))
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{"request_id": requestID, "results": results}, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	return subject, string(data), nil
+}
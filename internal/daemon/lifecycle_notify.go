@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxLifecycleNotify caps the notify list of one request, so a malformed
+// body can't turn the daemon into a mail cannon.
+const maxLifecycleNotify = 10
+
+// LifecycleResult is the body of the LIFECYCLE_RESULT mail sent back to the
+// requesting agent, and to the identities in the request's "notify" list,
+// once a single-action lifecycle request has been handled. Status and Code
+// let the requester decide without parsing Detail:
+//
+//	{"request_id": "hq-abc", "status": "rejected", "code": "state_verification_failed",
+//	 "detail": "cycle rejected: handoff document missing", ...}
+type LifecycleResult struct {
+	// RequestID is the ID of the LIFECYCLE mail this answers.
+	RequestID string          `json:"request_id"`
+	Target    string          `json:"target"`
+	Action    LifecycleAction `json:"action"`
+
+	// Status is "ok", "rejected" (refused before anything changed: stale,
+	// rate limited, unknown identity, failed state verification), or
+	// "failed" (the action ran and failed).
+	Status string `json:"status"`
+
+	// Code classifies a rejection or failure (see ErrorCode); unclassified
+	// failures are ErrorCodeInternal. Empty when Status is "ok".
+	Code string `json:"code,omitempty"`
+
+	// Detail is the human-readable reason for a rejection or failure.
+	Detail string `json:"detail,omitempty"`
+
+	RequestedBy string `json:"requested_by"`
+
+	// Reason, TaskBead, and Urgency echo the request's.
+	Reason   string `json:"reason,omitempty"`
+	TaskBead string `json:"task_bead,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+
+	DryRun      bool      `json:"dry_run,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+
+	// Traceparent identifies the request's trace (W3C format) when the
+	// daemon traces lifecycle operations.
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// resultStatus returns the LifecycleResult status for an action's error.
+func resultStatus(err error) string {
+	switch ErrorCode(err) {
+	case "":
+		if err == nil {
+			return BatchStatusOK
+		}
+		return BatchStatusFailed
+	case ErrorCodeSessionBackend, ErrorCodeSmokeTestFailed:
+		return BatchStatusFailed
+	default:
+		return BatchStatusRejected
+	}
+}
+
+// ParseLifecycleResult decodes the body of a LIFECYCLE_RESULT mail for a
+// single-action request.
+func ParseLifecycleResult(body string) (*LifecycleResult, error) {
+	var result LifecycleResult
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, fmt.Errorf("parsing lifecycle result: %w", err)
+	}
+	if result.Status == "" {
+		return nil, fmt.Errorf("parsing lifecycle result: missing status")
+	}
+	return &result, nil
+}
+
+// parseNotifyList cleans a request's notify list: blanks and duplicates are
+// dropped and the list is capped at maxLifecycleNotify.
+func (d *Daemon) parseNotifyList(from string, notify []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, identity := range notify {
+		identity = strings.TrimSpace(identity)
+		if identity == "" || seen[identity] {
+			continue
+		}
+		if len(out) == maxLifecycleNotify {
+			d.logger.Printf("Lifecycle request from %s: notify list truncated to %d identities", from, maxLifecycleNotify)
+			break
+		}
+		seen[identity] = true
+		out = append(out, identity)
+	}
+	return out
+}
+
+// identityToMailAddress converts a daemon identity ("mayor", "gastown-witness")
+// to the mail address its inbox is under. Values that are already addresses
+// (contain a slash) are returned as-is.
+func identityToMailAddress(identity string) string {
+	if strings.Contains(identity, "/") {
+		return identity
+	}
+	parsed, err := parseIdentity(identity)
+	if err != nil {
+		return identity
+	}
+	switch parsed.RoleType {
+	case "mayor", "deacon":
+		return parsed.RoleType + "/"
+	case "polecat":
+		return parsed.RigName + "/" + parsed.AgentName
+	default:
+		return identityToBDActor(identity)
+	}
+}
+
+// replyLifecycleResult mails the result of a single lifecycle action to
+// the requesting agent and every identity in request.Notify.
+func (d *Daemon) replyLifecycleResult(request *LifecycleRequest, requestedBy string, actionErr error) {
+	result := LifecycleResult{
+		RequestID:   request.RequestID,
+		Target:      request.From,
+		Action:      request.Action,
+		Status:      resultStatus(actionErr),
+		RequestedBy: requestedBy,
+		Reason:      request.Reason,
+		TaskBead:    request.TaskBead,
+		Urgency:     request.Urgency,
+		DryRun:      request.DryRun || d.config.DryRun,
+		CompletedAt: time.Now().UTC(),
+		Traceparent: d.span.Traceparent(),
+	}
+	if actionErr != nil {
+		result.Code = ErrorCode(actionErr)
+		if result.Code == "" {
+			result.Code = ErrorCodeInternal
+		}
+		result.Detail = actionErr.Error()
+	}
+
+	subject := fmt.Sprintf("LIFECYCLE_RESULT: %s %s %s", result.Action, result.Target, result.Status)
+	if result.DryRun {
+		subject = "[dry-run] " + subject
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		d.logger.Printf("Warning: failed to encode lifecycle result: %v", err)
+		return
+	}
+	d.sendLifecycleNotify(resultRecipients(request), subject, string(data))
+}
+
+// resultRecipients returns the requester followed by the notify list,
+// without repeating the requester.
+func resultRecipients(request *LifecycleRequest) []string {
+	requester := identityToMailAddress(request.From)
+	recipients := []string{request.From}
+	for _, identity := range request.Notify {
+		if identityToMailAddress(identity) != requester {
+			recipients = append(recipients, identity)
+		}
+	}
+	return recipients
+}
+
+// sendLifecycleNotify mails subject and body to each notify identity.
+// Delivery failures are logged and don't affect the other recipients.
+func (d *Daemon) sendLifecycleNotify(notify []string, subject, body string) {
+	for _, identity := range notify {
+		address := identityToMailAddress(identity)
+		if err := d.mailClient().Send(address, subject, body); err != nil {
+			d.logger.Printf("Warning: failed to send lifecycle result to %s: %v", address, err)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package daemon
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
 )
 
 // testDaemon creates a minimal Daemon for testing.
@@ -88,6 +95,8 @@ func TestParseLifecycleRequest_RestartAndShutdown(t *testing.T) {
 		{"LIFECYCLE: action", `{"action": "shutdown"}`, ActionShutdown},
 		{"lifecycle: action", "stop", ActionShutdown},
 		{"LIFECYCLE: action", "restart", ActionRestart},
+		{"LIFECYCLE: action", `{"action": "refresh"}`, ActionRefresh},
+		{"lifecycle: action", "refresh", ActionRefresh},
 	}
 
 	for _, tc := range tests {
@@ -252,3 +261,562 @@ func TestBeadsMessage_Serialization(t *testing.T) {
 		t.Errorf("From mismatch")
 	}
 }
+
+func TestParseLifecycleRequest_DryRun(t *testing.T) {
+	d := testDaemon()
+
+	tests := []struct {
+		body   string
+		dryRun bool
+	}{
+		{`{"action": "cycle", "dry_run": true}`, true},
+		{`{"action": "shutdown", "dry_run": false}`, false},
+		{`{"action": "restart"}`, false},
+		{"cycle", false},
+	}
+
+	for _, tc := range tests {
+		msg := &BeadsMessage{
+			Subject: "LIFECYCLE: action",
+			Body:    tc.body,
+			From:    "gastown-witness",
+		}
+		result := d.parseLifecycleRequest(msg)
+		if result == nil {
+			t.Errorf("parseLifecycleRequest(body=%q) returned nil", tc.body)
+			continue
+		}
+		if result.DryRun != tc.dryRun {
+			t.Errorf("parseLifecycleRequest(body=%q) dry_run = %v, expected %v", tc.body, result.DryRun, tc.dryRun)
+		}
+	}
+}
+
+func TestParseLifecycleRequest_MailTemplate(t *testing.T) {
+	d := testDaemon()
+	tmpl, err := mail.LookupTemplate("lifecycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := tmpl.Render("gastown-witness", map[string]string{
+		"action":       "cycle",
+		"dry-run":      "true",
+		"handoff-file": "notes/handoff.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := d.parseLifecycleRequest(&BeadsMessage{Subject: rendered.Subject, Body: rendered.Body, From: "gastown-witness"})
+	if result == nil {
+		t.Fatalf("template message not parsed as a lifecycle request:\n%s\n%s", rendered.Subject, rendered.Body)
+	}
+	if result.Action != ActionCycle || !result.DryRun || result.Handoff != "notes/handoff.md" {
+		t.Errorf("parsed = %+v", result)
+	}
+}
+
+func TestLifecyclePlan(t *testing.T) {
+	tests := []struct {
+		action  LifecycleAction
+		running bool
+		steps   int
+	}{
+		{ActionShutdown, true, 1},
+		{ActionShutdown, false, 1},
+		{ActionCycle, true, 2},
+		{ActionCycle, false, 1},
+		{ActionRestart, true, 2},
+		{ActionRefresh, true, 1},
+		{ActionRefresh, false, 1},
+	}
+
+	for _, tc := range tests {
+		steps := lifecyclePlan(tc.action, "gt-gastown-witness", tc.running)
+		if len(steps) != tc.steps {
+			t.Errorf("lifecyclePlan(%s, running=%v) = %v, expected %d steps", tc.action, tc.running, steps, tc.steps)
+		}
+	}
+}
+
+func TestRecordMailPoll(t *testing.T) {
+	d := testDaemon()
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < mailPollFailureThreshold; i++ {
+		d.recordMailPollFailure(errors.New("unknown flag: --identity"), now)
+	}
+	if d.mailPoll.ConsecutiveFailures != mailPollFailureThreshold {
+		t.Errorf("ConsecutiveFailures = %d, expected %d", d.mailPoll.ConsecutiveFailures, mailPollFailureThreshold)
+	}
+	if d.mailPoll.LastError == "" {
+		t.Error("expected LastError to be set")
+	}
+
+	messages := []BeadsMessage{
+		{ID: "a", Timestamp: "2026-01-02T11:00:00Z"},
+		{ID: "b", Timestamp: "2026-01-02T10:00:00Z", Read: true},
+		{ID: "c", Timestamp: "2026-01-02T11:30:00Z"},
+		{ID: "d", Timestamp: "garbage"},
+	}
+	d.recordMailPollSuccess(messages, now)
+	if d.mailPoll.ConsecutiveFailures != 0 || d.mailPoll.LastError != "" {
+		t.Errorf("expected failures reset, got %+v", d.mailPoll)
+	}
+	if !d.mailPoll.LastSuccessAt.Equal(now) {
+		t.Errorf("LastSuccessAt = %v, expected %v", d.mailPoll.LastSuccessAt, now)
+	}
+	if want := time.Date(2026, 1, 2, 11, 0, 0, 0, time.UTC); !d.mailPoll.OldestUnreadAt.Equal(want) {
+		t.Errorf("OldestUnreadAt = %v, expected %v", d.mailPoll.OldestUnreadAt, want)
+	}
+}
+
+func TestAdaptiveMailPollInterval(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{
+		MailPoll: &MailPollConfig{MinInterval: "10s", MaxInterval: "60s"},
+	}
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	if got := d.mailPollInterval(); got != 10*time.Second {
+		t.Errorf("initial interval = %v, expected min 10s", got)
+	}
+
+	// Idle polls back off by doubling up to the max.
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second} {
+		d.recordMailPollSuccess(nil, now)
+		if d.mailPoll.Interval != want {
+			t.Errorf("idle interval = %v, expected %v", d.mailPoll.Interval, want)
+		}
+	}
+
+	// Unread mail drops straight back to the minimum.
+	d.recordMailPollSuccess([]BeadsMessage{{ID: "a", Timestamp: "2026-01-02T11:59:00Z"}}, now)
+	if d.mailPoll.Interval != 10*time.Second || d.mailPoll.LastUnread != 1 || !d.mailPoll.LastActiveAt.Equal(now) {
+		t.Errorf("after mail: %+v, expected 10s interval, 1 unread, active now", d.mailPoll)
+	}
+
+	// Failures back off too.
+	d.recordMailPollFailure(errors.New("boom"), now)
+	if d.mailPoll.Interval != 20*time.Second {
+		t.Errorf("after failure interval = %v, expected 20s", d.mailPoll.Interval)
+	}
+}
+
+func TestLifecycleMessageMaxAge(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{
+		Lifecycle: &LifecycleConfig{
+			MaxAge: map[string]string{
+				"shutdown": "24h",
+				"cycle":    "30m",
+				"restart":  "bogus",
+			},
+			SenderMaxAge: map[string]string{
+				"mayor":     "1h",
+				"*-witness": "5m",
+			},
+		},
+	}
+
+	tests := []struct {
+		from     string
+		action   LifecycleAction
+		expected time.Duration
+	}{
+		{"gastown-crew-max", ActionShutdown, 24 * time.Hour},
+		{"gastown-crew-max", ActionCycle, 30 * time.Minute},
+		{"gastown-crew-max", ActionRestart, MaxLifecycleMessageAge}, // invalid override ignored
+		{"mayor", ActionShutdown, time.Hour},
+		{"gastown-witness", ActionShutdown, 5 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		got := d.lifecycleMessageMaxAge(&LifecycleRequest{From: tc.from, Action: tc.action})
+		if got != tc.expected {
+			t.Errorf("lifecycleMessageMaxAge(%s, %s) = %v, expected %v", tc.from, tc.action, got, tc.expected)
+		}
+	}
+
+	// No config falls back to the default
+	if got := testDaemon().lifecycleMessageMaxAge(&LifecycleRequest{From: "mayor", Action: ActionCycle}); got != MaxLifecycleMessageAge {
+		t.Errorf("default max age = %v, expected %v", got, MaxLifecycleMessageAge)
+	}
+}
+
+func TestParseLifecycleRequest_Batch(t *testing.T) {
+	d := testDaemon()
+
+	msg := &BeadsMessage{
+		Subject: "LIFECYCLE: coordinated restart",
+		Body:    `{"actions":[{"target":"gastown-crew-max","action":"cycle"},{"target":"gastown-witness","action":"stop"}]}`,
+		From:    "mayor/",
+	}
+	result := d.parseLifecycleRequest(msg)
+	if result == nil {
+		t.Fatal("parseLifecycleRequest(batch) returned nil")
+	}
+	if result.Action != ActionBatch || result.From != "mayor/" {
+		t.Errorf("batch request = %s from %q, expected batch from mayor/", result.Action, result.From)
+	}
+	if len(result.Batch) != 2 {
+		t.Fatalf("len(Batch) = %d, expected 2", len(result.Batch))
+	}
+	if result.Batch[0].From != "gastown-crew-max" || result.Batch[0].Action != ActionCycle {
+		t.Errorf("Batch[0] = %+v, expected cycle of gastown-crew-max", result.Batch[0])
+	}
+	if result.Batch[1].From != "gastown-witness" || result.Batch[1].Action != ActionShutdown {
+		t.Errorf("Batch[1] = %+v, expected shutdown of gastown-witness", result.Batch[1])
+	}
+
+	// One bad action rejects the whole batch
+	msg.Body = `{"actions":[{"target":"gastown-crew-max","action":"cycle"},{"target":"gastown-witness","action":"explode"}]}`
+	if result := d.parseLifecycleRequest(msg); result != nil {
+		t.Errorf("parseLifecycleRequest(bad batch) = %+v, expected nil", result)
+	}
+}
+
+func TestParseLifecycleRequest_Notify(t *testing.T) {
+	d := testDaemon()
+
+	msg := &BeadsMessage{
+		Subject: "LIFECYCLE: cycle please",
+		Body:    `{"action":"cycle","notify":["mayor"," gastown-witness ","mayor",""]}`,
+		From:    "gastown/crew/max",
+	}
+	result := d.parseLifecycleRequest(msg)
+	if result == nil {
+		t.Fatal("parseLifecycleRequest returned nil")
+	}
+	if !slices.Equal(result.Notify, []string{"mayor", "gastown-witness"}) {
+		t.Errorf("Notify = %q, expected [mayor gastown-witness]", result.Notify)
+	}
+
+	msg.Body = `{"actions":[{"target":"gastown-crew-max","action":"cycle"}],"notify":["deacon"]}`
+	if result := d.parseLifecycleRequest(msg); result == nil || !slices.Equal(result.Notify, []string{"deacon"}) {
+		t.Errorf("batch Notify = %+v, expected [deacon]", result)
+	}
+}
+
+func TestParseLifecycleRequest_Context(t *testing.T) {
+	d := testDaemon()
+
+	msg := &BeadsMessage{
+		Subject: "LIFECYCLE: cycle please",
+		Body:    `{"action":"cycle","reason":"context full","task_bead":"gt-abc","urgency":"HIGH"}`,
+		From:    "gastown/crew/max",
+	}
+	result := d.parseLifecycleRequest(msg)
+	if result == nil {
+		t.Fatal("parseLifecycleRequest returned nil")
+	}
+	if result.Reason != "context full" || result.TaskBead != "gt-abc" || result.Urgency != UrgencyHigh {
+		t.Errorf("request = %+v, expected reason, task bead, and high urgency", result)
+	}
+
+	// An unknown urgency is dropped, not fatal
+	msg.Body = `{"action":"cycle","urgency":"asap"}`
+	if result := d.parseLifecycleRequest(msg); result == nil || result.Urgency != "" {
+		t.Errorf("request with unknown urgency = %+v, expected it accepted without urgency", result)
+	}
+
+	// Batch items inherit the batch's context unless they override it
+	msg.From = "mayor/"
+	msg.Body = `{"actions":[{"target":"gastown-crew-max","action":"cycle","task_bead":"gt-1"},{"target":"gastown-witness","action":"cycle"}],` +
+		`"reason":"deploy","task_bead":"gt-2","urgency":"low"}`
+	result = d.parseLifecycleRequest(msg)
+	if result == nil || len(result.Batch) != 2 {
+		t.Fatalf("batch request = %+v", result)
+	}
+	if item := result.Batch[0]; item.TaskBead != "gt-1" || item.Reason != "deploy" || item.Urgency != UrgencyLow {
+		t.Errorf("Batch[0] = %+v, expected its own task bead", item)
+	}
+	if item := result.Batch[1]; item.TaskBead != "gt-2" || item.Urgency != UrgencyLow {
+		t.Errorf("Batch[1] = %+v, expected the batch's task bead", item)
+	}
+
+	body, ok := parseKeyValueBody("action: cycle\ntask-bead: gt-abc\nurgency: normal")
+	if !ok || body.TaskBead != "gt-abc" || body.Urgency != UrgencyNormal {
+		t.Errorf("kv body = %+v, %v; expected task bead and urgency", body, ok)
+	}
+}
+
+func TestIdentityToMailAddress(t *testing.T) {
+	tests := map[string]string{
+		"mayor":               "mayor/",
+		"deacon":              "deacon/",
+		"gastown-witness":     "gastown/witness",
+		"gastown-crew-max":    "gastown/crew/max",
+		"gastown-polecat-nux": "gastown/nux",
+		"gastown/refinery":    "gastown/refinery",
+	}
+	for identity, want := range tests {
+		if got := identityToMailAddress(identity); got != want {
+			t.Errorf("identityToMailAddress(%q) = %q, want %q", identity, got, want)
+		}
+	}
+}
+
+func TestReplyLifecycleResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+		wantCode   string
+	}{
+		{"ok", nil, BatchStatusOK, ""},
+		{"stale", fmt.Errorf("%w: expired", ErrStaleRequest), BatchStatusRejected, ErrorCodeStaleRequest},
+		{"rate limited", fmt.Errorf("%w: too many", ErrRateLimited), BatchStatusRejected, ErrorCodeRateLimited},
+		{"verification", classify(ErrStateVerificationFailed, errors.New("rig parked")), BatchStatusRejected, ErrorCodeStateVerificationFailed},
+		{"backend", classify(ErrSessionBackend, errors.New("no server")), BatchStatusFailed, ErrorCodeSessionBackend},
+		{"unclassified", errors.New("boom"), BatchStatusFailed, ErrorCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDaemon()
+			sent := &sentMail{}
+			d.mail = sent
+			d.replyLifecycleResult(&LifecycleRequest{
+				RequestID: "hq-req",
+				From:      "gastown-witness",
+				Action:    ActionCycle,
+				Notify:    []string{"gastown/witness", "mayor"},
+				Reason:    "context full",
+				TaskBead:  "gt-abc",
+			}, "gastown-witness", tt.err)
+
+			// The requester is mailed once even when also on the notify list
+			if len(sent.sent) != 2 || !strings.HasPrefix(sent.sent[0], "gastown/witness|LIFECYCLE_RESULT: cycle gastown-witness "+tt.wantStatus) ||
+				!strings.HasPrefix(sent.sent[1], "mayor/|") {
+				t.Fatalf("sent = %v", sent.sent)
+			}
+			result, err := ParseLifecycleResult(strings.SplitN(sent.sent[0], "|", 3)[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RequestID != "hq-req" || result.Status != tt.wantStatus || result.Code != tt.wantCode {
+				t.Errorf("result = %+v, want status %q code %q", result, tt.wantStatus, tt.wantCode)
+			}
+			if result.Reason != "context full" || result.TaskBead != "gt-abc" {
+				t.Errorf("result = %+v, want the request's reason and task bead", result)
+			}
+			if (tt.err == nil) != (result.Detail == "") {
+				t.Errorf("detail = %q for err %v", result.Detail, tt.err)
+			}
+		})
+	}
+
+	if _, err := ParseLifecycleResult(`{"results": []}`); err == nil {
+		t.Error("a batch reply should not parse as a single result")
+	}
+}
+
+func TestValidateLifecycleBatch(t *testing.T) {
+	d := testDaemon()
+	batch := []LifecycleRequest{
+		{From: "gastown-crew-max", Action: ActionCycle},
+		{From: "gastown-witness", Action: ActionRestart},
+	}
+
+	if results := d.validateLifecycleBatch(&LifecycleRequest{From: "mayor/", Action: ActionBatch, Batch: batch}); results != nil {
+		t.Errorf("mayor batch rejected: %+v", results)
+	}
+
+	results := d.validateLifecycleBatch(&LifecycleRequest{From: "gastown-crew-max", Action: ActionBatch, Batch: batch})
+	if len(results) != 2 || results[0].Status != BatchStatusRejected || results[1].Error == "" {
+		t.Errorf("crew batch = %+v, expected all rejected", results)
+	}
+
+	subject, _, err := formatLifecycleBatchReply("hq-batch", results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(subject, "rejected") {
+		t.Errorf("reply subject = %q, expected rejected", subject)
+	}
+}
+
+func TestRunAgentHookScript(t *testing.T) {
+	workDir := t.TempDir()
+	hooksDir := AgentHooksDir(workDir)
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeHook := func(name, body string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(hooksDir, name), []byte("#!/bin/sh\n"+body+"\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runAgentHookScript(workDir, HookPreCycle, nil, time.Second); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing hook: err = %v, expected ErrNotExist", err)
+	}
+
+	writeHook(HookPostStart, `echo "started $GT_AGENT in $(pwd)"`, 0755)
+	out, err := runAgentHookScript(workDir, HookPostStart, []string{"GT_AGENT=gastown-crew-max"}, time.Second)
+	if err != nil {
+		t.Fatalf("post-start hook: %v", err)
+	}
+	if !strings.Contains(out, "started gastown-crew-max") || !strings.Contains(out, workDir) {
+		t.Errorf("post-start output = %q, expected agent and workdir", out)
+	}
+
+	writeHook(HookPreCycle, "echo flushing; sleep 5", 0755)
+	out, err = runAgentHookScript(workDir, HookPreCycle, nil, 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow hook: err = %v, expected timeout", err)
+	}
+	if out != "flushing" {
+		t.Errorf("slow hook output = %q, expected output captured before the kill", out)
+	}
+
+	writeHook(HookPreShutdown, "exit 0", 0644)
+	if _, err := runAgentHookScript(workDir, HookPreShutdown, nil, time.Second); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("non-executable hook: err = %v, expected refusal", err)
+	}
+}
+
+func TestRestartThrottle(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	th := &restartThrottle{
+		maxPerPass:  2,
+		stagger:     10 * time.Second,
+		minInterval: 5 * time.Minute,
+		lastRestart: make(map[string]time.Time),
+		sleep:       func(d time.Duration) { slept = append(slept, d) },
+		now:         func() time.Time { return now },
+	}
+
+	for _, id := range []string{"gastown-crew-max", "gastown-crew-joe"} {
+		if ok, reason := th.admit(id, time.Time{}); !ok {
+			t.Fatalf("admit(%s) refused: %s", id, reason)
+		}
+		th.wait()
+		th.record(id)
+	}
+	if len(slept) != 1 || slept[0] != 10*time.Second {
+		t.Errorf("slept %v, want one 10s stagger before the second restart", slept)
+	}
+	if ok, _ := th.admit("gastown-crew-tom", time.Time{}); ok {
+		t.Error("third restart admitted past the per-heartbeat cap")
+	}
+
+	th.beginPass()
+	if ok, _ := th.admit("gastown-crew-max", time.Time{}); ok {
+		t.Error("restart admitted inside the agent's minimum interval")
+	}
+	if ok, _ := th.admit("gastown-crew-tom", now.Add(-time.Minute)); ok {
+		t.Error("restart admitted despite a recent persisted kill")
+	}
+	now = now.Add(6 * time.Minute)
+	if ok, reason := th.admit("gastown-crew-max", time.Time{}); !ok {
+		t.Errorf("restart refused after the minimum interval: %s", reason)
+	}
+
+	var nilThrottle *restartThrottle
+	if ok, _ := nilThrottle.admit("gastown-crew-max", now); !ok {
+		t.Error("nil throttle refused a restart")
+	}
+}
+
+// lingeringTmux reports a killed session for a number of polls before it
+// disappears.
+type lingeringTmux struct {
+	SessionBackend
+	polls int
+}
+
+func (l *lingeringTmux) HasSession(string) (bool, error) {
+	l.polls--
+	return l.polls >= 0, nil
+}
+
+func TestAwaitTeardown(t *testing.T) {
+	var slept []time.Duration
+	d := testDaemon()
+	d.sleep = func(delay time.Duration) { slept = append(slept, delay) }
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{
+		RestartDelay:    map[string]string{"crew": "2s", "*": "0s"},
+		TeardownTimeout: "300ms",
+	}}
+	total := func() time.Duration {
+		var sum time.Duration
+		for _, delay := range slept {
+			sum += delay
+		}
+		slept = nil
+		return sum
+	}
+
+	// Two polls of teardown count toward the crew's 2s delay
+	d.tmux = &lingeringTmux{polls: 2}
+	d.awaitTeardown("gastown-crew-max", "gt-gastown-crew-max")
+	if got := total(); got != 2*time.Second {
+		t.Errorf("crew waited %v, want 2s from the kill", got)
+	}
+
+	// No delay for other roles once the session is gone
+	d.tmux = &lingeringTmux{polls: 1}
+	d.awaitTeardown("gastown-witness", "gt-gastown-witness")
+	if got := total(); got != teardownPollInterval {
+		t.Errorf("witness waited %v, want one poll", got)
+	}
+
+	// A session that never goes away is given up on at the timeout
+	d.tmux = &lingeringTmux{polls: 100}
+	d.awaitTeardown("gastown-witness", "gt-gastown-witness")
+	if got := total(); got != 300*time.Millisecond {
+		t.Errorf("waited %v for a stuck session, want the 300ms timeout", got)
+	}
+
+	d.patrolConfig = nil
+	if got := d.restartDelay("mayor"); got != defaultRestartDelay {
+		t.Errorf("default restart delay = %v", got)
+	}
+}
+
+// filteredMail is a mail client that filters the inbox itself.
+type filteredMail struct {
+	sentMail
+	inboxCalls int
+	identity   string
+	prefixes   []string
+}
+
+func (m *filteredMail) Inbox(string) ([]BeadsMessage, error) {
+	m.inboxCalls++
+	return nil, nil
+}
+
+func (m *filteredMail) UnreadInbox(identity string, subjectPrefixes ...string) ([]BeadsMessage, error) {
+	m.identity = identity
+	m.prefixes = subjectPrefixes
+	return nil, nil
+}
+
+func TestProcessLifecycleRequestsFiltersInbox(t *testing.T) {
+	d := testDaemon()
+	client := &filteredMail{}
+	d.mail = client
+
+	d.ProcessLifecycleRequests()
+	if client.inboxCalls != 0 {
+		t.Errorf("fetched the whole inbox %d times, want only the filtered one", client.inboxCalls)
+	}
+	if client.identity != "deacon/" || !slices.Equal(client.prefixes, deaconMailSubjects) {
+		t.Errorf("UnreadInbox(%q, %v), want deacon/ with %v", client.identity, client.prefixes, deaconMailSubjects)
+	}
+}
+
+func TestGtMailInboxArgs(t *testing.T) {
+	got := strings.Join(gtMailInboxArgs("deacon/", true, []string{"lifecycle:", "escalate:"}), " ")
+	want := "mail inbox --identity deacon/ --json --unread --subject lifecycle: --subject escalate:"
+	if got != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+	if got := strings.Join(gtMailInboxArgs("mayor/", false, nil), " "); got != "mail inbox --identity mayor/ --json" {
+		t.Errorf("unfiltered args = %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Duplicate mail collapsing. Agents sometimes send the same lifecycle mail
+// twice (a retried gt command, a hook firing twice). Deacon messages with
+// the same sender, subject, and body sent within the dedup window of each
+// other are collapsed into the first: the later copies are deleted unread
+// and counted in the mail poll stats. A request that failed is forgotten,
+// so the sender can retry it at once.
+
+// defaultMailDedupWindow is how close together identical messages must be
+// sent to be collapsed.
+const defaultMailDedupWindow = 2 * time.Minute
+
+// mailDeduper remembers recently seen deacon messages by content hash.
+type mailDeduper struct {
+	seen map[string]dedupEntry // by content hash
+}
+
+type dedupEntry struct {
+	messageID string
+	sentAt    time.Time // message timestamp, or when it was first seen
+	seenAt    time.Time
+}
+
+// mailDigest hashes the parts of a message that make two sends identical.
+func mailDigest(msg *BeadsMessage) string {
+	h := sha256.New()
+	for _, part := range []string{msg.From, msg.Subject, msg.Body} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// mailDedupWindow returns the configured dedup window; 0 turns dedup off.
+func (d *Daemon) mailDedupWindow() time.Duration {
+	return d.lifecycleDuration("dedup_window", d.patrolConfig.lifecycleConfig().DedupWindow, defaultMailDedupWindow)
+}
+
+// duplicateOf returns the ID of an earlier message msg duplicates, or ""
+// if it is the first of its kind in the window. The first message is
+// remembered, so it is recognized again on later polls.
+func (m *mailDeduper) duplicateOf(msg *BeadsMessage, window time.Duration, now time.Time) string {
+	for digest, entry := range m.seen {
+		if now.Sub(entry.seenAt) >= window {
+			delete(m.seen, digest)
+		}
+	}
+
+	sentAt, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		sentAt = now
+	}
+	digest := mailDigest(msg)
+	if entry, ok := m.seen[digest]; ok && entry.messageID != msg.ID {
+		gap := sentAt.Sub(entry.sentAt)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap <= window {
+			return entry.messageID
+		}
+	}
+	m.seen[digest] = dedupEntry{messageID: msg.ID, sentAt: sentAt, seenAt: now}
+	return ""
+}
+
+// collapseDuplicateMail deletes msg if it duplicates a message seen within
+// the dedup window. Returns true if it did, in which case the caller must
+// skip msg.
+func (d *Daemon) collapseDuplicateMail(msg *BeadsMessage, now time.Time) bool {
+	window := d.mailDedupWindow()
+	if window == 0 {
+		return false
+	}
+	if d.mailDedup == nil {
+		d.mailDedup = &mailDeduper{seen: make(map[string]dedupEntry)}
+	}
+	original := d.mailDedup.duplicateOf(msg, window, now)
+	if original == "" {
+		return false
+	}
+
+	d.mailPoll.Duplicates++
+	d.healthState().mailPolled(d.mailPoll)
+	d.logger.Printf("Collapsing duplicate message %s from %s (%q) into %s", msg.ID, msg.From, msg.Subject, original)
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would delete duplicate message %s", msg.ID)
+		return true
+	}
+	if err := d.closeMessage(msg.ID, msg.From, "duplicate of "+original); err != nil {
+		d.logger.Printf("Warning: failed to delete duplicate message %s: %v", msg.ID, err)
+	}
+	return true
+}
+
+// forgetDuplicates stops treating copies of msg as duplicates, for a
+// request that failed and may be sent again.
+func (d *Daemon) forgetDuplicates(msg *BeadsMessage) {
+	if d.mailDedup != nil {
+		delete(d.mailDedup.seen, mailDigest(msg))
+	}
+}
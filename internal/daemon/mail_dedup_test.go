@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollapseDuplicateMail(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	sent := &sentMail{}
+	d.mail = sent
+	now := time.Now()
+	message := func(id, subject string, at time.Time) *BeadsMessage {
+		return &BeadsMessage{ID: id, From: "gastown-crew-max", Subject: subject, Body: "reason: context full", Timestamp: at.Format(time.RFC3339)}
+	}
+
+	if d.collapseDuplicateMail(message("hq-1", "LIFECYCLE: cycle", now), now) {
+		t.Fatal("first message collapsed")
+	}
+	// The same message on a later poll is not its own duplicate.
+	if d.collapseDuplicateMail(message("hq-1", "LIFECYCLE: cycle", now), now.Add(time.Second)) {
+		t.Fatal("first message collapsed when seen again")
+	}
+	if !d.collapseDuplicateMail(message("hq-2", "LIFECYCLE: cycle", now.Add(10*time.Second)), now.Add(10*time.Second)) {
+		t.Fatal("identical message sent 10s later not collapsed")
+	}
+	if d.collapseDuplicateMail(message("hq-3", "LIFECYCLE: restart", now.Add(10*time.Second)), now.Add(10*time.Second)) {
+		t.Error("message with a different subject collapsed")
+	}
+	if !slices.Equal(sent.deleted, []string{"hq-2"}) {
+		t.Errorf("deleted = %v, want [hq-2]", sent.deleted)
+	}
+	if d.mailPoll.Duplicates != 1 {
+		t.Errorf("Duplicates = %d, want 1", d.mailPoll.Duplicates)
+	}
+
+	// A retry of a request that failed is not a duplicate.
+	d.forgetDuplicates(message("hq-1", "LIFECYCLE: cycle", now))
+	if d.collapseDuplicateMail(message("hq-5", "LIFECYCLE: cycle", now.Add(20*time.Second)), now.Add(20*time.Second)) {
+		t.Error("retry of a failed request collapsed")
+	}
+
+	// Outside the window the same content is a new request.
+	later := now.Add(defaultMailDedupWindow + time.Minute)
+	if d.collapseDuplicateMail(message("hq-4", "LIFECYCLE: cycle", later), later) {
+		t.Error("identical message sent after the window collapsed")
+	}
+}
+
+func TestCollapseDuplicateMailDisabled(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	d.mail = &sentMail{}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{DedupWindow: "0s"}}
+	now := time.Now()
+	msg := &BeadsMessage{ID: "hq-1", From: "gastown-crew-max", Subject: "LIFECYCLE: cycle"}
+	d.collapseDuplicateMail(msg, now)
+	msg.ID = "hq-2"
+	if d.collapseDuplicateMail(msg, now) {
+		t.Error("duplicate collapsed with dedup_window 0s")
+	}
+}
@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/notifier"
+)
+
+// mailPollFailureThreshold is how many consecutive inbox poll failures are
+// tolerated before the daemon raises an alert.
+const mailPollFailureThreshold = 3
+
+// Adaptive poll bounds. The daemon polls the deacon inbox at the minimum
+// interval while mail is arriving and backs off by doubling toward the
+// maximum while it is idle. The heartbeat polls too, so the maximum never
+// needs to exceed the heartbeat interval.
+const (
+	defaultMailPollMinInterval = 15 * time.Second
+	defaultMailPollMaxInterval = recoveryHeartbeatInterval
+)
+
+// MailPollConfig bounds the adaptive deacon inbox poll interval.
+// Set min_interval equal to max_interval for a fixed cadence.
+type MailPollConfig struct {
+	// MinInterval is the delay between polls while mail is arriving
+	// (Go duration string, default "15s").
+	MinInterval string `json:"min_interval,omitempty"`
+
+	// MaxInterval is the longest delay reached while the inbox is idle
+	// (Go duration string, default "3m").
+	MaxInterval string `json:"max_interval,omitempty"`
+}
+
+// mailPollBounds returns the configured min and max poll intervals.
+func (d *Daemon) mailPollBounds() (time.Duration, time.Duration) {
+	minInterval, maxInterval := defaultMailPollMinInterval, defaultMailPollMaxInterval
+	if d.patrolConfig != nil && d.patrolConfig.MailPoll != nil {
+		cfg := d.patrolConfig.MailPoll
+		minInterval = d.mailPollDuration("min_interval", cfg.MinInterval, minInterval)
+		maxInterval = d.mailPollDuration("max_interval", cfg.MaxInterval, maxInterval)
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return minInterval, maxInterval
+}
+
+func (d *Daemon) mailPollDuration(key, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		d.logger.Printf("Warning: invalid mail_poll.%s %q, using %v", key, value, def)
+		return def
+	}
+	return parsed
+}
+
+// mailPollInterval returns the delay until the next inbox poll. Before the
+// first poll it is the minimum, so a fresh daemon picks up queued mail fast.
+func (d *Daemon) mailPollInterval() time.Duration {
+	if d.mailPoll.Interval > 0 {
+		return d.mailPoll.Interval
+	}
+	minInterval, _ := d.mailPollBounds()
+	return minInterval
+}
+
+// nextMailPollInterval drops to minInterval when a poll found mail and
+// otherwise doubles current, clamped to [minInterval, maxInterval].
+func nextMailPollInterval(current, minInterval, maxInterval time.Duration, busy bool) time.Duration {
+	if busy || current <= 0 {
+		return minInterval
+	}
+	next := current * 2
+	if next > maxInterval {
+		next = maxInterval
+	}
+	if next < minInterval {
+		next = minInterval
+	}
+	return next
+}
+
+// adaptMailPollInterval updates the poll interval after a poll.
+func (d *Daemon) adaptMailPollInterval(busy bool) {
+	minInterval, maxInterval := d.mailPollBounds()
+	d.mailPoll.Interval = nextMailPollInterval(d.mailPoll.Interval, minInterval, maxInterval, busy)
+}
+
+// recordMailPollSuccess notes a successful inbox poll and the oldest unread
+// message it returned.
+func (d *Daemon) recordMailPollSuccess(messages []BeadsMessage, now time.Time) {
+	if d.mailPoll.ConsecutiveFailures >= mailPollFailureThreshold {
+		d.logger.Printf("Deacon inbox polling recovered after %d failures", d.mailPoll.ConsecutiveFailures)
+	}
+
+	d.mailPoll.LastPollAt = now
+	d.mailPoll.LastSuccessAt = now
+	d.mailPoll.ConsecutiveFailures = 0
+	d.mailPoll.LastError = ""
+	d.mailPoll.OldestUnreadAt = oldestUnread(messages)
+	d.mailPoll.LastUnread = countUnread(messages)
+	if d.mailPoll.LastUnread > 0 {
+		d.mailPoll.LastActiveAt = now
+	}
+	d.adaptMailPollInterval(d.mailPoll.LastUnread > 0)
+	d.healthState().mailPolled(d.mailPoll)
+}
+
+// recordMailPollFailure notes a failed inbox poll, alerting once when the
+// failure streak reaches mailPollFailureThreshold.
+func (d *Daemon) recordMailPollFailure(err error, now time.Time) {
+	d.mailPoll.LastPollAt = now
+	d.mailPoll.ConsecutiveFailures++
+	d.mailPoll.LastError = err.Error()
+	d.adaptMailPollInterval(false)
+	d.healthState().mailPolled(d.mailPoll)
+
+	if d.mailPoll.ConsecutiveFailures != mailPollFailureThreshold {
+		return
+	}
+
+	since := "never"
+	if !d.mailPoll.LastSuccessAt.IsZero() {
+		since = d.mailPoll.LastSuccessAt.Format(time.RFC3339)
+	}
+	d.logger.Printf("MAIL POLL FAILING: %d consecutive deacon inbox polls failed (last success: %s): %v",
+		d.mailPoll.ConsecutiveFailures, since, err)
+	d.notify(notifier.EventMailPollFailing, map[string]string{
+		"failures":     strconv.Itoa(d.mailPoll.ConsecutiveFailures),
+		"last_success": since,
+		"error":        err.Error(),
+	})
+}
+
+func countUnread(messages []BeadsMessage) int {
+	n := 0
+	for _, msg := range messages {
+		if !msg.Read {
+			n++
+		}
+	}
+	return n
+}
+
+// oldestUnread returns the timestamp of the oldest unread message, or the
+// zero time if there are none with a parseable timestamp.
+func oldestUnread(messages []BeadsMessage) time.Time {
+	var oldest time.Time
+	for _, msg := range messages {
+		if msg.Read {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, msg.Timestamp)
+		if err != nil {
+			continue
+		}
+		if oldest.IsZero() || ts.Before(oldest) {
+			oldest = ts
+		}
+	}
+	return oldest
+}
+
+// subscribeMailTransport wakes the main loop whenever the town's mail
+// transport pushes a message to the deacon inbox, so lifecycle requests are
+// handled as they are sent instead of on the next poll. The adaptive poll
+// keeps running as the fallback. A no-op without a transport or when
+// already subscribed.
+func (d *Daemon) subscribeMailTransport() {
+	if d.mailTransport != nil {
+		return
+	}
+	transport, err := mail.TransportForTown(d.config.TownRoot, os.Getenv)
+	if err != nil {
+		d.logger.Printf("Warning: mail transport unavailable, polling only: %v", err)
+		return
+	}
+	if transport == nil {
+		return
+	}
+	d.mailTransport = transport
+	d.mailWake = make(chan struct{}, 1)
+	d.logger.Println("Subscribed to deacon mail on the mail transport")
+
+	go func() {
+		retry, _ := d.mailPollBounds()
+		for d.ctx.Err() == nil {
+			err := transport.Subscribe(d.ctx, []string{"deacon"}, func(*mail.Message) {
+				select {
+				case d.mailWake <- struct{}{}:
+				default: // a wake is already pending
+				}
+			})
+			if err != nil {
+				d.logger.Printf("Warning: mail transport subscription dropped, resubscribing in %v: %v", retry, err)
+			}
+			select {
+			case <-d.ctx.Done():
+			case <-time.After(retry):
+			}
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
+)
+
+// defaultMailRetentionInterval is how often retention rules are applied.
+const defaultMailRetentionInterval = 24 * time.Hour
+
+// MailArchiveDir returns the town's mail archive directory.
+func MailArchiveDir(townRoot string) string {
+	return filepath.Join(townRoot, mail.ArchiveDirName)
+}
+
+// mailRetentionConfig returns the retention config, or nil if it is unset,
+// has no rules, or is invalid.
+func (c *DaemonPatrolConfig) mailRetentionConfig() *mail.RetentionConfig {
+	if c == nil || c.MailRetention == nil || len(c.MailRetention.Rules) == 0 || c.MailRetention.Validate() != nil {
+		return nil
+	}
+	return c.MailRetention
+}
+
+// mailRetentionStore returns the store retention runs against: the town
+// beads database unless a test replaced it.
+func (d *Daemon) mailRetentionStore() mail.RetentionStore {
+	if d.retentionStore != nil {
+		return d.retentionStore
+	}
+	return mail.NewBeadsRetentionStore(d.config.TownRoot, filepath.Join(d.config.TownRoot, ".beads"))
+}
+
+// applyMailRetentionIfDue applies the mail retention rules once per
+// configured interval, archiving or deleting old messages.
+func (d *Daemon) applyMailRetentionIfDue(state *State, now time.Time) {
+	cfg := d.patrolConfig.mailRetentionConfig()
+	if cfg == nil {
+		return
+	}
+	interval := defaultMailRetentionInterval
+	if cfg.Interval != "" {
+		interval, _ = time.ParseDuration(cfg.Interval) // checked by Validate
+	}
+	if !state.LastMailRetention.IsZero() && now.Sub(state.LastMailRetention) < interval {
+		return
+	}
+	state.LastMailRetention = now
+
+	report, err := d.applyMailRetention(cfg, now, d.config.DryRun)
+	if err != nil {
+		d.logger.Printf("Warning: mail retention failed: %v", err)
+		return
+	}
+	if report.Archived+report.Deleted == 0 {
+		return
+	}
+	prefix := ""
+	if d.config.DryRun {
+		prefix = "[dry-run] would have "
+	}
+	d.logger.Printf("Mail retention: %sarchived %d, deleted %d of %d messages", prefix, report.Archived, report.Deleted, report.Scanned)
+}
+
+func (d *Daemon) applyMailRetention(cfg *mail.RetentionConfig, now time.Time, dryRun bool) (*mail.RetentionReport, error) {
+	span := d.startSpan("mail.retention")
+	report, err := mail.ApplyRetention(d.mailRetentionStore(), cfg, MailArchiveDir(d.config.TownRoot), now, dryRun)
+	d.endSpan(span, err)
+	return report, err
+}
+
+// ApplyMailRetention applies the town's mail retention rules immediately.
+// With dryRun, it only reports what would be archived or deleted.
+func (c *SessionController) ApplyMailRetention(dryRun bool) (*mail.RetentionReport, error) {
+	if c.d.patrolConfig == nil || c.d.patrolConfig.MailRetention == nil || len(c.d.patrolConfig.MailRetention.Rules) == 0 {
+		return nil, fmt.Errorf("no mail_retention rules in %s", PatrolConfigFile(c.d.config.TownRoot))
+	}
+	if err := c.d.patrolConfig.MailRetention.Validate(); err != nil {
+		return nil, err
+	}
+	return c.d.applyMailRetention(c.d.patrolConfig.MailRetention, time.Now(), dryRun)
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
+)
+
+// retentionStore is an in-memory mail.RetentionStore.
+type retentionStore struct {
+	messages []*mail.Message
+	deleted  []string
+}
+
+func (s *retentionStore) ListAll() ([]*mail.Message, error) { return s.messages, nil }
+
+func (s *retentionStore) DeleteMessages(ids []string) error {
+	s.deleted = append(s.deleted, ids...)
+	return nil
+}
+
+func TestApplyMailRetentionIfDue(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	now := time.Now()
+	store := &retentionStore{messages: []*mail.Message{
+		{ID: "hq-old", Read: true, Timestamp: now.Add(-60 * 24 * time.Hour)},
+		{ID: "hq-new", Read: true, Timestamp: now.Add(-time.Hour)},
+	}}
+	d.retentionStore = store
+	d.patrolConfig = &DaemonPatrolConfig{MailRetention: &mail.RetentionConfig{
+		Rules: []mail.RetentionRule{{OlderThan: "30d"}},
+	}}
+	state := &State{}
+
+	d.applyMailRetentionIfDue(state, now)
+	if len(store.deleted) != 1 || store.deleted[0] != "hq-old" {
+		t.Fatalf("deleted = %v, want [hq-old]", store.deleted)
+	}
+	if !state.LastMailRetention.Equal(now) {
+		t.Errorf("LastMailRetention = %v, want %v", state.LastMailRetention, now)
+	}
+	found, err := mail.SearchArchive(MailArchiveDir(d.config.TownRoot), mail.ArchiveQuery{})
+	if err != nil || len(found) != 1 {
+		t.Errorf("archive = %v, %v; want hq-old", found, err)
+	}
+
+	// Not due again until the interval passes
+	store.deleted = nil
+	d.applyMailRetentionIfDue(state, now.Add(time.Hour))
+	if len(store.deleted) != 0 {
+		t.Errorf("ran again before the interval: %v", store.deleted)
+	}
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+
+	"github.com/steveyegge/gastown/internal/refinery"
+	"github.com/steveyegge/gastown/internal/rig"
+)
+
+// defaultMergeQueueMaxPerPass caps the MRs merged per rig per heartbeat.
+const defaultMergeQueueMaxPerPass = 5
+
+// MergeQueueConfig has the daemon work rig merge queues, under
+// "merge_queue" in mayor/daemon.json:
+//
+//	"merge_queue": {"enabled": true, "rigs": ["gastown"], "max_per_pass": 3}
+//
+// Each heartbeat the daemon merges the ready MRs of each rig one at a time
+// (see refinery.QueueRunner), running the workspace sync it does before
+// session starts between merges, and closes or reopens the MR beads. For
+// rigs whose merges need no agent judgement; the merge lock keeps it from
+// racing 'gt refinery queue process' run by a refinery agent.
+type MergeQueueConfig struct {
+	// Enabled turns on the daemon's merge pass.
+	Enabled bool `json:"enabled"`
+
+	// Rigs limits the pass to these rigs (default: every rig).
+	Rigs []string `json:"rigs,omitempty"`
+
+	// MaxPerPass caps the MRs merged per rig per heartbeat (default 5).
+	MaxPerPass int `json:"max_per_pass,omitempty"`
+}
+
+// processMergeQueues starts a merge pass over the configured rigs in the
+// background, so long test runs don't hold up the heartbeat. A pass still
+// running from an earlier heartbeat is left to finish.
+func (d *Daemon) processMergeQueues() {
+	cfg := d.patrolConfig
+	if cfg == nil || cfg.MergeQueue == nil || !cfg.MergeQueue.Enabled {
+		return
+	}
+	if d.config.DryRun {
+		d.logger.Println("[dry-run] Would process merge queues")
+		return
+	}
+	if !d.mergeQueueBusy.CompareAndSwap(false, true) {
+		return
+	}
+	rigs := d.getKnownRigs()
+	if len(cfg.MergeQueue.Rigs) > 0 {
+		rigs = slices.DeleteFunc(rigs, func(name string) bool { return !slices.Contains(cfg.MergeQueue.Rigs, name) })
+	}
+	limit := cfg.MergeQueue.MaxPerPass
+	if limit <= 0 {
+		limit = defaultMergeQueueMaxPerPass
+	}
+
+	go func() {
+		defer d.mergeQueueBusy.Store(false)
+		for _, rigName := range rigs {
+			if d.ctx.Err() != nil {
+				return
+			}
+			d.processMergeQueue(rigName, limit)
+		}
+	}()
+}
+
+// processMergeQueue merges up to limit ready MRs of one rig.
+func (d *Daemon) processMergeQueue(rigName string, limit int) {
+	if operational, reason := d.isRigOperational(rigName); !operational {
+		d.logger.Printf("Skipping merge queue for %s: %s", rigName, reason)
+		return
+	}
+	r := &rig.Rig{Name: rigName, Path: filepath.Join(d.config.TownRoot, rigName)}
+	runner := refinery.NewQueueRunner(r)
+	runner.WorkerID = rigName + "/refinery"
+	runner.SetOutput(d.logger.Writer())
+	runner.Sync = func(workDir string) error { return d.syncWorkspace(d.context(), workDir) }
+
+	results, err := runner.Run(d.ctx, limit)
+	switch {
+	case errors.Is(err, refinery.ErrQueueBusy), errors.Is(err, refinery.ErrQueueDisabled):
+		return
+	case err != nil:
+		d.logger.Printf("Warning: merge queue for %s: %v", rigName, err)
+	}
+	merged := 0
+	for _, res := range results {
+		if res.Result.Success {
+			merged++
+		}
+	}
+	if len(results) > 0 {
+		d.logger.Printf("Merge queue for %s: merged %d of %d MR(s)", rigName, merged, len(results))
+	}
+}
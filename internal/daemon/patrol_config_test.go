@@ -51,3 +51,40 @@ func TestIsPatrolEnabled_NilConfig(t *testing.T) {
 		t.Error("expected default to be enabled")
 	}
 }
+
+func TestTmuxSocket(t *testing.T) {
+	t.Setenv("GT_TMUX_SOCKET", "")
+	townRoot := t.TempDir()
+	mayorDir := filepath.Join(townRoot, "mayor")
+	if err := os.MkdirAll(mayorDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mayorDir, "town.json"), []byte(`{"type":"town","name":"west side"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(cfg string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(mayorDir, "daemon.json"), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := TmuxSocket(townRoot); got != "" {
+		t.Errorf("no config: socket = %q, want shared server", got)
+	}
+
+	writeConfig(`{"tmux": {"isolate": true}}`)
+	if got := TmuxSocket(townRoot); got != "gastown-west-side" {
+		t.Errorf("isolate: socket = %q, want gastown-west-side", got)
+	}
+
+	writeConfig(`{"tmux": {"socket": "custom"}}`)
+	if got := TmuxSocket(townRoot); got != "custom" {
+		t.Errorf("explicit socket = %q, want custom", got)
+	}
+
+	t.Setenv("GT_TMUX_SOCKET", "from-env")
+	if got := TmuxSocket(townRoot); got != "from-env" {
+		t.Errorf("env override: socket = %q, want from-env", got)
+	}
+}
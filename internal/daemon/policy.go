@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultPolicyTimeout bounds a policy hook call.
+const defaultPolicyTimeout = 10 * time.Second
+
+// PolicyConfig asks an external policy before the daemon kills a session,
+// under "lifecycle.policy" in mayor/daemon.json:
+//
+//	"policy": {
+//	  "command": "~/gt-policy/check.sh",
+//	  "url": "https://policy.example.com/gastown",
+//	  "timeout": "5s"
+//	}
+//
+// The hook gets the proposed action as a PolicyProposal. A command receives
+// it as JSON on stdin (and as GT_POLICY_* variables) and allows the action
+// by exiting 0; any other exit denies it, with its output as the reason. A
+// URL receives it as a JSON POST and allows the action with a 2xx response,
+// unless the response body is a PolicyDecision with "allow": false. With
+// both set, both must allow.
+//
+// Lets a town enforce its own rules ("never cycle the refinery during
+// release week") without patching the daemon. An emergency stop is never
+// put to the policy.
+type PolicyConfig struct {
+	// Command is run with sh -c in the town root.
+	Command string `json:"command,omitempty"`
+
+	// URL is POSTed the proposal.
+	URL string `json:"url,omitempty"`
+
+	// Timeout bounds each call (Go duration string, default "10s").
+	Timeout string `json:"timeout,omitempty"`
+
+	// Actions are the actions put to the policy (default: shutdown,
+	// cycle, restart).
+	Actions []LifecycleAction `json:"actions,omitempty"`
+
+	// FailOpen allows the action when the hook can't be reached, times
+	// out, or can't be run. By default such failures deny it.
+	FailOpen bool `json:"fail_open,omitempty"`
+}
+
+// covers reports whether action is put to the policy.
+func (c *PolicyConfig) covers(action LifecycleAction) bool {
+	if c == nil || (c.Command == "" && c.URL == "") {
+		return false
+	}
+	if len(c.Actions) == 0 {
+		return action == ActionShutdown || action.restartsSession()
+	}
+	for _, a := range c.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyProposal is the action the daemon proposes to take.
+type PolicyProposal struct {
+	Identity    string          `json:"identity"`
+	Session     string          `json:"session"`
+	Action      LifecycleAction `json:"action"`
+	RequestedBy string          `json:"requested_by"`
+	Reason      string          `json:"reason,omitempty"`
+	RequestID   string          `json:"request_id,omitempty"`
+	DryRun      bool            `json:"dry_run,omitempty"`
+	TownRoot    string          `json:"town_root"`
+	Time        time.Time       `json:"time"`
+}
+
+// PolicyDecision is the optional JSON answer of a policy URL.
+type PolicyDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// checkPolicy puts a proposed action to the configured policy hooks and
+// returns an ErrPolicyDenied error if they deny it.
+func (d *Daemon) checkPolicy(p *PolicyProposal) error {
+	cfg := d.patrolConfig.lifecycleConfig().Policy
+	if !cfg.covers(p.Action) {
+		return nil
+	}
+	p.TownRoot = d.config.TownRoot
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	timeout := d.lifecycleDuration("policy.timeout", cfg.Timeout, defaultPolicyTimeout)
+
+	for _, hook := range []struct {
+		name string
+		run  func() (allowed bool, reason string, err error)
+	}{
+		{cfg.Command, func() (bool, string, error) { return runPolicyCommand(cfg.Command, p, timeout) }},
+		{cfg.URL, func() (bool, string, error) { return callPolicyURL(cfg.URL, p, timeout) }},
+	} {
+		if hook.name == "" {
+			continue
+		}
+		allowed, reason, err := hook.run()
+		if err != nil {
+			if cfg.FailOpen {
+				d.logger.Printf("Warning: policy hook %s failed, allowing %s of %s (fail_open): %v", hook.name, p.Action, p.Identity, err)
+				continue
+			}
+			return classify(ErrPolicyDenied, fmt.Errorf("policy hook %s failed: %w", hook.name, err))
+		}
+		if !allowed {
+			if reason == "" {
+				reason = "no reason given"
+			}
+			d.logger.Printf("Policy denied %s of %s requested by %s: %s", p.Action, p.Identity, p.RequestedBy, reason)
+			return classify(ErrPolicyDenied, fmt.Errorf("policy denied %s of %s: %s", p.Action, p.Identity, reason))
+		}
+	}
+	return nil
+}
+
+// runPolicyCommand runs a policy command with the proposal on stdin. A
+// non-zero exit denies; failing to run the command at all is an error.
+func runPolicyCommand(command string, p *PolicyProposal, timeout time.Duration) (bool, string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return false, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // G204: command comes from the town's daemon.json
+	cmd.Dir = p.TownRoot
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"GT_POLICY_IDENTITY="+p.Identity,
+		"GT_POLICY_SESSION="+p.Session,
+		"GT_POLICY_ACTION="+string(p.Action),
+		"GT_POLICY_REQUESTED_BY="+p.RequestedBy,
+		"GT_POLICY_REASON="+p.Reason,
+		"GT_TOWN_ROOT="+p.TownRoot,
+	)
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	output := truncateHookOutput(strings.TrimSpace(string(out)))
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, "", fmt.Errorf("timed out after %v", timeout)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		if output == "" {
+			output = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+		}
+		return false, output, nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// callPolicyURL POSTs the proposal to a policy URL. A non-2xx status or an
+// "allow": false answer denies; a failed request or a 5xx is an error.
+func callPolicyURL(url string, p *PolicyProposal, timeout time.Duration) (bool, string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return false, "", err
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHookOutputLog))
+	if resp.StatusCode >= 500 {
+		return false, "", fmt.Errorf("HTTP %s", resp.Status)
+	}
+
+	var decision PolicyDecision
+	isDecision := json.Unmarshal(body, &decision) == nil && bytes.Contains(body, []byte(`"allow"`))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reason := strings.TrimSpace(string(body))
+		if isDecision && decision.Reason != "" {
+			reason = decision.Reason
+		}
+		if reason == "" {
+			reason = resp.Status
+		}
+		return false, reason, nil
+	}
+	if isDecision && !decision.Allow {
+		return false, decision.Reason, nil
+	}
+	return true, "", nil
+}
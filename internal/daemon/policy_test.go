@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckPolicyCommand(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ghost")
+	defer cleanup()
+
+	policy := &PolicyConfig{
+		// Deny anything touching the refinery, read from the JSON proposal
+		Command: `if grep -q '"identity":"gastown-refinery"'; then echo "release week: hands off the refinery"; exit 1; fi`,
+	}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{Policy: policy}}
+
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-witness", Action: ActionCycle, RequestedBy: "mayor"}); err != nil {
+		t.Errorf("witness cycle: %v, want allowed", err)
+	}
+
+	err := d.checkPolicy(&PolicyProposal{Identity: "gastown-refinery", Action: ActionRestart, RequestedBy: "mayor"})
+	if !errors.Is(err, ErrPolicyDenied) || ErrorCode(err) != ErrorCodePolicyDenied {
+		t.Fatalf("refinery restart: %v, want policy denial", err)
+	}
+	if !strings.Contains(err.Error(), "release week") {
+		t.Errorf("denial %q should carry the hook's output", err)
+	}
+
+	// Actions outside the policy's list aren't asked about
+	policy.Actions = []LifecycleAction{ActionShutdown}
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-refinery", Action: ActionRestart}); err != nil {
+		t.Errorf("uncovered action: %v, want allowed", err)
+	}
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-refinery", Action: ActionShutdown}); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("covered shutdown: %v, want denied", err)
+	}
+}
+
+func TestCheckPolicyURL(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ghost")
+	defer cleanup()
+
+	var got PolicyProposal
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		switch got.Identity {
+		case "gastown-refinery":
+			_ = json.NewEncoder(w).Encode(PolicyDecision{Allow: false, Reason: "release freeze"})
+		case "gastown-crew-max":
+			http.Error(w, "max is presenting", http.StatusForbidden)
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	policy := &PolicyConfig{URL: srv.URL}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{Policy: policy}}
+
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-witness", Action: ActionCycle, RequestedBy: requestedByHeartbeat, Reason: "silent"}); err != nil {
+		t.Errorf("witness: %v, want allowed", err)
+	}
+	if got.RequestedBy != requestedByHeartbeat || got.Reason != "silent" || got.TownRoot != d.config.TownRoot {
+		t.Errorf("proposal = %+v", got)
+	}
+
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-refinery", Action: ActionCycle}); err == nil || !strings.Contains(err.Error(), "release freeze") {
+		t.Errorf("refinery: %v, want denied with the decision's reason", err)
+	}
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-crew-max", Action: ActionCycle}); err == nil || !strings.Contains(err.Error(), "presenting") {
+		t.Errorf("crew: %v, want denied by 403", err)
+	}
+
+	// A failing hook denies unless the policy fails open
+	if err := d.checkPolicy(&PolicyProposal{Identity: "broken", Action: ActionCycle}); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("5xx: %v, want denied", err)
+	}
+	policy.FailOpen = true
+	if err := d.checkPolicy(&PolicyProposal{Identity: "broken", Action: ActionCycle}); err != nil {
+		t.Errorf("5xx with fail_open: %v, want allowed", err)
+	}
+}
+
+func TestCheckPolicyOff(t *testing.T) {
+	d := testDaemon()
+	if err := d.checkPolicy(&PolicyProposal{Identity: "gastown-refinery", Action: ActionRestart}); err != nil {
+		t.Errorf("no policy configured: %v, want allowed", err)
+	}
+	if (&PolicyConfig{Command: "true"}).covers(ActionRefresh) {
+		t.Error("refresh should not be put to the policy by default")
+	}
+}
@@ -0,0 +1,208 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPrewarmDuration is how long a prewarmed session stays up.
+	defaultPrewarmDuration = 10 * time.Minute
+
+	// defaultPrewarmConcurrency caps simultaneous prewarmed sessions.
+	defaultPrewarmConcurrency = 2
+)
+
+// defaultPrewarmAgents matches every crew member.
+var defaultPrewarmAgents = []string{"*-crew-*"}
+
+// prewarmWindow is a daily local-time window, in minutes since midnight.
+type prewarmWindow struct {
+	start, end int
+}
+
+// parsePrewarmWindow parses "HH:MM-HH:MM".
+func parsePrewarmWindow(s string) (prewarmWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return prewarmWindow{}, fmt.Errorf("invalid window %q: expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return prewarmWindow{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return prewarmWindow{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	if start == end {
+		return prewarmWindow{}, fmt.Errorf("invalid window %q: empty", s)
+	}
+	return prewarmWindow{start: start, end: end}, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// opened returns when the window containing now opened, and whether now is
+// inside the window at all. Windows that wrap midnight opened the previous day
+// when now is before the end.
+func (w prewarmWindow) opened(now time.Time) (time.Time, bool) {
+	minute := now.Hour()*60 + now.Minute()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startToday := midnight.Add(time.Duration(w.start) * time.Minute)
+
+	if w.start < w.end {
+		return startToday, minute >= w.start && minute < w.end
+	}
+	// Wraps midnight
+	if minute >= w.start {
+		return startToday, true
+	}
+	if minute < w.end {
+		return startToday.AddDate(0, 0, -1), true
+	}
+	return time.Time{}, false
+}
+
+// matchesPrewarmAgents reports whether identity matches any pattern.
+func matchesPrewarmAgents(identity string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == identity {
+			return true
+		}
+		if ok, _ := path.Match(pattern, identity); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// prewarmAgents starts idle agents during the configured off-peak window so
+// their workspace sync and context priming happen before the morning's first
+// assignment, and stops them again once the prewarm duration has passed.
+// Each agent is prewarmed at most once per window. Sessions that picked up
+// work (hook_bead set) are left running.
+func (d *Daemon) prewarmAgents(state *State, now time.Time) {
+	if d.patrolConfig == nil || d.patrolConfig.Prewarm == nil || !d.patrolConfig.Prewarm.Enabled {
+		return
+	}
+	cfg := d.patrolConfig.Prewarm
+
+	window, err := parsePrewarmWindow(cfg.Window)
+	if err != nil {
+		d.logger.Printf("Warning: prewarm disabled: %v", err)
+		return
+	}
+	duration := defaultPrewarmDuration
+	if cfg.Duration != "" {
+		if parsed, err := time.ParseDuration(cfg.Duration); err == nil && parsed > 0 {
+			duration = parsed
+		} else {
+			d.logger.Printf("Warning: invalid prewarm.duration %q, using %v", cfg.Duration, duration)
+		}
+	}
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultPrewarmConcurrency
+	}
+	if state.Prewarmed == nil {
+		state.Prewarmed = make(map[string]*PrewarmRecord)
+	}
+
+	// Stop prewarmed sessions whose time is up
+	active := 0
+	for identity, record := range state.Prewarmed {
+		if record.Stopped {
+			continue
+		}
+		sessionName := d.identityToSession(identity)
+		running, _ := d.tmux.HasSession(sessionName)
+		if !running {
+			record.Stopped = true
+			continue
+		}
+		if now.Sub(record.StartedAt) < duration {
+			active++
+			continue
+		}
+		if d.hasHookedWork(identity) {
+			d.logger.Printf("Prewarm: %s picked up work, leaving it running", identity)
+			record.Stopped = true
+			continue
+		}
+		err := d.tmux.KillSessionWithProcesses(sessionName)
+		d.audit(AuditKillSession, sessionName, "daemon/prewarm",
+			[]string{"session started by prewarm", fmt.Sprintf("prewarm duration %v elapsed", duration), "no hooked work"}, err)
+		if err != nil {
+			d.logger.Printf("Warning: prewarm: failed to stop %s: %v", sessionName, err)
+			active++
+			continue
+		}
+		d.logger.Printf("Prewarm: stopped %s after %v", sessionName, duration)
+		record.Stopped = true
+	}
+
+	opened, inWindow := window.opened(now)
+	if !inWindow {
+		return
+	}
+
+	patterns := cfg.Agents
+	if len(patterns) == 0 {
+		patterns = defaultPrewarmAgents
+	}
+	for _, identity := range d.managedIdentities() {
+		if active >= maxConcurrent {
+			return
+		}
+		if !matchesPrewarmAgents(identity, patterns) {
+			continue
+		}
+		if record := state.Prewarmed[identity]; record != nil && !record.StartedAt.Before(opened) {
+			continue // already prewarmed this window
+		}
+		sessionName := d.identityToSession(identity)
+		if sessionName == "" {
+			continue
+		}
+		if running, err := d.tmux.HasSession(sessionName); err != nil || running {
+			continue
+		}
+
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Prewarm: would start %s", sessionName)
+			continue
+		}
+		if err := d.restartSession(sessionName, identity); err != nil {
+			// Don't retry every heartbeat; try again next window
+			d.logger.Printf("Warning: prewarm: failed to start %s: %v", sessionName, err)
+			state.Prewarmed[identity] = &PrewarmRecord{StartedAt: now, Stopped: true}
+			continue
+		}
+		d.logger.Printf("Prewarm: started %s for %v", sessionName, duration)
+		state.Prewarmed[identity] = &PrewarmRecord{StartedAt: now}
+		active++
+	}
+}
+
+// hasHookedWork reports whether the agent's bead has work on its hook.
+func (d *Daemon) hasHookedWork(identity string) bool {
+	agentBeadID := d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return false
+	}
+	info, err := d.getAgentBeadInfo(agentBeadID)
+	if err != nil {
+		return false
+	}
+	return info.HookBead != ""
+}
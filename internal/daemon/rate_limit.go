@@ -0,0 +1,224 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+)
+
+// Lifecycle request rate limiting. A confused agent can mail a cycle request
+// every few seconds; each one costs a session restart (or at least a deferral
+// per heartbeat). Requests over the per-sender limit are rejected with a
+// reply, counted in daemon state, and escalated to the mayor when a sender
+// keeps going.
+const (
+	// defaultRateLimitRequests is how many lifecycle requests a sender may
+	// make per window.
+	defaultRateLimitRequests = 6
+
+	// defaultRateLimitWindow is the sliding window requests are counted over.
+	defaultRateLimitWindow = 10 * time.Minute
+
+	// defaultRateLimitEscalateAfter is how many rejections of one sender are
+	// tolerated before the mayor is told.
+	defaultRateLimitEscalateAfter = 3
+
+	// rateLimitEscalationIdentity receives rate limit escalations.
+	rateLimitEscalationIdentity = "mayor"
+)
+
+// RateLimitConfig limits lifecycle requests per sender.
+type RateLimitConfig struct {
+	// Requests is how many lifecycle requests one sender may make per
+	// window (default 6, -1 for no limit).
+	Requests int `json:"requests,omitempty"`
+
+	// Window is the sliding window requests are counted over (Go duration
+	// string, default "10m").
+	Window string `json:"window,omitempty"`
+
+	// Senders overrides Requests per sender identity. Keys may be glob
+	// patterns (e.g. "*-witness"); an exact match wins over patterns.
+	Senders map[string]int `json:"senders,omitempty"`
+
+	// EscalateAfter is how many rejections of one sender trigger mail to the
+	// mayor (default 3, -1 to never escalate). The count restarts after
+	// each escalation.
+	EscalateAfter int `json:"escalate_after,omitempty"`
+}
+
+// RateLimitStats is one sender's rate limiting history, kept in daemon state.
+type RateLimitStats struct {
+	// Rejected counts every request rejected since the daemon started.
+	Rejected int `json:"rejected"`
+
+	// LastRejectedAt is when the most recent request was rejected.
+	LastRejectedAt time.Time `json:"last_rejected_at"`
+
+	// Escalations counts the escalations sent to the mayor.
+	Escalations int `json:"escalations,omitempty"`
+
+	// LastEscalatedAt is when the mayor was last told.
+	LastEscalatedAt time.Time `json:"last_escalated_at,omitzero"`
+
+	// sinceEscalation counts rejections since the last escalation.
+	sinceEscalation int
+}
+
+// rateLimiter counts lifecycle requests per sender over a sliding window.
+// Requests are counted once per message, so a request that stays in the
+// inbox (deferred by the restart throttle or a handoff) is not counted again
+// on every poll.
+type rateLimiter struct {
+	window time.Duration
+	seen   map[string][]rateLimitHit // by sender
+	stats  map[string]*RateLimitStats
+}
+
+type rateLimitHit struct {
+	messageID string
+	at        time.Time
+}
+
+// rateLimitState returns the daemon's limiter, creating it on first use.
+func (d *Daemon) rateLimitState() *rateLimiter {
+	if d.rateLimits == nil {
+		cfg := d.patrolConfig.lifecycleConfig().RateLimit
+		window := defaultRateLimitWindow
+		if cfg != nil {
+			window = d.lifecycleDuration("rate_limit.window", cfg.Window, defaultRateLimitWindow)
+		}
+		d.rateLimits = &rateLimiter{
+			window: window,
+			seen:   make(map[string][]rateLimitHit),
+			stats:  make(map[string]*RateLimitStats),
+		}
+	}
+	return d.rateLimits
+}
+
+// rateLimitFor returns how many requests sender may make per window, or a
+// negative number for no limit.
+func (d *Daemon) rateLimitFor(sender string) int {
+	cfg := d.patrolConfig.lifecycleConfig().RateLimit
+	if cfg == nil {
+		return defaultRateLimitRequests
+	}
+	if limit, ok := cfg.Senders[sender]; ok {
+		return limit
+	}
+	patterns := make([]string, 0, len(cfg.Senders))
+	for pattern := range cfg.Senders {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, sender); matched {
+			return cfg.Senders[pattern]
+		}
+	}
+	if cfg.Requests != 0 {
+		return cfg.Requests
+	}
+	return defaultRateLimitRequests
+}
+
+func (d *Daemon) rateLimitEscalateAfter() int {
+	if cfg := d.patrolConfig.lifecycleConfig().RateLimit; cfg != nil && cfg.EscalateAfter != 0 {
+		return cfg.EscalateAfter
+	}
+	return defaultRateLimitEscalateAfter
+}
+
+// admit counts a request and reports whether it is within the limit.
+func (l *rateLimiter) admit(sender, messageID string, limit int, now time.Time) bool {
+	hits := l.seen[sender][:0]
+	for _, hit := range l.seen[sender] {
+		if now.Sub(hit.at) < l.window {
+			hits = append(hits, hit)
+		}
+	}
+	l.seen[sender] = hits
+
+	for _, hit := range hits {
+		if hit.messageID == messageID {
+			return true // Already counted on an earlier poll
+		}
+	}
+	if limit >= 0 && len(hits) >= limit {
+		return false
+	}
+	l.seen[sender] = append(hits, rateLimitHit{messageID: messageID, at: now})
+	return true
+}
+
+// reject records a rejection and reports whether the sender is now due for
+// escalation.
+func (l *rateLimiter) reject(sender string, escalateAfter int, now time.Time) bool {
+	stats := l.stats[sender]
+	if stats == nil {
+		stats = &RateLimitStats{}
+		l.stats[sender] = stats
+	}
+	stats.Rejected++
+	stats.LastRejectedAt = now
+	stats.sinceEscalation++
+	if escalateAfter <= 0 || stats.sinceEscalation < escalateAfter {
+		return false
+	}
+	stats.sinceEscalation = 0
+	stats.Escalations++
+	stats.LastEscalatedAt = now
+	return true
+}
+
+// snapshot copies the per-sender stats for daemon state.
+func (l *rateLimiter) snapshot() map[string]*RateLimitStats {
+	if l == nil || len(l.stats) == 0 {
+		return nil
+	}
+	out := make(map[string]*RateLimitStats, len(l.stats))
+	for sender, stats := range l.stats {
+		copied := *stats
+		out[sender] = &copied
+	}
+	return out
+}
+
+// checkRateLimit applies the sender's rate limit to a lifecycle request
+// message. A rejected request is closed, answered, and possibly escalated;
+// the caller must skip it.
+func (d *Daemon) checkRateLimit(msg *BeadsMessage, request *LifecycleRequest, now time.Time) bool {
+	limiter := d.rateLimitState()
+	limit := d.rateLimitFor(request.From)
+	if limiter.admit(request.From, msg.ID, limit, now) {
+		return true
+	}
+
+	reason := fmt.Sprintf("more than %d lifecycle requests in %s", limit, limiter.window)
+	d.logger.Printf("Rejecting %s from %s: rate limited (%s)", request.Action, request.From, reason)
+	escalate := limiter.reject(request.From, d.rateLimitEscalateAfter(), now)
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would delete message %s and reply to %s", msg.ID, request.From)
+		return false
+	}
+
+	if err := d.closeMessage(msg.ID, request.From, "rejected: rate limited"); err != nil {
+		d.logger.Printf("Warning: failed to delete message %s: %v", msg.ID, err)
+	}
+	d.clearAgentRequestFlags(request.From)
+	d.replyLifecycleResult(request, request.From,
+		fmt.Errorf("%w: %s; wait before requesting again, repeated requests are reported to the mayor", ErrRateLimited, reason))
+
+	if escalate {
+		stats := limiter.stats[request.From]
+		d.logger.Printf("Escalating lifecycle rate limit violations by %s to %s", request.From, rateLimitEscalationIdentity)
+		d.sendLifecycleNotify([]string{rateLimitEscalationIdentity},
+			fmt.Sprintf("ESCALATION: %s is flooding lifecycle requests", request.From),
+			fmt.Sprintf("%s has had %d lifecycle requests rejected for exceeding the rate limit (%s); the latest was %s.\n\n"+
+				"The agent may be stuck in a loop. Check it with: gt agent logs %s",
+				request.From, stats.Rejected, reason, request.Action, identityToMailAddress(request.From)))
+	}
+	return false
+}
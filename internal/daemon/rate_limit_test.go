@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAdmit(t *testing.T) {
+	l := &rateLimiter{window: 10 * time.Minute, seen: map[string][]rateLimitHit{}, stats: map[string]*RateLimitStats{}}
+	now := time.Now()
+
+	if !l.admit("gastown-crew-max", "m1", 2, now) || !l.admit("gastown-crew-max", "m2", 2, now.Add(time.Second)) {
+		t.Fatal("requests within the limit rejected")
+	}
+	if l.admit("gastown-crew-max", "m3", 2, now.Add(2*time.Second)) {
+		t.Error("third request in the window admitted")
+	}
+	// A deferred request seen again on the next poll isn't counted twice
+	if !l.admit("gastown-crew-max", "m1", 2, now.Add(time.Minute)) {
+		t.Error("already-counted request rejected on re-poll")
+	}
+	// Other senders have their own budget
+	if !l.admit("gastown-witness", "m4", 2, now) {
+		t.Error("other sender rejected")
+	}
+	// Once the window has passed, the sender may request again
+	if !l.admit("gastown-crew-max", "m5", 2, now.Add(11*time.Minute)) {
+		t.Error("request after the window rejected")
+	}
+	// A negative limit admits everything
+	for i := range 10 {
+		if !l.admit("mayor", string(rune('a'+i)), -1, now) {
+			t.Fatal("unlimited sender rejected")
+		}
+	}
+}
+
+func TestRateLimiterEscalation(t *testing.T) {
+	l := &rateLimiter{window: time.Minute, seen: map[string][]rateLimitHit{}, stats: map[string]*RateLimitStats{}}
+	now := time.Now()
+
+	var escalations int
+	for range 7 {
+		if l.reject("gastown-crew-max", 3, now) {
+			escalations++
+		}
+	}
+	if escalations != 2 {
+		t.Errorf("escalations = %d, want 2 (after 3rd and 6th rejection)", escalations)
+	}
+	stats := l.snapshot()["gastown-crew-max"]
+	if stats.Rejected != 7 || stats.Escalations != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestRateLimitFor(t *testing.T) {
+	d := testDaemon()
+	if got := d.rateLimitFor("gastown-crew-max"); got != defaultRateLimitRequests {
+		t.Errorf("default limit = %d", got)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{RateLimit: &RateLimitConfig{
+		Requests: 4,
+		Senders:  map[string]int{"*-witness": 20, "mayor": -1},
+	}}}
+	for sender, want := range map[string]int{"gastown-crew-max": 4, "gastown-witness": 20, "mayor": -1} {
+		if got := d.rateLimitFor(sender); got != want {
+			t.Errorf("rateLimitFor(%s) = %d, want %d", sender, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+// Read-only agents. Agents of a role plugin with "read_only": true, such
+// as the built-in observer role, can read a rig's code but not change it:
+// analytics and reporting agents. Each works in its own clone of the rig's
+// repository, whose push URLs go nowhere and whose git hooks refuse commits
+// and local ref updates, and its session starts without the push
+// credentials (tokens, ssh agent, credential helpers) of the daemon's
+// environment.
+//
+// Observers are named rig agents, like crew: create <rig>/observer/<name>/
+// and start it with 'gt rig up' or a lifecycle request. The daemon clones
+// the rig's repository into the directory on first start. A town can
+// redefine the role in roles/observer/role.json.
+
+// ObserverRole is the built-in read-only role.
+const ObserverRole = "observer"
+
+// observerRole returns the built-in observer role definition. Its hooks
+// live where a town's own plugin's would.
+func observerRole(townRoot string) *RolePlugin {
+	return &RolePlugin{
+		Name:     ObserverRole,
+		Dir:      filepath.Join(townRoot, RolesDir, ObserverRole),
+		Named:    true,
+		ReadOnly: true,
+	}
+}
+
+// readOnlyPushURL replaces the push URL of every remote of a read-only
+// checkout.
+const readOnlyPushURL = "read-only-checkout://push-disabled"
+
+// readOnlyHooksDir is where a read-only checkout's git hooks are written,
+// inside its .git directory.
+const readOnlyHooksDir = "gt-read-only-hooks"
+
+// readOnlyHooks are the git hooks of a read-only checkout. --no-verify
+// skips pre-commit but not reference-transaction, which refuses to move
+// anything but HEAD and remote-tracking refs, so fetches and checkouts of
+// existing commits still work.
+var readOnlyHooks = map[string]string{
+	"pre-commit": `#!/bin/sh
+echo "gt: read-only checkout, commits are disabled" >&2
+exit 1
+`,
+	"pre-push": `#!/bin/sh
+echo "gt: read-only checkout, pushes are disabled" >&2
+exit 1
+`,
+	"reference-transaction": `#!/bin/sh
+[ "$1" = prepared ] || exit 0
+[ -n "$GT_READ_ONLY_SYNC" ] && exit 0
+while read -r old new ref; do
+	case "$ref" in
+	HEAD|FETCH_HEAD|ORIG_HEAD|refs/remotes/*) ;;
+	*) echo "gt: read-only checkout, refusing to update $ref" >&2; exit 1 ;;
+	esac
+done
+`,
+}
+
+// readOnlyUnsetEnv are the credential variables removed from a read-only
+// agent's environment.
+var readOnlyUnsetEnv = []string{
+	"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITLAB_TOKEN", "GL_TOKEN",
+	"BITBUCKET_TOKEN", "GIT_ASKPASS", "SSH_ASKPASS", "SSH_AUTH_SOCK", "GIT_SSH", "GIT_SSH_COMMAND",
+}
+
+// readOnlyEnv is set in a read-only agent's environment. The GIT_CONFIG_*
+// entry empties credential.helper, so git can't fetch stored credentials.
+var readOnlyEnv = map[string]string{
+	"GT_READ_ONLY":        "1",
+	"GIT_TERMINAL_PROMPT": "0",
+	"GIT_CONFIG_COUNT":    "1",
+	"GIT_CONFIG_KEY_0":    "credential.helper",
+	"GIT_CONFIG_VALUE_0":  "",
+}
+
+// isReadOnlyRole reports whether a role's agents are read-only.
+func isReadOnlyRole(role string) bool {
+	p := lookupRolePlugin(role)
+	return p != nil && p.ReadOnly
+}
+
+// readOnlyStartCommand strips push credentials from the environment of a
+// read-only agent's start command. Other agents' commands are returned as is.
+func readOnlyStartCommand(parsed *ParsedIdentity, startCmd string) string {
+	if !isReadOnlyRole(parsed.RoleType) {
+		return startCmd
+	}
+	return "unset " + strings.Join(readOnlyUnsetEnv, " ") + " && " + config.PrependEnv(startCmd, readOnlyEnv)
+}
+
+// ensureReadOnlyCheckout prepares a read-only agent's workdir: clones the
+// rig's repository into it if it is missing or empty, locks it down, and
+// fast-forwards it to its upstream. The workdir must be a clone of its own;
+// locking a worktree would change the config of the repository it shares.
+func (d *Daemon) ensureReadOnlyCheckout(parsed *ParsedIdentity, workDir string) error {
+	if entries, err := os.ReadDir(workDir); (err != nil && os.IsNotExist(err)) || (err == nil && len(entries) == 0) {
+		if err := d.cloneReadOnlyCheckout(parsed.RigName, workDir); err != nil {
+			return classify(ErrStateVerificationFailed, fmt.Errorf("cloning read-only checkout %s: %w", workDir, err))
+		}
+		d.logger.Printf("Cloned %s's repository into read-only checkout %s", parsed.RigName, workDir)
+	}
+
+	if info, err := os.Stat(filepath.Join(workDir, ".git")); err != nil || !info.IsDir() {
+		return classify(ErrStateVerificationFailed, fmt.Errorf("%s agents need a clone of their own, and %s is not one", parsed.RoleType, workDir))
+	}
+	if err := lockReadOnlyCheckout(workDir); err != nil {
+		return classify(ErrStateVerificationFailed, fmt.Errorf("locking read-only checkout %s: %w", workDir, err))
+	}
+
+	// Best-effort refresh: a stale checkout is still readable
+	if err := runGit(workDir, nil, "fetch", "--quiet", "--all"); err != nil {
+		d.logger.Printf("Warning: fetching read-only checkout %s: %v", workDir, err)
+	} else if err := runGit(workDir, []string{"GT_READ_ONLY_SYNC=1"}, "merge", "--ff-only", "--quiet", "@{upstream}"); err != nil {
+		d.logger.Printf("Warning: updating read-only checkout %s: %v", workDir, err)
+	}
+	return nil
+}
+
+// cloneReadOnlyCheckout clones a rig's repository into dir, borrowing
+// objects from the rig's shared repository when it has one.
+func (d *Daemon) cloneReadOnlyCheckout(rigName, dir string) error {
+	rigPath := filepath.Join(d.config.TownRoot, rigName)
+	reference := filepath.Join(rigPath, ".repo.git")
+	if _, err := os.Stat(reference); err != nil {
+		reference = filepath.Join(rigPath, "mayor", "rig")
+	}
+
+	url := reference
+	if rigsConfig, err := config.LoadRigsConfig(filepath.Join(d.config.TownRoot, "mayor", "rigs.json")); err == nil {
+		if entry, ok := rigsConfig.Rigs[rigName]; ok && entry.GitURL != "" {
+			url = entry.GitURL
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if url == reference {
+		return git.NewGit("").Clone(url, dir)
+	}
+	return git.NewGit("").CloneWithReference(url, dir, reference)
+}
+
+// lockReadOnlyCheckout disables pushing from a checkout and installs the
+// read-only git hooks. It is applied on every start, so a lock an agent
+// undid is restored.
+func lockReadOnlyCheckout(dir string) error {
+	hooksDir := filepath.Join(dir, ".git", readOnlyHooksDir)
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+	for name, script := range readOnlyHooks {
+		if err := os.WriteFile(filepath.Join(hooksDir, name), []byte(script), 0755); err != nil {
+			return err
+		}
+	}
+	if err := runGit(dir, nil, "config", "core.hooksPath", hooksDir); err != nil {
+		return err
+	}
+
+	remotes, err := git.NewGit(dir).Remotes()
+	if err != nil {
+		return err
+	}
+	for _, remote := range remotes {
+		if err := runGit(dir, nil, "config", "remote."+remote+".pushurl", readOnlyPushURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGit runs git in dir with extra environment, returning its stderr in
+// the error.
+func runGit(dir string, env []string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...) //nolint:gosec // G204: args are fixed git subcommands
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return nil
+}
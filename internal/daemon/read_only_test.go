@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newObserverTown creates a town whose rig gastown has a mayor/rig clone
+// with one commit, and an empty observer directory.
+func newObserverTown(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	townRoot := t.TempDir()
+	repo := filepath.Join(townRoot, "gastown", "mayor", "rig")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q")
+	if err := os.WriteFile(filepath.Join(repo, "README"), []byte("gastown\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "add", "README")
+	gitIn(t, repo, "commit", "-q", "-m", "init")
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", ObserverRole, "stats"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return townRoot
+}
+
+func TestObserverRoleBuiltin(t *testing.T) {
+	townRoot := t.TempDir()
+	useRolePlugins(t, townRoot)
+	parsed, err := parseIdentity("gastown-observer-stats")
+	if err != nil || parsed.RoleType != ObserverRole || parsed.RigName != "gastown" || parsed.AgentName != "stats" {
+		t.Fatalf("parseIdentity = %+v, %v", parsed, err)
+	}
+	if !isReadOnlyRole(ObserverRole) || isReadOnlyRole("crew") {
+		t.Error("only observers should be read-only")
+	}
+
+	// A town's own definition replaces the built-in one
+	writeRolePlugin(t, townRoot, ObserverRole, `{"named": true, "start_command": "exec ./report.sh"}`)
+	useRolePlugins(t, townRoot)
+	if p := lookupRolePlugin(ObserverRole); p == nil || p.StartCommand != "exec ./report.sh" || p.ReadOnly {
+		t.Errorf("observer = %+v, want the town's definition", p)
+	}
+}
+
+func TestReadOnlyStartCommand(t *testing.T) {
+	useRolePlugins(t, t.TempDir())
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+	if got := readOnlyStartCommand(crew, "exec claude"); got != "exec claude" {
+		t.Errorf("crew start command = %q, want it unchanged", got)
+	}
+
+	observer := &ParsedIdentity{RoleType: ObserverRole, RigName: "gastown", AgentName: "stats"}
+	got := readOnlyStartCommand(observer, "exec claude")
+	for _, want := range []string{"unset GH_TOKEN GITHUB_TOKEN", "SSH_AUTH_SOCK", "GIT_CONFIG_KEY_0=credential.helper", "GT_READ_ONLY=1", "&& exec claude"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("observer start command %q lacks %q", got, want)
+		}
+	}
+}
+
+func TestEnsureReadOnlyCheckout(t *testing.T) {
+	townRoot := newObserverTown(t)
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	parsed := &ParsedIdentity{RoleType: ObserverRole, RigName: "gastown", AgentName: "stats"}
+	workDir := filepath.Join(townRoot, "gastown", ObserverRole, "stats")
+
+	if err := d.ensureReadOnlyCheckout(parsed, workDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "README")); err != nil {
+		t.Fatalf("checkout not cloned: %v", err)
+	}
+	if got := gitIn(t, workDir, "config", "remote.origin.pushurl"); got != readOnlyPushURL {
+		t.Errorf("pushurl = %q", got)
+	}
+
+	// Commits are refused, even with --no-verify
+	if err := os.WriteFile(filepath.Join(workDir, "notes"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, workDir, "add", "notes")
+	for _, args := range [][]string{{"commit", "-q", "-m", "x"}, {"commit", "-q", "--no-verify", "-m", "x"}} {
+		cmd := exec.Command("git", append([]string{"-C", workDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err == nil {
+			t.Errorf("git %v succeeded in a read-only checkout: %s", args, out)
+		}
+	}
+
+	// Locking is reapplied on every start
+	gitIn(t, workDir, "config", "--unset", "remote.origin.pushurl")
+	if err := d.ensureReadOnlyCheckout(parsed, workDir); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitIn(t, workDir, "config", "remote.origin.pushurl"); got != readOnlyPushURL {
+		t.Errorf("pushurl after restart = %q", got)
+	}
+}
+
+func TestEnsureReadOnlyCheckoutRefusesWorktree(t *testing.T) {
+	townRoot := newObserverTown(t)
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	parsed := &ParsedIdentity{RoleType: ObserverRole, RigName: "gastown", AgentName: "stats"}
+	workDir := filepath.Join(townRoot, "gastown", ObserverRole, "stats")
+	if err := os.Remove(workDir); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, filepath.Join(townRoot, "gastown", "mayor", "rig"), "worktree", "add", "-q", "--detach", workDir)
+
+	err := d.ensureReadOnlyCheckout(parsed, workDir)
+	if err == nil || !strings.Contains(err.Error(), "clone of their own") {
+		t.Errorf("err = %v, want a worktree refused", err)
+	}
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/budget"
+	"github.com/steveyegge/gastown/internal/manifest"
+)
+
+// reconcileRequestedBy is recorded as the requester of manifest-driven stops.
+const reconcileRequestedBy = "daemon/reconciler"
+
+// loadManifest reads town.yaml for this heartbeat. A broken manifest is
+// logged and treated as absent, so a typo never stops sessions.
+func (d *Daemon) loadManifest() *manifest.Manifest {
+	m, err := manifest.Load(d.config.TownRoot)
+	if err != nil {
+		d.logger.Printf("Warning: ignoring town manifest: %v", err)
+		return nil
+	}
+	return m
+}
+
+// observeAgents returns the observed state of every managed agent plus any
+// agent the manifest declares that doesn't exist on disk yet.
+func (d *Daemon) observeAgents(m *manifest.Manifest) []manifest.Agent {
+	identities := d.managedIdentities()
+	seen := make(map[string]bool, len(identities))
+	for _, identity := range identities {
+		seen[identity] = true
+	}
+	for _, change := range m.Plan(d.getKnownRigs(), nil) {
+		if change.Action == manifest.ActionStart && !seen[change.Identity] {
+			identities = append(identities, change.Identity)
+		}
+	}
+
+	var agents []manifest.Agent
+	for _, identity := range identities {
+		parsed, err := parseIdentity(identity)
+		if err != nil {
+			continue
+		}
+		sessionName := d.identityToSession(identity)
+		if sessionName == "" {
+			continue
+		}
+		running, err := d.tmux.HasSession(sessionName)
+		if err != nil {
+			continue
+		}
+		agent := manifest.Agent{
+			Identity: identity,
+			Rig:      parsed.RigName,
+			Role:     parsed.RoleType,
+			Running:  running,
+		}
+		if agentBeadID := d.identityToAgentBeadID(identity); agentBeadID != "" {
+			agent.BeadState, _ = d.getAgentBeadState(agentBeadID)
+		}
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// reconcilePlan compares the manifest with the observed town. Agents that
+// must stay down whatever the manifest says are reported, not started.
+// Hibernating agents are left out: they are down on purpose, and
+// hibernateIdleAgents wakes them when work or mail arrives.
+func (d *Daemon) reconcilePlan(m *manifest.Manifest, hibernating map[string]time.Time) []manifest.Change {
+	var changes []manifest.Change
+	for _, change := range m.Plan(d.getKnownRigs(), d.observeAgents(m)) {
+		if change.Action == manifest.ActionStart {
+			if _, ok := hibernating[change.Identity]; ok {
+				continue
+			}
+			if reason := d.startHeld(change.Identity); reason != "" {
+				change = manifest.Change{
+					Action:   manifest.ActionWarn,
+					Identity: change.Identity,
+					Reason:   "declared running but held down: " + reason,
+				}
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// startHeld returns why identity must not be started, or "" if it may be:
+// its rig is parked or docked, its rig's budget hard stop has tripped, or
+// it is outside its working hours.
+func (d *Daemon) startHeld(identity string) string {
+	parsed, err := parseIdentity(identity)
+	if err != nil {
+		return ""
+	}
+	if parsed.RigName != "" {
+		if operational, reason := d.isRigOperational(parsed.RigName); !operational {
+			return reason
+		}
+		if ok, reason := budget.CheckRole(d.config.TownRoot, parsed.RigName, parsed.RoleType); !ok {
+			return reason
+		}
+	}
+	if outside, reason := d.outsideSchedule(identity, time.Now()); outside {
+		return reason
+	}
+	return ""
+}
+
+// applyChange carries out one reconciler change. Starts are checked again
+// with startHeld, since a plan may be applied after it was made.
+func (d *Daemon) applyChange(change manifest.Change) error {
+	ctl := &SessionController{d: d}
+	switch change.Action {
+	case manifest.ActionStart:
+		if reason := d.startHeld(change.Identity); reason != "" {
+			return fmt.Errorf("not starting: %s", reason)
+		}
+		_, err := ctl.Start(change.Identity)
+		return err
+	case manifest.ActionStop:
+		_, err := ctl.Stop(change.Identity, reconcileRequestedBy)
+		return err
+	case manifest.ActionSyncBead:
+		ctl.reportState(change.Identity, change.BeadState)
+	}
+	return nil
+}
+
+// reconcileManifest converges the town toward town.yaml, if the town has one.
+func (d *Daemon) reconcileManifest(state *State, m *manifest.Manifest) {
+	if m == nil {
+		return
+	}
+	for _, change := range d.reconcilePlan(m, state.Hibernating) {
+		if change.Action == manifest.ActionWarn {
+			d.logger.Printf("Manifest: %s", change)
+			continue
+		}
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Manifest: would %s", change)
+			continue
+		}
+		if err := d.applyChange(change); err != nil {
+			d.logger.Printf("Manifest: %s failed: %v", change, err)
+			continue
+		}
+		d.logger.Printf("Manifest: %s", change)
+	}
+}
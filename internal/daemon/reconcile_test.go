@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/wisp"
+)
+
+func TestReconcilePlan_HoldsAgentsThatMustStayDown(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	t.Setenv("PATH", t.TempDir()) // no bd: bead states are unknown
+
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{},"beads":{},"sandbox":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.tmux = &hibernateTmux{running: map[string]bool{}}
+
+	// gastown is parked; beads is outside its working hours
+	if err := wisp.NewConfig(townRoot, "gastown").Set("status", "parked"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	hours := now.Add(2*time.Hour).Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04")
+	d.patrolConfig = &DaemonPatrolConfig{Schedules: []*ActivitySchedule{{Rigs: []string{"beads"}, Hours: hours}}}
+
+	m, err := manifest.Parse([]byte("rigs:\n  gastown: {}\n  beads: {}\n  sandbox: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held := map[string]string{}
+	var started []string
+	for _, change := range d.reconcilePlan(m, nil) {
+		switch change.Action {
+		case manifest.ActionStart:
+			started = append(started, change.Identity)
+		case manifest.ActionWarn:
+			held[change.Identity] = change.Reason
+		}
+	}
+	if !strings.Contains(held["gastown-witness"], "rig is parked") || !strings.Contains(held["gastown-refinery"], "rig is parked") {
+		t.Errorf("parked rig not held: %v", held)
+	}
+	if !strings.Contains(held["beads-witness"], "outside working hours") {
+		t.Errorf("scheduled agent not held: %v", held)
+	}
+	if strings.Join(started, ",") != "sandbox-witness,sandbox-refinery" {
+		t.Errorf("started %v, want only sandbox's agents", started)
+	}
+}
+
+func TestReconcileManifest_LeavesHibernatingCrewDown(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", "max"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.Parse([]byte("rigs:\n  gastown:\n    witness: false\n    refinery: false\n    crew: [max]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	session := d.identityToSession("gastown-crew-max")
+	fakeTmux := &hibernateTmux{running: map[string]bool{session: true}}
+	d.tmux, d.beads, d.mail = fakeTmux, &hibernateBeads{state: "idle", updatedAt: now.Add(-2 * time.Hour)}, &hibernateMail{}
+	d.patrolConfig = &DaemonPatrolConfig{Hibernate: &HibernateConfig{Enabled: true, IdleAfter: "1h"}}
+	var logged strings.Builder
+	d.logger.SetOutput(&logged)
+	state := &State{}
+
+	// Steps 16 and 25 over three heartbeats: max goes idle, is hibernated,
+	// and must stay down although town.yaml declares it running
+	for i := 0; i < 3; i++ {
+		d.reconcileManifest(state, m)
+		d.hibernateIdleAgents(state, now.Add(time.Duration(i)*time.Minute))
+	}
+	if fakeTmux.running[session] {
+		t.Error("hibernated crew is running")
+	}
+	if _, ok := state.Hibernating["gastown-crew-max"]; !ok {
+		t.Errorf("crew no longer hibernating: %v", state.Hibernating)
+	}
+	if strings.Contains(logged.String(), "start gastown-crew-max") {
+		t.Errorf("reconciler tried to start hibernated crew:\n%s", logged.String())
+	}
+}
@@ -0,0 +1,405 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/state"
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+// Decision recording. With --record the daemon writes everything it learns
+// from the outside world to a session file, one JSON entry per line: every
+// tmux, mail, and bd call with its answer, and at the start of each
+// lifecycle pass a snapshot of the files its decisions read (daemon.json,
+// rigs.json, role plugins, daemon state, agent state files). 'gt daemon
+// replay' re-runs the recorded lifecycle passes against that input (see
+// replay.go), to answer "why did the daemon kill my agent" after the fact.
+
+// Recording entry kinds.
+const (
+	RecordPass = "pass" // a lifecycle pass begins; carries the file snapshot
+	RecordCall = "call" // a backend call and its answer
+)
+
+// Recorded backends.
+const (
+	RecordSessions = "sessions"
+	RecordMail     = "mail"
+	RecordBeads    = "beads"
+)
+
+// RecordEntry is one line of a recording.
+type RecordEntry struct {
+	Seq  int       `json:"seq"`
+	At   time.Time `json:"at"`
+	Kind string    `json:"kind"`
+
+	// Pass numbers the lifecycle pass a call was made in, 0 outside passes.
+	Pass int `json:"pass,omitempty"`
+
+	Backend string          `json:"backend,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Args    []string        `json:"args,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+
+	// TownRoot and Files are set on pass entries: the town the recording
+	// was made in, and the town-relative paths and contents of the files
+	// the pass's decisions read.
+	TownRoot string            `json:"town_root,omitempty"`
+	Files    map[string]string `json:"files,omitempty"`
+}
+
+// LoadRecording reads a recording.
+func LoadRecording(path string) ([]RecordEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []RecordEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry RecordEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// recorder appends entries to a recording. Entries are written as they
+// happen, so a daemon that crashes leaves a usable recording.
+type recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int
+	pass int
+	open bool // a lifecycle pass is in progress
+	mute bool // drop calls (the daemon is snapshotting its own input)
+}
+
+// openRecorder opens a recording for appending, creating its directory.
+func openRecorder(path string) (*recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{file: f}
+	// Continue numbering after an earlier session in the same file
+	if entries, err := LoadRecording(path); err == nil {
+		for _, e := range entries {
+			r.seq = max(r.seq, e.Seq)
+			r.pass = max(r.pass, e.Pass)
+		}
+	}
+	return r, nil
+}
+
+func (r *recorder) write(entry RecordEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mute && entry.Kind == RecordCall {
+		return
+	}
+	r.seq++
+	entry.Seq = r.seq
+	entry.At = time.Now().UTC()
+	if entry.Kind == RecordPass {
+		r.pass++
+		r.open = true
+	}
+	if r.open {
+		entry.Pass = r.pass
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	_, _ = r.file.Write(append(data, '\n'))
+}
+
+// call records a backend call. result is encoded as JSON.
+func (r *recorder) call(backend, method string, args []string, result any, err error) {
+	entry := RecordEntry{Kind: RecordCall, Backend: backend, Method: method, Args: args}
+	if result != nil {
+		entry.Result, _ = json.Marshal(result)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.write(entry)
+}
+
+// setMute starts or stops dropping calls.
+func (r *recorder) setMute(mute bool) {
+	r.mu.Lock()
+	r.mute = mute
+	r.mu.Unlock()
+}
+
+// endPass marks the end of the current lifecycle pass.
+func (r *recorder) endPass() {
+	r.mu.Lock()
+	r.open = false
+	r.mu.Unlock()
+}
+
+func (r *recorder) close() {
+	_ = r.file.Close()
+}
+
+// startRecording routes the daemon's session, mail, and bd backends
+// through rec.
+func (d *Daemon) startRecording(rec *recorder) {
+	d.recorder = rec
+	d.tmux = &recordingSessions{inner: d.tmux, rec: rec}
+	d.mail = &recordingMail{inner: d.mailClient(), rec: rec}
+	d.beads = &recordingBeads{inner: d.beadsClient(), rec: rec}
+}
+
+// beginRecordedPass starts a lifecycle pass in the recording, snapshotting
+// the files its decisions read. The returned func ends the pass.
+func (d *Daemon) beginRecordedPass() func() {
+	if d.recorder == nil {
+		return func() {}
+	}
+	// Finding the agent state files asks bd; those answers belong to no pass
+	d.recorder.setMute(true)
+	files := d.decisionFiles()
+	d.recorder.setMute(false)
+	d.recorder.write(RecordEntry{Kind: RecordPass, TownRoot: d.config.TownRoot, Files: files})
+	return d.recorder.endPass
+}
+
+// decisionFiles returns the town-relative paths and contents of the files
+// lifecycle decisions read. Agent state is included only for the file
+// backend; missing files are left out.
+func (d *Daemon) decisionFiles() map[string]string {
+	townRoot := d.config.TownRoot
+	paths := append(configFiles(townRoot), StateFile(townRoot), filepath.Join(townRoot, "mayor", "town.json"))
+	if _, ok := d.agentStates().(state.FileBackend); ok {
+		for _, identity := range d.managedIdentities() {
+			if workDir := d.agentWorkDir(identity); workDir != "" {
+				paths = append(paths, agentStateFile(workDir))
+			}
+		}
+	}
+
+	files := make(map[string]string)
+	for _, path := range paths {
+		rel, err := filepath.Rel(townRoot, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			files[filepath.ToSlash(rel)] = string(data)
+		}
+	}
+	return files
+}
+
+// recordingSessions records the calls to a session backend.
+type recordingSessions struct {
+	inner SessionBackend
+	rec   *recorder
+}
+
+func (s *recordingSessions) IsAvailable() bool {
+	ok := s.inner.IsAvailable()
+	s.rec.call(RecordSessions, "IsAvailable", nil, ok, nil)
+	return ok
+}
+
+func (s *recordingSessions) HasSession(name string) (bool, error) {
+	ok, err := s.inner.HasSession(name)
+	s.rec.call(RecordSessions, "HasSession", []string{name}, ok, err)
+	return ok, err
+}
+
+func (s *recordingSessions) ListSessions() ([]string, error) {
+	names, err := s.inner.ListSessions()
+	s.rec.call(RecordSessions, "ListSessions", nil, names, err)
+	return names, err
+}
+
+func (s *recordingSessions) EnsureSessionFresh(name, workDir string) error {
+	err := s.inner.EnsureSessionFresh(name, workDir)
+	s.rec.call(RecordSessions, "EnsureSessionFresh", []string{name, workDir}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) KillSession(name string) error {
+	err := s.inner.KillSession(name)
+	s.rec.call(RecordSessions, "KillSession", []string{name}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) KillSessionWithProcesses(name string) error {
+	err := s.inner.KillSessionWithProcesses(name)
+	s.rec.call(RecordSessions, "KillSessionWithProcesses", []string{name}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) GetPanePID(session string) (string, error) {
+	pid, err := s.inner.GetPanePID(session)
+	s.rec.call(RecordSessions, "GetPanePID", []string{session}, pid, err)
+	return pid, err
+}
+
+func (s *recordingSessions) SessionActivity(session string) (time.Time, error) {
+	at, err := s.inner.SessionActivity(session)
+	s.rec.call(RecordSessions, "SessionActivity", []string{session}, at, err)
+	return at, err
+}
+
+func (s *recordingSessions) RenameSession(oldName, newName string) error {
+	err := s.inner.RenameSession(oldName, newName)
+	s.rec.call(RecordSessions, "RenameSession", []string{oldName, newName}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) SetEnvironment(session, key, value string) error {
+	err := s.inner.SetEnvironment(session, key, value)
+	s.rec.call(RecordSessions, "SetEnvironment", []string{session, key, value}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) ConfigureGasTownSession(session string, theme tmux.Theme, rig, worker, role string) error {
+	err := s.inner.ConfigureGasTownSession(session, theme, rig, worker, role)
+	s.rec.call(RecordSessions, "ConfigureGasTownSession", []string{session, rig, worker, role}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) ApplyLayout(session, workDir string, layout tmux.SessionLayout) error {
+	err := s.inner.ApplyLayout(session, workDir, layout)
+	s.rec.call(RecordSessions, "ApplyLayout", []string{session, workDir}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) SendKeys(session, keys string) error {
+	err := s.inner.SendKeys(session, keys)
+	s.rec.call(RecordSessions, "SendKeys", []string{session, keys}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) SafeSendKeys(target, text string, timeout time.Duration) error {
+	sender, ok := s.inner.(safeSender)
+	if !ok {
+		return s.SendKeys(target, text)
+	}
+	err := sender.SafeSendKeys(target, text, timeout)
+	s.rec.call(RecordSessions, "SafeSendKeys", []string{target, text}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) NudgeSession(session, message string) error {
+	err := s.inner.NudgeSession(session, message)
+	s.rec.call(RecordSessions, "NudgeSession", []string{session, message}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) WaitForCommand(session string, excludeCommands []string, timeout time.Duration) error {
+	err := s.inner.WaitForCommand(session, excludeCommands, timeout)
+	s.rec.call(RecordSessions, "WaitForCommand", []string{session}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) AcceptBypassPermissionsWarning(session string) error {
+	err := s.inner.AcceptBypassPermissionsWarning(session)
+	s.rec.call(RecordSessions, "AcceptBypassPermissionsWarning", []string{session}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) IsClaudeRunning(session string) bool {
+	ok := s.inner.IsClaudeRunning(session)
+	s.rec.call(RecordSessions, "IsClaudeRunning", []string{session}, ok, nil)
+	return ok
+}
+
+func (s *recordingSessions) IsPanePiped(session string) (bool, error) {
+	ok, err := s.inner.IsPanePiped(session)
+	s.rec.call(RecordSessions, "IsPanePiped", []string{session}, ok, err)
+	return ok, err
+}
+
+func (s *recordingSessions) PipePane(session, command string) error {
+	err := s.inner.PipePane(session, command)
+	s.rec.call(RecordSessions, "PipePane", []string{session, command}, nil, err)
+	return err
+}
+
+func (s *recordingSessions) SetPaneDiedHook(session, agentID string) error {
+	err := s.inner.SetPaneDiedHook(session, agentID)
+	s.rec.call(RecordSessions, "SetPaneDiedHook", []string{session, agentID}, nil, err)
+	return err
+}
+
+// recordingMail records the calls to a mail client.
+type recordingMail struct {
+	inner MailClient
+	rec   *recorder
+}
+
+func (m *recordingMail) Inbox(identity string) ([]BeadsMessage, error) {
+	messages, err := m.inner.Inbox(identity)
+	m.rec.call(RecordMail, "Inbox", []string{identity}, messages, err)
+	return messages, err
+}
+
+func (m *recordingMail) UnreadInbox(identity string, subjectPrefixes ...string) ([]BeadsMessage, error) {
+	filtered, ok := m.inner.(unreadInbox)
+	if !ok {
+		return m.Inbox(identity)
+	}
+	messages, err := filtered.UnreadInbox(identity, subjectPrefixes...)
+	m.rec.call(RecordMail, "UnreadInbox", append([]string{identity}, subjectPrefixes...), messages, err)
+	return messages, err
+}
+
+func (m *recordingMail) Delete(id string) error {
+	err := m.inner.Delete(id)
+	m.rec.call(RecordMail, "Delete", []string{id}, nil, err)
+	return err
+}
+
+func (m *recordingMail) Send(to, subject, body string) error {
+	err := m.inner.Send(to, subject, body)
+	m.rec.call(RecordMail, "Send", []string{to, subject, body}, nil, err)
+	return err
+}
+
+// recordingBeads records the calls to a bd client.
+type recordingBeads struct {
+	inner BeadsClient
+	rec   *recorder
+}
+
+func (b *recordingBeads) RoleConfig(roleBeadID string) (*beads.RoleConfig, error) {
+	cfg, err := b.inner.RoleConfig(roleBeadID)
+	b.rec.call(RecordBeads, "RoleConfig", []string{roleBeadID}, cfg, err)
+	return cfg, err
+}
+
+func (b *recordingBeads) Run(dir string, args ...string) ([]byte, error) {
+	out, err := b.inner.Run(dir, args...)
+	b.rec.call(RecordBeads, "Run", append([]string{dir}, args...), string(out), err)
+	return out, err
+}
@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// Config hot reload. The daemon reloads its configuration without a
+// restart when it gets SIGHUP ('gt daemon reload') or when it notices one
+// of its config files change: mayor/daemon.json (schedules, thresholds,
+// notification sinks, every patrol section), mayor/rigs.json (rig
+// aliases), and roles/*/role.json (role plugins). Files are watched by
+// polling their size and modification time.
+//
+// A reload is all or nothing: if daemon.json doesn't parse, any section
+// fails validation, or any role plugin is broken, the new config is
+// rejected, the error is logged and recorded for 'gt daemon status', and
+// the running config stays in effect. Sections that can only change with a
+// restart (session naming, tmux server, API listener, agent state backend,
+// tracing) keep their old values, with a warning.
+
+// configWatchInterval is how often the config files are checked for changes.
+const configWatchInterval = 5 * time.Second
+
+// ConfigReload records the outcome of the last config reload.
+type ConfigReload struct {
+	At      time.Time `json:"at"`
+	Trigger string    `json:"trigger"` // "signal" or "file change"
+	Error   string    `json:"error,omitempty"`
+
+	// NeedsRestart lists changed sections that only take effect when the
+	// daemon restarts.
+	NeedsRestart []string `json:"needs_restart,omitempty"`
+}
+
+// ReadPatrolConfig reads mayor/daemon.json, reporting parse errors that
+// LoadPatrolConfig swallows. Returns nil with no error if there is no file.
+func ReadPatrolConfig(townRoot string) (*DaemonPatrolConfig, error) {
+	data, err := os.ReadFile(PatrolConfigFile(townRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var config DaemonPatrolConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", PatrolConfigFile(townRoot), err)
+	}
+	return &config, nil
+}
+
+// Validate checks every section of the config. At startup invalid optional
+// sections are disabled with a warning; a reload rejects them instead.
+func (c *DaemonPatrolConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	errs := []error{
+		c.sessionNaming().Validate(),
+		c.Notifications.Validate(),
+		c.Tracing.Validate(),
+		c.Rollup.Validate(),
+		c.AgentState.Validate(),
+		c.SelfUpdate.Validate(),
+		c.MailRetention.Validate(),
+		c.DiskQuota.Validate(),
+		c.CapabilityRouting.Validate(),
+		c.Hibernate.Validate(),
+		c.Containers.Validate(),
+		c.RigProvisioning.Validate(),
+		c.SessionGC.Validate(),
+	}
+	for i, s := range c.Schedules {
+		if s == nil {
+			continue
+		}
+		if _, err := s.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("schedules[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restartOnlySections returns the config sections a reload can't apply,
+// by JSON name.
+func restartOnlySections(c *DaemonPatrolConfig) map[string]any {
+	if c == nil {
+		c = &DaemonPatrolConfig{}
+	}
+	return map[string]any{
+		"session_naming": c.SessionNaming,
+		"tmux":           c.Tmux,
+		"api":            c.API,
+		"agent_state":    c.AgentState,
+		"tracing":        c.Tracing,
+	}
+}
+
+// keepRestartOnlySections copies the restart-only sections of old into
+// next and returns the names of those that differed.
+func keepRestartOnlySections(old, next *DaemonPatrolConfig) []string {
+	oldSections, nextSections := restartOnlySections(old), restartOnlySections(next)
+	var changed []string
+	for name, value := range oldSections {
+		if !reflect.DeepEqual(value, nextSections[name]) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	if old == nil {
+		old = &DaemonPatrolConfig{}
+	}
+	next.SessionNaming = old.SessionNaming
+	next.Tmux = old.Tmux
+	next.API = old.API
+	next.AgentState = old.AgentState
+	next.Tracing = old.Tracing
+	return changed
+}
+
+// configFiles returns the files a reload reads.
+func configFiles(townRoot string) []string {
+	files := []string{PatrolConfigFile(townRoot), filepath.Join(townRoot, "mayor", "rigs.json")}
+	plugins, _ := filepath.Glob(filepath.Join(townRoot, RolesDir, "*", rolePluginFile))
+	return append(files, plugins...)
+}
+
+// configFingerprint summarizes the size and modification time of the
+// config files, so a change to any of them (or a file appearing or
+// disappearing) changes it.
+func configFingerprint(townRoot string) string {
+	var b strings.Builder
+	for _, path := range configFiles(townRoot) {
+		b.WriteString(path)
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, ":%d:%d", info.Size(), info.ModTime().UnixNano())
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// loadTownConfig reads and validates everything a reload applies. The
+// config is never nil.
+func loadTownConfig(townRoot string) (*DaemonPatrolConfig, []*RolePlugin, error) {
+	cfg, err := ReadPatrolConfig(townRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", PatrolConfigFile(townRoot), err)
+	}
+	plugins, errs := LoadRolePlugins(townRoot)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("role plugins: %w", errors.Join(errs...))
+	}
+	if cfg == nil {
+		cfg = &DaemonPatrolConfig{}
+	}
+	return cfg, plugins, nil
+}
+
+// CheckConfig validates the town's daemon config and role plugins as a
+// reload would, without applying them.
+func CheckConfig(townRoot string) error {
+	_, _, err := loadTownConfig(townRoot)
+	return err
+}
+
+// checkConfigFiles reloads the config if a config file changed since the
+// last check.
+func (d *Daemon) checkConfigFiles(state *State) {
+	fingerprint := configFingerprint(d.config.TownRoot)
+	if d.configFingerprint == "" || fingerprint == d.configFingerprint {
+		d.configFingerprint = fingerprint
+		return
+	}
+	d.configFingerprint = fingerprint
+	_ = d.reloadConfig(state, "file change")
+}
+
+// reloadConfig reads, validates, and applies the town's configuration. On
+// any error the running configuration is kept.
+func (d *Daemon) reloadConfig(state *State, trigger string) error {
+	record := &ConfigReload{At: time.Now(), Trigger: trigger}
+	state.ConfigReload = record
+	defer func() {
+		if err := d.saveState(state, "daemon/reload"); err != nil {
+			d.logger.Printf("Warning: failed to save state: %v", err)
+		}
+	}()
+	reject := func(err error) error {
+		record.Error = err.Error()
+		d.logger.Printf("Config reload (%s) rejected, keeping the running config: %v", trigger, err)
+		d.notify(notifier.EventConfigRejected, map[string]string{"error": err.Error()})
+		return err
+	}
+
+	cfg, plugins, err := loadTownConfig(d.config.TownRoot)
+	if err != nil {
+		return reject(err)
+	}
+	record.NeedsRestart = keepRestartOnlySections(d.patrolConfig, cfg)
+	for _, name := range record.NeedsRestart {
+		d.logger.Printf("Warning: config reload: %s changed, takes effect when the daemon restarts", name)
+	}
+
+	d.patrolConfig = cfg
+	setRolePlugins(plugins)
+	registerRigAliases(d.config.TownRoot)
+	townName, _ := workspace.GetTownName(d.config.TownRoot)
+	d.notifier = notifier.New(cfg.Notifications, townName)
+	if d.restarts != nil {
+		history := d.restarts.lastRestart
+		d.restarts = d.newRestartThrottle()
+		d.restarts.lastRestart = history
+	}
+	d.configFingerprint = configFingerprint(d.config.TownRoot)
+	d.logger.Printf("Config reloaded (%s): %d role plugin(s), %d schedule(s)", trigger, len(plugins), len(cfg.Schedules))
+	return nil
+}
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writePatrolConfig(t *testing.T, townRoot, content string) {
+	t.Helper()
+	if err := os.WriteFile(PatrolConfigFile(townRoot), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPatrolConfigValidate(t *testing.T) {
+	var nilConfig *DaemonPatrolConfig
+	if err := nilConfig.Validate(); err != nil {
+		t.Errorf("nil config: %v", err)
+	}
+	bad := &DaemonPatrolConfig{Schedules: []*ActivitySchedule{{Hours: "nine to five"}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for invalid schedule hours")
+	}
+	bad = &DaemonPatrolConfig{Hibernate: &HibernateConfig{Enabled: true, IdleAfter: "soon"}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for invalid hibernate section")
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	d.patrolConfig = &DaemonPatrolConfig{SessionNaming: &SessionNamingConfig{Prefix: "gt-"}}
+	state := &State{}
+
+	// A valid change is applied; a restart-only change is held back.
+	writePatrolConfig(t, townRoot, `{
+		"session_naming": {"prefix": "town-"},
+		"hibernate": {"enabled": true, "idle_after": "90m"}
+	}`)
+	if err := d.reloadConfig(state, "signal"); err != nil {
+		t.Fatalf("reloadConfig: %v", err)
+	}
+	if cfg := d.patrolConfig.hibernateConfig(); cfg == nil || cfg.idleAfter() != 90*time.Minute {
+		t.Errorf("hibernate section not applied: %+v", d.patrolConfig.Hibernate)
+	}
+	if got := d.patrolConfig.SessionNaming.Prefix; got != "gt-" {
+		t.Errorf("session_naming prefix = %q, want it kept until restart", got)
+	}
+	if state.ConfigReload == nil || state.ConfigReload.Error != "" || !slices.Equal(state.ConfigReload.NeedsRestart, []string{"session_naming"}) {
+		t.Errorf("ConfigReload = %+v", state.ConfigReload)
+	}
+
+	// An invalid config is rejected and the running one kept.
+	running := d.patrolConfig
+	writePatrolConfig(t, townRoot, `{"hibernate": {"enabled": true, "idle_after": "soon"}}`)
+	if err := d.reloadConfig(state, "signal"); err == nil {
+		t.Fatal("expected invalid config to be rejected")
+	}
+	if d.patrolConfig != running {
+		t.Error("running config replaced by a rejected one")
+	}
+	if state.ConfigReload.Error == "" {
+		t.Error("rejection not recorded in state")
+	}
+
+	writePatrolConfig(t, townRoot, `{"hibernate": `)
+	if err := d.reloadConfig(state, "signal"); err == nil || d.patrolConfig != running {
+		t.Error("unparseable config not rejected")
+	}
+
+	loaded, err := LoadState(townRoot)
+	if err != nil || loaded.ConfigReload == nil || loaded.ConfigReload.Error == "" {
+		t.Errorf("reload outcome not saved: %+v, %v", loaded, err)
+	}
+}
+
+func TestCheckConfigFiles(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	d.patrolConfig = &DaemonPatrolConfig{}
+	state := &State{}
+
+	// The first check only takes the fingerprint.
+	d.checkConfigFiles(state)
+	if state.ConfigReload != nil {
+		t.Fatal("first check reloaded the config")
+	}
+
+	writePatrolConfig(t, townRoot, `{"hibernate": {"enabled": true}}`)
+	d.checkConfigFiles(state)
+	if state.ConfigReload == nil || state.ConfigReload.Trigger != "file change" {
+		t.Fatalf("ConfigReload = %+v, want a reload for the file change", state.ConfigReload)
+	}
+	if d.patrolConfig.hibernateConfig() == nil {
+		t.Error("changed file not applied")
+	}
+
+	at := state.ConfigReload.At
+	d.checkConfigFiles(state)
+	if !state.ConfigReload.At.Equal(at) {
+		t.Error("reloaded with no file change")
+	}
+}
@@ -0,0 +1,412 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+// Decision replay. Each recorded lifecycle pass (see recording.go) is
+// re-run in a sandbox town holding the pass's file snapshot, with session,
+// mail, and bd backends that answer from the recording. The replay is a
+// dry run: the decisions are made and logged exactly as they were, but
+// nothing is executed. Mail timestamps are shifted by the time since the
+// recording, so a request that was fresh then is fresh in the replay.
+
+// ReplayPass is the replay of one recorded lifecycle pass.
+type ReplayPass struct {
+	Pass int       `json:"pass"`
+	At   time.Time `json:"at"`
+
+	// Recorded lists what the daemon did in the pass: its session, mail,
+	// and bd calls that change something ("KillSession gt-gastown-crew-max").
+	Recorded []string `json:"recorded,omitempty"`
+
+	// Replayed lists the changing calls the replay made. A dry run makes
+	// few; its decisions are in Log.
+	Replayed []string `json:"replayed,omitempty"`
+
+	// Log is the replay's daemon log.
+	Log string `json:"log"`
+
+	// Missing lists the queries the replay made that the recording has no
+	// answer for: the replay took a path the recorded run didn't.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// ReplayReport is the outcome of a replay.
+type ReplayReport struct {
+	Passes []ReplayPass `json:"passes"`
+}
+
+// Replay re-runs the lifecycle passes of a recording, or only pass number
+// pass if it is positive.
+func Replay(entries []RecordEntry, pass int) (*ReplayReport, error) {
+	report := &ReplayReport{}
+	for _, entry := range entries {
+		if entry.Kind != RecordPass || (pass > 0 && entry.Pass != pass) {
+			continue
+		}
+		var calls []RecordEntry
+		for _, e := range entries {
+			if e.Kind == RecordCall && e.Pass == entry.Pass {
+				calls = append(calls, e)
+			}
+		}
+		result, err := replayPass(entry, calls)
+		if err != nil {
+			return nil, fmt.Errorf("replaying pass %d: %w", entry.Pass, err)
+		}
+		report.Passes = append(report.Passes, *result)
+	}
+	if pass > 0 && len(report.Passes) == 0 {
+		return nil, fmt.Errorf("the recording has no pass %d", pass)
+	}
+	return report, nil
+}
+
+// replayPass re-runs one lifecycle pass in a sandbox town.
+func replayPass(pass RecordEntry, calls []RecordEntry) (*ReplayPass, error) {
+	townRoot, err := os.MkdirTemp("", "gt-replay-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(townRoot)
+	for rel, data := range pass.Files {
+		path := filepath.Join(townRoot, filepath.FromSlash(rel))
+		if !strings.HasPrefix(path, townRoot+string(filepath.Separator)) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	src := newReplaySource(calls, pass.TownRoot, townRoot, time.Since(pass.At))
+	result := &ReplayPass{Pass: pass.Pass, At: pass.At}
+	for _, call := range calls {
+		if isChangingCall(call.Backend, call.Method, call.Args) {
+			result.Recorded = append(result.Recorded, describeCall(call.Method, call.Args))
+		}
+	}
+
+	var logBuf bytes.Buffer
+	d := NewWithBackends(&Config{TownRoot: townRoot, DryRun: true}, Backends{
+		Sessions:    &replaySessions{src: src},
+		Mail:        &replayMail{src: src},
+		Beads:       &replayBeads{src: src},
+		Containers:  replayNoop{src: src},
+		Provisioner: replayNoop{src: src},
+		Sleep:       func(time.Duration) {},
+	}, log.New(&logBuf, "", 0))
+	d.ProcessLifecycleRequests()
+
+	result.Log = strings.ReplaceAll(logBuf.String(), townRoot, pass.TownRoot)
+	result.Replayed = src.changes
+	result.Missing = src.missing
+	return result, nil
+}
+
+// replaySource answers backend calls from a pass's recorded calls. Each
+// query gets the recorded answers to the same call in order; once they run
+// out, the last one is repeated.
+type replaySource struct {
+	answers  map[string][]RecordEntry
+	last     map[string]RecordEntry
+	fromRoot string // the recorded town root
+	toRoot   string // the sandbox town root
+	shift    time.Duration
+	changes  []string
+	missing  []string
+}
+
+func newReplaySource(calls []RecordEntry, fromRoot, toRoot string, shift time.Duration) *replaySource {
+	src := &replaySource{
+		answers:  make(map[string][]RecordEntry),
+		last:     make(map[string]RecordEntry),
+		fromRoot: fromRoot,
+		toRoot:   toRoot,
+		shift:    shift,
+	}
+	for _, call := range calls {
+		key := callKey(call.Backend, call.Method, call.Args)
+		src.answers[key] = append(src.answers[key], call)
+	}
+	return src
+}
+
+func callKey(backend, method string, args []string) string {
+	return backend + "\x00" + method + "\x00" + strings.Join(args, "\x00")
+}
+
+// answer decodes the recorded result of a call into out (if not nil) and
+// returns its recorded error. Changing calls are noted, and succeed unless
+// the recorded one failed.
+func (s *replaySource) answer(backend, method string, args []string, out any) error {
+	// Paths in the sandbox are recorded under the real town root
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, s.toRoot, s.fromRoot)
+	}
+	changing := isChangingCall(backend, method, args)
+	if changing {
+		s.changes = append(s.changes, describeCall(method, args))
+	}
+
+	key := callKey(backend, method, args)
+	entry, ok := s.last[key]
+	if queue := s.answers[key]; len(queue) > 0 {
+		entry, ok = queue[0], true
+		s.answers[key] = queue[1:]
+		s.last[key] = entry
+	}
+	if !ok {
+		if changing {
+			return nil
+		}
+		s.missing = append(s.missing, describeCall(method, args))
+		return fmt.Errorf("replay: no recorded answer to %s", describeCall(method, args))
+	}
+	if out != nil && len(entry.Result) > 0 {
+		if err := json.Unmarshal(entry.Result, out); err != nil {
+			return fmt.Errorf("replay: decoding recorded %s: %w", method, err)
+		}
+	}
+	if entry.Error != "" {
+		return errors.New(entry.Error)
+	}
+	return nil
+}
+
+// shiftMessages moves message timestamps forward by the time since the
+// recording.
+func (s *replaySource) shiftMessages(messages []BeadsMessage) {
+	for i := range messages {
+		if t, err := time.Parse(time.RFC3339, messages[i].Timestamp); err == nil {
+			messages[i].Timestamp = t.Add(s.shift).Format(time.RFC3339)
+		}
+	}
+}
+
+// readOnlyBdCommands are the bd subcommands that change nothing.
+var readOnlyBdCommands = map[string]bool{
+	"show": true, "list": true, "ready": true, "search": true, "count": true,
+	"stats": true, "info": true, "status": true, "version": true, "blocked": true,
+}
+
+// isChangingCall reports whether a backend call changes something, as
+// opposed to asking something.
+func isChangingCall(backend, method string, args []string) bool {
+	switch backend {
+	case RecordSessions:
+		switch method {
+		case "IsAvailable", "HasSession", "ListSessions", "GetPanePID", "SessionActivity",
+			"IsClaudeRunning", "IsPanePiped", "WaitForCommand":
+			return false
+		}
+		return true
+	case RecordMail:
+		return method == "Send" || method == "Delete"
+	case RecordBeads:
+		// Run's args are the directory, then bd's arguments
+		return method == "Run" && len(args) > 1 && !readOnlyBdCommands[args[1]]
+	}
+	return true
+}
+
+// describeCall renders a call on one line, abbreviating long arguments
+// (message bodies, nudge text).
+func describeCall(method string, args []string) string {
+	parts := []string{method}
+	for _, arg := range args {
+		arg = strings.Join(strings.Fields(arg), " ")
+		if len(arg) > 60 {
+			arg = arg[:57] + "..."
+		}
+		if strings.ContainsAny(arg, " \t") || arg == "" {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// replaySessions is the session backend of a replay.
+type replaySessions struct {
+	src *replaySource
+}
+
+func (s *replaySessions) IsAvailable() bool {
+	var ok bool
+	_ = s.src.answer(RecordSessions, "IsAvailable", nil, &ok)
+	return ok
+}
+
+func (s *replaySessions) HasSession(name string) (bool, error) {
+	var ok bool
+	err := s.src.answer(RecordSessions, "HasSession", []string{name}, &ok)
+	return ok, err
+}
+
+func (s *replaySessions) ListSessions() ([]string, error) {
+	var names []string
+	err := s.src.answer(RecordSessions, "ListSessions", nil, &names)
+	return names, err
+}
+
+func (s *replaySessions) EnsureSessionFresh(name, workDir string) error {
+	return s.src.answer(RecordSessions, "EnsureSessionFresh", []string{name, workDir}, nil)
+}
+
+func (s *replaySessions) KillSession(name string) error {
+	return s.src.answer(RecordSessions, "KillSession", []string{name}, nil)
+}
+
+func (s *replaySessions) KillSessionWithProcesses(name string) error {
+	return s.src.answer(RecordSessions, "KillSessionWithProcesses", []string{name}, nil)
+}
+
+func (s *replaySessions) GetPanePID(session string) (string, error) {
+	var pid string
+	err := s.src.answer(RecordSessions, "GetPanePID", []string{session}, &pid)
+	return pid, err
+}
+
+func (s *replaySessions) SessionActivity(session string) (time.Time, error) {
+	var at time.Time
+	err := s.src.answer(RecordSessions, "SessionActivity", []string{session}, &at)
+	if !at.IsZero() {
+		at = at.Add(s.src.shift)
+	}
+	return at, err
+}
+
+func (s *replaySessions) RenameSession(oldName, newName string) error {
+	return s.src.answer(RecordSessions, "RenameSession", []string{oldName, newName}, nil)
+}
+
+func (s *replaySessions) SetEnvironment(session, key, value string) error {
+	return s.src.answer(RecordSessions, "SetEnvironment", []string{session, key, value}, nil)
+}
+
+func (s *replaySessions) ConfigureGasTownSession(session string, theme tmux.Theme, rig, worker, role string) error {
+	return s.src.answer(RecordSessions, "ConfigureGasTownSession", []string{session, rig, worker, role}, nil)
+}
+
+func (s *replaySessions) ApplyLayout(session, workDir string, layout tmux.SessionLayout) error {
+	return s.src.answer(RecordSessions, "ApplyLayout", []string{session, workDir}, nil)
+}
+
+func (s *replaySessions) SendKeys(session, keys string) error {
+	return s.src.answer(RecordSessions, "SendKeys", []string{session, keys}, nil)
+}
+
+func (s *replaySessions) NudgeSession(session, message string) error {
+	return s.src.answer(RecordSessions, "NudgeSession", []string{session, message}, nil)
+}
+
+func (s *replaySessions) WaitForCommand(session string, excludeCommands []string, timeout time.Duration) error {
+	return s.src.answer(RecordSessions, "WaitForCommand", []string{session}, nil)
+}
+
+func (s *replaySessions) AcceptBypassPermissionsWarning(session string) error {
+	return s.src.answer(RecordSessions, "AcceptBypassPermissionsWarning", []string{session}, nil)
+}
+
+func (s *replaySessions) IsClaudeRunning(session string) bool {
+	var ok bool
+	_ = s.src.answer(RecordSessions, "IsClaudeRunning", []string{session}, &ok)
+	return ok
+}
+
+func (s *replaySessions) IsPanePiped(session string) (bool, error) {
+	var ok bool
+	err := s.src.answer(RecordSessions, "IsPanePiped", []string{session}, &ok)
+	return ok, err
+}
+
+func (s *replaySessions) PipePane(session, command string) error {
+	return s.src.answer(RecordSessions, "PipePane", []string{session, command}, nil)
+}
+
+func (s *replaySessions) SetPaneDiedHook(session, agentID string) error {
+	return s.src.answer(RecordSessions, "SetPaneDiedHook", []string{session, agentID}, nil)
+}
+
+// replayMail is the mail client of a replay.
+type replayMail struct {
+	src *replaySource
+}
+
+func (m *replayMail) Inbox(identity string) ([]BeadsMessage, error) {
+	var messages []BeadsMessage
+	err := m.src.answer(RecordMail, "Inbox", []string{identity}, &messages)
+	m.src.shiftMessages(messages)
+	return messages, err
+}
+
+func (m *replayMail) UnreadInbox(identity string, subjectPrefixes ...string) ([]BeadsMessage, error) {
+	args := append([]string{identity}, subjectPrefixes...)
+	// A recording made through an unfiltered mail client has Inbox calls
+	if _, ok := m.src.answers[callKey(RecordMail, "UnreadInbox", args)]; !ok {
+		return m.Inbox(identity)
+	}
+	var messages []BeadsMessage
+	err := m.src.answer(RecordMail, "UnreadInbox", args, &messages)
+	m.src.shiftMessages(messages)
+	return messages, err
+}
+
+func (m *replayMail) Delete(id string) error {
+	return m.src.answer(RecordMail, "Delete", []string{id}, nil)
+}
+
+func (m *replayMail) Send(to, subject, body string) error {
+	return m.src.answer(RecordMail, "Send", []string{to, subject, body}, nil)
+}
+
+// replayBeads is the bd client of a replay.
+type replayBeads struct {
+	src *replaySource
+}
+
+func (b *replayBeads) RoleConfig(roleBeadID string) (*beads.RoleConfig, error) {
+	var cfg *beads.RoleConfig
+	err := b.src.answer(RecordBeads, "RoleConfig", []string{roleBeadID}, &cfg)
+	return cfg, err
+}
+
+func (b *replayBeads) Run(dir string, args ...string) ([]byte, error) {
+	var out string
+	err := b.src.answer(RecordBeads, "Run", append([]string{dir}, args...), &out)
+	return []byte(out), err
+}
+
+// replayNoop stands in for the container and provisioning backends, which
+// aren't recorded: a replay must never start or remove anything.
+type replayNoop struct {
+	src *replaySource
+}
+
+func (n replayNoop) Remove(name string) error {
+	n.src.changes = append(n.src.changes, describeCall("RemoveContainer", []string{name}))
+	return nil
+}
+
+func (n replayNoop) List(string) ([]string, error) { return nil, nil }
+
+func (n replayNoop) Provision(townRoot, dir string) error {
+	n.src.changes = append(n.src.changes, describeCall("ProvisionRig", []string{dir}))
+	return nil
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inboxMail serves a fixed deacon inbox.
+type inboxMail struct {
+	sentMail
+	messages []BeadsMessage
+}
+
+func (m *inboxMail) Inbox(string) ([]BeadsMessage, error) { return m.messages, nil }
+
+// recordShutdown records a lifecycle pass in which the daemon shuts down
+// gastown's witness on its request.
+func recordShutdown(t *testing.T) (string, []RecordEntry) {
+	t.Helper()
+	d, _ := testDaemonWithTown(t, "testtown")
+	d.tmux = &hibernateTmux{running: map[string]bool{"gt-gastown-witness": true}}
+	d.mail = &inboxMail{messages: []BeadsMessage{{
+		ID:        "msg-1",
+		From:      "gastown-witness",
+		Subject:   "LIFECYCLE: action",
+		Body:      `{"action": "shutdown"}`,
+		Timestamp: time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}}}
+	d.beads = &hibernateBeads{}
+
+	path := filepath.Join(t.TempDir(), "daemon.rec")
+	rec, err := openRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.startRecording(rec)
+	d.ProcessLifecycleRequests()
+	rec.close()
+
+	entries, err := LoadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.config.TownRoot, entries
+}
+
+func TestRecording(t *testing.T) {
+	townRoot, entries := recordShutdown(t)
+	if len(entries) < 2 || entries[0].Kind != RecordPass {
+		t.Fatalf("entries = %+v, want a pass and its calls", entries)
+	}
+	pass := entries[0]
+	if pass.Pass != 1 || pass.TownRoot != townRoot || pass.Files["mayor/town.json"] == "" {
+		t.Errorf("pass = %+v, want pass 1 of %s with town.json", pass, townRoot)
+	}
+
+	var calls []string
+	for _, e := range entries[1:] {
+		if e.Kind != RecordCall || e.Pass != 1 {
+			t.Errorf("entry %+v, want a call in pass 1", e)
+		}
+		calls = append(calls, describeCall(e.Method, e.Args))
+	}
+	if !slices.Contains(calls, "Inbox deacon/") || !slices.Contains(calls, "KillSession gt-gastown-witness") {
+		t.Errorf("calls = %v, want the inbox fetch and the kill", calls)
+	}
+
+	// A second session in the same file continues the numbering
+	path := filepath.Join(t.TempDir(), "daemon.rec")
+	for i := 0; i < 2; i++ {
+		rec, err := openRecorder(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.write(RecordEntry{Kind: RecordPass})
+		rec.close()
+	}
+	entries, err := LoadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Seq != 2 || entries[1].Pass != 2 {
+		t.Errorf("entries = %+v, want seq and pass 2 after reopening", entries)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	_, entries := recordShutdown(t)
+
+	report, err := Replay(entries, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Passes) != 1 {
+		t.Fatalf("passes = %+v, want one", report.Passes)
+	}
+	pass := report.Passes[0]
+	if !slices.Contains(pass.Recorded, "KillSession gt-gastown-witness") {
+		t.Errorf("recorded = %v, want the kill", pass.Recorded)
+	}
+	if slices.Contains(pass.Replayed, "KillSession gt-gastown-witness") {
+		t.Errorf("replayed = %v, a replay must not kill", pass.Replayed)
+	}
+	if !strings.Contains(pass.Log, "[dry-run]") || !strings.Contains(pass.Log, "gastown-witness") {
+		t.Errorf("log = %q, want the dry-run decision", pass.Log)
+	}
+	if len(pass.Missing) != 0 {
+		t.Errorf("missing = %v, want every query answered", pass.Missing)
+	}
+
+	if _, err := Replay(entries, 2); err == nil {
+		t.Error("Replay of a pass not in the recording succeeded")
+	}
+}
+
+func TestIsChangingCall(t *testing.T) {
+	tests := []struct {
+		backend, method string
+		args            []string
+		want            bool
+	}{
+		{RecordSessions, "HasSession", []string{"gt-mayor"}, false},
+		{RecordSessions, "KillSession", []string{"gt-mayor"}, true},
+		{RecordMail, "Inbox", []string{"deacon/"}, false},
+		{RecordMail, "Delete", []string{"msg-1"}, true},
+		{RecordBeads, "Run", []string{"/town", "show", "gt-1"}, false},
+		{RecordBeads, "Run", []string{"/town", "update", "gt-1"}, true},
+	}
+	for _, tc := range tests {
+		if got := isChangingCall(tc.backend, tc.method, tc.args); got != tc.want {
+			t.Errorf("isChangingCall(%s, %s, %v) = %v, want %v", tc.backend, tc.method, tc.args, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// The restart history records every cycle and restart the daemon carries
+// out: who it was for, who asked and why, how long it took, and whether it
+// worked. It is an append-only log in the town store, read back by
+// 'gt agent history' so an operator can see that a crew member has been
+// cycled a dozen times today and what kept asking for it.
+
+// restartHistoryKey is the town store key of the restart history log.
+const restartHistoryKey = "daemon/restarts.jsonl"
+
+// Requesters recorded for restarts the daemon decides on by itself.
+const (
+	requestedByHeartbeat     = "daemon/heartbeat"
+	requestedByEscalation    = "daemon/escalation"
+	requestedByContextBudget = "daemon/context-budget"
+	requestedByStaleFlag     = "daemon/stale-flag"
+)
+
+// RestartRecord is one restart of an agent.
+type RestartRecord struct {
+	Time     time.Time       `json:"ts"`
+	Identity string          `json:"identity"`
+	Session  string          `json:"session,omitempty"`
+	Action   LifecycleAction `json:"action"`
+
+	// RequestedBy is who asked for the restart: the agent itself, the
+	// sender of a batch, an API token's identity, or a daemon check
+	// ("daemon/heartbeat", ...).
+	RequestedBy string `json:"requested_by"`
+
+	// Reason, TaskBead, and Urgency are given with the request, if any.
+	Reason    string `json:"reason,omitempty"`
+	TaskBead  string `json:"task_bead,omitempty"`
+	Urgency   string `json:"urgency,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Duration is how long the kill and restart took.
+	Duration time.Duration `json:"duration"`
+
+	Outcome string `json:"outcome"` // AuditOutcomeOK or AuditOutcomeFailed
+	Error   string `json:"error,omitempty"`
+}
+
+// RestartQuery selects restart records. Zero fields match everything.
+type RestartQuery struct {
+	// Identity matches the agent restarted, in daemon or path form.
+	Identity string
+
+	// Since drops records older than this.
+	Since time.Time
+
+	// Limit keeps only the newest Limit records.
+	Limit int
+}
+
+// AppendRestartRecord adds rec to the town's restart history.
+func AppendRestartRecord(townRoot string, rec RestartRecord) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Time = rec.Time.UTC()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return store.Append(restartHistoryKey, append(data, '\n'))
+}
+
+// RestartHistory returns the restart records matching q, oldest first.
+// Unreadable lines are skipped.
+func RestartHistory(townRoot string, q RestartQuery) ([]RestartRecord, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(restartHistoryKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	identity := historyIdentity(q.Identity)
+	var records []RestartRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec RestartRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if identity != "" && historyIdentity(rec.Identity) != identity {
+			continue
+		}
+		if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
+
+// historyIdentity puts an identity in the form restart records are keyed
+// by, so "gastown/crew/max" and "gastown-crew-max" find the same history.
+func historyIdentity(identity string) string {
+	return normalizeIdentity(strings.TrimSuffix(identity, "/"))
+}
+
+// recordRestart adds a finished cycle or restart to the restart history.
+// Failures to write it are logged, never fatal.
+func (d *Daemon) recordRestart(request *LifecycleRequest, sessionName string, started time.Time, opErr error) {
+	rec := RestartRecord{
+		Time:        started,
+		Identity:    historyIdentity(request.From),
+		Session:     sessionName,
+		Action:      request.Action,
+		RequestedBy: request.requester(),
+		Reason:      request.Reason,
+		TaskBead:    request.TaskBead,
+		Urgency:     request.Urgency,
+		RequestID:   request.RequestID,
+		Duration:    time.Since(started),
+		Outcome:     AuditOutcomeOK,
+	}
+	if opErr != nil {
+		rec.Outcome = AuditOutcomeFailed
+		rec.Error = opErr.Error()
+	}
+	if err := AppendRestartRecord(d.config.TownRoot, rec); err != nil {
+		d.logger.Printf("Warning: failed to record %s of %s in restart history: %v", request.Action, request.From, err)
+	}
+}
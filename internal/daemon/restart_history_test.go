@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRestartHistory(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ghost")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+
+	start := time.Now().Add(-2 * time.Hour)
+	d.recordRestart(&LifecycleRequest{From: "gastown/crew/max", Action: ActionCycle, Reason: "context full", TaskBead: "gt-abc", Urgency: UrgencyHigh},
+		"gt-gastown-crew-max", start, nil)
+	d.recordRestart(&LifecycleRequest{From: "gastown-witness", Action: ActionRestart, RequestedBy: "mayor"},
+		"gt-gastown-witness", start.Add(time.Minute), nil)
+	d.recordRestart(&LifecycleRequest{From: "gastown-crew-max", Action: ActionCycle, RequestedBy: requestedByHeartbeat},
+		"gt-gastown-crew-max", start.Add(time.Hour), errors.New("session failed to start"))
+
+	records, err := RestartHistory(townRoot, RestartQuery{Identity: "gastown-crew-max"})
+	if err != nil {
+		t.Fatalf("RestartHistory: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records for gastown-crew-max, want 2: %+v", len(records), records)
+	}
+	first, second := records[0], records[1]
+	if first.Identity != "gastown-crew-max" || first.RequestedBy != "gastown/crew/max" || first.Reason != "context full" ||
+		first.TaskBead != "gt-abc" || first.Urgency != UrgencyHigh || first.Outcome != AuditOutcomeOK {
+		t.Errorf("first record = %+v", first)
+	}
+	if second.RequestedBy != requestedByHeartbeat || second.Outcome != AuditOutcomeFailed || second.Error == "" {
+		t.Errorf("second record = %+v", second)
+	}
+
+	// Path form finds the same history
+	if records, _ := RestartHistory(townRoot, RestartQuery{Identity: "gastown/crew/max"}); len(records) != 2 {
+		t.Errorf("path form: got %d records, want 2", len(records))
+	}
+
+	if records, _ := RestartHistory(townRoot, RestartQuery{Since: start.Add(30 * time.Minute)}); len(records) != 1 || records[0].Outcome != AuditOutcomeFailed {
+		t.Errorf("since: got %+v, want the failed cycle only", records)
+	}
+
+	records, _ = RestartHistory(townRoot, RestartQuery{Limit: 2})
+	if len(records) != 2 || records[0].Identity != "gastown-witness" {
+		t.Errorf("limit: got %+v, want the newest two", records)
+	}
+}
+
+func TestRestartHistoryEmpty(t *testing.T) {
+	records, err := RestartHistory(t.TempDir(), RestartQuery{})
+	if err != nil || records != nil {
+		t.Errorf("RestartHistory on a new town = %v, %v; want nil, nil", records, err)
+	}
+}
+
+func TestParseLifecycleBodyReason(t *testing.T) {
+	body, ok := parseKeyValueBody("action: cycle\nreason: stuck on a merge")
+	if !ok || body.Reason != "stuck on a merge" {
+		t.Errorf("kv body = %+v, %v; want reason", body, ok)
+	}
+	body, ok = parseKeywordBody("please cycle me, context is full")
+	if !ok || body.Reason != "please cycle me, context is full" {
+		t.Errorf("keyword body = %+v, %v; want the text as reason", body, ok)
+	}
+}
@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Post-restart smoke tests. An agent (or its role plugin, for every agent
+// of the role) can ship a smoke-test.sh lifecycle hook that checks a
+// freshly restarted session actually works: that `bd list` runs in the
+// workdir, or that the agent answers a ping typed into $GT_SESSION. The
+// restart only counts as successful once the smoke tests pass. A failure
+// restarts the session once more; a second failure is mailed to the
+// agent's witness (the mayor, for agents outside a rig) and fails the
+// restart with ErrSmokeTestFailed.
+
+// defaultSmokeTestTimeout bounds one smoke-test.sh run.
+const defaultSmokeTestTimeout = 2 * time.Minute
+
+// smokeTestTimeout returns the configured smoke test timeout.
+func (d *Daemon) smokeTestTimeout() time.Duration {
+	cfg := d.patrolConfig.lifecycleConfig()
+	if cfg.SmokeTestTimeout == "" {
+		return defaultSmokeTestTimeout
+	}
+	timeout, err := time.ParseDuration(cfg.SmokeTestTimeout)
+	if err != nil || timeout <= 0 {
+		d.logger.Printf("Warning: invalid lifecycle.smoke_test_timeout %q, using %v", cfg.SmokeTestTimeout, defaultSmokeTestTimeout)
+		return defaultSmokeTestTimeout
+	}
+	return timeout
+}
+
+// runSmokeTest runs the role plugin's smoke test, then the agent's own.
+// Returns nil if the agent has none, or the first failure with its output.
+func (d *Daemon) runSmokeTest(identity, sessionName string, action LifecycleAction) error {
+	config, parsed, err := d.getRoleConfigForIdentity(identity)
+	if err != nil {
+		return nil
+	}
+	workDir := d.getWorkDir(config, parsed)
+	if workDir == "" {
+		return nil
+	}
+
+	env := []string{
+		"GT_AGENT=" + identity,
+		"GT_SESSION=" + sessionName,
+		"GT_LIFECYCLE_ACTION=" + string(action),
+		"GT_TOWN_ROOT=" + d.config.TownRoot,
+	}
+	hooks := []string{filepath.Join(AgentHooksDir(workDir), HookSmokeTest)}
+	if p := lookupRolePlugin(parsed.RoleType); p != nil {
+		hooks = append([]string{filepath.Join(p.HooksDir(), HookSmokeTest)}, hooks...)
+	}
+	for _, hook := range hooks {
+		output, err := runHookScript(hook, workDir, env, d.smokeTestTimeout())
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			if output != "" {
+				return fmt.Errorf("%s: %w\n%s", hook, err, truncateHookOutput(output))
+			}
+			return fmt.Errorf("%s: %w", hook, err)
+		}
+		d.logger.Printf("Smoke test %s passed for %s", hook, identity)
+	}
+	return nil
+}
+
+// smokeTestRestart smoke tests a session restarted for request. A failure
+// gets one more restart; if that fails its smoke test too, the failure is
+// escalated and returned.
+func (d *Daemon) smokeTestRestart(request *LifecycleRequest, sessionName string) error {
+	span := d.startSpan("session.smoke_test")
+	err := d.runSmokeTest(request.From, sessionName, request.Action)
+	d.endSpan(span, err)
+	if err == nil {
+		return nil
+	}
+	d.logger.Printf("Smoke test failed for %s after restart, restarting again: %v", request.From, err)
+
+	killErr := d.runStep(StepKill, func(ctx context.Context) error { return d.sessionsFor(ctx).KillSession(sessionName) })
+	d.auditRequest(AuditKillSession, sessionName, request, []string{"smoke test failed after restart"}, killErr)
+	if killErr != nil {
+		return classify(ErrSessionBackend, fmt.Errorf("killing session that failed its smoke test: %w", killErr))
+	}
+	d.removeAgentContainer(sessionName)
+	d.awaitTeardown(request.From, sessionName)
+	if err := d.restartSession(sessionName, request.From); err != nil {
+		return fmt.Errorf("restarting session after failed smoke test: %w", err)
+	}
+
+	span = d.startSpan("session.smoke_test", "gt.retry", "true")
+	err = d.runSmokeTest(request.From, sessionName, request.Action)
+	d.endSpan(span, err)
+	if err == nil {
+		d.logger.Printf("Smoke test passed for %s on the second restart", request.From)
+		return nil
+	}
+	d.escalateSmokeTestFailure(request, sessionName, err)
+	return classify(ErrSmokeTestFailed, fmt.Errorf("session %s failed its smoke test twice: %w", sessionName, err))
+}
+
+// escalateSmokeTestFailure mails an agent's witness, or the mayor for
+// agents outside a rig, about a session that keeps failing its smoke test.
+func (d *Daemon) escalateSmokeTestFailure(request *LifecycleRequest, sessionName string, testErr error) {
+	to := "mayor/"
+	if parsed, err := parseIdentity(request.From); err == nil && parsed.RigName != "" && parsed.RoleType != "witness" {
+		to = parsed.RigName + "/witness"
+	}
+	subject := fmt.Sprintf("SMOKE_TEST_FAILED: %s", request.From)
+	body := fmt.Sprintf(`Agent %s was restarted (%s) and failed its smoke test, twice.
+
+session: %s
+error: %v
+
+The session is left running. Check it, and the agent's smoke-test.sh.`,
+		request.From, request.Action, sessionName, testErr)
+	if ctx := request.contextLines(); ctx != "" {
+		body += "\n\n" + ctx
+	}
+
+	if err := d.mailClient().Send(to, subject, body); err != nil {
+		d.logger.Printf("Warning: failed to escalate smoke test failure of %s: %v", request.From, err)
+		return
+	}
+	d.logger.Printf("Escalated smoke test failure of %s to %s", request.From, to)
+}
+
+// contextLines describes why the request was made, for mail bodies.
+func (r *LifecycleRequest) contextLines() string {
+	var lines []string
+	if r.Reason != "" {
+		lines = append(lines, "reason: "+r.Reason)
+	}
+	if r.TaskBead != "" {
+		lines = append(lines, "task_bead: "+r.TaskBead)
+	}
+	if r.Urgency != "" {
+		lines = append(lines, "urgency: "+r.Urgency)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSmokeTest(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	d.beads = &hibernateBeads{}
+	tmux := &hibernateTmux{running: map[string]bool{"hq-mayor": true}}
+	d.tmux = tmux
+	request := &LifecycleRequest{From: "mayor", Action: ActionRestart}
+
+	// No smoke test: the restart stands
+	if err := d.runSmokeTest("mayor", "hq-mayor", ActionRestart); err != nil {
+		t.Fatalf("no smoke test: %v", err)
+	}
+	if err := d.smokeTestRestart(request, "hq-mayor"); err != nil || !tmux.running["hq-mayor"] {
+		t.Fatalf("no smoke test: err = %v, running = %v", err, tmux.running)
+	}
+
+	hooksDir := AgentHooksDir(d.config.TownRoot)
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(hooksDir, HookSmokeTest)
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\ntest \"$GT_SESSION\" = hq-mayor\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.runSmokeTest("mayor", "hq-mayor", ActionRestart); err != nil {
+		t.Errorf("passing smoke test: %v", err)
+	}
+
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho 'bd list: no database'\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := d.runSmokeTest("mayor", "hq-mayor", ActionRestart)
+	if err == nil || !strings.Contains(err.Error(), "bd list: no database") {
+		t.Errorf("failing smoke test: err = %v, want the hook's output", err)
+	}
+}
+
+func TestEscalateSmokeTestFailure(t *testing.T) {
+	d := testDaemon()
+	sent := &sentMail{}
+	d.mail = sent
+	testErr := errors.New("smoke-test.sh: exit status 1")
+
+	d.escalateSmokeTestFailure(&LifecycleRequest{From: "gastown-crew-max", Action: ActionCycle, Reason: "context full"}, "gt-gastown-crew-max", testErr)
+	d.escalateSmokeTestFailure(&LifecycleRequest{From: "gastown-witness", Action: ActionRestart}, "gt-gastown-witness", testErr)
+	if len(sent.sent) != 2 {
+		t.Fatalf("sent = %v, want two escalations", sent.sent)
+	}
+	if !strings.HasPrefix(sent.sent[0], "gastown/witness|SMOKE_TEST_FAILED: gastown-crew-max|") ||
+		!strings.Contains(sent.sent[0], "reason: context full") {
+		t.Errorf("crew escalation = %q, want it mailed to the witness with the reason", sent.sent[0])
+	}
+	if !strings.HasPrefix(sent.sent[1], "mayor/|SMOKE_TEST_FAILED: gastown-witness|") {
+		t.Errorf("witness escalation = %q, want it mailed to the mayor", sent.sent[1])
+	}
+}
@@ -0,0 +1,196 @@
+package daemon
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/constants"
+)
+
+const (
+	// defaultMaxRestartsPerHeartbeat caps session restarts in one heartbeat.
+	// Requests over the cap stay in the inbox and run on a later heartbeat.
+	defaultMaxRestartsPerHeartbeat = 3
+
+	// defaultRestartStagger is the pause between consecutive restarts in one
+	// heartbeat, so a wave of cycle requests (e.g. after a shared tool
+	// update) doesn't hit git remotes and the API all at once.
+	defaultRestartStagger = 10 * time.Second
+
+	// defaultRestartJitter is the maximum random delay added to each stagger.
+	defaultRestartJitter = 5 * time.Second
+
+	// defaultRestartDelay is the minimum time from killing a session to
+	// starting its replacement.
+	defaultRestartDelay = constants.ShutdownNotifyDelay
+
+	// defaultTeardownTimeout bounds the wait for a killed session to
+	// disappear from tmux before its replacement is started anyway.
+	defaultTeardownTimeout = 5 * time.Second
+
+	// teardownPollInterval is how often a killed session is checked for.
+	teardownPollInterval = 100 * time.Millisecond
+)
+
+// restartThrottle spaces out session restarts. It is reset at the start of
+// each heartbeat; the per-agent history persists for the daemon's lifetime
+// and is backed by last_killed_at in each agent's state file.
+type restartThrottle struct {
+	maxPerPass  int
+	stagger     time.Duration
+	jitter      time.Duration
+	minInterval time.Duration
+
+	restarted   int                  // restarts so far this heartbeat
+	lastRestart map[string]time.Time // by identity
+
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// newRestartThrottle builds a throttle from the lifecycle config.
+func (d *Daemon) newRestartThrottle() *restartThrottle {
+	cfg := d.patrolConfig.lifecycleConfig()
+	t := &restartThrottle{
+		maxPerPass:  defaultMaxRestartsPerHeartbeat,
+		stagger:     d.lifecycleDuration("restart_stagger", cfg.RestartStagger, defaultRestartStagger),
+		jitter:      d.lifecycleDuration("restart_jitter", cfg.RestartJitter, defaultRestartJitter),
+		minInterval: d.lifecycleDuration("min_restart_interval", cfg.MinRestartInterval, 0),
+		lastRestart: make(map[string]time.Time),
+		sleep:       time.Sleep,
+		now:         time.Now,
+	}
+	if cfg.MaxRestartsPerHeartbeat != 0 {
+		t.maxPerPass = cfg.MaxRestartsPerHeartbeat
+	}
+	return t
+}
+
+// lifecycleDuration parses an optional lifecycle duration setting, logging
+// and falling back to def when it is invalid. Zero is a valid setting.
+func (d *Daemon) lifecycleDuration(key, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		d.logger.Printf("Warning: invalid lifecycle.%s %q, using %v", key, value, def)
+		return def
+	}
+	return parsed
+}
+
+// beginPass resets the per-heartbeat restart count.
+func (t *restartThrottle) beginPass() {
+	if t != nil {
+		t.restarted = 0
+	}
+}
+
+// admit reports whether identity may be restarted now. When it may not, the
+// reason says why and the caller should leave the request for a later
+// heartbeat. A nil throttle admits everything. lastKilled is the agent's
+// persisted last kill time (zero if unknown).
+func (t *restartThrottle) admit(identity string, lastKilled time.Time) (bool, string) {
+	if t == nil {
+		return true, ""
+	}
+	if t.maxPerPass > 0 && t.restarted >= t.maxPerPass {
+		return false, "restart limit for this heartbeat reached"
+	}
+	if t.minInterval > 0 {
+		last := t.lastRestart[identity]
+		if lastKilled.After(last) {
+			last = lastKilled
+		}
+		if !last.IsZero() {
+			if since := t.now().Sub(last); since < t.minInterval {
+				return false, "restarted " + since.Round(time.Second).String() + " ago (min interval " + t.minInterval.String() + ")"
+			}
+		}
+	}
+	return true, ""
+}
+
+// wait pauses before a restart if another restart already ran this
+// heartbeat: the stagger plus a random jitter.
+func (t *restartThrottle) wait() {
+	if t == nil || t.restarted == 0 {
+		return
+	}
+	delay := t.stagger
+	if t.jitter > 0 {
+		delay += rand.N(t.jitter)
+	}
+	if delay > 0 {
+		t.sleep(delay)
+	}
+}
+
+// record notes a restart of identity.
+func (t *restartThrottle) record(identity string) {
+	if t == nil {
+		return
+	}
+	t.restarted++
+	t.lastRestart[identity] = t.now()
+}
+
+// admitRestart applies the restart throttle to a request. Requests that don't
+// restart a session are always admitted. Dry runs are admitted without
+// counting against the limits.
+func (d *Daemon) admitRestart(request *LifecycleRequest) bool {
+	if !request.Action.restartsSession() || request.DryRun || d.config.DryRun {
+		return true
+	}
+	var lastKilled time.Time
+	if s, err := d.readAgentState(request.From); err == nil && s != nil {
+		lastKilled = s.LastKilledAt
+	}
+	ok, reason := d.restarts.admit(request.From, lastKilled)
+	if !ok {
+		d.logger.Printf("Deferring %s for %s: %s", request.Action, request.From, reason)
+		return false
+	}
+	d.restarts.wait()
+	d.restarts.record(request.From)
+	return true
+}
+
+// restartDelay returns the kill-to-restart delay for identity's role: the
+// role's lifecycle.restart_delay entry, else the "*" entry, else
+// defaultRestartDelay.
+func (d *Daemon) restartDelay(identity string) time.Duration {
+	delays := d.patrolConfig.lifecycleConfig().RestartDelay
+	if parsed, err := parseIdentity(identity); err == nil {
+		if value, ok := delays[parsed.RoleType]; ok {
+			return d.lifecycleDuration("restart_delay."+parsed.RoleType, value, defaultRestartDelay)
+		}
+	}
+	return d.lifecycleDuration("restart_delay.*", delays["*"], defaultRestartDelay)
+}
+
+// awaitTeardown waits between killing sessionName and restarting it: first
+// until tmux no longer lists the session (polling, at most
+// lifecycle.teardown_timeout), then for whatever is left of the role's
+// restart delay. Both are counted from the kill, so a slow teardown
+// shortens the delay rather than adding to it.
+func (d *Daemon) awaitTeardown(identity, sessionName string) {
+	timeout := d.lifecycleDuration("teardown_timeout", d.patrolConfig.lifecycleConfig().TeardownTimeout, defaultTeardownTimeout)
+	var waited time.Duration
+	for {
+		exists, err := d.tmux.HasSession(sessionName)
+		if err == nil && !exists {
+			break
+		}
+		if waited >= timeout {
+			d.logger.Printf("Warning: session %s still present %v after kill, restarting anyway", sessionName, waited)
+			break
+		}
+		d.pause(teardownPollInterval)
+		waited += teardownPollInterval
+	}
+	if remaining := d.restartDelay(identity) - waited; remaining > 0 {
+		d.pause(remaining)
+	}
+}
@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// rigAliases holds the town's rig aliases (old name -> current name), so
+// identities naming a renamed rig resolve to its current name.
+var rigAliases = struct {
+	sync.RWMutex
+	config *config.RigsConfig
+}{}
+
+// registerRigAliases loads the rig aliases from mayor/rigs.json. A missing
+// or unreadable file clears them.
+func registerRigAliases(townRoot string) {
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil || len(rigsConfig.Aliases) == 0 {
+		rigsConfig = nil
+	}
+	rigAliases.Lock()
+	rigAliases.config = rigsConfig
+	rigAliases.Unlock()
+}
+
+// resolveRigAlias returns the current name of a rig.
+func resolveRigAlias(rigName string) string {
+	rigAliases.RLock()
+	defer rigAliases.RUnlock()
+	return rigAliases.config.ResolveRig(rigName)
+}
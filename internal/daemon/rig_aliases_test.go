@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIdentityResolvesRigAliases(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rigsJSON := `{"version": 1, "rigs": {"gt_core": {}}, "aliases": {"gastown": "gt_core"}}`
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(rigsJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registerRigAliases(townRoot)
+	t.Cleanup(func() { registerRigAliases(t.TempDir()) })
+
+	tests := []struct {
+		identity string
+		wantRig  string
+		wantName string
+	}{
+		{"gastown-witness", "gt_core", ""},
+		{"gastown-crew-max", "gt_core", "max"},
+		{"gastown-polecat-toast", "gt_core", "toast"},
+		{"gt_core-refinery", "gt_core", ""},
+		{"beads-witness", "beads", ""},
+	}
+	for _, tt := range tests {
+		parsed, err := parseIdentity(tt.identity)
+		if err != nil {
+			t.Fatalf("parseIdentity(%q): %v", tt.identity, err)
+		}
+		if parsed.RigName != tt.wantRig || parsed.AgentName != tt.wantName {
+			t.Errorf("parseIdentity(%q) = rig %q name %q, want rig %q name %q",
+				tt.identity, parsed.RigName, parsed.AgentName, tt.wantRig, tt.wantName)
+		}
+	}
+
+}
@@ -0,0 +1,299 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+// Rig auto-provisioning. A git repo cloned straight into the town root
+// (~/gt/myproject/.git) isn't a rig: it has no witness, refinery, or rig
+// beads, so nothing patrols it. Every heartbeat the daemon looks for such
+// checkouts and, per "rig_provisioning" in mayor/daemon.json:
+//
+//	"rig_provisioning": {"mode": "auto", "ignore": ["scratch*"]}
+//
+// offers to provision them (mode "offer", the default: the mayor is mailed
+// once, suggesting 'gt rig provision <dir>'), provisions them itself (mode
+// "auto", by running that command), or leaves them alone (mode "off").
+//
+// Provisioning moves the checkout to daemon/provision/<dir>, where it stays
+// as the rig's local reference repo, and adds a rig named after the
+// directory cloned from the checkout's origin: refinery and mayor clones,
+// witness and polecat directories, rig beads, and agent beads. The
+// heartbeat then starts the rig's witness and refinery like any rig's.
+
+// Rig provisioning modes.
+const (
+	RigProvisionOffer = "offer"
+	RigProvisionAuto  = "auto"
+	RigProvisionOff   = "off"
+)
+
+// rigProvisionTimeout bounds one automatic provisioning, which clones the
+// repo twice.
+const rigProvisionTimeout = 10 * time.Minute
+
+// reservedTownDirs are town root directories that are never rigs.
+var reservedTownDirs = map[string]bool{
+	"mayor":   true,
+	"deacon":  true,
+	"daemon":  true,
+	"plugins": true,
+	"logs":    true,
+	RolesDir:  true,
+}
+
+// RigProvisionConfig controls what the daemon does with git checkouts that
+// appear in the town root.
+type RigProvisionConfig struct {
+	// Mode is "offer" (default), "auto", or "off".
+	Mode string `json:"mode,omitempty"`
+
+	// Ignore lists directory names (or path.Match globs) that are never
+	// offered or provisioned.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *RigProvisionConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Mode {
+	case "", RigProvisionOffer, RigProvisionAuto, RigProvisionOff:
+	default:
+		return fmt.Errorf("rig_provisioning: unknown mode %q (want offer, auto, or off)", c.Mode)
+	}
+	for _, pattern := range c.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rig_provisioning: invalid ignore pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// rigProvisionConfig returns the rig provisioning config, never nil. An
+// invalid config turns provisioning off.
+func (c *DaemonPatrolConfig) rigProvisionConfig() *RigProvisionConfig {
+	if c == nil || c.RigProvisioning == nil {
+		return &RigProvisionConfig{}
+	}
+	if c.RigProvisioning.Validate() != nil {
+		return &RigProvisionConfig{Mode: RigProvisionOff}
+	}
+	return c.RigProvisioning
+}
+
+// mode returns the provisioning mode.
+func (c *RigProvisionConfig) mode() string {
+	if c.Mode == "" {
+		return RigProvisionOffer
+	}
+	return c.Mode
+}
+
+// NewRig is a git checkout in the town root that isn't a rig yet.
+type NewRig struct {
+	// Dir is the checkout's directory under the town root; the rig gets
+	// the same name.
+	Dir string `json:"dir"`
+
+	// GitURL is the checkout's origin, empty if it has none (the rig is
+	// then cloned from the checkout itself).
+	GitURL string `json:"git_url,omitempty"`
+
+	// Problem says why the checkout can't be provisioned as is.
+	Problem string `json:"problem,omitempty"`
+}
+
+// DetectNewRigs returns the directories in the town root that hold a git
+// checkout but aren't registered rigs, sorted by name. Directories matching
+// an ignore pattern are skipped.
+func DetectNewRigs(townRoot string, ignore []string) ([]NewRig, error) {
+	entries, err := os.ReadDir(townRoot)
+	if err != nil {
+		return nil, err
+	}
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil {
+		rigsConfig = &config.RigsConfig{}
+	}
+
+	var found []NewRig
+	for _, entry := range entries {
+		dir := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(dir, ".") || reservedTownDirs[dir] || matchesPrewarmAgents(dir, ignore) {
+			continue
+		}
+		if _, ok := rigsConfig.Rigs[dir]; ok {
+			continue
+		}
+		checkout := filepath.Join(townRoot, dir)
+		if _, err := os.Stat(filepath.Join(checkout, ".git")); err != nil {
+			continue
+		}
+		rig := NewRig{Dir: dir}
+		rig.GitURL, _ = git.NewGit(checkout).RemoteURL("origin")
+		switch {
+		case strings.ContainsAny(dir, "-. "):
+			rig.Problem = "rig names can't contain '-', '.', or spaces; rename the directory"
+		case rigsConfig.ResolveRig(dir) != dir:
+			rig.Problem = fmt.Sprintf("%s is an alias of rig %s", dir, rigsConfig.ResolveRig(dir))
+		}
+		found = append(found, rig)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Dir < found[j].Dir })
+	return found, nil
+}
+
+// RigProvisionStagingDir returns where a provisioned checkout is kept.
+func RigProvisionStagingDir(townRoot, dir string) string {
+	return filepath.Join(townRoot, "daemon", "provision", dir)
+}
+
+// DetectedRig is a new rig the daemon has found, in daemon state.
+type DetectedRig struct {
+	NewRig
+	Since time.Time `json:"since"`
+
+	// Offered is set once the mayor has been mailed about the checkout.
+	Offered bool `json:"offered,omitempty"`
+
+	// Error is why automatic provisioning failed. It isn't retried; the
+	// mayor is mailed instead.
+	Error string `json:"error,omitempty"`
+}
+
+// provisionNewRigs offers or provisions the git checkouts that appeared in
+// the town root, per the rig provisioning mode.
+func (d *Daemon) provisionNewRigs(state *State, now time.Time) {
+	cfg := d.patrolConfig.rigProvisionConfig()
+	if cfg.mode() == RigProvisionOff {
+		state.DetectedRigs = nil
+		return
+	}
+	found, err := DetectNewRigs(d.config.TownRoot, cfg.Ignore)
+	if err != nil {
+		d.logger.Printf("Warning: looking for new rigs: %v", err)
+		return
+	}
+
+	detected := make(map[string]*DetectedRig)
+	for _, rig := range found {
+		rec := state.DetectedRigs[rig.Dir]
+		if rec == nil {
+			rec = &DetectedRig{Since: now}
+			d.logger.Printf("Found git checkout %s in the town root that isn't a rig", rig.Dir)
+		}
+		rec.NewRig = rig
+		if rig.Problem == "" && cfg.mode() == RigProvisionAuto && rec.Error == "" {
+			if d.provisionRig(rec) {
+				continue
+			}
+		}
+		if !rec.Offered {
+			d.offerRig(rec)
+		}
+		detected[rig.Dir] = rec
+	}
+	if len(detected) == 0 {
+		detected = nil
+	}
+	state.DetectedRigs = detected
+}
+
+// provisionRig provisions a detected checkout as a rig. Returns whether it
+// succeeded; a failure is recorded in rec.
+func (d *Daemon) provisionRig(rec *DetectedRig) bool {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would provision %s as a rig", rec.Dir)
+		return false
+	}
+	d.logger.Printf("Provisioning %s as a rig", rec.Dir)
+	if err := d.rigProvisioner().Provision(d.config.TownRoot, rec.Dir); err != nil {
+		rec.Error = err.Error()
+		d.logger.Printf("Error provisioning %s: %v", rec.Dir, err)
+		return false
+	}
+	d.logger.Printf("Provisioned rig %s", rec.Dir)
+	body := fmt.Sprintf("The git checkout %s appeared in the town root and was provisioned as rig %s.\n\n"+
+		"The checkout itself is kept at %s as the rig's reference repo. The witness and refinery start with this heartbeat.",
+		rec.Dir, rec.Dir, RigProvisionStagingDir(d.config.TownRoot, rec.Dir))
+	if err := d.mailClient().Send("mayor/", "RIG_PROVISIONED: "+rec.Dir, body); err != nil {
+		d.logger.Printf("Warning: failed to mail the mayor about rig %s: %v", rec.Dir, err)
+	}
+	return true
+}
+
+// offerRig mails the mayor about a checkout that could be a rig, once.
+func (d *Daemon) offerRig(rec *DetectedRig) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "A git checkout appeared at %s", filepath.Join(d.config.TownRoot, rec.Dir))
+	if rec.GitURL != "" {
+		fmt.Fprintf(&b, " (origin %s)", rec.GitURL)
+	}
+	b.WriteString(", but it isn't a rig, so no witness or refinery watches it.\n\n")
+	switch {
+	case rec.Problem != "":
+		fmt.Fprintf(&b, "It can't be provisioned as is: %s.\n", rec.Problem)
+	case rec.Error != "":
+		fmt.Fprintf(&b, "Provisioning it failed: %s\n\nTo retry:\n  gt rig provision %s\n", rec.Error, rec.Dir)
+	default:
+		fmt.Fprintf(&b, "To provision it as rig %s:\n  gt rig provision %s\n", rec.Dir, rec.Dir)
+	}
+	b.WriteString("\nTo stop these offers, move the directory or add it to rig_provisioning.ignore in mayor/daemon.json.")
+
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would offer to provision %s as a rig", rec.Dir)
+		return
+	}
+	if err := d.mailClient().Send("mayor/", "RIG_DETECTED: "+rec.Dir, b.String()); err != nil {
+		d.logger.Printf("Warning: failed to mail the mayor about %s: %v", rec.Dir, err)
+		return
+	}
+	rec.Offered = true
+}
+
+// rigProvisioner returns the daemon's rig provisioning backend.
+func (d *Daemon) rigProvisioner() RigProvisioner {
+	if d.provisioner == nil {
+		return gtRigProvision{}
+	}
+	return d.provisioner
+}
+
+// gtRigProvision is the RigProvisioner backed by 'gt rig provision'.
+type gtRigProvision struct{}
+
+func (gtRigProvision) Provision(townRoot, dir string) error {
+	cmd := exec.Command("gt", "rig", "provision", dir) //nolint:gosec // G204: dir is a town root entry
+	cmd.Dir = townRoot
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(out.String()))
+		}
+		return nil
+	case <-time.After(rigProvisionTimeout):
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("gt rig provision timed out after %v", rigProvisionTimeout)
+	}
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvisioner records provisioned checkouts and fails with err.
+type fakeProvisioner struct {
+	provisioned []string
+	err         error
+}
+
+func (p *fakeProvisioner) Provision(townRoot, dir string) error {
+	p.provisioned = append(p.provisioned, dir)
+	return p.err
+}
+
+// newRigTown creates a town with rig gastown registered and git checkouts
+// in the town root.
+func newRigTown(t *testing.T, checkouts ...string) string {
+	t.Helper()
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rigs := `{"version": 1, "rigs": {"gastown": {"git_url": "https://example.com/gastown.git"}}}`
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(rigs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range append([]string{"gastown"}, checkouts...) {
+		if err := os.MkdirAll(filepath.Join(townRoot, dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return townRoot
+}
+
+func TestRigProvisionConfigValidate(t *testing.T) {
+	for _, cfg := range []*RigProvisionConfig{nil, {}, {Mode: "auto"}, {Mode: "off", Ignore: []string{"scratch*"}}} {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", cfg, err)
+		}
+	}
+	for _, cfg := range []*RigProvisionConfig{{Mode: "always"}, {Ignore: []string{"["}}} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", cfg)
+		}
+	}
+}
+
+func TestDetectNewRigs(t *testing.T) {
+	townRoot := newRigTown(t, "myproject", "my-app", "scratch", ".hidden", "mayor/rig")
+	if err := os.MkdirAll(filepath.Join(townRoot, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	remote := exec.Command("git", "init", "-q", filepath.Join(townRoot, "myproject"))
+	if err := remote.Run(); err != nil {
+		t.Skipf("git init: %v", err)
+	}
+	if err := exec.Command("git", "-C", filepath.Join(townRoot, "myproject"), "remote", "add", "origin", "https://example.com/myproject.git").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := DetectNewRigs(townRoot, []string{"scratch*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %+v, want my-app and myproject", found)
+	}
+	if found[0].Dir != "my-app" || found[0].Problem == "" {
+		t.Errorf("found[0] = %+v, want my-app with a naming problem", found[0])
+	}
+	if found[1].Dir != "myproject" || found[1].GitURL != "https://example.com/myproject.git" || found[1].Problem != "" {
+		t.Errorf("found[1] = %+v, want myproject with its origin", found[1])
+	}
+}
+
+func TestProvisionNewRigsOffer(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = newRigTown(t, "myproject")
+	sent := &sentMail{}
+	d.mail = sent
+	state := &State{}
+	now := time.Now()
+
+	d.provisionNewRigs(state, now)
+	d.provisionNewRigs(state, now.Add(time.Minute))
+	if len(sent.sent) != 1 || !strings.HasPrefix(sent.sent[0], "mayor/|RIG_DETECTED: myproject|") ||
+		!strings.Contains(sent.sent[0], "gt rig provision myproject") {
+		t.Fatalf("sent = %v, want one offer to the mayor", sent.sent)
+	}
+	rec := state.DetectedRigs["myproject"]
+	if rec == nil || !rec.Offered || !rec.Since.Equal(now) {
+		t.Errorf("detected = %+v, want myproject offered since the first pass", rec)
+	}
+
+	// A checkout that goes away is forgotten
+	if err := os.RemoveAll(filepath.Join(d.config.TownRoot, "myproject")); err != nil {
+		t.Fatal(err)
+	}
+	d.provisionNewRigs(state, now.Add(2*time.Minute))
+	if state.DetectedRigs != nil {
+		t.Errorf("detected = %+v after the checkout was removed", state.DetectedRigs)
+	}
+}
+
+func TestProvisionNewRigsAuto(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = newRigTown(t, "myproject", "my-app")
+	d.patrolConfig = &DaemonPatrolConfig{RigProvisioning: &RigProvisionConfig{Mode: RigProvisionAuto}}
+	sent := &sentMail{}
+	d.mail = sent
+	provisioner := &fakeProvisioner{}
+	d.provisioner = provisioner
+	state := &State{}
+
+	d.provisionNewRigs(state, time.Now())
+	if len(provisioner.provisioned) != 1 || provisioner.provisioned[0] != "myproject" {
+		t.Errorf("provisioned = %v, want only myproject (my-app needs renaming)", provisioner.provisioned)
+	}
+	if _, ok := state.DetectedRigs["myproject"]; ok {
+		t.Error("a provisioned checkout should not stay detected")
+	}
+	if len(sent.sent) != 2 || !strings.HasPrefix(sent.sent[0], "mayor/|RIG_DETECTED: my-app|") ||
+		!strings.HasPrefix(sent.sent[1], "mayor/|RIG_PROVISIONED: myproject|") {
+		t.Errorf("sent = %v", sent.sent)
+	}
+}
+
+func TestProvisionNewRigsAutoFailure(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = newRigTown(t, "myproject")
+	d.patrolConfig = &DaemonPatrolConfig{RigProvisioning: &RigProvisionConfig{Mode: RigProvisionAuto}}
+	sent := &sentMail{}
+	d.mail = sent
+	provisioner := &fakeProvisioner{err: errors.New("clone failed")}
+	d.provisioner = provisioner
+	state := &State{}
+
+	d.provisionNewRigs(state, time.Now())
+	d.provisionNewRigs(state, time.Now())
+	if len(provisioner.provisioned) != 1 {
+		t.Errorf("provisioned %d times, want a failure not retried", len(provisioner.provisioned))
+	}
+	if rec := state.DetectedRigs["myproject"]; rec == nil || rec.Error != "clone failed" {
+		t.Errorf("detected = %+v, want the failure recorded", rec)
+	}
+	if len(sent.sent) != 1 || !strings.Contains(sent.sent[0], "Provisioning it failed: clone failed") {
+		t.Errorf("sent = %v, want the failure mailed once", sent.sent)
+	}
+}
+
+func TestProvisionNewRigsOff(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = newRigTown(t, "myproject")
+	d.patrolConfig = &DaemonPatrolConfig{RigProvisioning: &RigProvisionConfig{Mode: RigProvisionOff}}
+	sent := &sentMail{}
+	d.mail = sent
+	state := &State{DetectedRigs: map[string]*DetectedRig{"old": {}}}
+
+	d.provisionNewRigs(state, time.Now())
+	if len(sent.sent) != 0 || state.DetectedRigs != nil {
+		t.Errorf("mode off: sent %v, detected %+v", sent.sent, state.DetectedRigs)
+	}
+}
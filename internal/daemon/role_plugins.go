@@ -0,0 +1,421 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// RolesDir is the town directory third-party agent roles are installed in,
+// one subdirectory per role: <townRoot>/roles/<name>/role.json, with optional
+// lifecycle hooks in <townRoot>/roles/<name>/hooks/.
+const RolesDir = "roles"
+
+// rolePluginFile is the role definition inside a role plugin directory.
+const rolePluginFile = "role.json"
+
+// builtinRoles are the roles parseIdentity knows natively. Plugins can't
+// reuse their names.
+var builtinRoles = map[string]bool{
+	"mayor": true, "deacon": true, "witness": true,
+	"refinery": true, "crew": true, "polecat": true,
+}
+
+var rolePluginNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// RolePlugin is an agent role defined outside gt, loaded from
+// <townRoot>/roles/<name>/role.json. Once loaded its agents are parsed,
+// named, started, restarted, and hooked like the built-in roles.
+type RolePlugin struct {
+	// Name is the role type, taken from the plugin directory name.
+	Name string `json:"-"`
+
+	// Dir is the plugin directory.
+	Dir string `json:"-"`
+
+	// Scope is "town" (one agent per town, like the mayor) or "rig" (agents
+	// live in rigs, like the witness). Default: rig.
+	Scope string `json:"scope,omitempty"`
+
+	// Named gives a rig-scoped role many named agents per rig (like crew)
+	// instead of one. Named agents are discovered from the subdirectories of
+	// <townRoot>/<rig>/<name>/.
+	Named bool `json:"named,omitempty"`
+
+	// Identity is the identity pattern the role's agents send mail as, with
+	// {rig} and {name} placeholders. Default: "<name>" for town roles,
+	// "{rig}-<name>" for rig roles, "{rig}-<name>-{name}" for named roles.
+	Identity string `json:"identity,omitempty"`
+
+	// AutoStart has the heartbeat keep the role's agents running, as it
+	// does for witnesses and refineries. Named roles are never auto-started.
+	AutoStart bool `json:"auto_start,omitempty"`
+
+	// ReadOnly gives the role's agents read-only checkouts and strips push
+	// credentials from their sessions (see read_only.go).
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// The rest mirror role bead config and are used when the role has no
+	// role bead. Patterns support {town}, {rig}, {name}, {role}, and session
+	// patterns also the session_naming {prefix}, {hq}, {sep} placeholders.
+	SessionPattern string            `json:"session_pattern,omitempty"`
+	WorkDirPattern string            `json:"work_dir,omitempty"`
+	NeedsPreSync   bool              `json:"needs_pre_sync,omitempty"`
+	StartCommand   string            `json:"start_command,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+
+	identityRe *regexp.Regexp
+}
+
+// rigScoped reports whether the role's agents live in rigs.
+func (p *RolePlugin) rigScoped() bool {
+	return p.Scope != "town"
+}
+
+// identityPattern returns the configured or default identity pattern.
+func (p *RolePlugin) identityPattern() string {
+	switch {
+	case p.Identity != "":
+		return p.Identity
+	case !p.rigScoped():
+		return p.Name
+	case p.Named:
+		return "{rig}-" + p.Name + "-{name}"
+	default:
+		return "{rig}-" + p.Name
+	}
+}
+
+// sessionTemplate returns the session name template used when neither
+// session_naming nor a role bead names the role's sessions.
+func (p *RolePlugin) sessionTemplate() string {
+	switch {
+	case p.SessionPattern != "":
+		return p.SessionPattern
+	case !p.rigScoped():
+		return "{hq}" + p.Name
+	case p.Named:
+		return "{prefix}{rig}{sep}" + p.Name + "{sep}{name}"
+	default:
+		return "{prefix}{rig}{sep}" + p.Name
+	}
+}
+
+// roleConfig returns the plugin's definition as role bead config.
+func (p *RolePlugin) roleConfig() *beads.RoleConfig {
+	workDir := p.WorkDirPattern
+	if workDir == "" {
+		switch {
+		case !p.rigScoped():
+			workDir = "{town}"
+		case p.Named:
+			workDir = "{town}/{rig}/{role}/{name}"
+		default:
+			workDir = "{town}/{rig}"
+		}
+	}
+	return &beads.RoleConfig{
+		SessionPattern: p.SessionPattern,
+		WorkDirPattern: workDir,
+		NeedsPreSync:   p.NeedsPreSync,
+		StartCommand:   p.StartCommand,
+		EnvVars:        p.Env,
+	}
+}
+
+// HooksDir returns the directory of the role's lifecycle hooks. They use
+// the agent hook names (pre-cycle.sh, pre-shutdown.sh, post-start.sh,
+// smoke-test.sh) and run before the agent's own hooks.
+func (p *RolePlugin) HooksDir() string {
+	return filepath.Join(p.Dir, "hooks")
+}
+
+// validate checks the definition and compiles the identity pattern.
+func (p *RolePlugin) validate() error {
+	if !rolePluginNameRe.MatchString(p.Name) {
+		return fmt.Errorf("role name %q must be lowercase letters, digits, and underscores", p.Name)
+	}
+	if builtinRoles[p.Name] {
+		return fmt.Errorf("role name %q is a built-in role", p.Name)
+	}
+	switch p.Scope {
+	case "", "town", "rig":
+	default:
+		return fmt.Errorf("scope %q: must be town or rig", p.Scope)
+	}
+	if !p.rigScoped() && p.Named {
+		return fmt.Errorf("town roles cannot be named")
+	}
+
+	pattern := p.identityPattern()
+	hasRig := strings.Contains(pattern, "{rig}")
+	hasName := strings.Contains(pattern, "{name}")
+	if hasRig != p.rigScoped() {
+		return fmt.Errorf("identity %q: {rig} is required for rig roles and not allowed for town roles", pattern)
+	}
+	if hasName != p.Named {
+		return fmt.Errorf("identity %q: {name} is required for named roles and not allowed otherwise", pattern)
+	}
+	if strings.ContainsAny(pattern, "/ ") {
+		return fmt.Errorf("identity %q: cannot contain '/' or spaces", pattern)
+	}
+
+	// The rig is matched shortest-first, like <rig>-crew-<name>.
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{rig}"), `(?P<rig>.+?)`, 1)
+	expr = strings.Replace(expr, regexp.QuoteMeta("{name}"), `(?P<name>.+)`, 1)
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return fmt.Errorf("identity %q: %w", pattern, err)
+	}
+	p.identityRe = re
+
+	// Identities the built-in patterns already claim would never reach the
+	// plugin (e.g. "{rig}-crew-{name}").
+	sample := beads.ExpandRolePattern(pattern, "", "myrig", "alice", p.Name)
+	if parsed, err := parseBuiltinIdentity(sample); err == nil {
+		return fmt.Errorf("identity %q: %s already parses as a %s identity", pattern, sample, parsed.RoleType)
+	}
+
+	if p.SessionPattern != "" && strings.ContainsAny(p.SessionPattern, ".: ") {
+		return fmt.Errorf("session_pattern %q: session names cannot contain '.', ':' or spaces", p.SessionPattern)
+	}
+	return nil
+}
+
+// parse matches identity against the role's identity pattern.
+func (p *RolePlugin) parse(identity string) (*ParsedIdentity, bool) {
+	m := p.identityRe.FindStringSubmatch(identity)
+	if m == nil {
+		return nil, false
+	}
+	parsed := &ParsedIdentity{RoleType: p.Name}
+	for i, group := range p.identityRe.SubexpNames() {
+		switch group {
+		case "rig":
+			parsed.RigName = m[i]
+		case "name":
+			parsed.AgentName = m[i]
+		}
+	}
+	return parsed, true
+}
+
+// identity builds the identity of one of the role's agents.
+func (p *RolePlugin) identity(rigName, agentName string) string {
+	return beads.ExpandRolePattern(p.identityPattern(), "", rigName, agentName, p.Name)
+}
+
+// LoadRolePlugins reads every role plugin installed in the town, plus the
+// built-in observer role unless the town defines its own. Plugins that fail
+// to load are returned as errors alongside the good ones, so one broken
+// plugin doesn't disable the rest.
+func LoadRolePlugins(townRoot string) ([]*RolePlugin, []error) {
+	plugins, errs := loadTownRolePlugins(townRoot)
+	for _, p := range plugins {
+		if p.Name == ObserverRole {
+			return plugins, errs
+		}
+	}
+	observer := observerRole(townRoot)
+	if err := observer.validate(); err != nil {
+		return plugins, append(errs, fmt.Errorf("role %s: %w", ObserverRole, err))
+	}
+	return append(plugins, observer), errs
+}
+
+// loadTownRolePlugins reads the role plugins in the town's roles directory.
+func loadTownRolePlugins(townRoot string) ([]*RolePlugin, []error) {
+	rolesDir := filepath.Join(townRoot, RolesDir)
+	entries, err := os.ReadDir(rolesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	var plugins []*RolePlugin
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(rolesDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, rolePluginFile))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("role %s: %w", entry.Name(), err))
+			continue
+		}
+		p := &RolePlugin{}
+		if err := json.Unmarshal(data, p); err != nil {
+			errs = append(errs, fmt.Errorf("role %s: parsing %s: %w", entry.Name(), rolePluginFile, err))
+			continue
+		}
+		p.Name = entry.Name()
+		p.Dir = dir
+		if err := p.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("role %s: %w", entry.Name(), err))
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, errs
+}
+
+// rolePlugins holds the role plugins of the town this process manages.
+// parseIdentity is a package-level function called from everywhere an
+// identity is handled, so the registry is too.
+var rolePlugins = struct {
+	sync.RWMutex
+	byName map[string]*RolePlugin
+	sorted []*RolePlugin
+}{}
+
+// setRolePlugins replaces the registered role plugins.
+func setRolePlugins(plugins []*RolePlugin) {
+	sorted := append([]*RolePlugin(nil), plugins...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	byName := make(map[string]*RolePlugin, len(sorted))
+	for _, p := range sorted {
+		byName[p.Name] = p
+	}
+	rolePlugins.Lock()
+	rolePlugins.byName = byName
+	rolePlugins.sorted = sorted
+	rolePlugins.Unlock()
+}
+
+// registeredRolePlugins returns the registered role plugins sorted by name.
+func registeredRolePlugins() []*RolePlugin {
+	rolePlugins.RLock()
+	defer rolePlugins.RUnlock()
+	return rolePlugins.sorted
+}
+
+// lookupRolePlugin returns the registered plugin for a role type, or nil.
+func lookupRolePlugin(role string) *RolePlugin {
+	rolePlugins.RLock()
+	defer rolePlugins.RUnlock()
+	return rolePlugins.byName[role]
+}
+
+// parsePluginIdentity matches identity against the registered plugins.
+func parsePluginIdentity(identity string) (*ParsedIdentity, bool) {
+	for _, p := range registeredRolePlugins() {
+		if parsed, ok := p.parse(identity); ok {
+			return parsed, true
+		}
+	}
+	return nil, false
+}
+
+// registerRolePlugins loads and registers the town's role plugins, logging
+// the ones that fail to load.
+func registerRolePlugins(townRoot string, logger *log.Logger) {
+	plugins, errs := LoadRolePlugins(townRoot)
+	for _, err := range errs {
+		logger.Printf("Warning: skipping role plugin: %v", err)
+	}
+	for _, p := range plugins {
+		logger.Printf("Loaded role plugin %s (identity %s)", p.Name, p.identityPattern())
+	}
+	setRolePlugins(plugins)
+}
+
+// pluginIdentities returns the agents of every plugin role: town roles,
+// then the rig roles of each rig.
+func (d *Daemon) pluginIdentities() []string {
+	var identities []string
+	for _, p := range registeredRolePlugins() {
+		if !p.rigScoped() {
+			identities = append(identities, p.identity("", ""))
+		}
+	}
+	for _, rigName := range d.getKnownRigs() {
+		identities = append(identities, rigPluginIdentities(d.config.TownRoot, rigName)...)
+	}
+	return identities
+}
+
+// rigPluginIdentities returns a rig's plugin agents: one per singleton role
+// and the discovered agents of named roles, sorted by role then name.
+func rigPluginIdentities(townRoot, rigName string) []string {
+	var identities []string
+	for _, p := range registeredRolePlugins() {
+		switch {
+		case !p.rigScoped():
+		case p.Named:
+			names, _ := listPolecatWorktrees(filepath.Join(townRoot, rigName, p.Name))
+			sort.Strings(names)
+			for _, name := range names {
+				identities = append(identities, p.identity(rigName, name))
+			}
+		default:
+			identities = append(identities, p.identity(rigName, ""))
+		}
+	}
+	return identities
+}
+
+// ensureRolePluginsRunning starts the agents of auto_start plugin roles
+// that aren't running, honoring rig state and the town manifest like the
+// witness and refinery checks.
+func (d *Daemon) ensureRolePluginsRunning() {
+	for _, p := range registeredRolePlugins() {
+		if !p.AutoStart || p.Named {
+			continue
+		}
+		if !p.rigScoped() {
+			d.ensurePluginAgentRunning(p, p.identity("", ""))
+			continue
+		}
+		for _, rigName := range d.getKnownRigs() {
+			if operational, reason := d.isRigOperational(rigName); !operational {
+				d.logger.Printf("Skipping %s auto-start for %s: %s", p.Name, rigName, reason)
+				continue
+			}
+			if d.manifest.RoleDisabled(rigName, p.Name) {
+				d.logger.Printf("Skipping %s auto-start for %s: disabled in town manifest", p.Name, rigName)
+				continue
+			}
+			identity := p.identity(rigName, "")
+			if outside, reason := d.outsideSchedule(identity, time.Now()); outside {
+				d.logger.Printf("Skipping %s auto-start for %s: %s", p.Name, rigName, reason)
+				continue
+			}
+			d.ensurePluginAgentRunning(p, identity)
+		}
+	}
+}
+
+// ensurePluginAgentRunning starts one plugin agent if it isn't running. A
+// role with its own start_command may not run Claude at all, so for those a
+// live session is enough.
+func (d *Daemon) ensurePluginAgentRunning(p *RolePlugin, identity string) {
+	sessionName := d.identityToSession(identity)
+	if sessionName == "" {
+		return
+	}
+	parsed, _ := parseIdentity(identity)
+	if has, err := d.tmux.HasSession(sessionName); err != nil || (has && (p.StartCommand != "" || d.agentRunning(identity, sessionName, parsed))) {
+		return
+	}
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would start %s agent %s in session %s", p.Name, identity, sessionName)
+		return
+	}
+	d.logger.Printf("%s agent %s not running, starting session %s", p.Name, identity, sessionName)
+	if err := d.restartSession(sessionName, identity); err != nil {
+		d.logger.Printf("Error starting %s: %v", identity, err)
+	}
+}
@@ -0,0 +1,210 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRolePlugin(t *testing.T, townRoot, name, def string) {
+	t.Helper()
+	dir := filepath.Join(townRoot, RolesDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, rolePluginFile), []byte(def), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useRolePlugins loads the town's role plugins for the rest of the test.
+func useRolePlugins(t *testing.T, townRoot string) []error {
+	t.Helper()
+	plugins, errs := LoadRolePlugins(townRoot)
+	setRolePlugins(plugins)
+	t.Cleanup(func() { setRolePlugins(nil) })
+	return errs
+}
+
+func TestLoadRolePlugins(t *testing.T) {
+	townRoot := t.TempDir()
+	writeRolePlugin(t, townRoot, "auditor", `{"auto_start": true}`)
+	writeRolePlugin(t, townRoot, "scribe", `{"scope": "town"}`)
+	writeRolePlugin(t, townRoot, "reviewer", `{"named": true}`)
+	writeRolePlugin(t, townRoot, "crew", `{}`)
+	writeRolePlugin(t, townRoot, "clash", `{"named": true, "identity": "{rig}-crew-{name}"}`)
+	writeRolePlugin(t, townRoot, "broken", `{`)
+
+	errs := useRolePlugins(t, townRoot)
+	if len(errs) != 3 {
+		t.Fatalf("errors = %v, want 3 (crew, clash, broken)", errs)
+	}
+	for _, want := range []string{"built-in role", "already parses as a crew identity", "parsing role.json"} {
+		found := false
+		for _, err := range errs {
+			found = found || strings.Contains(err.Error(), want)
+		}
+		if !found {
+			t.Errorf("no error mentions %q: %v", want, errs)
+		}
+	}
+
+	var names []string
+	for _, p := range registeredRolePlugins() {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "auditor,observer,reviewer,scribe" {
+		t.Errorf("registered = %s", got)
+	}
+}
+
+func TestRolePluginValidate(t *testing.T) {
+	for _, tc := range []struct {
+		plugin RolePlugin
+		errSub string
+	}{
+		{RolePlugin{Name: "Auditor"}, "lowercase"},
+		{RolePlugin{Name: "auditor", Scope: "galaxy"}, "must be town or rig"},
+		{RolePlugin{Name: "auditor", Scope: "town", Named: true}, "cannot be named"},
+		{RolePlugin{Name: "auditor", Identity: "auditor"}, "{rig} is required"},
+		{RolePlugin{Name: "auditor", Identity: "{rig}-auditor-{name}"}, "{name} is required for named roles and not allowed"},
+		{RolePlugin{Name: "auditor", Identity: "{rig}/auditor"}, "cannot contain"},
+		{RolePlugin{Name: "auditor", SessionPattern: "gt:{rig}"}, "session names cannot contain"},
+		{RolePlugin{Name: "auditor", Identity: "{rig}-witness"}, "already parses as a witness identity"},
+		{RolePlugin{Name: "auditor"}, ""},
+		{RolePlugin{Name: "auditor", Scope: "town", Identity: "hq-auditor"}, ""},
+	} {
+		err := tc.plugin.validate()
+		switch {
+		case tc.errSub == "" && err != nil:
+			t.Errorf("validate(%+v) = %v, want nil", tc.plugin, err)
+		case tc.errSub != "" && (err == nil || !strings.Contains(err.Error(), tc.errSub)):
+			t.Errorf("validate(%+v) = %v, want error containing %q", tc.plugin, err, tc.errSub)
+		}
+	}
+}
+
+func TestParseIdentityRolePlugins(t *testing.T) {
+	townRoot := t.TempDir()
+	writeRolePlugin(t, townRoot, "auditor", `{}`)
+	writeRolePlugin(t, townRoot, "scribe", `{"scope": "town"}`)
+	writeRolePlugin(t, townRoot, "reviewer", `{"named": true, "identity": "{rig}-review-{name}"}`)
+	if errs := useRolePlugins(t, townRoot); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	for _, tc := range []struct {
+		identity string
+		want     ParsedIdentity
+	}{
+		{"gastown-auditor", ParsedIdentity{RoleType: "auditor", RigName: "gastown"}},
+		{"my-rig-auditor", ParsedIdentity{RoleType: "auditor", RigName: "my-rig"}},
+		{"scribe", ParsedIdentity{RoleType: "scribe"}},
+		{"gastown-review-alice", ParsedIdentity{RoleType: "reviewer", RigName: "gastown", AgentName: "alice"}},
+		// Built-in patterns still win
+		{"gastown-witness", ParsedIdentity{RoleType: "witness", RigName: "gastown"}},
+		{"gastown-crew-max", ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}},
+	} {
+		parsed, err := parseIdentity(tc.identity)
+		if err != nil {
+			t.Errorf("parseIdentity(%q): %v", tc.identity, err)
+			continue
+		}
+		if *parsed != tc.want {
+			t.Errorf("parseIdentity(%q) = %+v, want %+v", tc.identity, *parsed, tc.want)
+		}
+	}
+
+	if _, err := parseIdentity("gastown-unknown"); err == nil {
+		t.Error("parseIdentity(gastown-unknown) should fail")
+	}
+
+	setRolePlugins(nil)
+	if _, err := parseIdentity("gastown-auditor"); err == nil {
+		t.Error("plugin identities should not parse once unregistered")
+	}
+}
+
+func TestRolePluginSessionsAndConfig(t *testing.T) {
+	townRoot := t.TempDir()
+	writeRolePlugin(t, townRoot, "auditor", `{"start_command": "exec auditor --rig {rig}", "env": {"AUDIT_RIG": "{rig}"}}`)
+	writeRolePlugin(t, townRoot, "scribe", `{"scope": "town", "session_pattern": "{hq}scribe-main"}`)
+	writeRolePlugin(t, townRoot, "reviewer", `{"named": true}`)
+	if errs := useRolePlugins(t, townRoot); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	for identity, want := range map[string]string{
+		"gastown-auditor":         "gt-gastown-auditor",
+		"scribe":                  "hq-scribe-main",
+		"gastown-reviewer-alice":  "gt-gastown-reviewer-alice",
+		"gastown-reviewer-bob-jr": "gt-gastown-reviewer-bob-jr",
+	} {
+		if got := d.identityToSession(identity); got != want {
+			t.Errorf("identityToSession(%q) = %q, want %q", identity, got, want)
+		}
+	}
+
+	cfg, parsed, err := d.getRoleConfigForIdentity("gastown-auditor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.getWorkDir(cfg, parsed); got != filepath.Join(townRoot, "gastown") {
+		t.Errorf("auditor workdir = %q", got)
+	}
+	if got := d.getStartCommand(cfg, parsed); got != "exec auditor --rig gastown" {
+		t.Errorf("auditor start command = %q", got)
+	}
+
+	cfg, parsed, _ = d.getRoleConfigForIdentity("gastown-reviewer-alice")
+	if got := d.getWorkDir(cfg, parsed); got != filepath.Join(townRoot, "gastown", "reviewer", "alice") {
+		t.Errorf("reviewer workdir = %q", got)
+	}
+
+	// Configured session_naming templates may name plugin roles
+	naming := &SessionNamingConfig{Templates: map[string]string{"reviewer": "{prefix}{rig}{sep}{name}"}}
+	if err := naming.Validate(); err != nil {
+		t.Errorf("Validate with plugin template: %v", err)
+	}
+	naming.Templates["reviewer"] = "{prefix}{rig}"
+	if err := naming.Validate(); err == nil {
+		t.Error("Validate should require {name} for a named plugin role")
+	}
+}
+
+func TestPluginIdentities(t *testing.T) {
+	townRoot := t.TempDir()
+	writeRolePlugin(t, townRoot, "auditor", `{}`)
+	writeRolePlugin(t, townRoot, "scribe", `{"scope": "town"}`)
+	writeRolePlugin(t, townRoot, "reviewer", `{"named": true}`)
+	if errs := useRolePlugins(t, townRoot); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs": {"gastown": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bob", "alice"} {
+		if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "reviewer", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	got := strings.Join(d.pluginIdentities(), ",")
+	want := "scribe,gastown-auditor,gastown-reviewer-alice,gastown-reviewer-bob"
+	if got != want {
+		t.Errorf("pluginIdentities = %s, want %s", got, want)
+	}
+
+	rig := strings.Join(RigIdentities(townRoot, "gastown"), ",")
+	if !strings.HasSuffix(rig, ",gastown-auditor,gastown-reviewer-alice,gastown-reviewer-bob") {
+		t.Errorf("RigIdentities = %s", rig)
+	}
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// rollupPushTimeout bounds a single gt rollup push run.
+const rollupPushTimeout = 2 * time.Minute
+
+// pushRollup runs 'gt rollup push' once per configured interval. The command
+// does the collecting (it owns the cost ledger queries); the daemon only
+// schedules it. A failed push waits for the next interval rather than retrying
+// every heartbeat.
+func (d *Daemon) pushRollup(state *State, now time.Time) {
+	if d.patrolConfig == nil || d.patrolConfig.Rollup == nil || !d.patrolConfig.Rollup.Enabled {
+		return
+	}
+	cfg := d.patrolConfig.Rollup
+	if err := cfg.Validate(); err != nil {
+		d.logger.Printf("Warning: rollup disabled: %v", err)
+		return
+	}
+	if !state.LastRollupPush.IsZero() && now.Sub(state.LastRollupPush) < cfg.PushInterval() {
+		return
+	}
+	state.LastRollupPush = now
+
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Rollup: would push metrics to %s", cfg.Endpoint)
+		return
+	}
+
+	cmd := exec.Command("gt", "rollup", "push")
+	cmd.Dir = d.config.TownRoot
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		d.logger.Printf("Warning: rollup push failed to start: %v", err)
+		return
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			d.logger.Printf("Warning: rollup push failed: %v: %s", err, strings.TrimSpace(out.String()))
+			return
+		}
+		d.logger.Printf("Rollup: pushed metrics to %s", cfg.Endpoint)
+	case <-time.After(rollupPushTimeout):
+		_ = cmd.Process.Kill()
+		d.logger.Printf("Warning: rollup push timed out after %v", rollupPushTimeout)
+	}
+}
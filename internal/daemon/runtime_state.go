@@ -0,0 +1,313 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// runtimeStateKey is the town storage key for the daemon's in-flight
+// knowledge (daemon/runtime.json on disk). It lives in the town store, so
+// a town on the sqlite store (GT_STORAGE_URL=sqlite:///path/to/town.db)
+// keeps it in that database.
+const runtimeStateKey = "daemon/runtime.json"
+
+// RuntimeState is what the daemon otherwise only knows in memory: crash
+// counts, restart and handoff timers, rate limit windows, escalation
+// history, stuck agents, and pending actions. It is saved every heartbeat and reloaded when a
+// daemon becomes leader, so crash-loop detection and retry schedules survive
+// daemon restarts and upgrades.
+type RuntimeState struct {
+	SavedAt time.Time `json:"saved_at"`
+
+	// CrashHistory is the recent deaths of each session (crash loop detection).
+	CrashHistory map[string][]time.Time `json:"crash_history,omitempty"`
+
+	// LastRestart is when the daemon last restarted each identity (restart throttle).
+	LastRestart map[string]time.Time `json:"last_restart,omitempty"`
+
+	// HandoffsRequested is when each pending handoff was requested;
+	// HandoffsReady maps identities to stored handoffs awaiting injection.
+	HandoffsRequested map[string]time.Time `json:"handoffs_requested,omitempty"`
+	HandoffsReady     map[string]string    `json:"handoffs_ready,omitempty"`
+
+	// RateLimits is each sender's rate limit window and counters.
+	RateLimits map[string]*RateLimitRecord `json:"rate_limits,omitempty"`
+
+	// Escalations is each agent's witness escalation history.
+	Escalations map[string]*EscalationHistory `json:"escalations,omitempty"`
+
+	// AgentHeartbeats is each running agent's heartbeat record, so an agent
+	// marked stuck is cleared even if it recovers under the next daemon.
+	AgentHeartbeats map[string]*AgentHeartbeat `json:"agent_heartbeats,omitempty"`
+
+	// DeaconLastStarted is when the daemon last started the Deacon, which
+	// shields a fresh session from the heartbeat check.
+	DeaconLastStarted time.Time `json:"deacon_last_started,omitzero"`
+
+	// PendingRequests are lifecycle requests accepted through the API but
+	// not yet run, oldest first. The API answered them 202, so they must
+	// not be lost to a restart.
+	PendingRequests []PendingRequest `json:"pending_requests,omitempty"`
+
+	// ContextCycles are the cycles scheduled for agents near the end of
+	// their context window, waiting for the agent to go idle.
+	ContextCycles map[string]*ContextCycle `json:"context_cycles,omitempty"`
+}
+
+// PendingRequest is a persisted API lifecycle request.
+type PendingRequest struct {
+	Request     *LifecycleRequest `json:"request"`
+	RequestedBy string            `json:"requested_by"`
+}
+
+// RateLimitRecord is one sender's persisted rate limit state.
+type RateLimitRecord struct {
+	Requests        []RateLimitRequest `json:"requests,omitempty"`
+	Stats           RateLimitStats     `json:"stats"`
+	SinceEscalation int                `json:"since_escalation,omitempty"`
+}
+
+// RateLimitRequest is a request counted in a sender's current window.
+type RateLimitRequest struct {
+	MessageID string    `json:"message_id"`
+	At        time.Time `json:"at"`
+}
+
+// LoadRuntimeState loads the daemon's runtime state from the town store.
+// A missing record yields an empty state.
+func LoadRuntimeState(townRoot string) (*RuntimeState, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(runtimeStateKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return &RuntimeState{}, nil
+		}
+		return nil, err
+	}
+
+	var rs RuntimeState
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// SaveRuntimeState saves the daemon's runtime state to the town store.
+func SaveRuntimeState(townRoot string, rs *RuntimeState) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(runtimeStateKey, data)
+}
+
+// captureRuntimeState copies the daemon's in-memory state.
+func (d *Daemon) captureRuntimeState(now time.Time) *RuntimeState {
+	rs := &RuntimeState{SavedAt: now, DeaconLastStarted: d.deaconLastStarted}
+
+	d.deathsMu.Lock()
+	if len(d.crashHistory) > 0 {
+		rs.CrashHistory = make(map[string][]time.Time, len(d.crashHistory))
+		for session, deaths := range d.crashHistory {
+			rs.CrashHistory[session] = append([]time.Time(nil), deaths...)
+		}
+	}
+	d.deathsMu.Unlock()
+
+	if d.restarts != nil && len(d.restarts.lastRestart) > 0 {
+		rs.LastRestart = make(map[string]time.Time, len(d.restarts.lastRestart))
+		for identity, at := range d.restarts.lastRestart {
+			rs.LastRestart[identity] = at
+		}
+	}
+
+	if h := d.handoffs; h != nil {
+		if len(h.requested) > 0 {
+			rs.HandoffsRequested = make(map[string]time.Time, len(h.requested))
+			for identity, at := range h.requested {
+				rs.HandoffsRequested[identity] = at
+			}
+		}
+		if len(h.ready) > 0 {
+			rs.HandoffsReady = make(map[string]string, len(h.ready))
+			for identity, path := range h.ready {
+				rs.HandoffsReady[identity] = path
+			}
+		}
+	}
+
+	if l := d.rateLimits; l != nil {
+		senders := make(map[string]bool)
+		for sender := range l.seen {
+			senders[sender] = true
+		}
+		for sender := range l.stats {
+			senders[sender] = true
+		}
+		for sender := range senders {
+			record := &RateLimitRecord{}
+			for _, hit := range l.seen[sender] {
+				record.Requests = append(record.Requests, RateLimitRequest{MessageID: hit.messageID, At: hit.at})
+			}
+			if stats := l.stats[sender]; stats != nil {
+				record.Stats = *stats
+				record.SinceEscalation = stats.sinceEscalation
+			}
+			if len(record.Requests) == 0 && record.Stats.Rejected == 0 {
+				continue
+			}
+			if rs.RateLimits == nil {
+				rs.RateLimits = make(map[string]*RateLimitRecord)
+			}
+			rs.RateLimits[sender] = record
+		}
+	}
+
+	rs.Escalations = d.escalations.snapshot()
+	rs.AgentHeartbeats = d.heartbeats.snapshot()
+	rs.ContextCycles = d.contextBudgets.snapshot()
+
+	d.apiMu.Lock()
+	for _, queued := range d.apiQueue {
+		rs.PendingRequests = append(rs.PendingRequests, PendingRequest{Request: queued.request, RequestedBy: queued.requestedBy})
+	}
+	d.apiMu.Unlock()
+	return rs
+}
+
+// restoreRuntimeState merges saved state into the daemon. Entries that have
+// aged out of their window by now are dropped.
+func (d *Daemon) restoreRuntimeState(rs *RuntimeState, now time.Time) {
+	if rs.DeaconLastStarted.After(d.deaconLastStarted) {
+		d.deaconLastStarted = rs.DeaconLastStarted
+	}
+
+	d.deathsMu.Lock()
+	if d.crashHistory == nil {
+		d.crashHistory = make(map[string][]time.Time)
+	}
+	for session, deaths := range rs.CrashHistory {
+		for _, at := range deaths {
+			if now.Sub(at) < crashLoopWindow {
+				d.crashHistory[session] = append(d.crashHistory[session], at)
+			}
+		}
+	}
+	d.deathsMu.Unlock()
+
+	if d.restarts != nil {
+		for identity, at := range rs.LastRestart {
+			if at.After(d.restarts.lastRestart[identity]) {
+				d.restarts.lastRestart[identity] = at
+			}
+		}
+	}
+
+	h := d.handoffState()
+	for identity, at := range rs.HandoffsRequested {
+		if _, ok := h.requested[identity]; !ok {
+			h.requested[identity] = at
+		}
+	}
+	for identity, path := range rs.HandoffsReady {
+		if _, ok := h.ready[identity]; !ok {
+			h.ready[identity] = path
+		}
+	}
+
+	l := d.rateLimitState()
+	for sender, record := range rs.RateLimits {
+		for _, req := range record.Requests {
+			if now.Sub(req.At) < l.window {
+				l.seen[sender] = append(l.seen[sender], rateLimitHit{messageID: req.MessageID, at: req.At})
+			}
+		}
+		if record.Stats.Rejected > 0 && l.stats[sender] == nil {
+			stats := record.Stats
+			stats.sinceEscalation = record.SinceEscalation
+			l.stats[sender] = &stats
+		}
+	}
+
+	e := d.escalationState()
+	for agent, history := range rs.Escalations {
+		if _, ok := e.history[agent]; !ok && history != nil {
+			if history.ByKind == nil {
+				history.ByKind = make(map[string]int)
+			}
+			e.history[agent] = history
+		}
+	}
+
+	if len(rs.AgentHeartbeats) > 0 {
+		m := d.heartbeatState()
+		for identity, h := range rs.AgentHeartbeats {
+			// Only stuck marks matter; the rest restart their clock
+			if _, ok := m.agents[identity]; !ok && h != nil && !h.StuckSince.IsZero() {
+				m.agents[identity] = h
+			}
+		}
+	}
+
+	if len(rs.ContextCycles) > 0 {
+		m := d.contextBudgetState()
+		for identity, c := range rs.ContextCycles {
+			if _, ok := m.pending[identity]; !ok && c != nil {
+				m.pending[identity] = c
+			}
+		}
+	}
+
+	// Saved requests are older than any queued since; skip ones still queued
+	d.apiMu.Lock()
+	queued := make(map[string]bool, len(d.apiQueue))
+	for _, q := range d.apiQueue {
+		queued[q.request.RequestID] = true
+	}
+	var restored []queuedLifecycle
+	for _, p := range rs.PendingRequests {
+		if p.Request != nil && !queued[p.Request.RequestID] {
+			restored = append(restored, queuedLifecycle{request: p.Request, requestedBy: p.RequestedBy})
+		}
+	}
+	d.apiQueue = append(restored, d.apiQueue...)
+	d.apiMu.Unlock()
+}
+
+// loadRuntimeState restores the state a previous daemon saved. Failures are
+// logged: a daemon that forgot its timers is still better than none.
+func (d *Daemon) loadRuntimeState() {
+	rs, err := LoadRuntimeState(d.config.TownRoot)
+	if err != nil {
+		d.logger.Printf("Warning: failed to load runtime state: %v", err)
+		return
+	}
+	if rs.SavedAt.IsZero() {
+		return
+	}
+	d.restoreRuntimeState(rs, time.Now())
+	d.logger.Printf("Restored runtime state saved at %s (%d crash histories, %d restart timers, %d rate limits, %d pending requests)",
+		rs.SavedAt.Format(time.RFC3339), len(rs.CrashHistory), len(rs.LastRestart), len(rs.RateLimits), len(rs.PendingRequests))
+}
+
+// saveRuntimeState persists the daemon's in-memory state.
+func (d *Daemon) saveRuntimeState() {
+	if err := SaveRuntimeState(d.config.TownRoot, d.captureRuntimeState(time.Now())); err != nil {
+		d.logger.Printf("Warning: failed to save runtime state: %v", err)
+	}
+}
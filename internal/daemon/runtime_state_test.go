@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuntimeStateRoundTrip(t *testing.T) {
+	townRoot := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.restarts = d.newRestartThrottle()
+	d.crashHistory = map[string][]time.Time{
+		"gt-gastown-witness": {now.Add(-2 * time.Minute), now.Add(-time.Minute)},
+	}
+	d.restarts.lastRestart["gastown-crew-max"] = now.Add(-5 * time.Minute)
+	d.handoffState().requested["gastown-crew-max"] = now.Add(-30 * time.Second)
+	d.handoffState().ready["gastown-crew-joe"] = "/town/daemon/handoffs/gastown-crew-joe/1.md"
+	limiter := d.rateLimitState()
+	limiter.admit("gastown-crew-max", "hq-msg-1", 6, now.Add(-time.Minute))
+	limiter.reject("gastown-crew-max", 3, now)
+	d.deaconLastStarted = now.Add(-10 * time.Second)
+	d.apiQueue = []queuedLifecycle{{request: &LifecycleRequest{RequestID: "req-1", Action: ActionRestart, From: "gastown-crew-max"}, requestedBy: "mayor"}}
+	d.contextBudgetState().pending["gastown-crew-joe"] = &ContextCycle{Usage: 88, Source: "state", ScheduledAt: now.Add(-time.Minute)}
+
+	if err := SaveRuntimeState(townRoot, d.captureRuntimeState(now)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(townRoot, "daemon", "runtime.json")); err != nil {
+		t.Fatalf("runtime state not written to daemon/runtime.json: %v", err)
+	}
+
+	rs, err := LoadRuntimeState(townRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := testDaemon()
+	restarted.config.TownRoot = townRoot
+	restarted.restarts = restarted.newRestartThrottle()
+	restarted.restoreRuntimeState(rs, now)
+
+	if got := len(restarted.crashHistory["gt-gastown-witness"]); got != 2 {
+		t.Errorf("crash history = %d deaths, want 2", got)
+	}
+	if got := restarted.restarts.lastRestart["gastown-crew-max"]; !got.Equal(now.Add(-5 * time.Minute)) {
+		t.Errorf("last restart = %v", got)
+	}
+	if _, ok := restarted.handoffs.requested["gastown-crew-max"]; !ok {
+		t.Error("pending handoff request lost")
+	}
+	if got := restarted.handoffs.ready["gastown-crew-joe"]; got == "" {
+		t.Error("ready handoff lost")
+	}
+	if got := len(restarted.rateLimits.seen["gastown-crew-max"]); got != 1 {
+		t.Errorf("rate limit window = %d requests, want 1", got)
+	}
+	stats := restarted.rateLimits.stats["gastown-crew-max"]
+	if stats == nil || stats.Rejected != 1 || stats.sinceEscalation != 1 {
+		t.Errorf("rate limit stats = %+v", stats)
+	}
+	// The counted message is not counted again after the restart
+	for i := 0; i < 5; i++ {
+		restarted.rateLimits.admit("gastown-crew-max", "hq-msg-1", 1, now)
+	}
+	if got := len(restarted.rateLimits.seen["gastown-crew-max"]); got != 1 {
+		t.Errorf("re-admitting a restored message counted it again (%d)", got)
+	}
+	if !restarted.deaconLastStarted.Equal(now.Add(-10 * time.Second)) {
+		t.Errorf("deacon last started = %v", restarted.deaconLastStarted)
+	}
+	if len(restarted.apiQueue) != 1 || restarted.apiQueue[0].request.RequestID != "req-1" || restarted.apiQueue[0].requestedBy != "mayor" {
+		t.Errorf("queued API request lost: %+v", restarted.apiQueue)
+	}
+	if c := restarted.contextBudgetState().pending["gastown-crew-joe"]; c == nil || c.Usage != 88 {
+		t.Errorf("scheduled context cycle lost: %+v", c)
+	}
+	// Restoring again (regaining leadership) does not queue the request twice
+	restarted.restoreRuntimeState(rs, now)
+	if len(restarted.apiQueue) != 1 {
+		t.Errorf("restored request queued %d times", len(restarted.apiQueue))
+	}
+}
+
+func TestRestoreRuntimeStateDropsExpired(t *testing.T) {
+	now := time.Now()
+	d := testDaemon()
+	d.restarts = d.newRestartThrottle()
+	d.restoreRuntimeState(&RuntimeState{
+		SavedAt: now.Add(-2 * time.Hour),
+		CrashHistory: map[string][]time.Time{
+			"gt-gastown-witness": {now.Add(-2 * time.Hour), now.Add(-time.Minute)},
+		},
+		RateLimits: map[string]*RateLimitRecord{
+			"gastown-crew-max": {Requests: []RateLimitRequest{{MessageID: "hq-msg-1", At: now.Add(-time.Hour)}}},
+		},
+	}, now)
+
+	if got := len(d.crashHistory["gt-gastown-witness"]); got != 1 {
+		t.Errorf("crash history = %d deaths, want 1 (old death outside the window)", got)
+	}
+	if got := len(d.rateLimits.seen["gastown-crew-max"]); got != 0 {
+		t.Errorf("rate limit window = %d requests, want 0", got)
+	}
+}
+
+func TestLoadRuntimeStateMissing(t *testing.T) {
+	rs, err := LoadRuntimeState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.SavedAt.IsZero() || rs.CrashHistory != nil {
+		t.Errorf("missing runtime state = %+v, want empty", rs)
+	}
+}
@@ -0,0 +1,263 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/storage"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// Safe mode: if the daemon keeps dying before its first heartbeat completes
+// (bad config, corrupt store, a panic in startup code), the next start comes
+// up in safe mode instead of joining the crash loop. A safe-mode daemon holds
+// the lock and PID file, reports why it is in safe mode, and ignores lifecycle
+// signals; it runs no heartbeat, executors, or watchers. Safe mode is sticky
+// until an operator clears it with 'gt daemon safe-mode clear'.
+//
+// The startup journal is a plain file rather than a town store key so it
+// stays readable when the store itself is what's broken.
+
+const (
+	// safeModeCrashThreshold is how many unstable startups inside
+	// safeModeWindow put the next start into safe mode.
+	safeModeCrashThreshold = 3
+	safeModeWindow         = 10 * time.Minute
+
+	// maxStartupRecords caps the journal.
+	maxStartupRecords = 10
+)
+
+// StartupRecord is one daemon start attempt.
+type StartupRecord struct {
+	StartedAt time.Time `json:"started_at"`
+	PID       int       `json:"pid,omitempty"`
+
+	// Stable is set once the first heartbeat completes (or the daemon shuts
+	// down cleanly). An attempt that never became stable crashed.
+	Stable bool `json:"stable,omitempty"`
+
+	// Cause is the panic or error that ended the attempt, when known.
+	Cause string `json:"cause,omitempty"`
+}
+
+// StartupJournal records recent start attempts and the safe-mode state.
+type StartupJournal struct {
+	Attempts []StartupRecord `json:"attempts,omitempty"`
+
+	SafeMode       bool      `json:"safe_mode,omitempty"`
+	SafeModeSince  time.Time `json:"safe_mode_since,omitzero"`
+	SafeModeReason string    `json:"safe_mode_reason,omitempty"`
+}
+
+// StartupJournalFile returns the path to the startup journal.
+func StartupJournalFile(townRoot string) string {
+	return filepath.Join(townRoot, "daemon", "startups.json")
+}
+
+// LoadStartupJournal reads the startup journal. A missing journal is empty.
+func LoadStartupJournal(townRoot string) (*StartupJournal, error) {
+	data, err := os.ReadFile(StartupJournalFile(townRoot))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &StartupJournal{}, nil
+		}
+		return nil, err
+	}
+	var j StartupJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", StartupJournalFile(townRoot), err)
+	}
+	return &j, nil
+}
+
+func saveStartupJournal(townRoot string, j *StartupJournal) error {
+	if len(j.Attempts) > maxStartupRecords {
+		j.Attempts = j.Attempts[len(j.Attempts)-maxStartupRecords:]
+	}
+	if err := os.MkdirAll(filepath.Dir(StartupJournalFile(townRoot)), 0755); err != nil {
+		return err
+	}
+	return util.AtomicWriteJSON(StartupJournalFile(townRoot), j)
+}
+
+// ClearSafeMode removes the startup journal, so the next start is normal.
+func ClearSafeMode(townRoot string) error {
+	err := os.Remove(StartupJournalFile(townRoot))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RecordStartupFailure journals a start attempt that failed before the
+// daemon could run (e.g. its log file couldn't be opened).
+func RecordStartupFailure(townRoot string, cause error) {
+	j, err := LoadStartupJournal(townRoot)
+	if err != nil {
+		j = &StartupJournal{}
+	}
+	j.Attempts = append(j.Attempts, StartupRecord{StartedAt: time.Now(), PID: os.Getpid(), Cause: cause.Error()})
+	_ = saveStartupJournal(townRoot, j)
+}
+
+// RecentCrashes returns the unstable attempts inside the safe-mode window.
+func (j *StartupJournal) RecentCrashes(now time.Time) []StartupRecord {
+	var crashes []StartupRecord
+	for _, a := range j.Attempts {
+		if !a.Stable && now.Sub(a.StartedAt) <= safeModeWindow {
+			crashes = append(crashes, a)
+		}
+	}
+	return crashes
+}
+
+// safeModeReason explains why the daemon is entering safe mode: the last
+// recorded crash cause, else a preflight problem, else the crash count.
+func safeModeReason(crashes []StartupRecord, preflightErr error) string {
+	for i := len(crashes) - 1; i >= 0; i-- {
+		if crashes[i].Cause != "" {
+			return fmt.Sprintf("%d failed starts in %v; last cause: %s", len(crashes), safeModeWindow, crashes[i].Cause)
+		}
+	}
+	if preflightErr != nil {
+		return fmt.Sprintf("%d failed starts in %v; preflight: %v", len(crashes), safeModeWindow, preflightErr)
+	}
+	return fmt.Sprintf("%d failed starts in %v with no recorded cause (see daemon.log)", len(crashes), safeModeWindow)
+}
+
+// preflight checks the inputs most likely to crash startup: the patrol
+// config and the daemon state in the town store.
+func preflight(townRoot string) error {
+	if data, err := os.ReadFile(PatrolConfigFile(townRoot)); err == nil {
+		var config DaemonPatrolConfig
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("%s: %w", PatrolConfigFile(townRoot), err)
+		}
+	}
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("opening town store: %w", err)
+	}
+	defer store.Close()
+	if data, err := store.Get(stateKey); err == nil {
+		var state State
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("daemon state is corrupt: %w", err)
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("reading daemon state: %w", err)
+	}
+	return nil
+}
+
+// beginStartup journals this start attempt and decides whether to come up
+// in safe mode. Returns the reason when safe mode applies.
+func (d *Daemon) beginStartup(now time.Time) (bool, string) {
+	townRoot := d.config.TownRoot
+	j, err := LoadStartupJournal(townRoot)
+	if err != nil {
+		d.logger.Printf("Warning: startup journal unreadable, starting fresh: %v", err)
+		j = &StartupJournal{}
+	}
+
+	crashes := j.RecentCrashes(now)
+	if !j.SafeMode && len(crashes) >= safeModeCrashThreshold {
+		j.SafeMode = true
+		j.SafeModeSince = now
+		j.SafeModeReason = safeModeReason(crashes, preflight(townRoot))
+	}
+
+	// A safe-mode start can't crash in startup code, so it is stable at once.
+	j.Attempts = append(j.Attempts, StartupRecord{StartedAt: now, PID: os.Getpid(), Stable: j.SafeMode})
+	if err := saveStartupJournal(townRoot, j); err != nil {
+		d.logger.Printf("Warning: failed to write startup journal: %v", err)
+	}
+	return j.SafeMode, j.SafeModeReason
+}
+
+// updateStartupRecord applies fn to this process's journal record.
+func (d *Daemon) updateStartupRecord(fn func(*StartupRecord)) {
+	j, err := LoadStartupJournal(d.config.TownRoot)
+	if err != nil {
+		return
+	}
+	for i := len(j.Attempts) - 1; i >= 0; i-- {
+		if j.Attempts[i].PID == os.Getpid() {
+			fn(&j.Attempts[i])
+			if err := saveStartupJournal(d.config.TownRoot, j); err != nil {
+				d.logger.Printf("Warning: failed to write startup journal: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// markStartupStable records that this start got through its first heartbeat.
+func (d *Daemon) markStartupStable() {
+	if d.startupStable {
+		return
+	}
+	d.startupStable = true
+	d.updateStartupRecord(func(r *StartupRecord) { r.Stable = true })
+}
+
+// recordStartupPanic journals a panic that escapes Run before the daemon is
+// stable, then re-panics so the crash is still visible.
+func (d *Daemon) recordStartupPanic() {
+	if r := recover(); r != nil {
+		if !d.startupStable {
+			d.updateStartupRecord(func(rec *StartupRecord) { rec.Cause = fmt.Sprintf("panic: %v", r) })
+		}
+		panic(r)
+	}
+}
+
+// runSafeMode keeps a minimal daemon alive for diagnosis: it reports status
+// and waits for a stop signal, executing nothing.
+func (d *Daemon) runSafeMode(reason string) error {
+	d.logger.Printf("SAFE MODE: %s", reason)
+	d.logger.Println("SAFE MODE: heartbeat, lifecycle processing, and watchers are disabled")
+	d.logger.Println("SAFE MODE: fix the cause, then run 'gt daemon safe-mode clear' and 'gt daemon restart'")
+
+	state := &State{
+		Running:        true,
+		PID:            os.Getpid(),
+		StartedAt:      time.Now(),
+		SafeMode:       true,
+		SafeModeReason: reason,
+	}
+	if err := d.saveState(state, "daemon/safe-mode"); err != nil {
+		d.logger.Printf("Warning: failed to save state: %v", err)
+	}
+	d.notify(notifier.EventDaemonSafeMode, map[string]string{
+		"pid":    strconv.Itoa(os.Getpid()),
+		"reason": reason,
+	})
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, daemonSignals()...)
+	for {
+		select {
+		case <-d.ctx.Done():
+			d.logger.Println("Safe-mode daemon stopping")
+			return nil
+		case sig := <-sigChan:
+			if isLifecycleSignal(sig) {
+				d.logger.Println("SAFE MODE: ignoring lifecycle signal")
+				continue
+			}
+			d.logger.Printf("Received signal %v, safe-mode daemon stopping", sig)
+			state.Running = false
+			_ = d.saveState(state, "daemon/safe-mode")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// requestedBySchedule marks shutdowns and restarts made for activity
+// schedules in audit entries and restart history.
+const requestedBySchedule = "daemon/schedule"
+
+// Schedule modes.
+const (
+	// ScheduleModePause stops running agents when their hours end and
+	// starts them again when the next period begins.
+	ScheduleModePause = "pause"
+
+	// ScheduleModeNoRestart leaves running agents alone but doesn't restart
+	// them outside their hours once they stop.
+	ScheduleModeNoRestart = "no_restart"
+)
+
+// ActivitySchedule sets the working hours of a group of rig agents, under
+// "schedules" in mayor/daemon.json:
+//
+//	"schedules": [
+//	  {"rigs": ["gastown"], "hours": "08:00-19:00", "days": "mon-fri",
+//	   "timezone": "America/New_York"},
+//	  {"agents": ["beads-crew-*"], "hours": "09:00-17:00", "mode": "no_restart"}
+//	]
+//
+// Outside its hours the daemon stops the agent (gracefully, like a
+// shutdown request) and starts it again when the next period begins, or in
+// "no_restart" mode just declines to restart it. Rigs that only need
+// daytime coverage stop spending tokens overnight. The first schedule
+// matching an agent applies; agents no schedule matches run around the
+// clock. Town-level agents (mayor, deacon) are never scheduled.
+type ActivitySchedule struct {
+	// Agents are the identities covered, exact or path.Match globs
+	// (e.g. "gastown-crew-*").
+	Agents []string `json:"agents,omitempty"`
+
+	// Rigs covers every agent of the named rigs. With neither Agents nor
+	// Rigs the schedule covers every rig agent.
+	Rigs []string `json:"rigs,omitempty"`
+
+	// Hours is the daily working period, "HH:MM-HH:MM" in Timezone. May
+	// wrap midnight (e.g. "22:00-06:00").
+	Hours string `json:"hours"`
+
+	// Days are the days a period may begin on: comma-separated names or
+	// ranges ("mon-fri", "mon,wed,fri"). Default: every day.
+	Days string `json:"days,omitempty"`
+
+	// Timezone is the IANA zone Hours are in (default: the daemon's local
+	// time).
+	Timezone string `json:"timezone,omitempty"`
+
+	// Mode is "pause" (default) or "no_restart".
+	Mode string `json:"mode,omitempty"`
+}
+
+// covers reports whether the schedule applies to identity.
+func (s *ActivitySchedule) covers(identity string, parsed *ParsedIdentity) bool {
+	if len(s.Agents) == 0 && len(s.Rigs) == 0 {
+		return true
+	}
+	return slices.Contains(s.Rigs, parsed.RigName) || matchesPrewarmAgents(identity, s.Agents)
+}
+
+// pauses reports whether the schedule stops running agents.
+func (s *ActivitySchedule) pauses() bool {
+	return s.Mode == "" || s.Mode == ScheduleModePause
+}
+
+// compiledSchedule is an ActivitySchedule with its fields parsed.
+type compiledSchedule struct {
+	window prewarmWindow
+	days   [7]bool // by time.Weekday
+	loc    *time.Location
+}
+
+// compile parses the schedule's hours, days, and timezone.
+func (s *ActivitySchedule) compile() (*compiledSchedule, error) {
+	window, err := parsePrewarmWindow(s.Hours)
+	if err != nil {
+		return nil, fmt.Errorf("hours: %w", err)
+	}
+	c := &compiledSchedule{window: window, loc: time.Local}
+	if s.Timezone != "" {
+		if c.loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	if c.days, err = parseScheduleDays(s.Days); err != nil {
+		return nil, fmt.Errorf("days: %w", err)
+	}
+	if s.Mode != "" && s.Mode != ScheduleModePause && s.Mode != ScheduleModeNoRestart {
+		return nil, fmt.Errorf("mode: unknown mode %q", s.Mode)
+	}
+	return c, nil
+}
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseScheduleDays parses "mon-fri", "sat,sun", etc. Ranges may wrap the
+// week ("fri-mon"). Empty means every day.
+func parseScheduleDays(s string) ([7]bool, error) {
+	var days [7]bool
+	if strings.TrimSpace(s) == "" {
+		return [7]bool{true, true, true, true, true, true, true}, nil
+	}
+	day := func(name string) (time.Weekday, error) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) >= 3 {
+			if d, ok := scheduleWeekdays[name[:3]]; ok {
+				return d, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown day %q", name)
+	}
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := day(from)
+		if err != nil {
+			return days, err
+		}
+		last := first
+		if isRange {
+			if last, err = day(to); err != nil {
+				return days, err
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// active reports whether now falls in a working period. A period that
+// wraps midnight belongs to the day it began on.
+func (c *compiledSchedule) active(now time.Time) bool {
+	opened, in := c.window.opened(now.In(c.loc))
+	return in && c.days[opened.Weekday()]
+}
+
+// nextStart returns when the next working period after now begins.
+func (c *compiledSchedule) nextStart(now time.Time) time.Time {
+	local := now.In(c.loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+	for i := 0; i <= 7; i++ {
+		day := midnight.AddDate(0, 0, i)
+		start := time.Date(day.Year(), day.Month(), day.Day(), c.window.start/60, c.window.start%60, 0, 0, c.loc)
+		if start.After(local) && c.days[start.Weekday()] {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// scheduleFor returns the schedule covering identity, compiled, or nil if
+// the agent runs around the clock. Invalid schedules are skipped with a
+// warning.
+func (d *Daemon) scheduleFor(identity string) (*ActivitySchedule, *compiledSchedule) {
+	if d.patrolConfig == nil || len(d.patrolConfig.Schedules) == 0 {
+		return nil, nil
+	}
+	identity = historyIdentity(identity)
+	parsed, err := parseIdentity(identity)
+	if err != nil || parsed.RigName == "" {
+		return nil, nil
+	}
+	for i, s := range d.patrolConfig.Schedules {
+		if s == nil || !s.covers(identity, parsed) {
+			continue
+		}
+		c, err := s.compile()
+		if err != nil {
+			d.logger.Printf("Warning: ignoring schedules[%d]: %v", i, err)
+			continue
+		}
+		return s, c
+	}
+	return nil, nil
+}
+
+// outsideSchedule reports whether identity is outside its working hours,
+// with a reason naming when they resume.
+func (d *Daemon) outsideSchedule(identity string, now time.Time) (bool, string) {
+	s, c := d.scheduleFor(identity)
+	if s == nil || c.active(now) {
+		return false, ""
+	}
+	reason := fmt.Sprintf("outside working hours (%s %s)", s.Hours, c.loc)
+	if next := c.nextStart(now); !next.IsZero() {
+		reason += ", resumes " + next.Format("Mon 15:04")
+	}
+	return true, reason
+}
+
+// enforceSchedules stops scheduled agents whose working hours have ended
+// and starts the ones it stopped once their next period begins. Stopped
+// agents are remembered in state, so only agents the schedule stopped are
+// started again.
+func (d *Daemon) enforceSchedules(state *State, now time.Time) {
+	if d.patrolConfig == nil || len(d.patrolConfig.Schedules) == 0 {
+		return
+	}
+	for _, identity := range d.managedIdentities() {
+		s, c := d.scheduleFor(identity)
+		if s == nil {
+			continue
+		}
+		sessionName := d.identityToSession(identity)
+		if sessionName == "" {
+			continue
+		}
+		running, err := d.tmux.HasSession(sessionName)
+		if err != nil {
+			continue
+		}
+
+		if c.active(now) {
+			pausedAt, paused := state.SchedulePaused[identity]
+			if !paused {
+				continue
+			}
+			if running {
+				delete(state.SchedulePaused, identity)
+				continue
+			}
+			if d.config.DryRun {
+				d.logger.Printf("[dry-run] Schedule: would resume %s (paused %s)", identity, pausedAt.Format(time.RFC3339))
+				continue
+			}
+			if err := d.restartSession(sessionName, identity); err != nil {
+				d.logger.Printf("Warning: schedule: failed to resume %s: %v", identity, err)
+				continue // Try again next heartbeat
+			}
+			d.logger.Printf("Schedule: resumed %s for its working hours (%s)", identity, s.Hours)
+			delete(state.SchedulePaused, identity)
+			continue
+		}
+
+		if !running || !s.pauses() {
+			continue
+		}
+		_, reason := d.outsideSchedule(identity, now)
+		request := &LifecycleRequest{
+			From:        identity,
+			Action:      ActionShutdown,
+			Timestamp:   now,
+			DryRun:      d.config.DryRun,
+			Reason:      reason,
+			RequestedBy: requestedBySchedule,
+		}
+		if err := d.executeLifecycleAction(request); err != nil {
+			d.logger.Printf("Warning: schedule: failed to pause %s: %v", identity, err)
+			continue
+		}
+		if request.DryRun {
+			continue
+		}
+		d.logger.Printf("Schedule: paused %s, %s", identity, reason)
+		if state.SchedulePaused == nil {
+			state.SchedulePaused = make(map[string]time.Time)
+		}
+		state.SchedulePaused[identity] = now
+	}
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleDays(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // days set, Sunday first
+	}{
+		{"", "SMTWTFS"},
+		{"mon-fri", "-MTWTF-"},
+		{"fri-mon", "SM---FS"},
+		{"sat, sunday", "S-----S"},
+		{"Mon,wed,FRI", "-M-W-F-"},
+	}
+	for _, tt := range tests {
+		days, err := parseScheduleDays(tt.in)
+		if err != nil {
+			t.Errorf("parseScheduleDays(%q): %v", tt.in, err)
+			continue
+		}
+		got := ""
+		for i, on := range days {
+			if on {
+				got += string("SMTWTFS"[i])
+			} else {
+				got += "-"
+			}
+		}
+		if got != tt.want {
+			t.Errorf("parseScheduleDays(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if _, err := parseScheduleDays("mon-funday"); err == nil {
+		t.Error("unknown day should fail")
+	}
+}
+
+func TestCompiledScheduleActive(t *testing.T) {
+	s := &ActivitySchedule{Hours: "09:00-18:00", Days: "mon-fri", Timezone: "America/New_York"}
+	c, err := s.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ny := c.loc
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 10, 14, 9, 0, 0, 0, ny), true},   // Wed opening
+		{time.Date(2026, 10, 14, 17, 59, 0, 0, ny), true}, // Wed closing
+		{time.Date(2026, 10, 14, 18, 0, 0, 0, ny), false},
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, ny), false}, // Saturday
+		// 14:00 UTC is 10:00 in New York
+		{time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := c.active(tt.at); got != tt.want {
+			t.Errorf("active(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	// Friday evening: next period is Monday morning
+	next := c.nextStart(time.Date(2026, 10, 16, 19, 0, 0, 0, ny))
+	if want := time.Date(2026, 10, 19, 9, 0, 0, 0, ny); !next.Equal(want) {
+		t.Errorf("nextStart = %v, want %v", next, want)
+	}
+
+	// A night shift belongs to the day it began on
+	night, err := (&ActivitySchedule{Hours: "22:00-06:00", Days: "fri", Timezone: "UTC"}).compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !night.active(time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)) {
+		t.Error("early Saturday should be in Friday's night shift")
+	}
+	if night.active(time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)) {
+		t.Error("early Friday belongs to Thursday's shift, which isn't scheduled")
+	}
+
+	for _, bad := range []*ActivitySchedule{
+		{Hours: "9-5"},
+		{Hours: "09:00-17:00", Timezone: "Mars/Olympus"},
+		{Hours: "09:00-17:00", Mode: "nap"},
+	} {
+		if _, err := bad.compile(); err == nil {
+			t.Errorf("compile(%+v) should fail", bad)
+		}
+	}
+}
+
+func TestOutsideSchedule(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{Schedules: []*ActivitySchedule{
+		{Agents: []string{"gastown-crew-*"}, Hours: "10:00-16:00", Timezone: "UTC", Mode: ScheduleModeNoRestart},
+		{Rigs: []string{"gastown"}, Hours: "08:00-20:00", Timezone: "UTC"},
+	}}
+	evening := time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)
+
+	// First matching schedule wins: the crew's shorter hours
+	outside, reason := d.outsideSchedule("gastown/crew/max", evening)
+	if !outside || !strings.Contains(reason, "10:00-16:00") || !strings.Contains(reason, "resumes Thu 10:00") {
+		t.Errorf("crew at 18:00 = %v, %q", outside, reason)
+	}
+	if outside, _ := d.outsideSchedule("gastown-witness", evening); outside {
+		t.Error("witness is inside the rig's hours at 18:00")
+	}
+	if outside, _ := d.outsideSchedule("gastown-witness", evening.Add(3*time.Hour)); !outside {
+		t.Error("witness is outside the rig's hours at 21:00")
+	}
+	for _, identity := range []string{"beads-witness", "mayor", "deacon"} {
+		if outside, _ := d.outsideSchedule(identity, evening.Add(6*time.Hour)); outside {
+			t.Errorf("%s has no schedule and should run around the clock", identity)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/feed"
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// selfUpdateTimeout bounds each download from the release location.
+const selfUpdateTimeout = 5 * time.Minute
+
+// ErrChecksumMismatch is returned when a downloaded binary doesn't match its
+// published checksum. The running binary is left untouched.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// SelfUpdateConfig lets the daemon replace its own binary, under
+// "self_update" in mayor/daemon.json:
+//
+//	"self_update": {
+//	  "source": "https://releases.example.com/gt/latest/gt-{os}-{arch}",
+//	  "check_interval": "6h"
+//	}
+//
+// An update is applied when requested ('gt daemon update') or, with
+// CheckInterval, whenever the published checksum differs from the running
+// binary's. The daemon verifies the download, swaps it in (keeping the old
+// binary as <binary>.prev), and execs it in place with the same PID. Pending
+// lifecycle mail stays in the inbox and runtime state is saved first, so
+// queued actions survive the upgrade. A new binary that crash-loops at
+// startup puts the daemon in safe mode.
+type SelfUpdateConfig struct {
+	// Source is the URL (http, https) or file path of the release binary.
+	// {os} and {arch} expand to the daemon's GOOS and GOARCH.
+	Source string `json:"source"`
+
+	// Checksum is where the binary's SHA-256 is published, as hex optionally
+	// followed by a file name (sha256sum format). Default: Source + ".sha256".
+	Checksum string `json:"checksum,omitempty"`
+
+	// CheckInterval checks for a new release on this interval (Go duration
+	// string). Default: only on request.
+	CheckInterval string `json:"check_interval,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *SelfUpdateConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Source == "" {
+		return fmt.Errorf("self_update: source is required")
+	}
+	if c.CheckInterval != "" {
+		if _, err := time.ParseDuration(c.CheckInterval); err != nil {
+			return fmt.Errorf("self_update: invalid check_interval %q: %w", c.CheckInterval, err)
+		}
+	}
+	return nil
+}
+
+// expand fills in the {os} and {arch} placeholders.
+func (c *SelfUpdateConfig) expand(s string) string {
+	return strings.NewReplacer("{os}", runtime.GOOS, "{arch}", runtime.GOARCH).Replace(s)
+}
+
+func (c *SelfUpdateConfig) binarySource() string {
+	return c.expand(c.Source)
+}
+
+func (c *SelfUpdateConfig) checksumSource() string {
+	if c.Checksum != "" {
+		return c.expand(c.Checksum)
+	}
+	return c.binarySource() + ".sha256"
+}
+
+// fetchRelease reads a release file from a URL or local path.
+func fetchRelease(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	client := &http.Client{Timeout: selfUpdateTimeout}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseChecksum extracts the hex SHA-256 from a checksum file.
+func parseChecksum(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+	sum := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("malformed checksum %q", fields[0])
+	}
+	return sum, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// StageUpdate replaces the binary at exe with the published release if its
+// checksum differs, verifying the download first. The old binary is kept
+// as <exe>.prev. Returns false (and changes nothing) if exe is already the
+// published release.
+func StageUpdate(cfg *SelfUpdateConfig, exe string) (bool, error) {
+	data, err := fetchRelease(cfg.checksumSource())
+	if err != nil {
+		return false, fmt.Errorf("fetching checksum: %w", err)
+	}
+	want, err := parseChecksum(data)
+	if err != nil {
+		return false, err
+	}
+	if have, err := fileSHA256(exe); err == nil && have == want {
+		return false, nil
+	}
+
+	binary, err := fetchRelease(cfg.binarySource())
+	if err != nil {
+		return false, fmt.Errorf("fetching binary: %w", err)
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return false, fmt.Errorf("%w: %s has sha256 %s, want %s", ErrChecksumMismatch, cfg.binarySource(), got, want)
+	}
+
+	// Write next to the binary so the final rename is atomic
+	staged := exe + ".new"
+	if err := os.WriteFile(staged, binary, 0755); err != nil {
+		return false, fmt.Errorf("staging binary: %w", err)
+	}
+	_ = os.Remove(exe + ".prev")
+	if err := copyFile(exe, exe+".prev"); err != nil {
+		_ = os.Remove(staged)
+		return false, fmt.Errorf("keeping previous binary: %w", err)
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		_ = os.Remove(staged)
+		return false, fmt.Errorf("replacing binary: %w", err)
+	}
+	return true, nil
+}
+
+// UpdateRequest asks the running daemon to update itself.
+type UpdateRequest struct {
+	RequestedAt time.Time `json:"requested_at"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+}
+
+// UpdateRequestFile returns the path of the pending update request.
+func UpdateRequestFile(townRoot string) string {
+	return filepath.Join(townRoot, "daemon", "update-request.json")
+}
+
+// RequestUpdate asks the daemon to check for and apply an update on its
+// next heartbeat (or sooner, if it is woken with the lifecycle signal).
+func RequestUpdate(townRoot, requestedBy string) error {
+	if err := os.MkdirAll(filepath.Dir(UpdateRequestFile(townRoot)), 0755); err != nil {
+		return err
+	}
+	return util.AtomicWriteJSON(UpdateRequestFile(townRoot), &UpdateRequest{
+		RequestedAt: time.Now().UTC(),
+		RequestedBy: requestedBy,
+	})
+}
+
+// takeUpdateRequest returns and removes the pending update request, or nil.
+func takeUpdateRequest(townRoot string) *UpdateRequest {
+	data, err := os.ReadFile(UpdateRequestFile(townRoot))
+	if err != nil {
+		return nil
+	}
+	_ = os.Remove(UpdateRequestFile(townRoot))
+	req := &UpdateRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		req.RequestedBy = "unknown"
+	}
+	return req
+}
+
+// checkSelfUpdate applies a release update when one was requested or the
+// check interval has passed, then restarts the daemon in place. On success
+// it does not return.
+func (d *Daemon) checkSelfUpdate(state *State, now time.Time) {
+	cfg := d.patrolConfig.selfUpdateConfig()
+	req := takeUpdateRequest(d.config.TownRoot)
+	if cfg == nil {
+		if req != nil {
+			d.logger.Printf("Update requested by %s, but self_update is not configured", req.RequestedBy)
+		}
+		return
+	}
+	interval := d.lifecycleDuration("self_update.check_interval", cfg.CheckInterval, 0)
+	due := interval > 0 && now.Sub(d.lastUpdateCheck) >= interval
+	if req == nil && !due {
+		return
+	}
+	d.lastUpdateCheck = now
+
+	locate := d.selfBinary
+	if locate == nil {
+		locate = os.Executable
+	}
+	exe, err := locate()
+	if err != nil {
+		d.logger.Printf("Warning: self-update: cannot locate own binary: %v", err)
+		return
+	}
+	updated, err := StageUpdate(cfg, exe)
+	if err != nil {
+		d.logger.Printf("Warning: self-update failed: %v", err)
+		return
+	}
+	if !updated {
+		if req != nil {
+			d.logger.Printf("Self-update: %s is already the published release", exe)
+		}
+		return
+	}
+
+	d.logger.Printf("Self-update: installed new %s, restarting in place", exe)
+	d.notify(notifier.EventDaemonUpdate, map[string]string{"binary": exe})
+	d.restartInPlace(state, exe)
+}
+
+// restartInPlace stops the daemon's background work, saves its state, and
+// execs exe with the same arguments and PID. The leader lock is released by
+// the exec and retaken by the new process at startup. Returns only if the
+// exec fails, in which case the daemon carries on with the old code.
+func (d *Daemon) restartInPlace(state *State, exe string) {
+	if d.curator != nil {
+		d.curator.Stop()
+	}
+	if d.convoyWatcher != nil {
+		d.convoyWatcher.Stop()
+	}
+	d.stopAPI()
+	d.flushTraces()
+	if err := d.saveState(state, "daemon/self-update"); err != nil {
+		d.logger.Printf("Warning: failed to save state: %v", err)
+	}
+	d.saveRuntimeState()
+
+	exec := d.execSelf
+	if exec == nil {
+		exec = execBinary
+	}
+	err := exec(exe)
+	d.logger.Printf("Warning: restart in place failed, continuing on the old binary: %v", err)
+	d.startBackground()
+}
+
+// startBackground (re)starts the feed curator, convoy watcher, and API.
+func (d *Daemon) startBackground() {
+	d.curator = feed.NewCurator(d.config.TownRoot)
+	if err := d.curator.Start(); err != nil {
+		d.logger.Printf("Warning: failed to start feed curator: %v", err)
+	} else {
+		d.logger.Println("Feed curator started")
+	}
+
+	d.convoyWatcher = NewConvoyWatcher(d.config.TownRoot, d.logger.Printf)
+	if err := d.convoyWatcher.Start(); err != nil {
+		d.logger.Printf("Warning: failed to start convoy watcher: %v", err)
+	} else {
+		d.logger.Println("Convoy watcher started")
+	}
+
+	d.startAPI()
+	d.subscribeMailTransport()
+}
+
+// SelfUpdateConfigured reports whether the town's daemon.json configures
+// self-update, for 'gt daemon update'.
+func SelfUpdateConfigured(townRoot string) bool {
+	return LoadPatrolConfig(townRoot).selfUpdateConfig() != nil
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRelease publishes binary and its checksum file under dir.
+func writeRelease(t *testing.T, dir string, binary []byte) *SelfUpdateConfig {
+	t.Helper()
+	src := filepath.Join(dir, "gt-release")
+	if err := os.WriteFile(src, binary, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(binary)
+	if err := os.WriteFile(src+".sha256", []byte(hex.EncodeToString(sum[:])+"  gt-release\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &SelfUpdateConfig{Source: src}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("x"))
+	want := hex.EncodeToString(sum[:])
+	if got, err := parseChecksum([]byte(want + "  gt-linux-amd64\n")); err != nil || got != want {
+		t.Errorf("parseChecksum = %q, %v; want %q", got, err, want)
+	}
+	for _, bad := range []string{"", "abc", "zz" + want[2:]} {
+		if _, err := parseChecksum([]byte(bad)); err == nil {
+			t.Errorf("parseChecksum(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStageUpdate(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "gt")
+	if err := os.WriteFile(exe, []byte("old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same build published: nothing to do
+	cfg := writeRelease(t, t.TempDir(), []byte("old build"))
+	if updated, err := StageUpdate(cfg, exe); err != nil || updated {
+		t.Fatalf("StageUpdate(same) = %v, %v; want false, nil", updated, err)
+	}
+
+	cfg = writeRelease(t, t.TempDir(), []byte("new build"))
+	if updated, err := StageUpdate(cfg, exe); err != nil || !updated {
+		t.Fatalf("StageUpdate(new) = %v, %v; want true, nil", updated, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new build" {
+		t.Errorf("binary = %q, want the new build", data)
+	}
+	if data, _ := os.ReadFile(exe + ".prev"); string(data) != "old build" {
+		t.Errorf("previous binary = %q, want the old build", data)
+	}
+	if info, err := os.Stat(exe); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("new binary is not executable: %v", err)
+	}
+}
+
+func TestStageUpdate_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "gt")
+	if err := os.WriteFile(exe, []byte("old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := writeRelease(t, t.TempDir(), []byte("new build"))
+	if err := os.WriteFile(cfg.Source, []byte("tampered build"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := StageUpdate(cfg, exe); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("err = %v, want ErrChecksumMismatch", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old build" {
+		t.Errorf("binary = %q, should be untouched", data)
+	}
+	if _, err := os.Stat(exe + ".new"); !os.IsNotExist(err) {
+		t.Error("a rejected download should not be staged")
+	}
+}
+
+func TestCheckSelfUpdate(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	exe := filepath.Join(t.TempDir(), "gt")
+	if err := os.WriteFile(exe, []byte("old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{SelfUpdate: writeRelease(t, t.TempDir(), []byte("new build"))}
+	d.selfBinary = func() (string, error) { return exe, nil }
+	var execed []string
+	d.execSelf = func(path string) error {
+		execed = append(execed, path)
+		return errors.New("exec disabled in tests")
+	}
+	state := &State{Running: true}
+
+	// Without a request or check interval nothing is checked
+	d.checkSelfUpdate(state, time.Now())
+	if len(execed) != 0 {
+		t.Fatalf("updated without a request: %v", execed)
+	}
+
+	if err := RequestUpdate(d.config.TownRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	d.checkSelfUpdate(state, time.Now())
+	// The failed exec restarted background work
+	defer func() {
+		d.curator.Stop()
+		d.convoyWatcher.Stop()
+		d.stopAPI()
+	}()
+	if len(execed) != 1 || execed[0] != exe {
+		t.Fatalf("exec calls = %v, want [%s]", execed, exe)
+	}
+	if _, err := os.Stat(UpdateRequestFile(d.config.TownRoot)); !os.IsNotExist(err) {
+		t.Error("update request should be consumed")
+	}
+	if rs, err := LoadRuntimeState(d.config.TownRoot); err != nil || rs.SavedAt.IsZero() {
+		t.Errorf("runtime state not saved before exec: %+v, %v", rs, err)
+	}
+}
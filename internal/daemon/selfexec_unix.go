@@ -0,0 +1,14 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// execBinary replaces the process with exe, keeping its arguments,
+// environment, and PID.
+func execBinary(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
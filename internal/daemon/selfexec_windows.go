@@ -0,0 +1,11 @@
+//go:build windows
+
+package daemon
+
+import "errors"
+
+// execBinary is not available on Windows, which has no exec; the updated
+// binary is picked up at the next daemon start.
+func execBinary(exe string) error {
+	return errors.New("restart in place is not supported on windows")
+}
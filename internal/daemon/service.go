@@ -0,0 +1,360 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// User services. The daemon can be installed as a per-user service so it
+// starts at login and is restarted if it crashes: a systemd user unit on
+// Linux, a launchd agent on macOS. The service runs 'gt daemon run' in the
+// foreground and leaves supervision (restart policy, logs) to the service
+// manager.
+
+// ErrServiceUnsupported is returned on platforms without a supported
+// service manager.
+var ErrServiceUnsupported = errors.New("no supported service manager (want systemd or launchd)")
+
+// Service managers.
+const (
+	ServiceSystemd = "systemd"
+	ServiceLaunchd = "launchd"
+)
+
+// ServiceSpec describes the daemon service of one town.
+type ServiceSpec struct {
+	// Manager is ServiceSystemd or ServiceLaunchd.
+	Manager string
+
+	// Name is the unit name or launchd label, e.g. "gt-daemon-gt".
+	Name string
+
+	// Executable is the gt binary the service runs.
+	Executable string
+
+	// TownRoot is the town the daemon runs for (its working directory).
+	TownRoot string
+
+	// Env is the service environment. Service managers start with an
+	// almost empty one, so PATH must reach tmux, git, and bd.
+	Env map[string]string
+
+	// LogFile receives the service's stdout and stderr. The daemon keeps
+	// its own log in daemon/daemon.log; this catches what happens before
+	// it opens it.
+	LogFile string
+}
+
+// serviceNameRe matches characters not allowed in a service name.
+var serviceNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// NewServiceSpec returns the service spec for the town at townRoot, run by
+// the gt binary at executable, for the current platform's service manager.
+func NewServiceSpec(townRoot, executable string) (*ServiceSpec, error) {
+	manager, err := serviceManager()
+	if err != nil {
+		return nil, err
+	}
+	townName := filepath.Base(townRoot)
+	if townCfg, err := config.LoadTownConfig(filepath.Join(townRoot, "mayor", "town.json")); err == nil && townCfg.Name != "" {
+		townName = townCfg.Name
+	}
+	name := "gt-daemon-" + strings.Trim(serviceNameRe.ReplaceAllString(townName, "-"), "-")
+	if manager == ServiceLaunchd {
+		name = "io.gastown.daemon." + strings.TrimPrefix(name, "gt-daemon-")
+	}
+
+	env := map[string]string{
+		"GT_TOWN_ROOT": townRoot,
+		"PATH":         os.Getenv("PATH"),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		env["HOME"] = home
+	}
+	return &ServiceSpec{
+		Manager:    manager,
+		Name:       name,
+		Executable: executable,
+		TownRoot:   townRoot,
+		Env:        env,
+		LogFile:    filepath.Join(townRoot, "daemon", "service.log"),
+	}, nil
+}
+
+// serviceManager returns the service manager of the current platform.
+func serviceManager() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if _, err := exec.LookPath("systemctl"); err == nil {
+			return ServiceSystemd, nil
+		}
+	case "darwin":
+		return ServiceLaunchd, nil
+	}
+	return "", ErrServiceUnsupported
+}
+
+// Path returns where the service definition is installed.
+func (s *ServiceSpec) Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	if s.Manager == ServiceLaunchd {
+		return filepath.Join(home, "Library", "LaunchAgents", s.Name+".plist"), nil
+	}
+	return filepath.Join(home, ".config", "systemd", "user", s.Name+".service"), nil
+}
+
+var systemdUnitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=Gas Town daemon ({{.TownRoot}})
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart="{{.Executable}}" daemon run
+WorkingDirectory={{.TownRoot}}
+{{range .EnvList}}Environment="{{.}}"
+{{end}}Restart=on-failure
+RestartSec=10
+StandardOutput=append:{{.LogFile}}
+StandardError=append:{{.LogFile}}
+
+[Install]
+WantedBy=default.target
+`))
+
+var launchdPlistTemplate = template.Must(template.New("plist").Funcs(template.FuncMap{
+	"xml": xmlEscape,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{xml .Name}}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{xml .Executable}}</string>
+		<string>daemon</string>
+		<string>run</string>
+	</array>
+	<key>WorkingDirectory</key>
+	<string>{{xml .TownRoot}}</string>
+	<key>EnvironmentVariables</key>
+	<dict>
+{{- range $k, $v := .Env}}
+		<key>{{xml $k}}</key>
+		<string>{{xml $v}}</string>
+{{- end}}
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>10</integer>
+	<key>StandardOutPath</key>
+	<string>{{xml .LogFile}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{xml .LogFile}}</string>
+</dict>
+</plist>
+`))
+
+// Render returns the service definition: a systemd unit or launchd plist.
+func (s *ServiceSpec) Render() ([]byte, error) {
+	tmpl := systemdUnitTemplate
+	if s.Manager == ServiceLaunchd {
+		tmpl = launchdPlistTemplate
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		*ServiceSpec
+		EnvList []string
+	}{s, s.envList()}); err != nil {
+		return nil, fmt.Errorf("rendering %s service: %w", s.Manager, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// envList returns Env as sorted KEY=value pairs, quoted for systemd.
+func (s *ServiceSpec) envList() []string {
+	var out []string
+	for k, v := range s.Env {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		out = append(out, k+"="+strings.ReplaceAll(v, `"`, `\"`))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// xmlEscape escapes s for plist text.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// InstallService writes the service definition and enables and starts the
+// service, replacing any earlier definition. Returns the definition's path.
+func InstallService(s *ServiceSpec) (string, error) {
+	path, err := s.Path()
+	if err != nil {
+		return "", err
+	}
+	data, err := s.Render()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.LogFile), 0755); err != nil {
+		return "", fmt.Errorf("creating log dir: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating service dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil { //nolint:gosec // G306: service definitions are world-readable
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	switch s.Manager {
+	case ServiceSystemd:
+		if err := runServiceCmd("systemctl", "--user", "daemon-reload"); err != nil {
+			return path, err
+		}
+		return path, runServiceCmd("systemctl", "--user", "enable", "--now", s.Name+".service")
+	case ServiceLaunchd:
+		// Unload first so a changed plist takes effect; it may not be loaded.
+		_ = runServiceCmd("launchctl", "unload", path)
+		return path, runServiceCmd("launchctl", "load", "-w", path)
+	}
+	return path, ErrServiceUnsupported
+}
+
+// StartService starts an installed service, so a daemon started by hand
+// is still supervised by the service manager.
+func StartService(s *ServiceSpec) error {
+	switch s.Manager {
+	case ServiceSystemd:
+		return runServiceCmd("systemctl", "--user", "start", s.Name+".service")
+	case ServiceLaunchd:
+		return runServiceCmd("launchctl", "start", s.Name)
+	}
+	return ErrServiceUnsupported
+}
+
+// UninstallService stops and disables the service and removes its
+// definition. Returns an error wrapping os.ErrNotExist if it isn't installed.
+func UninstallService(s *ServiceSpec) error {
+	path, err := s.Path()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s service %s: %w", s.Manager, s.Name, err)
+	}
+
+	switch s.Manager {
+	case ServiceSystemd:
+		if err := runServiceCmd("systemctl", "--user", "disable", "--now", s.Name+".service"); err != nil {
+			return err
+		}
+	case ServiceLaunchd:
+		if err := runServiceCmd("launchctl", "unload", "-w", path); err != nil {
+			return err
+		}
+	default:
+		return ErrServiceUnsupported
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing %s: %w", path, err)
+	}
+	if s.Manager == ServiceSystemd {
+		return runServiceCmd("systemctl", "--user", "daemon-reload")
+	}
+	return nil
+}
+
+// ServiceStatus is what the service manager reports about the daemon
+// service.
+type ServiceStatus struct {
+	Manager string `json:"manager"`
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+
+	// Installed is whether the service definition exists.
+	Installed bool `json:"installed"`
+
+	// Enabled is whether the service starts at login.
+	Enabled bool `json:"enabled"`
+
+	// Active is whether the service is running (loaded, for launchd).
+	Active bool `json:"active"`
+
+	// State is the manager's own word for it, e.g. "activating" or
+	// "failed" from systemd.
+	State string `json:"state,omitempty"`
+}
+
+// QueryService returns the service's status. A service that isn't
+// installed is not an error.
+func QueryService(s *ServiceSpec) (*ServiceStatus, error) {
+	path, err := s.Path()
+	if err != nil {
+		return nil, err
+	}
+	status := &ServiceStatus{Manager: s.Manager, Name: s.Name, Path: path}
+	if _, err := os.Stat(path); err != nil {
+		return status, nil
+	}
+	status.Installed = true
+
+	switch s.Manager {
+	case ServiceSystemd:
+		// is-enabled and is-active exit non-zero for "disabled" and
+		// "inactive"; their output is the answer either way.
+		unit := s.Name + ".service"
+		enabled, _ := exec.Command("systemctl", "--user", "is-enabled", unit).Output() //nolint:gosec // G204: fixed command
+		active, _ := exec.Command("systemctl", "--user", "is-active", unit).Output()   //nolint:gosec // G204: fixed command
+		status.Enabled = strings.TrimSpace(string(enabled)) == "enabled"
+		status.State = strings.TrimSpace(string(active))
+		status.Active = status.State == "active"
+	case ServiceLaunchd:
+		// A loaded agent is enabled; RunAtLoad starts it at login.
+		if err := exec.Command("launchctl", "list", s.Name).Run(); err == nil { //nolint:gosec // G204: fixed command
+			status.Enabled = true
+			status.Active = true
+			status.State = "loaded"
+		} else {
+			status.State = "not loaded"
+		}
+	}
+	return status, nil
+}
+
+// runServiceCmd runs a service manager command, returning its output on
+// failure.
+func runServiceCmd(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput() //nolint:gosec // G204: fixed service manager commands
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), msg)
+		}
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
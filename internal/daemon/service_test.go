@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServiceRender(t *testing.T) {
+	spec := &ServiceSpec{
+		Manager:    ServiceSystemd,
+		Name:       "gt-daemon-gt",
+		Executable: "/usr/local/bin/gt",
+		TownRoot:   "/home/me/gt",
+		Env:        map[string]string{"PATH": "/usr/bin:/bin", "HOME": `/home/"me"`},
+		LogFile:    "/home/me/gt/daemon/service.log",
+	}
+	unit, err := spec.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ExecStart="/usr/local/bin/gt" daemon run`,
+		"WorkingDirectory=/home/me/gt",
+		`Environment="HOME=/home/\"me\""` + "\nEnvironment=\"PATH=/usr/bin:/bin\"",
+		"Restart=on-failure",
+		"StandardOutput=append:/home/me/gt/daemon/service.log",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(string(unit), want) {
+			t.Errorf("systemd unit missing %q:\n%s", want, unit)
+		}
+	}
+
+	spec.Manager = ServiceLaunchd
+	spec.Name = "io.gastown.daemon.gt"
+	plist, err := spec.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<string>io.gastown.daemon.gt</string>",
+		"<string>/usr/local/bin/gt</string>\n\t\t<string>daemon</string>",
+		"<key>HOME</key>\n\t\t<string>/home/&#34;me&#34;</string>",
+		"<key>SuccessfulExit</key>\n\t\t<false/>",
+		"<key>StandardErrorPath</key>\n\t<string>/home/me/gt/daemon/service.log</string>",
+	} {
+		if !strings.Contains(string(plist), want) {
+			t.Errorf("launchd plist missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestServiceNotInstalled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	spec := &ServiceSpec{Manager: ServiceSystemd, Name: "gt-daemon-gt"}
+	status, err := QueryService(spec)
+	if err != nil || status.Installed || status.Active {
+		t.Errorf("QueryService = %+v, %v", status, err)
+	}
+	if err := UninstallService(spec); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("UninstallService = %v, want os.ErrNotExist", err)
+	}
+}
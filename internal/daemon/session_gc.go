@@ -0,0 +1,261 @@
+package daemon
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/boot"
+)
+
+// Stale session collection. Sessions outlive the agents they were started
+// for: a rig renamed with 'gt rig rename' leaves gt-<oldname>-* sessions
+// behind, a crew member removed by hand leaves its session running. Nothing
+// ever looks at them again. Every heartbeat the daemon lists the tmux
+// sessions carrying the town's session prefixes and compares them to the
+// sessions of the agents it knows. Per "session_gc" in mayor/daemon.json:
+//
+//	"session_gc": {"mode": "kill", "grace": "30m", "ignore": ["gt-scratch*"]}
+//
+// a session that has matched no registered agent for the grace period is
+// reported to the mayor (mode "report", the default: mailed once), killed
+// (mode "kill"), or left alone (mode "off").
+//
+// Towns sharing a tmux server see each other's sessions as unregistered;
+// isolate them with "tmux" in daemon.json before turning on mode "kill".
+
+// Session collection modes.
+const (
+	SessionGCReport = "report"
+	SessionGCKill   = "kill"
+	SessionGCOff    = "off"
+)
+
+// defaultSessionGCGrace is how long a session must match no agent before
+// it is reported or killed. It covers agents being set up (a session can
+// start before its workdir is registered) and sessions mid-rename.
+const defaultSessionGCGrace = 10 * time.Minute
+
+// sessionGCRequester is the audit requester of stale session kills.
+const sessionGCRequester = "daemon/session-gc"
+
+// SessionGCConfig controls what the daemon does with tmux sessions that
+// belong to no registered agent.
+type SessionGCConfig struct {
+	// Mode is "report" (default), "kill", or "off".
+	Mode string `json:"mode,omitempty"`
+
+	// Grace is how long a session must be unregistered before it is
+	// reported or killed (Go duration string, default "10m").
+	Grace string `json:"grace,omitempty"`
+
+	// Ignore lists session names (or path.Match globs) that are never
+	// reported or killed.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *SessionGCConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Mode {
+	case "", SessionGCReport, SessionGCKill, SessionGCOff:
+	default:
+		return fmt.Errorf("session_gc: unknown mode %q (want report, kill, or off)", c.Mode)
+	}
+	if c.Grace != "" {
+		if d, err := time.ParseDuration(c.Grace); err != nil || d < 0 {
+			return fmt.Errorf("session_gc: invalid grace %q", c.Grace)
+		}
+	}
+	for _, pattern := range c.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("session_gc: invalid ignore pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// sessionGCConfig returns the stale session config, never nil. An invalid
+// config turns collection off.
+func (c *DaemonPatrolConfig) sessionGCConfig() *SessionGCConfig {
+	if c == nil || c.SessionGC == nil {
+		return &SessionGCConfig{}
+	}
+	if c.SessionGC.Validate() != nil {
+		return &SessionGCConfig{Mode: SessionGCOff}
+	}
+	return c.SessionGC
+}
+
+// mode returns the collection mode.
+func (c *SessionGCConfig) mode() string {
+	if c.Mode == "" {
+		return SessionGCReport
+	}
+	return c.Mode
+}
+
+// grace returns the configured grace period.
+func (c *SessionGCConfig) grace() time.Duration {
+	if c.Grace == "" {
+		return defaultSessionGCGrace
+	}
+	d, _ := time.ParseDuration(c.Grace) // checked by Validate
+	return d
+}
+
+// ignored reports whether a session matches an ignore pattern.
+func (c *SessionGCConfig) ignored(name string) bool {
+	for _, pattern := range c.Ignore {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// StaleSession is a tmux session that matches no registered agent.
+type StaleSession struct {
+	// Since is when the session was first seen unregistered.
+	Since time.Time `json:"since"`
+
+	// Reported is set once the mayor has been mailed about the session.
+	Reported bool `json:"reported,omitempty"`
+}
+
+// registeredSessions returns the session names the town's agents may run
+// under: each managed identity's session, also under the built-in naming
+// that sessions started outside the daemon keep, plus Boot and the warm
+// pool slots.
+func (d *Daemon) registeredSessions() map[string]bool {
+	registered := map[string]bool{boot.SessionName: true}
+	builtin := &SessionNamingConfig{}
+	for _, identity := range d.managedIdentities() {
+		if name := d.identityToSession(identity); name != "" {
+			registered[name] = true
+		}
+		if parsed, err := parseIdentity(identity); err == nil {
+			if name := builtin.sessionName(parsed, d.config.TownRoot, ""); name != "" {
+				registered[name] = true
+			}
+		}
+	}
+	if cfg := d.warmPoolConfig(); cfg != nil {
+		for _, rigName := range d.warmPoolRigs(cfg) {
+			for _, name := range d.warmSessionNames(rigName, cfg.size()) {
+				registered[name] = true
+			}
+		}
+	}
+	return registered
+}
+
+// collectStaleSessions reports or kills the town's sessions that have
+// matched no registered agent for the grace period. Sessions seen
+// unregistered are remembered in state.
+func (d *Daemon) collectStaleSessions(state *State, now time.Time) {
+	cfg := d.patrolConfig.sessionGCConfig()
+	if cfg.mode() == SessionGCOff {
+		state.StaleSessions = nil
+		return
+	}
+	names, err := d.tmux.ListSessions()
+	if err != nil {
+		d.logger.Printf("Warning: listing sessions for stale session collection: %v", err)
+		return
+	}
+
+	naming := d.patrolConfig.sessionNaming()
+	builtin := &SessionNamingConfig{}
+	var registered map[string]bool // built only if the town has candidates
+	stale := make(map[string]*StaleSession)
+	var report []string
+	for _, name := range names {
+		if !naming.ownsSession(name) && !builtin.ownsSession(name) || cfg.ignored(name) {
+			continue
+		}
+		if registered == nil {
+			registered = d.registeredSessions()
+		}
+		if registered[name] {
+			continue
+		}
+
+		rec := state.StaleSessions[name]
+		if rec == nil {
+			rec = &StaleSession{Since: now}
+			d.logger.Printf("Session %s matches no registered agent", name)
+		}
+		if now.Sub(rec.Since) < cfg.grace() {
+			stale[name] = rec
+			continue
+		}
+		if cfg.mode() == SessionGCKill {
+			if d.killStaleSession(name, rec, now) {
+				continue
+			}
+		} else if !rec.Reported {
+			report = append(report, name)
+		}
+		stale[name] = rec
+	}
+
+	if len(report) > 0 && d.reportStaleSessions(report, stale, now) {
+		for _, name := range report {
+			stale[name].Reported = true
+		}
+	}
+	if len(stale) == 0 {
+		stale = nil
+	}
+	state.StaleSessions = stale
+}
+
+// killStaleSession kills a stale session. Returns whether it is gone.
+func (d *Daemon) killStaleSession(name string, rec *StaleSession, now time.Time) bool {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would kill stale session %s", name)
+		return false
+	}
+	err := d.tmux.KillSessionWithProcesses(name)
+	d.audit(AuditKillSession, name, sessionGCRequester,
+		[]string{"matches no registered agent", "unregistered for " + now.Sub(rec.Since).Round(time.Minute).String()}, err)
+	if err != nil {
+		d.logger.Printf("Warning: failed to kill stale session %s: %v", name, err)
+		return false
+	}
+	d.removeAgentContainer(name)
+	d.logger.Printf("Killed stale session %s: it has matched no registered agent since %s", name, rec.Since.Format(time.RFC3339))
+	return true
+}
+
+// reportStaleSessions mails the mayor about newly stale sessions. Returns
+// whether the mail was sent.
+func (d *Daemon) reportStaleSessions(names []string, stale map[string]*StaleSession, now time.Time) bool {
+	sort.Strings(names)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tmux session(s) match no registered agent of this town:\n\n", len(names))
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s (unregistered for %s)\n", name, now.Sub(stale[name].Since).Round(time.Minute))
+	}
+	b.WriteString("\nThey are usually left over from a renamed rig or a removed agent. To kill one:\n")
+	fmt.Fprintf(&b, "  tmux kill-session -t %s\n", names[0])
+	b.WriteString("\nTo have the daemon kill them, set session_gc.mode to \"kill\" in mayor/daemon.json; " +
+		"to keep one, add it to session_gc.ignore.")
+
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Would report %d stale session(s) to the mayor", len(names))
+		return false
+	}
+	subject := fmt.Sprintf("STALE_SESSIONS: %s", strings.Join(names, ", "))
+	if err := d.mailClient().Send("mayor/", subject, b.String()); err != nil {
+		d.logger.Printf("Warning: failed to report stale sessions to the mayor: %v", err)
+		return false
+	}
+	d.logger.Printf("Reported %d stale session(s) to the mayor", len(names))
+	return true
+}
@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gcTmux lists and kills the sessions of a fake tmux server.
+type gcTmux struct {
+	hibernateTmux
+}
+
+func (f *gcTmux) ListSessions() ([]string, error) {
+	var names []string
+	for name, running := range f.running {
+		if running {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (f *gcTmux) KillSessionWithProcesses(name string) error { return f.KillSession(name) }
+
+// newGCDaemon returns a daemon for a town with rig gastown and crew member
+// max, whose tmux server runs their sessions and the given others.
+func newGCDaemon(t *testing.T, cfg *SessionGCConfig, others ...string) (*Daemon, *gcTmux, *sentMail) {
+	t.Helper()
+	d, _ := testDaemonWithTown(t, "testtown")
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs":{"gastown":{}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "gastown", "crew", "max"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{SessionGC: cfg}
+	d.beads = &hibernateBeads{}
+	mail := &sentMail{}
+	d.mail = mail
+
+	tmux := &gcTmux{hibernateTmux{running: map[string]bool{}}}
+	for _, identity := range []string{"mayor", "gastown-witness", "gastown-crew-max"} {
+		tmux.running[d.identityToSession(identity)] = true
+	}
+	for _, name := range others {
+		tmux.running[name] = true
+	}
+	d.tmux = tmux
+	return d, tmux, mail
+}
+
+func TestCollectStaleSessionsReports(t *testing.T) {
+	d, tmux, mail := newGCDaemon(t, nil, "gt-oldrig-witness", "notes")
+	state := &State{}
+	now := time.Now()
+
+	d.collectStaleSessions(state, now)
+	if rec := state.StaleSessions["gt-oldrig-witness"]; rec == nil || len(state.StaleSessions) != 1 {
+		t.Fatalf("stale = %v, want only gt-oldrig-witness", state.StaleSessions)
+	}
+	if len(mail.sent) != 0 {
+		t.Errorf("sent = %v, want nothing within the grace period", mail.sent)
+	}
+
+	// Reported once after the grace period, and never killed
+	for i := 0; i < 2; i++ {
+		d.collectStaleSessions(state, now.Add(defaultSessionGCGrace+time.Duration(i)*time.Minute))
+	}
+	if len(mail.sent) != 1 || !strings.HasPrefix(mail.sent[0], "mayor/|STALE_SESSIONS: gt-oldrig-witness|") {
+		t.Errorf("sent = %v, want one report to the mayor", mail.sent)
+	}
+	if !tmux.running["gt-oldrig-witness"] || !state.StaleSessions["gt-oldrig-witness"].Reported {
+		t.Errorf("stale session killed or not marked reported: %v", state.StaleSessions)
+	}
+
+	// Forgotten once it is gone
+	delete(tmux.running, "gt-oldrig-witness")
+	d.collectStaleSessions(state, now.Add(time.Hour))
+	if state.StaleSessions != nil {
+		t.Errorf("stale = %v, want none", state.StaleSessions)
+	}
+}
+
+func TestCollectStaleSessionsKills(t *testing.T) {
+	cfg := &SessionGCConfig{Mode: SessionGCKill, Grace: "1m", Ignore: []string{"gt-scratch*"}}
+	d, tmux, mail := newGCDaemon(t, cfg, "gt-oldrig-crew-max", "gt-scratch-1")
+	state := &State{}
+	now := time.Now()
+
+	d.collectStaleSessions(state, now)
+	d.collectStaleSessions(state, now.Add(2*time.Minute))
+	if tmux.running["gt-oldrig-crew-max"] {
+		t.Error("stale session not killed after the grace period")
+	}
+	if !tmux.running["gt-scratch-1"] || !tmux.running[d.identityToSession("gastown-crew-max")] {
+		t.Errorf("running = %v, ignored and registered sessions must survive", tmux.running)
+	}
+	if len(mail.sent) != 0 || state.StaleSessions != nil {
+		t.Errorf("sent = %v, stale = %v, want neither after a kill", mail.sent, state.StaleSessions)
+	}
+}
+
+func TestSessionGCConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg     *SessionGCConfig
+		wantErr bool
+	}{
+		{nil, false},
+		{&SessionGCConfig{Mode: SessionGCKill, Grace: "30m", Ignore: []string{"gt-x*"}}, false},
+		{&SessionGCConfig{Mode: "delete"}, true},
+		{&SessionGCConfig{Grace: "soon"}, true},
+		{&SessionGCConfig{Ignore: []string{"gt-["}}, true},
+	}
+	for _, tc := range tests {
+		if err := tc.cfg.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("Validate(%+v) = %v, wantErr %v", tc.cfg, err, tc.wantErr)
+		}
+	}
+
+	invalid := &DaemonPatrolConfig{SessionGC: &SessionGCConfig{Mode: "delete"}}
+	if got := invalid.sessionGCConfig().mode(); got != SessionGCOff {
+		t.Errorf("mode of an invalid config = %q, want off", got)
+	}
+}
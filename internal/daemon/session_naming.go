@@ -0,0 +1,206 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/session"
+)
+
+// SessionNamingConfig configures the tmux session names the daemon gives
+// agents. Configured under "session_naming" in mayor/daemon.json, e.g.
+//
+//	"session_naming": {
+//	  "prefix": "town1_", "hq_prefix": "town1_hq_", "separator": "_",
+//	  "templates": {"crew": "{prefix}{rig}{sep}{name}"}
+//	}
+//
+// Only the daemon's own naming follows this scheme: sessions started by
+// other commands (gt crew start, gt sling) keep the built-in gt-/hq- names.
+// After changing it, run 'gt daemon rename-sessions' so running agents are
+// found under their new names instead of being started a second time.
+type SessionNamingConfig struct {
+	// Prefix starts rig-level session names. Default: "gt-".
+	Prefix string `json:"prefix,omitempty"`
+
+	// HQPrefix starts town-level (mayor, deacon) session names. Default: "hq-".
+	HQPrefix string `json:"hq_prefix,omitempty"`
+
+	// Separator joins the parts of a name. Default: "-".
+	Separator string `json:"separator,omitempty"`
+
+	// Templates overrides the name pattern per role. Placeholders: {prefix},
+	// {hq}, {sep}, {town}, {rig}, {name}, {role}. A template takes
+	// precedence over the role bead's session_pattern.
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// defaultSessionTemplates reproduce the built-in names (see session/names.go).
+var defaultSessionTemplates = map[string]string{
+	"mayor":    "{hq}mayor",
+	"deacon":   "{hq}deacon",
+	"witness":  "{prefix}{rig}{sep}witness",
+	"refinery": "{prefix}{rig}{sep}refinery",
+	"crew":     "{prefix}{rig}{sep}crew{sep}{name}",
+	"polecat":  "{prefix}{rig}{sep}{name}",
+}
+
+// sessionNaming returns the configured naming scheme, never nil.
+func (c *DaemonPatrolConfig) sessionNaming() *SessionNamingConfig {
+	if c == nil || c.SessionNaming == nil {
+		return &SessionNamingConfig{}
+	}
+	return c.SessionNaming
+}
+
+func (c *SessionNamingConfig) prefix() string {
+	if c.Prefix != "" {
+		return c.Prefix
+	}
+	return session.Prefix
+}
+
+func (c *SessionNamingConfig) hqPrefix() string {
+	if c.HQPrefix != "" {
+		return c.HQPrefix
+	}
+	return session.HQPrefix
+}
+
+func (c *SessionNamingConfig) separator() string {
+	if c.Separator != "" {
+		return c.Separator
+	}
+	return "-"
+}
+
+// Validate rejects schemes tmux would mangle or that give two agents the
+// same session name.
+func (c *SessionNamingConfig) Validate() error {
+	for _, part := range []string{c.Prefix, c.HQPrefix, c.Separator} {
+		if strings.ContainsAny(part, ".: ") {
+			return fmt.Errorf("%q: session names cannot contain '.', ':' or spaces", part)
+		}
+	}
+	roles := make([]string, 0, len(c.Templates))
+	for role := range c.Templates {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		tmpl := c.Templates[role]
+		plugin := lookupRolePlugin(role)
+		if _, ok := defaultSessionTemplates[role]; !ok && plugin == nil {
+			return fmt.Errorf("templates: unknown role %q", role)
+		}
+		if strings.ContainsAny(tmpl, ".: ") {
+			return fmt.Errorf("templates.%s %q: session names cannot contain '.', ':' or spaces", role, tmpl)
+		}
+		rigScoped := role != "mayor" && role != "deacon"
+		named := role == "crew" || role == "polecat"
+		if plugin != nil {
+			rigScoped, named = plugin.rigScoped(), plugin.Named
+		}
+		if rigScoped && !strings.Contains(tmpl, "{rig}") {
+			return fmt.Errorf("templates.%s %q: must contain {rig}", role, tmpl)
+		}
+		if named && !strings.Contains(tmpl, "{name}") {
+			return fmt.Errorf("templates.%s %q: must contain {name}", role, tmpl)
+		}
+	}
+	return nil
+}
+
+// sessionName names the identity's session. A configured template wins over
+// the role bead's session_pattern, which wins over the default template of
+// the built-in role or role plugin.
+func (c *SessionNamingConfig) sessionName(parsed *ParsedIdentity, townRoot, rolePattern string) string {
+	tmpl := c.Templates[parsed.RoleType]
+	if tmpl == "" {
+		tmpl = rolePattern
+	}
+	if tmpl == "" {
+		tmpl = defaultSessionTemplates[parsed.RoleType]
+	}
+	if tmpl == "" {
+		if p := lookupRolePlugin(parsed.RoleType); p != nil {
+			tmpl = p.sessionTemplate()
+		}
+	}
+	if tmpl == "" {
+		return ""
+	}
+	r := strings.NewReplacer("{prefix}", c.prefix(), "{hq}", c.hqPrefix(), "{sep}", c.separator())
+	return beads.ExpandRolePattern(r.Replace(tmpl), townRoot, parsed.RigName, parsed.AgentName, parsed.RoleType)
+}
+
+// ownsSession reports whether a session name carries one of the scheme's
+// prefixes, i.e. is probably a Gas Town agent.
+func (c *SessionNamingConfig) ownsSession(name string) bool {
+	return strings.HasPrefix(name, c.prefix()) || strings.HasPrefix(name, c.hqPrefix())
+}
+
+// defaultSessionName names a session without consulting role beads, for
+// hot paths (deacon checks, polecat health) that never honored them.
+func (d *Daemon) defaultSessionName(parsed *ParsedIdentity) string {
+	return d.patrolConfig.sessionNaming().sessionName(parsed, d.config.TownRoot, "")
+}
+
+// SessionRename is one session 'gt daemon rename-sessions' moves to the
+// configured naming scheme.
+type SessionRename struct {
+	Identity string `json:"identity"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+
+	// Skipped explains why the rename can't be done, e.g. the new name is
+	// already taken.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// PlanSessionRenames lists the running sessions whose name differs from the
+// one the configured scheme gives them. Sessions are matched by the name
+// they would have had without session_naming (built-in defaults or role
+// bead session_pattern), so it migrates from that scheme to the configured
+// one. live is the set of running tmux sessions.
+func (c *SessionController) PlanSessionRenames(live []string) []SessionRename {
+	d := c.d
+	running := make(map[string]bool, len(live))
+	for _, name := range live {
+		running[name] = true
+	}
+	unconfigured := &SessionNamingConfig{}
+
+	var plan []SessionRename
+	for _, identity := range d.managedIdentities() {
+		roleConfig, parsed, err := d.getRoleConfigForIdentity(identity)
+		if err != nil {
+			continue
+		}
+		var rolePattern string
+		if roleConfig != nil {
+			rolePattern = roleConfig.SessionPattern
+		}
+		from := unconfigured.sessionName(parsed, d.config.TownRoot, rolePattern)
+		to := d.patrolConfig.sessionNaming().sessionName(parsed, d.config.TownRoot, rolePattern)
+		if from == "" || to == "" || from == to || !running[from] {
+			continue
+		}
+		r := SessionRename{Identity: identity, From: from, To: to}
+		if running[to] {
+			r.Skipped = "a session named " + to + " is already running"
+		}
+		plan = append(plan, r)
+	}
+	return plan
+}
+
+// RenameSession moves one planned session to its new name.
+func (c *SessionController) RenameSession(r SessionRename) error {
+	if err := c.d.tmux.RenameSession(r.From, r.To); err != nil {
+		return fmt.Errorf("renaming %s to %s: %w", r.From, r.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package daemon
+
+import "testing"
+
+func TestSessionNamingDefaults(t *testing.T) {
+	naming := (*DaemonPatrolConfig)(nil).sessionNaming()
+	for _, tc := range []struct {
+		parsed ParsedIdentity
+		want   string
+	}{
+		{ParsedIdentity{RoleType: "mayor"}, "hq-mayor"},
+		{ParsedIdentity{RoleType: "deacon"}, "hq-deacon"},
+		{ParsedIdentity{RoleType: "witness", RigName: "gastown"}, "gt-gastown-witness"},
+		{ParsedIdentity{RoleType: "refinery", RigName: "gastown"}, "gt-gastown-refinery"},
+		{ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}, "gt-gastown-crew-max"},
+		{ParsedIdentity{RoleType: "polecat", RigName: "gastown", AgentName: "Toast"}, "gt-gastown-Toast"},
+		{ParsedIdentity{RoleType: "unknown"}, ""},
+	} {
+		if got := naming.sessionName(&tc.parsed, "/town", ""); got != tc.want {
+			t.Errorf("sessionName(%+v) = %q, want %q", tc.parsed, got, tc.want)
+		}
+	}
+}
+
+func TestSessionNamingConfigured(t *testing.T) {
+	naming := &SessionNamingConfig{
+		Prefix:    "t1_",
+		HQPrefix:  "t1_hq_",
+		Separator: "_",
+		Templates: map[string]string{"crew": "{prefix}{name}{sep}{rig}"},
+	}
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+	witness := &ParsedIdentity{RoleType: "witness", RigName: "gastown"}
+
+	if got := naming.sessionName(&ParsedIdentity{RoleType: "deacon"}, "/town", ""); got != "t1_hq_deacon" {
+		t.Errorf("deacon = %q", got)
+	}
+	if got := naming.sessionName(witness, "/town", ""); got != "t1_gastown_witness" {
+		t.Errorf("witness = %q", got)
+	}
+	// A configured template beats the role bead pattern
+	if got := naming.sessionName(crew, "/town", "rb-{rig}-{name}"); got != "t1_max_gastown" {
+		t.Errorf("crew = %q", got)
+	}
+	// The role bead pattern beats the default template
+	if got := naming.sessionName(witness, "/town", "rb-{rig}-{role}"); got != "rb-gastown-witness" {
+		t.Errorf("witness with role pattern = %q", got)
+	}
+}
+
+func TestSessionNamingValidate(t *testing.T) {
+	for name, cfg := range map[string]*SessionNamingConfig{
+		"dot in separator":    {Separator: "."},
+		"unknown role":        {Templates: map[string]string{"boot": "{prefix}boot"}},
+		"crew without name":   {Templates: map[string]string{"crew": "{prefix}{rig}{sep}crew"}},
+		"witness without rig": {Templates: map[string]string{"witness": "{prefix}witness"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: Validate() = nil, want error", name)
+		}
+	}
+	ok := &SessionNamingConfig{Prefix: "t1_", Templates: map[string]string{"mayor": "{hq}boss"}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestPlanSessionRenames(t *testing.T) {
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	d.patrolConfig = &DaemonPatrolConfig{SessionNaming: &SessionNamingConfig{HQPrefix: "t1-hq-"}}
+	ctl := &SessionController{d: d}
+
+	plan := ctl.PlanSessionRenames([]string{"hq-mayor", "hq-deacon", "t1-hq-deacon", "unrelated"})
+	if len(plan) != 2 {
+		t.Fatalf("plan = %+v, want mayor and deacon", plan)
+	}
+	for _, r := range plan {
+		switch r.Identity {
+		case "mayor":
+			if r.From != "hq-mayor" || r.To != "t1-hq-mayor" || r.Skipped != "" {
+				t.Errorf("mayor rename = %+v", r)
+			}
+		case "deacon":
+			if r.Skipped == "" {
+				t.Errorf("deacon rename onto a running session not skipped: %+v", r)
+			}
+		default:
+			t.Errorf("unexpected rename %+v", r)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+// Agent bead states reported by SessionController after a start or stop.
+const (
+	AgentBeadStateRunning = "running"
+	AgentBeadStateStopped = "stopped"
+)
+
+// SessionController starts and stops agent sessions from outside the daemon
+// process (e.g. gt rig up/down) using the same machinery the daemon uses for
+// lifecycle restarts, so role config, rig state, budgets, and per-agent
+// lifecycle hooks all apply the same way.
+type SessionController struct {
+	d *Daemon
+}
+
+// NewSessionController creates a controller for the town at townRoot.
+// Progress and warnings go to logger.
+func NewSessionController(townRoot string, logger *log.Logger) *SessionController {
+	registerRolePlugins(townRoot, logger)
+	registerRigAliases(townRoot)
+	return &SessionController{d: &Daemon{
+		config:       &Config{TownRoot: townRoot},
+		patrolConfig: LoadPatrolConfig(townRoot),
+		tmux:         tmux.NewTmuxWithSocket(TmuxSocket(townRoot)),
+		logger:       logger,
+	}}
+}
+
+// RigIdentities returns the persistent agents of a rig in bring-up order:
+// witness, refinery, crew members sorted by name, then the rig's role plugin
+// agents. Polecats are not included; they are spawned on demand when work is
+// assigned.
+func RigIdentities(townRoot, rigName string) []string {
+	identities := []string{rigName + "-witness", rigName + "-refinery"}
+	crewNames, _ := listPolecatWorktrees(filepath.Join(townRoot, rigName, "crew"))
+	sort.Strings(crewNames)
+	for _, name := range crewNames {
+		identities = append(identities, rigName+"-crew-"+name)
+	}
+	return append(identities, rigPluginIdentities(townRoot, rigName)...)
+}
+
+// Identities returns every agent the daemon manages in the town.
+func (c *SessionController) Identities() []string {
+	return c.d.managedIdentities()
+}
+
+// IdentityRole returns the role type of an identity (mayor, witness, crew,
+// ...), or "" if the identity isn't recognized.
+func IdentityRole(identity string) string {
+	parsed, err := parseIdentity(identity)
+	if err != nil {
+		return ""
+	}
+	return parsed.RoleType
+}
+
+// SessionName returns the tmux session name for an identity.
+func (c *SessionController) SessionName(identity string) string {
+	return c.d.identityToSession(identity)
+}
+
+// IsRunning reports whether the identity's session exists.
+func (c *SessionController) IsRunning(identity string) (bool, error) {
+	sessionName := c.SessionName(identity)
+	if sessionName == "" {
+		return false, fmt.Errorf("%w: %s", ErrUnknownIdentity, identity)
+	}
+	running, err := c.d.tmux.HasSession(sessionName)
+	if err != nil {
+		return false, classify(ErrSessionBackend, fmt.Errorf("checking session %s: %w", sessionName, err))
+	}
+	return running, nil
+}
+
+// Start starts the identity's session if it isn't running.
+// Returns false if the session was already running.
+func (c *SessionController) Start(identity string) (bool, error) {
+	running, err := c.IsRunning(identity)
+	if err != nil {
+		return false, err
+	}
+	if running {
+		c.reportState(identity, AgentBeadStateRunning)
+		return false, nil
+	}
+	if err := c.d.restartSession(c.SessionName(identity), identity); err != nil {
+		return false, err
+	}
+	c.reportState(identity, AgentBeadStateRunning)
+	return true, nil
+}
+
+// Stop kills the identity's session if it is running, running the agent's
+// pre-shutdown hook first and recording the kill in its state file.
+// Returns false if the session wasn't running.
+func (c *SessionController) Stop(identity, requestedBy string) (bool, error) {
+	running, err := c.IsRunning(identity)
+	if err != nil {
+		return false, err
+	}
+	if !running {
+		c.reportState(identity, AgentBeadStateStopped)
+		return false, nil
+	}
+
+	sessionName := c.SessionName(identity)
+	if err := c.d.checkPolicy(&PolicyProposal{
+		Identity:    identity,
+		Session:     sessionName,
+		Action:      ActionShutdown,
+		RequestedBy: requestedBy,
+	}); err != nil {
+		return false, err
+	}
+	c.d.runAgentHook(identity, sessionName, HookPreShutdown, ActionShutdown)
+	err = c.d.tmux.KillSessionWithProcesses(sessionName)
+	c.d.audit(AuditKillSession, sessionName, requestedBy, []string{"session running", "action shutdown"}, err)
+	if err != nil {
+		return false, classify(ErrSessionBackend, fmt.Errorf("killing session %s: %w", sessionName, err))
+	}
+	c.d.recordKill(identity, ActionShutdown, requestedBy)
+	c.reportState(identity, AgentBeadStateStopped)
+	return true, nil
+}
+
+// drainPrompt is typed into a session being drained before its workspace
+// is removed.
+const drainPrompt = "LIFECYCLE_DRAIN: this workspace is being removed. " +
+	"Commit and push your work, hand off anything unfinished with `gt handoff`, then exit."
+
+// drainPollInterval is how often Drain checks whether the session exited.
+const drainPollInterval = 2 * time.Second
+
+// Drain asks the identity's running session to wrap up (commit, push, hand
+// off) and waits up to timeout for it to exit on its own, then stops
+// whatever is left. Returns false if the session wasn't running.
+func (c *SessionController) Drain(identity, requestedBy string, timeout time.Duration) (bool, error) {
+	running, err := c.IsRunning(identity)
+	if err != nil || !running {
+		return false, err
+	}
+
+	sessionName := c.SessionName(identity)
+	if err := c.d.tmux.NudgeSession(sessionName, drainPrompt); err != nil {
+		return false, classify(ErrSessionBackend, fmt.Errorf("sending drain prompt: %w", err))
+	}
+	c.d.logger.Printf("Draining %s (waiting up to %v)", identity, timeout)
+	for waited := time.Duration(0); waited < timeout; waited += drainPollInterval {
+		c.d.pause(drainPollInterval)
+		if exists, err := c.d.tmux.HasSession(sessionName); err == nil && !exists {
+			c.reportState(identity, AgentBeadStateStopped)
+			return true, nil
+		}
+	}
+
+	c.d.logger.Printf("%s still running after %v, stopping", identity, timeout)
+	return c.Stop(identity, requestedBy)
+}
+
+// WorkDir returns the directory the identity's session runs in, or "" if
+// the identity isn't recognized.
+func (c *SessionController) WorkDir(identity string) string {
+	config, parsed, err := c.d.getRoleConfigForIdentity(identity)
+	if err != nil {
+		return ""
+	}
+	return c.d.getWorkDir(config, parsed)
+}
+
+// AgentBeadID returns the ID of the identity's agent bead, or "" if it has
+// none.
+func (c *SessionController) AgentBeadID(identity string) string {
+	return c.d.identityToAgentBeadID(identity)
+}
+
+// ReportedSince reports whether the identity's agent itself reported in
+// (last_heartbeat in its state file, stamped by gt prime at session start
+// and by gt agents heartbeat) at or after since. Unlike the agent bead's
+// agent_state, which Start writes, this is only set from inside the session.
+func (c *SessionController) ReportedSince(identity string, since time.Time) bool {
+	last := c.d.readAgentHeartbeat(normalizeIdentity(identity))
+	return !last.IsZero() && !last.Before(since)
+}
+
+// AgentBeadState returns the agent_state recorded on the identity's agent bead.
+func (c *SessionController) AgentBeadState(identity string) (string, error) {
+	agentBeadID := c.d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return "", fmt.Errorf("no agent bead for %s", identity)
+	}
+	return c.d.getAgentBeadState(agentBeadID)
+}
+
+// StateDiscrepancies compares the identity's agent bead with its state
+// file (see state_sync.go).
+func (c *SessionController) StateDiscrepancies(identity string) ([]StateDiscrepancy, error) {
+	return c.d.stateDiscrepancies(identity)
+}
+
+// SyncState fixes the discrepancies that aren't conflicts by taking the
+// bead's value.
+func (c *SessionController) SyncState(identity string, found []StateDiscrepancy) error {
+	return c.d.fixStateDiscrepancies(identity, found)
+}
+
+// reportState records a start/stop on the agent bead. Agents without a bead
+// (or a town without bd) just don't get the record.
+func (c *SessionController) reportState(identity, agentState string) {
+	agentBeadID := c.d.identityToAgentBeadID(identity)
+	if agentBeadID == "" {
+		return
+	}
+	if err := beads.New(c.d.config.TownRoot).UpdateAgentState(agentBeadID, agentState, nil); err != nil {
+		c.d.logger.Printf("Warning: could not set %s agent_state=%s: %v", agentBeadID, agentState, err)
+	}
+}
+
+// ReconcilePlan returns the changes that would converge the town toward m.
+// Agents the daemon has hibernated are left down.
+func (c *SessionController) ReconcilePlan(m *manifest.Manifest) []manifest.Change {
+	var hibernating map[string]time.Time
+	if state, err := LoadState(c.d.config.TownRoot); err == nil {
+		hibernating = state.Hibernating
+	}
+	return c.d.reconcilePlan(m, hibernating)
+}
+
+// Apply carries out one change from ReconcilePlan. Warnings are no-ops.
+func (c *SessionController) Apply(change manifest.Change) error {
+	return c.d.applyChange(change)
+}
+
+// Refresh asks the identity's running session to re-prime in place
+// (ActionRefresh): the session keeps its conversation and re-reads its
+// project docs and role context.
+func (c *SessionController) Refresh(identity string) error {
+	return c.d.executeLifecycleAction(&LifecycleRequest{
+		From:      normalizeIdentity(identity),
+		Action:    ActionRefresh,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+// drainTmux is a session that exits on its own after exitAfter polls, or
+// never if exitAfter is negative.
+type drainTmux struct {
+	SessionBackend
+	exitAfter int
+	polls     int
+	nudges    []string
+	killed    []string
+}
+
+func (f *drainTmux) HasSession(string) (bool, error) {
+	if len(f.killed) > 0 {
+		return false, nil
+	}
+	f.polls++
+	return f.exitAfter < 0 || f.polls <= f.exitAfter, nil
+}
+
+func (f *drainTmux) NudgeSession(_, message string) error {
+	f.nudges = append(f.nudges, message)
+	return nil
+}
+
+func (f *drainTmux) KillSessionWithProcesses(name string) error {
+	f.killed = append(f.killed, name)
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		exitAfter int
+		wantKill  bool
+	}{
+		{"exits on its own", 3, false},
+		{"stopped after timeout", -1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, cleanup := testDaemonWithTown(t, "ai")
+			defer cleanup()
+			fake := &drainTmux{exitAfter: tt.exitAfter}
+			d.tmux = fake
+			var slept time.Duration
+			d.sleep = func(delay time.Duration) { slept += delay }
+
+			stopped, err := (&SessionController{d: d}).Drain("ai-crew-max", "test", 10*time.Second)
+			if err != nil || !stopped {
+				t.Fatalf("Drain = %v, %v; want true, nil", stopped, err)
+			}
+			if len(fake.nudges) != 1 || fake.nudges[0] != drainPrompt {
+				t.Errorf("nudges = %q, want the drain prompt", fake.nudges)
+			}
+			if got := len(fake.killed) > 0; got != tt.wantKill {
+				t.Errorf("killed = %v, want kill %v", fake.killed, tt.wantKill)
+			}
+			if slept > 10*time.Second {
+				t.Errorf("waited %v, more than the timeout", slept)
+			}
+		})
+	}
+}
+
+func TestDrain_NotRunning(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	fake := &drainTmux{exitAfter: 0}
+	d.tmux = fake
+
+	stopped, err := (&SessionController{d: d}).Drain("ai-crew-max", "test", time.Minute)
+	if err != nil || stopped {
+		t.Errorf("Drain = %v, %v; want false, nil", stopped, err)
+	}
+	if len(fake.nudges) != 0 {
+		t.Errorf("nudged a session that wasn't running: %q", fake.nudges)
+	}
+}
@@ -12,9 +12,27 @@ func daemonSignals() []os.Signal {
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGUSR1,
+		syscall.SIGHUP,
 	}
 }
 
+func isReloadSignal(sig os.Signal) bool {
+	return sig == syscall.SIGHUP
+}
+
 func isLifecycleSignal(sig os.Signal) bool {
 	return sig == syscall.SIGUSR1
 }
+
+// WakeDaemon sends the running daemon the lifecycle signal, so it processes
+// lifecycle requests (and a pending update request) now instead of at the
+// next poll or heartbeat.
+func WakeDaemon(pid int) error {
+	return syscall.Kill(pid, syscall.SIGUSR1)
+}
+
+// ReloadDaemon sends the running daemon the reload signal, so it reloads
+// its config now instead of when it next checks the config files.
+func ReloadDaemon(pid int) error {
+	return syscall.Kill(pid, syscall.SIGHUP)
+}
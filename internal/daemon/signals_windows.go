@@ -17,3 +17,19 @@ func daemonSignals() []os.Signal {
 func isLifecycleSignal(sig os.Signal) bool {
 	return false
 }
+
+func isReloadSignal(sig os.Signal) bool {
+	return false
+}
+
+// WakeDaemon is a no-op on Windows, which has no lifecycle signal; the
+// daemon picks requests up at its next poll or heartbeat.
+func WakeDaemon(pid int) error {
+	return nil
+}
+
+// ReloadDaemon is a no-op on Windows, which has no reload signal; the
+// daemon reloads when it next checks its config files.
+func ReloadDaemon(pid int) error {
+	return nil
+}
@@ -0,0 +1,138 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+// Agent state sync. An agent's state is split between its agent bead
+// (agent_state, hook_bead) and its state file (requesting flags,
+// current_task), and the two drift: an agent hooks new work but its state
+// file still names the old task, or a stopped agent's file still asks to be
+// cycled. The bead is the source of truth for what the agent is doing, the
+// state file for what it asked for. Drift the rule settles is fixed every
+// heartbeat; a contradiction it can't settle (a request from an agent whose
+// bead says it is stopped or done) is a conflict, logged and left for an
+// operator. 'gt doctor' reports both.
+
+// StateDiscrepancy is one disagreement between an agent's bead and its
+// state file.
+type StateDiscrepancy struct {
+	Identity string `json:"identity"`
+	Field    string `json:"field"` // state file field
+	File     string `json:"file"`  // value in the state file
+	Bead     string `json:"bead"`  // value on the agent bead
+
+	// Conflict is set when the discrepancy can't be fixed automatically.
+	Conflict bool   `json:"conflict,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+func (s StateDiscrepancy) String() string {
+	return fmt.Sprintf("%s: %s", s.Identity, s.Reason)
+}
+
+// inactiveBeadStates are agent_state values of an agent with no session to
+// act on a request.
+var inactiveBeadStates = map[string]bool{
+	AgentBeadStateStopped: true,
+	"done":                true,
+	"closed":              true,
+}
+
+// stateDiscrepancies compares identity's agent bead with its state file.
+// Agents without a bead or a state file have nothing to compare.
+func (d *Daemon) stateDiscrepancies(identity string) ([]StateDiscrepancy, error) {
+	beadID := d.identityToAgentBeadID(identity)
+	if beadID == "" {
+		return nil, nil
+	}
+	file, err := d.readAgentState(identity)
+	if err != nil || file == nil {
+		return nil, err
+	}
+	bead, err := d.getAgentBeadInfo(beadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []StateDiscrepancy
+	if file.CurrentTask != bead.HookBead {
+		found = append(found, StateDiscrepancy{
+			Identity: identity,
+			Field:    "current_task",
+			File:     file.CurrentTask,
+			Bead:     bead.HookBead,
+			Reason:   fmt.Sprintf("state file current_task %q but bead hook_bead %q", file.CurrentTask, bead.HookBead),
+		})
+	}
+	if action, requesting := requestedAction(file); requesting && inactiveBeadStates[bead.State] {
+		found = append(found, StateDiscrepancy{
+			Identity: identity,
+			Field:    "requesting_" + string(action),
+			File:     "true",
+			Bead:     bead.State,
+			Conflict: true,
+			Reason:   fmt.Sprintf("state file requests %s but bead agent_state is %s", action, bead.State),
+		})
+	}
+	return found, nil
+}
+
+// fixStateDiscrepancies brings identity's state file in line with its bead
+// for every fixable discrepancy.
+func (d *Daemon) fixStateDiscrepancies(identity string, found []StateDiscrepancy) error {
+	var fixes []StateDiscrepancy
+	for _, s := range found {
+		if !s.Conflict {
+			fixes = append(fixes, s)
+		}
+	}
+	if len(fixes) == 0 {
+		return nil
+	}
+	return d.agentStates().Update(d.agentRef(identity, ""), func(s *state.AgentState) error {
+		for _, fix := range fixes {
+			if fix.Field == "current_task" {
+				s.CurrentTask = fix.Bead
+			}
+		}
+		return nil
+	})
+}
+
+// syncAgentStates fixes drift between agent beads and state files and logs
+// conflicts, each once until it clears.
+func (d *Daemon) syncAgentStates() {
+	conflicts := make(map[string]bool)
+	for _, identity := range d.managedIdentities() {
+		found, err := d.stateDiscrepancies(identity)
+		if err != nil {
+			continue
+		}
+		for _, s := range found {
+			if !s.Conflict {
+				continue
+			}
+			key := s.Identity + "/" + s.Field
+			conflicts[key] = true
+			if !d.stateConflicts[key] {
+				d.logger.Printf("Warning: agent state conflict: %s", s)
+			}
+		}
+		if d.config.DryRun {
+			continue
+		}
+		if err := d.fixStateDiscrepancies(identity, found); err != nil {
+			d.logger.Printf("Warning: state sync: failed to update %s: %v", identity, err)
+			continue
+		}
+		for _, s := range found {
+			if !s.Conflict {
+				d.logger.Printf("State sync: %s (took the bead's value)", s)
+			}
+		}
+	}
+	d.stateConflicts = conflicts
+}
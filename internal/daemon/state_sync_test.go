@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+func TestSyncAgentStates(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs": {"gastown": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stateFile := state.AgentStatePath(workDir)
+	if err := state.UpdateAgentState(stateFile, func(s *state.AgentState) error {
+		s.CurrentTask = "gt-1"
+		s.RequestingCycle = true
+		s.RequestingTime = time.Now()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fakeBeads := &hibernateBeads{state: AgentBeadStateStopped, hook: "gt-2"}
+	d.beads = fakeBeads
+
+	found, err := d.stateDiscrepancies("gastown-crew-max")
+	if err != nil {
+		t.Fatalf("stateDiscrepancies: %v", err)
+	}
+	if len(found) != 2 || found[0].Field != "current_task" || found[0].Conflict ||
+		found[1].Field != "requesting_cycle" || !found[1].Conflict {
+		t.Fatalf("discrepancies = %+v, want a current_task fix and a requesting_cycle conflict", found)
+	}
+
+	d.syncAgentStates()
+	s, err := state.ReadAgentState(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.CurrentTask != "gt-2" {
+		t.Errorf("current_task = %q, want the bead's hook gt-2", s.CurrentTask)
+	}
+	if !s.RequestingCycle {
+		t.Error("conflicting request flag cleared; conflicts are for an operator")
+	}
+	if !d.stateConflicts["gastown-crew-max/requesting_cycle"] {
+		t.Errorf("conflict not remembered: %v", d.stateConflicts)
+	}
+
+	// In agreement, apart from the request of a running agent.
+	fakeBeads.state = AgentBeadStateRunning
+	if found, _ := d.stateDiscrepancies("gastown-crew-max"); len(found) != 0 {
+		t.Errorf("discrepancies after sync = %+v", found)
+	}
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Lifecycle step timeouts. A hung git fetch or tmux call would otherwise
+// stall the lifecycle goroutine, and with it the heartbeat. Each step of a
+// lifecycle action runs under its own deadline: the step's tmux, vcs, and
+// bd commands are bound to the step's context, so a step that misses its
+// deadline has its commands killed, and the action fails with ErrTimeout
+// once the step has returned. Nothing a step started outlives it.
+
+// Lifecycle steps, as named in lifecycle.step_timeouts.
+const (
+	StepKill      = "kill"      // killing the old session
+	StepSync      = "sync"      // fetching and updating the workspace
+	StepCreate    = "create"    // creating the new session
+	StepConfigure = "configure" // session environment, theme, and layout
+	StepSendKeys  = "send_keys" // start command and startup nudges
+)
+
+// defaultStepTimeouts bound each lifecycle step.
+var defaultStepTimeouts = map[string]time.Duration{
+	StepKill:      30 * time.Second,
+	StepSync:      5 * time.Minute,
+	StepCreate:    30 * time.Second,
+	StepConfigure: 30 * time.Second,
+	StepSendKeys:  time.Minute,
+}
+
+// stepTimeout returns the deadline for a lifecycle step; 0 means none.
+func (d *Daemon) stepTimeout(step string) time.Duration {
+	value := d.patrolConfig.lifecycleConfig().StepTimeouts[step]
+	return d.lifecycleDuration("step_timeouts."+step, value, defaultStepTimeouts[step])
+}
+
+// runStep runs one lifecycle step under its timeout. fn must run its
+// commands with ctx (see sessionsFor, beadsFor, and vcs.WithContext);
+// runStep returns only after fn has.
+func (d *Daemon) runStep(step string, fn func(ctx context.Context) error) error {
+	parent := d.context()
+	timeout := d.stepTimeout(step)
+	if timeout == 0 {
+		return fn(parent)
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	err := fn(ctx)
+	if ctx.Err() == nil {
+		return err
+	}
+	if parent.Err() != nil {
+		return parent.Err()
+	}
+	d.logger.Printf("Warning: lifecycle step %s timed out after %v, its commands were killed", step, timeout)
+	return classify(ErrTimeout, fmt.Errorf("%s step timed out after %v", step, timeout))
+}
+
+// context returns the daemon's context, or a background context for a
+// daemon that isn't running (tests, tools driving daemon logic).
+func (d *Daemon) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+// fakeCommand puts an executable script named name on a PATH of its own.
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestLifecycleStepTimeout(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	// tmux that hangs on kill-session, and on nothing else
+	fakeCommand(t, "tmux", `case "$*" in *kill-session*) exec /bin/sleep 30;; esac`)
+	d.tmux = tmux.NewTmuxWithSocket("")
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{
+		StepTimeouts: map[string]string{StepKill: "100ms"},
+	}}
+
+	start := time.Now()
+	err := d.executeLifecycleAction(&LifecycleRequest{From: "ai-crew-max", Action: ActionShutdown, Timestamp: start})
+	if !errors.Is(err, ErrTimeout) || ErrorCode(err) != ErrorCodeTimeout {
+		t.Fatalf("executeLifecycleAction = %v (code %q), want a timeout", err, ErrorCode(err))
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hung kill held the action for %v", elapsed)
+	}
+}
+
+func TestRunStepKillsCommandsAndWaits(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	// bd that hangs in a child holding its output open
+	fakeCommand(t, "bd", "/bin/sleep 30")
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{
+		StepTimeouts: map[string]string{StepSync: "100ms"},
+	}}
+
+	returned := false
+	start := time.Now()
+	err := d.runStep(StepSync, func(ctx context.Context) error {
+		_, err := d.beadsFor(ctx).Run(d.config.TownRoot, "sync")
+		returned = true
+		return err
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("runStep = %v, want a timeout", err)
+	}
+	if !returned {
+		t.Error("runStep returned before its step did")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hung bd was not killed: step took %v", elapsed)
+	}
+}
+
+func TestStepTimeoutConfig(t *testing.T) {
+	d := testDaemon()
+	if got := d.stepTimeout(StepSync); got != defaultStepTimeouts[StepSync] {
+		t.Errorf("default sync timeout = %v", got)
+	}
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{
+		StepTimeouts: map[string]string{StepSync: "0s", StepCreate: "bogus"},
+	}}
+	if got := d.stepTimeout(StepSync); got != 0 {
+		t.Errorf("sync timeout with \"0s\" = %v, want none", got)
+	}
+	if got := d.stepTimeout(StepCreate); got != defaultStepTimeouts[StepCreate] {
+		t.Errorf("create timeout with an invalid value = %v, want the default", got)
+	}
+	if err := d.runStep(StepSync, func(context.Context) error { return errors.New("boom") }); err == nil || errors.Is(err, ErrTimeout) {
+		t.Errorf("runStep without a limit = %v, want the step's error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"path/filepath"
+
+	"github.com/steveyegge/gastown/internal/tmux"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// TmuxConfig selects the tmux server the town's sessions run on. By default
+// they share the user's default tmux server, where another town (or the
+// user's own sessions) can collide with their names.
+type TmuxConfig struct {
+	// Isolate runs the town on its own server, "tmux -L gastown-<town>".
+	Isolate bool `json:"isolate,omitempty"`
+
+	// Socket names the server's socket explicitly (implies Isolate).
+	Socket string `json:"socket,omitempty"`
+}
+
+// TmuxSocket returns the tmux socket the town's sessions live on, or "" for
+// tmux's default server. GT_TMUX_SOCKET overrides mayor/daemon.json.
+//
+// Every gt command and the daemon must agree on the socket, so gt resolves
+// it at start-up and sets it as the tmux package default.
+func TmuxSocket(townRoot string) string {
+	if socket := tmux.SocketFromEnv(); socket != "" {
+		return socket
+	}
+	return LoadPatrolConfig(townRoot).tmuxSocket(townRoot)
+}
+
+// tmuxSocket resolves the socket from the config, deriving it from the town
+// name when isolation is on without an explicit socket.
+func (c *DaemonPatrolConfig) tmuxSocket(townRoot string) string {
+	if c == nil || c.Tmux == nil {
+		return ""
+	}
+	if c.Tmux.Socket != "" {
+		return c.Tmux.Socket
+	}
+	if !c.Tmux.Isolate {
+		return ""
+	}
+	townName, err := workspace.GetTownName(townRoot)
+	if err != nil || townName == "" {
+		townName = filepath.Base(townRoot)
+	}
+	return tmux.SocketName(townName)
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"github.com/steveyegge/gastown/internal/tracing"
+)
+
+// Lifecycle operations are traced when "tracing" is enabled in
+// mayor/daemon.json: each lifecycle mail gets a trace, with spans for
+// parsing, verification, the kill, teardown wait, restart, and workspace
+// sync, so an operator can see where a slow restart spent its time. The
+// trace ID is carried into LIFECYCLE_RESULT mail and the audit log.
+//
+// Spans nest by call order: startSpan makes the new span the current one
+// and endSpan restores its parent. Only the heartbeat loop goroutine
+// traces; SessionController has no tracer, so CLI callers never touch the
+// current span.
+
+// startSpan starts a span under the current one (or a new trace) and makes
+// it current. Returns nil when tracing is off.
+func (d *Daemon) startSpan(name string, attrs ...string) *tracing.Span {
+	span := d.tracer.Start(d.span, name, attrs...)
+	if span != nil {
+		d.span = span
+	}
+	return span
+}
+
+// endSpan finishes span with err as its status and makes its parent current.
+func (d *Daemon) endSpan(span *tracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	span.Finish(err)
+	d.span = span.Parent()
+}
+
+// traceFields returns the current trace and span IDs, "" when untraced.
+func (d *Daemon) traceFields() (traceID, spanID string) {
+	if d.span == nil {
+		return "", ""
+	}
+	return d.span.TraceID, d.span.SpanID
+}
+
+// flushTraces exports the spans finished since the last flush.
+func (d *Daemon) flushTraces() {
+	if err := d.tracer.Flush(); err != nil {
+		d.logger.Printf("Warning: trace export failed: %v", err)
+	}
+}
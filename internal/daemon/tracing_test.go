@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/tracing"
+)
+
+// spanRecorder collects the spans the daemon exports.
+type spanRecorder struct{ spans []*tracing.Span }
+
+func (r *spanRecorder) Export(spans []*tracing.Span) error {
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+// killableTmux is a session backend with one running session that can be killed.
+type killableTmux struct {
+	SessionBackend
+	killed []string
+}
+
+func (k *killableTmux) HasSession(string) (bool, error)   { return len(k.killed) == 0, nil }
+func (k *killableTmux) GetPanePID(string) (string, error) { return "", errors.New("no pane") }
+func (k *killableTmux) KillSession(name string) error     { k.killed = append(k.killed, name); return nil }
+
+func TestLifecycleTracing(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	rec := &spanRecorder{}
+	d.tracer = tracing.NewWithExporter(rec)
+	d.tmux = &killableTmux{}
+	mail := &sentMail{}
+	d.mail = mail
+
+	d.processLifecycleMessage(&BeadsMessage{
+		ID:      "hq-msg-1",
+		From:    "mayor",
+		Subject: "LIFECYCLE: shutdown",
+		Body:    `{"action": "shutdown", "notify": ["deacon"]}`,
+	})
+	if d.span != nil {
+		t.Errorf("current span %q left open", d.span.Name)
+	}
+	d.flushTraces()
+
+	byName := make(map[string]*tracing.Span)
+	for _, s := range rec.spans {
+		byName[s.Name] = s
+	}
+	root := byName["lifecycle.message"]
+	if root == nil || root.ParentID != "" || root.Attributes["gt.action"] != "shutdown" {
+		t.Fatalf("root span = %+v (spans %d)", root, len(rec.spans))
+	}
+	for child, parent := range map[string]string{
+		"lifecycle.parse":    "lifecycle.message",
+		"lifecycle.shutdown": "lifecycle.message",
+		"lifecycle.verify":   "lifecycle.shutdown",
+		"session.kill":       "lifecycle.shutdown",
+	} {
+		s := byName[child]
+		if s == nil || s.TraceID != root.TraceID || s.ParentID != byName[parent].SpanID {
+			t.Errorf("span %s = %+v, want child of %s", child, s, parent)
+		}
+	}
+
+	// The result mail (to the requester and the notify list) and the audit
+	// log carry the trace
+	if len(mail.sent) != 2 || !strings.Contains(mail.sent[1], `"traceparent": "00-`+root.TraceID+`-`+root.SpanID+`-01"`) {
+		t.Errorf("result mail = %v", mail.sent)
+	}
+	records, err := LoadAuditLog(d.config.TownRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var killTraced bool
+	for _, r := range records {
+		if r.Op == AuditKillSession {
+			killTraced = r.TraceID == root.TraceID && r.SpanID == byName["session.kill"].SpanID
+		}
+	}
+	if !killTraced {
+		data, _ := json.Marshal(records)
+		t.Errorf("kill audit record not linked to the session.kill span: %s", data)
+	}
+}
+
+func TestUntracedDaemon(t *testing.T) {
+	d := testDaemon()
+	if span := d.startSpan("mail.poll"); span != nil || d.span != nil {
+		t.Error("daemon without a tracer started a span")
+	}
+	if traceID, spanID := d.traceFields(); traceID != "" || spanID != "" {
+		t.Errorf("traceFields = %q, %q", traceID, spanID)
+	}
+}
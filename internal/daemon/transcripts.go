@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/session"
+	"github.com/steveyegge/gastown/internal/transcript"
+)
+
+// ensureTranscriptCapture pipes every Gas Town session's output into
+// "gt transcript record" so it survives the session. Sessions already being
+// piped are left alone, so this is cheap to run every heartbeat and picks up
+// sessions started by anything (daemon, witness, humans).
+func (d *Daemon) ensureTranscriptCapture() {
+	if d.patrolConfig == nil || d.patrolConfig.Transcripts == nil || !d.patrolConfig.Transcripts.Enabled {
+		return
+	}
+	cfg := d.patrolConfig.Transcripts
+	naming := d.patrolConfig.sessionNaming()
+
+	sessions, err := d.tmux.ListSessions()
+	if err != nil {
+		d.logger.Printf("Warning: transcript capture: listing sessions: %v", err)
+		return
+	}
+
+	for _, sess := range sessions {
+		if !naming.ownsSession(sess) && !strings.HasPrefix(sess, session.Prefix) && !strings.HasPrefix(sess, session.HQPrefix) {
+			continue
+		}
+		piped, err := d.tmux.IsPanePiped(sess)
+		if err != nil || piped {
+			continue
+		}
+
+		if d.config.DryRun {
+			d.logger.Printf("[dry-run] Would start transcript capture for %s", sess)
+			continue
+		}
+		command := transcriptRecordCommand(transcript.Dir(d.config.TownRoot, sess), cfg)
+		if err := d.tmux.PipePane(sess, command); err != nil {
+			d.logger.Printf("Warning: failed to start transcript capture for %s: %v", sess, err)
+			continue
+		}
+		d.logger.Printf("Started transcript capture for %s", sess)
+	}
+}
+
+// transcriptRecordCommand builds the shell command tmux pipes pane output into.
+func transcriptRecordCommand(dir string, cfg *TranscriptConfig) string {
+	dir = strings.ReplaceAll(dir, "'", "'\\''")
+	command := fmt.Sprintf("gt transcript record --dir '%s'", dir)
+	if cfg.MaxSizeMB > 0 {
+		command += fmt.Sprintf(" --max-size-mb %d", cfg.MaxSizeMB)
+	}
+	if cfg.Keep > 0 {
+		command += fmt.Sprintf(" --keep %d", cfg.Keep)
+	}
+	return command
+}
@@ -2,7 +2,7 @@
 //
 // The daemon is a simple Go process (not a Claude agent) that:
 // 1. Pokes agents periodically (heartbeat)
-// 2. Processes lifecycle requests (cycle, restart, shutdown)
+// 2. Processes lifecycle requests (cycle, restart, shutdown, refresh)
 // 3. Restarts sessions when agents request cycling
 //
 // The daemon is a "dumb scheduler" - all intelligence is in agents.
@@ -10,11 +10,17 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"time"
 
-	"github.com/steveyegge/gastown/internal/util"
+	"github.com/steveyegge/gastown/internal/mail"
+	"github.com/steveyegge/gastown/internal/notifier"
+	"github.com/steveyegge/gastown/internal/rollup"
+	"github.com/steveyegge/gastown/internal/state"
+	"github.com/steveyegge/gastown/internal/storage"
+	"github.com/steveyegge/gastown/internal/tracing"
 )
 
 // Config holds daemon configuration.
@@ -30,6 +36,22 @@ type Config struct {
 
 	// PidFile is the path to the PID file.
 	PidFile string `json:"pid_file"`
+
+	// DryRun makes the daemon verify-only: lifecycle requests and heartbeat
+	// checks run and the intended actions are logged, but no sessions are
+	// started, killed or nudged, no processes are signalled, and no mail is
+	// sent or deleted beyond result replies marked [dry-run]. A dry-run
+	// daemon still holds the daemon lock and leader lease, so it runs in
+	// place of the real daemon, not beside it.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Standby waits for the running daemon to die and then takes over,
+	// instead of exiting because one is already running (see leader.go).
+	Standby bool `json:"standby,omitempty"`
+
+	// RecordFile, if set, is where the daemon records the inputs of its
+	// decisions for 'gt daemon replay' (see recording.go).
+	RecordFile string `json:"record_file,omitempty"`
 }
 
 // DefaultConfig returns the default daemon configuration.
@@ -59,6 +81,122 @@ type State struct {
 
 	// HeartbeatCount is how many heartbeats have completed.
 	HeartbeatCount int64 `json:"heartbeat_count"`
+
+	// MailPoll tracks the health of deacon inbox polling.
+	MailPoll *MailPollStats `json:"mail_poll,omitempty"`
+
+	// RateLimited counts lifecycle requests rejected by the per-sender rate
+	// limit, by sender identity.
+	RateLimited map[string]*RateLimitStats `json:"rate_limited,omitempty"`
+
+	// Escalations is each agent's witness escalation history, by identity.
+	Escalations map[string]*EscalationHistory `json:"escalations,omitempty"`
+
+	// Zombies counts agent processes found outliving their session and
+	// what the reaper did about them.
+	Zombies *ZombieStats `json:"zombies,omitempty"`
+
+	// AgentHeartbeats is each running agent's self-reported heartbeat, by
+	// identity, when the heartbeat protocol is on.
+	AgentHeartbeats map[string]*AgentHeartbeat `json:"agent_heartbeats,omitempty"`
+
+	// ContextCycles are the cycles scheduled for agents near the end of
+	// their context window, by identity.
+	ContextCycles map[string]*ContextCycle `json:"context_cycles,omitempty"`
+
+	// Prewarmed tracks sessions started by off-peak prewarming, by identity.
+	Prewarmed map[string]*PrewarmRecord `json:"prewarmed,omitempty"`
+
+	// SchedulePaused records when agents were stopped at the end of their
+	// working hours, by identity, so they are started again when the next
+	// period begins.
+	SchedulePaused map[string]time.Time `json:"schedule_paused,omitempty"`
+
+	// Hibernating records when idle agents were hibernated, by identity,
+	// so they are woken when work or mail arrives for them.
+	Hibernating map[string]time.Time `json:"hibernating,omitempty"`
+
+	// DetectedRigs are the git checkouts in the town root that aren't rigs
+	// yet, by directory (see rig_provision.go).
+	DetectedRigs map[string]*DetectedRig `json:"detected_rigs,omitempty"`
+
+	// StaleSessions are the town's tmux sessions that match no registered
+	// agent, by session name (see session_gc.go).
+	StaleSessions map[string]*StaleSession `json:"stale_sessions,omitempty"`
+
+	// LastRollupPush is when metrics were last pushed to the rollup service.
+	LastRollupPush time.Time `json:"last_rollup_push,omitzero"`
+
+	// LastBeadsSync is when the scheduled bd sync coordinator last ran.
+	LastBeadsSync time.Time `json:"last_beads_sync,omitzero"`
+
+	// LastMailRetention is when the mail retention rules were last applied.
+	LastMailRetention time.Time `json:"last_mail_retention,omitzero"`
+
+	// LastBudgetCheck is when rig budgets were last checked.
+	LastBudgetCheck time.Time `json:"last_budget_check,omitzero"`
+
+	// DiskUsage is the last measured usage of each workspace with a disk
+	// quota, by identity.
+	DiskUsage map[string]*WorkspaceUsage `json:"disk_usage,omitempty"`
+
+	// CapabilityGaps is the ready work no crew member could take at the
+	// last capability routing pass.
+	CapabilityGaps []CapabilityGap `json:"capability_gaps,omitempty"`
+
+	// SafeMode is set when the daemon came up in safe mode after repeated
+	// startup crashes; SafeModeReason says why. See StartupJournal.
+	SafeMode       bool   `json:"safe_mode,omitempty"`
+	SafeModeReason string `json:"safe_mode_reason,omitempty"`
+
+	// ConfigReload is the outcome of the last config reload, if any.
+	ConfigReload *ConfigReload `json:"config_reload,omitempty"`
+
+	// EmergencyStop is the emergency stop in effect, if any.
+	EmergencyStop *EmergencyStop `json:"emergency_stop,omitempty"`
+}
+
+// PrewarmRecord is one agent's most recent prewarm.
+type PrewarmRecord struct {
+	// StartedAt is when the daemon started the prewarm session.
+	StartedAt time.Time `json:"started_at"`
+
+	// Stopped is set once the session was stopped, or handed over to real
+	// work and no longer the daemon's to stop.
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// MailPollStats records how deacon inbox polling is going. A broken poll
+// (e.g. an incompatible gt binary) otherwise just looks like a quiet town.
+type MailPollStats struct {
+	// LastPollAt is when the inbox was last polled, successfully or not.
+	LastPollAt time.Time `json:"last_poll_at"`
+
+	// LastSuccessAt is when the inbox was last fetched and parsed.
+	LastSuccessAt time.Time `json:"last_success_at"`
+
+	// ConsecutiveFailures counts polls that failed since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// LastError is the error from the most recent failed poll.
+	LastError string `json:"last_error,omitempty"`
+
+	// OldestUnreadAt is the timestamp of the oldest unread deacon message
+	// seen on the last successful poll. Zero when the inbox was empty.
+	OldestUnreadAt time.Time `json:"oldest_unread_at"`
+
+	// LastUnread is how many unread messages the last successful poll found.
+	LastUnread int `json:"last_unread"`
+
+	// LastActiveAt is when a poll last found unread mail.
+	LastActiveAt time.Time `json:"last_active_at,omitzero"`
+
+	// Interval is the current adaptive delay until the next poll.
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// Duplicates counts identical messages collapsed since the daemon
+	// started (see mail_dedup.go).
+	Duplicates int `json:"duplicates,omitempty"`
 }
 
 // StateFile returns the path to the state file.
@@ -66,12 +204,20 @@ func StateFile(townRoot string) string {
 	return filepath.Join(townRoot, "daemon", "state.json")
 }
 
-// LoadState loads daemon state from disk.
+// stateKey is the town storage key for daemon state (daemon/state.json on disk).
+const stateKey = "daemon/state.json"
+
+// LoadState loads daemon state from the town store.
 func LoadState(townRoot string) (*State, error) {
-	stateFile := StateFile(townRoot)
-	data, err := os.ReadFile(stateFile)
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(stateKey)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, storage.ErrNotFound) {
 			return &State{}, nil
 		}
 		return nil, err
@@ -84,16 +230,19 @@ func LoadState(townRoot string) (*State, error) {
 	return &state, nil
 }
 
-// SaveState saves daemon state to disk using atomic write.
+// SaveState saves daemon state to the town store using atomic write.
 func SaveState(townRoot string, state *State) error {
-	stateFile := StateFile(townRoot)
-
-	// Ensure daemon directory exists
-	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
 		return err
 	}
+	defer store.Close()
 
-	return util.AtomicWriteJSON(stateFile, state)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(stateKey, data)
 }
 
 // PatrolConfig holds configuration for a single patrol.
@@ -115,12 +264,272 @@ type PatrolsConfig struct {
 	Deacon   *PatrolConfig `json:"deacon,omitempty"`
 }
 
+// LifecycleConfig tunes how the daemon handles lifecycle requests.
+type LifecycleConfig struct {
+	// StaleFlagThreshold is how long a requesting_* flag may sit in an agent's
+	// state file with no matching lifecycle mail before the reaper acts on it
+	// (Go duration string, default "10m").
+	StaleFlagThreshold string `json:"stale_flag_threshold,omitempty"`
+
+	// StaleFlagAction is what the reaper does with a stale flag: "clear"
+	// (default) removes it and tells the agent to re-request, "execute"
+	// performs the requested action as if the mail had arrived.
+	StaleFlagAction string `json:"stale_flag_action,omitempty"`
+
+	// MaxAge overrides MaxLifecycleMessageAge per action ("cycle", "restart",
+	// "shutdown", "refresh"). Values are Go duration strings, e.g. {"shutdown": "24h",
+	// "cycle": "30m"}: a day-old shutdown is usually still safe to honor,
+	// a day-old cycle is not.
+	MaxAge map[string]string `json:"max_age,omitempty"`
+
+	// SenderMaxAge overrides the max age per sender identity. Keys may be
+	// glob patterns (e.g. "*-witness"). Sender overrides win over MaxAge.
+	SenderMaxAge map[string]string `json:"sender_max_age,omitempty"`
+
+	// HookTimeout bounds each per-agent lifecycle hook (pre-cycle.sh,
+	// post-start.sh, ...) before it is killed (Go duration string, default "30s").
+	HookTimeout string `json:"hook_timeout,omitempty"`
+
+	// SmokeTestTimeout bounds each smoke-test.sh run after a restart (Go
+	// duration string, default "2m"). Smoke tests that ping the agent need
+	// longer than other hooks.
+	SmokeTestTimeout string `json:"smoke_test_timeout,omitempty"`
+
+	// StepTimeouts bounds each step of a lifecycle action, by step: kill,
+	// sync, create, configure, send_keys (Go duration strings, defaults 30s,
+	// 5m, 30s, 30s, 1m; "0s" for no limit). A step that runs over fails the
+	// action. See step_timeout.go.
+	StepTimeouts map[string]string `json:"step_timeouts,omitempty"`
+
+	// RefreshPrompt overrides the instruction typed into a session for a
+	// refresh request (default: defaultRefreshPrompt).
+	RefreshPrompt string `json:"refresh_prompt,omitempty"`
+
+	// MaxRestartsPerHeartbeat caps how many sessions are restarted (cycle or
+	// restart) in one heartbeat (default 3, -1 for no limit). Requests over
+	// the cap stay in the inbox for the next heartbeat.
+	MaxRestartsPerHeartbeat int `json:"max_restarts_per_heartbeat,omitempty"`
+
+	// RestartStagger is the pause between consecutive restarts in one
+	// heartbeat (Go duration string, default "10s").
+	RestartStagger string `json:"restart_stagger,omitempty"`
+
+	// RestartJitter is the maximum random delay added to each stagger
+	// (Go duration string, default "5s").
+	RestartJitter string `json:"restart_jitter,omitempty"`
+
+	// RestartDelay is the minimum time from killing a session to starting
+	// its replacement, per role ("crew", "witness", ...; "*" for the rest).
+	// Go duration strings, default "500ms". Slow tmux servers may need more;
+	// "0s" restarts as soon as the old session is gone.
+	RestartDelay map[string]string `json:"restart_delay,omitempty"`
+
+	// TeardownTimeout bounds the wait for a killed session to disappear
+	// before its replacement is started anyway (Go duration string,
+	// default "5s").
+	TeardownTimeout string `json:"teardown_timeout,omitempty"`
+
+	// MinRestartInterval is the minimum time between restarts of the same
+	// agent (Go duration string, default: no minimum). Earlier requests are
+	// deferred, not dropped.
+	MinRestartInterval string `json:"min_restart_interval,omitempty"`
+
+	// Handoff asks a cycling agent to write a handoff document for its
+	// successor before its session is killed: "off" (default), "request"
+	// (wait up to HandoffTimeout, then cycle anyway), or "require" (reject
+	// the cycle if no handoff arrives). See handoff.go.
+	Handoff string `json:"handoff,omitempty"`
+
+	// HandoffTimeout is how long a cycle waits for the handoff document
+	// (Go duration string, default "5m").
+	HandoffTimeout string `json:"handoff_timeout,omitempty"`
+
+	// RequireToken rejects lifecycle mail that doesn't carry an API token
+	// (body "token") allowing the action on its target: lifecycle-self for
+	// the sender's own session, lifecycle-any for others (see api_tokens.go).
+	RequireToken bool `json:"require_token,omitempty"`
+
+	// EmergencyStopSenders may send EMERGENCY_STOP mail (default: mayor
+	// and overseer). See emergency_stop.go.
+	EmergencyStopSenders []string `json:"emergency_stop_senders,omitempty"`
+
+	// BodyStrictness picks which lifecycle mail body formats are accepted:
+	// "strict" (JSON only), "standard" (JSON, YAML, key: value; the
+	// default), or "lenient" (also free text naming one action).
+	BodyStrictness string `json:"body_strictness,omitempty"`
+
+	// BodyParsers lists the body parser chain explicitly, overriding
+	// BodyStrictness (see body_parsers.go).
+	BodyParsers []string `json:"body_parsers,omitempty"`
+
+	// DedupWindow collapses identical deacon messages (same sender,
+	// subject, and body) sent within this long of each other into the
+	// first (Go duration string, default "2m"; "0s" turns it off). See
+	// mail_dedup.go.
+	DedupWindow string `json:"dedup_window,omitempty"`
+
+	// RateLimit limits lifecycle requests per sender (see rate_limit.go).
+	// Default: 6 requests per 10 minutes.
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+
+	// Escalation sets the policies applied to witness escalation reports
+	// (see escalation.go). Default: bead and mayor mail after 3 reports.
+	Escalation *EscalationConfig `json:"escalation,omitempty"`
+
+	// AgentHeartbeats holds agents to the self-report heartbeat protocol
+	// (see heartbeats.go). Default: off.
+	AgentHeartbeats *AgentHeartbeatConfig `json:"agent_heartbeats,omitempty"`
+
+	// ContextBudget cycles agents near the end of their context window at
+	// their next idle point (see context_budget.go). Default: off.
+	ContextBudget *ContextBudgetConfig `json:"context_budget,omitempty"`
+
+	// Policy puts kills and restarts to an external command or URL, which
+	// can deny them (see policy.go). Default: off.
+	Policy *PolicyConfig `json:"policy,omitempty"`
+
+	// WorkspaceProblems turns workspace sync failures into beads for the
+	// rig's witness and keeps agents off conflicted workspaces (see
+	// workspace_problems.go). Default: both on.
+	WorkspaceProblems *WorkspaceProblemConfig `json:"workspace_problems,omitempty"`
+}
+
+// TranscriptConfig controls capture of session output to transcript files.
+type TranscriptConfig struct {
+	// Enabled turns on continuous capture of every Gas Town session.
+	Enabled bool `json:"enabled"`
+
+	// MaxSizeMB is the size at which a transcript file rotates (default 10).
+	MaxSizeMB int `json:"max_size_mb,omitempty"`
+
+	// Keep is how many rotated files are kept per session (default 5).
+	Keep int `json:"keep,omitempty"`
+}
+
+// PrewarmConfig controls off-peak prewarming of idle agents. During the
+// window, agents that aren't running are started briefly so their workspace
+// sync and context priming happen before the first assignment of the day.
+type PrewarmConfig struct {
+	// Enabled turns on prewarming.
+	Enabled bool `json:"enabled"`
+
+	// Window is the local-time range to prewarm in, "HH:MM-HH:MM".
+	// May wrap midnight (e.g. "23:00-05:00").
+	Window string `json:"window"`
+
+	// Duration is how long a prewarmed session stays up (default "10m").
+	Duration string `json:"duration,omitempty"`
+
+	// Agents are the identities to prewarm, exact or path.Match globs
+	// (e.g. "gastown-crew-*"). Default: all crew members.
+	Agents []string `json:"agents,omitempty"`
+
+	// MaxConcurrent caps how many prewarmed sessions run at once (default 2).
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+}
+
 // DaemonPatrolConfig is the structure of mayor/daemon.json.
 type DaemonPatrolConfig struct {
-	Type      string         `json:"type"`
-	Version   int            `json:"version"`
-	Heartbeat *PatrolConfig  `json:"heartbeat,omitempty"`
-	Patrols   *PatrolsConfig `json:"patrols,omitempty"`
+	Type      string           `json:"type"`
+	Version   int              `json:"version"`
+	Heartbeat *PatrolConfig    `json:"heartbeat,omitempty"`
+	Patrols   *PatrolsConfig   `json:"patrols,omitempty"`
+	Lifecycle *LifecycleConfig `json:"lifecycle,omitempty"`
+
+	// Notifications routes daemon events to external alert sinks.
+	Notifications *notifier.Config `json:"notifications,omitempty"`
+
+	// Transcripts enables persistent capture of session output.
+	Transcripts *TranscriptConfig `json:"transcripts,omitempty"`
+
+	// Prewarm starts idle agents during off-peak hours to refresh context.
+	Prewarm *PrewarmConfig `json:"prewarm,omitempty"`
+
+	// Rollup pushes anonymized town metrics to a central aggregation service.
+	Rollup *rollup.Config `json:"rollup,omitempty"`
+
+	// BeadsSync schedules bd sync across every beads database in the town.
+	BeadsSync *BeadsSyncConfig `json:"beads_sync,omitempty"`
+
+	// MailPoll bounds the adaptive deacon inbox poll interval.
+	MailPoll *MailPollConfig `json:"mail_poll,omitempty"`
+
+	// WorkDirs configures session working directories per role.
+	WorkDirs map[string]*WorkDirConfig `json:"workdirs,omitempty"`
+
+	// Env declares extra session environment per role ("*" for all).
+	Env map[string]*EnvBundleConfig `json:"env,omitempty"`
+
+	// SessionNaming configures the tmux session names given to agents.
+	SessionNaming *SessionNamingConfig `json:"session_naming,omitempty"`
+
+	// WarmPool keeps idle shell sessions per rig for faster crew start-up.
+	WarmPool *WarmPoolConfig `json:"warm_pool,omitempty"`
+
+	// API serves the daemon's HTTP API (event stream) when set.
+	API *APIConfig `json:"api,omitempty"`
+
+	// Tracing exports OpenTelemetry spans of lifecycle operations.
+	Tracing *tracing.Config `json:"tracing,omitempty"`
+
+	// AgentState picks where agent state (request flags, kill records,
+	// heartbeats) is kept: state files (default), agent beads, or sqlite.
+	AgentState *state.BackendConfig `json:"agent_state,omitempty"`
+
+	// SelfUpdate lets the daemon replace its binary from a release location.
+	SelfUpdate *SelfUpdateConfig `json:"self_update,omitempty"`
+
+	// MailRetention archives or deletes old mail on a schedule.
+	MailRetention *mail.RetentionConfig `json:"mail_retention,omitempty"`
+
+	// DiskQuota tracks workspace disk usage and configures cleanup.
+	DiskQuota *DiskQuotaConfig `json:"disk_quota,omitempty"`
+
+	// Tmux selects a dedicated tmux server for the town's sessions.
+	Tmux *TmuxConfig `json:"tmux,omitempty"`
+
+	// Schedules set working hours for rig agents (see schedule.go).
+	Schedules []*ActivitySchedule `json:"schedules,omitempty"`
+
+	// MergeQueue has the daemon merge ready MRs (see merge_queue.go).
+	MergeQueue *MergeQueueConfig `json:"merge_queue,omitempty"`
+
+	// CapabilityRouting routes ready work to crew by declared capability
+	// (see capability_routing.go).
+	CapabilityRouting *CapabilityRoutingConfig `json:"capability_routing,omitempty"`
+
+	// Hibernate stops idle crew sessions and wakes them for new work or
+	// mail (see hibernate.go).
+	Hibernate *HibernateConfig `json:"hibernate,omitempty"`
+
+	// Containers runs agents inside docker or podman containers (see
+	// container.go).
+	Containers *ContainerConfig `json:"containers,omitempty"`
+
+	// RigProvisioning offers or performs provisioning of git checkouts that
+	// appear in the town root (see rig_provision.go).
+	RigProvisioning *RigProvisionConfig `json:"rig_provisioning,omitempty"`
+
+	// SessionGC reports or kills tmux sessions that belong to no registered
+	// agent (see session_gc.go).
+	SessionGC *SessionGCConfig `json:"session_gc,omitempty"`
+}
+
+// lifecycleConfig returns the lifecycle section of the config, never nil.
+func (c *DaemonPatrolConfig) lifecycleConfig() *LifecycleConfig {
+	if c == nil || c.Lifecycle == nil {
+		return &LifecycleConfig{}
+	}
+	return c.Lifecycle
+}
+
+// selfUpdateConfig returns the self-update config, or nil if it is unset
+// or invalid.
+func (c *DaemonPatrolConfig) selfUpdateConfig() *SelfUpdateConfig {
+	if c == nil || c.SelfUpdate.Validate() != nil {
+		return nil
+	}
+	return c.SelfUpdate
 }
 
 // PatrolConfigFile returns the path to the patrol config file.
@@ -180,10 +589,27 @@ const (
 
 	// ActionShutdown terminates without restart.
 	ActionShutdown LifecycleAction = "shutdown"
+
+	// ActionRefresh asks the running session to re-prime (re-read CLAUDE.md
+	// and reload role context) without killing it, so the conversation
+	// survives while new project docs take effect.
+	ActionRefresh LifecycleAction = "refresh"
+
+	// ActionBatch runs the requests in LifecycleRequest.Batch in order.
+	ActionBatch LifecycleAction = "batch"
 )
 
+// restartsSession reports whether the action kills and recreates the session.
+func (a LifecycleAction) restartsSession() bool {
+	return a == ActionCycle || a == ActionRestart
+}
+
 // LifecycleRequest represents a request from an agent to the daemon.
 type LifecycleRequest struct {
+	// RequestID is the ID of the mail the request arrived in, echoed in
+	// the result.
+	RequestID string `json:"request_id,omitempty"`
+
 	// From is the agent requesting the action (e.g., "mayor/", "gastown/witness").
 	From string `json:"from"`
 
@@ -192,4 +618,61 @@ type LifecycleRequest struct {
 
 	// Timestamp is when the request was made.
 	Timestamp time.Time `json:"timestamp"`
+
+	// DryRun asks the daemon to run all verification and log what it would
+	// do without killing or creating anything.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Batch holds the per-target requests of an ActionBatch request, in
+	// execution order. Each entry's From is the target agent.
+	Batch []LifecycleRequest `json:"batch,omitempty"`
+
+	// Notify lists identities that are mailed the result when the request
+	// completes or fails.
+	Notify []string `json:"notify,omitempty"`
+
+	// Handoff is a handoff document the agent wrote before requesting a
+	// cycle, relative to its working directory. Stored and passed to the
+	// new session as GT_HANDOFF.
+	Handoff string `json:"handoff,omitempty"`
+
+	// Token is the API token the request was sent with, if any. It is
+	// never persisted or echoed.
+	Token string `json:"-"`
+
+	// Parser names the body parser that read the request's mail (json,
+	// yaml, kv, keyword, ...); empty for requests that didn't come by mail.
+	Parser string `json:"parser,omitempty"`
+
+	// Reason says why the action was requested, TaskBead is the bead the
+	// agent was working on, and Urgency is how soon it needs the action
+	// (see Urgency*). All three are recorded in the restart history and the
+	// audit log and echoed in the result mail.
+	Reason   string `json:"reason,omitempty"`
+	TaskBead string `json:"task_bead,omitempty"`
+	Urgency  string `json:"urgency,omitempty"`
+
+	// RequestedBy is who asked for the action when that isn't the agent
+	// acted on: a batch sender, an API token's identity, or a daemon check.
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// withContext adds the request's reason, task bead, and urgency, where
+// given, to notification fields.
+func (r *LifecycleRequest) withContext(fields map[string]string) map[string]string {
+	for key, value := range map[string]string{"reason": r.Reason, "task_bead": r.TaskBead, "urgency": r.Urgency} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// requester returns who asked for the request: RequestedBy, or else the
+// agent itself.
+func (r *LifecycleRequest) requester() string {
+	if r.RequestedBy != "" {
+		return r.RequestedBy
+	}
+	return r.From
 }
@@ -0,0 +1,271 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// Session uptime history. Every heartbeat the daemon checks which managed
+// agents have a running session and appends a record to an append-only log
+// in the town store whenever that changes, so 'gt agent uptime' can report
+// availability, restarts, and mean time between cycles over any window.
+// Agents whose workdir disappears (finished polecats) are recorded as down.
+// While the daemon itself is down nothing is observed; the last recorded
+// state is assumed to have held.
+
+// uptimeHistoryKey is the town store key of the uptime log.
+const uptimeHistoryKey = "daemon/uptime.jsonl"
+
+// UptimeEvent is one change of an agent's session between up and down.
+type UptimeEvent struct {
+	Time     time.Time `json:"ts"`
+	Identity string    `json:"identity"`
+	Up       bool      `json:"up"`
+}
+
+// AppendUptimeEvents adds events to the town's uptime log.
+func AppendUptimeEvents(townRoot string, events []UptimeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var buf bytes.Buffer
+	for _, ev := range events {
+		ev.Time = ev.Time.UTC()
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return store.Append(uptimeHistoryKey, buf.Bytes())
+}
+
+// UptimeHistory returns the town's uptime events, oldest first. Unreadable
+// lines are skipped.
+func UptimeHistory(townRoot string) ([]UptimeEvent, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(uptimeHistoryKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var events []UptimeEvent
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev UptimeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// trackUptime records agents whose session came up or went down since the
+// last heartbeat. The first call after startup records every agent.
+func (d *Daemon) trackUptime(now time.Time) {
+	if d.uptimeSeen == nil {
+		d.uptimeSeen = make(map[string]bool)
+	}
+	managed := make(map[string]bool)
+	var events []UptimeEvent
+	for _, identity := range d.managedIdentities() {
+		managed[identity] = true
+		sessionName := d.identityToSession(identity)
+		if sessionName == "" {
+			continue
+		}
+		up, err := d.tmux.HasSession(sessionName)
+		if err != nil {
+			continue
+		}
+		if was, seen := d.uptimeSeen[identity]; !seen || was != up {
+			events = append(events, UptimeEvent{Time: now, Identity: identity, Up: up})
+			d.uptimeSeen[identity] = up
+		}
+	}
+	for identity, up := range d.uptimeSeen {
+		if !managed[identity] {
+			if up {
+				events = append(events, UptimeEvent{Time: now, Identity: identity, Up: false})
+			}
+			delete(d.uptimeSeen, identity)
+		}
+	}
+	if err := AppendUptimeEvents(d.config.TownRoot, events); err != nil {
+		d.logger.Printf("Warning: failed to record session uptime: %v", err)
+	}
+}
+
+// AgentUptime is one agent's (or a group's) availability over a window.
+type AgentUptime struct {
+	Identity string `json:"identity,omitempty"`
+	Rig      string `json:"rig,omitempty"` // "" for town-level agents
+
+	// Observed is how much of the window the agent was tracked for; Up is
+	// how much of that its session was running.
+	Observed time.Duration `json:"observed"`
+	Up       time.Duration `json:"up"`
+
+	// Restarts counts the cycles and restarts in the window.
+	Restarts int `json:"restarts"`
+}
+
+// Availability returns the fraction of the observed time the agent was up,
+// or 0 if it wasn't observed.
+func (u *AgentUptime) Availability() float64 {
+	if u.Observed <= 0 {
+		return 0
+	}
+	return float64(u.Up) / float64(u.Observed)
+}
+
+// MeanTimeBetweenCycles returns the uptime per restart, or 0 if there were
+// no restarts.
+func (u *AgentUptime) MeanTimeBetweenCycles() time.Duration {
+	if u.Restarts == 0 {
+		return 0
+	}
+	return u.Up / time.Duration(u.Restarts)
+}
+
+// add accumulates other into u.
+func (u *AgentUptime) add(other *AgentUptime) {
+	u.Observed += other.Observed
+	u.Up += other.Up
+	u.Restarts += other.Restarts
+}
+
+// UptimeReport is availability over a window, per agent, per rig, and for
+// the whole town.
+type UptimeReport struct {
+	Since  time.Time      `json:"since"`
+	Until  time.Time      `json:"until"`
+	Agents []*AgentUptime `json:"agents"`
+	Rigs   []*AgentUptime `json:"rigs"`
+	Town   *AgentUptime   `json:"town"`
+}
+
+// BuildUptimeReport computes availability between since and until from
+// uptime events and restart records.
+func BuildUptimeReport(events []UptimeEvent, restarts []RestartRecord, since, until time.Time) *UptimeReport {
+	byAgent := make(map[string]*AgentUptime)
+	agent := func(identity string) *AgentUptime {
+		identity = historyIdentity(identity)
+		u := byAgent[identity]
+		if u == nil {
+			u = &AgentUptime{Identity: identity}
+			if parsed, err := parseIdentity(identity); err == nil {
+				u.Rig = parsed.RigName
+			}
+			byAgent[identity] = u
+		}
+		return u
+	}
+
+	// Walk each agent's events, crediting the time between them.
+	type tracking struct {
+		at time.Time
+		up bool
+	}
+	last := make(map[string]tracking)
+	credit := func(identity string, from tracking, to time.Time) {
+		if from.at.Before(since) {
+			from.at = since
+		}
+		if !to.After(from.at) {
+			return
+		}
+		u := agent(identity)
+		u.Observed += to.Sub(from.at)
+		if from.up {
+			u.Up += to.Sub(from.at)
+		}
+	}
+	for _, ev := range events {
+		if ev.Time.After(until) {
+			break
+		}
+		identity := historyIdentity(ev.Identity)
+		if prev, ok := last[identity]; ok {
+			credit(identity, prev, ev.Time)
+		}
+		last[identity] = tracking{at: ev.Time, up: ev.Up}
+	}
+	for identity, prev := range last {
+		if !prev.up && prev.at.Before(since) {
+			continue // Long gone before the window
+		}
+		credit(identity, prev, until)
+	}
+
+	for _, rec := range restarts {
+		if rec.Time.Before(since) || rec.Time.After(until) {
+			continue
+		}
+		agent(rec.Identity).Restarts++
+	}
+
+	report := &UptimeReport{Since: since, Until: until, Town: &AgentUptime{}}
+	byRig := make(map[string]*AgentUptime)
+	for _, u := range byAgent {
+		report.Agents = append(report.Agents, u)
+		report.Town.add(u)
+		rig := byRig[u.Rig]
+		if rig == nil {
+			rig = &AgentUptime{Rig: u.Rig}
+			byRig[u.Rig] = rig
+			report.Rigs = append(report.Rigs, rig)
+		}
+		rig.add(u)
+	}
+	sort.Slice(report.Agents, func(i, j int) bool {
+		if report.Agents[i].Rig != report.Agents[j].Rig {
+			return report.Agents[i].Rig < report.Agents[j].Rig
+		}
+		return report.Agents[i].Identity < report.Agents[j].Identity
+	})
+	sort.Slice(report.Rigs, func(i, j int) bool { return report.Rigs[i].Rig < report.Rigs[j].Rig })
+	return report
+}
+
+// LoadUptimeReport builds the town's uptime report for the window from its
+// uptime log and restart history.
+func LoadUptimeReport(townRoot string, since, until time.Time) (*UptimeReport, error) {
+	events, err := UptimeHistory(townRoot)
+	if err != nil {
+		return nil, err
+	}
+	restarts, err := RestartHistory(townRoot, RestartQuery{Since: since})
+	if err != nil {
+		return nil, err
+	}
+	return BuildUptimeReport(events, restarts, since, until), nil
+}
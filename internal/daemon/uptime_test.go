@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildUptimeReport(t *testing.T) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	events := []UptimeEvent{
+		{Time: at(0), Identity: "mayor", Up: true},
+		{Time: at(0), Identity: "gastown-crew-max", Up: true},
+		{Time: at(6), Identity: "gastown-crew-max", Up: false},
+		{Time: at(8), Identity: "gastown-crew-max", Up: true},
+		{Time: at(4), Identity: "gastown-witness", Up: true},
+	}
+	restarts := []RestartRecord{
+		{Time: at(3), Identity: "gastown-crew-max", Action: ActionCycle},
+		{Time: at(8), Identity: "gastown-crew-max", Action: ActionRestart},
+		{Time: at(-5), Identity: "gastown-crew-max", Action: ActionCycle}, // Before the window
+	}
+
+	report := BuildUptimeReport(events, restarts, at(0), at(10))
+	byIdentity := make(map[string]*AgentUptime)
+	for _, u := range report.Agents {
+		byIdentity[u.Identity] = u
+	}
+
+	max := byIdentity["gastown-crew-max"]
+	if max == nil || max.Observed != 10*time.Hour || max.Up != 8*time.Hour || max.Restarts != 2 {
+		t.Fatalf("crew max = %+v, want 8h up of 10h, 2 restarts", max)
+	}
+	if got := max.Availability(); got != 0.8 {
+		t.Errorf("Availability = %v, want 0.8", got)
+	}
+	if got := max.MeanTimeBetweenCycles(); got != 4*time.Hour {
+		t.Errorf("MeanTimeBetweenCycles = %v, want 4h", got)
+	}
+	if w := byIdentity["gastown-witness"]; w == nil || w.Observed != 6*time.Hour || w.Availability() != 1 {
+		t.Errorf("witness = %+v, want observed from its first event", w)
+	}
+
+	if len(report.Rigs) != 2 || report.Rigs[0].Rig != "" || report.Rigs[1].Rig != "gastown" {
+		t.Fatalf("rigs = %+v, want town-level and gastown", report.Rigs)
+	}
+	if rig := report.Rigs[1]; rig.Up != 14*time.Hour || rig.Observed != 16*time.Hour {
+		t.Errorf("gastown = %+v, want 14h up of 16h", rig)
+	}
+	if town := report.Town; town.Up != 24*time.Hour || town.Observed != 26*time.Hour || town.Restarts != 2 {
+		t.Errorf("town = %+v", town)
+	}
+
+	// A window starting later counts the state carried into it.
+	report = BuildUptimeReport(events, restarts, at(7), at(10))
+	for _, u := range report.Agents {
+		if u.Identity == "gastown-crew-max" && (u.Observed != 3*time.Hour || u.Up != 2*time.Hour) {
+			t.Errorf("crew max from 7h = %+v, want 2h up of 3h", u)
+		}
+	}
+}
+
+func TestTrackUptime(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	townRoot := d.config.TownRoot
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(`{"rigs": {"gastown": {}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	crewDir := filepath.Join(townRoot, "gastown", "crew", "max")
+	if err := os.MkdirAll(crewDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	session := d.identityToSession("gastown-crew-max")
+	fake := &hibernateTmux{running: map[string]bool{session: true}}
+	d.tmux = fake
+	now := time.Now()
+
+	d.trackUptime(now)
+	d.trackUptime(now.Add(time.Minute)) // Nothing changed
+	delete(fake.running, session)
+	d.trackUptime(now.Add(2 * time.Minute))
+	fake.running[session] = true
+	d.trackUptime(now.Add(3 * time.Minute))
+	if err := os.RemoveAll(crewDir); err != nil {
+		t.Fatal(err)
+	}
+	d.trackUptime(now.Add(4 * time.Minute))
+
+	events, err := UptimeHistory(townRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []bool
+	for _, ev := range events {
+		if ev.Identity == "gastown-crew-max" {
+			got = append(got, ev.Up)
+		}
+	}
+	want := []bool{true, false, true, false}
+	if len(got) != len(want) {
+		t.Fatalf("crew max events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("crew max events = %v, want %v", got, want)
+		}
+	}
+}
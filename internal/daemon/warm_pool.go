@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/steveyegge/gastown/internal/budget"
+)
+
+// The warm pool keeps idle, already-created sessions per rig so a crew
+// member can be brought up without waiting for tmux and a login shell. A warm
+// session is only a shell at a prompt in the rig root; no agent runs in it.
+// When a crew identity is started, a warm session of its rig is renamed to
+// the crew session name and moved into the crew workspace, and the usual
+// start path takes over from there: the agent is launched with the
+// identity's own environment (GT_CREW, BD_ACTOR, ...) and start command, the
+// same as in a freshly created session. Pre-starting the agent itself isn't
+// possible, since a running agent can't take on another identity's
+// environment.
+
+const (
+	// defaultWarmPoolSize is how many warm sessions are kept per rig.
+	defaultWarmPoolSize = 1
+
+	// warmSessionRole is the role segment of warm pool session names.
+	warmSessionRole = "warm"
+)
+
+// WarmPoolConfig configures the warm session pool.
+type WarmPoolConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Size is how many warm sessions are kept per rig (default 1).
+	Size int `json:"size,omitempty"`
+
+	// Rigs limits the pool to these rigs. Default: every known rig.
+	Rigs []string `json:"rigs,omitempty"`
+}
+
+// warmPoolConfig returns the warm pool config if the pool is enabled.
+func (d *Daemon) warmPoolConfig() *WarmPoolConfig {
+	if d.patrolConfig == nil || d.patrolConfig.WarmPool == nil || !d.patrolConfig.WarmPool.Enabled {
+		return nil
+	}
+	return d.patrolConfig.WarmPool
+}
+
+// warmPoolRigs returns the rigs that keep a warm pool.
+func (d *Daemon) warmPoolRigs(cfg *WarmPoolConfig) []string {
+	if len(cfg.Rigs) > 0 {
+		return cfg.Rigs
+	}
+	return d.getKnownRigs()
+}
+
+func (c *WarmPoolConfig) size() int {
+	if c.Size > 0 {
+		return c.Size
+	}
+	return defaultWarmPoolSize
+}
+
+// warmSessionNames returns the session names of a rig's warm pool slots.
+func (d *Daemon) warmSessionNames(rigName string, size int) []string {
+	naming := d.patrolConfig.sessionNaming()
+	names := make([]string, size)
+	for i := range names {
+		names[i] = naming.prefix() + rigName + naming.separator() + warmSessionRole + naming.separator() + strconv.Itoa(i+1)
+	}
+	return names
+}
+
+// maintainWarmPool tops up each rig's warm pool.
+func (d *Daemon) maintainWarmPool() {
+	cfg := d.warmPoolConfig()
+	if cfg == nil {
+		return
+	}
+	if err := d.checkNotStopped("warm sessions"); err != nil {
+		d.logger.Printf("Skipping warm pool: %v", err)
+		return
+	}
+	for _, rigName := range d.warmPoolRigs(cfg) {
+		if operational, _ := d.isRigOperational(rigName); !operational {
+			continue
+		}
+		if ok, _ := budget.CheckRole(d.config.TownRoot, rigName, "crew"); !ok {
+			continue
+		}
+		for _, name := range d.warmSessionNames(rigName, cfg.size()) {
+			if alive, err := d.tmux.HasSession(name); err != nil || alive {
+				continue
+			}
+			if d.config.DryRun {
+				d.logger.Printf("[dry-run] Would start warm session %s", name)
+				continue
+			}
+			if err := d.tmux.EnsureSessionFresh(name, filepath.Join(d.config.TownRoot, rigName)); err != nil {
+				d.logger.Printf("Warning: failed to start warm session %s: %v", name, err)
+				continue
+			}
+			d.logger.Printf("Started warm session %s", name)
+		}
+	}
+}
+
+// claimWarmSession hands a warm session of the identity's rig to a crew
+// member: it is renamed to sessionName and its shell moved into workDir,
+// ready for the start command. Returns false if the rig has no idle warm
+// session, in which case the caller creates one as usual.
+func (d *Daemon) claimWarmSession(identity, sessionName, workDir string, parsed *ParsedIdentity) bool {
+	cfg := d.warmPoolConfig()
+	if cfg == nil || parsed.RoleType != "crew" || !slices.Contains(d.warmPoolRigs(cfg), parsed.RigName) {
+		return false
+	}
+
+	var warm string
+	for _, name := range d.warmSessionNames(parsed.RigName, cfg.size()) {
+		if alive, err := d.tmux.HasSession(name); err == nil && alive && !d.tmux.IsClaudeRunning(name) {
+			warm = name
+			break
+		}
+	}
+	if warm == "" {
+		return false
+	}
+
+	// Clear a zombie session holding the name, as EnsureSessionFresh would
+	if exists, _ := d.tmux.HasSession(sessionName); exists {
+		if err := d.tmux.KillSessionWithProcesses(sessionName); err != nil {
+			d.logger.Printf("Warning: cannot claim warm session for %s: %v", identity, err)
+			return false
+		}
+	}
+	if err := d.tmux.RenameSession(warm, sessionName); err != nil {
+		d.logger.Printf("Warning: cannot claim warm session %s for %s: %v", warm, identity, err)
+		return false
+	}
+	if err := d.sendStartCommand(d.tmux, sessionName, "cd "+shellQuote(workDir)); err != nil {
+		d.logger.Printf("Warning: cannot move claimed warm session %s into %s: %v", sessionName, workDir, err)
+		_ = d.tmux.KillSession(sessionName)
+		return false
+	}
+	d.logger.Printf("Claimed warm session %s for %s", warm, identity)
+	return true
+}
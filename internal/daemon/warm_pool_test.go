@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWarmSessionNames(t *testing.T) {
+	d := testDaemon()
+	if got, want := d.warmSessionNames("gastown", 2), []string{"gt-gastown-warm-1", "gt-gastown-warm-2"}; !slices.Equal(got, want) {
+		t.Errorf("warmSessionNames = %v, want %v", got, want)
+	}
+
+	// Warm sessions follow the configured naming scheme
+	d.patrolConfig = &DaemonPatrolConfig{SessionNaming: &SessionNamingConfig{Prefix: "t1_", Separator: "_"}}
+	if got := d.warmSessionNames("gastown", 1); got[0] != "t1_gastown_warm_1" {
+		t.Errorf("warmSessionNames with naming = %v", got)
+	}
+}
+
+func TestWarmPoolConfig(t *testing.T) {
+	d := testDaemon()
+	if d.warmPoolConfig() != nil {
+		t.Error("warm pool enabled without config")
+	}
+	d.patrolConfig = &DaemonPatrolConfig{WarmPool: &WarmPoolConfig{Enabled: true, Rigs: []string{"gastown"}}}
+	cfg := d.warmPoolConfig()
+	if cfg == nil || cfg.size() != defaultWarmPoolSize {
+		t.Fatalf("warmPoolConfig = %+v", cfg)
+	}
+	if rigs := d.warmPoolRigs(cfg); !slices.Equal(rigs, []string{"gastown"}) {
+		t.Errorf("warmPoolRigs = %v", rigs)
+	}
+}
+
+func TestClaimWarmSessionIneligible(t *testing.T) {
+	d := testDaemon()
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+
+	// Pool disabled
+	if d.claimWarmSession("gastown-crew-max", "gt-gastown-crew-max", "/w", crew) {
+		t.Error("claimed with the pool disabled")
+	}
+
+	d.patrolConfig = &DaemonPatrolConfig{WarmPool: &WarmPoolConfig{Enabled: true, Rigs: []string{"gastown"}}}
+	witness := &ParsedIdentity{RoleType: "witness", RigName: "gastown"}
+	if d.claimWarmSession("gastown-witness", "gt-gastown-witness", "/w", witness) {
+		t.Error("claimed for a witness")
+	}
+	other := &ParsedIdentity{RoleType: "crew", RigName: "beads", AgentName: "max"}
+	if d.claimWarmSession("beads-crew-max", "gt-beads-crew-max", "/w", other) {
+		t.Error("claimed for a rig without a pool")
+	}
+}
+
+// warmTmux serves warm sessions, some of which run an agent.
+type warmTmux struct {
+	SessionBackend
+	sessions map[string]bool // name -> agent running
+	keys     []string
+}
+
+func (f *warmTmux) HasSession(name string) (bool, error) {
+	_, ok := f.sessions[name]
+	return ok, nil
+}
+
+func (f *warmTmux) IsClaudeRunning(name string) bool { return f.sessions[name] }
+
+func (f *warmTmux) RenameSession(oldName, newName string) error {
+	f.sessions[newName] = f.sessions[oldName]
+	delete(f.sessions, oldName)
+	return nil
+}
+
+func (f *warmTmux) SendKeys(name, keys string) error {
+	f.keys = append(f.keys, name+": "+keys)
+	return nil
+}
+
+func TestClaimWarmSession(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{WarmPool: &WarmPoolConfig{Enabled: true, Rigs: []string{"gastown"}, Size: 2}}
+	// Slot 1 runs an agent (left over from before warm sessions were
+	// shell-only); slot 2 is an idle shell
+	tm := &warmTmux{sessions: map[string]bool{"gt-gastown-warm-1": true, "gt-gastown-warm-2": false}}
+	d.tmux = tm
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+
+	if !d.claimWarmSession("gastown-crew-max", "gt-gastown-crew-max", "/town/gastown/crew/max", crew) {
+		t.Fatal("idle warm shell not claimed")
+	}
+	if _, ok := tm.sessions["gt-gastown-warm-2"]; ok || tm.sessions["gt-gastown-crew-max"] {
+		t.Errorf("wrong session claimed: %v", tm.sessions)
+	}
+	if len(tm.keys) != 1 || tm.keys[0] != "gt-gastown-crew-max: cd '/town/gastown/crew/max'" {
+		t.Errorf("sent %v, want only a cd into the workspace", tm.keys)
+	}
+
+	// Only the agent session is left, which can't be claimed
+	if d.claimWarmSession("gastown-crew-joe", "gt-gastown-crew-joe", "/w", crew) {
+		t.Error("claimed a warm session running an agent")
+	}
+}
+
+func TestMaintainWarmPoolHonorsEmergencyStop(t *testing.T) {
+	d, cleanup := testDaemonWithTown(t, "ai")
+	defer cleanup()
+	d.patrolConfig = &DaemonPatrolConfig{WarmPool: &WarmPoolConfig{Enabled: true, Rigs: []string{"gastown"}}}
+	// EnsureSessionFresh is not faked: a start would panic
+	d.tmux = &hibernateTmux{running: map[string]bool{}}
+	if _, err := RequestEmergencyStop(d.config.TownRoot, "test", "overseer"); err != nil {
+		t.Fatal(err)
+	}
+
+	d.maintainWarmPool()
+}
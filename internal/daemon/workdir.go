@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// WorkDirConfig configures where a role's sessions start and what the
+// daemon checks before starting one there. Configured per role under
+// "workdirs" in mayor/daemon.json, e.g.
+//
+//	"workdirs": {
+//	  "crew": {"template": "{town}/{rig}/crew/{name}", "skeleton": "{town}/{rig}/crew/.skeleton"}
+//	}
+type WorkDirConfig struct {
+	// Template is the working directory pattern. Placeholders: {town},
+	// {rig}, {name}, {role}. It takes precedence over the role bead's
+	// work_dir_pattern, so a town can relocate workspaces without editing
+	// shared role beads.
+	Template string `json:"template,omitempty"`
+
+	// RequireGit refuses to start a session whose working directory is not
+	// inside a checkout (git, jj, or hg) below the town root. Default: true
+	// for refinery, crew, and polecat.
+	RequireGit *bool `json:"require_git,omitempty"`
+
+	// Skeleton is a directory (same placeholders as Template) whose contents
+	// are copied into a crew workspace the daemon recreates, without
+	// overwriting files the checkout already has. Use it for local-only
+	// files such as editor or agent settings.
+	Skeleton string `json:"skeleton,omitempty"`
+}
+
+// workDirConfig returns the configured workdir settings for a role, or nil.
+func (d *Daemon) workDirConfig(role string) *WorkDirConfig {
+	if d.patrolConfig == nil || d.patrolConfig.WorkDirs == nil {
+		return nil
+	}
+	return d.patrolConfig.WorkDirs[role]
+}
+
+// workDirTemplate returns the configured template for the identity's role,
+// expanded, or "" if none is configured.
+func (d *Daemon) workDirTemplate(parsed *ParsedIdentity) string {
+	cfg := d.workDirConfig(parsed.RoleType)
+	if cfg == nil || cfg.Template == "" {
+		return ""
+	}
+	return d.expandWorkDirPattern(cfg.Template, parsed)
+}
+
+func (d *Daemon) expandWorkDirPattern(pattern string, parsed *ParsedIdentity) string {
+	return filepath.Clean(beads.ExpandRolePattern(pattern, d.config.TownRoot, parsed.RigName, parsed.AgentName, parsed.RoleType))
+}
+
+// workDirRequiresGit reports whether a role's sessions must start in a checkout.
+func (d *Daemon) workDirRequiresGit(role string) bool {
+	if cfg := d.workDirConfig(role); cfg != nil && cfg.RequireGit != nil {
+		return *cfg.RequireGit
+	}
+	switch role {
+	case "refinery", "crew", "polecat":
+		return true
+	default:
+		return false
+	}
+}
+
+// validateWorkDir checks a session's working directory before the session is
+// created, so a broken path fails the start instead of leaving an agent
+// running somewhere unexpected.
+func (d *Daemon) validateWorkDir(parsed *ParsedIdentity, workDir string) error {
+	return classify(ErrStateVerificationFailed, d.checkWorkDir(parsed, workDir))
+}
+
+// checkWorkDir does the checks of validateWorkDir.
+func (d *Daemon) checkWorkDir(parsed *ParsedIdentity, workDir string) error {
+	info, err := os.Stat(workDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("working directory %s does not exist", workDir)
+		}
+		return fmt.Errorf("working directory %s: %w", workDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("working directory %s is not a directory", workDir)
+	}
+	if d.workDirRequiresGit(parsed.RoleType) && !isCheckout(workDir, d.config.TownRoot) {
+		return fmt.Errorf("working directory %s is not a git checkout (required for %s)", workDir, parsed.RoleType)
+	}
+	return nil
+}
+
+// isCheckout reports whether dir is inside a git, jj, or hg checkout other
+// than the town root's own repository.
+func isCheckout(dir, townRoot string) bool {
+	townRoot = filepath.Clean(townRoot)
+	for d := filepath.Clean(dir); d != townRoot; {
+		for _, marker := range []string{".git", ".jj", ".hg"} {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return true
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return false
+		}
+		d = parent
+	}
+	return false
+}
+
+// applyWorkDirSkeleton copies the role's skeleton into a freshly created
+// workspace. Missing or unset skeletons are not an error.
+func (d *Daemon) applyWorkDirSkeleton(parsed *ParsedIdentity, workDir string) error {
+	cfg := d.workDirConfig(parsed.RoleType)
+	if cfg == nil || cfg.Skeleton == "" {
+		return nil
+	}
+	skeleton := d.expandWorkDirPattern(cfg.Skeleton, parsed)
+	if _, err := os.Stat(skeleton); os.IsNotExist(err) {
+		d.logger.Printf("Warning: %s skeleton %s does not exist", parsed.RoleType, skeleton)
+		return nil
+	}
+	return copySkeleton(skeleton, workDir)
+}
+
+// copySkeleton copies the tree at src into dst, keeping files dst already has.
+func copySkeleton(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return nil // Never overwrite checkout content
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkDirTemplate(t *testing.T) {
+	d := testDaemon()
+	d.patrolConfig = &DaemonPatrolConfig{
+		WorkDirs: map[string]*WorkDirConfig{
+			"crew": {Template: "{town}/workspaces/{rig}/{name}"},
+		},
+	}
+
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+	if got, want := d.getWorkDir(nil, crew), "/tmp/test/workspaces/gastown/max"; got != want {
+		t.Errorf("crew workdir = %q, want %q", got, want)
+	}
+
+	// Roles without a template keep the defaults
+	witness := &ParsedIdentity{RoleType: "witness", RigName: "gastown"}
+	if got, want := d.getWorkDir(nil, witness), "/tmp/test/gastown"; got != want {
+		t.Errorf("witness workdir = %q, want %q", got, want)
+	}
+}
+
+func TestValidateWorkDir(t *testing.T) {
+	townRoot := t.TempDir()
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+
+	// The town root is itself a git repo; that must not satisfy require_git.
+	if err := os.MkdirAll(filepath.Join(townRoot, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	crew := &ParsedIdentity{RoleType: "crew", RigName: "gastown", AgentName: "max"}
+	workDir := filepath.Join(townRoot, "gastown", "crew", "max")
+
+	if err := d.validateWorkDir(crew, workDir); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("missing workdir: err = %v", err)
+	}
+
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.validateWorkDir(crew, workDir); err == nil || !strings.Contains(err.Error(), "not a git checkout") {
+		t.Errorf("non-checkout crew workdir: err = %v", err)
+	}
+
+	// Witnesses don't need a checkout by default
+	witness := &ParsedIdentity{RoleType: "witness", RigName: "gastown"}
+	if err := d.validateWorkDir(witness, filepath.Join(townRoot, "gastown")); err != nil {
+		t.Errorf("witness workdir: %v", err)
+	}
+
+	// Worktrees have a .git file rather than a directory
+	if err := os.WriteFile(filepath.Join(workDir, ".git"), []byte("gitdir: elsewhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.validateWorkDir(crew, workDir); err != nil {
+		t.Errorf("worktree workdir: %v", err)
+	}
+
+	// require_git can be turned off
+	noGit := false
+	d.patrolConfig = &DaemonPatrolConfig{WorkDirs: map[string]*WorkDirConfig{"polecat": {RequireGit: &noGit}}}
+	polecatDir := filepath.Join(townRoot, "gastown", "polecats", "nux")
+	if err := os.MkdirAll(polecatDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.validateWorkDir(&ParsedIdentity{RoleType: "polecat", RigName: "gastown", AgentName: "nux"}, polecatDir); err != nil {
+		t.Errorf("polecat with require_git=false: %v", err)
+	}
+}
+
+func TestCopySkeleton(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(src, ".claude", "settings.json"), "skeleton")
+	write(filepath.Join(src, "CLAUDE.md"), "skeleton")
+	write(filepath.Join(dst, "CLAUDE.md"), "checkout")
+
+	if err := copySkeleton(src, dst); err != nil {
+		t.Fatalf("copySkeleton: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, ".claude", "settings.json")); string(data) != "skeleton" {
+		t.Errorf("settings.json = %q, want copied", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "CLAUDE.md")); string(data) != "checkout" {
+		t.Errorf("CLAUDE.md = %q, want existing file kept", data)
+	}
+}
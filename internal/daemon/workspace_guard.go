@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/state"
+	"github.com/steveyegge/gastown/internal/vcs"
+)
+
+// AutoStashRecord is one auto-stash syncWorkspace made of uncommitted work
+// before updating a workspace. Records are appended to
+// <workdir>/.runtime/autostash.jsonl.
+type AutoStashRecord struct {
+	Time    time.Time `json:"ts"`
+	Branch  string    `json:"branch,omitempty"`
+	Ref     string    `json:"ref"`
+	Message string    `json:"message"`
+}
+
+// workspaceRuntimeFiles are the files Gas Town itself writes into a
+// workspace: the agent state file, the runtime directory (auto-stash log,
+// handoffs), and the session env file. They are never the agent's work.
+var workspaceRuntimeFiles = []string{state.AgentStateFile, ".runtime/", EnvFileName}
+
+// excludeRuntimeFiles adds workspaceRuntimeFiles to the info/exclude of the
+// git repo holding workDir, so they neither make it dirty nor get stashed
+// or committed. A workDir outside any git repo is left alone.
+func excludeRuntimeFiles(workDir string) error {
+	g := git.NewGit(workDir)
+	if !g.IsRepo() {
+		return nil
+	}
+	return g.ExcludeLocally(workspaceRuntimeFiles...)
+}
+
+// autoStashFile returns the auto-stash record file of a workspace.
+func autoStashFile(workDir string) string {
+	return filepath.Join(workDir, ".runtime", "autostash.jsonl")
+}
+
+// prepareWorkspaceUpdate runs the checks syncWorkspace makes before it
+// rebases a workspace onto the rig's branch. It refuses (returns the reason)
+// when the checked-out branch is protected and carries commits the remote
+// doesn't have, and stashes uncommitted work otherwise. Backends without
+// these operations (jj, hg) are updated as before. Gas Town's own runtime
+// files are excluded first, so they neither count as uncommitted work nor
+// get stashed.
+func (d *Daemon) prepareWorkspaceUpdate(backend vcs.VCS, spec vcs.Spec, workDir string) error {
+	branch, err := backend.CurrentBranch(workDir)
+	if errors.Is(err, vcs.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		d.logger.Printf("Warning: cannot determine branch in %s: %v", workDir, err)
+	}
+
+	if spec.IsProtected(branch) {
+		unpushed, err := backend.Unpushed(workDir, spec.Remote)
+		if err != nil {
+			// Can't prove the branch is safe to rewrite; leave it alone
+			return fmt.Errorf("not updating protected branch %s in %s: checking for unpushed commits failed: %w",
+				branch, workDir, err)
+		}
+		if unpushed > 0 {
+			return fmt.Errorf("not updating %s: protected branch %s has %d unpushed commit(s); push or move them first",
+				workDir, branch, unpushed)
+		}
+	}
+
+	if backend.Kind() == vcs.Git {
+		if err := excludeRuntimeFiles(workDir); err != nil {
+			d.logger.Printf("Warning: cannot exclude runtime files in %s: %v", workDir, err)
+		}
+	}
+
+	dirty, err := backend.Dirty(workDir)
+	if err != nil {
+		d.logger.Printf("Warning: cannot check %s for uncommitted changes: %v", workDir, err)
+		return nil
+	}
+	if !dirty {
+		return nil
+	}
+
+	now := time.Now()
+	message := fmt.Sprintf("gt auto-stash before sync to %s/%s at %s", spec.Remote, spec.Branch, now.Format(time.RFC3339))
+	ref, err := backend.Stash(workDir, message)
+	if err != nil {
+		return fmt.Errorf("auto-stash failed in %s, not updating: %w", workDir, err)
+	}
+	d.logger.Printf("Auto-stashed uncommitted changes in %s as %s", workDir, ref)
+	if err := recordAutoStash(workDir, AutoStashRecord{Time: now, Branch: branch, Ref: ref, Message: message}); err != nil {
+		d.logger.Printf("Warning: failed to record auto-stash %s in %s: %v", ref, workDir, err)
+	}
+	return nil
+}
+
+// recordAutoStash appends an auto-stash record to the workspace's log.
+func recordAutoStash(workDir string, rec AutoStashRecord) error {
+	path := autoStashFile(workDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// LoadAutoStashes returns a workspace's auto-stash records, oldest first.
+func LoadAutoStashes(workDir string) ([]AutoStashRecord, error) {
+	data, err := os.ReadFile(autoStashFile(workDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []AutoStashRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var rec AutoStashRecord
+		if err := dec.Decode(&rec); err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/vcs"
+)
+
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newSyncedClone returns a clone of a fresh remote with one pushed commit.
+func newSyncedClone(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	gitIn(t, root, "init", "--bare", "-b", "main", remote)
+	gitIn(t, root, "clone", remote, work)
+	gitIn(t, work, "config", "user.name", "test")
+	gitIn(t, work, "config", "user.email", "test@example.com")
+	gitIn(t, work, "commit", "--allow-empty", "-m", "first")
+	gitIn(t, work, "push", "origin", "main")
+	return work
+}
+
+func TestPrepareWorkspaceUpdateProtectedBranch(t *testing.T) {
+	work := newSyncedClone(t)
+	d := testDaemon()
+	backend, _ := vcs.For(vcs.Git)
+	spec := (&vcs.Config{ProtectedBranches: []string{"main"}}).Resolve(vcs.Git, "main")
+
+	if err := d.prepareWorkspaceUpdate(backend, spec, work); err != nil {
+		t.Errorf("refused to update a protected branch with nothing unpushed: %v", err)
+	}
+
+	gitIn(t, work, "commit", "--allow-empty", "-m", "local only")
+	if d.prepareWorkspaceUpdate(backend, spec, work) == nil {
+		t.Error("agreed to update a protected branch with an unpushed commit")
+	}
+
+	// Unpushed commits on an unprotected branch are rebased as before
+	gitIn(t, work, "checkout", "-b", "polecat/nux")
+	if err := d.prepareWorkspaceUpdate(backend, spec, work); err != nil {
+		t.Errorf("refused to update an unprotected branch: %v", err)
+	}
+}
+
+func TestPrepareWorkspaceUpdateAutoStash(t *testing.T) {
+	work := newSyncedClone(t)
+	d := testDaemon()
+	backend, _ := vcs.For(vcs.Git)
+	spec := (*vcs.Config)(nil).Resolve(vcs.Git, "main")
+
+	if err := os.WriteFile(filepath.Join(work, "wip.txt"), []byte("half done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.prepareWorkspaceUpdate(backend, spec, work); err != nil {
+		t.Fatalf("refused to update a dirty workspace: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "wip.txt")); !os.IsNotExist(err) {
+		t.Error("dirty work was not stashed")
+	}
+
+	records, err := LoadAutoStashes(work)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("auto-stash records = %v, %v", records, err)
+	}
+	rec := records[0]
+	if rec.Branch != "main" || !strings.Contains(rec.Message, "origin/main") {
+		t.Errorf("record = %+v", rec)
+	}
+	if got := gitIn(t, work, "rev-parse", "stash@{0}"); got != rec.Ref {
+		t.Errorf("recorded ref %s, stash is %s", rec.Ref, got)
+	}
+	gitIn(t, work, "stash", "apply", rec.Ref)
+	if _, err := os.Stat(filepath.Join(work, "wip.txt")); err != nil {
+		t.Errorf("recorded ref does not restore the work: %v", err)
+	}
+}
+
+func TestPrepareWorkspaceUpdateLeavesRuntimeFiles(t *testing.T) {
+	work := newSyncedClone(t)
+	d := testDaemon()
+	backend, _ := vcs.For(vcs.Git)
+	spec := (*vcs.Config)(nil).Resolve(vcs.Git, "main")
+
+	runtimeFiles := []string{"state.json", EnvFileName, filepath.Join(".runtime", "autostash.jsonl")}
+	for _, name := range runtimeFiles {
+		path := filepath.Join(work, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Runtime files alone don't make the workspace dirty
+	if err := d.prepareWorkspaceUpdate(backend, spec, work); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := LoadAutoStashes(work); len(records) != 0 {
+		t.Errorf("runtime files were auto-stashed: %v", records)
+	}
+
+	// Nor are they stashed along with real work
+	if err := os.WriteFile(filepath.Join(work, "wip.txt"), []byte("half done\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.prepareWorkspaceUpdate(backend, spec, work); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "wip.txt")); !os.IsNotExist(err) {
+		t.Error("dirty work was not stashed")
+	}
+	for _, name := range runtimeFiles {
+		if _, err := os.Stat(filepath.Join(work, name)); err != nil {
+			t.Errorf("runtime file %s was stashed: %v", name, err)
+		}
+	}
+}
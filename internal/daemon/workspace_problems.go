@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// Workspace problems. A sync that fails (fetch, update, bd sync) or leaves
+// conflicts behind is recorded in a workspace-problem bead assigned to the
+// rig's witness, one open bead per workspace, updated on every failed sync
+// and closed by the first clean one. An agent is not started on a
+// conflicted workspace unless the town opts out.
+
+// workspaceProblemType is the bead type (gt:workspace-problem label) of
+// workspace-problem beads.
+const workspaceProblemType = "workspace-problem"
+
+// WorkspaceProblemConfig configures workspace problem handling, under
+// "lifecycle.workspace_problems" in mayor/daemon.json:
+//
+//	"workspace_problems": {"beads": true, "block_on_conflict": false}
+type WorkspaceProblemConfig struct {
+	// Beads opens or updates a workspace-problem bead when a sync fails.
+	// Default: true.
+	Beads *bool `json:"beads,omitempty"`
+
+	// BlockOnConflict refuses to start an agent whose workspace has
+	// unresolved conflicts after the sync. Default: true.
+	BlockOnConflict *bool `json:"block_on_conflict,omitempty"`
+}
+
+// WorkspaceProblemStore is the subset of the beads API workspace problems
+// are recorded with.
+type WorkspaceProblemStore interface {
+	List(opts beads.ListOptions) ([]*beads.Issue, error)
+	Create(opts beads.CreateOptions) (*beads.Issue, error)
+	Update(id string, opts beads.UpdateOptions) error
+	CloseWithReason(reason string, ids ...string) error
+}
+
+// workspaceProblemConfig returns the configured settings, or the defaults.
+func (d *Daemon) workspaceProblemConfig() (beadsOn, blockOnConflict bool) {
+	cfg := d.patrolConfig.lifecycleConfig().WorkspaceProblems
+	beadsOn, blockOnConflict = true, true
+	if cfg == nil {
+		return
+	}
+	if cfg.Beads != nil {
+		beadsOn = *cfg.Beads
+	}
+	if cfg.BlockOnConflict != nil {
+		blockOnConflict = *cfg.BlockOnConflict
+	}
+	return
+}
+
+// workspaceProblemStore returns the store problems are recorded in: the
+// town beads database unless a test replaced it.
+func (d *Daemon) workspaceProblemStore() WorkspaceProblemStore {
+	if d.problemStore != nil {
+		return d.problemStore
+	}
+	return beads.New(d.config.TownRoot)
+}
+
+// workspaceProblemTitle names a workspace's problem bead; the title is how
+// the open bead of a workspace is found again.
+func (d *Daemon) workspaceProblemTitle(workDir string) string {
+	rel, err := filepath.Rel(d.config.TownRoot, workDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = workDir
+	}
+	return "Workspace problem: " + filepath.ToSlash(rel)
+}
+
+// workspaceWitness returns the witness responsible for a workspace, or ""
+// for workspaces outside any rig.
+func (d *Daemon) workspaceWitness(workDir string) string {
+	rel, err := filepath.Rel(d.config.TownRoot, workDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	rigName := strings.Split(rel, string(filepath.Separator))[0]
+	if rigName == "." || rigName == "mayor" || rigName == "deacon" {
+		return ""
+	}
+	return rigName + "/witness"
+}
+
+// recordWorkspaceProblems brings a workspace's problem bead up to date with
+// the outcome of a sync: problems open or update it, a clean sync closes it.
+// Failures to reach the store are logged; they never fail the sync.
+func (d *Daemon) recordWorkspaceProblems(workDir string, problems, conflicts []string, now time.Time) {
+	if on, _ := d.workspaceProblemConfig(); !on || d.config.DryRun {
+		return
+	}
+	store := d.workspaceProblemStore()
+	title := d.workspaceProblemTitle(workDir)
+	open, err := store.List(beads.ListOptions{Status: "open", Label: "gt:" + workspaceProblemType, Priority: -1})
+	if err != nil {
+		d.logger.Printf("Warning: listing workspace-problem beads: %v", err)
+		return
+	}
+	var existing *beads.Issue
+	for _, issue := range open {
+		if issue.Title == title {
+			existing = issue
+			break
+		}
+	}
+
+	if len(problems) == 0 && len(conflicts) == 0 {
+		if existing != nil {
+			if err := store.CloseWithReason("workspace synced cleanly", existing.ID); err != nil {
+				d.logger.Printf("Warning: closing workspace-problem bead %s: %v", existing.ID, err)
+				return
+			}
+			d.logger.Printf("Closed workspace-problem bead %s for %s", existing.ID, workDir)
+		}
+		return
+	}
+
+	description := workspaceProblemDescription(workDir, problems, conflicts, now)
+	priority := 2
+	if len(conflicts) > 0 {
+		priority = 1
+	}
+	id := ""
+	if existing != nil {
+		id = existing.ID
+		if err := store.Update(id, beads.UpdateOptions{Description: &description, Priority: &priority}); err != nil {
+			d.logger.Printf("Warning: updating workspace-problem bead %s: %v", id, err)
+			return
+		}
+	} else {
+		issue, err := store.Create(beads.CreateOptions{
+			Title:       title,
+			Type:        workspaceProblemType,
+			Priority:    priority,
+			Description: description,
+			Actor:       "daemon",
+		})
+		if err != nil {
+			d.logger.Printf("Warning: creating workspace-problem bead for %s: %v", workDir, err)
+			return
+		}
+		id = issue.ID
+		d.logger.Printf("Opened workspace-problem bead %s for %s", id, workDir)
+	}
+
+	if witness := d.workspaceWitness(workDir); witness != "" && (existing == nil || existing.Assignee != witness) {
+		if err := store.Update(id, beads.UpdateOptions{Assignee: &witness}); err != nil {
+			d.logger.Printf("Warning: assigning workspace-problem bead %s to %s: %v", id, witness, err)
+		}
+	}
+}
+
+// workspaceProblemDescription renders the body of a workspace-problem bead.
+func workspaceProblemDescription(workDir string, problems, conflicts []string, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "workdir: %s\n", workDir)
+	fmt.Fprintf(&b, "last_failure: %s\n", now.UTC().Format(time.RFC3339))
+	if len(problems) > 0 {
+		b.WriteString("\nSync errors:\n")
+		for _, p := range problems {
+			fmt.Fprintf(&b, "- %s\n", p)
+		}
+	}
+	if len(conflicts) > 0 {
+		b.WriteString("\nUnresolved conflicts:\n")
+		for _, f := range conflicts {
+			fmt.Fprintf(&b, "- %s\n", f)
+		}
+		b.WriteString("\nResolve the conflicts (or abort the rebase) before the agent is restarted.\n")
+	}
+	return b.String()
+}
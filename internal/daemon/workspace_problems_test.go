@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// problemStore is an in-memory WorkspaceProblemStore.
+type problemStore struct {
+	issues []*beads.Issue
+}
+
+func (s *problemStore) List(opts beads.ListOptions) ([]*beads.Issue, error) {
+	var out []*beads.Issue
+	for _, issue := range s.issues {
+		if issue.Status == opts.Status {
+			out = append(out, issue)
+		}
+	}
+	return out, nil
+}
+
+func (s *problemStore) Create(opts beads.CreateOptions) (*beads.Issue, error) {
+	issue := &beads.Issue{ID: fmt.Sprintf("hq-%d", len(s.issues)+1), Title: opts.Title, Description: opts.Description,
+		Priority: opts.Priority, Status: "open", Labels: []string{"gt:" + opts.Type}}
+	s.issues = append(s.issues, issue)
+	return issue, nil
+}
+
+func (s *problemStore) Update(id string, opts beads.UpdateOptions) error {
+	for _, issue := range s.issues {
+		if issue.ID == id {
+			if opts.Description != nil {
+				issue.Description = *opts.Description
+			}
+			if opts.Assignee != nil {
+				issue.Assignee = *opts.Assignee
+			}
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (s *problemStore) CloseWithReason(reason string, ids ...string) error {
+	for _, issue := range s.issues {
+		for _, id := range ids {
+			if issue.ID == id {
+				issue.Status = "closed"
+			}
+		}
+	}
+	return nil
+}
+
+func TestRecordWorkspaceProblems(t *testing.T) {
+	d := testDaemon()
+	store := &problemStore{}
+	d.problemStore = store
+	workDir := filepath.Join(d.config.TownRoot, "gastown", "refinery", "rig")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	d.recordWorkspaceProblems(workDir, []string{"git fetch failed"}, nil, now)
+	if len(store.issues) != 1 {
+		t.Fatalf("got %d beads, want 1", len(store.issues))
+	}
+	issue := store.issues[0]
+	if issue.Title != "Workspace problem: gastown/refinery/rig" || issue.Assignee != "gastown/witness" {
+		t.Errorf("bead = %q assigned to %q", issue.Title, issue.Assignee)
+	}
+
+	// A second failure updates the open bead instead of opening another
+	d.recordWorkspaceProblems(workDir, nil, []string{"a.go"}, now)
+	if len(store.issues) != 1 || !strings.Contains(issue.Description, "a.go") {
+		t.Errorf("beads = %d, description %q", len(store.issues), issue.Description)
+	}
+
+	d.recordWorkspaceProblems(workDir, nil, nil, now)
+	if issue.Status != "closed" {
+		t.Errorf("clean sync left bead %s", issue.Status)
+	}
+
+	off := false
+	d.patrolConfig = &DaemonPatrolConfig{Lifecycle: &LifecycleConfig{WorkspaceProblems: &WorkspaceProblemConfig{Beads: &off}}}
+	d.recordWorkspaceProblems(workDir, []string{"bd sync failed"}, nil, now)
+	if len(store.issues) != 1 {
+		t.Error("opened a bead with workspace-problem beads disabled")
+	}
+}
+
+func TestSyncWorkspaceBlocksOnConflict(t *testing.T) {
+	work := newSyncedClone(t)
+	root := filepath.Dir(work)
+	other := filepath.Join(root, "other")
+	gitIn(t, root, "clone", filepath.Join(root, "remote.git"), other)
+	commitFile(t, other, "theirs\n")
+	gitIn(t, other, "push", "origin", "main")
+	commitFile(t, work, "ours\n")
+
+	d := testDaemon()
+	d.config.TownRoot = root
+	d.patrolConfig = &DaemonPatrolConfig{BeadsSync: &BeadsSyncConfig{Enabled: true}} // no bd sync
+	store := &problemStore{}
+	d.problemStore = store
+
+	err := d.syncWorkspace(context.Background(), work)
+	if !errors.Is(err, ErrStateVerificationFailed) {
+		t.Fatalf("syncWorkspace = %v, want a state verification failure", err)
+	}
+	if len(store.issues) != 1 || !strings.Contains(store.issues[0].Description, "a.txt") {
+		t.Errorf("workspace-problem beads = %+v", store.issues)
+	}
+
+	block := false
+	d.patrolConfig.Lifecycle = &LifecycleConfig{WorkspaceProblems: &WorkspaceProblemConfig{BlockOnConflict: &block}}
+	if err := d.syncWorkspace(context.Background(), work); err != nil {
+		t.Errorf("syncWorkspace with blocking off = %v", err)
+	}
+}
+
+func commitFile(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, dir, "add", "a.txt")
+	gitIn(t, dir, "commit", "-m", "edit a.txt")
+}
@@ -0,0 +1,388 @@
+package daemon
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// Zombie reaping parameters
+const (
+	// zombieGracePeriod is how long a zombie has to exit after SIGTERM
+	// before it is sent SIGKILL, and after SIGKILL before it is reported
+	// as unkillable.
+	zombieGracePeriod = time.Minute
+
+	// zombieMinAge is how old (in seconds) an untracked process must be
+	// before its env markers alone condemn it. Younger ones may belong to
+	// a session that is still starting.
+	zombieMinAge = 60
+)
+
+// ZombieStats counts agent processes that outlived their tmux session.
+// Copied into State each heartbeat for gt daemon status.
+type ZombieStats struct {
+	// LastScanAt is when the reaper last scanned the process table.
+	LastScanAt time.Time `json:"last_scan_at"`
+
+	// Found is how many zombies the last scan saw, including ones already
+	// signaled that have not exited yet.
+	Found int `json:"found"`
+
+	// Terminated, Killed, and Unkillable count zombies sent SIGTERM,
+	// escalated to SIGKILL, and still alive after SIGKILL, since the
+	// daemon started.
+	Terminated int `json:"terminated,omitempty"`
+	Killed     int `json:"killed,omitempty"`
+	Unkillable int `json:"unkillable,omitempty"`
+
+	// ByIdentity counts terminated zombies by the identity they belonged to.
+	ByIdentity map[string]int `json:"by_identity,omitempty"`
+}
+
+// zombieReaper finds agent (claude/codex) processes that survived the
+// tmux session they ran in and terminates them.
+//
+// A process is a zombie when either
+//   - it was seen in a managed session's process tree (tracked), and the
+//     session is gone or the process has left its tree (detached); or
+//   - it carries the env markers of one of this town's managed identities
+//     (GT_ROOT, GT_ROLE, ...), is in no running session's tree, and that
+//     identity's session is gone.
+//
+// Env markers alone never condemn a process of an identity whose session
+// is alive (a user may run claude from a shell that sourced .gt-env) or
+// of a containerized identity (its agent runs under the container
+// runtime, and reapContainers removes containers that outlive their
+// session).
+//
+// Zombies get SIGTERM, then SIGKILL a grace period later.
+type zombieReaper struct {
+	// tracked maps session names to the agent processes last seen in them.
+	tracked map[string]*sessionProcesses
+
+	// signaled records the signal last sent to each zombie.
+	signaled map[int]*zombieSignal
+
+	stats ZombieStats
+
+	// Process table access, replaced in tests.
+	listProcesses func() ([]util.ProcessInfo, error)
+	processEnv    func(pid int) (map[string]string, error)
+	signal        func(pid int, force bool) error
+}
+
+// sessionProcesses is the process tree of one session at the last look.
+type sessionProcesses struct {
+	identity string
+
+	// pids maps each agent process to when it was first seen, which tells
+	// a tracked process from a new one that reused its PID.
+	pids map[int]time.Time
+}
+
+// zombieSignal is the last signal sent to a zombie.
+type zombieSignal struct {
+	at       time.Time
+	forced   bool // SIGKILL sent
+	reported bool // reported as unkillable
+}
+
+// zombie is an agent process found outside its session.
+type zombie struct {
+	pid      int
+	cmd      string
+	identity string
+	reason   string
+}
+
+func newZombieReaper() *zombieReaper {
+	return &zombieReaper{
+		tracked:       make(map[string]*sessionProcesses),
+		signaled:      make(map[int]*zombieSignal),
+		listProcesses: util.ListProcesses,
+		processEnv:    util.ProcessEnv,
+		signal:        util.SignalProcess,
+	}
+}
+
+func (d *Daemon) zombieState() *zombieReaper {
+	if d.zombies == nil {
+		d.zombies = newZombieReaper()
+	}
+	return d.zombies
+}
+
+// zombieStats returns a copy of the reaper's counters, or nil before the
+// first scan.
+func (d *Daemon) zombieStats() *ZombieStats {
+	if d.zombies == nil || d.zombies.stats.LastScanAt.IsZero() {
+		return nil
+	}
+	stats := d.zombies.stats
+	if len(stats.ByIdentity) > 0 {
+		stats.ByIdentity = make(map[string]int, len(d.zombies.stats.ByIdentity))
+		for identity, n := range d.zombies.stats.ByIdentity {
+			stats.ByIdentity[identity] = n
+		}
+	}
+	return &stats
+}
+
+// sessionTree returns the pane process of a session and all its
+// descendants, or nil if the session has no pane.
+func (d *Daemon) sessionTree(session string, procs []util.ProcessInfo) []int {
+	out, err := d.tmux.GetPanePID(session)
+	if err != nil {
+		return nil
+	}
+	var tree []int
+	for _, field := range strings.Fields(out) {
+		if pid, err := strconv.Atoi(field); err == nil && pid > 0 {
+			tree = append(tree, pid)
+			tree = append(tree, util.Descendants(procs, pid)...)
+		}
+	}
+	return tree
+}
+
+// track records the agent processes among a session's tree.
+func (r *zombieReaper) track(session, identity string, tree []int, byPID map[int]util.ProcessInfo, now time.Time) {
+	sp := r.tracked[session]
+	if sp == nil {
+		sp = &sessionProcesses{pids: make(map[int]time.Time)}
+		r.tracked[session] = sp
+	}
+	sp.identity = identity
+	for _, pid := range tree {
+		if p, ok := byPID[pid]; ok && util.IsAgentCommand(p.Cmd) {
+			if _, seen := sp.pids[pid]; !seen {
+				sp.pids[pid] = now
+			}
+		}
+	}
+}
+
+// trackSessionProcesses records the agent processes running in a session.
+// Called before the daemon kills a session, so processes that survive the
+// kill are recognized as the session's on the next scan.
+func (d *Daemon) trackSessionProcesses(identity, session string) {
+	r := d.zombieState()
+	procs, err := r.listProcesses()
+	if err != nil {
+		return
+	}
+	r.track(session, identity, d.sessionTree(session, procs), indexProcesses(procs), time.Now())
+}
+
+func indexProcesses(procs []util.ProcessInfo) map[int]util.ProcessInfo {
+	byPID := make(map[int]util.ProcessInfo, len(procs))
+	for _, p := range procs {
+		byPID[p.PID] = p
+	}
+	return byPID
+}
+
+// reapZombies scans the process table for agent processes that outlived
+// their session and signals them. Counts land in the health summary of
+// gt daemon status.
+func (d *Daemon) reapZombies(now time.Time) {
+	r := d.zombieState()
+	procs, err := r.listProcesses()
+	if err != nil {
+		d.logger.Printf("Warning: zombie scan failed: %v", err)
+		return
+	}
+	byPID := indexProcesses(procs)
+
+	// Refresh the process trees of running sessions. Anything in a live
+	// tree is where it belongs.
+	managed := make(map[string]bool)
+	live := make(map[int]bool)
+	running := make(map[string]bool)
+	for _, identity := range d.managedIdentities() {
+		managed[identity] = true
+		session := d.identityToSession(identity)
+		if session == "" {
+			continue
+		}
+		if ok, _ := d.tmux.HasSession(session); !ok {
+			continue
+		}
+		running[session] = true
+		tree := d.sessionTree(session, procs)
+		for _, pid := range tree {
+			live[pid] = true
+		}
+		r.track(session, identity, tree, byPID, now)
+	}
+
+	zombies := d.findZombies(r, byPID, live, running, managed, now)
+	r.stats.LastScanAt = now
+	r.stats.Found = len(zombies)
+
+	for _, z := range zombies {
+		d.signalZombie(r, z, now)
+	}
+
+	// Forget processes that exited and sessions with nothing left to watch
+	for pid := range r.signaled {
+		if _, ok := byPID[pid]; !ok {
+			delete(r.signaled, pid)
+		}
+	}
+	for session, sp := range r.tracked {
+		for pid := range sp.pids {
+			if _, ok := byPID[pid]; !ok {
+				delete(sp.pids, pid)
+			}
+		}
+		if len(sp.pids) == 0 && !running[session] {
+			delete(r.tracked, session)
+		}
+	}
+}
+
+// findZombies returns the agent processes outside any live session tree
+// that belong to a managed session, by tracking or by env markers.
+func (d *Daemon) findZombies(r *zombieReaper, byPID map[int]util.ProcessInfo, live map[int]bool, running, managed map[string]bool, now time.Time) []zombie {
+	var zombies []zombie
+	found := make(map[int]bool)
+
+	sessions := make([]string, 0, len(r.tracked))
+	for session := range r.tracked {
+		sessions = append(sessions, session)
+	}
+	sort.Strings(sessions)
+	for _, session := range sessions {
+		sp := r.tracked[session]
+		for pid, firstSeen := range sp.pids {
+			p, ok := byPID[pid]
+			if !ok || live[pid] || !util.IsAgentCommand(p.Cmd) {
+				continue
+			}
+			// A process younger than our first sighting reused the PID
+			if time.Duration(p.Age)*time.Second+time.Second < now.Sub(firstSeen) {
+				delete(sp.pids, pid)
+				continue
+			}
+			reason := "session " + session + " is gone"
+			if running[session] {
+				reason = "detached from session " + session
+			}
+			zombies = append(zombies, zombie{pid: pid, cmd: p.Cmd, identity: sp.identity, reason: reason})
+			found[pid] = true
+		}
+	}
+
+	pids := make([]int, 0, len(byPID))
+	for pid := range byPID {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		p := byPID[pid]
+		if found[pid] || live[pid] || p.Age < zombieMinAge || !util.IsAgentCommand(p.Cmd) {
+			continue
+		}
+		env, err := r.processEnv(pid)
+		if err != nil || env["GT_ROOT"] != d.config.TownRoot {
+			continue
+		}
+		identity := envIdentity(env)
+		if identity == "" || !managed[identity] || running[d.identityToSession(identity)] || d.containerized(identity) {
+			continue
+		}
+		zombies = append(zombies, zombie{pid: pid, cmd: p.Cmd, identity: identity, reason: "in no running session"})
+	}
+	return zombies
+}
+
+// containerized reports whether identity's agent runs in a container.
+func (d *Daemon) containerized(identity string) bool {
+	cfg := d.patrolConfig.containerConfig()
+	if cfg == nil {
+		return false
+	}
+	parsed, err := parseIdentity(identity)
+	if err != nil {
+		return false
+	}
+	return cfg.profileFor(identity, parsed) != nil
+}
+
+// signalZombie moves a zombie one step along SIGTERM → SIGKILL → unkillable.
+func (d *Daemon) signalZombie(r *zombieReaper, z zombie, now time.Time) {
+	if d.config.DryRun {
+		d.logger.Printf("[dry-run] Zombie reaper: would send SIGTERM to PID %d (%s of %s, %s)", z.pid, z.cmd, z.identity, z.reason)
+		return
+	}
+	s := r.signaled[z.pid]
+	switch {
+	case s == nil:
+		if err := r.signal(z.pid, false); err != nil {
+			d.logger.Printf("Warning: SIGTERM to zombie PID %d (%s) failed: %v", z.pid, z.identity, err)
+			return
+		}
+		r.signaled[z.pid] = &zombieSignal{at: now}
+		r.stats.Terminated++
+		if r.stats.ByIdentity == nil {
+			r.stats.ByIdentity = make(map[string]int)
+		}
+		r.stats.ByIdentity[z.identity]++
+		d.logger.Printf("Zombie reaper: sent SIGTERM to PID %d (%s of %s, %s)", z.pid, z.cmd, z.identity, z.reason)
+
+	case now.Sub(s.at) < zombieGracePeriod:
+		// Still within its grace period
+
+	case !s.forced:
+		if err := r.signal(z.pid, true); err != nil {
+			d.logger.Printf("Warning: SIGKILL to zombie PID %d (%s) failed: %v", z.pid, z.identity, err)
+			return
+		}
+		s.at, s.forced = now, true
+		r.stats.Killed++
+		d.logger.Printf("Zombie reaper: sent SIGKILL to PID %d (%s of %s)", z.pid, z.cmd, z.identity)
+
+	case !s.reported:
+		s.reported = true
+		r.stats.Unkillable++
+		d.logger.Printf("Zombie reaper: WARNING: PID %d (%s of %s) survived SIGKILL", z.pid, z.cmd, z.identity)
+	}
+}
+
+// envIdentity derives the daemon identity of an agent process from the
+// env markers its session set (see setSessionEnvironment), or "" if the
+// markers don't name one.
+func envIdentity(env map[string]string) string {
+	role, rig := env["GT_ROLE"], env["GT_RIG"]
+	switch role {
+	case "":
+		return ""
+	case "mayor", "deacon":
+		return role
+	case "witness", "refinery":
+		if rig == "" {
+			return ""
+		}
+		return rig + "-" + role
+	case "crew":
+		if rig == "" || env["GT_CREW"] == "" {
+			return ""
+		}
+		return rig + "-crew-" + env["GT_CREW"]
+	case "polecat":
+		if rig == "" || env["GT_POLECAT"] == "" {
+			return ""
+		}
+		return rig + "-polecat-" + env["GT_POLECAT"]
+	}
+	// Named plugin agents carry no name marker; only unnamed ones resolve
+	if p := lookupRolePlugin(role); p != nil && !p.Named {
+		return p.identity(rig, "")
+	}
+	return ""
+}
@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// paneTmux reports running sessions and their pane processes; other
+// SessionBackend methods are not used by the reaper.
+type paneTmux struct {
+	SessionBackend
+	panes map[string]int
+}
+
+func (p *paneTmux) HasSession(name string) (bool, error) {
+	_, ok := p.panes[name]
+	return ok, nil
+}
+
+func (p *paneTmux) GetPanePID(session string) (string, error) {
+	pid, ok := p.panes[session]
+	if !ok {
+		return "", fmt.Errorf("session not found: %s", session)
+	}
+	return strconv.Itoa(pid), nil
+}
+
+// fakeProcs is a process table whose ages advance with the test clock.
+type fakeProcs struct {
+	now     time.Time
+	procs   map[int]util.ProcessInfo
+	born    map[int]time.Time
+	env     map[int]map[string]string
+	signals []string
+}
+
+func (f *fakeProcs) add(pid, ppid int, cmd string, age time.Duration, env map[string]string) {
+	f.procs[pid] = util.ProcessInfo{PID: pid, PPID: ppid, Cmd: cmd}
+	f.born[pid] = f.now.Add(-age)
+	if env != nil {
+		f.env[pid] = env
+	}
+}
+
+func (f *fakeProcs) install(r *zombieReaper) {
+	r.listProcesses = func() ([]util.ProcessInfo, error) {
+		var out []util.ProcessInfo
+		for pid, p := range f.procs {
+			p.Age = int(f.now.Sub(f.born[pid]).Seconds())
+			out = append(out, p)
+		}
+		return out, nil
+	}
+	r.processEnv = func(pid int) (map[string]string, error) {
+		if env, ok := f.env[pid]; ok {
+			return env, nil
+		}
+		return nil, fmt.Errorf("no environ for %d", pid)
+	}
+	r.signal = func(pid int, force bool) error {
+		sig := "TERM"
+		if force {
+			sig = "KILL"
+		}
+		f.signals = append(f.signals, fmt.Sprintf("%s %d", sig, pid))
+		return nil
+	}
+}
+
+func (f *fakeProcs) takeSignals() []string {
+	out := f.signals
+	f.signals = nil
+	return out
+}
+
+func TestReapZombies(t *testing.T) {
+	townRoot := t.TempDir()
+	now := time.Now()
+	procs := &fakeProcs{now: now, procs: map[int]util.ProcessInfo{}, born: map[int]time.Time{}, env: map[int]map[string]string{}}
+	tmux := &paneTmux{panes: map[string]int{"hq-mayor": 100}}
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.tmux = tmux
+	procs.install(d.zombieState())
+
+	deaconEnv := map[string]string{"GT_ROOT": townRoot, "GT_ROLE": "deacon"}
+	procs.add(100, 1, "bash", time.Hour, nil)
+	procs.add(101, 100, "claude", time.Hour, map[string]string{"GT_ROOT": townRoot, "GT_ROLE": "mayor"})
+	procs.add(200, 1, "claude", 2*time.Minute, deaconEnv)                                              // deacon not running
+	procs.add(201, 1, "claude", 10*time.Second, deaconEnv)                                             // too young to judge
+	procs.add(300, 1, "claude", time.Hour, map[string]string{"GT_ROOT": "/other", "GT_ROLE": "mayor"}) // another town
+	procs.add(301, 1, "claude", time.Hour, nil)                                                        // unreadable env
+
+	// Pass 1: the deacon's stray process is condemned by its env markers
+	d.reapZombies(procs.now)
+	if got := fmt.Sprint(procs.takeSignals()); got != "[TERM 200]" {
+		t.Fatalf("pass 1 signals = %s, want [TERM 200]", got)
+	}
+
+	// The mayor session is killed; its claude survives, reparented to init
+	delete(tmux.panes, "hq-mayor")
+	delete(procs.procs, 100)
+	procs.procs[101] = util.ProcessInfo{PID: 101, PPID: 1, Cmd: "claude"}
+	delete(procs.env, 101) // tracked processes need no env markers
+	delete(procs.procs, 201)
+
+	procs.now = now.Add(30 * time.Second)
+	d.reapZombies(procs.now)
+	if got := fmt.Sprint(procs.takeSignals()); got != "[TERM 101]" {
+		t.Fatalf("pass 2 signals = %s, want [TERM 101] (200 still in grace period)", got)
+	}
+
+	procs.now = now.Add(2 * time.Minute)
+	d.reapZombies(procs.now)
+	if got := fmt.Sprint(procs.takeSignals()); got != "[KILL 101 KILL 200]" && got != "[KILL 200 KILL 101]" {
+		t.Fatalf("pass 3 signals = %s, want SIGKILL for 101 and 200", got)
+	}
+
+	procs.now = now.Add(4 * time.Minute)
+	d.reapZombies(procs.now)
+	d.reapZombies(procs.now.Add(time.Minute))
+	if got := procs.takeSignals(); len(got) != 0 {
+		t.Errorf("unkillable processes signaled again: %v", got)
+	}
+
+	stats := d.zombieStats()
+	if stats == nil || stats.Found != 2 || stats.Terminated != 2 || stats.Killed != 2 || stats.Unkillable != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats.ByIdentity["mayor"] != 1 || stats.ByIdentity["deacon"] != 1 {
+		t.Errorf("by identity = %v", stats.ByIdentity)
+	}
+
+	// Exited zombies are forgotten
+	procs.procs = map[int]util.ProcessInfo{}
+	d.reapZombies(procs.now.Add(2 * time.Minute))
+	if stats := d.zombieStats(); stats.Found != 0 || len(d.zombies.signaled) != 0 || len(d.zombies.tracked) != 0 {
+		t.Errorf("after exit: stats = %+v, signaled = %v, tracked = %v", stats, d.zombies.signaled, d.zombies.tracked)
+	}
+}
+
+func TestReapZombiesSparesMarkedProcessesOutsideSessions(t *testing.T) {
+	townRoot := t.TempDir()
+	now := time.Now()
+	procs := &fakeProcs{now: now, procs: map[int]util.ProcessInfo{}, born: map[int]time.Time{}, env: map[int]map[string]string{}}
+	tmux := &paneTmux{panes: map[string]int{"hq-deacon": 100}}
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.tmux = tmux
+	d.patrolConfig = &DaemonPatrolConfig{Containers: &ContainerConfig{
+		Runtime:  "podman",
+		Profiles: []*ContainerProfile{{Roles: []string{"mayor"}, Image: "ghcr.io/acme/agent:latest"}},
+	}}
+	procs.install(d.zombieState())
+
+	procs.add(100, 1, "bash", time.Hour, nil)
+	// A user ran claude from a shell that sourced the deacon's .gt-env
+	procs.add(200, 1, "zsh", time.Hour, nil)
+	procs.add(201, 200, "claude", time.Hour, map[string]string{"GT_ROOT": townRoot, "GT_ROLE": "deacon"})
+	// The containerized mayor's claude runs under conmon, outside any session
+	procs.add(300, 1, "conmon", time.Hour, nil)
+	procs.add(301, 300, "claude", time.Hour, map[string]string{"GT_ROOT": townRoot, "GT_ROLE": "mayor"})
+
+	d.reapZombies(procs.now)
+	if got := procs.takeSignals(); len(got) != 0 {
+		t.Errorf("processes outside a session were signaled: %v", got)
+	}
+	if stats := d.zombieStats(); stats == nil || stats.Found != 0 {
+		t.Errorf("stats = %+v, want no zombies", stats)
+	}
+
+	// Once the deacon's session is gone, its marked process is a zombie
+	delete(tmux.panes, "hq-deacon")
+	d.reapZombies(procs.now)
+	if got := fmt.Sprint(procs.takeSignals()); got != "[TERM 201]" {
+		t.Errorf("signals after the deacon session died = %s, want [TERM 201]", got)
+	}
+}
+
+func TestReapZombiesIgnoresReusedPID(t *testing.T) {
+	now := time.Now()
+	procs := &fakeProcs{now: now, procs: map[int]util.ProcessInfo{}, born: map[int]time.Time{}, env: map[int]map[string]string{}}
+	tmux := &paneTmux{panes: map[string]int{"hq-mayor": 100}}
+
+	d := testDaemon()
+	d.config.TownRoot = t.TempDir()
+	d.tmux = tmux
+	procs.install(d.zombieState())
+
+	procs.add(100, 1, "bash", time.Hour, nil)
+	procs.add(101, 100, "claude", time.Hour, nil)
+	d.trackSessionProcesses("mayor", "hq-mayor")
+
+	// The session and its claude exit; an unrelated claude gets PID 101
+	delete(tmux.panes, "hq-mayor")
+	procs.procs = map[int]util.ProcessInfo{}
+	procs.now = now.Add(10 * time.Minute)
+	procs.add(101, 1, "claude", 2*time.Minute, nil)
+
+	d.reapZombies(procs.now)
+	if got := procs.takeSignals(); len(got) != 0 {
+		t.Errorf("process reusing a tracked PID was signaled: %v", got)
+	}
+}
+
+func TestReapZombiesDryRun(t *testing.T) {
+	townRoot := t.TempDir()
+	procs := &fakeProcs{now: time.Now(), procs: map[int]util.ProcessInfo{}, born: map[int]time.Time{}, env: map[int]map[string]string{}}
+
+	d := testDaemon()
+	d.config.TownRoot = townRoot
+	d.config.DryRun = true
+	d.tmux = &paneTmux{panes: map[string]int{}}
+	procs.install(d.zombieState())
+
+	procs.add(200, 1, "claude", 2*time.Minute, map[string]string{"GT_ROOT": townRoot, "GT_ROLE": "deacon"})
+
+	d.reapZombies(procs.now)
+	if got := procs.takeSignals(); len(got) != 0 {
+		t.Errorf("dry run signaled zombies: %v", got)
+	}
+	if stats := d.zombieStats(); stats.Found != 1 || stats.Terminated != 0 {
+		t.Errorf("dry run stats = %+v, want 1 found and none terminated", stats)
+	}
+}
+
+func TestEnvIdentity(t *testing.T) {
+	for _, tc := range []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"GT_ROLE": "mayor"}, "mayor"},
+		{map[string]string{"GT_ROLE": "witness", "GT_RIG": "gastown"}, "gastown-witness"},
+		{map[string]string{"GT_ROLE": "crew", "GT_RIG": "gastown", "GT_CREW": "max"}, "gastown-crew-max"},
+		{map[string]string{"GT_ROLE": "polecat", "GT_RIG": "gastown", "GT_POLECAT": "nux"}, "gastown-polecat-nux"},
+		{map[string]string{"GT_ROLE": "crew", "GT_RIG": "gastown"}, ""},
+		{map[string]string{"GT_ROLE": "refinery"}, ""},
+		{map[string]string{"GT_ROLE": "auditor", "GT_RIG": "gastown"}, ""},
+		{map[string]string{}, ""},
+	} {
+		if got := envIdentity(tc.env); got != tc.want {
+			t.Errorf("envIdentity(%v) = %q, want %q", tc.env, got, tc.want)
+		}
+	}
+}
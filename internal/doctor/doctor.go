@@ -55,6 +55,16 @@ func (d *Doctor) Run(ctx *CheckContext) *Report {
 // Fix runs all checks with auto-fix enabled where possible.
 // It first runs the check, then if it fails and can be fixed, attempts the fix.
 func (d *Doctor) Fix(ctx *CheckContext) *Report {
+	return d.fix(ctx, nil)
+}
+
+// FixInteractive is like Fix but asks confirm before applying each fix.
+// Declined fixes leave the original result in the report.
+func (d *Doctor) FixInteractive(ctx *CheckContext, confirm func(check Check, result *CheckResult) bool) *Report {
+	return d.fix(ctx, confirm)
+}
+
+func (d *Doctor) fix(ctx *CheckContext, confirm func(check Check, result *CheckResult) bool) *Report {
 	report := NewReport()
 
 	for _, check := range d.checks {
@@ -68,7 +78,7 @@ func (d *Doctor) Fix(ctx *CheckContext) *Report {
 		}
 
 		// Attempt fix if check failed and is fixable
-		if result.Status != StatusOK && check.CanFix() {
+		if result.Status != StatusOK && check.CanFix() && (confirm == nil || confirm(check, result)) {
 			err := check.Fix(ctx)
 			if err == nil {
 				// Re-run check to verify fix worked
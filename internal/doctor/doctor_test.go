@@ -330,6 +330,34 @@ func TestDoctor_Fix(t *testing.T) {
 	}
 }
 
+func TestDoctor_FixInteractive(t *testing.T) {
+	d := NewDoctor()
+
+	accepted := newMockCheck("accepted", StatusError)
+	accepted.fixable = true
+	d.Register(accepted)
+
+	declined := newMockCheck("declined", StatusWarning)
+	declined.fixable = true
+	d.Register(declined)
+
+	var asked []string
+	report := d.FixInteractive(&CheckContext{TownRoot: "/test"}, func(check Check, result *CheckResult) bool {
+		asked = append(asked, result.Name)
+		return check.Name() == "accepted"
+	})
+
+	if len(asked) != 2 {
+		t.Fatalf("confirm called for %v, want both failing checks", asked)
+	}
+	if accepted.fixCount != 1 || report.Checks[0].Status != StatusOK {
+		t.Error("accepted check should be fixed")
+	}
+	if declined.fixCount != 0 || report.Checks[1].Status != StatusWarning {
+		t.Error("declined check should not be fixed")
+	}
+}
+
 func TestBaseCheck(t *testing.T) {
 	b := &BaseCheck{
 		CheckName:        "test",
@@ -100,7 +100,7 @@ func (c *ThemeCheck) Fix(ctx *CheckContext) error {
 
 // getSessionStatusLeft retrieves the status-left setting for a tmux session.
 func getSessionStatusLeft(session string) (string, error) {
-	cmd := exec.Command("tmux", "show-options", "-t", session, "status-left")
+	cmd := tmux.Command("show-options", "-t", session, "status-left")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -0,0 +1,530 @@
+package doctor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/manifest"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// requiredBinaries are the tools Gas Town shells out to.
+var requiredBinaries = []struct {
+	name string
+	hint string
+}{
+	{"tmux", "install tmux (e.g. 'brew install tmux' or 'apt install tmux')"},
+	{"git", "install git"},
+	{"bd", "install beads: 'go install github.com/steveyegge/beads/cmd/bd@latest'"},
+	{"gt", "install gt: 'go install github.com/steveyegge/gastown/cmd/gt@latest'"},
+}
+
+// RequiredBinariesCheck verifies that tmux, git, bd, and gt are on PATH.
+type RequiredBinariesCheck struct {
+	BaseCheck
+}
+
+// NewRequiredBinariesCheck creates a new required binaries check.
+func NewRequiredBinariesCheck() *RequiredBinariesCheck {
+	return &RequiredBinariesCheck{
+		BaseCheck: BaseCheck{
+			CheckName:        "required-binaries",
+			CheckDescription: "Check tmux, git, bd, and gt are on PATH",
+			CheckCategory:    CategoryInfrastructure,
+		},
+	}
+}
+
+// Run looks up each required binary.
+func (c *RequiredBinariesCheck) Run(ctx *CheckContext) *CheckResult {
+	var missing, hints []string
+	for _, bin := range requiredBinaries {
+		if _, err := exec.LookPath(bin.name); err != nil {
+			missing = append(missing, bin.name)
+			hints = append(hints, bin.hint)
+		}
+	}
+	if len(missing) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "tmux, git, bd, and gt found on PATH",
+		}
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusError,
+		Message: fmt.Sprintf("Missing from PATH: %s", strings.Join(missing, ", ")),
+		Details: hints,
+		FixHint: "Install the missing tools and make sure they are on PATH",
+	}
+}
+
+// stateFile is one town state file the daemon or CLI reads on startup.
+type stateFile struct {
+	name string
+	load func(townRoot string) error
+
+	// quarantine moves a corrupt file aside so it is regenerated. Nil for
+	// files that hold user configuration and must be repaired by hand.
+	quarantine func(townRoot string) error
+}
+
+// townStateFiles lists the state files StateFilesCheck parses.
+var townStateFiles = []stateFile{
+	{
+		name: "daemon/state.json",
+		load: func(townRoot string) error {
+			_, err := daemon.LoadState(townRoot)
+			return err
+		},
+		quarantine: func(townRoot string) error {
+			return quarantineStoreKey(townRoot, "daemon/state.json")
+		},
+	},
+	{
+		name: "daemon/startups.json",
+		load: func(townRoot string) error {
+			_, err := daemon.LoadStartupJournal(townRoot)
+			return err
+		},
+		quarantine: func(townRoot string) error {
+			return quarantineFile(daemon.StartupJournalFile(townRoot))
+		},
+	},
+	{
+		name: "daemon/beads-sync.json",
+		load: func(townRoot string) error {
+			_, err := daemon.LoadBeadsSyncReport(townRoot)
+			return err
+		},
+		quarantine: func(townRoot string) error {
+			return quarantineStoreKey(townRoot, "daemon/beads-sync.json")
+		},
+	},
+	{
+		name: "mayor/daemon.json",
+		load: func(townRoot string) error {
+			return parseJSONFile(daemon.PatrolConfigFile(townRoot))
+		},
+	},
+	{
+		name: manifest.FileName,
+		load: func(townRoot string) error {
+			_, err := manifest.Load(townRoot)
+			return err
+		},
+	},
+}
+
+// StateFilesCheck verifies that the town's state files parse.
+type StateFilesCheck struct {
+	FixableCheck
+	corrupt []stateFile
+}
+
+// NewStateFilesCheck creates a new state files check.
+func NewStateFilesCheck() *StateFilesCheck {
+	return &StateFilesCheck{
+		FixableCheck: FixableCheck{
+			BaseCheck: BaseCheck{
+				CheckName:        "state-files",
+				CheckDescription: "Check daemon and town state files are parseable",
+				CheckCategory:    CategoryCore,
+			},
+		},
+	}
+}
+
+// Run parses each state file. Missing files are fine.
+func (c *StateFilesCheck) Run(ctx *CheckContext) *CheckResult {
+	c.corrupt = nil
+	var details []string
+	fixable := false
+	for _, f := range townStateFiles {
+		if err := f.load(ctx.TownRoot); err != nil {
+			c.corrupt = append(c.corrupt, f)
+			details = append(details, fmt.Sprintf("%s: %v", f.name, err))
+			if f.quarantine != nil {
+				fixable = true
+			}
+		}
+	}
+	if len(c.corrupt) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "All state files parse",
+		}
+	}
+
+	hint := "Repair the listed files by hand"
+	if fixable {
+		hint = "Run 'gt doctor --fix' to move corrupt daemon state aside (it is rebuilt); repair config files by hand"
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusError,
+		Message: fmt.Sprintf("%d state file(s) failed to parse", len(c.corrupt)),
+		Details: details,
+		FixHint: hint,
+	}
+}
+
+// Fix quarantines corrupt daemon-owned state. Configuration files are left
+// alone; overwriting them would lose the user's settings.
+func (c *StateFilesCheck) Fix(ctx *CheckContext) error {
+	var errs []error
+	for _, f := range c.corrupt {
+		if f.quarantine == nil {
+			continue
+		}
+		if err := f.quarantine(ctx.TownRoot); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// quarantineSuffix names the copy a corrupt state file is moved to.
+func quarantineSuffix() string {
+	return ".corrupt-" + time.Now().Format("20060102-150405")
+}
+
+// quarantineStoreKey moves a corrupt town store key aside.
+func quarantineStoreKey(townRoot, key string) error {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := store.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := store.Put(key+quarantineSuffix(), data); err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+// quarantineFile renames a corrupt file aside.
+func quarantineFile(path string) error {
+	if err := os.Rename(path, path+quarantineSuffix()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func parseJSONFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var v any
+	return json.Unmarshal(data, &v)
+}
+
+// AgentSessionConsistencyCheck verifies that each agent bead's agent_state
+// agrees with whether its tmux session exists.
+type AgentSessionConsistencyCheck struct {
+	FixableCheck
+	drift []manifest.Change
+}
+
+// NewAgentSessionConsistencyCheck creates a new agent/session consistency check.
+func NewAgentSessionConsistencyCheck() *AgentSessionConsistencyCheck {
+	return &AgentSessionConsistencyCheck{
+		FixableCheck: FixableCheck{
+			BaseCheck: BaseCheck{
+				CheckName:        "agent-session-consistency",
+				CheckDescription: "Check agent beads agree with running sessions",
+				CheckCategory:    CategoryCore,
+			},
+		},
+	}
+}
+
+func newQuietSessionController(townRoot string) *daemon.SessionController {
+	return daemon.NewSessionController(townRoot, log.New(io.Discard, "", 0))
+}
+
+// Run compares each managed agent's session with its agent bead.
+func (c *AgentSessionConsistencyCheck) Run(ctx *CheckContext) *CheckResult {
+	c.drift = nil
+	ctl := newQuietSessionController(ctx.TownRoot)
+
+	var details []string
+	for _, identity := range ctl.Identities() {
+		running, err := ctl.IsRunning(identity)
+		if err != nil {
+			continue
+		}
+		state, err := ctl.AgentBeadState(identity)
+		if err != nil {
+			continue // No bead (or no bd): nothing to disagree with
+		}
+		switch {
+		case running && state == daemon.AgentBeadStateStopped:
+			c.drift = append(c.drift, manifest.Change{Action: manifest.ActionSyncBead, Identity: identity,
+				Reason: "agent bead reports stopped but session is running", BeadState: daemon.AgentBeadStateRunning})
+		case !running && state == daemon.AgentBeadStateRunning:
+			c.drift = append(c.drift, manifest.Change{Action: manifest.ActionSyncBead, Identity: identity,
+				Reason: "agent bead reports running but session is stopped", BeadState: daemon.AgentBeadStateStopped})
+		default:
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s: %s", identity, c.drift[len(c.drift)-1].Reason))
+	}
+
+	if len(c.drift) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "Agent beads agree with sessions",
+		}
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: fmt.Sprintf("%d agent bead(s) disagree with tmux", len(c.drift)),
+		Details: details,
+		FixHint: "Run 'gt doctor --fix' to record the observed session state on the beads",
+	}
+}
+
+// Fix records the observed session state on each drifted agent bead. It never
+// starts or stops sessions.
+func (c *AgentSessionConsistencyCheck) Fix(ctx *CheckContext) error {
+	ctl := newQuietSessionController(ctx.TownRoot)
+	var errs []error
+	for _, change := range c.drift {
+		if err := ctl.Apply(change); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", change.Identity, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AgentStateSyncCheck verifies that each agent's state file agrees with
+// its agent bead: the bead is the source of truth for what the agent is
+// doing, the state file for what it requested.
+type AgentStateSyncCheck struct {
+	FixableCheck
+	drift map[string][]daemon.StateDiscrepancy // by identity
+}
+
+// NewAgentStateSyncCheck creates a new agent state sync check.
+func NewAgentStateSyncCheck() *AgentStateSyncCheck {
+	return &AgentStateSyncCheck{
+		FixableCheck: FixableCheck{
+			BaseCheck: BaseCheck{
+				CheckName:        "agent-state-sync",
+				CheckDescription: "Check agent state files agree with agent beads",
+				CheckCategory:    CategoryCore,
+			},
+		},
+	}
+}
+
+// Run compares each managed agent's state file with its agent bead.
+func (c *AgentStateSyncCheck) Run(ctx *CheckContext) *CheckResult {
+	c.drift = make(map[string][]daemon.StateDiscrepancy)
+	ctl := newQuietSessionController(ctx.TownRoot)
+
+	var details []string
+	fixable, conflicts := 0, 0
+	for _, identity := range ctl.Identities() {
+		found, err := ctl.StateDiscrepancies(identity)
+		if err != nil || len(found) == 0 {
+			continue // No bead or state file (or no bd): nothing to disagree with
+		}
+		c.drift[identity] = found
+		for _, s := range found {
+			if s.Conflict {
+				conflicts++
+				details = append(details, s.String()+" (conflict)")
+			} else {
+				fixable++
+				details = append(details, s.String())
+			}
+		}
+	}
+
+	if fixable+conflicts == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "Agent state files agree with agent beads",
+		}
+	}
+	result := &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: fmt.Sprintf("%d agent state discrepancies (%d conflicts)", fixable+conflicts, conflicts),
+		Details: details,
+	}
+	if fixable > 0 {
+		result.FixHint = "Run 'gt doctor --fix' to copy the agent beads' values into the state files"
+	}
+	if conflicts > 0 {
+		result.FixHint = strings.TrimSpace(result.FixHint + "; conflicts need a look: clear the request or restart the agent")
+	}
+	return result
+}
+
+// Fix copies the bead's value into the state file for each discrepancy
+// that isn't a conflict.
+func (c *AgentStateSyncCheck) Fix(ctx *CheckContext) error {
+	ctl := newQuietSessionController(ctx.TownRoot)
+	var errs []error
+	for identity, found := range c.drift {
+		if err := ctl.SyncState(identity, found); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", identity, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Mail backlog thresholds for the deacon inbox.
+const (
+	mailBacklogMaxAge      = time.Hour
+	mailBacklogMaxFailures = 3
+)
+
+// MailBacklogCheck reports a deacon inbox that isn't being drained, using the
+// poll health the daemon records in its state.
+type MailBacklogCheck struct {
+	BaseCheck
+}
+
+// NewMailBacklogCheck creates a new mail backlog check.
+func NewMailBacklogCheck() *MailBacklogCheck {
+	return &MailBacklogCheck{
+		BaseCheck: BaseCheck{
+			CheckName:        "mail-backlog",
+			CheckDescription: "Check the deacon inbox is being drained",
+			CheckCategory:    CategoryCore,
+		},
+	}
+}
+
+// Run inspects the daemon's last mail poll.
+func (c *MailBacklogCheck) Run(ctx *CheckContext) *CheckResult {
+	state, err := daemon.LoadState(ctx.TownRoot)
+	if err != nil || state.MailPoll == nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "No mail poll recorded",
+		}
+	}
+
+	poll := state.MailPoll
+	var details []string
+	if poll.ConsecutiveFailures >= mailBacklogMaxFailures {
+		details = append(details, fmt.Sprintf("%d consecutive inbox polls failed", poll.ConsecutiveFailures))
+	}
+	if !poll.OldestUnreadAt.IsZero() {
+		if age := time.Since(poll.OldestUnreadAt); age > mailBacklogMaxAge {
+			details = append(details, fmt.Sprintf("oldest unread deacon message is %s old", age.Round(time.Minute)))
+		}
+	}
+	if len(details) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "Deacon inbox is being drained",
+		}
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: "Deacon inbox has a stale backlog",
+		Details: details,
+		FixHint: "Check 'gt mail inbox --identity deacon/' and 'gt daemon logs'; restart the deacon if it is stuck",
+	}
+}
+
+// WorkspaceCleanCheck reports agent git workspaces with uncommitted changes.
+// It never fixes them: the changes may be an agent's unfinished work.
+type WorkspaceCleanCheck struct {
+	BaseCheck
+}
+
+// NewWorkspaceCleanCheck creates a new workspace clean check.
+func NewWorkspaceCleanCheck() *WorkspaceCleanCheck {
+	return &WorkspaceCleanCheck{
+		BaseCheck: BaseCheck{
+			CheckName:        "workspace-clean",
+			CheckDescription: "Check agent git workspaces have no uncommitted changes",
+			CheckCategory:    CategoryRig,
+		},
+	}
+}
+
+// Run runs git status in each agent's workspace.
+func (c *WorkspaceCleanCheck) Run(ctx *CheckContext) *CheckResult {
+	ctl := newQuietSessionController(ctx.TownRoot)
+
+	seen := make(map[string]bool)
+	var details []string
+	for _, identity := range ctl.Identities() {
+		dir := ctl.WorkDir(identity)
+		if dir == "" || dir == ctx.TownRoot || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+
+		cmd := exec.Command("git", "status", "--porcelain")
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			continue // Not a git workspace
+		}
+		if n := countLines(string(out)); n > 0 {
+			details = append(details, fmt.Sprintf("%s: %d uncommitted change(s) in %s", identity, n, dir))
+		}
+	}
+
+	if len(details) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "Agent workspaces are clean",
+		}
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: fmt.Sprintf("%d agent workspace(s) have uncommitted changes", len(details)),
+		Details: details,
+		FixHint: "Ask the agent to commit or stash, or inspect with 'git status' in the listed directory",
+	}
+}
+
+func countLines(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	return strings.Count(s, "\n") + 1
+}
@@ -0,0 +1,50 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequiredBinariesCheck_Missing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	result := NewRequiredBinariesCheck().Run(&CheckContext{TownRoot: t.TempDir()})
+	if result.Status != StatusError {
+		t.Fatalf("status = %v, want error", result.Status)
+	}
+	for _, bin := range []string{"tmux", "git", "bd", "gt"} {
+		if !strings.Contains(result.Message, bin) {
+			t.Errorf("message %q does not name %s", result.Message, bin)
+		}
+	}
+}
+
+func TestStateFilesCheck_QuarantinesCorruptDaemonState(t *testing.T) {
+	townRoot := t.TempDir()
+	daemonDir := filepath.Join(townRoot, "daemon")
+	if err := os.MkdirAll(daemonDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(daemonDir, "startups.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := NewStateFilesCheck()
+	ctx := &CheckContext{TownRoot: townRoot}
+	if result := check.Run(ctx); result.Status != StatusError {
+		t.Fatalf("status = %v, want error for corrupt journal", result.Status)
+	}
+	if err := check.Fix(ctx); err != nil {
+		t.Fatalf("Fix: %v", err)
+	}
+	if result := check.Run(ctx); result.Status != StatusOK {
+		t.Fatalf("status after fix = %v (%v), want OK", result.Status, result.Details)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(daemonDir, "startups.json.corrupt-*"))
+	if len(matches) != 1 {
+		t.Errorf("quarantined copies = %v, want one", matches)
+	}
+}
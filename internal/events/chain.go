@@ -0,0 +1,110 @@
+package events
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// The events log is a hash chain. Each event is chained when it is
+// appended: Seq counts events from 1, PrevHash is the Hash of the event
+// before it (empty for the first), and Hash is the SHA-256 of PrevHash and
+// the event's content. Editing, dropping, inserting, or reordering a line
+// breaks the chain from that point, which export reports. Events logged
+// before chaining existed have no hash; the chain starts after them.
+
+// chainTailWindow is how much of the end of the log is read to find the
+// last chained event when appending.
+const chainTailWindow = 64 * 1024
+
+// ChainError reports where the events chain breaks.
+type ChainError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("events chain broken at seq %d: %s", e.Seq, e.Reason)
+}
+
+// chainFields is the content an event's hash covers. Payload is kept as
+// written, so hashes don't depend on how numbers survive a round trip
+// through interface{}.
+type chainFields struct {
+	Seq        int64           `json:"seq"`
+	Timestamp  string          `json:"ts"`
+	Source     string          `json:"source"`
+	Type       string          `json:"type"`
+	Actor      string          `json:"actor"`
+	Visibility string          `json:"visibility"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// chainHash returns the hash of an event's content chained to prevHash.
+func chainHash(prevHash string, f chainFields) string {
+	data, _ := json.Marshal(f) // chainFields always marshals
+	sum := sha256.Sum256(append([]byte(prevHash), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// chainEvent sets ev's Seq, PrevHash, and Hash to follow last, the last
+// chained event in the log (nil if there is none).
+func chainEvent(ev *Event, last *rawEvent) error {
+	var payload json.RawMessage
+	if len(ev.Payload) > 0 {
+		data, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return fmt.Errorf("marshaling payload: %w", err)
+		}
+		payload = data
+	}
+
+	ev.Seq, ev.PrevHash = 1, ""
+	if last != nil {
+		ev.Seq, ev.PrevHash = last.Seq+1, last.Hash
+	}
+	ev.Hash = chainHash(ev.PrevHash, chainFields{
+		Seq:        ev.Seq,
+		Timestamp:  ev.Timestamp,
+		Source:     ev.Source,
+		Type:       ev.Type,
+		Actor:      ev.Actor,
+		Visibility: ev.Visibility,
+		Payload:    payload,
+	})
+	return nil
+}
+
+// lastChainedEvent returns the last chained event in the store's log, or
+// nil if none is chained yet. Only the end of the log is read unless the
+// chain starts before it.
+func lastChainedEvent(store storage.Store) (*rawEvent, error) {
+	for window := int64(chainTailWindow); ; window *= 16 {
+		tail, err := storage.Tail(store, EventsFile, window)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lines := bytes.Split(tail, []byte("\n"))
+		if int64(len(tail)) == window {
+			lines = lines[1:] // the first line may be cut
+		}
+		for i := len(lines) - 1; i >= 0; i-- {
+			var ev rawEvent
+			if json.Unmarshal(lines[i], &ev) == nil && ev.Hash != "" {
+				return &ev, nil
+			}
+		}
+		if int64(len(tail)) < window {
+			return nil, nil // the whole log was read
+		}
+	}
+}
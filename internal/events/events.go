@@ -1,7 +1,8 @@
 // Package events provides event logging for the gt activity feed.
 //
-// Events are written to ~/gt/.events.jsonl (raw audit log) and later
-// curated by the feed daemon into ~/.feed.jsonl (user-facing).
+// Events are written to ~/gt/.events.jsonl (raw audit log, hash-chained as
+// they are appended) and later curated by the feed daemon into
+// ~/.feed.jsonl (user-facing).
 package events
 
 import (
@@ -12,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/storage"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
 
@@ -23,6 +26,12 @@ type Event struct {
 	Actor      string                 `json:"actor"`
 	Payload    map[string]interface{} `json:"payload,omitempty"`
 	Visibility string                 `json:"visibility"`
+
+	// Seq, PrevHash, and Hash chain the event to the one logged before it
+	// (see chain.go). They are set when the event is appended.
+	Seq      int64  `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 // Visibility levels for events.
@@ -45,6 +54,10 @@ const (
 	TypeNudge   = "nudge"
 	TypeBoot    = "boot"
 	TypeHalt    = "halt"
+	TypeAttach  = "attach"
+
+	// Budget events
+	TypeBudgetApproved = "budget_approved"
 
 	// Session events (for seance discovery)
 	TypeSessionStart = "session_start"
@@ -101,7 +114,7 @@ func LogAudit(eventType, actor string, payload map[string]interface{}) error {
 	return Log(eventType, actor, payload, VisibilityAudit)
 }
 
-// write appends an event to the events file.
+// write chains an event onto the events log and appends it.
 func write(event Event) error {
 	// Find town root
 	townRoot, err := workspace.FindFromCwd()
@@ -110,29 +123,37 @@ func write(event Event) error {
 		return nil
 	}
 
-	eventsPath := filepath.Join(townRoot, EventsFile)
-
-	// Marshal event to JSON
-	data, err := json.Marshal(event)
+	store, err := storage.ForTown(townRoot, os.Getenv)
 	if err != nil {
-		return fmt.Errorf("marshaling event: %w", err)
+		return fmt.Errorf("opening town store: %w", err)
 	}
-	data = append(data, '\n')
+	defer store.Close()
 
-	// Append to file with proper locking
+	// Serialize appends across goroutines in this process, and across
+	// processes with a lock file, so each event chains onto the last one
 	mutex.Lock()
 	defer mutex.Unlock()
+	lock := flock.New(filepath.Join(townRoot, EventsFile+".lock"))
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("locking events log: %w", err)
+	}
+	defer func() { _ = lock.Unlock() }()
 
-	f, err := os.OpenFile(eventsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) //nolint:gosec // G302: events file is non-sensitive operational data
+	last, err := lastChainedEvent(store)
 	if err != nil {
-		return fmt.Errorf("opening events file: %w", err)
+		return fmt.Errorf("reading events log: %w", err)
+	}
+	if err := chainEvent(&event, last); err != nil {
+		return err
 	}
-	defer f.Close()
 
-	if _, err := f.Write(data); err != nil {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+	if err := store.Append(EventsFile, append(data, '\n')); err != nil {
 		return fmt.Errorf("writing event: %w", err)
 	}
-
 	return nil
 }
 
@@ -273,6 +294,21 @@ func KillPayload(rig, target, reason string) map[string]interface{} {
 	}
 }
 
+// AttachPayload creates a payload for attach events.
+// session: tmux session the operator attached to
+// agent: Gas Town agent identity (e.g., "gastown/crew/max")
+// pending: lifecycle actions queued for the agent at attach time
+func AttachPayload(session, agent string, pending []string) map[string]interface{} {
+	p := map[string]interface{}{
+		"session": session,
+		"agent":   agent,
+	}
+	if len(pending) > 0 {
+		p["pending"] = pending
+	}
+	return p
+}
+
 // HaltPayload creates a payload for halt events.
 func HaltPayload(services []string) map[string]interface{} {
 	return map[string]interface{}{
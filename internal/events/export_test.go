@@ -0,0 +1,159 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvents are chained into testEventLog after one event logged before
+// chaining existed and a malformed line.
+var testEvents = []Event{
+	{Timestamp: "2026-01-01T00:00:00Z", Source: "gt", Type: "spawn", Actor: "gastown/witness", Payload: map[string]interface{}{"rig": "gastown", "count": 12345678901}, Visibility: "feed"},
+	{Timestamp: "2026-01-02T00:00:00Z", Source: "gt", Type: "kill", Actor: "daemon", Visibility: "audit"},
+	{Timestamp: "2026-01-03T00:00:00Z", Source: "gt", Type: "mass_death", Actor: "daemon", Payload: map[string]interface{}{"note": "a=b|c <x>"}, Visibility: "both"},
+}
+
+var testEventLog = buildEventLog(testEvents)
+
+// buildEventLog chains events the way write does and renders the log.
+func buildEventLog(evs []Event) string {
+	var b strings.Builder
+	b.WriteString(`{"ts":"2025-12-31T00:00:00Z","source":"gt","type":"boot","actor":"daemon","visibility":"audit"}` + "\n")
+	b.WriteString("not json\n")
+	var last *rawEvent
+	for _, ev := range evs {
+		if err := chainEvent(&ev, last); err != nil {
+			panic(err)
+		}
+		line, _ := json.Marshal(ev)
+		b.Write(append(line, '\n'))
+		var raw rawEvent
+		_ = json.Unmarshal(line, &raw)
+		last = &raw
+	}
+	return b.String()
+}
+
+func exportJSON(t *testing.T, records []ExportRecord) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	return buf.String()
+}
+
+func TestExport_ChainVerifies(t *testing.T) {
+	records, err := ReadExportRecords(strings.NewReader(testEventLog), ExportFilter{})
+	if err != nil {
+		t.Fatalf("ReadExportRecords: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records (malformed line skipped), got %d", len(records))
+	}
+	if records[0].Hash != "" || records[1].Seq != 1 || records[1].PrevHash != "" || records[2].PrevHash != records[1].Hash {
+		t.Errorf("records are not chained as logged: %+v", records)
+	}
+
+	count, err := VerifyChain(strings.NewReader(exportJSON(t, records)))
+	if err != nil || count != 3 {
+		t.Errorf("VerifyChain = %d, %v; expected 3, nil", count, err)
+	}
+}
+
+func TestExport_TamperedLogDetected(t *testing.T) {
+	for name, log := range map[string]string{
+		"edited":   strings.Replace(testEventLog, `"actor":"daemon","visibility":"audit","seq"`, `"actor":"mayor","visibility":"audit","seq"`, 1),
+		"dropped":  strings.Replace(testEventLog, strings.Split(testEventLog, "\n")[3]+"\n", "", 1),
+		"inserted": testEventLog + `{"ts":"2026-01-04T00:00:00Z","source":"gt","type":"kill","actor":"mayor","visibility":"audit"}` + "\n",
+	} {
+		var chainErr *ChainError
+		if _, err := ReadExportRecords(strings.NewReader(log), ExportFilter{}); !errors.As(err, &chainErr) {
+			t.Errorf("%s log: err = %v, want a ChainError", name, err)
+		}
+	}
+}
+
+func TestExport_TamperedExportDetected(t *testing.T) {
+	records, _ := ReadExportRecords(strings.NewReader(testEventLog), ExportFilter{})
+
+	altered := exportJSON(t, records)
+	altered = strings.Replace(altered, `"type":"kill","actor":"daemon"`, `"type":"kill","actor":"mayor"`, 1)
+	if _, err := VerifyChain(strings.NewReader(altered)); err == nil {
+		t.Error("expected altered record to fail verification")
+	}
+
+	dropped := exportJSON(t, append([]ExportRecord{records[1]}, records[3]))
+	if _, err := VerifyChain(strings.NewReader(dropped)); err == nil {
+		t.Error("expected dropped record to fail verification")
+	}
+}
+
+func TestExport_Since(t *testing.T) {
+	since := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	records, err := ReadExportRecords(strings.NewReader(testEventLog), ExportFilter{Since: since})
+	if err != nil || len(records) != 2 || records[0].Type != TypeKill {
+		t.Fatalf("expected events from Jan 2 on, got %+v, %v", records, err)
+	}
+	if records[0].Seq != 2 || records[0].PrevHash == "" {
+		t.Errorf("chain restarted for --since: %+v", records[0])
+	}
+	if count, err := VerifyChain(strings.NewReader(exportJSON(t, records))); err != nil || count != 2 {
+		t.Errorf("VerifyChain(since export) = %d, %v", count, err)
+	}
+}
+
+func TestLog_ChainsAppends(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "town.json"), []byte(`{"name":"test"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(townRoot)
+
+	for i := 0; i < 3; i++ {
+		if err := LogAudit(TypeKill, "daemon", map[string]interface{}{"n": i}); err != nil {
+			t.Fatalf("LogAudit: %v", err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(townRoot, EventsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := ReadExportRecords(f, ExportFilter{})
+	if err != nil {
+		t.Fatalf("ReadExportRecords: %v", err)
+	}
+	if len(records) != 3 || records[2].Seq != 3 || records[2].PrevHash != records[1].Hash {
+		t.Errorf("appends not chained: %+v", records)
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	records, _ := ReadExportRecords(strings.NewReader(testEventLog), ExportFilter{})
+	line := FormatCEF(records[3], "0.4.0")
+
+	if !strings.HasPrefix(line, "CEF:0|Gas Town|gt|0.4.0|mass_death|mass_death|8|") {
+		t.Errorf("unexpected CEF header: %s", line)
+	}
+	if !strings.Contains(line, `cs3={"note":"a\=b|c \\u003cx\\u003e"}`) {
+		t.Errorf("expected escaped payload in extension: %s", line)
+	}
+	if !strings.Contains(line, "cs5="+records[3].Hash) {
+		t.Errorf("expected hash in extension: %s", line)
+	}
+}
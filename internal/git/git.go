@@ -387,6 +387,63 @@ func (g *Git) Remotes() ([]string, error) {
 	return strings.Split(out, "\n"), nil
 }
 
+// ExcludeLocally adds paths, relative to the work dir, to the repo's
+// info/exclude file, so git ignores them in this clone without touching
+// .gitignore. A trailing slash matches only a directory. Paths already
+// listed are skipped. Worktrees share the exclude file of their main
+// repository.
+func (g *Git) ExcludeLocally(paths ...string) error {
+	path, err := g.run("rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(path) && g.workDir != "" {
+		path = filepath.Join(g.workDir, path)
+	}
+	// Exclude patterns are relative to the top of the work tree
+	prefix, err := g.run("rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	patterns := make([]string, len(paths))
+	for i, p := range paths {
+		patterns[i] = "/" + prefix + strings.TrimPrefix(filepath.ToSlash(p), "/")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, p := range patterns {
+		if !existing[p] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating info directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	additions := strings.Join(missing, "\n") + "\n"
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		additions = "\n" + additions
+	}
+	_, err = f.WriteString(additions)
+	return err
+}
+
 // Merge merges the given branch into the current branch.
 func (g *Git) Merge(branch string) error {
 	_, err := g.run("merge", branch)
@@ -791,6 +848,13 @@ func (g *Git) WorktreePrune() error {
 	return err
 }
 
+// WorktreeRepair repairs the links between this repository and its linked
+// worktrees after either was moved. paths are the worktrees' new locations.
+func (g *Git) WorktreeRepair(paths ...string) error {
+	_, err := g.run(append([]string{"worktree", "repair"}, paths...)...)
+	return err
+}
+
 // Worktree represents a git worktree.
 type Worktree struct {
 	Path   string
@@ -890,6 +954,18 @@ func (g *Git) CommitsAhead(base, branch string) (int, error) {
 	return count, nil
 }
 
+// ChangedFiles returns the files changed on branch since it diverged from base.
+func (g *Git) ChangedFiles(base, branch string) ([]string, error) {
+	out, err := g.run("diff", "--name-only", base+"..."+branch)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
 // CountCommitsBehind returns the number of commits that HEAD is behind the given ref.
 // For example, CountCommitsBehind("origin/main") returns how many commits
 // are on origin/main that are not on the current HEAD.
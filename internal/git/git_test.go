@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -189,6 +190,51 @@ func TestHasUncommittedChanges(t *testing.T) {
 	}
 }
 
+func TestExcludeLocally(t *testing.T) {
+	dir := initTestRepo(t)
+	g := NewGit(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, ".gt-env"), []byte("GT_ROLE='crew'\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if has, _ := g.HasUncommittedChanges(); !has {
+		t.Fatal("expected untracked file to show as a change")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := g.ExcludeLocally(".gt-env", ".runtime/"); err != nil {
+			t.Fatalf("ExcludeLocally: %v", err)
+		}
+	}
+	has, err := g.HasUncommittedChanges()
+	if err != nil {
+		t.Fatalf("HasUncommittedChanges: %v", err)
+	}
+	if has {
+		t.Error("expected excluded file to be ignored")
+	}
+
+	// Paths are relative to the work dir, not the top of the repo
+	sub := filepath.Join(dir, "mayor")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := NewGit(sub).ExcludeLocally(".gt-env"); err != nil {
+		t.Fatalf("ExcludeLocally in subdir: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, ".git", "info", "exclude"))
+	if err != nil {
+		t.Fatalf("read exclude: %v", err)
+	}
+	if n := strings.Count(string(content), "\n/.gt-env\n"); n != 1 {
+		t.Errorf("exclude lists /.gt-env %d times, want once:\n%s", n, content)
+	}
+	if !strings.Contains(string(content), "\n/mayor/.gt-env\n") {
+		t.Errorf("subdir path not anchored to the subdir:\n%s", content)
+	}
+}
+
 func TestCheckout(t *testing.T) {
 	dir := initTestRepo(t)
 	g := NewGit(dir)
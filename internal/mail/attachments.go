@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxInlineBody is the largest body stored directly on the message bead.
+// Longer bodies are stored as a "body.txt" attachment and the message keeps
+// a preview, so logs and diffs don't bloat the beads database or exceed the
+// argument limit of the bd invocation that creates the message.
+const MaxInlineBody = 32 * 1024
+
+// bodyPreviewSize is how much of a spilled body stays inline.
+const bodyPreviewSize = 2 * 1024
+
+// attachmentDir is the content-addressed store under the mail beads directory.
+const attachmentDir = "mail-attachments"
+
+// Attachment is a file attached to a message. Contents are stored once per
+// town, named by their SHA-256, so the same log mailed to ten agents is kept
+// once; the message bead only carries an attachment:<sha256>:<name> label.
+type Attachment struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// label returns the bead label recording the attachment.
+func (a Attachment) label() string {
+	return "attachment:" + a.SHA256 + ":" + a.Name
+}
+
+// parseAttachmentLabel parses the value of an attachment: label.
+func parseAttachmentLabel(value string) (Attachment, bool) {
+	sum, name, ok := strings.Cut(value, ":")
+	if !ok || !validSHA256(sum) || name == "" {
+		return Attachment{}, false
+	}
+	return Attachment{Name: name, SHA256: sum}, true
+}
+
+func validSHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// attachmentName makes a file name safe to carry in a label: labels are
+// comma-separated on the bd command line and the name is shown to readers.
+func attachmentName(name string) string {
+	name = filepath.Base(name)
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == ',' || r == ':' || r == '/' || r == '\\':
+			return '_'
+		case r < ' ':
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." {
+		return "attachment"
+	}
+	return name
+}
+
+// AttachmentPath returns where an attachment's contents are stored for the
+// mail beads directory beadsDir.
+func AttachmentPath(beadsDir string, a Attachment) string {
+	return filepath.Join(beadsDir, attachmentDir, a.SHA256[:2], a.SHA256)
+}
+
+// StoreAttachment stores data under beadsDir and returns its attachment.
+// Storing the same contents twice is a no-op.
+func StoreAttachment(beadsDir, name string, data []byte) (Attachment, error) {
+	sum := sha256.Sum256(data)
+	a := Attachment{Name: attachmentName(name), SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+
+	path := AttachmentPath(beadsDir, a)
+	if info, err := os.Stat(path); err == nil && info.Size() == a.Size {
+		return a, nil
+	}
+	if err := ensureAttachmentDir(beadsDir, filepath.Dir(path)); err != nil {
+		return Attachment{}, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return Attachment{}, fmt.Errorf("storing attachment %s: %w", a.Name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return Attachment{}, fmt.Errorf("storing attachment %s: %w", a.Name, err)
+	}
+	return a, nil
+}
+
+// AttachFile stores the file at path under beadsDir and returns its attachment.
+func AttachFile(beadsDir, path string) (Attachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+	return StoreAttachment(beadsDir, path, data)
+}
+
+// ReadAttachment returns an attachment's contents.
+func ReadAttachment(beadsDir string, a Attachment) ([]byte, error) {
+	if !validSHA256(a.SHA256) {
+		return nil, fmt.Errorf("invalid attachment hash %q", a.SHA256)
+	}
+	data, err := os.ReadFile(AttachmentPath(beadsDir, a))
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment %s: %w", a.Name, err)
+	}
+	return data, nil
+}
+
+// ensureAttachmentDir creates dir inside the attachment store, keeping the
+// store out of git: the town's .beads directory may be committed, and
+// attachments are as transient as the wisp mail that carries them.
+func ensureAttachmentDir(beadsDir, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating attachment store: %w", err)
+	}
+	ignore := filepath.Join(beadsDir, attachmentDir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		_ = os.WriteFile(ignore, []byte("*\n"), 0644)
+	}
+	return nil
+}
+
+// spillBody moves an oversized body into a body.txt attachment, leaving a
+// preview and a pointer inline.
+func spillBody(beadsDir string, msg *Message) error {
+	if len(msg.Body) <= MaxInlineBody {
+		return nil
+	}
+	a, err := StoreAttachment(beadsDir, "body.txt", []byte(msg.Body))
+	if err != nil {
+		return err
+	}
+	preview := msg.Body[:bodyPreviewSize]
+	for !utf8.ValidString(preview) {
+		preview = preview[:len(preview)-1] // Don't split a multi-byte rune
+	}
+	msg.Body = fmt.Sprintf("%s\n\n[... body truncated: full %d bytes in attachment %s]", preview, a.Size, a.Name)
+	msg.Attachments = append(msg.Attachments, a)
+	return nil
+}
+
+// attachmentLabels returns the bead labels for a message's attachments.
+func attachmentLabels(msg *Message) []string {
+	labels := make([]string, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		labels = append(labels, a.label())
+	}
+	return labels
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreAttachment(t *testing.T) {
+	beadsDir := t.TempDir()
+
+	a, err := StoreAttachment(beadsDir, "/tmp/logs/cycle,1.log", []byte("log line\n"))
+	if err != nil {
+		t.Fatalf("StoreAttachment: %v", err)
+	}
+	if a.Name != "cycle_1.log" || a.Size != 9 || len(a.SHA256) != 64 {
+		t.Errorf("attachment = %+v", a)
+	}
+
+	// Same contents under another name share the stored file
+	b, err := StoreAttachment(beadsDir, "other.log", []byte("log line\n"))
+	if err != nil {
+		t.Fatalf("StoreAttachment again: %v", err)
+	}
+	if AttachmentPath(beadsDir, a) != AttachmentPath(beadsDir, b) {
+		t.Error("identical contents stored twice")
+	}
+
+	data, err := ReadAttachment(beadsDir, a)
+	if err != nil || string(data) != "log line\n" {
+		t.Errorf("ReadAttachment = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(beadsDir, attachmentDir, ".gitignore")); err != nil {
+		t.Errorf("attachment store not gitignored: %v", err)
+	}
+}
+
+func TestAttachmentLabelsRoundTrip(t *testing.T) {
+	a := Attachment{Name: "diff.patch", SHA256: strings.Repeat("ab", 32)}
+	bm := BeadsMessage{
+		ID:     "hq-1",
+		Labels: []string{"from:mayor/", a.label(), "attachment:not-a-hash:x"},
+	}
+	msg := bm.ToMessage()
+	if len(msg.Attachments) != 1 || msg.Attachments[0] != a {
+		t.Errorf("Attachments = %+v, want [%+v]", msg.Attachments, a)
+	}
+
+	// Parsing twice must not duplicate attachments
+	bm.ParseLabels()
+	if len(bm.Attachments) != 1 {
+		t.Errorf("Attachments after reparse = %+v", bm.Attachments)
+	}
+}
+
+func TestSpillBody(t *testing.T) {
+	beadsDir := t.TempDir()
+	body := strings.Repeat("é", MaxInlineBody) // multi-byte, so the preview cut is exercised
+	msg := &Message{To: "mayor/", Body: body}
+
+	if err := spillBody(beadsDir, msg); err != nil {
+		t.Fatalf("spillBody: %v", err)
+	}
+	if len(msg.Body) > bodyPreviewSize+200 || !strings.Contains(msg.Body, "body truncated") {
+		t.Errorf("inline body not truncated: %d bytes", len(msg.Body))
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Name != "body.txt" {
+		t.Fatalf("Attachments = %+v", msg.Attachments)
+	}
+	data, err := ReadAttachment(beadsDir, msg.Attachments[0])
+	if err != nil || string(data) != body {
+		t.Errorf("stored body mismatch (%d bytes), err %v", len(data), err)
+	}
+
+	small := &Message{To: "mayor/", Body: "short"}
+	if err := spillBody(beadsDir, small); err != nil || small.Body != "short" || len(small.Attachments) != 0 {
+		t.Errorf("short body changed: %+v, %v", small, err)
+	}
+}
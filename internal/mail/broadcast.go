@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+// Broadcasts: one send to a group of agents, fanned out into a message per
+// recipient. Every copy carries the same broadcast ID and the address it
+// was sent to, so a broadcast can be found (and filtered) in any inbox.
+//
+// Besides @group addresses, the group forms are:
+//   - all-agents: every agent in the town
+//   - witness/*, refinery/*, crew/*, polecats/*: a role across all rigs
+//   - gastown/crew/*, gastown/polecats/*, */witness: wildcard addresses,
+//     '*' matching one path segment
+
+// AllAgentsAddress addresses every agent in the town.
+const AllAgentsAddress = "all-agents"
+
+// broadcastRoles maps the role names accepted in role patterns ("witness/*")
+// to the role in agent bead IDs.
+var broadcastRoles = map[string]string{
+	"mayor":      "mayor",
+	"deacon":     "deacon",
+	"witness":    "witness",
+	"witnesses":  "witness",
+	"refinery":   "refinery",
+	"refineries": "refinery",
+	"crew":       "crew",
+	"polecat":    "polecat",
+	"polecats":   "polecat",
+	"dog":        "dog",
+	"dogs":       "dog",
+}
+
+// IsBroadcastAddress reports whether address names a group of agents
+// rather than one recipient.
+func IsBroadcastAddress(address string) bool {
+	return address == AllAgentsAddress || isGroupAddress(address) ||
+		(strings.Contains(address, "/") && strings.Contains(address, "*"))
+}
+
+// matchRolePattern reports whether the agent bead id matches a role
+// pattern like "witness/*".
+func matchRolePattern(pattern, id string) bool {
+	name, rest, ok := strings.Cut(pattern, "/")
+	if !ok || rest != "*" {
+		return false
+	}
+	role, ok := broadcastRoles[name]
+	return ok && agentBeadIDRole(id) == role
+}
+
+// agentBeadIDRole returns the role of an agent bead ID: "mayor" for
+// gt-mayor, "witness" for gt-gastown-witness, "crew" for gt-gastown-crew-max.
+func agentBeadIDRole(id string) string {
+	rest, ok := strings.CutPrefix(id, "gt-")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(rest, "-")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// generateBroadcastID creates a random broadcast ID.
+// Falls back to time-based ID if crypto/rand fails (extremely rare).
+func generateBroadcastID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("bcast-%x", time.Now().UnixNano())
+	}
+	return "bcast-" + hex.EncodeToString(b)
+}
+
+// MarkBroadcast records that the message is a broadcast to address, giving
+// it a broadcast ID. A message already marked keeps its ID, so nested
+// fan-outs (a list containing a group) stay one broadcast.
+func (m *Message) MarkBroadcast(address string) {
+	if m.BroadcastID != "" {
+		return
+	}
+	m.BroadcastID = generateBroadcastID()
+	m.BroadcastTo = address
+}
+
+// InBroadcast reports whether the message was delivered by the broadcast
+// with the given ID, or by a broadcast to the given address.
+func (m *Message) InBroadcast(idOrAddress string) bool {
+	return m.BroadcastID != "" && (m.BroadcastID == idOrAddress || m.BroadcastTo == idOrAddress)
+}
+
+// broadcastLabels returns the labels recording a message's broadcast.
+func broadcastLabels(msg *Message) []string {
+	if msg.BroadcastID == "" {
+		return nil
+	}
+	return []string{"broadcast:" + msg.BroadcastID, "broadcast-to:" + msg.BroadcastTo}
+}
+
+// sendToPattern resolves all-agents or a wildcard address and sends each
+// matching agent a copy of the message.
+func (r *Router) sendToPattern(msg *Message) error {
+	beadsDir := r.resolveBeadsDir("")
+	resolver := NewResolver(beads.NewWithBeadsDir(filepath.Dir(beadsDir), beadsDir), r.townRoot)
+	recipients, err := resolver.Resolve(msg.To)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", msg.To, err)
+	}
+
+	broadcast := *msg
+	broadcast.MarkBroadcast(msg.To)
+	var errs []string
+	for _, recipient := range recipients {
+		msgCopy := broadcast
+		msgCopy.To = recipient.Address
+		if err := r.sendToSingle(&msgCopy); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", recipient.Address, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("some broadcast sends failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
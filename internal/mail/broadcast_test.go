@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsBroadcastAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"all-agents", true},
+		{"witness/*", true},
+		{"gastown/crew/*", true},
+		{"*/witness", true},
+		{"@town", true},
+		{"gastown/witness", false},
+		{"mayor/", false},
+		{"list:oncall", false},
+	}
+	for _, tt := range tests {
+		if got := IsBroadcastAddress(tt.address); got != tt.want {
+			t.Errorf("IsBroadcastAddress(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRolePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		id      string
+		want    bool
+	}{
+		{"witness/*", "gt-gastown-witness", true},
+		{"witnesses/*", "gt-beads-witness", true},
+		{"witness/*", "gt-gastown-refinery", false},
+		{"crew/*", "gt-gastown-crew-max", true},
+		{"polecats/*", "gt-gastown-polecat-Toast", true},
+		{"mayor/*", "gt-mayor", true},
+		{"witness/max", "gt-gastown-witness", false},
+		{"gastown/*", "gt-gastown-witness", false},
+		{"witness/*", "hq-witness", false},
+	}
+	for _, tt := range tests {
+		if got := matchRolePattern(tt.pattern, tt.id); got != tt.want {
+			t.Errorf("matchRolePattern(%q, %q) = %v, want %v", tt.pattern, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMarkBroadcast(t *testing.T) {
+	msg := NewMessage("mayor/", "witness/*", "Freeze", "Stop merging")
+	msg.MarkBroadcast("witness/*")
+	if !strings.HasPrefix(msg.BroadcastID, "bcast-") {
+		t.Fatalf("BroadcastID = %q, want bcast- prefix", msg.BroadcastID)
+	}
+	if msg.BroadcastTo != "witness/*" {
+		t.Errorf("BroadcastTo = %q, want witness/*", msg.BroadcastTo)
+	}
+
+	// Marking again (nested fan-out) keeps the broadcast
+	id := msg.BroadcastID
+	msg.MarkBroadcast("gastown/witness")
+	if msg.BroadcastID != id || msg.BroadcastTo != "witness/*" {
+		t.Errorf("re-marked broadcast = %s to %s, want %s to witness/*", msg.BroadcastID, msg.BroadcastTo, id)
+	}
+
+	if !msg.InBroadcast(id) || !msg.InBroadcast("witness/*") {
+		t.Error("InBroadcast should match the broadcast ID and address")
+	}
+	if msg.InBroadcast("crew/*") {
+		t.Error("InBroadcast(crew/*) = true, want false")
+	}
+	if (&Message{}).InBroadcast("") {
+		t.Error("InBroadcast on a direct message = true, want false")
+	}
+}
+
+func TestBeadsMessageBroadcastLabels(t *testing.T) {
+	msg := NewMessage("mayor/", "all-agents", "Hi", "")
+	msg.MarkBroadcast("all-agents")
+
+	bm := BeadsMessage{
+		ID:     "hq-1",
+		Title:  "Hi",
+		Labels: append([]string{"from:mayor/"}, broadcastLabels(msg)...),
+	}
+	got := bm.ToMessage()
+	if got.BroadcastID != msg.BroadcastID || got.BroadcastTo != "all-agents" {
+		t.Errorf("ToMessage broadcast = %q to %q, want %q to all-agents", got.BroadcastID, got.BroadcastTo, msg.BroadcastID)
+	}
+
+	if labels := broadcastLabels(&Message{}); labels != nil {
+		t.Errorf("broadcastLabels on a direct message = %v, want nil", labels)
+	}
+}
@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// InboxFilter selects and pages inbox messages. The zero value matches
+// every message and returns them all.
+type InboxFilter struct {
+	// From matches the sender address, exactly or as a path.Match glob
+	// ("gastown/*"). A trailing slash is ignored, so "mayor" matches "mayor/".
+	From string
+
+	// Type matches the message type; messages without one are notifications.
+	Type MessageType
+
+	// Priority is the lowest priority to include: PriorityHigh matches high
+	// and urgent messages.
+	Priority Priority
+
+	// UnreadOnly drops messages that have been read.
+	UnreadOnly bool
+
+	// Since and Until bound the message timestamp (inclusive). Zero values
+	// don't bound it.
+	Since time.Time
+	Until time.Time
+
+	// SubjectPrefixes matches messages whose subject starts with any of the
+	// prefixes, ignoring case.
+	SubjectPrefixes []string
+
+	// Offset skips that many matching messages, and Limit caps how many are
+	// returned (0 for no cap).
+	Offset int
+	Limit  int
+}
+
+// Matches reports whether msg passes the filter. Offset and Limit don't
+// apply to a single message.
+func (f *InboxFilter) Matches(msg *Message) bool {
+	if f.UnreadOnly && msg.Read {
+		return false
+	}
+	if f.From != "" && !matchSender(f.From, msg.From) {
+		return false
+	}
+	if f.Type != "" {
+		msgType := msg.Type
+		if msgType == "" {
+			msgType = TypeNotification
+		}
+		if msgType != f.Type {
+			return false
+		}
+	}
+	if f.Priority != "" && PriorityToBeads(msg.Priority) > PriorityToBeads(f.Priority) {
+		return false
+	}
+	if !f.Since.IsZero() && msg.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && msg.Timestamp.After(f.Until) {
+		return false
+	}
+	if len(f.SubjectPrefixes) > 0 {
+		subject := strings.ToLower(msg.Subject)
+		matched := false
+		for _, prefix := range f.SubjectPrefixes {
+			if strings.HasPrefix(subject, strings.ToLower(prefix)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSender matches a sender address against an address or glob.
+func matchSender(pattern, from string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	from = strings.TrimSuffix(from, "/")
+	if strings.EqualFold(pattern, from) {
+		return true
+	}
+	matched, _ := path.Match(pattern, from)
+	return matched
+}
+
+// InboxPage is one page of filtered inbox messages.
+type InboxPage struct {
+	// Messages is the page, newest first.
+	Messages []*Message `json:"messages"`
+
+	// Total is how many messages matched the filter across all pages.
+	Total int `json:"total"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit,omitempty"`
+}
+
+// NextOffset returns the offset of the next page, or 0 if this is the last.
+func (p *InboxPage) NextOffset() int {
+	next := p.Offset + len(p.Messages)
+	if len(p.Messages) == 0 || next >= p.Total {
+		return 0
+	}
+	return next
+}
+
+// FilterMessages returns the page of messages (as listed by Mailbox.List,
+// newest first) that match the filter.
+func FilterMessages(messages []*Message, f InboxFilter) *InboxPage {
+	var matched []*Message
+	for _, msg := range messages {
+		if f.Matches(msg) {
+			matched = append(matched, msg)
+		}
+	}
+	page := &InboxPage{Messages: []*Message{}, Total: len(matched), Offset: f.Offset, Limit: f.Limit}
+	if f.Offset < len(matched) {
+		matched = matched[max(f.Offset, 0):]
+		if f.Limit > 0 && len(matched) > f.Limit {
+			matched = matched[:f.Limit]
+		}
+		page.Messages = matched
+	}
+	return page
+}
@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInboxFilterMatches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	msg := &Message{
+		From:      "gastown/Toast",
+		Subject:   "LIFECYCLE: cycle",
+		Timestamp: now,
+		Priority:  PriorityHigh,
+		Type:      TypeTask,
+	}
+	tests := []struct {
+		name   string
+		filter InboxFilter
+		want   bool
+	}{
+		{"zero filter", InboxFilter{}, true},
+		{"exact sender", InboxFilter{From: "gastown/Toast"}, true},
+		{"sender glob", InboxFilter{From: "gastown/*"}, true},
+		{"other sender", InboxFilter{From: "mayor/"}, false},
+		{"type", InboxFilter{Type: TypeTask}, true},
+		{"other type", InboxFilter{Type: TypeReply}, false},
+		{"priority at least normal", InboxFilter{Priority: PriorityNormal}, true},
+		{"priority at least urgent", InboxFilter{Priority: PriorityUrgent}, false},
+		{"since", InboxFilter{Since: now.Add(-time.Hour)}, true},
+		{"since later", InboxFilter{Since: now.Add(time.Hour)}, false},
+		{"until earlier", InboxFilter{Until: now.Add(-time.Hour)}, false},
+		{"subject prefix ignores case", InboxFilter{SubjectPrefixes: []string{"escalate:", "lifecycle:"}}, true},
+		{"other subject", InboxFilter{SubjectPrefixes: []string{"ESCALATE:"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(msg); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	read := &Message{From: "mayor/", Read: true}
+	if (&InboxFilter{UnreadOnly: true}).Matches(read) {
+		t.Error("UnreadOnly matched a read message")
+	}
+	if !(&InboxFilter{From: "mayor"}).Matches(read) {
+		t.Error("sender match should ignore a trailing slash")
+	}
+	if !(&InboxFilter{Type: TypeNotification}).Matches(read) {
+		t.Error("a message without a type should match notification")
+	}
+}
+
+func TestFilterMessagesPaging(t *testing.T) {
+	var messages []*Message
+	for _, id := range []string{"m5", "m4", "m3", "m2", "m1"} {
+		messages = append(messages, &Message{ID: id, Read: id == "m4"})
+	}
+
+	page := FilterMessages(messages, InboxFilter{UnreadOnly: true, Offset: 1, Limit: 2})
+	if page.Total != 4 || len(page.Messages) != 2 || page.Messages[0].ID != "m3" || page.Messages[1].ID != "m2" {
+		t.Fatalf("page = %+v, want m3 and m2 of 4", page)
+	}
+	if page.NextOffset() != 3 {
+		t.Errorf("NextOffset() = %d, want 3", page.NextOffset())
+	}
+
+	last := FilterMessages(messages, InboxFilter{Offset: 3, Limit: 5})
+	if len(last.Messages) != 2 || last.NextOffset() != 0 {
+		t.Errorf("last page = %+v, want 2 messages and no next page", last)
+	}
+	past := FilterMessages(messages, InboxFilter{Offset: 10})
+	if past.Messages == nil || len(past.Messages) != 0 || past.Total != 5 {
+		t.Errorf("page past the end = %+v, want empty with total 5", past)
+	}
+}
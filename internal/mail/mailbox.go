@@ -78,6 +78,15 @@ func (m *Mailbox) Identity() string {
 	return m.identity
 }
 
+// AttachmentPath returns where a message attachment is stored.
+func (m *Mailbox) AttachmentPath(a Attachment) string {
+	beadsDir := m.beadsDir
+	if beadsDir == "" {
+		beadsDir = filepath.Join(m.workDir, ".beads")
+	}
+	return AttachmentPath(beadsDir, a)
+}
+
 // Path returns the JSONL path for legacy mailboxes.
 func (m *Mailbox) Path() string {
 	return m.path
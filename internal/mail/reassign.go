@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ReassignRig readdresses the open messages of a renamed rig's agents from
+// oldRig to newRig ("oldrig/witness" -> "newrig/witness"), so they show up
+// in the agents' inboxes under the new name. Returns the IDs of the
+// messages it moved.
+func ReassignRig(workDir, beadsDir, oldRig, newRig string) ([]string, error) {
+	stdout, err := runBdCommand([]string{"list", "--type", "message", "--status=open", "--json"}, workDir, beadsDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(stdout) == 0 || string(stdout) == "null" {
+		return nil, nil
+	}
+	var beadsMsgs []BeadsMessage
+	if err := json.Unmarshal(stdout, &beadsMsgs); err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for _, bm := range beadsMsgs {
+		rest, ok := strings.CutPrefix(bm.Assignee, oldRig+"/")
+		if !ok {
+			continue
+		}
+		if _, err := runBdCommand([]string{"update", bm.ID, "--assignee", newRig + "/" + rest}, workDir, beadsDir); err != nil {
+			return moved, err
+		}
+		moved = append(moved, bm.ID)
+	}
+	return moved, nil
+}
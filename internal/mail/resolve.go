@@ -8,6 +8,7 @@ package mail
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/steveyegge/gastown/internal/beads"
@@ -81,6 +82,11 @@ func (r *Resolver) Resolve(address string) ([]Recipient, error) {
 		return r.resolveAtPattern(address)
 	}
 
+	// Every agent in the town
+	if address == AllAgentsAddress {
+		return r.resolvePattern(address)
+	}
+
 	// 4. Name lookup: group → queue → channel
 	return r.resolveByName(address)
 }
@@ -101,7 +107,8 @@ func (r *Resolver) resolveAgentAddress(address string) ([]Recipient, error) {
 }
 
 // resolvePattern expands a wildcard pattern to matching agents.
-// Patterns like "*/witness" or "gastown/*" are expanded.
+// Patterns like "*/witness" or "gastown/*" are expanded, as are role
+// patterns ("witness/*") and all-agents.
 func (r *Resolver) resolvePattern(pattern string) ([]Recipient, error) {
 	if r.beads == nil {
 		return nil, fmt.Errorf("beads not available for pattern resolution")
@@ -117,7 +124,7 @@ func (r *Resolver) resolvePattern(pattern string) ([]Recipient, error) {
 	for id := range agents {
 		// Convert bead ID to address and check match
 		addr := agentBeadIDToAddress(id)
-		if addr != "" && matchPattern(pattern, addr) {
+		if addr != "" && (pattern == AllAgentsAddress || matchPattern(pattern, addr) || matchRolePattern(pattern, id)) {
 			recipients = append(recipients, Recipient{
 				Address: addr,
 				Type:    RecipientAgent,
@@ -128,6 +135,7 @@ func (r *Resolver) resolvePattern(pattern string) ([]Recipient, error) {
 	if len(recipients) == 0 {
 		return nil, fmt.Errorf("no agents match pattern: %s", pattern)
 	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].Address < recipients[j].Address })
 
 	return recipients, nil
 }
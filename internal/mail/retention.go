@@ -0,0 +1,413 @@
+package mail
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention rule actions
+const (
+	RetentionArchive = "archive" // copy to the monthly archive, then delete
+	RetentionDelete  = "delete"  // delete without keeping a copy
+)
+
+// Retention rule read-status filters
+const (
+	RetentionRead   = "read"
+	RetentionUnread = "unread"
+)
+
+// ArchiveDirName is the directory under the town root holding the mail
+// archive: one gzip-compressed JSONL file per month (2026-01.jsonl.gz).
+const ArchiveDirName = "mail-archive"
+
+// RetentionConfig configures mail retention, under "mail_retention" in
+// mayor/daemon.json:
+//
+//	"mail_retention": {
+//	  "interval": "24h",
+//	  "rules": [
+//	    {"older_than": "7d", "types": ["notification"], "status": "read", "action": "delete"},
+//	    {"older_than": "30d", "status": "read"},
+//	    {"older_than": "90d"}
+//	  ]
+//	}
+//
+// The first rule a message matches decides its fate. Pinned messages and
+// messages matching no rule are kept.
+type RetentionConfig struct {
+	// Interval is how often the daemon applies the rules (Go duration
+	// string, default "24h").
+	Interval string `json:"interval,omitempty"`
+
+	Rules []RetentionRule `json:"rules"`
+}
+
+// RetentionRule selects messages by age, type, and read status.
+type RetentionRule struct {
+	// OlderThan is the minimum message age: a Go duration or a number of
+	// days ("30d"). Required.
+	OlderThan string `json:"older_than"`
+
+	// Types limits the rule to these message types (task, scavenge,
+	// notification, reply). Default: all.
+	Types []string `json:"types,omitempty"`
+
+	// Status limits the rule to "read" or "unread" messages. Default: both.
+	Status string `json:"status,omitempty"`
+
+	// Action is "archive" (default) or "delete".
+	Action string `json:"action,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *RetentionConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("mail_retention: invalid interval %q", c.Interval)
+		}
+	}
+	for i, r := range c.Rules {
+		if _, err := parseAge(r.OlderThan); err != nil {
+			return fmt.Errorf("mail_retention: rule %d: %w", i+1, err)
+		}
+		for _, t := range r.Types {
+			switch MessageType(t) {
+			case TypeTask, TypeScavenge, TypeNotification, TypeReply:
+			default:
+				return fmt.Errorf("mail_retention: rule %d: unknown message type %q", i+1, t)
+			}
+		}
+		switch r.Status {
+		case "", RetentionRead, RetentionUnread:
+		default:
+			return fmt.Errorf("mail_retention: rule %d: status must be %q or %q", i+1, RetentionRead, RetentionUnread)
+		}
+		switch r.Action {
+		case "", RetentionArchive, RetentionDelete:
+		default:
+			return fmt.Errorf("mail_retention: rule %d: action must be %q or %q", i+1, RetentionArchive, RetentionDelete)
+		}
+	}
+	return nil
+}
+
+// parseAge parses a rule age: a Go duration or whole days ("30d").
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// matches reports whether msg falls under the rule at now.
+func (r *RetentionRule) matches(msg *Message, now time.Time) bool {
+	age, err := parseAge(r.OlderThan)
+	if err != nil || now.Sub(msg.Timestamp) < age {
+		return false
+	}
+	if len(r.Types) > 0 && !slices.Contains(r.Types, string(msg.Type)) {
+		return false
+	}
+	switch r.Status {
+	case RetentionRead:
+		return msg.Read
+	case RetentionUnread:
+		return !msg.Read
+	}
+	return true
+}
+
+// action returns the rule's action with the default applied.
+func (r *RetentionRule) action() string {
+	if r.Action == "" {
+		return RetentionArchive
+	}
+	return r.Action
+}
+
+// RetentionStore is the message store retention runs against.
+type RetentionStore interface {
+	// ListAll returns every message, read or not.
+	ListAll() ([]*Message, error)
+
+	// DeleteMessages removes messages permanently.
+	DeleteMessages(ids []string) error
+}
+
+// RetentionReport is the outcome of one retention run.
+type RetentionReport struct {
+	Scanned  int `json:"scanned"`
+	Archived int `json:"archived"`
+	Deleted  int `json:"deleted"` // without archiving
+	Kept     int `json:"kept"`
+}
+
+// ApplyRetention applies cfg's rules to every message in store, archiving
+// to archiveDir first where the rule says so. With dryRun, it only reports
+// what would happen.
+func ApplyRetention(store RetentionStore, cfg *RetentionConfig, archiveDir string, now time.Time, dryRun bool) (*RetentionReport, error) {
+	messages, err := store.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("listing messages: %w", err)
+	}
+
+	report := &RetentionReport{Scanned: len(messages)}
+	var toArchive []*Message
+	var toRemove []string
+	for _, msg := range messages {
+		rule := cfg.match(msg, now)
+		if rule == nil {
+			report.Kept++
+			continue
+		}
+		if rule.action() == RetentionArchive {
+			toArchive = append(toArchive, msg)
+			report.Archived++
+		} else {
+			report.Deleted++
+		}
+		toRemove = append(toRemove, msg.ID)
+	}
+	if dryRun || len(toRemove) == 0 {
+		return report, nil
+	}
+
+	// Archive before deleting, so a failed write loses nothing
+	if err := AppendToArchive(archiveDir, toArchive); err != nil {
+		return nil, fmt.Errorf("archiving messages: %w", err)
+	}
+	if err := store.DeleteMessages(toRemove); err != nil {
+		return nil, fmt.Errorf("deleting messages: %w", err)
+	}
+	return report, nil
+}
+
+// match returns the first rule msg matches, or nil. Pinned messages match
+// nothing.
+func (c *RetentionConfig) match(msg *Message, now time.Time) *RetentionRule {
+	if msg.Pinned {
+		return nil
+	}
+	for i := range c.Rules {
+		if c.Rules[i].matches(msg, now) {
+			return &c.Rules[i]
+		}
+	}
+	return nil
+}
+
+// archiveFile returns the monthly archive file for a message time.
+func archiveFile(dir string, t time.Time) string {
+	return filepath.Join(dir, t.UTC().Format("2006-01")+".jsonl.gz")
+}
+
+// AppendToArchive adds messages to the monthly archive files by message
+// time. Each call appends a gzip member, which readers see as one stream.
+func AppendToArchive(dir string, messages []*Message) error {
+	byFile := make(map[string][]*Message)
+	for _, msg := range messages {
+		path := archiveFile(dir, msg.Timestamp)
+		byFile[path] = append(byFile[path], msg)
+	}
+	if len(byFile) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for path, msgs := range byFile {
+		if err := appendArchiveMember(path, msgs); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+func appendArchiveMember(path string, messages []*Message) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) //nolint:gosec // G302: archive is non-sensitive operational data
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ArchiveQuery selects archived messages. Empty fields match everything.
+type ArchiveQuery struct {
+	// Text matches the subject, body, sender, or recipient, ignoring case.
+	Text string
+
+	// From and To match the sender and recipient addresses exactly.
+	From string
+	To   string
+
+	// Since and Until bound the message time.
+	Since time.Time
+	Until time.Time
+}
+
+func (q *ArchiveQuery) matches(msg *Message) bool {
+	if q.From != "" && msg.From != q.From {
+		return false
+	}
+	if q.To != "" && msg.To != q.To {
+		return false
+	}
+	if !q.Since.IsZero() && msg.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !msg.Timestamp.Before(q.Until) {
+		return false
+	}
+	if q.Text == "" {
+		return true
+	}
+	text := strings.ToLower(q.Text)
+	for _, field := range []string{msg.Subject, msg.Body, msg.From, msg.To} {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchArchive returns archived messages matching q, oldest first. Months
+// outside q's time bounds are not read.
+func SearchArchive(dir string, q ArchiveQuery) ([]*Message, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var found []*Message
+	for _, path := range files {
+		month, err := time.Parse("2006-01", strings.TrimSuffix(filepath.Base(path), ".jsonl.gz"))
+		if err != nil {
+			continue
+		}
+		if !q.Since.IsZero() && month.AddDate(0, 1, 0).Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && !month.Before(q.Until) {
+			continue
+		}
+		msgs, err := readArchiveFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		for _, msg := range msgs {
+			if q.matches(msg) {
+				found = append(found, msg)
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Timestamp.Before(found[j].Timestamp) })
+	return found, nil
+}
+
+func readArchiveFile(path string) ([]*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var messages []*Message
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue // Skip malformed lines
+		}
+		messages = append(messages, &msg)
+	}
+	return messages, scanner.Err()
+}
+
+// BeadsRetentionStore is the RetentionStore for a town's beads database.
+type BeadsRetentionStore struct {
+	workDir  string
+	beadsDir string
+}
+
+// NewBeadsRetentionStore returns the retention store for the beads
+// database at beadsDir.
+func NewBeadsRetentionStore(workDir, beadsDir string) *BeadsRetentionStore {
+	return &BeadsRetentionStore{workDir: workDir, beadsDir: beadsDir}
+}
+
+// ListAll returns every message in the database, open or closed.
+func (s *BeadsRetentionStore) ListAll() ([]*Message, error) {
+	stdout, err := runBdCommand([]string{"list", "--type", "message", "--status=all", "--json"}, s.workDir, s.beadsDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(stdout) == 0 || string(stdout) == "null" {
+		return nil, nil
+	}
+	var beadsMsgs []BeadsMessage
+	if err := json.Unmarshal(stdout, &beadsMsgs); err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0, len(beadsMsgs))
+	for i := range beadsMsgs {
+		msg := beadsMsgs[i].ToMessage()
+		msg.Pinned = beadsMsgs[i].Pinned
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// maxDeleteBatch caps the IDs passed to one bd delete.
+const maxDeleteBatch = 100
+
+// DeleteMessages deletes messages from the database.
+func (s *BeadsRetentionStore) DeleteMessages(ids []string) error {
+	for start := 0; start < len(ids); start += maxDeleteBatch {
+		end := min(start+maxDeleteBatch, len(ids))
+		args := append([]string{"delete", "--force"}, ids[start:end]...)
+		if _, err := runBdCommand(args, s.workDir, s.beadsDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeRetentionStore is an in-memory RetentionStore.
+type fakeRetentionStore struct {
+	messages []*Message
+	deleted  []string
+}
+
+func (s *fakeRetentionStore) ListAll() ([]*Message, error) { return s.messages, nil }
+
+func (s *fakeRetentionStore) DeleteMessages(ids []string) error {
+	s.deleted = append(s.deleted, ids...)
+	return nil
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for in, want := range tests {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "d", "-3d", "xd", "0h"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("parseAge(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRetentionConfigValidate(t *testing.T) {
+	valid := &RetentionConfig{Interval: "6h", Rules: []RetentionRule{
+		{OlderThan: "7d", Types: []string{"notification"}, Status: RetentionRead, Action: RetentionDelete},
+		{OlderThan: "90d"},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for name, cfg := range map[string]*RetentionConfig{
+		"interval": {Interval: "soon"},
+		"age":      {Rules: []RetentionRule{{}}},
+		"type":     {Rules: []RetentionRule{{OlderThan: "1d", Types: []string{"memo"}}}},
+		"status":   {Rules: []RetentionRule{{OlderThan: "1d", Status: "seen"}}},
+		"action":   {Rules: []RetentionRule{{OlderThan: "1d", Action: "shred"}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: invalid config accepted", name)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	store := &fakeRetentionStore{messages: []*Message{
+		{ID: "hq-1", Subject: "old read note", Type: TypeNotification, Read: true, Timestamp: now.Add(-10 * day)},
+		{ID: "hq-2", Subject: "old read task", Type: TypeTask, Read: true, Timestamp: now.Add(-40 * day)},
+		{ID: "hq-3", Subject: "old unread task", Type: TypeTask, Timestamp: now.Add(-40 * day)},
+		{ID: "hq-4", Subject: "ancient pinned", Type: TypeTask, Pinned: true, Timestamp: now.Add(-400 * day)},
+		{ID: "hq-5", Subject: "recent", Type: TypeNotification, Read: true, Timestamp: now.Add(-day)},
+	}}
+	cfg := &RetentionConfig{Rules: []RetentionRule{
+		{OlderThan: "7d", Types: []string{"notification"}, Status: RetentionRead, Action: RetentionDelete},
+		{OlderThan: "30d", Status: RetentionRead},
+	}}
+	dir := t.TempDir()
+
+	report, err := ApplyRetention(store, cfg, dir, now, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Archived != 1 || report.Deleted != 1 || report.Kept != 3 {
+		t.Errorf("dry-run report = %+v", report)
+	}
+	if len(store.deleted) != 0 {
+		t.Fatalf("dry run deleted %v", store.deleted)
+	}
+
+	if _, err := ApplyRetention(store, cfg, dir, now, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.deleted) != 2 || store.deleted[0] != "hq-1" || store.deleted[1] != "hq-2" {
+		t.Errorf("deleted = %v, want [hq-1 hq-2]", store.deleted)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-02.jsonl.gz")); err != nil {
+		t.Errorf("monthly archive not written: %v", err)
+	}
+
+	found, err := SearchArchive(dir, ArchiveQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "hq-2" {
+		t.Errorf("archive holds %v, want only hq-2 (hq-1 was deleted outright)", found)
+	}
+}
+
+func TestSearchArchive(t *testing.T) {
+	dir := t.TempDir()
+	jan := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 9, 0, 0, 0, time.UTC)
+	// Two appends to the same month must both be readable
+	if err := AppendToArchive(dir, []*Message{
+		{ID: "hq-a", From: "mayor/", To: "gastown/witness", Subject: "Deploy window", Timestamp: jan},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendToArchive(dir, []*Message{
+		{ID: "hq-b", From: "gastown/witness", To: "mayor/", Subject: "status", Body: "deploy done", Timestamp: jan.Add(time.Hour)},
+		{ID: "hq-c", From: "mayor/", To: "gastown/refinery", Subject: "merge queue", Timestamp: feb},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(msgs []*Message) []string {
+		var out []string
+		for _, m := range msgs {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		q    ArchiveQuery
+		want []string
+	}{
+		{"all", ArchiveQuery{}, []string{"hq-a", "hq-b", "hq-c"}},
+		{"text matches subject and body", ArchiveQuery{Text: "DEPLOY"}, []string{"hq-a", "hq-b"}},
+		{"from", ArchiveQuery{From: "mayor/"}, []string{"hq-a", "hq-c"}},
+		{"to", ArchiveQuery{To: "mayor/"}, []string{"hq-b"}},
+		{"month", ArchiveQuery{Since: feb.AddDate(0, 0, -2), Until: feb.AddDate(0, 1, -2)}, []string{"hq-c"}},
+	}
+	for _, tt := range tests {
+		found, err := SearchArchive(dir, tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(found); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/config"
@@ -31,6 +32,10 @@ type Router struct {
 	workDir  string // fallback directory to run bd commands in
 	townRoot string // town root directory (e.g., ~/gt)
 	tmux     *tmux.Tmux
+
+	// Mail transport, opened on first send (see transport.go)
+	transportOnce sync.Once
+	transportConn Transport
 }
 
 // NewRouter creates a new mail router.
@@ -56,6 +61,12 @@ func NewRouterWithTownRoot(workDir, townRoot string) *Router {
 	}
 }
 
+// AttachFile stores the file at path in the town's attachment store and
+// returns the attachment to add to a message.
+func (r *Router) AttachFile(path string) (Attachment, error) {
+	return AttachFile(r.resolveBeadsDir(""), path)
+}
+
 // isListAddress returns true if the address uses list:name syntax.
 func isListAddress(address string) bool {
 	return strings.HasPrefix(address, "list:")
@@ -502,6 +513,17 @@ func (r *Router) shouldBeWisp(msg *Message) bool {
 // - Queues (queue:name) - stores single message for worker claiming
 // - Announces (announce:name) - bulletin board, no claiming, retention-limited
 func (r *Router) Send(msg *Message) error {
+	// Store an oversized body as an attachment before any fan-out, so every
+	// copy shares one stored body. Work on a copy: callers reuse msg.
+	if len(msg.Body) > MaxInlineBody {
+		spilled := *msg
+		spilled.Attachments = append([]Attachment(nil), msg.Attachments...)
+		if err := spillBody(r.resolveBeadsDir(msg.To), &spilled); err != nil {
+			return fmt.Errorf("storing message body: %w", err)
+		}
+		msg = &spilled
+	}
+
 	// Check for mailing list address
 	if isListAddress(msg.To) {
 		return r.sendToList(msg)
@@ -527,6 +549,11 @@ func (r *Router) Send(msg *Message) error {
 		return r.sendToGroup(msg)
 	}
 
+	// Check for all-agents or a wildcard address - resolve and fan-out
+	if IsBroadcastAddress(msg.To) {
+		return r.sendToPattern(msg)
+	}
+
 	// Single recipient - send directly
 	return r.sendToSingle(msg)
 }
@@ -547,11 +574,13 @@ func (r *Router) sendToGroup(msg *Message) error {
 		return fmt.Errorf("no recipients found for group: %s", msg.To)
 	}
 
-	// Fan-out: send a copy to each recipient
+	// Fan-out: send a copy to each recipient, all under one broadcast ID
+	broadcast := *msg
+	broadcast.MarkBroadcast(msg.To)
 	var errs []string
 	for _, recipient := range recipients {
 		// Create a copy of the message for this recipient
-		msgCopy := *msg
+		msgCopy := broadcast
 		msgCopy.To = recipient
 
 		if err := r.sendToSingle(&msgCopy); err != nil {
@@ -569,7 +598,7 @@ func (r *Router) sendToGroup(msg *Message) error {
 // sendToSingle sends a message to a single recipient.
 func (r *Router) sendToSingle(msg *Message) error {
 	// Convert addresses to beads identities
-	toIdentity := addressToIdentity(msg.To)
+	toIdentity := addressToIdentity(r.resolveRigAlias(msg.To))
 
 	// Build labels for from/thread/reply-to/cc
 	var labels []string
@@ -585,9 +614,11 @@ func (r *Router) sendToSingle(msg *Message) error {
 		ccIdentity := addressToIdentity(cc)
 		labels = append(labels, "cc:"+ccIdentity)
 	}
+	labels = append(labels, attachmentLabels(msg)...)
+	labels = append(labels, broadcastLabels(msg)...)
 
 	// Build command: bd create <subject> --type=message --assignee=<recipient> -d <body>
-	args := []string{"create", msg.Subject,
+	args := []string{"create", msg.Subject, "--json",
 		"--type", "message",
 		"--assignee", toIdentity,
 		"-d", msg.Body,
@@ -611,11 +642,28 @@ func (r *Router) sendToSingle(msg *Message) error {
 	}
 
 	beadsDir := r.resolveBeadsDir(msg.To)
-	_, err := runBdCommand(args, filepath.Dir(beadsDir), beadsDir)
+	out, err := runBdCommand(args, filepath.Dir(beadsDir), beadsDir)
 	if err != nil {
 		return fmt.Errorf("sending message: %w", err)
 	}
 
+	// Push the stored message to watchers, under its bead ID so they can
+	// tell it from the copy they will also find in the store
+	if r.transport() != nil {
+		stored := *msg
+		var created struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(out, &created) == nil && created.ID != "" {
+			stored.ID = created.ID
+		}
+		recipients := []string{toIdentity}
+		for _, cc := range msg.CC {
+			recipients = append(recipients, addressToIdentity(cc))
+		}
+		r.publish(&stored, recipients...)
+	}
+
 	// Notify recipient if they have an active session (best-effort notification)
 	// Skip notification for self-mail (handoffs to future-self don't need present-self notified)
 	if !isSelfMail(msg.From, msg.To) {
@@ -635,12 +683,14 @@ func (r *Router) sendToList(msg *Message) error {
 		return err
 	}
 
-	// Send to each recipient
+	// Send to each recipient, all under one broadcast ID
+	broadcast := *msg
+	broadcast.MarkBroadcast(msg.To)
 	var lastErr error
 	successCount := 0
 	for _, recipient := range recipients {
 		// Create a copy of the message for this recipient
-		copy := *msg
+		copy := broadcast
 		copy.To = recipient
 
 		if err := r.Send(&copy); err != nil {
@@ -695,6 +745,7 @@ func (r *Router) sendToQueue(msg *Message) error {
 		ccIdentity := addressToIdentity(cc)
 		labels = append(labels, "cc:"+ccIdentity)
 	}
+	labels = append(labels, attachmentLabels(msg)...)
 
 	// Build command: bd create <subject> --type=message --assignee=queue:<name> -d <body>
 	// Use queue:<name> as assignee so inbox queries can filter by queue
@@ -766,6 +817,7 @@ func (r *Router) sendToAnnounce(msg *Message) error {
 		ccIdentity := addressToIdentity(cc)
 		labels = append(labels, "cc:"+ccIdentity)
 	}
+	labels = append(labels, attachmentLabels(msg)...)
 
 	// Build command: bd create <subject> --type=message --assignee=announce:<name> -d <body>
 	// Use announce:<name> as assignee so queries can filter by channel
@@ -839,6 +891,7 @@ func (r *Router) sendToChannel(msg *Message) error {
 		ccIdentity := addressToIdentity(cc)
 		labels = append(labels, "cc:"+ccIdentity)
 	}
+	labels = append(labels, attachmentLabels(msg)...)
 
 	// Build command: bd create <subject> --type=message --assignee=channel:<name> -d <body>
 	// Use channel:<name> as assignee so queries can filter by channel
@@ -958,7 +1011,21 @@ func isSelfMail(from, to string) bool {
 func (r *Router) GetMailbox(address string) (*Mailbox, error) {
 	beadsDir := r.resolveBeadsDir(address)
 	workDir := filepath.Dir(beadsDir) // Parent of .beads
-	return NewMailboxFromAddress(address, workDir), nil
+	return NewMailboxFromAddress(r.resolveRigAlias(address), workDir), nil
+}
+
+// resolveRigAlias rewrites a rig-level address that names a renamed rig
+// ("oldrig/witness") to the rig's current name. Other addresses are returned
+// as-is.
+func (r *Router) resolveRigAlias(address string) string {
+	rigName, rest, ok := strings.Cut(address, "/")
+	if !ok || r.townRoot == "" || rigName == "mayor" || rigName == "deacon" {
+		return address
+	}
+	if current := config.ResolveRigName(r.townRoot, rigName); current != rigName {
+		return current + "/" + rest
+	}
+	return address
 }
 
 // notifyRecipient sends a notification to a recipient's tmux session.
@@ -0,0 +1,303 @@
+package mail
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TemplateField is one input of a message template. Fields are supplied as
+// flags of 'gt mail send' (--<name>).
+type TemplateField struct {
+	Name        string
+	Description string
+	Required    bool
+
+	// Choices, if set, are the only accepted values.
+	Choices []string
+
+	// Bool fields take "true"/"false"; List fields take comma-separated values.
+	Bool bool
+	List bool
+}
+
+// Template builds a structured message (subject and JSON body) from field
+// values, so standard messages are formatted the same way by everyone.
+type Template struct {
+	Name        string
+	Description string
+	Fields      []TemplateField
+
+	// DefaultTo is the address used when the sender gives none.
+	DefaultTo string
+
+	// Type is the message type of rendered messages.
+	Type MessageType
+
+	// render builds the subject and body from validated values.
+	render func(from string, v templateValues) (string, any)
+
+	// priority, if set, picks the message priority from the values.
+	priority func(v templateValues) Priority
+}
+
+// templateValues holds validated field values by name.
+type templateValues map[string]string
+
+func (v templateValues) list(name string) []string {
+	if v[name] == "" {
+		return nil
+	}
+	var out []string
+	for _, item := range strings.Split(v[name], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// LifecycleTemplateBody is the body of a lifecycle request. It matches the
+// body the daemon parses from LIFECYCLE: mail to the deacon.
+type LifecycleTemplateBody struct {
+	Action  string   `json:"action"`
+	DryRun  bool     `json:"dry_run,omitempty"`
+	Notify  []string `json:"notify,omitempty"`
+	Handoff string   `json:"handoff,omitempty"`
+}
+
+// HandoffTemplateBody is the body of a handoff message.
+type HandoffTemplateBody struct {
+	Summary    string `json:"summary"`
+	Next       string `json:"next,omitempty"`
+	HookedBead string `json:"hooked_bead,omitempty"`
+}
+
+// StatusTemplateBody is the body of a status report.
+type StatusTemplateBody struct {
+	State   string `json:"state"`
+	Summary string `json:"summary"`
+	Bead    string `json:"bead,omitempty"`
+}
+
+// EscalationTemplateBody is the body of an escalation.
+type EscalationTemplateBody struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Reason   string `json:"reason,omitempty"`
+	Bead     string `json:"bead,omitempty"`
+}
+
+// AgentEscalationTemplateBody is the body of a witness's report of a
+// recurring problem with an agent. It matches the body the daemon parses
+// from ESCALATE: mail to the deacon.
+type AgentEscalationTemplateBody struct {
+	Agent  string `json:"agent"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var templates = []*Template{
+	{
+		Name:        "lifecycle",
+		Description: "Ask the daemon to restart, cycle, refresh, or shut down your session",
+		DefaultTo:   "deacon/",
+		Type:        TypeTask,
+		Fields: []TemplateField{
+			{Name: "action", Description: "Lifecycle action", Required: true, Choices: []string{"cycle", "restart", "refresh", "shutdown"}},
+			{Name: "dry-run", Description: "Verify the request without executing it", Bool: true},
+			{Name: "result-to", Description: "Identities to mail the result to (comma-separated)", List: true},
+			{Name: "handoff-file", Description: "Handoff document for a cycle (default .runtime/handoff.md)"},
+		},
+		render: func(from string, v templateValues) (string, any) {
+			return fmt.Sprintf("LIFECYCLE: %s requesting %s", from, v["action"]), LifecycleTemplateBody{
+				Action:  v["action"],
+				DryRun:  v["dry-run"] == "true",
+				Notify:  v.list("result-to"),
+				Handoff: v["handoff-file"],
+			}
+		},
+	},
+	{
+		Name:        "handoff",
+		Description: "Hand context to the next session",
+		Type:        TypeNotification,
+		Fields: []TemplateField{
+			{Name: "summary", Description: "What was done and where things stand", Required: true},
+			{Name: "next", Description: "What the next session should do first"},
+			{Name: "bead", Description: "Bead on the hook"},
+		},
+		render: func(from string, v templateValues) (string, any) {
+			return "🤝 HANDOFF: " + firstLine(v["summary"]), HandoffTemplateBody{
+				Summary:    v["summary"],
+				Next:       v["next"],
+				HookedBead: v["bead"],
+			}
+		},
+	},
+	{
+		Name:        "status",
+		Description: "Report progress to a coordinator",
+		Type:        TypeNotification,
+		Fields: []TemplateField{
+			{Name: "state", Description: "Current state", Required: true, Choices: []string{"working", "blocked", "idle", "done"}},
+			{Name: "summary", Description: "What was done and where things stand", Required: true},
+			{Name: "bead", Description: "Bead the report is about"},
+		},
+		render: func(from string, v templateValues) (string, any) {
+			return fmt.Sprintf("STATUS: %s %s", from, v["state"]), StatusTemplateBody{
+				State:   v["state"],
+				Summary: v["summary"],
+				Bead:    v["bead"],
+			}
+		},
+		priority: func(v templateValues) Priority {
+			if v["state"] == "blocked" {
+				return PriorityHigh
+			}
+			return PriorityNormal
+		},
+	},
+	{
+		Name:        "escalate-agent",
+		Description: "Report a recurring problem with an agent to the daemon (witnesses)",
+		DefaultTo:   "deacon/",
+		Type:        TypeTask,
+		Fields: []TemplateField{
+			{Name: "agent", Description: "Agent the report is about (e.g. gastown/crew/max)", Required: true},
+			{Name: "kind", Description: "Problem, e.g. stuck or tests-failing; reports of one kind add up", Required: true},
+			{Name: "detail", Description: "What was seen"},
+		},
+		render: func(from string, v templateValues) (string, any) {
+			kind := strings.ToLower(strings.Join(strings.Fields(v["kind"]), "-"))
+			return fmt.Sprintf("ESCALATE: %s %s", kind, v["agent"]), AgentEscalationTemplateBody{
+				Agent:  v["agent"],
+				Kind:   kind,
+				Detail: v["detail"],
+			}
+		},
+	},
+	{
+		Name:        "escalation",
+		Description: "Raise a problem you can't resolve yourself",
+		DefaultTo:   "mayor/",
+		Type:        TypeTask,
+		Fields: []TemplateField{
+			{Name: "severity", Description: "How bad it is", Required: true, Choices: []string{"critical", "high", "medium", "low"}},
+			{Name: "summary", Description: "What was done and where things stand", Required: true},
+			{Name: "reason", Description: "Why it needs attention"},
+			{Name: "bead", Description: "Related bead"},
+		},
+		render: func(from string, v templateValues) (string, any) {
+			return fmt.Sprintf("ESCALATION: [%s] %s", strings.ToUpper(v["severity"]), firstLine(v["summary"])), EscalationTemplateBody{
+				Severity: v["severity"],
+				Summary:  v["summary"],
+				Reason:   v["reason"],
+				Bead:     v["bead"],
+			}
+		},
+		priority: func(v templateValues) Priority {
+			switch v["severity"] {
+			case "critical":
+				return PriorityUrgent
+			case "high":
+				return PriorityHigh
+			case "low":
+				return PriorityLow
+			}
+			return PriorityNormal
+		},
+	},
+}
+
+// Templates returns the message templates sorted by name.
+func Templates() []*Template {
+	out := slices.Clone(templates)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LookupTemplate returns the named template.
+func LookupTemplate(name string) (*Template, error) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	names := make([]string, 0, len(templates))
+	for _, t := range Templates() {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Render validates values against the template's fields and builds the
+// message from sender from: subject, JSON body, type, and priority.
+func (t *Template) Render(from string, values map[string]string) (*Message, error) {
+	v := make(templateValues, len(values))
+	for name, value := range values {
+		field := t.field(name)
+		if field == nil {
+			return nil, fmt.Errorf("template %s has no field %q", t.Name, name)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case field.Bool:
+			switch strings.ToLower(value) {
+			case "true", "yes", "1", "":
+				value = "true"
+			case "false", "no", "0":
+				value = "false"
+			default:
+				return nil, fmt.Errorf("%s: %q is not true or false", name, value)
+			}
+		case len(field.Choices) > 0:
+			value = strings.ToLower(value)
+			if !slices.Contains(field.Choices, value) {
+				return nil, fmt.Errorf("%s: %q is not one of %s", name, value, strings.Join(field.Choices, ", "))
+			}
+		}
+		v[name] = value
+	}
+	for _, field := range t.Fields {
+		if field.Required && v[field.Name] == "" {
+			return nil, fmt.Errorf("template %s requires --%s", t.Name, field.Name)
+		}
+	}
+
+	subject, body := t.render(from, v)
+	data, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s body: %w", t.Name, err)
+	}
+	msg := &Message{
+		From:     from,
+		Subject:  subject,
+		Body:     string(data),
+		Type:     t.Type,
+		Priority: PriorityNormal,
+	}
+	if t.priority != nil {
+		msg.Priority = t.priority(v)
+	}
+	return msg, nil
+}
+
+func (t *Template) field(name string) *TemplateField {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+// firstLine returns the first line of s, for use in a subject.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
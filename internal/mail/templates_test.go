@@ -0,0 +1,111 @@
+package mail
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplateRenderLifecycle(t *testing.T) {
+	tmpl, err := LookupTemplate("lifecycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := tmpl.Render("gastown/crew/max", map[string]string{
+		"action":    "Cycle",
+		"dry-run":   "true",
+		"result-to": "mayor, gastown-witness",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "LIFECYCLE: gastown/crew/max requesting cycle" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	if msg.Type != TypeTask || tmpl.DefaultTo != "deacon/" {
+		t.Errorf("type = %s, default to = %s", msg.Type, tmpl.DefaultTo)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, msg.Body)
+	}
+	if body["action"] != "cycle" || body["dry_run"] != true {
+		t.Errorf("body = %v", body)
+	}
+	if notify, _ := body["notify"].([]any); len(notify) != 2 || notify[1] != "gastown-witness" {
+		t.Errorf("notify = %v", body["notify"])
+	}
+	if _, ok := body["handoff"]; ok {
+		t.Errorf("empty handoff should be omitted: %v", body)
+	}
+}
+
+func TestTemplateRenderValidation(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		values   map[string]string
+		errSub   string
+	}{
+		{"lifecycle", map[string]string{}, "requires --action"},
+		{"lifecycle", map[string]string{"action": "explode"}, "not one of"},
+		{"lifecycle", map[string]string{"action": "cycle", "dry-run": "maybe"}, "not true or false"},
+		{"lifecycle", map[string]string{"action": "cycle", "severity": "high"}, "no field"},
+		{"status", map[string]string{"state": "working"}, "requires --summary"},
+		{"escalation", map[string]string{"summary": "x"}, "requires --severity"},
+	} {
+		tmpl, err := LookupTemplate(tc.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tmpl.Render("mayor/", tc.values)
+		if err == nil || !strings.Contains(err.Error(), tc.errSub) {
+			t.Errorf("%s %v: err = %v, want %q", tc.template, tc.values, err, tc.errSub)
+		}
+	}
+
+	if _, err := LookupTemplate("memo"); err == nil || !strings.Contains(err.Error(), "escalate-agent, escalation, handoff, lifecycle, status") {
+		t.Errorf("LookupTemplate(memo) = %v", err)
+	}
+}
+
+func TestTemplateRenderPriorityAndSubjects(t *testing.T) {
+	esc, _ := LookupTemplate("escalation")
+	msg, err := esc.Render("gastown/witness", map[string]string{
+		"severity": "critical",
+		"summary":  "Refinery wedged\nmerge queue stuck for 2h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "ESCALATION: [CRITICAL] Refinery wedged" || msg.Priority != PriorityUrgent {
+		t.Errorf("escalation = %q, %s", msg.Subject, msg.Priority)
+	}
+
+	status, _ := LookupTemplate("status")
+	msg, err = status.Render("gastown/crew/max", map[string]string{"state": "blocked", "summary": "waiting on review"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "STATUS: gastown/crew/max blocked" || msg.Priority != PriorityHigh {
+		t.Errorf("status = %q, %s", msg.Subject, msg.Priority)
+	}
+
+	handoff, _ := LookupTemplate("handoff")
+	msg, err = handoff.Render("gastown/crew/max", map[string]string{"summary": "Auth refactor half done", "bead": "gt-abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "🤝 HANDOFF: Auth refactor half done" || !strings.Contains(msg.Body, `"hooked_bead": "gt-abc"`) {
+		t.Errorf("handoff = %q\n%s", msg.Subject, msg.Body)
+	}
+
+	report, _ := LookupTemplate("escalate-agent")
+	msg, err = report.Render("gastown/witness", map[string]string{"agent": "gastown/crew/max", "kind": "Tests Failing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "ESCALATE: tests-failing gastown/crew/max" || !strings.Contains(msg.Body, `"kind": "tests-failing"`) {
+		t.Errorf("escalate-agent = %q\n%s", msg.Subject, msg.Body)
+	}
+}
@@ -0,0 +1,197 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// Mail transports push new messages to watching agents without waiting for
+// the next poll of the beads store. The store stays the system of record:
+// a message is written there first and only then published, and watchers
+// keep polling the store (less often) alongside their subscription. A
+// message whose publish is lost, or that arrives while a watcher is
+// disconnected, is still delivered from the store, so delivery stays
+// at-least-once; watchers drop the duplicates by message ID.
+//
+// Transports register under a URL scheme, like storage drivers:
+//
+//	nats://host:4222     NATS core pub/sub (built in)
+//	redis://host:6379    Redis pub/sub (built in)
+//
+// The built-in drivers are minimal notify-only clients (plain TCP, no
+// reconnect); see natsTransport and redisTransport.
+//
+// A town picks one with "transport" in config/messaging.json or with
+// GT_MAIL_TRANSPORT. A ?prefix= query parameter namespaces the subjects
+// (default "gastown"), so towns can share a server.
+
+// EnvMailTransport overrides the mail transport a town uses.
+const EnvMailTransport = "GT_MAIL_TRANSPORT"
+
+// defaultTransportPrefix namespaces transport subjects.
+const defaultTransportPrefix = "gastown"
+
+// transportDialTimeout bounds connecting to a transport server.
+const transportDialTimeout = 3 * time.Second
+
+// ErrUnknownTransport is returned for a transport URL with no registered driver.
+var ErrUnknownTransport = errors.New("unknown mail transport")
+
+// Transport publishes messages to inboxes and delivers them to subscribers.
+// Delivery is best-effort; the beads store is the fallback.
+type Transport interface {
+	// Publish sends msg to the subscribers of inbox.
+	Publish(inbox string, msg *Message) error
+
+	// Subscribe calls fn with each message published to any of the inboxes
+	// until ctx is cancelled (returning nil) or the connection fails.
+	Subscribe(ctx context.Context, inboxes []string, fn func(*Message)) error
+
+	// Close releases the transport's connection.
+	Close() error
+}
+
+// TransportDriver opens a transport from a parsed URL.
+type TransportDriver func(u *url.URL) (Transport, error)
+
+var (
+	transportsMu sync.RWMutex
+	transports   = map[string]TransportDriver{
+		"nats":  openNATSTransport,
+		"redis": openRedisTransport,
+	}
+)
+
+// RegisterTransport makes a transport available under a URL scheme,
+// replacing any driver already registered for it.
+func RegisterTransport(scheme string, driver TransportDriver) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[scheme] = driver
+}
+
+// TransportSchemes returns the registered transport schemes, sorted.
+func TransportSchemes() []string {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+	schemes := make([]string, 0, len(transports))
+	for scheme := range transports {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// OpenTransport opens a transport from a URL.
+func OpenTransport(rawURL string) (Transport, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing mail transport URL: %w", err)
+	}
+	transportsMu.RLock()
+	driver, ok := transports[u.Scheme]
+	transportsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", ErrUnknownTransport, u.Scheme, strings.Join(TransportSchemes(), ", "))
+	}
+	return driver(u)
+}
+
+// TransportURL returns the town's configured transport URL, or "" if mail
+// goes through the store alone.
+func TransportURL(townRoot string, getenv func(string) string) string {
+	if rawURL := getenv(EnvMailTransport); rawURL != "" {
+		return rawURL
+	}
+	if townRoot == "" {
+		return ""
+	}
+	cfg, err := config.LoadMessagingConfig(config.MessagingConfigPath(townRoot))
+	if err != nil {
+		return ""
+	}
+	return cfg.Transport
+}
+
+// TransportForTown opens the town's transport. Returns nil, nil when none
+// is configured.
+func TransportForTown(townRoot string, getenv func(string) string) (Transport, error) {
+	rawURL := TransportURL(townRoot, getenv)
+	if rawURL == "" {
+		return nil, nil
+	}
+	return OpenTransport(rawURL)
+}
+
+// transportPrefix returns the subject prefix from a transport URL.
+func transportPrefix(u *url.URL) string {
+	if prefix := u.Query().Get("prefix"); prefix != "" {
+		return prefix
+	}
+	return defaultTransportPrefix
+}
+
+// inboxKey is the transport name of a recipient's inbox: the beads identity
+// without a trailing slash, so "mayor/" and "mayor" share an inbox.
+func inboxKey(identity string) string {
+	return strings.TrimSuffix(identity, "/")
+}
+
+// encodeTransportMessage and decodeTransportMessage are the wire format
+// shared by the built-in transports.
+func encodeTransportMessage(msg *Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func decodeTransportMessage(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.ID == "" {
+		return nil, fmt.Errorf("transport message without an ID")
+	}
+	return &msg, nil
+}
+
+// transport returns the router's transport, opening it on first use. A
+// transport that fails to open is not retried for the router's lifetime.
+func (r *Router) transport() Transport {
+	r.transportOnce.Do(func() {
+		t, err := TransportForTown(r.townRoot, os.Getenv)
+		if err == nil {
+			r.transportConn = t
+		}
+	})
+	return r.transportConn
+}
+
+// publish pushes a stored message to its recipients' inboxes on the town's
+// transport. Best-effort: watchers fall back to polling the store.
+func (r *Router) publish(msg *Message, recipients ...string) {
+	t := r.transport()
+	if t == nil {
+		return
+	}
+	for _, identity := range recipients {
+		_ = t.Publish(inboxKey(identity), msg)
+	}
+}
+
+// Close releases the router's transport connection, if any.
+func (r *Router) Close() error {
+	if r.transportConn != nil {
+		return r.transportConn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTransport speaks the NATS core text protocol: PUB to publish, SUB to
+// subscribe. Core NATS delivers at most once; the store poll covers the
+// rest. Subjects are "<prefix>.mail.<inbox>", with the inbox's path
+// segments as subject tokens (gastown.mail.gastown.crew.max).
+//
+// Its only role is to notify: a lost notification costs a poll interval,
+// never a message. So rather than take on the nats.go client, it speaks
+// just the handshake (user/password auth), PUB, SUB, and PING/PONG over
+// plain TCP. There is no TLS, no cluster discovery or failover, no
+// reconnect (a dropped connection ends Subscribe, and the watcher falls
+// back to polling), and no JetStream. A town that needs those should
+// register its own "nats" driver built on nats.go with RegisterTransport.
+type natsTransport struct {
+	addr   string
+	user   string
+	pass   string
+	prefix string
+
+	mu   sync.Mutex // guards pub and subs
+	pub  *natsConn  // publishing connection, dialed on first Publish
+	subs []*natsConn
+}
+
+// natsConn is one connection to a NATS server.
+type natsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func openNATSTransport(u *url.URL) (Transport, error) {
+	host := u.Host
+	if host == "" {
+		return nil, fmt.Errorf("nats transport URL needs a host: %s", u.Redacted())
+	}
+	if u.Port() == "" {
+		host = net.JoinHostPort(host, "4222")
+	}
+	t := &natsTransport{addr: host, prefix: transportPrefix(u)}
+	if u.User != nil {
+		t.user = u.User.Username()
+		t.pass, _ = u.User.Password()
+	}
+	return t, nil
+}
+
+// subject returns the NATS subject of an inbox.
+func (t *natsTransport) subject(inbox string) string {
+	token := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/':
+			return '.'
+		case r == '.' || r == '*' || r == '>' || r <= ' ':
+			return '_'
+		}
+		return r
+	}, strings.Trim(inbox, "/"))
+	return t.prefix + ".mail." + token
+}
+
+// dial connects and handshakes: read INFO, send CONNECT, confirm with a
+// PING/PONG round trip so auth errors surface here.
+func (t *natsTransport) dial() (*natsConn, error) {
+	conn, err := net.DialTimeout("tcp", t.addr, transportDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &natsConn{conn: conn, r: bufio.NewReader(conn)}
+	_ = conn.SetDeadline(time.Now().Add(transportDialTimeout))
+	line, err := c.readLine()
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("nats handshake: expected INFO, got %q: %v", line, err)
+	}
+	opts := map[string]any{"verbose": false, "pedantic": false, "name": "gastown-mail"}
+	if t.user != "" && t.pass == "" {
+		opts["auth_token"] = t.user
+	} else if t.user != "" {
+		opts["user"], opts["pass"] = t.user, t.pass
+	}
+	data, _ := json.Marshal(opts)
+	if err := c.write("CONNECT " + string(data) + "\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := c.flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *natsConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (c *natsConn) write(s string) error {
+	_, err := io.WriteString(c.conn, s)
+	return err
+}
+
+// flush sends a PING and waits for the PONG, so everything written before
+// it has been processed by the server.
+func (c *natsConn) flush() error {
+	if err := c.write("PING\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Publish sends msg to the inbox's subject, redialing once if the
+// connection has dropped.
+func (t *natsTransport) Publish(inbox string, msg *Message) error {
+	data, err := encodeTransportMessage(msg)
+	if err != nil {
+		return err
+	}
+	frame := fmt.Sprintf("PUB %s %d\r\n%s\r\n", t.subject(inbox), len(data), data)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if t.pub == nil {
+			if t.pub, err = t.dial(); err != nil {
+				return err
+			}
+		}
+		_ = t.pub.conn.SetDeadline(time.Now().Add(transportDialTimeout))
+		if err = t.pub.write(frame); err == nil {
+			err = t.pub.flush()
+		}
+		if err == nil {
+			_ = t.pub.conn.SetDeadline(time.Time{})
+			return nil
+		}
+		t.pub.conn.Close()
+		t.pub = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// Subscribe subscribes to the inboxes' subjects on a connection of its own.
+func (t *natsTransport) Subscribe(ctx context.Context, inboxes []string, fn func(*Message)) error {
+	c, err := t.dial()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.subs = append(t.subs, c)
+	t.mu.Unlock()
+	defer c.conn.Close()
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	for i, inbox := range inboxes {
+		if err := c.write(fmt.Sprintf("SUB %s %d\r\n", t.subject(inbox), i+1)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		switch {
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("nats: bad MSG line %q", line)
+			}
+			payload := make([]byte, size+2) // payload + CRLF
+			if _, err := io.ReadFull(c.r, payload); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if msg, err := decodeTransportMessage(payload[:size]); err == nil {
+				fn(msg)
+			}
+		}
+	}
+}
+
+// Close closes the publishing and subscribing connections.
+func (t *natsTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pub != nil {
+		t.pub.conn.Close()
+		t.pub = nil
+	}
+	for _, c := range t.subs {
+		c.conn.Close()
+	}
+	t.subs = nil
+	return nil
+}
@@ -0,0 +1,234 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTransport uses Redis pub/sub: PUBLISH to publish, SUBSCRIBE to
+// subscribe, over a minimal RESP client. Pub/sub is fire-and-forget; the
+// store poll covers messages published while nobody listened. Channels are
+// "<prefix>:mail:<inbox>".
+//
+// Its only role is to notify: a lost notification costs a poll interval,
+// never a message. So rather than take on a full Redis client, it speaks
+// just AUTH, PUBLISH, and SUBSCRIBE in RESP over plain TCP. There is no
+// TLS, no Sentinel or Cluster support, no reconnect (a dropped connection
+// ends Subscribe, and the watcher falls back to polling), and no streams.
+// A town that needs those should register its own "redis" driver built on
+// a standard client with RegisterTransport.
+type redisTransport struct {
+	addr   string
+	user   string
+	pass   string
+	prefix string
+
+	mu   sync.Mutex // guards pub and subs
+	pub  *redisConn // publishing connection, dialed on first Publish
+	subs []*redisConn
+}
+
+// redisConn is one connection to a Redis server.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func openRedisTransport(u *url.URL) (Transport, error) {
+	host := u.Host
+	if host == "" {
+		return nil, fmt.Errorf("redis transport URL needs a host: %s", u.Redacted())
+	}
+	if u.Port() == "" {
+		host = net.JoinHostPort(host, "6379")
+	}
+	t := &redisTransport{addr: host, prefix: transportPrefix(u)}
+	if u.User != nil {
+		t.user = u.User.Username()
+		t.pass, _ = u.User.Password()
+	}
+	return t, nil
+}
+
+// channel returns the Redis channel of an inbox.
+func (t *redisTransport) channel(inbox string) string {
+	return t.prefix + ":mail:" + strings.Trim(inbox, "/")
+}
+
+// dial connects and authenticates when the URL carries credentials.
+func (t *redisTransport) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", t.addr, transportDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if t.pass != "" {
+		args := []string{"AUTH", t.pass}
+		if t.user != "" {
+			args = []string{"AUTH", t.user, t.pass}
+		}
+		_ = conn.SetDeadline(time.Now().Add(transportDialTimeout))
+		if _, err := c.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return c, nil
+}
+
+// send writes a command as a RESP array of bulk strings.
+func (c *redisConn) send(args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(c.conn, b.String())
+	return err
+}
+
+// do sends a command and reads its reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads one RESP reply: a string, int64, []any, or nil. Error replies
+// are returned as errors.
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// Publish sends msg to the inbox's channel, redialing once if the
+// connection has dropped.
+func (t *redisTransport) Publish(inbox string, msg *Message) error {
+	data, err := encodeTransportMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if t.pub == nil {
+			if t.pub, err = t.dial(); err != nil {
+				return err
+			}
+		}
+		_ = t.pub.conn.SetDeadline(time.Now().Add(transportDialTimeout))
+		if _, err = t.pub.do("PUBLISH", t.channel(inbox), string(data)); err == nil {
+			_ = t.pub.conn.SetDeadline(time.Time{})
+			return nil
+		}
+		t.pub.conn.Close()
+		t.pub = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// Subscribe subscribes to the inboxes' channels on a connection of its own.
+func (t *redisTransport) Subscribe(ctx context.Context, inboxes []string, fn func(*Message)) error {
+	c, err := t.dial()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.subs = append(t.subs, c)
+	t.mu.Unlock()
+	defer c.conn.Close()
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	args := []string{"SUBSCRIBE"}
+	for _, inbox := range inboxes {
+		args = append(args, t.channel(inbox))
+	}
+	if err := c.send(args...); err != nil {
+		return err
+	}
+
+	for {
+		reply, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// ["message", channel, payload]; subscribe confirmations are skipped
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 || items[0] != "message" {
+			continue
+		}
+		payload, _ := items[2].(string)
+		if msg, err := decodeTransportMessage([]byte(payload)); err == nil {
+			fn(msg)
+		}
+	}
+}
+
+// Close closes the publishing and subscribing connections.
+func (t *redisTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pub != nil {
+		t.pub.conn.Close()
+		t.pub = nil
+	}
+	for _, c := range t.subs {
+		c.conn.Close()
+	}
+	t.subs = nil
+	return nil
+}
@@ -0,0 +1,326 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestOpenTransport(t *testing.T) {
+	if _, err := OpenTransport("kafka://localhost"); !errors.Is(err, ErrUnknownTransport) {
+		t.Errorf("kafka: %v, want ErrUnknownTransport", err)
+	}
+	if _, err := OpenTransport("nats://"); err == nil {
+		t.Error("nats URL without a host should fail")
+	}
+
+	tr, err := OpenTransport("nats://token@nats.internal?prefix=town2")
+	if err != nil {
+		t.Fatalf("nats: %v", err)
+	}
+	n := tr.(*natsTransport)
+	if n.addr != "nats.internal:4222" || n.user != "token" || n.prefix != "town2" {
+		t.Errorf("nats transport = %+v", n)
+	}
+	if got := n.subject("gastown/crew/max"); got != "town2.mail.gastown.crew.max" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := n.subject("mayor/"); got != "town2.mail.mayor" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := n.subject("gastown/polecats/v1.2 *"); got != "town2.mail.gastown.polecats.v1_2__" {
+		t.Errorf("wildcards and dots should not leak into the subject: %q", got)
+	}
+
+	tr, err = OpenTransport("redis://:secret@localhost")
+	if err != nil {
+		t.Fatalf("redis: %v", err)
+	}
+	r := tr.(*redisTransport)
+	if r.addr != "localhost:6379" || r.pass != "secret" {
+		t.Errorf("redis transport = %+v", r)
+	}
+	if got := r.channel("gastown/witness"); got != "gastown:mail:gastown/witness" {
+		t.Errorf("channel = %q", got)
+	}
+}
+
+func TestTransportURL(t *testing.T) {
+	townRoot := t.TempDir()
+	noEnv := func(string) string { return "" }
+
+	if got := TransportURL(townRoot, noEnv); got != "" {
+		t.Errorf("unconfigured town: %q, want store only", got)
+	}
+	if tr, err := TransportForTown(townRoot, noEnv); tr != nil || err != nil {
+		t.Errorf("TransportForTown = %v, %v; want nil, nil", tr, err)
+	}
+
+	cfg := config.NewMessagingConfig()
+	cfg.Transport = "redis://localhost:6379"
+	if err := config.SaveMessagingConfig(config.MessagingConfigPath(townRoot), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := TransportURL(townRoot, noEnv); got != "redis://localhost:6379" {
+		t.Errorf("from messaging.json: %q", got)
+	}
+
+	env := func(key string) string {
+		if key == EnvMailTransport {
+			return "nats://localhost"
+		}
+		return ""
+	}
+	if got := TransportURL(townRoot, env); got != "nats://localhost" {
+		t.Errorf("GT_MAIL_TRANSPORT should win: %q", got)
+	}
+}
+
+func TestNATSTransportRoundTrip(t *testing.T) {
+	addr := fakeNATSServer(t)
+	tr, err := OpenTransport("nats://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	testTransportRoundTrip(t, tr)
+}
+
+func TestRedisTransportRoundTrip(t *testing.T) {
+	addr := fakeRedisServer(t)
+	tr, err := OpenTransport("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	testTransportRoundTrip(t, tr)
+}
+
+// testTransportRoundTrip subscribes to one inbox and publishes to it and to
+// another, until the subscriber sees its message.
+func testTransportRoundTrip(t *testing.T, tr Transport) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan *Message, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- tr.Subscribe(ctx, []string{"gastown/crew/max"}, func(msg *Message) { got <- msg })
+	}()
+
+	// The subscription races the first publishes; keep publishing until it lands
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if err := tr.Publish("gastown/witness", &Message{ID: "hq-other", Subject: "not for max"}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		if err := tr.Publish("gastown/crew/max", &Message{ID: "hq-1", From: "mayor/", Subject: "hello"}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		select {
+		case msg := <-got:
+			if msg.ID != "hq-1" || msg.Subject != "hello" || msg.From != "mayor/" {
+				t.Fatalf("received %+v", msg)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Subscribe after cancel: %v, want nil", err)
+			}
+			return
+		case err := <-done:
+			t.Fatalf("Subscribe: %v", err)
+		case <-ctx.Done():
+			t.Fatal("message never delivered")
+		case <-tick.C:
+		}
+	}
+}
+
+// pushTransport is an in-memory Transport for Watch tests.
+type pushTransport struct {
+	inboxes chan []string
+	msgs    chan *Message
+}
+
+func (p *pushTransport) Publish(string, *Message) error { return nil }
+func (p *pushTransport) Close() error                   { return nil }
+
+func (p *pushTransport) Subscribe(ctx context.Context, inboxes []string, fn func(*Message)) error {
+	p.inboxes <- inboxes
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-p.msgs:
+			fn(msg)
+		}
+	}
+}
+
+func TestMailboxWatchTransportPush(t *testing.T) {
+	m := NewMailbox(t.TempDir())
+	m.identity = "mayor/"
+	push := &pushTransport{inboxes: make(chan []string, 1), msgs: make(chan *Message)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	errStop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		// The store is only checked once; everything else must be pushed
+		opts := WatchOptions{Interval: time.Hour, Transport: push}
+		done <- m.Watch(ctx, opts, func(msg *Message) error {
+			got = append(got, msg.ID)
+			if len(got) == 2 {
+				return errStop
+			}
+			return nil
+		})
+	}()
+
+	if inboxes := <-push.inboxes; strings.Join(inboxes, ",") != "mayor" {
+		t.Errorf("subscribed to %v, want [mayor]", inboxes)
+	}
+	push.msgs <- &Message{ID: "hq-1"}
+	push.msgs <- &Message{ID: "hq-1"} // redelivered: dropped
+	push.msgs <- &Message{ID: "hq-2"}
+
+	if err := <-done; !errors.Is(err, errStop) {
+		t.Fatalf("Watch returned %v, want errStop", err)
+	}
+	if strings.Join(got, ",") != "hq-1,hq-2" {
+		t.Errorf("streamed %v, want [hq-1 hq-2]", got)
+	}
+}
+
+// fakeNATSServer serves enough of the NATS protocol for the transport:
+// INFO, CONNECT, PING, SUB and PUB.
+func fakeNATSServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	type sub struct {
+		conn net.Conn
+		sid  string
+	}
+	var mu sync.Mutex
+	subs := map[string][]sub{}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "PING":
+				mu.Lock()
+				fmt.Fprint(conn, "PONG\r\n")
+				mu.Unlock()
+			case "SUB":
+				mu.Lock()
+				subs[fields[1]] = append(subs[fields[1]], sub{conn, fields[2]})
+				mu.Unlock()
+			case "PUB":
+				size, _ := strconv.Atoi(fields[len(fields)-1])
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				mu.Lock()
+				for _, s := range subs[fields[1]] {
+					fmt.Fprintf(s.conn, "MSG %s %s %d\r\n%s", fields[1], s.sid, size, payload)
+				}
+				mu.Unlock()
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// fakeRedisServer serves PUBLISH and SUBSCRIBE over RESP.
+func fakeRedisServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	subs := map[string][]*redisConn{}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+		for {
+			reply, err := c.read()
+			if err != nil {
+				return
+			}
+			args, _ := reply.([]any)
+			if len(args) == 0 {
+				return
+			}
+			mu.Lock()
+			switch args[0] {
+			case "SUBSCRIBE":
+				for i, ch := range args[1:] {
+					subs[ch.(string)] = append(subs[ch.(string)], c)
+					_ = c.send("subscribe", ch.(string), strconv.Itoa(i+1))
+				}
+			case "PUBLISH":
+				ch, payload := args[1].(string), args[2].(string)
+				for _, s := range subs[ch] {
+					_ = s.send("message", ch, payload)
+				}
+				fmt.Fprintf(conn, ":%d\r\n", len(subs[ch]))
+			}
+			mu.Unlock()
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
@@ -123,6 +123,14 @@ type Message struct {
 	// ClaimedAt is when the queue message was claimed.
 	// Only set for queue messages after claiming.
 	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+
+	// Attachments are files stored alongside the message (see attachments.go).
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// BroadcastID is shared by every copy of a message sent to a group of
+	// agents, and BroadcastTo is the group address (see broadcast.go).
+	BroadcastID string `json:"broadcast_id,omitempty"`
+	BroadcastTo string `json:"broadcast_to,omitempty"`
 }
 
 // NewMessage creates a new message with a generated ID and thread ID.
@@ -276,10 +284,13 @@ type BeadsMessage struct {
 	Priority    int       `json:"priority"`    // 0=urgent, 1=high, 2=normal, 3=low
 	Status      string    `json:"status"`      // open=unread, closed=read
 	CreatedAt   time.Time `json:"created_at"`
-	Labels      []string  `json:"labels"` // Metadata labels (from:X, thread:X, reply-to:X, msg-type:X, cc:X, queue:X, channel:X, claimed-by:X, claimed-at:X)
+	Labels      []string  `json:"labels"` // Metadata labels (from:X, thread:X, reply-to:X, msg-type:X, cc:X, queue:X, channel:X, claimed-by:X, claimed-at:X, broadcast:X, broadcast-to:X)
 	Pinned      bool      `json:"pinned,omitempty"`
 	Wisp        bool      `json:"wisp,omitempty"` // Ephemeral message (filtered from JSONL export)
 
+	// Attachments are parsed from attachment:<sha256>:<name> labels by ParseLabels.
+	Attachments []Attachment `json:"-"`
+
 	// Cached parsed values (populated by ParseLabels)
 	sender    string
 	threadID  string
@@ -290,10 +301,14 @@ type BeadsMessage struct {
 	channel   string     // Channel name (for broadcast messages)
 	claimedBy string     // Who claimed the queue message
 	claimedAt *time.Time // When the queue message was claimed
+
+	broadcastID string // Shared by every copy of a broadcast
+	broadcastTo string // Group address the broadcast was sent to
 }
 
 // ParseLabels extracts metadata from the labels array.
 func (bm *BeadsMessage) ParseLabels() {
+	bm.Attachments = nil
 	for _, label := range bm.Labels {
 		if strings.HasPrefix(label, "from:") {
 			bm.sender = strings.TrimPrefix(label, "from:")
@@ -316,6 +331,14 @@ func (bm *BeadsMessage) ParseLabels() {
 			if t, err := time.Parse(time.RFC3339, ts); err == nil {
 				bm.claimedAt = &t
 			}
+		} else if strings.HasPrefix(label, "broadcast:") {
+			bm.broadcastID = strings.TrimPrefix(label, "broadcast:")
+		} else if strings.HasPrefix(label, "broadcast-to:") {
+			bm.broadcastTo = strings.TrimPrefix(label, "broadcast-to:")
+		} else if strings.HasPrefix(label, "attachment:") {
+			if a, ok := parseAttachmentLabel(strings.TrimPrefix(label, "attachment:")); ok {
+				bm.Attachments = append(bm.Attachments, a)
+			}
 		}
 	}
 }
@@ -367,23 +390,26 @@ func (bm *BeadsMessage) ToMessage() *Message {
 	}
 
 	return &Message{
-		ID:        bm.ID,
-		From:      identityToAddress(bm.sender),
-		To:        identityToAddress(bm.Assignee),
-		Subject:   bm.Title,
-		Body:      bm.Description,
-		Timestamp: bm.CreatedAt,
-		Read:      bm.Status == "closed" || bm.HasLabel("read"),
-		Priority:  priority,
-		Type:      msgType,
-		ThreadID:  bm.threadID,
-		ReplyTo:   bm.replyTo,
-		Wisp:      bm.Wisp,
-		CC:        ccAddrs,
-		Queue:     bm.queue,
-		Channel:   bm.channel,
-		ClaimedBy: bm.claimedBy,
-		ClaimedAt: bm.claimedAt,
+		ID:          bm.ID,
+		From:        identityToAddress(bm.sender),
+		To:          identityToAddress(bm.Assignee),
+		Subject:     bm.Title,
+		Body:        bm.Description,
+		Timestamp:   bm.CreatedAt,
+		Read:        bm.Status == "closed" || bm.HasLabel("read"),
+		Priority:    priority,
+		Type:        msgType,
+		ThreadID:    bm.threadID,
+		ReplyTo:     bm.replyTo,
+		Wisp:        bm.Wisp,
+		CC:          ccAddrs,
+		Queue:       bm.queue,
+		Channel:     bm.channel,
+		ClaimedBy:   bm.claimedBy,
+		ClaimedAt:   bm.claimedAt,
+		Attachments: bm.Attachments,
+		BroadcastID: bm.broadcastID,
+		BroadcastTo: bm.broadcastTo,
 	}
 }
 
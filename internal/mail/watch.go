@@ -0,0 +1,208 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch checks the mail store for changes.
+const DefaultWatchInterval = 2 * time.Second
+
+// watchForcedPollEvery bounds how many unchanged store checks Watch tolerates
+// before querying anyway, in case a write did not touch a file it stats
+// (e.g. a remote database backend).
+const watchForcedPollEvery = 15
+
+// WatchOptions controls Mailbox.Watch.
+type WatchOptions struct {
+	// Interval between store checks. Zero means DefaultWatchInterval.
+	Interval time.Duration
+
+	// IncludeExisting emits messages already in the mailbox when the watch
+	// starts. When false, only messages that arrive afterwards are emitted.
+	IncludeExisting bool
+
+	// UnreadOnly restricts the watch to unread messages.
+	UnreadOnly bool
+
+	// Transport, if set, pushes messages as they are sent, between store
+	// checks (see transport.go). The store is still checked every Interval
+	// and catches anything the transport missed.
+	Transport Transport
+}
+
+// Watch streams new messages to fn until ctx is cancelled or fn returns an
+// error. Each message is delivered at most once, oldest first.
+//
+// The store is long-polled: the files backing the mailbox are stat'ed every
+// interval and the mailbox is only re-queried when they change, so an idle
+// watch costs a few stat calls rather than a bd invocation per tick.
+// With a transport, messages pushed to the mailbox are delivered as they
+// arrive; a message the sender couldn't tag with its store ID may then be
+// delivered twice. List errors are returned to the caller; cancellation
+// returns nil.
+func (m *Mailbox) Watch(ctx context.Context, opts WatchOptions, fn func(*Message) error) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	seen := make(map[string]bool)
+	first := true
+	var lastFP string
+	unchanged := 0
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pushed <-chan *Message
+	if opts.Transport != nil && m.identity != "" {
+		subCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		pushed = m.subscribe(subCtx, opts.Transport, interval)
+	}
+
+	for {
+		fp := m.storeFingerprint()
+		if first || fp == "" || fp != lastFP || unchanged >= watchForcedPollEvery {
+			messages, err := m.watchList(opts.UnreadOnly)
+			if err != nil {
+				return err
+			}
+			fresh := newMessages(messages, seen)
+			if !first || opts.IncludeExisting {
+				for _, msg := range fresh {
+					if err := fn(msg); err != nil {
+						return err
+					}
+				}
+			}
+			first = false
+			lastFP = fp
+			unchanged = 0
+		} else {
+			unchanged++
+		}
+
+	wait:
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case msg := <-pushed:
+			if !seen[msg.ID] && (!opts.UnreadOnly || !msg.Read) {
+				seen[msg.ID] = true
+				if err := fn(msg); err != nil {
+					return err
+				}
+			}
+			goto wait
+		}
+	}
+}
+
+// subscribe streams messages pushed to the mailbox on t, resubscribing
+// after retry whenever the connection fails, until ctx is cancelled.
+func (m *Mailbox) subscribe(ctx context.Context, t Transport, retry time.Duration) <-chan *Message {
+	var inboxes []string
+	for _, identity := range m.identityVariants() {
+		if key := inboxKey(identity); !slices.Contains(inboxes, key) {
+			inboxes = append(inboxes, key)
+		}
+	}
+
+	pushed := make(chan *Message, 16)
+	go func() {
+		for ctx.Err() == nil {
+			_ = t.Subscribe(ctx, inboxes, func(msg *Message) {
+				select {
+				case pushed <- msg:
+				case <-ctx.Done():
+				}
+			})
+			select {
+			case <-ctx.Done():
+			case <-time.After(retry):
+			}
+		}
+	}()
+	return pushed
+}
+
+func (m *Mailbox) watchList(unreadOnly bool) ([]*Message, error) {
+	if unreadOnly {
+		return m.ListUnread()
+	}
+	return m.List()
+}
+
+// newMessages returns the messages whose IDs are not in seen, oldest first,
+// and updates seen to the current listing so it does not grow without bound
+// as messages are archived.
+func newMessages(messages []*Message, seen map[string]bool) []*Message {
+	var fresh []*Message
+	current := make(map[string]bool, len(messages))
+	for _, msg := range messages {
+		current[msg.ID] = true
+		if !seen[msg.ID] {
+			fresh = append(fresh, msg)
+		}
+	}
+	for id := range seen {
+		if !current[id] {
+			delete(seen, id)
+		}
+	}
+	for id := range current {
+		seen[id] = true
+	}
+
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return fresh[i].Timestamp.Before(fresh[j].Timestamp)
+	})
+	return fresh
+}
+
+// storeFingerprint summarizes the modification state of the files backing
+// the mailbox. An empty result means the store cannot be stat'ed and the
+// caller should query every tick.
+func (m *Mailbox) storeFingerprint() string {
+	if m.legacy {
+		info, err := os.Stat(m.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "absent"
+			}
+			return ""
+		}
+		return info.ModTime().String() + "/" + strconv.FormatInt(info.Size(), 10)
+	}
+
+	if m.beadsDir == "" {
+		return ""
+	}
+	entries, err := os.ReadDir(m.beadsDir)
+	if err != nil {
+		return ""
+	}
+	var latest time.Time
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		total += info.Size()
+	}
+	return latest.String() + "/" + strconv.FormatInt(total, 10)
+}
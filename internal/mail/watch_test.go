@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMailboxWatchStreamsNewMessages(t *testing.T) {
+	m := NewMailbox(t.TempDir())
+	base := time.Now().Add(-time.Hour)
+
+	if err := m.Append(&Message{ID: "msg-old", Subject: "before", Timestamp: base}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	errStop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond}, func(msg *Message) error {
+			got = append(got, msg.ID)
+			if len(got) == 2 {
+				return errStop
+			}
+			return nil
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := m.Append(&Message{ID: "msg-a", Subject: "first", Timestamp: base.Add(time.Minute)}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := m.Append(&Message{ID: "msg-b", Subject: "second", Timestamp: base.Add(2 * time.Minute)}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, errStop) {
+		t.Fatalf("Watch returned %v, want errStop", err)
+	}
+	if len(got) != 2 || got[0] != "msg-a" || got[1] != "msg-b" {
+		t.Errorf("streamed %v, want [msg-a msg-b] (existing message suppressed)", got)
+	}
+}
+
+func TestMailboxWatchIncludeExisting(t *testing.T) {
+	m := NewMailbox(t.TempDir())
+	if err := m.Append(&Message{ID: "msg-old", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	err := m.Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond, IncludeExisting: true}, func(msg *Message) error {
+		got = append(got, msg.ID)
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if len(got) != 1 || got[0] != "msg-old" {
+		t.Errorf("streamed %v, want [msg-old]", got)
+	}
+}
+
+func TestNewMessagesOrderAndPrune(t *testing.T) {
+	base := time.Now()
+	seen := map[string]bool{"gone": true, "kept": true}
+	fresh := newMessages([]*Message{
+		{ID: "kept", Timestamp: base},
+		{ID: "newer", Timestamp: base.Add(2 * time.Minute)},
+		{ID: "older", Timestamp: base.Add(time.Minute)},
+	}, seen)
+
+	if len(fresh) != 2 || fresh[0].ID != "older" || fresh[1].ID != "newer" {
+		t.Errorf("fresh = %v, want [older newer]", fresh)
+	}
+	if seen["gone"] {
+		t.Error("seen still contains an ID no longer in the mailbox")
+	}
+	if !seen["newer"] || !seen["older"] || !seen["kept"] {
+		t.Errorf("seen = %v, want kept/newer/older", seen)
+	}
+}
@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// AddCrew declares a crew member under a listed rig. Returns false if the
+// rig isn't listed (its crew isn't managed by the manifest) or already
+// declares the member.
+func (m *Manifest) AddCrew(rigName, name string) bool {
+	spec, listed := m.Rigs[rigName]
+	if !listed {
+		return false
+	}
+	if spec == nil {
+		spec = &RigSpec{}
+		m.Rigs[rigName] = spec
+	}
+	if slices.Contains(spec.Crew, name) {
+		return false
+	}
+	spec.Crew = append(spec.Crew, name)
+	return true
+}
+
+// RemoveCrew drops a crew member from a rig's declaration. Returns false if
+// the rig doesn't declare the member.
+func (m *Manifest) RemoveCrew(rigName, name string) bool {
+	spec := m.Rigs[rigName]
+	if spec == nil {
+		return false
+	}
+	i := slices.Index(spec.Crew, name)
+	if i < 0 {
+		return false
+	}
+	spec.Crew = slices.Delete(spec.Crew, i, i+1)
+	return true
+}
+
+// Marshal renders the manifest as town.yaml. Comments and key order of a
+// hand-written file are not preserved; rigs are written in name order.
+func (m *Manifest) Marshal() []byte {
+	var b bytes.Buffer
+	version := m.Version
+	if version == 0 {
+		version = SchemaVersion
+	}
+	fmt.Fprintf(&b, "version: %d\n", version)
+	writeBool(&b, "", "mayor", m.Mayor)
+	writeBool(&b, "", "deacon", m.Deacon)
+	if m.Prune {
+		b.WriteString("prune: true\n")
+	}
+	if len(m.Rigs) == 0 {
+		return b.Bytes()
+	}
+
+	b.WriteString("rigs:\n")
+	names := make([]string, 0, len(m.Rigs))
+	for name := range m.Rigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s:\n", name)
+		spec := m.Rigs[name]
+		if spec == nil {
+			continue
+		}
+		writeBool(&b, "    ", "witness", spec.Witness)
+		writeBool(&b, "    ", "refinery", spec.Refinery)
+		if len(spec.Crew) > 0 {
+			crew := make([]string, len(spec.Crew))
+			for i, c := range spec.Crew {
+				crew[i] = yamlString(c)
+			}
+			fmt.Fprintf(&b, "    crew: [%s]\n", strings.Join(crew, ", "))
+		}
+		if spec.Polecats != nil {
+			fmt.Fprintf(&b, "    polecats: %d\n", *spec.Polecats)
+		}
+	}
+	return b.Bytes()
+}
+
+// Save writes the manifest to the town's town.yaml.
+func Save(townRoot string, m *Manifest) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	return util.AtomicWriteFile(Path(townRoot), m.Marshal(), 0644)
+}
+
+func writeBool(b *bytes.Buffer, indent, key string, v *bool) {
+	if v != nil {
+		fmt.Fprintf(b, "%s%s: %t\n", indent, key, *v)
+	}
+}
+
+// yamlString quotes s if it would otherwise parse as something other than
+// the same plain string, or break the flow sequence it is written in.
+func yamlString(s string) string {
+	if !strings.ContainsAny(s, ",[]{}#:\"'") {
+		var v any
+		if err := yaml.Unmarshal([]byte(s), &v); err == nil && v == s {
+			return s
+		}
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,182 @@
+// Package manifest reads the declarative town manifest, town.yaml, which
+// describes the agents a town is expected to run. The daemon's reconciler
+// compares it with the sessions and agent beads it observes and starts or
+// stops sessions to converge, instead of only reacting to lifecycle mail.
+//
+// Example <town>/town.yaml:
+//
+//	version: 1
+//	deacon: true
+//	rigs:
+//	  gastown:
+//	    witness: true
+//	    refinery: true
+//	    crew: [max, joe]
+//	    polecats: 4     # ceiling; excess is reported, never killed
+//	  sandbox:
+//	    refinery: false
+//
+// A town without town.yaml is not reconciled. Within the manifest:
+//   - mayor and deacon are left alone unless set;
+//   - a listed rig's witness and refinery default to running;
+//   - a listed rig's crew list is authoritative: undeclared crew sessions are stopped;
+//   - rigs not listed are left alone unless prune is true.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the manifest file at the town root.
+const FileName = "town.yaml"
+
+// SchemaVersion is the manifest version this package understands.
+const SchemaVersion = 1
+
+// Manifest is the desired state of a town.
+type Manifest struct {
+	Version int `json:"version"`
+
+	// Mayor and Deacon keep the town-level sessions running (true) or
+	// stopped (false). Unset leaves them to their usual management.
+	Mayor  *bool `json:"mayor,omitempty"`
+	Deacon *bool `json:"deacon,omitempty"`
+
+	// Prune stops witness, refinery, and crew sessions of registered rigs
+	// that aren't listed under Rigs. Polecats are never pruned.
+	Prune bool `json:"prune,omitempty"`
+
+	Rigs map[string]*RigSpec `json:"rigs,omitempty"`
+}
+
+// RigSpec is the desired state of one rig.
+type RigSpec struct {
+	// Witness and Refinery default to true.
+	Witness  *bool `json:"witness,omitempty"`
+	Refinery *bool `json:"refinery,omitempty"`
+
+	// Crew lists the crew members that should be running.
+	Crew []string `json:"crew,omitempty"`
+
+	// Polecats caps concurrent polecat sessions. Polecats are spawned by
+	// work assignment, so the reconciler reports sessions over the cap
+	// rather than killing work in progress. Unset means no cap.
+	Polecats *int `json:"polecats,omitempty"`
+}
+
+// Path returns the manifest path for a town.
+func Path(townRoot string) string {
+	return filepath.Join(townRoot, FileName)
+}
+
+// Load reads the town's manifest. Returns nil, nil if the town has none.
+func Load(townRoot string) (*Manifest, error) {
+	data, err := os.ReadFile(Path(townRoot))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", FileName, err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", FileName, err)
+	}
+	return m, nil
+}
+
+// Parse parses and validates manifest YAML.
+func Parse(data []byte) (*Manifest, error) {
+	doc := map[string]any{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	// Decode through JSON so the struct tags define the schema and
+	// misspelled keys are caught.
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks the manifest for errors.
+func (m *Manifest) Validate() error {
+	if m.Version != 0 && m.Version != SchemaVersion {
+		return fmt.Errorf("unsupported version %d (expected %d)", m.Version, SchemaVersion)
+	}
+	for name, spec := range m.Rigs {
+		if name == "" || strings.ContainsAny(name, " /") {
+			return fmt.Errorf("invalid rig name %q", name)
+		}
+		if spec == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, crew := range spec.Crew {
+			if crew == "" || strings.ContainsAny(crew, " /") {
+				return fmt.Errorf("rig %s: invalid crew name %q", name, crew)
+			}
+			if seen[crew] {
+				return fmt.Errorf("rig %s: crew %q listed twice", name, crew)
+			}
+			seen[crew] = true
+		}
+		if spec.Polecats != nil && *spec.Polecats < 0 {
+			return fmt.Errorf("rig %s: polecats must be >= 0", name)
+		}
+	}
+	return nil
+}
+
+// RoleDisabled reports whether the manifest keeps an agent role stopped:
+// the deacon or mayor (rigName ""), or a rig's witness or refinery. The
+// daemon's own auto-start checks consult this so they don't fight the
+// reconciler. Safe to call on a nil manifest.
+func (m *Manifest) RoleDisabled(rigName, role string) bool {
+	if m == nil {
+		return false
+	}
+	if rigName == "" {
+		switch role {
+		case "mayor":
+			return m.Mayor != nil && !*m.Mayor
+		case "deacon":
+			return m.Deacon != nil && !*m.Deacon
+		}
+		return false
+	}
+
+	spec, ok := m.Rigs[rigName]
+	if !ok {
+		return m.Prune
+	}
+	if spec == nil {
+		return false
+	}
+	switch role {
+	case "witness":
+		return spec.Witness != nil && !*spec.Witness
+	case "refinery":
+		return spec.Refinery != nil && !*spec.Refinery
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleManifest = `# Production town
+version: 1
+deacon: true
+rigs:
+  gastown:
+    crew: [max, "joe"]   # two crew members
+    polecats: 2
+  sandbox:
+    refinery: false
+    crew:
+      - ada
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(sampleManifest))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if m.Version != 1 || m.Deacon == nil || !*m.Deacon || m.Mayor != nil {
+		t.Errorf("town fields = %+v", m)
+	}
+	gastown := m.Rigs["gastown"]
+	if gastown == nil || len(gastown.Crew) != 2 || gastown.Crew[1] != "joe" {
+		t.Fatalf("gastown = %+v", gastown)
+	}
+	if gastown.Polecats == nil || *gastown.Polecats != 2 {
+		t.Errorf("gastown polecats = %v", gastown.Polecats)
+	}
+	sandbox := m.Rigs["sandbox"]
+	if sandbox == nil || sandbox.Refinery == nil || *sandbox.Refinery || len(sandbox.Crew) != 1 {
+		t.Errorf("sandbox = %+v", sandbox)
+	}
+	if !m.RoleDisabled("sandbox", "refinery") || m.RoleDisabled("gastown", "refinery") {
+		t.Error("RoleDisabled mismatch")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":    "rigs:\n  gastown:\n    witnes: true\n",
+		"bad version":    "version: 7\n",
+		"duplicate crew": "rigs:\n  gastown:\n    crew: [max, max]\n",
+		"duplicate key":  "version: 1\nversion: 1\n",
+		"tabs":           "rigs:\n\tgastown: {}\n",
+		"scalar rig":     "rigs:\n  gastown: yes\n",
+		"bad indent":     "rigs:\n    gastown:\n  other:\n",
+	}
+	for name, src := range tests {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil || m != nil {
+		t.Errorf("Load(no manifest) = %v, %v; want nil, nil", m, err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("version: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := Load(dir); err != nil || m == nil {
+		t.Errorf("Load = %v, %v", m, err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	m, err := Parse([]byte(sampleManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := []Agent{
+		{Identity: "deacon", Role: "deacon", Running: true},
+		{Identity: "gastown-witness", Rig: "gastown", Role: "witness", Running: true, BeadState: BeadStateStopped},
+		{Identity: "gastown-refinery", Rig: "gastown", Role: "refinery", BeadState: BeadStateRunning},
+		{Identity: "gastown-crew-max", Rig: "gastown", Role: "crew", Running: true},
+		{Identity: "gastown-crew-old", Rig: "gastown", Role: "crew", Running: true},
+		{Identity: "gastown-polecat-a", Rig: "gastown", Role: "polecat", Running: true},
+		{Identity: "gastown-polecat-b", Rig: "gastown", Role: "polecat", Running: true},
+		{Identity: "gastown-polecat-c", Rig: "gastown", Role: "polecat", Running: true},
+		{Identity: "other-witness", Rig: "other", Role: "witness", Running: true},
+	}
+
+	var got []string
+	for _, c := range m.Plan([]string{"gastown", "other"}, observed) {
+		got = append(got, string(c.Action)+" "+c.Identity)
+	}
+	want := []string{
+		"stop gastown-crew-old",
+		"start gastown-refinery",
+		"start gastown-crew-joe",
+		"sync-bead gastown-witness",
+		"warn gastown",
+		"warn sandbox",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// With prune, undeclared rigs are stopped too
+	m.Prune = true
+	var pruned bool
+	for _, c := range m.Plan([]string{"gastown", "other"}, observed) {
+		if c.Action == ActionStop && c.Identity == "other-witness" {
+			pruned = true
+		}
+	}
+	if !pruned {
+		t.Error("prune: expected other-witness to be stopped")
+	}
+}
+
+func TestEditCrew(t *testing.T) {
+	m, err := Parse([]byte(sampleManifest))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !m.AddCrew("gastown", "ada") {
+		t.Error("AddCrew(gastown, ada) = false, want true")
+	}
+	if m.AddCrew("gastown", "ada") {
+		t.Error("adding a declared member should report no change")
+	}
+	if m.AddCrew("unlisted", "ada") {
+		t.Error("adding to an unlisted rig should report no change")
+	}
+	if !m.RemoveCrew("sandbox", "ada") || m.RemoveCrew("sandbox", "ada") {
+		t.Error("RemoveCrew(sandbox, ada) should succeed once")
+	}
+
+	// Marshal round-trips through Parse
+	got, err := Parse(m.Marshal())
+	if err != nil {
+		t.Fatalf("Parse(Marshal): %v\n%s", err, m.Marshal())
+	}
+	if crew := got.Rigs["gastown"].Crew; strings.Join(crew, ",") != "max,joe,ada" {
+		t.Errorf("gastown crew = %v", crew)
+	}
+	if sandbox := got.Rigs["sandbox"]; len(sandbox.Crew) != 0 || sandbox.Refinery == nil || *sandbox.Refinery {
+		t.Errorf("sandbox = %+v", sandbox)
+	}
+	if got.Deacon == nil || !*got.Deacon || got.Rigs["gastown"].Polecats == nil {
+		t.Errorf("round trip lost fields: %s", m.Marshal())
+	}
+}
+
+func TestSave(t *testing.T) {
+	townRoot := t.TempDir()
+	m := &Manifest{Rigs: map[string]*RigSpec{"gastown": nil, "odd": {Crew: []string{"true", "7"}}}}
+	if err := Save(townRoot, m); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load(townRoot)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.Version != SchemaVersion {
+		t.Errorf("version = %d", got.Version)
+	}
+	if _, ok := got.Rigs["gastown"]; !ok {
+		t.Error("empty rig entry was dropped")
+	}
+	if crew := got.Rigs["odd"].Crew; len(crew) != 2 || crew[0] != "true" || crew[1] != "7" {
+		t.Errorf("odd crew = %v, want quoted strings preserved", crew)
+	}
+}
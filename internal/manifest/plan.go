@@ -0,0 +1,181 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Action is what the reconciler should do about one agent.
+type Action string
+
+const (
+	// ActionStart starts a declared agent whose session isn't running.
+	ActionStart Action = "start"
+
+	// ActionStop stops a running agent the manifest doesn't want.
+	ActionStop Action = "stop"
+
+	// ActionSyncBead corrects an agent bead whose state disagrees with tmux.
+	ActionSyncBead Action = "sync-bead"
+
+	// ActionWarn reports drift the reconciler won't fix on its own.
+	ActionWarn Action = "warn"
+)
+
+// Agent bead states the reconciler reads and writes.
+const (
+	BeadStateRunning = "running"
+	BeadStateStopped = "stopped"
+)
+
+// Agent is the observed state of one agent.
+type Agent struct {
+	Identity  string // daemon identity, e.g. "gastown-crew-max"
+	Rig       string // "" for town-level agents
+	Role      string // mayor, deacon, witness, refinery, crew, polecat
+	Running   bool   // tmux session exists
+	BeadState string // agent_state from the agent bead, "" if unknown
+}
+
+// Change is one step toward the desired state.
+type Change struct {
+	Action   Action
+	Identity string
+	Reason   string
+
+	// BeadState is the state to record for ActionSyncBead.
+	BeadState string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Action, c.Identity, c.Reason)
+}
+
+// desired is one declared agent.
+type desired struct {
+	identity string
+	rig      string
+	run      bool
+}
+
+// rigNames returns the declared rig names, sorted.
+func (m *Manifest) rigNames() []string {
+	names := make([]string, 0, len(m.Rigs))
+	for name := range m.Rigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// declared lists every agent the manifest has an opinion on, in bring-up
+// order: town agents, then per rig (sorted) witness, refinery, crew.
+func (m *Manifest) declared() []desired {
+	var out []desired
+	if m.Mayor != nil {
+		out = append(out, desired{identity: "mayor", run: *m.Mayor})
+	}
+	if m.Deacon != nil {
+		out = append(out, desired{identity: "deacon", run: *m.Deacon})
+	}
+
+	for _, name := range m.rigNames() {
+		spec := m.Rigs[name]
+		if spec == nil {
+			spec = &RigSpec{}
+		}
+		out = append(out,
+			desired{identity: name + "-witness", rig: name, run: spec.Witness == nil || *spec.Witness},
+			desired{identity: name + "-refinery", rig: name, run: spec.Refinery == nil || *spec.Refinery},
+		)
+		for _, crew := range spec.Crew {
+			out = append(out, desired{identity: name + "-crew-" + crew, rig: name, run: true})
+		}
+	}
+	return out
+}
+
+// Plan compares the manifest with the observed agents and returns the
+// changes that converge them: stops first (to free capacity), then starts in
+// bring-up order, then bead corrections and warnings. knownRigs are the
+// rigs registered in the town; declared rigs that aren't registered are
+// reported rather than started.
+func (m *Manifest) Plan(knownRigs []string, observed []Agent) []Change {
+	known := make(map[string]bool, len(knownRigs))
+	for _, name := range knownRigs {
+		known[name] = true
+	}
+	byIdentity := make(map[string]Agent, len(observed))
+	for _, a := range observed {
+		byIdentity[a.Identity] = a
+	}
+
+	var stops, starts, syncs, warns []Change
+	isDeclared := make(map[string]bool)
+
+	for _, want := range m.declared() {
+		isDeclared[want.identity] = true
+		if want.rig != "" && !known[want.rig] {
+			continue
+		}
+		agent := byIdentity[want.identity]
+		switch {
+		case want.run && !agent.Running:
+			reason := "declared in manifest but not running"
+			if agent.BeadState == BeadStateRunning {
+				reason = "agent bead reports running but session is dead"
+			}
+			starts = append(starts, Change{Action: ActionStart, Identity: want.identity, Reason: reason})
+		case !want.run && agent.Running:
+			stops = append(stops, Change{Action: ActionStop, Identity: want.identity, Reason: "disabled in manifest"})
+		case agent.Running && agent.BeadState == BeadStateStopped:
+			syncs = append(syncs, Change{Action: ActionSyncBead, Identity: want.identity,
+				Reason: "agent bead reports stopped but session is running", BeadState: BeadStateRunning})
+		case !agent.Running && agent.BeadState == BeadStateRunning:
+			syncs = append(syncs, Change{Action: ActionSyncBead, Identity: want.identity,
+				Reason: "agent bead reports running but session is stopped", BeadState: BeadStateStopped})
+		}
+	}
+
+	polecats := make(map[string]int)
+	sorted := append([]Agent(nil), observed...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Identity < sorted[j].Identity })
+	for _, a := range sorted {
+		if isDeclared[a.Identity] || !a.Running {
+			continue
+		}
+		_, rigListed := m.Rigs[a.Rig]
+		switch a.Role {
+		case "polecat":
+			polecats[a.Rig]++
+		case "crew":
+			if rigListed {
+				stops = append(stops, Change{Action: ActionStop, Identity: a.Identity, Reason: "crew member not declared in manifest"})
+			} else if m.Prune && a.Rig != "" {
+				stops = append(stops, Change{Action: ActionStop, Identity: a.Identity, Reason: "rig not declared in manifest (prune)"})
+			}
+		case "witness", "refinery":
+			if !rigListed && m.Prune && a.Rig != "" {
+				stops = append(stops, Change{Action: ActionStop, Identity: a.Identity, Reason: "rig not declared in manifest (prune)"})
+			}
+		}
+	}
+
+	for _, name := range m.rigNames() {
+		if !known[name] {
+			warns = append(warns, Change{Action: ActionWarn, Identity: name,
+				Reason: "rig is declared in manifest but not registered (gt rig add)"})
+			continue
+		}
+		spec := m.Rigs[name]
+		if spec == nil || spec.Polecats == nil || polecats[name] <= *spec.Polecats {
+			continue
+		}
+		warns = append(warns, Change{Action: ActionWarn, Identity: name,
+			Reason: fmt.Sprintf("%d polecat sessions running, manifest allows %d", polecats[name], *spec.Polecats)})
+	}
+
+	changes := append(stops, starts...)
+	changes = append(changes, syncs...)
+	return append(changes, warns...)
+}
@@ -0,0 +1,260 @@
+// Package notifier delivers operator alerts to external chat and webhook sinks.
+//
+// The daemon fires events (crash loops, failed restarts, daemon start/stop, ...)
+// and the notifier routes each event to the sinks configured for it, rendering
+// a per-route text/template with the event's fields. Delivery is best-effort:
+// a sink failure is reported to the caller but never retried.
+//
+// Configuration lives in the "notifications" section of mayor/daemon.json:
+//
+//	"notifications": {
+//	  "sinks": {
+//	    "ops": {"type": "slack", "url": "https://hooks.slack.com/services/..."}
+//	  },
+//	  "routes": [
+//	    {"events": ["crash_loop", "restart_failed"], "sinks": ["ops"],
+//	     "template": "{{.Event}}: {{.Fields.agent}} - {{.Fields.error}}"}
+//	  ]
+//	}
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Event types the daemon can fire.
+const (
+	EventCrashLoop           = "crash_loop"
+	EventRestartFailed       = "restart_failed"
+	EventStaleLifecycleSurge = "stale_lifecycle_surge"
+	EventDaemonStart         = "daemon_start"
+	EventDaemonStop          = "daemon_stop"
+	EventMailPollFailing     = "mail_poll_failing"
+	EventDaemonSafeMode      = "daemon_safe_mode"
+	EventBeadsSyncConflict   = "beads_sync_conflict"
+	EventDaemonUpdate        = "daemon_update"
+	EventDiskQuota           = "disk_quota"
+	EventEmergencyStop       = "emergency_stop"
+	EventConfigRejected      = "config_rejected"
+)
+
+// Sink types.
+const (
+	SinkWebhook = "webhook"
+	SinkSlack   = "slack"
+	SinkDiscord = "discord"
+)
+
+// sendTimeout bounds each HTTP delivery so a hung endpoint can't stall the daemon.
+const sendTimeout = 10 * time.Second
+
+// SinkConfig configures a single delivery target.
+type SinkConfig struct {
+	// Type is one of "webhook", "slack", or "discord".
+	Type string `json:"type"`
+
+	// URL is the endpoint messages are POSTed to.
+	URL string `json:"url"`
+}
+
+// RouteConfig maps a set of events to sinks.
+type RouteConfig struct {
+	// Events lists event types this route matches. "*" matches every event.
+	Events []string `json:"events"`
+
+	// Sinks names the sinks (keys of Config.Sinks) to deliver to.
+	Sinks []string `json:"sinks"`
+
+	// Template is a text/template rendered with a Message. Empty uses the
+	// default "[gastown] <event>: key=value ..." format.
+	Template string `json:"template,omitempty"`
+}
+
+// Config is the notifications section of the daemon config.
+type Config struct {
+	Sinks  map[string]SinkConfig `json:"sinks,omitempty"`
+	Routes []RouteConfig         `json:"routes,omitempty"`
+}
+
+// Message is a rendered event ready for delivery.
+type Message struct {
+	Event  string            `json:"event"`
+	Town   string            `json:"town,omitempty"`
+	Time   time.Time         `json:"time"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Text   string            `json:"text"`
+}
+
+// Notifier routes events to sinks.
+type Notifier struct {
+	config *Config
+	town   string
+	client *http.Client
+}
+
+// New creates a notifier. A nil config yields a notifier that drops everything.
+func New(config *Config, town string) *Notifier {
+	return &Notifier{
+		config: config,
+		town:   town,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Validate checks that every route references a defined sink of a known type
+// and that every template parses.
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for name, sink := range c.Sinks {
+		switch sink.Type {
+		case SinkWebhook, SinkSlack, SinkDiscord:
+		default:
+			return fmt.Errorf("sink %q: unknown type %q", name, sink.Type)
+		}
+		if sink.URL == "" {
+			return fmt.Errorf("sink %q: url is required", name)
+		}
+	}
+	for i, route := range c.Routes {
+		for _, name := range route.Sinks {
+			if _, ok := c.Sinks[name]; !ok {
+				return fmt.Errorf("route %d: unknown sink %q", i, name)
+			}
+		}
+		if route.Template != "" {
+			if _, err := template.New("route").Parse(route.Template); err != nil {
+				return fmt.Errorf("route %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Notify fires an event. Every matching route renders its template and
+// delivers to its sinks; errors from all deliveries are joined.
+func (n *Notifier) Notify(event string, fields map[string]string) error {
+	if n == nil || n.config == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, route := range n.config.Routes {
+		if !routeMatches(route, event) {
+			continue
+		}
+
+		msg := Message{
+			Event:  event,
+			Town:   n.town,
+			Time:   time.Now().UTC(),
+			Fields: fields,
+		}
+		text, err := renderText(route.Template, msg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rendering %s: %w", event, err))
+			continue
+		}
+		msg.Text = text
+
+		for _, name := range route.Sinks {
+			sink, ok := n.config.Sinks[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("unknown sink %q", name))
+				continue
+			}
+			if err := n.send(sink, msg); err != nil {
+				errs = append(errs, fmt.Errorf("sink %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routeMatches reports whether a route applies to an event.
+func routeMatches(route RouteConfig, event string) bool {
+	for _, e := range route.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// renderText renders a route template, falling back to the default format.
+func renderText(tmpl string, msg Message) (string, error) {
+	if tmpl == "" {
+		return defaultText(msg), nil
+	}
+	t, err := template.New("route").Option("missingkey=zero").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// defaultText formats a message as "[gastown/<town>] event: k=v k=v".
+func defaultText(msg Message) string {
+	prefix := "[gastown]"
+	if msg.Town != "" {
+		prefix = fmt.Sprintf("[gastown/%s]", msg.Town)
+	}
+
+	keys := make([]string, 0, len(msg.Fields))
+	for k := range msg.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{prefix + " " + msg.Event}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, msg.Fields[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// payload builds the request body for a sink type.
+func payload(sinkType string, msg Message) ([]byte, error) {
+	switch sinkType {
+	case SinkSlack:
+		return json.Marshal(map[string]string{"text": msg.Text})
+	case SinkDiscord:
+		return json.Marshal(map[string]string{"content": msg.Text})
+	case SinkWebhook:
+		return json.Marshal(msg)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sinkType)
+	}
+}
+
+// send POSTs a message to a sink.
+func (n *Notifier) send(sink SinkConfig, msg Message) error {
+	body, err := payload(sink.Type, msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(sink.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recorder is a test HTTP endpoint that keeps every request body.
+type recorder struct {
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func (r *recorder) handler(w http.ResponseWriter, req *http.Request) {
+	data, _ := io.ReadAll(req.Body)
+	var body map[string]interface{}
+	_ = json.Unmarshal(data, &body)
+	r.mu.Lock()
+	r.bodies = append(r.bodies, body)
+	r.mu.Unlock()
+}
+
+func TestNotify_RoutesToSinks(t *testing.T) {
+	slack, discord := &recorder{}, &recorder{}
+	slackSrv := httptest.NewServer(http.HandlerFunc(slack.handler))
+	defer slackSrv.Close()
+	discordSrv := httptest.NewServer(http.HandlerFunc(discord.handler))
+	defer discordSrv.Close()
+
+	cfg := &Config{
+		Sinks: map[string]SinkConfig{
+			"ops":  {Type: SinkSlack, URL: slackSrv.URL},
+			"chat": {Type: SinkDiscord, URL: discordSrv.URL},
+		},
+		Routes: []RouteConfig{
+			{Events: []string{EventRestartFailed}, Sinks: []string{"ops"}, Template: "restart failed: {{.Fields.agent}}"},
+			{Events: []string{"*"}, Sinks: []string{"chat"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	n := New(cfg, "ai")
+	if err := n.Notify(EventRestartFailed, map[string]string{"agent": "gastown-witness"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if err := n.Notify(EventDaemonStart, nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if len(slack.bodies) != 1 {
+		t.Fatalf("expected 1 slack message, got %d", len(slack.bodies))
+	}
+	if slack.bodies[0]["text"] != "restart failed: gastown-witness" {
+		t.Errorf("slack text = %v", slack.bodies[0]["text"])
+	}
+
+	if len(discord.bodies) != 2 {
+		t.Fatalf("expected 2 discord messages, got %d", len(discord.bodies))
+	}
+	if discord.bodies[1]["content"] != "[gastown/ai] daemon_start" {
+		t.Errorf("discord content = %v", discord.bodies[1]["content"])
+	}
+}
+
+func TestNotify_SinkErrorReported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n := New(&Config{
+		Sinks:  map[string]SinkConfig{"hook": {Type: SinkWebhook, URL: srv.URL}},
+		Routes: []RouteConfig{{Events: []string{EventCrashLoop}, Sinks: []string{"hook"}}},
+	}, "")
+	if err := n.Notify(EventCrashLoop, nil); err == nil {
+		t.Error("expected error for 500 response")
+	}
+}
+
+func TestNotify_NilConfig(t *testing.T) {
+	var n *Notifier
+	if err := n.Notify(EventDaemonStop, nil); err != nil {
+		t.Errorf("nil notifier should be a no-op, got %v", err)
+	}
+	if err := New(nil, "").Notify(EventDaemonStop, nil); err != nil {
+		t.Errorf("nil config should be a no-op, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"unknown type", &Config{Sinks: map[string]SinkConfig{"x": {Type: "pager", URL: "http://x"}}}, true},
+		{"missing url", &Config{Sinks: map[string]SinkConfig{"x": {Type: SinkSlack}}}, true},
+		{"unknown sink", &Config{Routes: []RouteConfig{{Events: []string{"*"}, Sinks: []string{"nope"}}}}, true},
+		{"bad template", &Config{
+			Sinks:  map[string]SinkConfig{"x": {Type: SinkSlack, URL: "http://x"}},
+			Routes: []RouteConfig{{Events: []string{"*"}, Sinks: []string{"x"}, Template: "{{.Event"}},
+		}, true},
+	}
+
+	for _, tc := range tests {
+		err := tc.cfg.Validate()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
@@ -304,6 +304,11 @@ func (e *Engineer) doMerge(ctx context.Context, branch, target, sourceIssue stri
 		}
 	}
 
+	// Step 3b: Reject changes to paths blocked by the rig's quality gates
+	if result := e.checkBlockedPaths(branch, target); !result.Success {
+		return result
+	}
+
 	// Step 4: Run tests if configured
 	if e.config.RunTests && e.config.TestCommand != "" {
 		_, _ = fmt.Fprintf(e.output, "[Engineer] Running tests: %s\n", e.config.TestCommand)
@@ -367,6 +372,35 @@ func (e *Engineer) doMerge(ctx context.Context, branch, target, sourceIssue stri
 	}
 }
 
+// checkBlockedPaths verifies the branch doesn't touch paths blocked by the
+// witness-published quality gates for this rig. Gates that can't be loaded
+// are skipped with a warning; gt done already enforced them at completion.
+func (e *Engineer) checkBlockedPaths(branch, target string) ProcessResult {
+	_, gates, err := beads.New(filepath.Dir(e.rig.Path)).GetQualityGates(e.rig.Name)
+	if err != nil {
+		_, _ = fmt.Fprintf(e.output, "[Engineer] Warning: could not load quality gates: %v\n", err)
+		return ProcessResult{Success: true}
+	}
+	if gates == nil || len(gates.BlockedPaths) == 0 {
+		return ProcessResult{Success: true}
+	}
+
+	changed, err := e.git.ChangedFiles(target, branch)
+	if err != nil {
+		return ProcessResult{
+			Success: false,
+			Error:   fmt.Sprintf("failed to list changed files: %v", err),
+		}
+	}
+	if violations := gates.BlockedPathViolations(changed); len(violations) > 0 {
+		return ProcessResult{
+			Success: false,
+			Error:   fmt.Sprintf("quality gates block changes to: %s", strings.Join(violations, ", ")),
+		}
+	}
+	return ProcessResult{Success: true}
+}
+
 // runTests runs the configured test command and returns the result.
 func (e *Engineer) runTests(ctx context.Context) ProcessResult {
 	if e.config.TestCommand == "" {
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -335,6 +336,13 @@ func (m *Manager) Queue() ([]QueueItem, error) {
 		return scored[i].score > scored[j].score
 	})
 
+	// MRs placed with 'gt refinery queue move' go first
+	order, err := m.QueueOrder()
+	if err != nil {
+		return nil, err
+	}
+	sortPinned(scored, func(s scoredIssue) string { return s.issue.ID }, order.Pinned)
+
 	// Convert scored issues to queue items
 	for _, s := range scored {
 		mr := m.issueToMR(s.issue)
@@ -347,6 +355,7 @@ func (m *Manager) Queue() ([]QueueItem, error) {
 				Position: pos,
 				MR:       mr,
 				Age:      formatAge(mr.CreatedAt),
+				Pinned:   slices.Contains(order.Pinned, mr.ID),
 			})
 			pos++
 		}
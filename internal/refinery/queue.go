@@ -0,0 +1,349 @@
+package refinery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/rig"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// Queue runner errors.
+var (
+	// ErrQueueBusy is returned when another process is already working the
+	// rig's merge queue.
+	ErrQueueBusy = errors.New("merge queue is being processed by another worker")
+
+	// ErrQueueDisabled is returned when the rig's config.json turns the
+	// merge queue off.
+	ErrQueueDisabled = errors.New("merge queue is disabled")
+)
+
+// defaultQueueWorker is the assignee recorded on MRs claimed by the queue
+// runner.
+const defaultQueueWorker = "merge-queue"
+
+// QueueOrder is the operator-set order of the head of a rig's merge queue,
+// kept in the town store under <rig>/.runtime/merge-queue.json. Pinned MRs
+// are processed first, in the listed order; the rest follow by score. MRs
+// that leave the queue drop out of the list when it is next saved.
+type QueueOrder struct {
+	// Pinned are MR bead IDs, first to be processed first.
+	Pinned []string `json:"pinned,omitempty"`
+
+	// UpdatedAt is when the order was last changed.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// queueOrderKey returns the town store key of the rig's queue order.
+func (m *Manager) queueOrderKey() string {
+	return m.rig.Name + "/.runtime/merge-queue.json"
+}
+
+// queueLockFile returns the path of the lock serializing merges in the rig.
+// The lock is local: queue runners for a rig run on the rig's host.
+func (m *Manager) queueLockFile() string {
+	return filepath.Join(m.rig.Path, ".runtime", "merge-queue.lock")
+}
+
+// townStore opens the store of the rig's town.
+func (m *Manager) townStore() (storage.Store, error) {
+	return storage.ForTown(filepath.Dir(m.rig.Path), os.Getenv)
+}
+
+// QueueOrder loads the rig's queue order. A missing order is empty.
+func (m *Manager) QueueOrder() (*QueueOrder, error) {
+	store, err := m.townStore()
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	order := &QueueOrder{}
+	data, err := store.Get(m.queueOrderKey())
+	if errors.Is(err, storage.ErrNotFound) {
+		return order, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, order); err != nil {
+		return nil, fmt.Errorf("reading queue order: %w", err)
+	}
+	return order, nil
+}
+
+func (m *Manager) saveQueueOrder(order *QueueOrder) error {
+	store, err := m.townStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	order.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(order, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(m.queueOrderKey(), data)
+}
+
+// MoveMR moves an MR to a 1-based position in the pending queue. The MRs
+// ahead of it and the MR itself are pinned there, so the order holds as
+// new, higher-scored MRs arrive.
+func (m *Manager) MoveMR(id string, position int) ([]QueueItem, error) {
+	if position < 1 {
+		return nil, fmt.Errorf("position must be 1 or more, got %d", position)
+	}
+	queue, err := m.Queue()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	found := false
+	for _, item := range queue {
+		if item.Position == 0 {
+			continue // being processed, can't move
+		}
+		if item.MR.ID == id {
+			found = true
+			continue
+		}
+		ids = append(ids, item.MR.ID)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s is not pending in the %s merge queue", ErrMRNotFound, id, m.rig.Name)
+	}
+	position = min(position, len(ids)+1)
+	ids = slices.Insert(ids, position-1, id)
+
+	order, err := m.QueueOrder()
+	if err != nil {
+		return nil, err
+	}
+	// Keep everything that was pinned pinned, but never less than the
+	// prefix up to the moved MR
+	keep := position
+	for i, qid := range ids {
+		if slices.Contains(order.Pinned, qid) {
+			keep = max(keep, i+1)
+		}
+	}
+	order.Pinned = ids[:keep]
+	if err := m.saveQueueOrder(order); err != nil {
+		return nil, err
+	}
+	return m.Queue()
+}
+
+// ResetQueueOrder unpins every MR, returning the queue to score order.
+func (m *Manager) ResetQueueOrder() error {
+	store, err := m.townStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.Delete(m.queueOrderKey())
+}
+
+// sortPinned stably moves pinned IDs to the front, in pinned order.
+func sortPinned[T any](items []T, id func(T) string, pinned []string) {
+	if len(pinned) == 0 {
+		return
+	}
+	rank := make(map[string]int, len(pinned))
+	for i, pid := range pinned {
+		rank[pid] = i
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ri, iPinned := rank[id(items[i])]
+		rj, jPinned := rank[id(items[j])]
+		switch {
+		case iPinned && jPinned:
+			return ri < rj
+		default:
+			return iPinned && !jPinned
+		}
+	})
+}
+
+// QueueRunner works a rig's merge queue one MR at a time: it takes the head
+// of the queue, syncs the refinery workspace, merges, and records the
+// outcome on the MR bead. A lock file in the rig serializes runners, so the
+// daemon and 'gt refinery queue process' never merge concurrently.
+type QueueRunner struct {
+	mgr *Manager
+	eng *Engineer
+
+	// Sync brings the refinery workspace up to date before each merge.
+	// Default: git fetch origin.
+	Sync func(workDir string) error
+
+	// WorkerID is the assignee recorded on claimed MRs.
+	WorkerID string
+}
+
+// QueueRunResult is the outcome of one MR worked by the runner.
+type QueueRunResult struct {
+	MR     *MRInfo       `json:"mr"`
+	Result ProcessResult `json:"result"`
+}
+
+// NewQueueRunner creates a runner for the rig's merge queue. The rig's
+// merge_queue config is loaded when it runs.
+func NewQueueRunner(r *rig.Rig) *QueueRunner {
+	q := &QueueRunner{mgr: NewManager(r), eng: NewEngineer(r), WorkerID: defaultQueueWorker}
+	q.Sync = func(string) error { return q.eng.git.Fetch("origin") }
+	return q
+}
+
+// SetOutput sets where merge progress is written.
+func (q *QueueRunner) SetOutput(w io.Writer) {
+	q.mgr.SetOutput(w)
+	q.eng.SetOutput(w)
+}
+
+// Pending returns the ready MRs in the order the runner will take them.
+func (q *QueueRunner) Pending() ([]*MRInfo, error) {
+	mrs, err := q.eng.ListReadyMRs()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sort.SliceStable(mrs, func(i, j int) bool { return mrs[i].ScoreAt(now) > mrs[j].ScoreAt(now) })
+	order, err := q.mgr.QueueOrder()
+	if err != nil {
+		return nil, err
+	}
+	sortPinned(mrs, func(mr *MRInfo) string { return mr.ID }, order.Pinned)
+	return mrs, nil
+}
+
+// Run works the queue until it is empty, limit MRs have been processed
+// (0: no limit), or ctx is cancelled. An MR that fails stays in the queue
+// but isn't retried in the same run. Returns ErrQueueBusy if another runner
+// holds the queue.
+func (q *QueueRunner) Run(ctx context.Context, limit int) ([]QueueRunResult, error) {
+	if err := q.eng.LoadConfig(); err != nil {
+		return nil, fmt.Errorf("loading merge queue config: %w", err)
+	}
+	if !q.eng.config.Enabled {
+		return nil, fmt.Errorf("%w for %s", ErrQueueDisabled, q.mgr.rig.Name)
+	}
+	lock := flock.New(q.mgr.queueLockFile())
+	if err := os.MkdirAll(filepath.Dir(q.mgr.queueLockFile()), 0755); err != nil {
+		return nil, err
+	}
+	locked, err := lock.TryLock()
+	if err != nil {
+		return nil, fmt.Errorf("locking merge queue: %w", err)
+	}
+	if !locked {
+		return nil, ErrQueueBusy
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	var results []QueueRunResult
+	tried := make(map[string]bool)
+	for (limit <= 0 || len(results) < limit) && ctx.Err() == nil {
+		pending, err := q.Pending()
+		if err != nil {
+			return results, err
+		}
+		var next *MRInfo
+		for _, mr := range pending {
+			if !tried[mr.ID] {
+				next = mr
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		tried[next.ID] = true
+		results = append(results, QueueRunResult{MR: next, Result: q.process(ctx, next)})
+	}
+	return results, nil
+}
+
+// process claims, syncs, merges, and records one MR.
+func (q *QueueRunner) process(ctx context.Context, mr *MRInfo) ProcessResult {
+	if err := q.eng.ClaimMR(mr.ID, q.WorkerID); err != nil {
+		return ProcessResult{Error: fmt.Sprintf("claiming %s: %v", mr.ID, err)}
+	}
+	inProgress := string(MRInProgress)
+	_ = q.eng.beads.Update(mr.ID, beads.UpdateOptions{Status: &inProgress})
+	q.setCurrent(mr)
+	defer q.setCurrent(nil)
+
+	if q.Sync != nil {
+		if err := q.Sync(q.eng.workDir); err != nil {
+			result := ProcessResult{Error: fmt.Sprintf("workspace sync failed: %v", err)}
+			q.release(mr)
+			return result
+		}
+	}
+
+	if mr.Target == "" {
+		mr.Target = q.eng.config.TargetBranch
+	}
+	result := q.eng.ProcessMRInfo(ctx, mr)
+	if result.Success {
+		q.eng.HandleMRInfoSuccess(mr, result)
+		q.mergedAt(time.Now())
+		return result
+	}
+	q.eng.HandleMRInfoFailure(mr, result)
+	q.release(mr)
+	return result
+}
+
+// release returns a failed MR to the queue: unclaimed and open.
+func (q *QueueRunner) release(mr *MRInfo) {
+	if err := q.eng.ReleaseMR(mr.ID); err != nil {
+		_, _ = fmt.Fprintf(q.eng.output, "[Queue] Warning: failed to release %s: %v\n", mr.ID, err)
+	}
+	open := string(MROpen)
+	_ = q.eng.beads.Update(mr.ID, beads.UpdateOptions{Status: &open})
+}
+
+// setCurrent records the MR being merged in the refinery state, for
+// 'gt refinery queue' and status.
+func (q *QueueRunner) setCurrent(mr *MRInfo) {
+	ref, err := q.mgr.loadState()
+	if err != nil {
+		return
+	}
+	ref.CurrentMR = nil
+	if mr != nil {
+		ref.CurrentMR = &MergeRequest{
+			ID:           mr.ID,
+			Branch:       mr.Branch,
+			Worker:       mr.Worker,
+			IssueID:      mr.SourceIssue,
+			TargetBranch: mr.Target,
+			CreatedAt:    mr.CreatedAt,
+			Status:       MRInProgress,
+		}
+	}
+	_ = q.mgr.saveState(ref)
+}
+
+func (q *QueueRunner) mergedAt(t time.Time) {
+	ref, err := q.mgr.loadState()
+	if err != nil {
+		return
+	}
+	ref.LastMergeAt = &t
+	_ = q.mgr.saveState(ref)
+}
@@ -0,0 +1,80 @@
+package refinery
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/flock"
+)
+
+func TestSortPinned(t *testing.T) {
+	// Score order, highest first
+	ids := []string{"mr-a", "mr-b", "mr-c", "mr-d", "mr-e"}
+	sortPinned(ids, func(id string) string { return id }, []string{"mr-d", "mr-b", "mr-gone"})
+	if got := strings.Join(ids, ","); got != "mr-d,mr-b,mr-a,mr-c,mr-e" {
+		t.Errorf("sorted = %s, want pinned first in pinned order, then score order", got)
+	}
+
+	ids = []string{"mr-b", "mr-a"}
+	sortPinned(ids, func(id string) string { return id }, nil)
+	if got := strings.Join(ids, ","); got != "mr-b,mr-a" {
+		t.Errorf("no pins should keep score order, got %s", got)
+	}
+}
+
+func TestQueueOrderPersistence(t *testing.T) {
+	mgr, rigPath := setupTestManager(t)
+
+	order, err := mgr.QueueOrder()
+	if err != nil || len(order.Pinned) != 0 {
+		t.Fatalf("fresh rig: %+v, %v; want empty order", order, err)
+	}
+
+	if err := mgr.saveQueueOrder(&QueueOrder{Pinned: []string{"mr-1", "mr-2"}}); err != nil {
+		t.Fatal(err)
+	}
+	order, err = mgr.QueueOrder()
+	if err != nil || strings.Join(order.Pinned, ",") != "mr-1,mr-2" || order.UpdatedAt.IsZero() {
+		t.Errorf("reloaded order = %+v, %v", order, err)
+	}
+
+	if err := mgr.ResetQueueOrder(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rigPath, ".runtime", "merge-queue.json")); !os.IsNotExist(err) {
+		t.Errorf("reset should remove the order file: %v", err)
+	}
+	if err := mgr.ResetQueueOrder(); err != nil {
+		t.Errorf("resetting twice: %v", err)
+	}
+}
+
+func TestQueueRunnerSerialized(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	runner := NewQueueRunner(mgr.rig)
+
+	// Another worker holds the rig's merge lock
+	lock := flock.New(mgr.queueLockFile())
+	if ok, err := lock.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock: %v, %v", ok, err)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	if _, err := runner.Run(context.Background(), 1); !errors.Is(err, ErrQueueBusy) {
+		t.Errorf("Run with the queue locked: %v, want ErrQueueBusy", err)
+	}
+}
+
+func TestQueueRunnerDisabled(t *testing.T) {
+	mgr, rigPath := setupTestManager(t)
+	if err := os.WriteFile(filepath.Join(rigPath, "config.json"), []byte(`{"merge_queue": {"enabled": false}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewQueueRunner(mgr.rig).Run(context.Background(), 0); !errors.Is(err, ErrQueueDisabled) {
+		t.Errorf("Run with merge_queue disabled: %v, want ErrQueueDisabled", err)
+	}
+}
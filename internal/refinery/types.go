@@ -150,6 +150,10 @@ type QueueItem struct {
 	Position  int       `json:"position"`
 	MR        *MergeRequest `json:"mr"`
 	Age       string    `json:"age"`
+
+	// Pinned is set when the MR's position was set with 'gt refinery
+	// queue move' (see QueueOrder).
+	Pinned bool `json:"pinned,omitempty"`
 }
 
 // State transition errors.
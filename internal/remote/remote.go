@@ -0,0 +1,210 @@
+// Package remote runs gt commands against towns on other machines over SSH.
+//
+// A remote is a named SSH destination (a Host entry from ~/.ssh/config or
+// user@host) plus the town root on that machine. Commands are executed by
+// ssh'ing in, changing to the town root, and running the remote gt, so the
+// remote daemon's lifecycle mail and state are reached exactly as a local
+// operator would reach them. Remotes are stored per user in
+// ~/.config/gastown/remotes.json.
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+// DefaultTown is the town root assumed on a remote when none is configured.
+const DefaultTown = "~/gt"
+
+// ErrNotFound is returned when a named remote isn't configured.
+var ErrNotFound = errors.New("remote not found")
+
+// validName matches remote names: short identifiers safe in messages and paths.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Remote is a town on another machine.
+type Remote struct {
+	Name string `json:"name"`
+
+	// Host is the ssh destination: a Host alias from ~/.ssh/config or
+	// user@hostname. SSH options (port, key, jump host) belong in ssh config.
+	Host string `json:"host"`
+
+	// Town is the town root on the remote machine. A leading "~/" is
+	// expanded by the remote shell. Default DefaultTown.
+	Town string `json:"town,omitempty"`
+
+	// GT is the gt binary on the remote machine. Default "gt" (on PATH).
+	GT string `json:"gt,omitempty"`
+}
+
+// Config is the set of configured remotes.
+type Config struct {
+	Remotes map[string]*Remote `json:"remotes"`
+}
+
+// ConfigPath returns the path to the remotes file.
+func ConfigPath() string {
+	return filepath.Join(state.ConfigDir(), "remotes.json")
+}
+
+// Load reads the configured remotes. A missing file is an empty config.
+func Load() (*Config, error) {
+	cfg := &Config{Remotes: map[string]*Remote{}}
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ConfigPath(), err)
+	}
+	if cfg.Remotes == nil {
+		cfg.Remotes = map[string]*Remote{}
+	}
+	return cfg, nil
+}
+
+// Save writes the configured remotes.
+func (c *Config) Save() error {
+	path := ConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Add validates r and adds or replaces it.
+func (c *Config) Add(r *Remote) error {
+	if !validName.MatchString(r.Name) {
+		return fmt.Errorf("invalid remote name %q", r.Name)
+	}
+	if r.Host == "" || strings.HasPrefix(r.Host, "-") {
+		return fmt.Errorf("invalid ssh host %q", r.Host)
+	}
+	c.Remotes[r.Name] = r
+	return nil
+}
+
+// Get returns the named remote.
+func (c *Config) Get(name string) (*Remote, error) {
+	r, ok := c.Remotes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return r, nil
+}
+
+// Names returns the configured remote names, sorted.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Remotes))
+	for name := range c.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TownRoot returns the configured town root or DefaultTown.
+func (r *Remote) TownRoot() string {
+	if r.Town != "" {
+		return r.Town
+	}
+	return DefaultTown
+}
+
+func (r *Remote) gtBinary() string {
+	if r.GT != "" {
+		return r.GT
+	}
+	return "gt"
+}
+
+// Script returns the shell command run on the remote host for a gt
+// invocation: change to the town root, set env, and exec gt with args.
+// Every argument is quoted for the remote shell.
+func (r *Remote) Script(env map[string]string, args ...string) string {
+	var b strings.Builder
+	b.WriteString("cd ")
+	b.WriteString(quotePath(r.TownRoot()))
+	b.WriteString(" && exec")
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		b.WriteString(" env")
+		for _, k := range keys {
+			b.WriteString(" ")
+			b.WriteString(Quote(k + "=" + env[k]))
+		}
+	}
+
+	b.WriteString(" ")
+	b.WriteString(quotePath(r.gtBinary()))
+	for _, arg := range args {
+		b.WriteString(" ")
+		b.WriteString(Quote(arg))
+	}
+	return b.String()
+}
+
+// Command returns an ssh command that runs gt with args in the remote town.
+// tty allocates a terminal, needed for interactive commands like attach.
+func (r *Remote) Command(tty bool, env map[string]string, args ...string) *exec.Cmd {
+	sshArgs := []string{}
+	if tty {
+		sshArgs = append(sshArgs, "-t")
+	}
+	sshArgs = append(sshArgs, "--", r.Host, r.Script(env, args...))
+	return exec.Command("ssh", sshArgs...)
+}
+
+// Quote quotes s for a POSIX shell.
+func Quote(s string) string {
+	if s != "" && !strings.ContainsFunc(s, needsQuote) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func needsQuote(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("-_./=:@,+%", r)
+}
+
+// quotePath quotes a path but leaves a leading "~/" for the remote shell
+// to expand to the remote user's home.
+func quotePath(p string) string {
+	if p == "~" {
+		return `"$HOME"`
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return `"$HOME"/` + Quote(rest)
+	}
+	return Quote(p)
+}
@@ -0,0 +1,82 @@
+package remote
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"status":           "status",
+		"gastown/crew/max": "gastown/crew/max",
+		"":                 "''",
+		"two words":        "'two words'",
+		"it's":             `'it'\''s'`,
+		"$(rm -rf /)":      "'$(rm -rf /)'",
+		`{"a":1}`:          `'{"a":1}'`,
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	r := &Remote{Name: "vm", Host: "gt-vm"}
+	got := r.Script(map[string]string{"GT_ROLE": "mayor"}, "mail", "send", "deacon/", "-s", "LIFECYCLE: batch")
+	want := `cd "$HOME"/gt && exec env GT_ROLE=mayor gt mail send deacon/ -s 'LIFECYCLE: batch'`
+	if got != want {
+		t.Errorf("Script =\n  %s\nwant\n  %s", got, want)
+	}
+
+	r = &Remote{Name: "vm", Host: "gt-vm", Town: "/srv/my town", GT: "/opt/gt/bin/gt"}
+	got = r.Script(nil, "status")
+	want = `cd '/srv/my town' && exec /opt/gt/bin/gt status`
+	if got != want {
+		t.Errorf("Script =\n  %s\nwant\n  %s", got, want)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	r := &Remote{Name: "vm", Host: "ops@vm.example.com"}
+	cmd := r.Command(true, nil, "agents", "attach", "mayor")
+	want := []string{"ssh", "-t", "--", "ops@vm.example.com", `cd "$HOME"/gt && exec gt agents attach mayor`}
+	if !slices.Equal(cmd.Args, want) {
+		t.Errorf("Args = %q, want %q", cmd.Args, want)
+	}
+}
+
+func TestConfigLoadSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load empty: %v", err)
+	}
+	if err := cfg.Add(&Remote{Name: "bad name", Host: "h"}); err == nil {
+		t.Error("expected invalid name to be rejected")
+	}
+	if err := cfg.Add(&Remote{Name: "vm", Host: "-oProxyCommand=x"}); err == nil {
+		t.Error("expected option-like host to be rejected")
+	}
+	if err := cfg.Add(&Remote{Name: "vm", Host: "gt-vm", Town: "/srv/gt"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r, err := cfg.Get("vm")
+	if err != nil || r.Host != "gt-vm" || r.TownRoot() != "/srv/gt" {
+		t.Errorf("Get(vm) = %+v, %v", r, err)
+	}
+	if _, err := cfg.Get("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(nope) err = %v, want ErrNotFound", err)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/steveyegge/gastown/internal/constants"
 	"github.com/steveyegge/gastown/internal/config"
 	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/vcs"
 )
 
 // Common errors
@@ -80,6 +81,7 @@ type RigConfig struct {
 	DefaultBranch string       `json:"default_branch,omitempty"` // main, master, etc.
 	CreatedAt     time.Time    `json:"created_at"`               // when rig was created
 	Beads         *BeadsConfig `json:"beads,omitempty"`
+	VCS           *vcs.Config  `json:"vcs,omitempty"` // remote/branch/kind for workspace sync
 }
 
 // BeadsConfig represents beads configuration for the rig.
@@ -247,6 +249,17 @@ func resolveLocalRepo(path, gitURL string) (string, string) {
 	return absPath, ""
 }
 
+// validateRigName rejects characters that break agent ID parsing.
+// Agent IDs use format <prefix>-<rig>-<role>[-<name>] with hyphens as delimiters.
+func validateRigName(name string) error {
+	if strings.ContainsAny(name, "-. ") {
+		sanitized := strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(name)
+		sanitized = strings.ToLower(sanitized)
+		return fmt.Errorf("rig name %q contains invalid characters; hyphens, dots, and spaces are reserved for agent ID parsing. Try %q instead (underscores are allowed)", name, sanitized)
+	}
+	return nil
+}
+
 // AddRig creates a new rig as a container with clones for each agent.
 // The rig structure is:
 //
@@ -263,12 +276,8 @@ func (m *Manager) AddRig(opts AddRigOptions) (*Rig, error) {
 		return nil, ErrRigExists
 	}
 
-	// Validate rig name: reject characters that break agent ID parsing
-	// Agent IDs use format <prefix>-<rig>-<role>[-<name>] with hyphens as delimiters
-	if strings.ContainsAny(opts.Name, "-. ") {
-		sanitized := strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(opts.Name)
-		sanitized = strings.ToLower(sanitized)
-		return nil, fmt.Errorf("rig name %q contains invalid characters; hyphens, dots, and spaces are reserved for agent ID parsing. Try %q instead (underscores are allowed)", opts.Name, sanitized)
+	if err := validateRigName(opts.Name); err != nil {
+		return nil, err
 	}
 
 	rigPath := filepath.Join(m.townRoot, opts.Name)
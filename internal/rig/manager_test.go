@@ -743,3 +743,66 @@ func TestConvertToSSH(t *testing.T) {
 		})
 	}
 }
+
+func TestRenameRig(t *testing.T) {
+	root, rigsConfig := setupTestTown(t)
+	rigsConfig.Rigs["gastown"] = config.RigEntry{GitURL: "git@example.com:gastown.git"}
+	createTestRig(t, root, "gastown")
+	if err := os.WriteFile(filepath.Join(root, "gastown", "config.json"), []byte(`{"type":"rig","name":"gastown"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".beads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	routes := `{"prefix":"hq-","path":"."}` + "\n" + `{"prefix":"gt-","path":"gastown/mayor/rig"}` + "\n"
+	if err := os.WriteFile(filepath.Join(root, ".beads", "routes.jsonl"), []byte(routes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(root, rigsConfig, git.NewGit(root))
+	if _, err := manager.RenameRig("gastown", "gt_core"); err != nil {
+		t.Fatalf("RenameRig: %v", err)
+	}
+
+	if manager.RigExists("gastown") || !manager.RigExists("gt_core") {
+		t.Errorf("registry = %v, want only gt_core", rigsConfig.Rigs)
+	}
+	if rigsConfig.Rigs["gt_core"].GitURL != "git@example.com:gastown.git" {
+		t.Error("registry entry not carried over")
+	}
+	if got := rigsConfig.ResolveRig("gastown"); got != "gt_core" {
+		t.Errorf("ResolveRig(gastown) = %q, want gt_core", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "gt_core", "polecats", "Toast")); err != nil {
+		t.Errorf("rig directory not moved: %v", err)
+	}
+	cfg, err := LoadRigConfig(filepath.Join(root, "gt_core"))
+	if err != nil || cfg.Name != "gt_core" {
+		t.Errorf("config.json name = %v (%v), want gt_core", cfg, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, ".beads", "routes.jsonl"))
+	if !strings.Contains(string(data), `"gt_core/mayor/rig"`) || strings.Contains(string(data), `"gastown/`) {
+		t.Errorf("routes not updated:\n%s", data)
+	}
+}
+
+func TestRenameRigRejects(t *testing.T) {
+	root, rigsConfig := setupTestTown(t)
+	rigsConfig.Rigs["gastown"] = config.RigEntry{}
+	rigsConfig.Rigs["beads"] = config.RigEntry{}
+	createTestRig(t, root, "gastown")
+	manager := NewManager(root, rigsConfig, git.NewGit(root))
+
+	if _, err := manager.RenameRig("missing", "other"); err != ErrRigNotFound {
+		t.Errorf("missing rig: err = %v, want ErrRigNotFound", err)
+	}
+	if _, err := manager.RenameRig("gastown", "beads"); err != ErrRigExists {
+		t.Errorf("existing name: err = %v, want ErrRigExists", err)
+	}
+	if _, err := manager.RenameRig("gastown", "gt-core"); err == nil {
+		t.Error("invalid name: expected error")
+	}
+	if _, err := os.Stat(filepath.Join(root, "gastown")); err != nil {
+		t.Errorf("rejected rename moved the rig: %v", err)
+	}
+}
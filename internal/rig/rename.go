@@ -0,0 +1,148 @@
+package rig
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+// worktreeSearchDepth is how deep under a rig RenameRig looks for linked
+// worktrees (e.g. polecats/<name>/<repo> is three levels down).
+const worktreeSearchDepth = 4
+
+// RenameRig renames a registered rig: it moves the rig directory, updates
+// the rig's config.json and the town's beads routes, moves the registry
+// entry, and records oldName as an alias of newName so identities and
+// addresses using the old name still resolve. Git worktrees inside the rig are repaired after the move; repair
+// failures are returned as warnings since the rename itself succeeded.
+//
+// The caller saves the registry. If RenameRig fails, nothing was changed.
+func (m *Manager) RenameRig(oldName, newName string) (warnings []string, err error) {
+	entry, ok := m.config.Rigs[oldName]
+	if !ok {
+		return nil, ErrRigNotFound
+	}
+	if m.RigExists(newName) {
+		return nil, ErrRigExists
+	}
+	if err := validateRigName(newName); err != nil {
+		return nil, err
+	}
+	oldPath := filepath.Join(m.townRoot, oldName)
+	newPath := filepath.Join(m.townRoot, newName)
+	if _, err := os.Stat(newPath); err == nil {
+		return nil, fmt.Errorf("directory already exists: %s", newPath)
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return nil, fmt.Errorf("moving rig directory: %w", err)
+	}
+	if err := renameRoutes(m.townRoot, oldName, newName); err != nil {
+		_ = os.Rename(newPath, oldPath)
+		return nil, fmt.Errorf("updating beads routes: %w", err)
+	}
+	if cfg, err := LoadRigConfig(newPath); err == nil {
+		cfg.Name = newName
+		if err := m.saveRigConfig(newPath, cfg); err != nil {
+			_ = renameRoutes(m.townRoot, newName, oldName)
+			_ = os.Rename(newPath, oldPath)
+			return nil, fmt.Errorf("updating rig config: %w", err)
+		}
+	}
+
+	delete(m.config.Rigs, oldName)
+	m.config.Rigs[newName] = entry
+	m.config.AddAlias(oldName, newName)
+
+	return repairWorktrees(newPath, oldPath), nil
+}
+
+// renameRoutes points the town's beads routes into oldName at newName.
+func renameRoutes(townRoot, oldName, newName string) error {
+	beadsDir := filepath.Join(townRoot, ".beads")
+	routes, err := beads.LoadRoutes(beadsDir)
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+	changed := false
+	for i, r := range routes {
+		if r.Path == oldName {
+			routes[i].Path, changed = newName, true
+		} else if rest, ok := strings.CutPrefix(r.Path, oldName+"/"); ok {
+			routes[i].Path, changed = newName+"/"+rest, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return beads.WriteRoutes(beadsDir, routes)
+}
+
+// repairWorktrees re-links the git worktrees under rigPath, which was moved
+// from oldPath, to their main repositories. Returns one warning per
+// repository that couldn't be repaired.
+func repairWorktrees(rigPath, oldPath string) []string {
+	byMain := make(map[string][]string)
+	_ = filepath.WalkDir(rigPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			rel, _ := filepath.Rel(rigPath, path)
+			if entry.Name() == ".git" || entry.Name() == "node_modules" || strings.Count(rel, string(filepath.Separator)) >= worktreeSearchDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != ".git" {
+			return nil
+		}
+		if main := worktreeMainRepo(path, rigPath, oldPath); main != "" {
+			byMain[main] = append(byMain[main], filepath.Dir(path))
+		}
+		return nil
+	})
+
+	mains := make([]string, 0, len(byMain))
+	for main := range byMain {
+		mains = append(mains, main)
+	}
+	sort.Strings(mains)
+	var warnings []string
+	for _, main := range mains {
+		if err := git.NewGit(main).WorktreeRepair(byMain[main]...); err != nil {
+			warnings = append(warnings, fmt.Sprintf("repairing worktrees of %s: %v", main, err))
+		}
+	}
+	return warnings
+}
+
+// worktreeMainRepo returns the current location of the main repository of
+// the linked worktree whose .git file is at gitFile, or "" if gitFile isn't
+// a worktree link. Main repositories that moved with the rig are mapped
+// from oldPath to rigPath.
+func worktreeMainRepo(gitFile, rigPath, oldPath string) string {
+	data, err := os.ReadFile(gitFile) //nolint:gosec // G304: path found under the rig directory
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	main, _, ok := strings.Cut(filepath.ToSlash(gitDir), "/.git/worktrees/")
+	if !ok {
+		return ""
+	}
+	main = filepath.FromSlash(main)
+	if rel, err := filepath.Rel(oldPath, main); err == nil && !strings.HasPrefix(rel, "..") {
+		main = filepath.Join(rigPath, rel)
+	}
+	return main
+}
@@ -0,0 +1,111 @@
+package rollup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds each call to the aggregation service.
+const requestTimeout = 15 * time.Second
+
+// Client talks to the central aggregation service.
+//
+// The service API:
+//
+//	POST /v1/snapshots   body: Snapshot          -> 202 Accepted
+//	GET  /v1/summary                             -> 200 OrgSummary
+//
+// Both endpoints take "Authorization: Bearer <token>" when a token is set.
+type Client struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// OrgSummary is the aggregation service's rollup across every reporting town.
+type OrgSummary struct {
+	Towns        int          `json:"towns"`
+	Agents       AgentMetrics `json:"agents"`
+	Availability float64      `json:"availability"`
+	CostMonthUSD float64      `json:"cost_month_usd"`
+	Throughput   Throughput   `json:"throughput"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// NewClient creates a client for the service at endpoint.
+func NewClient(endpoint, token string) *Client {
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		http:     &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Push sends one snapshot to the service.
+func (c *Client) Push(ctx context.Context, snapshot *Snapshot) error {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/v1/snapshots", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp)
+}
+
+// Summary fetches the organization-wide rollup.
+func (c *Client) Summary(ctx context.Context) (*OrgSummary, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/summary", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var summary OrgSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("decoding summary: %w", err)
+	}
+	return &summary, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// checkStatus turns a non-2xx response into an error with the service's message.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("aggregation service returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,202 @@
+// Package rollup exports anonymized town summary metrics to a central
+// aggregation service, for organizations that run many towns and want one
+// dashboard across them.
+//
+// Rollup is opt-in. It is configured in the "rollup" section of
+// mayor/daemon.json:
+//
+//	"rollup": {
+//	  "enabled": true,
+//	  "endpoint": "https://metrics.example.com",
+//	  "interval": "1h",
+//	  "token_env": "GT_ROLLUP_TOKEN"
+//	}
+//
+// Snapshots carry counts and totals only. The town is identified by a random
+// ID generated on first push; no town, rig, agent, or bead names leave the
+// machine.
+package rollup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// SchemaVersion is the snapshot schema version sent to the aggregation service.
+const SchemaVersion = 1
+
+const (
+	// DefaultInterval is how often the daemon pushes a snapshot.
+	DefaultInterval = time.Hour
+
+	// DefaultTokenEnv is the environment variable holding the service token.
+	DefaultTokenEnv = "GT_ROLLUP_TOKEN"
+
+	// idFile stores the town's anonymous rollup ID, relative to the town root.
+	idFile = ".runtime/rollup-id"
+)
+
+// Config is the rollup section of the daemon config.
+type Config struct {
+	// Enabled turns on periodic pushes. Default: false.
+	Enabled bool `json:"enabled"`
+
+	// Endpoint is the base URL of the aggregation service.
+	Endpoint string `json:"endpoint"`
+
+	// Interval between pushes (Go duration string, default "1h").
+	Interval string `json:"interval,omitempty"`
+
+	// TokenEnv names the environment variable holding the bearer token
+	// (default GT_ROLLUP_TOKEN). The token itself never goes in config.
+	TokenEnv string `json:"token_env,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *Config) Validate() error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+	if c.Endpoint == "" {
+		return errors.New("rollup: endpoint is required when enabled")
+	}
+	if !strings.HasPrefix(c.Endpoint, "https://") && !strings.HasPrefix(c.Endpoint, "http://") {
+		return fmt.Errorf("rollup: endpoint %q must be an http(s) URL", c.Endpoint)
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("rollup: invalid interval %q", c.Interval)
+		}
+	}
+	return nil
+}
+
+// PushInterval returns the configured interval, or DefaultInterval.
+func (c *Config) PushInterval() time.Duration {
+	if c != nil && c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err == nil && d > 0 {
+			return d
+		}
+	}
+	return DefaultInterval
+}
+
+// Token returns the bearer token from the configured environment variable.
+func (c *Config) Token() string {
+	name := DefaultTokenEnv
+	if c != nil && c.TokenEnv != "" {
+		name = c.TokenEnv
+	}
+	return os.Getenv(name)
+}
+
+// Snapshot is one anonymized summary of a town.
+type Snapshot struct {
+	SchemaVersion int       `json:"schema_version"`
+	TownID        string    `json:"town_id"`
+	CollectedAt   time.Time `json:"collected_at"`
+
+	// WindowHours is the lookback for Throughput.
+	WindowHours int `json:"window_hours"`
+
+	Agents AgentMetrics `json:"agents"`
+
+	// Availability is the fraction of persistent agents with a live session.
+	Availability float64 `json:"availability"`
+
+	// CostMonthUSD is the town's month-to-date spend.
+	CostMonthUSD float64 `json:"cost_month_usd"`
+
+	Throughput Throughput `json:"throughput"`
+}
+
+// AgentMetrics counts agents by role. Role names are generic (witness,
+// crew, ...) and carry no identifying information.
+type AgentMetrics struct {
+	Total   int            `json:"total"`
+	Running int            `json:"running"`
+	ByRole  map[string]int `json:"by_role,omitempty"`
+}
+
+// Throughput counts work events in the snapshot window.
+type Throughput struct {
+	Slung       int `json:"slung"`
+	Done        int `json:"done"`
+	Merged      int `json:"merged"`
+	MergeFailed int `json:"merge_failed"`
+}
+
+// TownID returns the town's anonymous rollup ID, generating and persisting
+// a random one on first use. The ID is not derived from the town's name or
+// path, so the service can correlate snapshots without learning either.
+func TownID(townRoot string) (string, error) {
+	path := filepath.Join(townRoot, idFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+
+	id := uuid.NewString()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("writing rollup id: %w", err)
+	}
+	return id, nil
+}
+
+// CountThroughput counts work events in the town's event log since the given time.
+func CountThroughput(townRoot string, since time.Time) (Throughput, error) {
+	var t Throughput
+
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return t, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(events.EventsFile)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return t, nil
+		}
+		return t, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil || ts.Before(since) {
+			continue
+		}
+		switch event.Type {
+		case events.TypeSling:
+			t.Slung++
+		case events.TypeDone:
+			t.Done++
+		case events.TypeMerged:
+			t.Merged++
+		case events.TypeMergeFailed:
+			t.MergeFailed++
+		}
+	}
+	return t, scanner.Err()
+}
@@ -0,0 +1,117 @@
+package rollup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTownIDStable(t *testing.T) {
+	townRoot := t.TempDir()
+	first, err := TownID(townRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := TownID(townRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "" || first != second {
+		t.Errorf("TownID() = %q then %q, expected a stable non-empty ID", first, second)
+	}
+	if strings.Contains(first, filepath.Base(townRoot)) {
+		t.Errorf("TownID %q leaks the town directory name", first)
+	}
+}
+
+func TestCountThroughput(t *testing.T) {
+	townRoot := t.TempDir()
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour).Format(time.RFC3339)
+	recent := now.Add(-time.Hour).Format(time.RFC3339)
+	lines := []string{
+		`{"ts":"` + recent + `","type":"sling"}`,
+		`{"ts":"` + recent + `","type":"done"}`,
+		`{"ts":"` + recent + `","type":"merged"}`,
+		`{"ts":"` + old + `","type":"merged"}`,
+		`not json`,
+	}
+	if err := os.WriteFile(filepath.Join(townRoot, ".events.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CountThroughput(townRoot, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Throughput{Slung: 1, Done: 1, Merged: 1}
+	if got != want {
+		t.Errorf("CountThroughput() = %+v, expected %+v", got, want)
+	}
+}
+
+func TestClientPushAndSummary(t *testing.T) {
+	var pushed Snapshot
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/snapshots":
+			_ = json.NewDecoder(r.Body).Decode(&pushed)
+			w.WriteHeader(http.StatusAccepted)
+		case "/v1/summary":
+			_ = json.NewEncoder(w).Encode(OrgSummary{Towns: 3, CostMonthUSD: 42})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "secret")
+	snapshot := &Snapshot{SchemaVersion: SchemaVersion, TownID: "abc", Agents: AgentMetrics{Total: 4, Running: 3}}
+	if err := client.Push(context.Background(), snapshot); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if pushed.TownID != "abc" || pushed.Agents.Running != 3 {
+		t.Errorf("server received %+v", pushed)
+	}
+
+	summary, err := client.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if summary.Towns != 3 || summary.CostMonthUSD != 42 {
+		t.Errorf("Summary() = %+v", summary)
+	}
+
+	if err := NewClient(server.URL, "wrong").Push(context.Background(), snapshot); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Push with bad token: err = %v, expected 401", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg   *Config
+		valid bool
+	}{
+		{nil, true},
+		{&Config{Enabled: false}, true},
+		{&Config{Enabled: true}, false},
+		{&Config{Enabled: true, Endpoint: "ftp://x"}, false},
+		{&Config{Enabled: true, Endpoint: "https://x", Interval: "soon"}, false},
+		{&Config{Enabled: true, Endpoint: "https://x", Interval: "30m"}, true},
+	}
+	for _, tc := range tests {
+		if err := tc.cfg.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate(%+v) = %v, expected valid=%v", tc.cfg, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package scorecard aggregates per-agent performance metrics from the town's
+// event log and cost ledger, so the mayor can compare agents and roles on
+// throughput, rework, and cost when deciding what to scale up.
+//
+// Metrics are derived from work events:
+//
+//   - completed: done events by the agent in the window
+//   - reworked: completed beads that were slung again after the agent's done
+//   - cycles: sling events per completed bead (1 = done on first assignment)
+//   - cost per bead: the agent's session spend divided by completed beads
+package scorecard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// Scorecard is one agent's (or one role's) metrics for a window.
+type Scorecard struct {
+	Agent string `json:"agent,omitempty"`
+	Role  string `json:"role"`
+
+	// Assigned counts sling events targeting the agent.
+	Assigned int `json:"assigned"`
+
+	// Completed counts done events by the agent.
+	Completed int `json:"completed"`
+
+	// Reworked counts completed beads that were slung again afterwards.
+	Reworked int `json:"reworked"`
+
+	// ReworkRate is Reworked / Completed.
+	ReworkRate float64 `json:"rework_rate"`
+
+	// AvgCycles is the mean number of slings per completed bead.
+	AvgCycles float64 `json:"avg_cycles"`
+
+	CostUSD     float64 `json:"cost_usd"`
+	CostPerBead float64 `json:"cost_per_bead_usd,omitempty"`
+
+	cycles int
+}
+
+// Report is a set of scorecards for one window.
+type Report struct {
+	Since  time.Time   `json:"since"`
+	Until  time.Time   `json:"until"`
+	Agents []Scorecard `json:"agents"`
+	ByRole []Scorecard `json:"by_role"`
+}
+
+// LoadEvents reads the town's event log. A town without an event log yields
+// no events and no error.
+func LoadEvents(townRoot string) ([]events.Event, error) {
+	store, err := storage.ForTown(townRoot, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	data, err := store.Get(events.EventsFile)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var evts []events.Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		evts = append(evts, event)
+	}
+	return evts, scanner.Err()
+}
+
+// Build computes scorecards from events in [since, until). costs maps agent
+// addresses to spend over the same window; keys are normalized with
+// NormalizeAgent, so ledger paths and mail addresses both match.
+func Build(evts []events.Event, costs map[string]float64, since, until time.Time) *Report {
+	type completion struct {
+		bead  string
+		agent string
+		at    time.Time
+	}
+
+	cards := make(map[string]*Scorecard)
+	card := func(agent string) *Scorecard {
+		c, ok := cards[agent]
+		if !ok {
+			c = &Scorecard{Agent: agent, Role: AgentRole(agent)}
+			cards[agent] = c
+		}
+		return c
+	}
+
+	slings := make(map[string][]time.Time) // bead -> sling times
+	var done []completion
+
+	for _, e := range evts {
+		ts, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil || ts.Before(since) || !ts.Before(until) {
+			continue
+		}
+		bead, _ := e.Payload["bead"].(string)
+		switch e.Type {
+		case events.TypeSling:
+			if target, _ := e.Payload["target"].(string); target != "" {
+				card(NormalizeAgent(target)).Assigned++
+			}
+			if bead != "" {
+				slings[bead] = append(slings[bead], ts)
+			}
+		case events.TypeDone:
+			agent := NormalizeAgent(e.Actor)
+			if agent == "" {
+				continue
+			}
+			card(agent).Completed++
+			if bead != "" {
+				done = append(done, completion{bead: bead, agent: agent, at: ts})
+			}
+		}
+	}
+
+	for _, d := range done {
+		c := cards[d.agent]
+		cycles, reslung := 0, false
+		for _, ts := range slings[d.bead] {
+			if ts.After(d.at) {
+				reslung = true
+			} else {
+				cycles++
+			}
+		}
+		if reslung {
+			c.Reworked++
+		}
+		if cycles == 0 {
+			cycles = 1 // slung before the window opened
+		}
+		c.cycles += cycles
+	}
+
+	for agent, usd := range costs {
+		if agent = NormalizeAgent(agent); agent != "" {
+			card(agent).CostUSD += usd
+		}
+	}
+
+	report := &Report{Since: since, Until: until}
+	roles := make(map[string]*Scorecard)
+	for _, c := range cards {
+		r, ok := roles[c.Role]
+		if !ok {
+			r = &Scorecard{Role: c.Role}
+			roles[c.Role] = r
+		}
+		r.Assigned += c.Assigned
+		r.Completed += c.Completed
+		r.Reworked += c.Reworked
+		r.CostUSD += c.CostUSD
+		r.cycles += c.cycles
+
+		c.finish()
+		report.Agents = append(report.Agents, *c)
+	}
+	for _, r := range roles {
+		r.finish()
+		report.ByRole = append(report.ByRole, *r)
+	}
+
+	sort.Slice(report.Agents, func(i, j int) bool {
+		a, b := report.Agents[i], report.Agents[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		return a.Agent < b.Agent
+	})
+	sort.Slice(report.ByRole, func(i, j int) bool { return report.ByRole[i].Role < report.ByRole[j].Role })
+	return report
+}
+
+// finish derives the ratio fields from the counts.
+func (c *Scorecard) finish() {
+	if c.Completed == 0 {
+		return
+	}
+	c.ReworkRate = float64(c.Reworked) / float64(c.Completed)
+	c.AvgCycles = float64(c.cycles) / float64(c.Completed)
+	c.CostPerBead = c.CostUSD / float64(c.Completed)
+}
+
+// NormalizeAgent maps the address forms agents appear under to one key:
+// trailing slashes are dropped and "rig/polecats/name" becomes "rig/name",
+// matching the mail address polecats sign their done events with.
+func NormalizeAgent(addr string) string {
+	addr = strings.TrimSuffix(strings.TrimSpace(addr), "/")
+	parts := strings.Split(addr, "/")
+	if len(parts) == 3 && parts[1] == "polecats" {
+		return parts[0] + "/" + parts[2]
+	}
+	return addr
+}
+
+// AgentRole returns the role for a normalized agent address.
+func AgentRole(agent string) string {
+	parts := strings.Split(agent, "/")
+	switch {
+	case len(parts) == 1:
+		return parts[0] // mayor, deacon, overseer
+	case len(parts) == 3 && parts[1] == "crew":
+		return "crew"
+	case len(parts) == 2 && (parts[1] == "witness" || parts[1] == "refinery"):
+		return parts[1]
+	case len(parts) == 2:
+		return "polecat"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,80 @@
+package scorecard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/events"
+)
+
+func TestBuild(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	at := func(m int) string { return base.Add(time.Duration(m) * time.Minute).Format(time.RFC3339) }
+	sling := func(m int, bead, target string) events.Event {
+		return events.Event{Timestamp: at(m), Type: events.TypeSling, Actor: "mayor", Payload: events.SlingPayload(bead, target)}
+	}
+	done := func(m int, bead, actor string) events.Event {
+		return events.Event{Timestamp: at(m), Type: events.TypeDone, Actor: actor, Payload: events.DonePayload(bead, "")}
+	}
+
+	evts := []events.Event{
+		sling(-120, "gt-old", "gastown/polecats/Toast"), // before window
+		sling(1, "gt-a", "gastown/polecats/Toast"),
+		done(10, "gt-a", "gastown/Toast"),
+		sling(20, "gt-a", "gastown/polecats/Nux"), // rework of Toast's gt-a
+		done(30, "gt-a", "gastown/Nux"),
+		sling(2, "gt-b", "gastown/polecats/Toast"),
+		done(40, "gt-b", "gastown/Toast"),
+		sling(3, "gt-c", "gastown/crew/joe"),
+		sling(4, "gt-c", "gastown/crew/joe"),
+		done(50, "gt-c", "gastown/crew/joe"),
+	}
+	costs := map[string]float64{
+		"gastown/polecats/Toast": 4,
+		"gastown/crew/joe":       3,
+	}
+
+	r := Build(evts, costs, base, base.Add(time.Hour))
+
+	byAgent := make(map[string]Scorecard)
+	for _, c := range r.Agents {
+		byAgent[c.Agent] = c
+	}
+
+	toast := byAgent["gastown/Toast"]
+	if toast.Role != "polecat" || toast.Assigned != 2 || toast.Completed != 2 || toast.Reworked != 1 {
+		t.Errorf("toast = %+v", toast)
+	}
+	if toast.ReworkRate != 0.5 || toast.AvgCycles != 1 || toast.CostPerBead != 2 {
+		t.Errorf("toast ratios = rework %v cycles %v cost/bead %v", toast.ReworkRate, toast.AvgCycles, toast.CostPerBead)
+	}
+
+	// Nux's completion of gt-a counts both slings of the bead as cycles.
+	if nux := byAgent["gastown/Nux"]; nux.Completed != 1 || nux.AvgCycles != 2 || nux.Reworked != 0 {
+		t.Errorf("nux = %+v", nux)
+	}
+	if joe := byAgent["gastown/crew/joe"]; joe.Role != "crew" || joe.AvgCycles != 2 || joe.CostUSD != 3 {
+		t.Errorf("joe = %+v", joe)
+	}
+	if r.Agents[0].Agent != "gastown/Toast" {
+		t.Errorf("first agent = %s, want most completions first", r.Agents[0].Agent)
+	}
+
+	if len(r.ByRole) != 2 || r.ByRole[0].Role != "crew" || r.ByRole[1].Completed != 3 {
+		t.Errorf("by role = %+v", r.ByRole)
+	}
+}
+
+func TestNormalizeAgent(t *testing.T) {
+	tests := map[string]string{
+		"mayor/":                 "mayor",
+		"gastown/polecats/Toast": "gastown/Toast",
+		"gastown/crew/joe":       "gastown/crew/joe",
+		"gastown/witness":        "gastown/witness",
+	}
+	for in, want := range tests {
+		if got := NormalizeAgent(in); got != want {
+			t.Errorf("NormalizeAgent(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
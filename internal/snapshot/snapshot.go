@@ -0,0 +1,443 @@
+// Package snapshot archives a town's control state so the town can be moved
+// to another host: the daemon's store records and journals, agent state
+// files, a bead export per beads database (agent beads and mail live there),
+// and the agent sessions that were running.
+//
+// A snapshot is a gzipped tar with manifest.json first, followed by one
+// entry per archived item. Workspaces themselves (rig clones, crew
+// worktrees) are not included; they are cloned again on the new host.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// FormatVersion is the snapshot format written by this version of gt.
+const FormatVersion = 1
+
+// ManifestName is the archive entry holding the manifest.
+const ManifestName = "manifest.json"
+
+// Entry kinds.
+const (
+	// KindStore is a town store record, restored with Put.
+	KindStore = "store"
+
+	// KindFile is a file relative to the town root.
+	KindFile = "file"
+
+	// KindBeads is a JSONL bead export of the beads database used by a
+	// workspace (relative to the town root, "." for the town itself).
+	KindBeads = "beads"
+)
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Host      string    `json:"host,omitempty"`
+
+	// TownRoot is where the town lived on the snapshotted host.
+	TownRoot string `json:"town_root"`
+
+	// Agents lists the town's agents and whether their session was running.
+	Agents []Agent `json:"agents,omitempty"`
+
+	Entries []Entry `json:"entries"`
+}
+
+// Agent is one agent's session at snapshot time.
+type Agent struct {
+	Identity string `json:"identity"`
+	Session  string `json:"session"`
+	Running  bool   `json:"running"`
+}
+
+// Entry is one archived item.
+type Entry struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// archiveName is the tar entry name of an item.
+func (e Entry) archiveName() string {
+	return e.Kind + "/" + e.Path
+}
+
+// Snapshot is a manifest with its entries' contents.
+type Snapshot struct {
+	Manifest
+	data map[string][]byte // by archive name
+}
+
+// New returns an empty snapshot of the town at townRoot.
+func New(townRoot string, now time.Time) *Snapshot {
+	host, _ := os.Hostname()
+	return &Snapshot{
+		Manifest: Manifest{Version: FormatVersion, CreatedAt: now.UTC(), Host: host, TownRoot: townRoot},
+		data:     make(map[string][]byte),
+	}
+}
+
+// Add archives data as an entry of the given kind. Paths are slash
+// separated and relative; adding a path twice replaces it.
+func (s *Snapshot) Add(kind, p string, data []byte) error {
+	clean := path.Clean(p)
+	if p == "" || path.IsAbs(p) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("snapshot: invalid %s path %q", kind, p)
+	}
+	sum := sha256.Sum256(data)
+	e := Entry{Kind: kind, Path: clean, Size: len(data), SHA256: hex.EncodeToString(sum[:])}
+	for i := range s.Entries {
+		if s.Entries[i].Kind == kind && s.Entries[i].Path == clean {
+			s.Entries[i] = e
+			s.data[e.archiveName()] = data
+			return nil
+		}
+	}
+	s.Entries = append(s.Entries, e)
+	s.data[e.archiveName()] = data
+	return nil
+}
+
+func (s *Snapshot) has(kind, p string) bool {
+	_, ok := s.data[kind+"/"+p]
+	return ok
+}
+
+// Data returns the contents of an entry.
+func (s *Snapshot) Data(e Entry) []byte {
+	return s.data[e.archiveName()]
+}
+
+// Write writes the snapshot archive to w.
+func (s *Snapshot) Write(w io.Writer) error {
+	sort.Slice(s.Entries, func(i, j int) bool { return s.Entries[i].archiveName() < s.Entries[j].archiveName() })
+	manifest, err := json.MarshalIndent(s.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	put := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: s.CreatedAt, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := put(ManifestName, manifest); err != nil {
+		return err
+	}
+	for _, e := range s.Entries {
+		if err := put(e.archiveName(), s.Data(e)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads a snapshot archive and verifies every entry against the
+// manifest.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: not a snapshot archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	s := &Snapshot{data: make(map[string][]byte)}
+	sawManifest := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: reading archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: reading %s: %w", hdr.Name, err)
+		}
+		if hdr.Name == ManifestName {
+			if err := json.Unmarshal(data, &s.Manifest); err != nil {
+				return nil, fmt.Errorf("snapshot: bad manifest: %w", err)
+			}
+			sawManifest = true
+			continue
+		}
+		s.data[hdr.Name] = data
+	}
+	if !sawManifest {
+		return nil, errors.New("snapshot: archive has no manifest")
+	}
+	if s.Version != FormatVersion {
+		return nil, fmt.Errorf("snapshot: format version %d not supported (want %d)", s.Version, FormatVersion)
+	}
+
+	for _, e := range s.Entries {
+		data, ok := s.data[e.archiveName()]
+		if !ok {
+			return nil, fmt.Errorf("snapshot: %s missing from archive", e.archiveName())
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != e.SHA256 {
+			return nil, fmt.Errorf("snapshot: %s is corrupt (checksum mismatch)", e.archiveName())
+		}
+	}
+	return s, nil
+}
+
+// CollectOptions says what to gather besides the town store.
+type CollectOptions struct {
+	// StorePrefixes are the town store key prefixes to archive.
+	StorePrefixes []string
+
+	// SkipKeys are store keys and files left out (locks, PID files, logs).
+	// Patterns are path.Match globs on the key.
+	SkipKeys []string
+
+	// Files are extra files to archive, relative to the town root. Missing
+	// files are skipped.
+	Files []string
+
+	// BeadsWorkspaces are the workspaces whose beads database is exported,
+	// relative to the town root.
+	BeadsWorkspaces []string
+
+	// ExportBeads returns the JSONL export of the beads database used by
+	// an absolute workspace path.
+	ExportBeads func(workDir string) ([]byte, error)
+
+	// Agents are recorded in the manifest as-is.
+	Agents []Agent
+}
+
+// Collect builds a snapshot of the town at townRoot from its store and the
+// items in opts. Export failures are returned as warnings alongside the
+// snapshot, so one broken database doesn't prevent the rest from moving.
+func Collect(townRoot string, store storage.Store, opts CollectOptions, now time.Time) (*Snapshot, []string, error) {
+	s := New(townRoot, now)
+	s.Agents = opts.Agents
+	var warnings []string
+
+	for _, prefix := range opts.StorePrefixes {
+		keys, err := store.List(prefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing store %s: %w", prefix, err)
+		}
+		for _, key := range keys {
+			if skipped(opts.SkipKeys, key) {
+				continue
+			}
+			data, err := store.Get(key)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue // Removed while we were listing
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("reading store %s: %w", key, err)
+			}
+			if err := s.Add(KindStore, key, data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, rel := range opts.Files {
+		rel = filepath.ToSlash(rel)
+		if skipped(opts.SkipKeys, rel) || s.has(KindStore, rel) {
+			continue // A filesystem store already archived it
+		}
+		data, err := os.ReadFile(filepath.Join(townRoot, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", rel, err)
+		}
+		if err := s.Add(KindFile, rel, data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, rel := range opts.BeadsWorkspaces {
+		data, err := opts.ExportBeads(filepath.Join(townRoot, filepath.FromSlash(rel)))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("beads in %s not exported: %v", rel, err))
+			continue
+		}
+		if err := s.Add(KindBeads, filepath.ToSlash(rel), data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s, warnings, nil
+}
+
+func skipped(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(key)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreOptions says where a snapshot is restored to.
+type RestoreOptions struct {
+	// Store receives the store records.
+	Store storage.Store
+
+	// Force overwrites existing store records and files.
+	Force bool
+
+	// CreateDirs are top-level town directories created when missing
+	// (e.g. "daemon"). Files elsewhere are only restored into directories
+	// that already exist, so agent state lands in re-cloned workspaces
+	// rather than in empty stand-ins.
+	CreateDirs []string
+
+	// ImportBeads loads a JSONL export into the beads database used by an
+	// absolute workspace path.
+	ImportBeads func(workDir, jsonlPath string) error
+}
+
+// RestoreResult reports what a restore did.
+type RestoreResult struct {
+	Restored []string // archive names
+	Skipped  []string // "name: reason"
+}
+
+// ErrConflict is returned when a restore would overwrite existing state.
+var ErrConflict = errors.New("snapshot: restore would overwrite existing town state")
+
+// Restore writes the snapshot into the town at townRoot. Without Force it
+// first checks that nothing would be overwritten and changes nothing if
+// something would.
+func (s *Snapshot) Restore(townRoot string, opts RestoreOptions) (*RestoreResult, error) {
+	if !opts.Force {
+		var conflicts []string
+		for _, e := range s.Entries {
+			if s.exists(townRoot, opts.Store, e) {
+				conflicts = append(conflicts, e.archiveName())
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, fmt.Errorf("%w: %s (use --force)", ErrConflict, strings.Join(conflicts, ", "))
+		}
+	}
+
+	result := &RestoreResult{}
+	skip := func(e Entry, reason string) {
+		result.Skipped = append(result.Skipped, e.archiveName()+": "+reason)
+	}
+	for _, e := range s.Entries {
+		data := s.Data(e)
+		switch e.Kind {
+		case KindStore:
+			if err := opts.Store.Put(e.Path, data); err != nil {
+				return result, fmt.Errorf("restoring %s: %w", e.archiveName(), err)
+			}
+
+		case KindFile:
+			target := filepath.Join(townRoot, filepath.FromSlash(e.Path))
+			if !s.prepareDir(townRoot, e.Path, opts.CreateDirs) {
+				skip(e, "directory missing (re-create the workspace, then restore again)")
+				continue
+			}
+			if err := util.AtomicWriteFile(target, data, 0644); err != nil {
+				return result, fmt.Errorf("restoring %s: %w", e.archiveName(), err)
+			}
+
+		case KindBeads:
+			workDir := filepath.Join(townRoot, filepath.FromSlash(e.Path))
+			if _, err := os.Stat(workDir); err != nil {
+				skip(e, "workspace missing (re-create it, then restore again)")
+				continue
+			}
+			if opts.ImportBeads == nil {
+				skip(e, "no bead importer")
+				continue
+			}
+			tmp, err := os.CreateTemp("", "gt-snapshot-*.jsonl")
+			if err != nil {
+				return result, err
+			}
+			_, werr := tmp.Write(data)
+			cerr := tmp.Close()
+			if werr == nil && cerr == nil {
+				werr = opts.ImportBeads(workDir, tmp.Name())
+			} else if werr == nil {
+				werr = cerr
+			}
+			_ = os.Remove(tmp.Name())
+			if werr != nil {
+				skip(e, werr.Error())
+				continue
+			}
+
+		default:
+			skip(e, "unknown kind")
+			continue
+		}
+		result.Restored = append(result.Restored, e.archiveName())
+	}
+	return result, nil
+}
+
+// exists reports whether restoring e would overwrite something. Bead
+// exports are merged by the importer, never overwritten.
+func (s *Snapshot) exists(townRoot string, store storage.Store, e Entry) bool {
+	switch e.Kind {
+	case KindStore:
+		_, err := store.Get(e.Path)
+		return err == nil
+	case KindFile:
+		_, err := os.Stat(filepath.Join(townRoot, filepath.FromSlash(e.Path)))
+		return err == nil
+	}
+	return false
+}
+
+// prepareDir makes sure the directory of a file entry exists, creating it
+// only under createDirs.
+func (s *Snapshot) prepareDir(townRoot, rel string, createDirs []string) bool {
+	dir := filepath.Join(townRoot, filepath.FromSlash(path.Dir(rel)))
+	if info, err := os.Stat(dir); err == nil {
+		return info.IsDir()
+	}
+	top, _, _ := strings.Cut(rel, "/")
+	for _, allowed := range createDirs {
+		if top == allowed && path.Dir(rel) != "." {
+			return os.MkdirAll(dir, 0755) == nil
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	town := t.TempDir()
+	writeFile(t, town, "daemon/state.json", `{"running":true}`)
+	writeFile(t, town, "daemon/runtime.json", `{"saved_at":"2026-03-01T09:00:00Z"}`)
+	writeFile(t, town, "daemon/daemon.pid", "4242")
+	writeFile(t, town, "daemon/startups.json", `{"attempts":[]}`)
+	writeFile(t, town, "mayor/daemon.json", `{"type":"daemon-patrol-config"}`)
+	writeFile(t, town, "gastown/crew/max/state.json", `{"role":"crew"}`)
+
+	snap, warnings, err := Collect(town, storage.NewFilesystem(town), CollectOptions{
+		StorePrefixes:   []string{"daemon/"},
+		SkipKeys:        []string{"*.pid"},
+		Files:           []string{"daemon/startups.json", "mayor/daemon.json", "gastown/crew/max/state.json", "gastown/crew/joe/state.json"},
+		BeadsWorkspaces: []string{".", "gastown"},
+		ExportBeads: func(workDir string) ([]byte, error) {
+			if workDir == filepath.Join(town, "gastown") {
+				return nil, errors.New("database locked")
+			}
+			return []byte(`{"id":"hq-1","title":"mail"}` + "\n"), nil
+		},
+		Agents: []Agent{{Identity: "gastown-crew-max", Session: "gt-gastown-crew-max", Running: true}},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "database locked") {
+		t.Errorf("warnings = %v", warnings)
+	}
+
+	var buf bytes.Buffer
+	if err := snap.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range read.Entries {
+		names = append(names, e.archiveName())
+	}
+	want := "beads/., file/gastown/crew/max/state.json, file/mayor/daemon.json, " +
+		"store/daemon/runtime.json, store/daemon/startups.json, store/daemon/state.json"
+	if got := strings.Join(names, ", "); got != want {
+		t.Errorf("entries = %s\nwant %s", got, want)
+	}
+	if len(read.Agents) != 1 || !read.Agents[0].Running {
+		t.Errorf("agents = %+v", read.Agents)
+	}
+
+	// Restore on a fresh host where the crew workspace isn't cloned yet
+	fresh := t.TempDir()
+	var imported string
+	opts := RestoreOptions{
+		Store:      storage.NewFilesystem(fresh),
+		CreateDirs: []string{"daemon", "mayor"},
+		ImportBeads: func(workDir, jsonlPath string) error {
+			data, err := os.ReadFile(jsonlPath)
+			imported = workDir + ": " + string(data)
+			return err
+		},
+	}
+	result, err := read.Restore(fresh, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Restored) != 5 || len(result.Skipped) != 1 || !strings.HasPrefix(result.Skipped[0], "file/gastown/crew/max/state.json") {
+		t.Errorf("restored %v, skipped %v", result.Restored, result.Skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(fresh, "mayor", "daemon.json")); string(data) != `{"type":"daemon-patrol-config"}` {
+		t.Errorf("mayor/daemon.json = %q", data)
+	}
+	if !strings.Contains(imported, `"hq-1"`) || !strings.HasPrefix(imported, fresh) {
+		t.Errorf("imported %q", imported)
+	}
+
+	// Restoring again would overwrite: refused without force
+	writeFile(t, fresh, "gastown/crew/max/.keep", "")
+	if _, err := read.Restore(fresh, opts); !errors.Is(err, ErrConflict) {
+		t.Errorf("second restore = %v, want ErrConflict", err)
+	}
+	if _, err := os.Stat(filepath.Join(fresh, "gastown", "crew", "max", "state.json")); err == nil {
+		t.Error("refused restore still wrote files")
+	}
+	opts.Force = true
+	if result, err := read.Restore(fresh, opts); err != nil || len(result.Skipped) != 0 {
+		t.Errorf("forced restore = %+v, %v", result, err)
+	}
+}
+
+func TestReadRejectsCorruptArchive(t *testing.T) {
+	snap := New("/town", time.Now())
+	if err := snap.Add(KindStore, "daemon/state.json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	snap.Entries[0].SHA256 = "0000"
+	var buf bytes.Buffer
+	if err := snap.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("Read = %v, want checksum error", err)
+	}
+
+	if err := snap.Add(KindFile, "../etc/passwd", nil); err == nil {
+		t.Error("path escaping the town accepted")
+	}
+	if _, err := Read(strings.NewReader("not gzip")); err == nil {
+		t.Error("garbage accepted as a snapshot")
+	}
+}
@@ -0,0 +1,295 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// AgentStateVersion is the current agent state file schema version.
+const AgentStateVersion = 1
+
+// AgentStateFile is the name of the per-agent state file in its working directory.
+const AgentStateFile = "state.json"
+
+// ErrInvalidAgentState indicates an agent state file failed validation.
+var ErrInvalidAgentState = errors.New("invalid agent state")
+
+// AgentState is the typed view of an agent's <workdir>/state.json.
+//
+// The file is shared: the agent (and its tooling) sets lifecycle request
+// flags, the daemon clears them and records kills, and crew tooling keeps its
+// own metadata there. Keys this struct doesn't know about are kept in Extra
+// and written back untouched, so every writer preserves the others' fields.
+type AgentState struct {
+	// SchemaVersion is the schema the file was last written with.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// RequestingCycle/RequestingShutdown are set by the agent before it mails
+	// the daemon a lifecycle request, so a lost mail can still be recovered.
+	// Gas Town's own commands never set them; the contract for an agent that
+	// asks to be cycled or shut down is: set one flag and RequestingTime to
+	// now, then send "LIFECYCLE: <action>" mail to deacon/. The daemon
+	// clears the flag when it claims the mail; if no mail from the agent is
+	// pending once the flag is older than lifecycle.stale_flag_threshold, the
+	// daemon clears it (or executes it, per lifecycle.stale_flag_action).
+	RequestingCycle    bool      `json:"requesting_cycle,omitempty"`
+	RequestingShutdown bool      `json:"requesting_shutdown,omitempty"`
+	RequestingTime     time.Time `json:"requesting_time,omitzero"`
+
+	// LastKilledAt/By/Action are written by the daemon after it kills the
+	// agent's session, so the next session can tell it was cycled and why.
+	LastKilledAt   time.Time `json:"last_killed_at,omitzero"`
+	LastKilledBy   string    `json:"last_killed_by,omitempty"`
+	LastKillAction string    `json:"last_kill_action,omitempty"`
+
+	// CurrentTask is the bead the agent is working on, if any.
+	CurrentTask string `json:"current_task,omitempty"`
+
+	// LastHeartbeat is when the agent last reported it was alive.
+	LastHeartbeat time.Time `json:"last_heartbeat,omitzero"`
+
+	// ContextUsage is the share of its context window the agent last
+	// reported using, in percent, and ContextReportedAt when it did.
+	ContextUsage      int       `json:"context_usage,omitempty"`
+	ContextReportedAt time.Time `json:"context_reported_at,omitzero"`
+
+	// Extra holds keys owned by other tools, preserved across writes.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// problems collects fields that couldn't be decoded. They are reported
+	// by Validate rather than failing the read, so a damaged file can still
+	// be repaired by rewriting it.
+	problems []string
+}
+
+// agentStateKeys are the keys decoded into AgentState fields.
+var agentStateKeys = []string{
+	"schema_version",
+	"requesting_cycle", "requesting_shutdown", "requesting_time",
+	"last_killed_at", "last_killed_by", "last_kill_action",
+	"current_task", "last_heartbeat",
+	"context_usage", "context_reported_at",
+}
+
+// agentStateFields is AgentState without its methods, for default JSON handling.
+type agentStateFields AgentState
+
+// UnmarshalJSON decodes known keys field by field and keeps unknown keys in Extra.
+func (s *AgentState) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = AgentState{}
+	for _, key := range agentStateKeys {
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		delete(raw, key)
+
+		// Decode each key alone so one bad value doesn't hide the rest
+		var one agentStateFields
+		if err := json.Unmarshal(singleKeyObject(key, value), &one); err != nil {
+			s.problems = append(s.problems, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		mergeAgentStateField(s, (*AgentState)(&one), key)
+	}
+	if len(raw) > 0 {
+		s.Extra = raw
+	}
+	return nil
+}
+
+// singleKeyObject builds the JSON object {key: value}.
+func singleKeyObject(key string, value json.RawMessage) []byte {
+	k, _ := json.Marshal(key)
+	return append(append(append(append([]byte{'{'}, k...), ':'), value...), '}')
+}
+
+// mergeAgentStateField copies the field for key from src into dst.
+func mergeAgentStateField(dst, src *AgentState, key string) {
+	switch key {
+	case "schema_version":
+		dst.SchemaVersion = src.SchemaVersion
+	case "requesting_cycle":
+		dst.RequestingCycle = src.RequestingCycle
+	case "requesting_shutdown":
+		dst.RequestingShutdown = src.RequestingShutdown
+	case "requesting_time":
+		dst.RequestingTime = src.RequestingTime
+	case "last_killed_at":
+		dst.LastKilledAt = src.LastKilledAt
+	case "last_killed_by":
+		dst.LastKilledBy = src.LastKilledBy
+	case "last_kill_action":
+		dst.LastKillAction = src.LastKillAction
+	case "current_task":
+		dst.CurrentTask = src.CurrentTask
+	case "last_heartbeat":
+		dst.LastHeartbeat = src.LastHeartbeat
+	case "context_usage":
+		dst.ContextUsage = src.ContextUsage
+	case "context_reported_at":
+		dst.ContextReportedAt = src.ContextReportedAt
+	}
+}
+
+// MarshalJSON writes known fields plus every preserved Extra key.
+func (s AgentState) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(agentStateFields(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Extra) == 0 {
+		return known, nil
+	}
+
+	merged := make(map[string]json.RawMessage, len(s.Extra)+len(agentStateKeys))
+	for k, v := range s.Extra {
+		merged[k] = v
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// Validate checks the state against the schema.
+func (s *AgentState) Validate() error {
+	problems := append([]string(nil), s.problems...)
+	if s.SchemaVersion > AgentStateVersion {
+		problems = append(problems, fmt.Sprintf("schema_version %d is newer than supported %d", s.SchemaVersion, AgentStateVersion))
+	}
+	if s.SchemaVersion < 0 {
+		problems = append(problems, "schema_version must be non-negative")
+	}
+	if (s.RequestingCycle || s.RequestingShutdown) && s.RequestingTime.IsZero() {
+		problems = append(problems, "requesting flag set without requesting_time")
+	}
+	if s.LastKillAction != "" && s.LastKilledAt.IsZero() {
+		problems = append(problems, "last_kill_action set without last_killed_at")
+	}
+	if s.ContextUsage < 0 || s.ContextUsage > 100 {
+		problems = append(problems, fmt.Sprintf("context_usage %d is not a percentage", s.ContextUsage))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidAgentState, problems)
+}
+
+// IsRequesting reports whether either lifecycle request flag is set.
+func (s *AgentState) IsRequesting() bool {
+	return s.RequestingCycle || s.RequestingShutdown
+}
+
+// ClearRequest clears the lifecycle request flags.
+func (s *AgentState) ClearRequest() {
+	s.RequestingCycle = false
+	s.RequestingShutdown = false
+	s.RequestingTime = time.Time{}
+}
+
+// AgentStatePath returns the state file path for an agent working directory.
+func AgentStatePath(workDir string) string {
+	return filepath.Join(workDir, AgentStateFile)
+}
+
+// ReadAgentState reads an agent state file.
+// Returns nil with no error if the file doesn't exist. Undecodable fields
+// don't fail the read; call Validate to find them.
+func ReadAgentState(path string) (*AgentState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return &AgentState{}, nil
+	}
+
+	var s AgentState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// UpdateAgentState atomically read-modify-writes an agent state file.
+// A lock file serializes concurrent updaters (daemon and agent tooling), and
+// the write goes through a temp file so readers never see a partial file.
+// fn receives an empty state if the file doesn't exist; if fn returns an
+// error nothing is written.
+func UpdateAgentState(path string, fn func(*AgentState) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating state dir: %w", err)
+	}
+
+	lock := flock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("locking %s: %w", path, err)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	s, err := ReadAgentState(path)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		s = &AgentState{}
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	s.SchemaVersion = AgentStateVersion
+	return util.AtomicWriteJSON(path, s)
+}
+
+// MergeAgentStateFields writes the top-level JSON fields of v into the state
+// file, leaving every other key alone. Tools that keep their own metadata in
+// state.json (e.g. crew) use this instead of overwriting the file.
+func MergeAgentStateFields(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%T is not a JSON object: %w", v, err)
+	}
+
+	return UpdateAgentState(path, func(s *AgentState) error {
+		var known AgentState
+		if err := known.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		for _, key := range agentStateKeys {
+			if _, ok := fields[key]; ok {
+				mergeAgentStateField(s, &known, key)
+			}
+		}
+		for k, v := range known.Extra {
+			if s.Extra == nil {
+				s.Extra = make(map[string]json.RawMessage)
+			}
+			s.Extra[k] = v
+		}
+		return nil
+	})
+}
@@ -0,0 +1,132 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadAgentState_Missing(t *testing.T) {
+	s, err := ReadAgentState(filepath.Join(t.TempDir(), AgentStateFile))
+	if err != nil {
+		t.Errorf("expected no error for missing file, got %v", err)
+	}
+	if s != nil {
+		t.Errorf("expected nil state, got %+v", s)
+	}
+}
+
+func TestUpdateAgentState_PreservesUnknownFields(t *testing.T) {
+	path := AgentStatePath(t.TempDir())
+	content := `{"name":"max","rig":"gastown","requesting_cycle":true,"requesting_time":"2026-01-01T00:00:00Z"}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := ReadAgentState(path)
+	if err != nil {
+		t.Fatalf("ReadAgentState: %v", err)
+	}
+	if !s.RequestingCycle || !s.RequestingTime.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("typed fields not decoded: %+v", s)
+	}
+
+	if err := UpdateAgentState(path, func(s *AgentState) error {
+		s.ClearRequest()
+		s.CurrentTask = "gt-abc"
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateAgentState: %v", err)
+	}
+
+	var raw map[string]interface{}
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["name"] != "max" || raw["rig"] != "gastown" {
+		t.Errorf("expected crew fields preserved, got %v", raw)
+	}
+	if _, ok := raw["requesting_cycle"]; ok {
+		t.Errorf("expected requesting_cycle cleared, got %v", raw)
+	}
+	if _, ok := raw["requesting_time"]; ok {
+		t.Errorf("expected requesting_time omitted, got %v", raw)
+	}
+	if raw["current_task"] != "gt-abc" || raw["schema_version"] != float64(AgentStateVersion) {
+		t.Errorf("expected current_task and schema_version written, got %v", raw)
+	}
+}
+
+func TestUpdateAgentState_ErrorSkipsWrite(t *testing.T) {
+	path := AgentStatePath(t.TempDir())
+	boom := errors.New("boom")
+	if err := UpdateAgentState(path, func(*AgentState) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("UpdateAgentState error = %v, expected boom", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no state file after failed update, got %v", err)
+	}
+}
+
+func TestMergeAgentStateFields(t *testing.T) {
+	path := AgentStatePath(t.TempDir())
+	if err := UpdateAgentState(path, func(s *AgentState) error {
+		s.LastKilledAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		s.LastKillAction = "cycle"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	crew := struct {
+		Name   string `json:"name"`
+		Branch string `json:"branch"`
+	}{"max", "crew/max"}
+	if err := MergeAgentStateFields(path, crew); err != nil {
+		t.Fatalf("MergeAgentStateFields: %v", err)
+	}
+
+	s, err := ReadAgentState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LastKillAction != "cycle" {
+		t.Errorf("daemon fields lost on merge: %+v", s)
+	}
+	if string(s.Extra["name"]) != `"max"` || string(s.Extra["branch"]) != `"crew/max"` {
+		t.Errorf("merged fields missing: %v", s.Extra)
+	}
+}
+
+func TestAgentState_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"empty", `{}`, true},
+		{"request with time", `{"requesting_cycle":true,"requesting_time":"2026-01-01T00:00:00Z"}`, true},
+		{"request without time", `{"requesting_shutdown":true}`, false},
+		{"bad time", `{"requesting_cycle":true,"requesting_time":"yesterday"}`, false},
+		{"future schema", `{"schema_version":99}`, false},
+		{"kill without time", `{"last_kill_action":"cycle"}`, false},
+	}
+
+	for _, tc := range tests {
+		var s AgentState
+		if err := json.Unmarshal([]byte(tc.content), &s); err != nil {
+			t.Fatalf("%s: unmarshal: %v", tc.name, err)
+		}
+		err := s.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: Validate() = %v, expected valid", tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidAgentState) {
+			t.Errorf("%s: Validate() = %v, expected ErrInvalidAgentState", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/storage"
+)
+
+// Agent state backends. The daemon reads and writes agent state (request
+// flags, kill records, heartbeats, context usage) through a Backend chosen
+// per town, so the checks built on it behave the same wherever the state is
+// kept:
+//
+//   - file:   <workdir>/state.json, shared with agent tooling (the default)
+//   - bead:   a lifecycle_state line on the agent's bead
+//   - sqlite: one row per agent in a town-level database (sqlite3 CLI)
+const (
+	BackendFile   = "file"
+	BackendBead   = "bead"
+	BackendSQLite = "sqlite"
+)
+
+// DefaultSQLitePath is the sqlite backend's database, relative to the town root.
+const DefaultSQLitePath = "daemon/agent-state.db"
+
+// ErrNoLocation is returned when an AgentRef lacks what a backend keys on.
+var ErrNoLocation = errors.New("agent state location unknown")
+
+// AgentRef locates one agent's state. Each backend keys on one field: file
+// on WorkDir, bead on BeadID, sqlite on Identity.
+type AgentRef struct {
+	// Identity is the daemon identity, e.g. "gastown-crew-max".
+	Identity string
+
+	// WorkDir is the agent's working directory.
+	WorkDir string
+
+	// BeadID is the agent bead, e.g. "gt-gastown-crew-max".
+	BeadID string
+}
+
+// Backend stores agent state.
+type Backend interface {
+	// Name is the backend's config name ("file", "bead", "sqlite").
+	Name() string
+
+	// Read returns the agent's state, or nil with no error if it has none.
+	Read(ref AgentRef) (*AgentState, error)
+
+	// Update atomically read-modify-writes the agent's state, as
+	// UpdateAgentState does for files. fn receives an empty state if there
+	// is none; if fn returns an error nothing is written.
+	Update(ref AgentRef, fn func(*AgentState) error) error
+}
+
+// BackendConfig selects a town's agent state backend, under "agent_state"
+// in mayor/daemon.json:
+//
+//	"agent_state": {"backend": "sqlite", "path": "daemon/agent-state.db"}
+type BackendConfig struct {
+	// Backend is "file" (default), "bead", or "sqlite".
+	Backend string `json:"backend,omitempty"`
+
+	// Path is the sqlite database, absolute or relative to the town root
+	// (default DefaultSQLitePath). Ignored by the other backends.
+	Path string `json:"path,omitempty"`
+}
+
+// Validate checks the config for unknown backends.
+func (c *BackendConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Backend {
+	case "", BackendFile, BackendBead, BackendSQLite:
+	default:
+		return fmt.Errorf("unknown agent state backend %q (want file, bead, or sqlite)", c.Backend)
+	}
+	if c.Path != "" && c.Backend != BackendSQLite {
+		return fmt.Errorf("agent state path is only used by the sqlite backend")
+	}
+	return nil
+}
+
+// OpenBackend returns the backend a config selects for the town at townRoot.
+// A nil config selects the file backend.
+func OpenBackend(townRoot string, cfg *BackendConfig) (Backend, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return FileBackend{}, nil
+	}
+	switch cfg.Backend {
+	case BackendBead:
+		return &BeadBackend{townRoot: townRoot}, nil
+	case BackendSQLite:
+		path := cfg.Path
+		if path == "" {
+			path = DefaultSQLitePath
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(townRoot, path)
+		}
+		return &SQLiteBackend{path: path}, nil
+	}
+	return FileBackend{}, nil
+}
+
+// FileBackend keeps agent state in <workdir>/state.json. It never creates
+// a working directory: a missing one has nobody to read the state.
+type FileBackend struct{}
+
+func (FileBackend) Name() string { return BackendFile }
+
+func (FileBackend) Read(ref AgentRef) (*AgentState, error) {
+	if ref.WorkDir == "" {
+		return nil, fmt.Errorf("%w: no working directory for %s", ErrNoLocation, ref.Identity)
+	}
+	return ReadAgentState(AgentStatePath(ref.WorkDir))
+}
+
+func (FileBackend) Update(ref AgentRef, fn func(*AgentState) error) error {
+	if ref.WorkDir == "" {
+		return fmt.Errorf("%w: no working directory for %s", ErrNoLocation, ref.Identity)
+	}
+	if _, err := os.Stat(ref.WorkDir); err != nil {
+		return fmt.Errorf("workspace of %s: %w", ref.Identity, err)
+	}
+	return UpdateAgentState(AgentStatePath(ref.WorkDir), fn)
+}
+
+// BeadBackend keeps agent state as a lifecycle_state line on the agent
+// bead, so it travels with the bead rather than the workspace. Updates are
+// serialized by a lock file under the town's daemon directory.
+type BeadBackend struct {
+	townRoot string
+}
+
+func (b *BeadBackend) Name() string { return BackendBead }
+
+func (b *BeadBackend) Read(ref AgentRef) (*AgentState, error) {
+	if ref.BeadID == "" {
+		return nil, fmt.Errorf("%w: no agent bead for %s", ErrNoLocation, ref.Identity)
+	}
+	_, fields, err := beads.New(b.townRoot).GetAgentBead(ref.BeadID)
+	if err != nil || fields == nil || fields.LifecycleState == "" {
+		return nil, err
+	}
+	return decodeAgentState([]byte(fields.LifecycleState), ref.BeadID)
+}
+
+func (b *BeadBackend) Update(ref AgentRef, fn func(*AgentState) error) error {
+	if ref.BeadID == "" {
+		return fmt.Errorf("%w: no agent bead for %s", ErrNoLocation, ref.Identity)
+	}
+	unlock, err := lockFile(filepath.Join(b.townRoot, "daemon", "agent-state.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bd := beads.New(b.townRoot)
+	issue, fields, err := bd.GetAgentBead(ref.BeadID)
+	if err != nil {
+		return err
+	}
+	if issue == nil {
+		return fmt.Errorf("agent bead %s not found", ref.BeadID)
+	}
+	s := &AgentState{}
+	if fields.LifecycleState != "" {
+		if s, err = decodeAgentState([]byte(fields.LifecycleState), ref.BeadID); err != nil {
+			return err
+		}
+	}
+	data, err := applyUpdate(s, fn)
+	if err != nil {
+		return err
+	}
+	return bd.UpdateAgentLifecycleState(ref.BeadID, string(data))
+}
+
+// SQLiteBackend keeps agent state in a town-level sqlite database, one row
+// per agent identity, through the sqlite3 CLI (storage.RunSQLite3, which
+// waits out other writers). Updates are serialized by a lock file next to
+// the database.
+type SQLiteBackend struct {
+	path string
+}
+
+// sqliteSchema creates the agent state table if it doesn't exist.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS agent_state (
+	identity   TEXT PRIMARY KEY,
+	state      TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);`
+
+func (b *SQLiteBackend) Name() string { return BackendSQLite }
+
+func (b *SQLiteBackend) Read(ref AgentRef) (*AgentState, error) {
+	if ref.Identity == "" {
+		return nil, fmt.Errorf("%w: no identity", ErrNoLocation)
+	}
+	if _, err := os.Stat(b.path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	out, err := storage.RunSQLite3(b.path, sqliteSchema+fmt.Sprintf("\nSELECT state FROM agent_state WHERE identity = %s;", storage.SQLQuote(ref.Identity)))
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	var rows []struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("parsing sqlite3 output: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return decodeAgentState([]byte(rows[0].State), ref.Identity)
+}
+
+func (b *SQLiteBackend) Update(ref AgentRef, fn func(*AgentState) error) error {
+	if ref.Identity == "" {
+		return fmt.Errorf("%w: no identity", ErrNoLocation)
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("creating state dir: %w", err)
+	}
+	unlock, err := lockFile(b.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := b.Read(ref)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		s = &AgentState{}
+	}
+	data, err := applyUpdate(s, fn)
+	if err != nil {
+		return err
+	}
+	_, err = storage.RunSQLite3(b.path, sqliteSchema+fmt.Sprintf(`
+INSERT INTO agent_state (identity, state, updated_at) VALUES (%s, %s, %s)
+ON CONFLICT(identity) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at;`,
+		storage.SQLQuote(ref.Identity), storage.SQLQuote(string(data)), storage.SQLQuote(time.Now().UTC().Format(time.RFC3339))))
+	return err
+}
+
+// decodeAgentState parses a stored state record; where names it in errors.
+func decodeAgentState(data []byte, where string) (*AgentState, error) {
+	var s AgentState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing agent state of %s: %w", where, err)
+	}
+	return &s, nil
+}
+
+// applyUpdate runs fn on s and returns the record to store.
+func applyUpdate(s *AgentState, fn func(*AgentState) error) ([]byte, error) {
+	if err := fn(s); err != nil {
+		return nil, err
+	}
+	s.SchemaVersion = AgentStateVersion
+	return json.Marshal(s)
+}
+
+// lockFile takes an exclusive lock on path, creating its directory.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating lock dir: %w", err)
+	}
+	lock := flock.New(path)
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	return func() { _ = lock.Unlock() }, nil
+}
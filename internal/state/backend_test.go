@@ -0,0 +1,96 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenBackend(t *testing.T) {
+	town := t.TempDir()
+	tests := []struct {
+		cfg     *BackendConfig
+		want    string
+		wantErr bool
+	}{
+		{nil, BackendFile, false},
+		{&BackendConfig{}, BackendFile, false},
+		{&BackendConfig{Backend: "bead"}, BackendBead, false},
+		{&BackendConfig{Backend: "sqlite"}, BackendSQLite, false},
+		{&BackendConfig{Backend: "redis"}, "", true},
+		{&BackendConfig{Backend: "file", Path: "x.db"}, "", true},
+	}
+	for _, tt := range tests {
+		backend, err := OpenBackend(town, tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("OpenBackend(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+			continue
+		}
+		if err == nil && backend.Name() != tt.want {
+			t.Errorf("OpenBackend(%+v) = %s, want %s", tt.cfg, backend.Name(), tt.want)
+		}
+	}
+
+	backend, _ := OpenBackend(town, &BackendConfig{Backend: "sqlite"})
+	if got := backend.(*SQLiteBackend).path; got != filepath.Join(town, DefaultSQLitePath) {
+		t.Errorf("sqlite path = %s", got)
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	var backend FileBackend
+	ref := AgentRef{Identity: "gastown-crew-max", WorkDir: t.TempDir()}
+	testBackendRoundTrip(t, backend, ref)
+
+	// A missing workspace is never created
+	gone := AgentRef{Identity: "gastown-crew-gone", WorkDir: filepath.Join(ref.WorkDir, "gone")}
+	err := backend.Update(gone, func(s *AgentState) error { return nil })
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Update of missing workspace = %v", err)
+	}
+	if _, err := backend.Read(AgentRef{Identity: "x"}); !errors.Is(err, ErrNoLocation) {
+		t.Errorf("Read without workdir = %v, want ErrNoLocation", err)
+	}
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	backend := &SQLiteBackend{path: filepath.Join(t.TempDir(), "daemon", "agent-state.db")}
+	if s, err := backend.Read(AgentRef{Identity: "gastown-crew-max"}); err != nil || s != nil {
+		t.Fatalf("Read before the database exists = %+v, %v", s, err)
+	}
+	testBackendRoundTrip(t, backend, AgentRef{Identity: "gastown-crew-max"})
+	testBackendRoundTrip(t, backend, AgentRef{Identity: "o'brien"})
+}
+
+// testBackendRoundTrip sets and clears a request flag through backend.
+func testBackendRoundTrip(t *testing.T, backend Backend, ref AgentRef) {
+	t.Helper()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := backend.Update(ref, func(s *AgentState) error {
+		s.RequestingCycle = true
+		s.RequestingTime = at
+		return nil
+	}); err != nil {
+		t.Fatalf("%s Update: %v", backend.Name(), err)
+	}
+	s, err := backend.Read(ref)
+	if err != nil || s == nil || !s.RequestingCycle || !s.RequestingTime.Equal(at) || s.SchemaVersion != AgentStateVersion {
+		t.Fatalf("%s Read = %+v, %v", backend.Name(), s, err)
+	}
+
+	if err := backend.Update(ref, func(s *AgentState) error {
+		s.ClearRequest()
+		return nil
+	}); err != nil {
+		t.Fatalf("%s Update: %v", backend.Name(), err)
+	}
+	if s, err = backend.Read(ref); err != nil || s.IsRequesting() {
+		t.Errorf("%s Read after clear = %+v, %v", backend.Name(), s, err)
+	}
+}
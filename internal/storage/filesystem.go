@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+func init() {
+	Register("file", func(u *url.URL) (Store, error) {
+		return NewFilesystem(u.Path), nil
+	})
+}
+
+// Filesystem stores each key as a file under a root directory.
+type Filesystem struct {
+	root string
+
+	// appendMu serializes appends within this process; O_APPEND keeps
+	// single-write records intact across processes.
+	appendMu sync.Mutex
+}
+
+// NewFilesystem creates a filesystem store rooted at root.
+func NewFilesystem(root string) *Filesystem {
+	return &Filesystem{root: root}
+}
+
+// Root returns the directory the store is rooted at.
+func (f *Filesystem) Root() string {
+	return f.root
+}
+
+// path resolves a key to a file path.
+func (f *Filesystem) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.root, filepath.FromSlash(key)), nil
+}
+
+// Get reads a key's file.
+func (f *Filesystem) Get(key string) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Tail reads up to the last n bytes of a key's file.
+func (f *Filesystem) Tail(key string, n int64) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-n, 0)
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put writes a key's file atomically, creating parent directories.
+func (f *Filesystem) Put(key string, data []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return util.AtomicWriteFile(path, data, 0644)
+}
+
+// Append appends a record to a key's file.
+func (f *Filesystem) Append(key string, record []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f.appendMu.Lock()
+	defer f.appendMu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) //nolint:gosec // G302: town data is non-sensitive operational data
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(record)
+	return err
+}
+
+// Delete removes a key's file.
+func (f *Filesystem) Delete(key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List walks the directory for prefix and returns the keys of all files.
+// The prefix is matched against whole keys, so "daemon/" lists that
+// directory while "daemon/st" matches "daemon/state.json".
+func (f *Filesystem) List(prefix string) ([]string, error) {
+	// Only walk the directory the prefix names, not the whole town
+	walkRoot := f.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir := prefix[:i]
+		if err := validateKey(dir); err != nil {
+			return nil, err
+		}
+		walkRoot = filepath.Join(f.root, filepath.FromSlash(dir))
+	}
+
+	var keys []string
+	err := filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close is a no-op for the filesystem store.
+func (f *Filesystem) Close() error {
+	return nil
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	Register("sqlite", func(u *url.URL) (Store, error) {
+		return NewSQLite(u.Path)
+	})
+}
+
+// SQLite stores every key as a row of one table in a sqlite database,
+// through the sqlite3 CLI (as the sqlite agent state backend does), so the
+// binary needs no cgo driver. sqlite's own locking serializes writers
+// across processes, and an append is a single statement, so concurrent
+// appends don't interleave.
+type SQLite struct {
+	path string
+}
+
+// sqliteStoreSchema creates the key/value table if it doesn't exist.
+const sqliteStoreSchema = `CREATE TABLE IF NOT EXISTS kv (
+	key   TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);`
+
+// sqliteBusyTimeout is how long a statement waits for another writer, in
+// milliseconds.
+const sqliteBusyTimeout = 5000
+
+// NewSQLite opens a sqlite store at path, creating its directory.
+func NewSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite storage URL has no database path")
+	}
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("sqlite storage needs the sqlite3 CLI: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &SQLite{path: path}, nil
+}
+
+// Path returns the database file.
+func (s *SQLite) Path() string {
+	return s.path
+}
+
+// Get reads a key's row.
+func (s *SQLite) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	rows, err := s.query(fmt.Sprintf("SELECT hex(value) AS value FROM kv WHERE key = %s;", SQLQuote(key)))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return hex.DecodeString(rows[0])
+}
+
+// Tail reads up to the last n bytes of a key's row.
+func (s *SQLite) Tail(key string, n int64) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	rows, err := s.query(fmt.Sprintf("SELECT hex(substr(value, %d)) AS value FROM kv WHERE key = %s;", -n, SQLQuote(key)))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return hex.DecodeString(rows[0])
+}
+
+// Put replaces a key's row.
+func (s *SQLite) Put(key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.query(fmt.Sprintf(
+		"INSERT INTO kv (key, value) VALUES (%s, %s) ON CONFLICT(key) DO UPDATE SET value = excluded.value;",
+		SQLQuote(key), blobLiteral(data)))
+	return err
+}
+
+// Append concatenates a record onto a key's row.
+func (s *SQLite) Append(key string, record []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.query(fmt.Sprintf(
+		"INSERT INTO kv (key, value) VALUES (%s, %s) ON CONFLICT(key) DO UPDATE SET value = CAST(value || excluded.value AS BLOB);",
+		SQLQuote(key), blobLiteral(record)))
+	return err
+}
+
+// Delete removes a key's row.
+func (s *SQLite) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	_, err := s.query(fmt.Sprintf("DELETE FROM kv WHERE key = %s;", SQLQuote(key)))
+	return err
+}
+
+// List returns the keys starting with prefix, sorted.
+func (s *SQLite) List(prefix string) ([]string, error) {
+	return s.query(fmt.Sprintf(
+		"SELECT key AS value FROM kv WHERE substr(key, 1, %d) = %s ORDER BY key;",
+		len(prefix), SQLQuote(prefix)))
+}
+
+// Close is a no-op; every call runs its own sqlite3 process.
+func (s *SQLite) Close() error {
+	return nil
+}
+
+// query runs a statement after ensuring the schema and returns the value
+// column of its rows.
+func (s *SQLite) query(sql string) ([]string, error) {
+	out, err := RunSQLite3(s.path, sqliteStoreSchema+"\n"+sql)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(out))) == 0 {
+		return nil, nil
+	}
+	var rows []struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("parsing sqlite3 output: %w", err)
+	}
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = row.Value
+	}
+	return values, nil
+}
+
+// RunSQLite3 runs sql against the database at path through the sqlite3 CLI
+// and returns its JSON output. Statements wait out other writers for up to
+// sqliteBusyTimeout instead of failing with "database is locked".
+func RunSQLite3(path, sql string) ([]byte, error) {
+	cmd := exec.Command("sqlite3", "-bail", "-json", //nolint:gosec // G204: path comes from town config
+		"-cmd", fmt.Sprintf(".timeout %d", sqliteBusyTimeout), path)
+	cmd.Stdin = strings.NewReader(sql)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sqlite3 %s: %s", path, msg)
+		}
+		return nil, fmt.Errorf("sqlite3 %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// SQLQuote renders s as an SQL string literal.
+func SQLQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// blobLiteral renders data as an SQL blob literal, so any bytes survive the
+// trip through the CLI.
+func blobLiteral(data []byte) string {
+	return "X'" + hex.EncodeToString(data) + "'"
+}
@@ -0,0 +1,253 @@
+// Package storage abstracts the town data layer behind a key/value store so
+// town state can live somewhere other than the local filesystem.
+//
+// Keys are slash-separated paths relative to the town root (for example
+// "daemon/state.json" or ".events.jsonl"), which lets the filesystem driver
+// keep today's on-disk layout unchanged. Other drivers register themselves
+// with Register and are selected by URL scheme:
+//
+//	file:///home/me/gt    filesystem rooted at /home/me/gt (the default)
+//	sqlite:///var/gt.db   any registered driver, looked up by scheme
+//
+// Set GT_STORAGE_URL to point a town at a non-default store. The filesystem
+// and sqlite (through the sqlite3 CLI) drivers are built in; a Postgres
+// driver would register itself under its scheme. Several towns can share
+// one store: a town opened through GT_STORAGE_URL keeps its keys under
+// towns/<town name>/.
+//
+// Mail messages are owned by beads (bd) and are not stored here; the merge
+// queue's order is.
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvStorageURL overrides the store a town uses.
+const EnvStorageURL = "GT_STORAGE_URL"
+
+// Common errors
+var (
+	ErrNotFound      = errors.New("key not found")
+	ErrInvalidKey    = errors.New("invalid storage key")
+	ErrUnknownDriver = errors.New("unknown storage driver")
+)
+
+// Store is a town data store.
+type Store interface {
+	// Get returns the value for key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+
+	// Put replaces the value for key atomically.
+	Put(key string, data []byte) error
+
+	// Append adds a record to the end of an append-only log (e.g. events).
+	// Concurrent appends must not interleave.
+	Append(key string, record []byte) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key string) error
+
+	// List returns the keys under prefix, sorted.
+	List(prefix string) ([]string, error)
+
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Tailer is implemented by stores that can read the end of a value without
+// reading all of it, for appenders that need the last record of a log.
+type Tailer interface {
+	// Tail returns up to the last n bytes of key's value, or ErrNotFound.
+	Tail(key string, n int64) ([]byte, error)
+}
+
+// Tail returns up to the last n bytes of key's value, reading only the end
+// when the store supports it.
+func Tail(store Store, key string, n int64) ([]byte, error) {
+	if t, ok := store.(Tailer); ok {
+		return t.Tail(key, n)
+	}
+	data, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > n {
+		data = data[int64(len(data))-n:]
+	}
+	return data, nil
+}
+
+// Driver opens a store from a parsed storage URL.
+type Driver func(u *url.URL) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register makes a driver available under a URL scheme.
+// It panics if the scheme is registered twice, like database/sql.
+func Register(scheme string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[scheme]; dup {
+		panic("storage: Register called twice for driver " + scheme)
+	}
+	drivers[scheme] = driver
+}
+
+// Drivers returns the registered driver schemes, sorted.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	schemes := make([]string, 0, len(drivers))
+	for scheme := range drivers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open opens a store from a URL. A bare path is treated as file://<path>.
+func Open(rawURL string) (Store, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "file://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing storage URL: %w", err)
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[u.Scheme]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", ErrUnknownDriver, u.Scheme, strings.Join(Drivers(), ", "))
+	}
+	return driver(u)
+}
+
+// ForTown opens the store for a town: GT_STORAGE_URL if set, with the
+// town's keys under towns/<town name>/, otherwise the filesystem rooted at
+// townRoot.
+func ForTown(townRoot string, getenv func(string) string) (Store, error) {
+	if rawURL := getenv(EnvStorageURL); rawURL != "" {
+		store, err := Open(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return Namespace(store, "towns/"+townName(townRoot)), nil
+	}
+	return NewFilesystem(townRoot), nil
+}
+
+// townName returns the name in a town's mayor/town.json, or the town
+// root's directory name.
+func townName(townRoot string) string {
+	var town struct {
+		Name string `json:"name"`
+	}
+	if data, err := os.ReadFile(filepath.Join(townRoot, "mayor", "town.json")); err == nil {
+		if json.Unmarshal(data, &town) == nil && validateKey(town.Name) == nil && !strings.Contains(town.Name, "/") {
+			return town.Name
+		}
+	}
+	return filepath.Base(townRoot)
+}
+
+// namespaced keeps a store's keys under a prefix.
+type namespaced struct {
+	store  Store
+	prefix string // ends in "/"
+}
+
+// Namespace returns a view of store whose keys live under ns.
+func Namespace(store Store, ns string) Store {
+	return &namespaced{store: store, prefix: strings.TrimSuffix(ns, "/") + "/"}
+}
+
+// key maps a key into the namespace, validating it first so ".." can't
+// climb out.
+func (n *namespaced) key(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return n.prefix + key, nil
+}
+
+func (n *namespaced) Get(key string) ([]byte, error) {
+	k, err := n.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return n.store.Get(k)
+}
+
+func (n *namespaced) Put(key string, data []byte) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.store.Put(k, data)
+}
+
+func (n *namespaced) Append(key string, record []byte) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.store.Append(k, record)
+}
+
+func (n *namespaced) Delete(key string) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.store.Delete(k)
+}
+
+func (n *namespaced) Tail(key string, size int64) ([]byte, error) {
+	k, err := n.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return Tail(n.store, k, size)
+}
+
+func (n *namespaced) List(prefix string) ([]string, error) {
+	keys, err := n.store.List(n.prefix + prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, n.prefix)
+	}
+	return keys, nil
+}
+
+func (n *namespaced) Close() error {
+	return n.store.Close()
+}
+
+// validateKey rejects keys that could escape the store root.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilesystem_GetPutDelete(t *testing.T) {
+	store := NewFilesystem(t.TempDir())
+
+	if _, err := store.Get("daemon/state.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Put("daemon/state.json", []byte(`{"running":true}`)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := store.Get("daemon/state.json")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != `{"running":true}` {
+		t.Errorf("Get = %q", data)
+	}
+
+	if err := store.Delete("daemon/state.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete("daemon/state.json"); err != nil {
+		t.Errorf("Delete of missing key should succeed, got %v", err)
+	}
+}
+
+func TestFilesystem_AppendAndList(t *testing.T) {
+	root := t.TempDir()
+	store := NewFilesystem(root)
+
+	for _, line := range []string{"a\n", "b\n"} {
+		if err := store.Append(".events.jsonl", []byte(line)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	data, err := store.Get(".events.jsonl")
+	if err != nil || string(data) != "a\nb\n" {
+		t.Errorf("Get after Append = %q, %v", data, err)
+	}
+
+	_ = store.Put("daemon/state.json", []byte("{}"))
+	_ = store.Put("daemon/logs/x.log", []byte(""))
+
+	keys, err := store.List("daemon/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !slices.Equal(keys, []string{"daemon/logs/x.log", "daemon/state.json"}) {
+		t.Errorf("List(daemon/) = %v", keys)
+	}
+
+	// The filesystem driver keeps the existing on-disk layout
+	if _, err := NewFilesystem(filepath.Join(root, "daemon")).Get("state.json"); err != nil {
+		t.Errorf("expected daemon/state.json on disk: %v", err)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	store := NewFilesystem(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../escape", "a/../../b", "a//b", `a\b`} {
+		if err := store.Put(key, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, expected ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	root := t.TempDir()
+
+	store, err := Open(root)
+	if err != nil {
+		t.Fatalf("Open(bare path): %v", err)
+	}
+	if fsStore, ok := store.(*Filesystem); !ok || fsStore.Root() != root {
+		t.Errorf("Open(bare path) = %#v", store)
+	}
+
+	if _, err := Open("postgres://db/gt"); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+
+	Register("memtest", func(u *url.URL) (Store, error) { return NewFilesystem(root), nil })
+	if _, err := Open("memtest://x"); err != nil {
+		t.Errorf("Open with registered driver: %v", err)
+	}
+}
+
+func TestForTown(t *testing.T) {
+	root := t.TempDir()
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	store, err := ForTown(root, getenv)
+	if err != nil {
+		t.Fatalf("ForTown: %v", err)
+	}
+	if store.(*Filesystem).Root() != root {
+		t.Errorf("expected filesystem store rooted at town")
+	}
+
+	env[EnvStorageURL] = "nosuch://x"
+	if _, err := ForTown(root, getenv); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("expected GT_STORAGE_URL to select driver, got %v", err)
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	store, err := Open("sqlite://" + filepath.Join(t.TempDir(), "gt.db"))
+	if err != nil {
+		t.Fatalf("Open(sqlite): %v", err)
+	}
+
+	if _, err := store.Get("daemon/state.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	binary := []byte("it's\x00\xff\n")
+	if err := store.Put("daemon/state.json", binary); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, err := store.Get("daemon/state.json"); err != nil || string(data) != string(binary) {
+		t.Errorf("Get = %q, %v", data, err)
+	}
+
+	for _, line := range []string{"a\n", "b\n"} {
+		if err := store.Append(".events.jsonl", []byte(line)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if data, err := store.Get(".events.jsonl"); err != nil || string(data) != "a\nb\n" {
+		t.Errorf("Get after Append = %q, %v", data, err)
+	}
+
+	keys, err := store.List("daemon/")
+	if err != nil || !slices.Equal(keys, []string{"daemon/state.json"}) {
+		t.Errorf("List(daemon/) = %v, %v", keys, err)
+	}
+	if err := store.Delete("daemon/state.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get("daemon/state.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v", err)
+	}
+}
+
+func TestRunSQLite3WaitsForWriters(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	path := filepath.Join(t.TempDir(), "gt.db")
+	if _, err := RunSQLite3(path, "CREATE TABLE t (v TEXT);"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another writer holds the database for a second
+	holder := exec.Command("sqlite3", path)
+	holder.Stdin = strings.NewReader("BEGIN EXCLUSIVE;\n.shell sleep 1\nCOMMIT;\n")
+	if err := holder.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = holder.Wait() }()
+	time.Sleep(200 * time.Millisecond)
+
+	if _, err := RunSQLite3(path, "INSERT INTO t VALUES ("+SQLQuote("it's")+");"); err != nil {
+		t.Fatalf("write behind a busy writer: %v", err)
+	}
+	out, err := RunSQLite3(path, "SELECT v FROM t;")
+	if err != nil || !strings.Contains(string(out), `"it's"`) {
+		t.Errorf("SELECT = %s, %v", out, err)
+	}
+}
+
+func TestForTownNamespacesSharedStores(t *testing.T) {
+	shared := t.TempDir()
+	getenv := func(k string) string {
+		if k == EnvStorageURL {
+			return "file://" + shared
+		}
+		return ""
+	}
+	towns := []string{t.TempDir(), t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(towns[0], "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(towns[0], "mayor", "town.json"), []byte(`{"name": "alpha"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, town := range towns {
+		store, err := ForTown(town, getenv)
+		if err != nil {
+			t.Fatalf("ForTown: %v", err)
+		}
+		if err := store.Put("daemon/state.json", []byte{byte('0' + i)}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if keys, _ := store.List("daemon/"); !slices.Equal(keys, []string{"daemon/state.json"}) {
+			t.Errorf("List(daemon/) = %v, want keys without the namespace", keys)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(shared, "towns", "alpha", "daemon", "state.json")); err != nil || string(data) != "0" {
+		t.Errorf("alpha's state = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(shared, "towns", filepath.Base(towns[1]), "daemon", "state.json")); err != nil || string(data) != "1" {
+		t.Errorf("unnamed town's state = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,96 @@
+package testharness
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+var _ daemon.BeadsClient = (*FakeBeads)(nil)
+
+// FakeAgentBead is an agent bead served by FakeBeads.
+type FakeAgentBead struct {
+	ID          string `json:"id"`
+	Type        string `json:"issue_type"`
+	State       string `json:"agent_state,omitempty"`
+	HookBead    string `json:"hook_bead,omitempty"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// FakeBeads is an in-memory daemon.BeadsClient. It answers the bd commands
+// the daemon runs (show, list --type=agent, sync) and records every call.
+type FakeBeads struct {
+	mu          sync.Mutex
+	roleConfigs map[string]*beads.RoleConfig
+	agents      map[string]FakeAgentBead
+	calls       []string
+}
+
+// NewFakeBeads returns a FakeBeads with no beads.
+func NewFakeBeads() *FakeBeads {
+	return &FakeBeads{
+		roleConfigs: make(map[string]*beads.RoleConfig),
+		agents:      make(map[string]FakeAgentBead),
+	}
+}
+
+// SetRoleConfig sets the config of a role bead (e.g. "hq-crew-role").
+func (b *FakeBeads) SetRoleConfig(roleBeadID string, cfg *beads.RoleConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roleConfigs[roleBeadID] = cfg
+}
+
+// SetAgent adds or replaces an agent bead.
+func (b *FakeBeads) SetAgent(agent FakeAgentBead) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if agent.Type == "" {
+		agent.Type = "agent"
+	}
+	b.agents[agent.ID] = agent
+}
+
+// Calls returns the bd commands run, as space-joined args.
+func (b *FakeBeads) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
+
+func (b *FakeBeads) RoleConfig(roleBeadID string) (*beads.RoleConfig, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.roleConfigs[roleBeadID], nil
+}
+
+func (b *FakeBeads) Run(dir string, args ...string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, strings.Join(args, " "))
+
+	switch {
+	case len(args) >= 2 && args[0] == "show":
+		agent, ok := b.agents[args[1]]
+		if !ok {
+			return nil, fmt.Errorf("bd show %s: not found", args[1])
+		}
+		return json.Marshal([]FakeAgentBead{agent})
+	case len(args) >= 2 && args[0] == "list" && args[1] == "--type=agent":
+		agents := make([]FakeAgentBead, 0, len(b.agents))
+		for _, agent := range b.agents {
+			agents = append(agents, agent)
+		}
+		sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+		return json.Marshal(agents)
+	case len(args) == 1 && args[0] == "sync":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("bd %s: not supported by FakeBeads", strings.Join(args, " "))
+}
@@ -0,0 +1,119 @@
+package testharness
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+var _ daemon.MailClient = (*FakeMail)(nil)
+
+// SentMail is a message sent through a FakeMail.
+type SentMail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// FakeMail is an in-memory daemon.MailClient. Sent mail is recorded and
+// also delivered to the recipient's inbox.
+type FakeMail struct {
+	mu      sync.Mutex
+	inboxes map[string][]daemon.BeadsMessage
+	sent    []SentMail
+	deleted []string
+	nextID  int
+
+	// InboxErr, if set, is returned by Inbox (e.g. a broken gt binary).
+	InboxErr error
+}
+
+// NewFakeMail returns a FakeMail with empty inboxes.
+func NewFakeMail() *FakeMail {
+	return &FakeMail{inboxes: make(map[string][]daemon.BeadsMessage)}
+}
+
+// Deliver puts a message in identity's inbox and returns its ID. A missing
+// ID or timestamp is filled in.
+func (m *FakeMail) Deliver(identity string, msg daemon.BeadsMessage) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if msg.ID == "" {
+		m.nextID++
+		msg.ID = "hq-msg-" + strconv.Itoa(m.nextID)
+	}
+	if msg.Timestamp == "" {
+		msg.Timestamp = time.Now().Format(time.RFC3339)
+	}
+	if msg.To == "" {
+		msg.To = identity
+	}
+	m.inboxes[identity] = append(m.inboxes[identity], msg)
+	return msg.ID
+}
+
+// Messages returns identity's inbox.
+func (m *FakeMail) Messages(identity string) []daemon.BeadsMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]daemon.BeadsMessage(nil), m.inboxes[identity]...)
+}
+
+// Sent returns every message sent, in order.
+func (m *FakeMail) Sent() []SentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SentMail(nil), m.sent...)
+}
+
+// SentTo returns the messages sent to an address.
+func (m *FakeMail) SentTo(to string) []SentMail {
+	var out []SentMail
+	for _, mail := range m.Sent() {
+		if mail.To == to {
+			out = append(out, mail)
+		}
+	}
+	return out
+}
+
+// Deleted returns the IDs of deleted messages, in order.
+func (m *FakeMail) Deleted() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.deleted...)
+}
+
+func (m *FakeMail) Inbox(identity string) ([]daemon.BeadsMessage, error) {
+	if m.InboxErr != nil {
+		return nil, m.InboxErr
+	}
+	return m.Messages(identity), nil
+}
+
+func (m *FakeMail) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for identity, msgs := range m.inboxes {
+		for i, msg := range msgs {
+			if msg.ID == id {
+				m.inboxes[identity] = append(msgs[:i:i], msgs[i+1:]...)
+				m.deleted = append(m.deleted, id)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("message not found: %s", id)
+}
+
+func (m *FakeMail) Send(to, subject, body string) error {
+	m.mu.Lock()
+	m.sent = append(m.sent, SentMail{To: to, Subject: subject, Body: body})
+	m.mu.Unlock()
+	m.Deliver(strings.TrimSpace(to), daemon.BeadsMessage{From: "deacon/", Subject: subject, Body: body})
+	return nil
+}
@@ -0,0 +1,54 @@
+package testharness
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/state"
+)
+
+// NewSandbox creates a scenario outside of a test, in a new temporary town
+// whose fake agents write a heartbeat to their state file when they start.
+// Steps are run with Exec; Close removes the town.
+func NewSandbox() (*Scenario, error) {
+	townRoot, err := os.MkdirTemp("", "gt-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{"mayor", "daemon"} {
+		if err := os.MkdirAll(filepath.Join(townRoot, dir), 0755); err != nil {
+			_ = os.RemoveAll(townRoot)
+			return nil, err
+		}
+	}
+	s := &Scenario{
+		TownRoot: townRoot,
+		Tmux:     NewFakeTmux(),
+		Mail:     NewFakeMail(),
+		Beads:    NewFakeBeads(),
+		log:      &syncBuffer{},
+	}
+	s.Tmux.OnAgentStart = func(session FakeSession) {
+		if session.WorkDir == "" {
+			return
+		}
+		_ = state.UpdateAgentState(state.AgentStatePath(session.WorkDir), func(st *state.AgentState) error {
+			st.LastHeartbeat = time.Now().UTC()
+			return nil
+		})
+	}
+	return s, nil
+}
+
+// Exec runs one step, creating the daemon first if needed. Unlike Run it
+// returns the step's error instead of failing a test.
+func (s *Scenario) Exec(step Step) error {
+	s.Daemon()
+	return step.Do(s)
+}
+
+// Close removes the scenario's town.
+func (s *Scenario) Close() error {
+	return os.RemoveAll(s.TownRoot)
+}
@@ -0,0 +1,310 @@
+package testharness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+// Step is one step of a Scenario: a setup, an action, or an expectation.
+type Step struct {
+	Name string
+	Do   func(s *Scenario) error
+}
+
+// Scenario runs a daemon against fake backends in a temporary town:
+//
+//	s := testharness.NewScenario(t)
+//	s.Run(
+//		testharness.SessionRunning("hq-mayor"),
+//		testharness.LifecycleMail("mayor", "restart", time.Minute),
+//		testharness.ProcessLifecycle(),
+//		testharness.ExpectEvents("kill hq-mayor", "new hq-mayor", "keys hq-mayor"),
+//		testharness.ExpectInboxEmpty("deacon/"),
+//	)
+//
+// The daemon is created on the first step, after Configure.
+type Scenario struct {
+	t        testing.TB
+	TownRoot string
+
+	Tmux  *FakeTmux
+	Mail  *FakeMail
+	Beads *FakeBeads
+
+	log    *syncBuffer
+	daemon *daemon.Daemon
+}
+
+// NewScenario creates a scenario in an empty temporary town.
+func NewScenario(t testing.TB) *Scenario {
+	t.Helper()
+	townRoot := t.TempDir()
+	for _, dir := range []string{"mayor", "daemon"} {
+		if err := os.MkdirAll(filepath.Join(townRoot, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Scenario{
+		t:        t,
+		TownRoot: townRoot,
+		Tmux:     NewFakeTmux(),
+		Mail:     NewFakeMail(),
+		Beads:    NewFakeBeads(),
+		log:      &syncBuffer{},
+	}
+}
+
+// Configure sets mayor/daemon.json. It must be called before the first step.
+func (s *Scenario) Configure(cfg *daemon.DaemonPatrolConfig) *Scenario {
+	s.t.Helper()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.WriteConfig(data); err != nil {
+		s.t.Fatal(err)
+	}
+	return s
+}
+
+// WriteConfig sets mayor/daemon.json from raw JSON. It must be called
+// before the daemon is created.
+func (s *Scenario) WriteConfig(data []byte) error {
+	if s.daemon != nil {
+		return fmt.Errorf("testharness: config written after the daemon was created")
+	}
+	return os.WriteFile(daemon.PatrolConfigFile(s.TownRoot), data, 0644)
+}
+
+// Daemon returns the daemon under test, creating it on first use.
+func (s *Scenario) Daemon() *daemon.Daemon {
+	if s.daemon == nil {
+		s.daemon = daemon.NewWithBackends(daemon.DefaultConfig(s.TownRoot), daemon.Backends{
+			Sessions: s.Tmux,
+			Mail:     s.Mail,
+			Beads:    s.Beads,
+			Sleep:    func(time.Duration) {},
+		}, log.New(s.log, "", 0))
+	}
+	return s.daemon
+}
+
+// Log returns everything the daemon has logged so far.
+func (s *Scenario) Log() string {
+	return s.log.String()
+}
+
+// Run runs steps in order, failing the test at the first step that fails.
+func (s *Scenario) Run(steps ...Step) {
+	s.t.Helper()
+	s.Daemon()
+	for i, step := range steps {
+		if err := step.Do(s); err != nil {
+			s.t.Fatalf("step %d (%s): %v\ndaemon log:\n%s", i+1, step.Name, err, s.Log())
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the daemon's logger.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// --- Setup steps ---
+
+// SessionRunning creates a session with a live agent.
+func SessionRunning(name string) Step {
+	return Step{Name: "session running " + name, Do: func(s *Scenario) error {
+		s.Tmux.AddSession(name, true)
+		return nil
+	}}
+}
+
+// SessionZombie creates a session whose agent has exited.
+func SessionZombie(name string) Step {
+	return Step{Name: "zombie session " + name, Do: func(s *Scenario) error {
+		s.Tmux.AddSession(name, false)
+		return nil
+	}}
+}
+
+// Rig registers a rig in mayor/rigs.json and creates its directory.
+func Rig(name string) Step {
+	return Step{Name: "rig " + name, Do: func(s *Scenario) error {
+		path := filepath.Join(s.TownRoot, "mayor", "rigs.json")
+		registry := struct {
+			Rigs map[string]any `json:"rigs"`
+		}{Rigs: map[string]any{}}
+		if data, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, &registry); err != nil {
+				return err
+			}
+		}
+		registry.Rigs[name] = map[string]any{}
+		data, err := json.Marshal(registry)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(s.TownRoot, name), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}}
+}
+
+// CrewWorkspace creates a crew member's workspace as a (bare-bones) checkout.
+func CrewWorkspace(rig, name string) Step {
+	return Step{Name: "crew workspace " + rig + "/" + name, Do: func(s *Scenario) error {
+		return os.MkdirAll(filepath.Join(s.TownRoot, rig, "crew", name, ".git"), 0755)
+	}}
+}
+
+// LifecycleMail delivers a lifecycle request from identity to the deacon,
+// sent age ago.
+func LifecycleMail(from, action string, age time.Duration) Step {
+	return Step{Name: fmt.Sprintf("lifecycle mail %s %s", from, action), Do: func(s *Scenario) error {
+		body, err := json.Marshal(daemon.LifecycleBody{Action: action})
+		if err != nil {
+			return err
+		}
+		s.Mail.Deliver("deacon/", daemon.BeadsMessage{
+			From:      from,
+			Subject:   fmt.Sprintf("LIFECYCLE: %s requesting %s", from, action),
+			Body:      string(body),
+			Timestamp: time.Now().Add(-age).Format(time.RFC3339),
+		})
+		return nil
+	}}
+}
+
+// --- Actions ---
+
+// ProcessLifecycle runs one pass over the deacon inbox.
+func ProcessLifecycle() Step {
+	return Step{Name: "process lifecycle requests", Do: func(s *Scenario) error {
+		s.Daemon().ProcessLifecycleRequests()
+		return nil
+	}}
+}
+
+// Do runs an arbitrary action or check.
+func Do(name string, fn func(s *Scenario) error) Step {
+	return Step{Name: name, Do: fn}
+}
+
+// --- Expectations ---
+
+// ExpectEvents checks that the tmux events occurred in this order (other
+// events may come between them).
+func ExpectEvents(events ...string) Step {
+	return Step{Name: "expect events " + strings.Join(events, ", "), Do: func(s *Scenario) error {
+		got := s.Tmux.Events()
+		next := 0
+		for _, event := range got {
+			if next < len(events) && event == events[next] {
+				next++
+			}
+		}
+		if next < len(events) {
+			return fmt.Errorf("missing event %q; events were %q", events[next], got)
+		}
+		return nil
+	}}
+}
+
+// ExpectNoEvent checks that a tmux event never occurred.
+func ExpectNoEvent(event string) Step {
+	return Step{Name: "expect no event " + event, Do: func(s *Scenario) error {
+		if got := s.Tmux.Events(); slices.Contains(got, event) {
+			return fmt.Errorf("unexpected event %q; events were %q", event, got)
+		}
+		return nil
+	}}
+}
+
+// ExpectAgentRunning checks that a session exists with a live agent.
+func ExpectAgentRunning(name string) Step {
+	return Step{Name: "expect agent running in " + name, Do: func(s *Scenario) error {
+		session := s.Tmux.Session(name)
+		if session == nil || !session.AgentRunning {
+			return fmt.Errorf("no live agent in session %s (session: %+v)", name, session)
+		}
+		return nil
+	}}
+}
+
+// ExpectNoSession checks that a session doesn't exist.
+func ExpectNoSession(name string) Step {
+	return Step{Name: "expect no session " + name, Do: func(s *Scenario) error {
+		if s.Tmux.Session(name) != nil {
+			return fmt.Errorf("session %s exists", name)
+		}
+		return nil
+	}}
+}
+
+// ExpectInboxEmpty checks that identity has no mail left.
+func ExpectInboxEmpty(identity string) Step {
+	return Step{Name: "expect empty inbox " + identity, Do: func(s *Scenario) error {
+		if msgs := s.Mail.Messages(identity); len(msgs) > 0 {
+			return fmt.Errorf("%d message(s) left in %s: %+v", len(msgs), identity, msgs)
+		}
+		return nil
+	}}
+}
+
+// ExpectInboxCount checks how many messages identity has.
+func ExpectInboxCount(identity string, n int) Step {
+	return Step{Name: fmt.Sprintf("expect %d message(s) in %s", n, identity), Do: func(s *Scenario) error {
+		if msgs := s.Mail.Messages(identity); len(msgs) != n {
+			return fmt.Errorf("%d message(s) in %s, want %d: %+v", len(msgs), identity, n, msgs)
+		}
+		return nil
+	}}
+}
+
+// ExpectMailSent checks that mail whose subject contains subject was sent to to.
+func ExpectMailSent(to, subject string) Step {
+	return Step{Name: fmt.Sprintf("expect mail to %s about %q", to, subject), Do: func(s *Scenario) error {
+		for _, mail := range s.Mail.SentTo(to) {
+			if strings.Contains(mail.Subject, subject) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no mail to %s with subject containing %q; sent: %+v", to, subject, s.Mail.Sent())
+	}}
+}
+
+// ExpectLog checks that the daemon logged a line containing substr.
+func ExpectLog(substr string) Step {
+	return Step{Name: fmt.Sprintf("expect log %q", substr), Do: func(s *Scenario) error {
+		if !strings.Contains(s.Log(), substr) {
+			return fmt.Errorf("daemon log has no %q", substr)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,162 @@
+package testharness
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/daemon"
+)
+
+func TestScenario_RestartRunningSession(t *testing.T) {
+	s := NewScenario(t)
+	s.Run(
+		SessionRunning("hq-mayor"),
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectEvents("kill hq-mayor", "new hq-mayor", "keys hq-mayor"),
+		ExpectAgentRunning("hq-mayor"),
+		ExpectInboxEmpty("deacon/"),
+	)
+}
+
+func TestScenario_StaleRequestDeletedWithoutExecution(t *testing.T) {
+	s := NewScenario(t)
+	s.Run(
+		SessionRunning("hq-mayor"),
+		LifecycleMail("mayor", "restart", 7*time.Hour),
+		ProcessLifecycle(),
+		ExpectNoEvent("kill hq-mayor"),
+		ExpectAgentRunning("hq-mayor"),
+		ExpectInboxEmpty("deacon/"),
+		ExpectLog("Ignoring stale lifecycle request from mayor"),
+	)
+}
+
+func TestScenario_ClaimBeforeExecute(t *testing.T) {
+	// The request must be deleted before the session is killed, so a daemon
+	// that dies mid-restart never replays it.
+	s := NewScenario(t)
+	s.Run(
+		SessionRunning("hq-mayor"),
+		LifecycleMail("mayor", "restart", time.Minute),
+		Do("delete before kill", func(s *Scenario) error {
+			id := s.Mail.Messages("deacon/")[0].ID
+			s.Tmux.OnKill = func(string) {
+				if !slices.Contains(s.Mail.Deleted(), id) {
+					s.t.Errorf("session killed before request %s was deleted", id)
+				}
+			}
+			return nil
+		}),
+		ProcessLifecycle(),
+		ExpectEvents("kill hq-mayor"),
+		ExpectInboxEmpty("deacon/"),
+	)
+}
+
+func TestScenario_RestartZombieSession(t *testing.T) {
+	s := NewScenario(t)
+	s.Run(
+		SessionZombie("hq-mayor"),
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectEvents("kill hq-mayor", "new hq-mayor", "keys hq-mayor"),
+		ExpectAgentRunning("hq-mayor"),
+	)
+}
+
+func TestScenario_UnreachableInboxDoesNothing(t *testing.T) {
+	s := NewScenario(t)
+	s.Mail.InboxErr = fmt.Errorf("gt: not found")
+	s.Run(
+		SessionRunning("hq-mayor"),
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectNoEvent("kill hq-mayor"),
+		ExpectInboxCount("deacon/", 1),
+		ExpectLog("failed to fetch deacon inbox"),
+	)
+}
+
+func TestScenario_RestartAppliesRoleLayout(t *testing.T) {
+	s := NewScenario(t)
+	s.Beads.SetRoleConfig(beads.RoleBeadIDTown("mayor"), &beads.RoleConfig{
+		Layout:        "main-vertical",
+		LayoutPanes:   []string{"tail -F {town}/daemon/daemon.log"},
+		LayoutWindows: []beads.RoleWindow{{Name: "feed", Command: "gt feed"}},
+	})
+	s.Run(
+		SessionRunning("hq-mayor"),
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectEvents("new hq-mayor", "layout hq-mayor", "keys hq-mayor"),
+		Do("layout expanded", func(s *Scenario) error {
+			layout := s.Tmux.Session("hq-mayor").Layout
+			want := "tail -F " + s.TownRoot + "/daemon/daemon.log"
+			if layout.Layout != "main-vertical" || len(layout.Panes) != 1 || layout.Panes[0] != want {
+				return fmt.Errorf("layout = %+v, want pane %q", layout, want)
+			}
+			if len(layout.Windows) != 1 || layout.Windows[0].Name != "feed" {
+				return fmt.Errorf("windows = %+v", layout.Windows)
+			}
+			return nil
+		}),
+	)
+}
+
+func TestScenario_EmergencyStop(t *testing.T) {
+	emergencyMail := func(from, priority string) Step {
+		return Do("emergency stop mail from "+from, func(s *Scenario) error {
+			s.Mail.Deliver("deacon/", daemon.BeadsMessage{
+				From:     from,
+				Subject:  "EMERGENCY_STOP: runaway spend",
+				Priority: priority,
+			})
+			return nil
+		})
+	}
+
+	s := NewScenario(t)
+	s.Run(
+		SessionRunning("hq-mayor"),
+		SessionRunning("hq-deacon"),
+
+		// Low priority or an unlisted sender is rejected
+		emergencyMail("mayor/", "normal"),
+		emergencyMail("gastown/crew/max", "urgent"),
+		ProcessLifecycle(),
+		ExpectNoEvent("kill hq-mayor"),
+		ExpectInboxEmpty("deacon/"),
+
+		emergencyMail("mayor/", "urgent"),
+		ProcessLifecycle(),
+		ExpectEvents("kill hq-mayor"),
+		ExpectEvents("kill hq-deacon"),
+		ExpectNoSession("hq-mayor"),
+		ExpectLog("EMERGENCY STOP requested by mayor/: runaway spend"),
+		Do("stop recorded", func(s *Scenario) error {
+			stop, err := daemon.LoadEmergencyStop(s.TownRoot)
+			if err != nil || stop == nil || len(stop.Killed) != 2 {
+				return fmt.Errorf("stop = %+v, %v", stop, err)
+			}
+			return nil
+		}),
+
+		// Restarts are refused until resume
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectNoEvent("new hq-mayor"),
+		ExpectMailSent("mayor/", "restart mayor rejected"),
+
+		Do("resume", func(s *Scenario) error {
+			_, err := daemon.ResumeFromEmergencyStop(s.TownRoot)
+			return err
+		}),
+		LifecycleMail("mayor", "restart", time.Minute),
+		ProcessLifecycle(),
+		ExpectEvents("new hq-mayor"),
+	)
+}
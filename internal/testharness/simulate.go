@@ -0,0 +1,257 @@
+package testharness
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Script is a lifecycle simulation for 'gt daemon simulate': a sandbox town
+// (rigs, crew, sessions), then steps that deliver lifecycle mail, let the
+// daemon process it, and check what it decided.
+//
+//	{
+//	  "name": "crew cycle restarts the session",
+//	  "rigs": ["gastown"],
+//	  "crew": ["gastown/max"],
+//	  "sessions": {"gt-gastown-crew-max": "running"},
+//	  "steps": [
+//	    {"mail": {"from": "gastown/crew/max", "action": "cycle"}},
+//	    {"process": true},
+//	    {"expect": {"events": ["kill gt-gastown-crew-max", "new gt-gastown-crew-max"],
+//	                "running": ["gt-gastown-crew-max"], "inbox_empty": ["deacon/"]}}
+//	  ]
+//	}
+type Script struct {
+	Name string `json:"name,omitempty"`
+
+	// Config is an inline mayor/daemon.json. It overrides the config the
+	// simulation is run with.
+	Config json.RawMessage `json:"config,omitempty"`
+
+	Rigs []string `json:"rigs,omitempty"`
+
+	// Crew lists crew workspaces as "rig/name".
+	Crew []string `json:"crew,omitempty"`
+
+	// Sessions maps session names to "running" or "zombie".
+	Sessions map[string]string `json:"sessions,omitempty"`
+
+	Steps []ScriptStep `json:"steps"`
+}
+
+// ScriptStep is one step of a Script; exactly one field is set.
+type ScriptStep struct {
+	Mail    *ScriptMail   `json:"mail,omitempty"`
+	Process bool          `json:"process,omitempty"`
+	Expect  *ScriptExpect `json:"expect,omitempty"`
+}
+
+// ScriptMail is a lifecycle request delivered to the deacon.
+type ScriptMail struct {
+	From   string `json:"from"`
+	Action string `json:"action"`
+
+	// Age is how long ago the request was sent (Go duration, default "1m").
+	Age string `json:"age,omitempty"`
+}
+
+// ScriptExpect lists checks of the daemon's decisions so far.
+type ScriptExpect struct {
+	// Events must have occurred in this order ("kill hq-mayor", "new
+	// hq-mayor", "keys hq-mayor", "rename a b", ...).
+	Events   []string `json:"events,omitempty"`
+	NoEvents []string `json:"no_events,omitempty"`
+
+	// Running sessions have a live agent; NoSession sessions don't exist.
+	Running   []string `json:"running,omitempty"`
+	NoSession []string `json:"no_session,omitempty"`
+
+	InboxEmpty []string `json:"inbox_empty,omitempty"`
+
+	// MailSent lists mail the daemon must have sent, matched by recipient
+	// and subject substring.
+	MailSent []ScriptSentMail `json:"mail_sent,omitempty"`
+
+	// Log lists substrings the daemon log must contain.
+	Log []string `json:"log,omitempty"`
+}
+
+// ScriptSentMail matches a sent message.
+type ScriptSentMail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+}
+
+// LoadScript reads and checks a simulation script.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: path is provided by the user
+	if err != nil {
+		return nil, err
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if _, err := script.steps(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &script, nil
+}
+
+// setup returns the steps that build the sandbox town.
+func (sc *Script) setup() []Step {
+	var steps []Step
+	for _, rig := range sc.Rigs {
+		steps = append(steps, Rig(rig))
+	}
+	for _, crew := range sc.Crew {
+		rig, name, _ := strings.Cut(crew, "/")
+		steps = append(steps, CrewWorkspace(rig, name))
+	}
+	names := make([]string, 0, len(sc.Sessions))
+	for name := range sc.Sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if sc.Sessions[name] == "zombie" {
+			steps = append(steps, SessionZombie(name))
+		} else {
+			steps = append(steps, SessionRunning(name))
+		}
+	}
+	return steps
+}
+
+// steps converts the script's steps, rejecting malformed ones. Each
+// expectation becomes one step per check, so a report names the check
+// that failed.
+func (sc *Script) steps() ([]Step, error) {
+	for _, crew := range sc.Crew {
+		if rig, name, ok := strings.Cut(crew, "/"); !ok || rig == "" || name == "" {
+			return nil, fmt.Errorf("crew %q: want rig/name", crew)
+		}
+	}
+	for name, kind := range sc.Sessions {
+		if kind != "running" && kind != "zombie" {
+			return nil, fmt.Errorf("session %s: state %q must be running or zombie", name, kind)
+		}
+	}
+
+	var steps []Step
+	for i, st := range sc.Steps {
+		set := 0
+		if st.Mail != nil {
+			set++
+			age := time.Minute
+			if st.Mail.Age != "" {
+				d, err := time.ParseDuration(st.Mail.Age)
+				if err != nil {
+					return nil, fmt.Errorf("step %d: invalid age %q", i+1, st.Mail.Age)
+				}
+				age = d
+			}
+			if st.Mail.From == "" || st.Mail.Action == "" {
+				return nil, fmt.Errorf("step %d: mail needs from and action", i+1)
+			}
+			steps = append(steps, LifecycleMail(st.Mail.From, st.Mail.Action, age))
+		}
+		if st.Process {
+			set++
+			steps = append(steps, ProcessLifecycle())
+		}
+		if e := st.Expect; e != nil {
+			set++
+			if len(e.Events) > 0 {
+				steps = append(steps, ExpectEvents(e.Events...))
+			}
+			for _, event := range e.NoEvents {
+				steps = append(steps, ExpectNoEvent(event))
+			}
+			for _, name := range e.Running {
+				steps = append(steps, ExpectAgentRunning(name))
+			}
+			for _, name := range e.NoSession {
+				steps = append(steps, ExpectNoSession(name))
+			}
+			for _, identity := range e.InboxEmpty {
+				steps = append(steps, ExpectInboxEmpty(identity))
+			}
+			for _, m := range e.MailSent {
+				steps = append(steps, ExpectMailSent(m.To, m.Subject))
+			}
+			for _, substr := range e.Log {
+				steps = append(steps, ExpectLog(substr))
+			}
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("step %d: set exactly one of mail, process, expect", i+1)
+		}
+	}
+	return steps, nil
+}
+
+// StepResult is the outcome of one simulation step.
+type StepResult struct {
+	Step  string `json:"step"`
+	Error string `json:"error,omitempty"`
+}
+
+// SimulationReport is the outcome of a simulation.
+type SimulationReport struct {
+	Name    string       `json:"name,omitempty"`
+	Steps   []StepResult `json:"steps"`
+	Failed  int          `json:"failed"`
+	Log     string       `json:"log"`
+	Events  []string     `json:"events"`
+	MailOut []SentMail   `json:"mail_sent,omitempty"`
+}
+
+// Simulate runs a script in a sandbox town with the given mayor/daemon.json
+// (nil for defaults; the script's inline config wins). Failed checks are
+// reported and don't stop the run; an error means the sandbox couldn't be
+// set up.
+func Simulate(sc *Script, config []byte) (*SimulationReport, error) {
+	steps, err := sc.steps()
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSandbox()
+	if err != nil {
+		return nil, fmt.Errorf("creating sandbox town: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	if len(sc.Config) > 0 {
+		config = sc.Config
+	}
+	if len(config) > 0 {
+		if err := s.WriteConfig(config); err != nil {
+			return nil, fmt.Errorf("writing sandbox config: %w", err)
+		}
+	}
+	// Rigs must exist before the daemon loads the town
+	for _, step := range sc.setup() {
+		if err := step.Do(s); err != nil {
+			return nil, fmt.Errorf("setting up sandbox: %s: %w", step.Name, err)
+		}
+	}
+
+	report := &SimulationReport{Name: sc.Name}
+	for _, step := range steps {
+		result := StepResult{Step: step.Name}
+		if err := s.Exec(step); err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	report.Log = s.Log()
+	report.Events = s.Tmux.Events()
+	report.MailOut = s.Mail.Sent()
+	return report, nil
+}
@@ -0,0 +1,81 @@
+package testharness
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	script := &Script{
+		Name:     "mayor restart",
+		Sessions: map[string]string{"hq-mayor": "running"},
+		Steps: []ScriptStep{
+			{Mail: &ScriptMail{From: "mayor", Action: "restart"}},
+			{Process: true},
+			{Expect: &ScriptExpect{
+				Events:     []string{"kill hq-mayor", "new hq-mayor"},
+				Running:    []string{"hq-mayor"},
+				InboxEmpty: []string{"deacon/"},
+				NoSession:  []string{"hq-mayor"}, // wrong on purpose
+			}},
+		},
+	}
+
+	report, err := Simulate(script, nil)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if report.Failed != 1 {
+		t.Fatalf("Failed = %d, want 1: %+v", report.Failed, report.Steps)
+	}
+	for _, step := range report.Steps {
+		if failed := step.Error != ""; failed != strings.HasPrefix(step.Step, "expect no session") {
+			t.Errorf("step %+v: only the no_session check should fail", step)
+		}
+	}
+	if len(report.Events) == 0 {
+		t.Error("report has no events")
+	}
+}
+
+func TestSimulateStaleRequestConfig(t *testing.T) {
+	script := &Script{
+		Config:   []byte(`{"type":"daemon-patrol-config","version":1,"lifecycle":{"max_age":{"restart":"10s"}}}`),
+		Sessions: map[string]string{"hq-mayor": "running"},
+		Steps: []ScriptStep{
+			{Mail: &ScriptMail{From: "mayor", Action: "restart", Age: "1m"}},
+			{Process: true},
+			{Expect: &ScriptExpect{NoEvents: []string{"kill hq-mayor"}, InboxEmpty: []string{"deacon/"}}},
+		},
+	}
+	report, err := Simulate(script, nil)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if report.Failed != 0 {
+		t.Fatalf("Failed = %d: %+v\n%s", report.Failed, report.Steps, report.Log)
+	}
+}
+
+func TestLoadScriptRejectsMalformedSteps(t *testing.T) {
+	tests := map[string]string{
+		"two fields":   `{"steps":[{"process":true,"mail":{"from":"mayor","action":"cycle"}}]}`,
+		"empty step":   `{"steps":[{}]}`,
+		"bad age":      `{"steps":[{"mail":{"from":"mayor","action":"cycle","age":"soon"}}]}`,
+		"bad session":  `{"sessions":{"hq-mayor":"asleep"},"steps":[]}`,
+		"bad crew":     `{"crew":["max"],"steps":[]}`,
+		"missing from": `{"steps":[{"mail":{"action":"cycle"}}]}`,
+	}
+	dir := t.TempDir()
+	for name, data := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScript(path); err == nil {
+			t.Errorf("%s: LoadScript succeeded, want error", name)
+		}
+	}
+}
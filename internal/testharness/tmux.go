@@ -0,0 +1,322 @@
+// Package testharness provides in-memory stand-ins for the daemon's tmux,
+// mail, and bd backends, and a small scenario DSL for driving the daemon's
+// lifecycle logic through multi-step tests without real subprocesses.
+package testharness
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/daemon"
+	"github.com/steveyegge/gastown/internal/tmux"
+)
+
+var _ daemon.SessionBackend = (*FakeTmux)(nil)
+
+// FakeSession is one session of a FakeTmux.
+type FakeSession struct {
+	Name    string
+	WorkDir string
+	Env     map[string]string
+
+	// Keys and Nudges record what was typed into the session, in order.
+	Keys   []string
+	Nudges []string
+
+	// AgentRunning reports whether an agent process runs in the session.
+	// It is set when a start command ("exec ...") is sent.
+	AgentRunning bool
+
+	Piped bool
+
+	// PanePID is reported by GetPanePID; 0 means the pane has no process.
+	PanePID int
+
+	// Activity is reported by SessionActivity as the last pane output.
+	Activity time.Time
+
+	// Layout is the last layout applied to the session.
+	Layout tmux.SessionLayout
+}
+
+// FakeTmux is an in-memory daemon.SessionBackend. Every mutating call is
+// recorded in Events ("new gt-x", "kill gt-x", "rename a b", ...).
+type FakeTmux struct {
+	mu       sync.Mutex
+	sessions map[string]*FakeSession
+	events   []string
+
+	// Unavailable makes IsAvailable report false.
+	Unavailable bool
+
+	// OnKill, if set, is called with the session name before a session is
+	// killed, for checking what happened before the kill.
+	OnKill func(name string)
+
+	// OnAgentStart, if set, is called when a start command starts the agent
+	// of a session (e.g. to have a fake agent write its state file).
+	OnAgentStart func(session FakeSession)
+}
+
+// NewFakeTmux returns a FakeTmux with no sessions.
+func NewFakeTmux() *FakeTmux {
+	return &FakeTmux{sessions: make(map[string]*FakeSession)}
+}
+
+// AddSession creates a session directly, without recording an event, for
+// setting up a scenario.
+func (f *FakeTmux) AddSession(name string, agentRunning bool) *FakeSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := &FakeSession{Name: name, Env: make(map[string]string), AgentRunning: agentRunning}
+	f.sessions[name] = s
+	return s
+}
+
+// Session returns a copy of the named session, or nil if it doesn't exist.
+func (f *FakeTmux) Session(name string) *FakeSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sessions[name]
+	if !ok {
+		return nil
+	}
+	copied := *s
+	copied.Env = make(map[string]string, len(s.Env))
+	for k, v := range s.Env {
+		copied.Env[k] = v
+	}
+	copied.Keys = append([]string(nil), s.Keys...)
+	copied.Nudges = append([]string(nil), s.Nudges...)
+	return &copied
+}
+
+// Events returns the recorded calls in order.
+func (f *FakeTmux) Events() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.events...)
+}
+
+func (f *FakeTmux) record(format string, args ...any) {
+	f.events = append(f.events, fmt.Sprintf(format, args...))
+}
+
+func (f *FakeTmux) get(name string) (*FakeSession, error) {
+	s, ok := f.sessions[name]
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", name)
+	}
+	return s, nil
+}
+
+func (f *FakeTmux) IsAvailable() bool {
+	return !f.Unavailable
+}
+
+func (f *FakeTmux) HasSession(name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.sessions[name]
+	return ok, nil
+}
+
+func (f *FakeTmux) ListSessions() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.sessions))
+	for name := range f.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// EnsureSessionFresh mirrors tmux.EnsureSessionFresh: a healthy session is
+// kept, a zombie (no agent) is replaced.
+func (f *FakeTmux) EnsureSessionFresh(name, workDir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[name]; ok {
+		if s.AgentRunning {
+			return nil
+		}
+		delete(f.sessions, name)
+		f.record("kill %s", name)
+	}
+	f.sessions[name] = &FakeSession{Name: name, WorkDir: workDir, Env: make(map[string]string)}
+	f.record("new %s", name)
+	return nil
+}
+
+func (f *FakeTmux) KillSession(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.get(name); err != nil {
+		return err
+	}
+	if f.OnKill != nil {
+		f.OnKill(name)
+	}
+	delete(f.sessions, name)
+	f.record("kill %s", name)
+	return nil
+}
+
+func (f *FakeTmux) KillSessionWithProcesses(name string) error {
+	return f.KillSession(name)
+}
+
+func (f *FakeTmux) GetPanePID(session string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil || s.PanePID == 0 {
+		return "", err
+	}
+	return strconv.Itoa(s.PanePID), nil
+}
+
+func (f *FakeTmux) SessionActivity(session string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Activity, nil
+}
+
+func (f *FakeTmux) RenameSession(oldName, newName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(oldName)
+	if err != nil {
+		return err
+	}
+	if _, taken := f.sessions[newName]; taken {
+		return fmt.Errorf("duplicate session: %s", newName)
+	}
+	delete(f.sessions, oldName)
+	s.Name = newName
+	f.sessions[newName] = s
+	f.record("rename %s %s", oldName, newName)
+	return nil
+}
+
+func (f *FakeTmux) SetEnvironment(session, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	s.Env[key] = value
+	return nil
+}
+
+func (f *FakeTmux) ConfigureGasTownSession(session string, theme tmux.Theme, rig, worker, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.get(session)
+	return err
+}
+
+func (f *FakeTmux) ApplyLayout(session, workDir string, layout tmux.SessionLayout) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	s.Layout = layout
+	f.record("layout %s", session)
+	return nil
+}
+
+// SendKeys records keys; a command containing "exec " starts the agent.
+func (f *FakeTmux) SendKeys(session, keys string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	s.Keys = append(s.Keys, keys)
+	if strings.Contains(keys, "exec ") {
+		s.AgentRunning = true
+		if f.OnAgentStart != nil {
+			f.OnAgentStart(*s)
+		}
+	}
+	f.record("keys %s", session)
+	return nil
+}
+
+func (f *FakeTmux) NudgeSession(session, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	s.Nudges = append(s.Nudges, message)
+	f.record("nudge %s", session)
+	return nil
+}
+
+func (f *FakeTmux) WaitForCommand(session string, excludeCommands []string, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	if !s.AgentRunning {
+		return fmt.Errorf("timeout waiting for command in %s", session)
+	}
+	return nil
+}
+
+func (f *FakeTmux) AcceptBypassPermissionsWarning(session string) error {
+	return nil
+}
+
+func (f *FakeTmux) IsClaudeRunning(session string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sessions[session]
+	return ok && s.AgentRunning
+}
+
+func (f *FakeTmux) IsPanePiped(session string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return false, err
+	}
+	return s.Piped, nil
+}
+
+func (f *FakeTmux) PipePane(session, command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.get(session)
+	if err != nil {
+		return err
+	}
+	s.Piped = true
+	return nil
+}
+
+func (f *FakeTmux) SetPaneDiedHook(session, agentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.get(session)
+	return err
+}
@@ -0,0 +1,89 @@
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Running commands beside an agent. RunInWindow runs a shell command in a
+// new, detached window of a session, so it sees the session's environment
+// and working directory without touching the agent's pane, and collects
+// the command's output and exit status once it finishes. The window closes
+// when the command exits.
+
+// ErrCommandTimeout is returned when a RunInWindow command doesn't finish
+// within its timeout. Its window is killed.
+var ErrCommandTimeout = errors.New("command did not finish in time")
+
+// runInWindowPoll is how often RunInWindow checks whether the command is done.
+const runInWindowPoll = 100 * time.Millisecond
+
+// WindowResult is the outcome of a RunInWindow command.
+type WindowResult struct {
+	// Output is the command's combined stdout and stderr.
+	Output string
+
+	// ExitCode is the command's exit status.
+	ExitCode int
+}
+
+// RunInWindow runs command with sh in a new detached window of session,
+// starting in workDir (the session's default directory if empty), and
+// waits up to timeout for it to finish.
+func (t *Tmux) RunInWindow(session, name, workDir, command string, timeout time.Duration) (*WindowResult, error) {
+	dir, err := os.MkdirTemp("", "gt-exec-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	outFile := filepath.Join(dir, "output")
+	exitFile := filepath.Join(dir, "exit")
+	script := fmt.Sprintf("(\n%s\n) >%s 2>&1 </dev/null\necho $? >%s.tmp && mv %s.tmp %s\n",
+		command, quoteArg(outFile), quoteArg(exitFile), quoteArg(exitFile), quoteArg(exitFile))
+	scriptFile := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(scriptFile, []byte(script), 0600); err != nil {
+		return nil, err
+	}
+
+	args := []string{"new-window", "-d", "-P", "-F", "#{window_id}", "-t", session + ":"}
+	if name != "" {
+		args = append(args, "-n", name)
+	}
+	if workDir != "" {
+		args = append(args, "-c", workDir)
+	}
+	windowID, err := t.run(append(args, "sh "+quoteArg(scriptFile))...)
+	if err != nil {
+		return nil, fmt.Errorf("opening window: %w", err)
+	}
+	windowID = strings.TrimSpace(windowID)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if data, err := os.ReadFile(exitFile); err == nil {
+			code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				return nil, fmt.Errorf("reading exit status: %w", err)
+			}
+			output, _ := os.ReadFile(outFile)
+			return &WindowResult{Output: string(output), ExitCode: code}, nil
+		}
+		if time.Now().After(deadline) {
+			_, _ = t.run("kill-window", "-t", windowID)
+			output, _ := os.ReadFile(outFile)
+			return &WindowResult{Output: string(output), ExitCode: -1}, ErrCommandTimeout
+		}
+		time.Sleep(runInWindowPoll)
+	}
+}
+
+// quoteArg single-quotes s for sh.
+func quoteArg(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
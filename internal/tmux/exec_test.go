@@ -0,0 +1,43 @@
+package tmux
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunInWindow(t *testing.T) {
+	if !hasTmux() {
+		t.Skip("tmux not installed")
+	}
+
+	tm := NewTmux()
+	sessionName := "gt-test-exec-" + t.Name()
+	_ = tm.KillSession(sessionName)
+	if err := tm.NewSession(sessionName, ""); err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer func() { _ = tm.KillSession(sessionName) }()
+	if err := tm.SetEnvironment(sessionName, "GT_EXEC_TEST", "from-session"); err != nil {
+		t.Fatalf("SetEnvironment: %v", err)
+	}
+
+	dir := t.TempDir()
+	result, err := tm.RunInWindow(sessionName, "exec", dir, "echo \"$GT_EXEC_TEST\"; pwd; exit 3", 10*time.Second)
+	if err != nil {
+		t.Fatalf("RunInWindow: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if !strings.Contains(result.Output, "from-session") || !strings.Contains(result.Output, filepath.Base(dir)) {
+		t.Errorf("Output = %q, want the session env and work dir", result.Output)
+	}
+
+	_, err = tm.RunInWindow(sessionName, "exec", dir, "sleep 5", 300*time.Millisecond)
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Errorf("RunInWindow with a slow command: err = %v, want ErrCommandTimeout", err)
+	}
+}
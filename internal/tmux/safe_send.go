@@ -0,0 +1,170 @@
+package tmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Safe send. Plain send-keys types into whatever the pane is doing: if a
+// user is mid-line or the agent is still printing, the keystrokes interleave
+// and Enter submits garbage. SafeSendKeys instead waits for an idle prompt,
+// pastes the text in one piece (bracketed, if the application asked for
+// it), and presses Enter only once the text has been echoed intact.
+
+var (
+	// ErrNotAtPrompt is returned when the pane shows no idle prompt
+	// within the timeout. Nothing was sent.
+	ErrNotAtPrompt = errors.New("pane is not at an idle prompt")
+
+	// ErrEchoMismatch is returned when the pasted text didn't appear in
+	// the pane. The input line was cleared and Enter was not pressed.
+	ErrEchoMismatch = errors.New("sent text was not echoed")
+)
+
+// DefaultSafeSendTimeout bounds each wait of SafeSendKeys: for a prompt,
+// then for the echo.
+const DefaultSafeSendTimeout = 5 * time.Second
+
+// shellPromptSuffixes end common shell prompts (bash, zsh, fish, starship,
+// oh-my-zsh themes).
+var shellPromptSuffixes = []string{"$", "#", "%", ">", "❯", "➜", "λ", "»"}
+
+// agentPromptMarkers start the input line of agent TUIs (Claude Code's
+// "> " and its newer "❯ "), possibly inside a "│" box.
+var agentPromptMarkers = []string{">", "❯"}
+
+// promptScanLines is how many non-empty lines above the bottom of the pane
+// are searched for an agent input line; TUIs draw rules and a status line
+// below it.
+const promptScanLines = 6
+
+// AtPrompt reports whether a pane's visible lines end at an idle prompt: an
+// agent input line with nothing typed on it, or a shell prompt with the
+// cursor line empty after it.
+func AtPrompt(lines []string) bool {
+	var tail []string
+	for i := len(lines) - 1; i >= 0 && len(tail) < promptScanLines; i-- {
+		if line := strings.TrimRightFunc(lines[i], unicode.IsSpace); line != "" {
+			tail = append(tail, line)
+		}
+	}
+	if len(tail) == 0 {
+		return false
+	}
+
+	// The bottom-most agent input line decides: empty is idle, text on it
+	// is someone typing.
+	for _, line := range tail {
+		inner := strings.TrimSpace(strings.Trim(strings.TrimSpace(line), "│|"))
+		for _, marker := range agentPromptMarkers {
+			if inner == marker {
+				return true
+			}
+			if strings.HasPrefix(inner, marker+" ") || strings.HasPrefix(inner, marker+"\u00a0") {
+				return false
+			}
+		}
+	}
+
+	last := tail[0]
+	for _, suffix := range shellPromptSuffixes {
+		if strings.HasSuffix(last, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Echoed reports whether text shows up in captured pane output. Whitespace
+// and box drawing are ignored, since long lines wrap, and only the tail of
+// text is compared, since long input scrolls. Agent TUIs that fold large
+// pastes into a "[Pasted text" placeholder count as echoed.
+func Echoed(capture, text string) bool {
+	want := []rune(squash(text))
+	if len(want) == 0 {
+		return true
+	}
+	if len(want) > 60 {
+		want = want[len(want)-60:]
+	}
+	got := squash(capture)
+	return strings.Contains(got, string(want)) || strings.Contains(capture, "[Pasted text")
+}
+
+// squash drops whitespace and box-drawing characters.
+func squash(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '│' || r == '|' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// SafeSendKeys sends text to target and presses Enter, guarding against
+// interleaving: it waits up to timeout for an idle prompt (ErrNotAtPrompt
+// if none), pastes text through a tmux buffer, and waits up to timeout for
+// the echo before pressing Enter (ErrEchoMismatch if it never shows, after
+// clearing the input line). Sends to the same target are serialized with
+// nudges. A zero timeout uses DefaultSafeSendTimeout.
+func (t *Tmux) SafeSendKeys(target, text string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultSafeSendTimeout
+	}
+	lock := getSessionNudgeLock(target)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !t.waitForPane(target, timeout, AtPrompt) {
+		return fmt.Errorf("%w: %s", ErrNotAtPrompt, target)
+	}
+
+	if err := t.pasteText(target, text); err != nil {
+		return err
+	}
+
+	if !t.waitForPane(target, timeout, func(lines []string) bool {
+		return Echoed(strings.Join(lines, "\n"), text)
+	}) {
+		_, _ = t.run("send-keys", "-t", target, "C-u")
+		return fmt.Errorf("%w: %s", ErrEchoMismatch, target)
+	}
+
+	_, err := t.run("send-keys", "-t", target, "Enter")
+	return err
+}
+
+// waitForPane polls the visible pane until check passes or timeout passes.
+func (t *Tmux) waitForPane(target string, timeout time.Duration, check func([]string) bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if out, err := t.run("capture-pane", "-p", "-t", target); err == nil && check(strings.Split(out, "\n")) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// pasteText pastes text into target in one piece through a named tmux
+// buffer. -p brackets the paste when the application enabled bracketed
+// paste mode, so shells and TUIs take embedded newlines as text.
+func (t *Tmux) pasteText(target, text string) error {
+	buffer := fmt.Sprintf("gt-send-%d", os.Getpid())
+	cmd := t.command("load-buffer", "-b", buffer, "-")
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return t.wrapError(err, stderr.String(), []string{"load-buffer"})
+	}
+	_, err := t.run("paste-buffer", "-d", "-p", "-b", buffer, "-t", target)
+	return err
+}
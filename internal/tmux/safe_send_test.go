@@ -0,0 +1,80 @@
+package tmux
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtPrompt(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{"bash prompt", []string{"$ ls", "a b", "user@host:~/gt$ ", "", ""}, true},
+		{"root prompt", []string{"root@host:/# "}, true},
+		{"starship", []string{"~/gt on main", "❯ "}, true},
+		{"shell typing", []string{"user@host:~$ git sta"}, false},
+		{"command output", []string{"$ make", "building..."}, false},
+		{"claude idle", []string{"● Done.", "", "────────", "> ", "────────", "  ⏵⏵ bypass permissions on"}, true},
+		{"claude boxed idle", []string{"╭──────╮", "│ >    │", "╰──────╯"}, true},
+		{"claude typing", []string{"────────", "> fix the bu", "────────", "  ? for shortcuts"}, false},
+		{"empty pane", []string{"", ""}, false},
+	}
+	for _, tt := range tests {
+		if got := AtPrompt(tt.lines); got != tt.want {
+			t.Errorf("%s: AtPrompt = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEchoed(t *testing.T) {
+	long := strings.Repeat("word ", 40) + "END"
+	tests := []struct {
+		capture, text string
+		want          bool
+	}{
+		{"$ echo hi", "echo hi", true},
+		{"$ echo h", "echo hi", false},
+		{"> " + long[:70] + "\n" + long[70:], long, true},
+		{"> [Pasted text #1 +12 lines]", "a\nb\nc", true},
+		{"$ ", "", true},
+	}
+	for _, tt := range tests {
+		if got := Echoed(tt.capture, tt.text); got != tt.want {
+			t.Errorf("Echoed(%q, %q) = %v, want %v", tt.capture, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSafeSendKeys(t *testing.T) {
+	if !hasTmux() {
+		t.Skip("tmux not installed")
+	}
+	tm := NewTmux()
+	session := "gt-test-safe-send"
+	_ = tm.KillSession(session)
+	if err := tm.NewSessionWithCommand(session, "", "env PS1='$ ' sh"); err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer func() { _ = tm.KillSession(session) }()
+
+	if err := tm.SafeSendKeys(session, "echo SAFE_$((20+22))", 5*time.Second); err != nil {
+		t.Fatalf("SafeSendKeys: %v", err)
+	}
+	if !tm.waitForPane(session, 5*time.Second, func(lines []string) bool {
+		return strings.Contains(strings.Join(lines, "\n"), "SAFE_42")
+	}) {
+		t.Error("command did not run")
+	}
+
+	// Someone typing at the prompt blocks the send
+	if err := tm.SendKeysRaw(session, "partial"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SafeSendKeys(session, "echo NEVER", 500*time.Millisecond); !errors.Is(err, ErrNotAtPrompt) {
+		t.Errorf("SafeSendKeys over typed input = %v, want ErrNotAtPrompt", err)
+	}
+}
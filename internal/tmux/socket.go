@@ -0,0 +1,102 @@
+package tmux
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A town can run on its own tmux server, selected with "tmux -L <socket>",
+// so its sessions never collide with another town's or the user's own, and
+// killing by prefix can't reach them. An empty socket means tmux's default
+// server (or, inside a tmux session, the server named by $TMUX).
+
+// SocketEnv overrides the tmux socket for gt commands and the daemon.
+const SocketEnv = "GT_TMUX_SOCKET"
+
+// SocketPrefix starts the socket names derived from a town name.
+const SocketPrefix = "gastown-"
+
+var (
+	defaultSocketMu sync.RWMutex
+	defaultSocket   string
+)
+
+// invalidSocketChars matches characters not allowed in a derived socket name.
+var invalidSocketChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// SetDefaultSocket sets the socket used by Tmux wrappers created with
+// NewTmux and by Command. gt sets it once at start-up from the town config.
+func SetDefaultSocket(name string) {
+	defaultSocketMu.Lock()
+	defer defaultSocketMu.Unlock()
+	defaultSocket = name
+}
+
+// DefaultSocket returns the socket set with SetDefaultSocket, or "".
+func DefaultSocket() string {
+	defaultSocketMu.RLock()
+	defer defaultSocketMu.RUnlock()
+	return defaultSocket
+}
+
+// SocketName returns the dedicated socket name for a town: "gastown-<town>",
+// with characters tmux or a file name would choke on replaced by "-".
+func SocketName(townName string) string {
+	name := strings.Trim(invalidSocketChars.ReplaceAllString(townName, "-"), "-")
+	if name == "" {
+		name = "town"
+	}
+	return SocketPrefix + name
+}
+
+// NewTmuxWithSocket creates a Tmux wrapper that talks to the tmux server on
+// the named socket. An empty name uses the default socket.
+func NewTmuxWithSocket(socket string) *Tmux {
+	return &Tmux{socket: socket}
+}
+
+// Socket returns the socket this wrapper talks to ("" for tmux's default).
+func (t *Tmux) Socket() string {
+	if t.socket != "" {
+		return t.socket
+	}
+	return DefaultSocket()
+}
+
+// socketArgs prefixes args with "-L <socket>" when a socket is set.
+func socketArgs(socket string, args []string) []string {
+	if socket == "" {
+		return args
+	}
+	return append([]string{"-L", socket}, args...)
+}
+
+// command builds a tmux command on this wrapper's socket, bound to the
+// wrapper's context if it has one.
+func (t *Tmux) command(args ...string) *exec.Cmd {
+	if t.ctx == nil {
+		return exec.Command("tmux", socketArgs(t.Socket(), args)...) //nolint:gosec // G204: args are tmux subcommands
+	}
+	cmd := exec.CommandContext(t.ctx, "tmux", socketArgs(t.Socket(), args)...) //nolint:gosec // G204: args are tmux subcommands
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+// commandWaitDelay bounds the wait for a killed command's output pipes,
+// which a child it spawned may still hold open.
+const commandWaitDelay = time.Second
+
+// Command builds a tmux command on the default socket, for callers that run
+// tmux directly rather than through a Tmux wrapper.
+func Command(args ...string) *exec.Cmd {
+	return exec.Command("tmux", socketArgs(DefaultSocket(), args)...) //nolint:gosec // G204: args are tmux subcommands
+}
+
+// SocketFromEnv returns the socket named by GT_TMUX_SOCKET, if set.
+func SocketFromEnv() string {
+	return strings.TrimSpace(os.Getenv(SocketEnv))
+}
@@ -0,0 +1,51 @@
+package tmux
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSocketName(t *testing.T) {
+	tests := map[string]string{
+		"gastown":     "gastown-gastown",
+		"My Town":     "gastown-My-Town",
+		"a/b.c":       "gastown-a-b-c",
+		"  ":          "gastown-town",
+		"work_town-2": "gastown-work_town-2",
+	}
+	for town, want := range tests {
+		if got := SocketName(town); got != want {
+			t.Errorf("SocketName(%q) = %q, want %q", town, got, want)
+		}
+	}
+}
+
+func TestSocketArgs(t *testing.T) {
+	if got := socketArgs("", []string{"list-sessions"}); !reflect.DeepEqual(got, []string{"list-sessions"}) {
+		t.Errorf("no socket: got %v", got)
+	}
+	want := []string{"-L", "gastown-x", "has-session", "-t", "=gt-mayor"}
+	if got := socketArgs("gastown-x", []string{"has-session", "-t", "=gt-mayor"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("with socket: got %v, want %v", got, want)
+	}
+}
+
+func TestSocketDefault(t *testing.T) {
+	defer SetDefaultSocket(DefaultSocket())
+
+	SetDefaultSocket("gastown-default")
+	if got := NewTmux().Socket(); got != "gastown-default" {
+		t.Errorf("NewTmux().Socket() = %q, want the default socket", got)
+	}
+	if got := NewTmuxWithSocket("gastown-other").Socket(); got != "gastown-other" {
+		t.Errorf("NewTmuxWithSocket().Socket() = %q, want gastown-other", got)
+	}
+	if got := Command("list-sessions").Args; !reflect.DeepEqual(got, []string{"tmux", "-L", "gastown-default", "list-sessions"}) {
+		t.Errorf("Command args = %v", got)
+	}
+
+	SetDefaultSocket("")
+	if got := Command("list-sessions").Args; !reflect.DeepEqual(got, []string{"tmux", "list-sessions"}) {
+		t.Errorf("Command args without socket = %v", got)
+	}
+}
@@ -3,11 +3,13 @@ package tmux
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,16 +37,27 @@ var (
 )
 
 // Tmux wraps tmux operations.
-type Tmux struct{}
+type Tmux struct {
+	socket string          // tmux -L socket; "" uses DefaultSocket
+	ctx    context.Context // bounds every tmux command; nil for none
+}
 
-// NewTmux creates a new Tmux wrapper.
+// NewTmux creates a new Tmux wrapper on the default socket.
 func NewTmux() *Tmux {
 	return &Tmux{}
 }
 
+// WithContext returns a copy of the wrapper whose tmux commands are killed
+// when ctx is done, so a caller's deadline covers a hung tmux call.
+func (t *Tmux) WithContext(ctx context.Context) *Tmux {
+	t2 := *t
+	t2.ctx = ctx
+	return &t2
+}
+
 // run executes a tmux command and returns stdout.
 func (t *Tmux) run(args ...string) (string, error) {
-	cmd := exec.Command("tmux", args...)
+	cmd := t.command(args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -686,6 +699,22 @@ func (t *Tmux) CapturePane(session string, lines int) (string, error) {
 	return t.run("capture-pane", "-p", "-t", session, "-S", fmt.Sprintf("-%d", lines))
 }
 
+// PipePane pipes a session's pane output into a shell command.
+// Uses -o so an existing pipe is left alone, making repeat calls idempotent.
+func (t *Tmux) PipePane(session, command string) error {
+	_, err := t.run("pipe-pane", "-o", "-t", session, command)
+	return err
+}
+
+// IsPanePiped reports whether a session's pane output is already being piped.
+func (t *Tmux) IsPanePiped(session string) (bool, error) {
+	out, err := t.run("display-message", "-p", "-t", session, "#{pane_pipe}")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "1", nil
+}
+
 // CapturePaneAll captures all scrollback history.
 func (t *Tmux) CapturePaneAll(session string) (string, error) {
 	return t.run("capture-pane", "-p", "-t", session, "-S", "-")
@@ -1035,6 +1064,20 @@ func (t *Tmux) GetSessionInfo(name string) (*SessionInfo, error) {
 	return info, nil
 }
 
+// SessionActivity returns when the session's current window last had
+// activity (pane output), from tmux's window_activity.
+func (t *Tmux) SessionActivity(session string) (time.Time, error) {
+	out, err := t.run("display-message", "-p", "-t", session, "#{window_activity}")
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing window_activity %q: %w", out, err)
+	}
+	return time.Unix(secs, 0), nil
+}
+
 // ApplyTheme sets the status bar style for a session.
 func (t *Tmux) ApplyTheme(session string, theme Theme) error {
 	_, err := t.run("set-option", "-t", session, "status-style", theme.Style())
@@ -1136,6 +1179,56 @@ func (t *Tmux) ConfigureGasTownSession(session string, theme Theme, rig, worker,
 	return nil
 }
 
+// SessionLayout describes panes and windows added around the agent's pane.
+type SessionLayout struct {
+	// Layout is a tmux layout name applied to the agent's window after the
+	// panes are added (e.g. "main-vertical", "tiled"). Empty keeps tmux's.
+	Layout string
+
+	// Panes are commands run in panes split off the agent's pane, in order.
+	Panes []string
+
+	// Windows are extra windows after the agent's window.
+	Windows []LayoutWindow
+}
+
+// LayoutWindow is an extra window of a SessionLayout.
+type LayoutWindow struct {
+	Name    string
+	Command string
+}
+
+// IsEmpty reports whether the layout adds nothing to a session.
+func (l SessionLayout) IsEmpty() bool {
+	return l.Layout == "" && len(l.Panes) == 0 && len(l.Windows) == 0
+}
+
+// ApplyLayout adds a layout's panes and windows to a session, running their
+// commands in workDir. The agent's pane stays the active one, so keys sent
+// to the session still reach the agent.
+func (t *Tmux) ApplyLayout(session, workDir string, layout SessionLayout) error {
+	for _, command := range layout.Panes {
+		if _, err := t.run("split-window", "-d", "-t", session, "-c", workDir, command); err != nil {
+			return fmt.Errorf("adding pane %q: %w", command, err)
+		}
+	}
+	if layout.Layout != "" {
+		if _, err := t.run("select-layout", "-t", session, layout.Layout); err != nil {
+			return fmt.Errorf("selecting layout %s: %w", layout.Layout, err)
+		}
+	}
+	for _, w := range layout.Windows {
+		args := []string{"new-window", "-d", "-t", session + ":", "-c", workDir}
+		if w.Name != "" {
+			args = append(args, "-n", w.Name)
+		}
+		if _, err := t.run(append(args, w.Command)...); err != nil {
+			return fmt.Errorf("adding window %s: %w", w.Name, err)
+		}
+	}
+	return nil
+}
+
 // EnableMouseMode enables mouse support for a tmux session.
 // This allows clicking to select panes/windows, scrolling with mouse wheel,
 // and dragging to resize panes. Hold Shift for native terminal text selection.
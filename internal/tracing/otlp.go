@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTimeout bounds each export to the collector.
+const exportTimeout = 10 * time.Second
+
+// scopeName is the instrumentation scope of exported spans.
+const scopeName = "github.com/steveyegge/gastown/internal/daemon"
+
+// OTLPExporter posts spans to an OTLP/HTTP collector using the JSON
+// encoding (POST <endpoint>/v1/traces).
+type OTLPExporter struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	http     *http.Client
+}
+
+// NewOTLPExporter creates an exporter for the collector at endpoint.
+func NewOTLPExporter(endpoint, service string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		service:  service,
+		headers:  headers,
+		http:     &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// Export sends spans to the collector.
+func (e *OTLPExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(encodeOTLP(e.service, spans))
+	if err != nil {
+		return fmt.Errorf("encoding spans: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("exporting spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// OTLP JSON wire types (opentelemetry-proto, trace/v1).
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP enum values
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// encodeOTLP builds the export request for spans.
+func encodeOTLP(service string, spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        keyValues(s.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		out = append(out, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: keyValues(map[string]string{"service.name": service})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: scopeName},
+			Spans: out,
+		}},
+	}}}
+}
+
+// keyValues converts attributes to OTLP key/values in key order.
+func keyValues(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, otlpKeyValue{Key: k, Value: otlpValue{StringValue: attrs[k]}})
+	}
+	return out
+}
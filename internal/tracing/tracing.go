@@ -0,0 +1,299 @@
+// Package tracing records OpenTelemetry-compatible spans for daemon
+// operations and exports them to an OTLP/HTTP collector as JSON.
+//
+// It implements the small part of OpenTelemetry the daemon needs (span
+// trees, W3C traceparent propagation, OTLP export) without the SDK. A nil
+// *Tracer and a nil *Span are valid and record nothing, so call sites don't
+// need to check whether tracing is on.
+//
+// Why not the OpenTelemetry SDK: gt is a single binary that every agent
+// shell runs, and the SDK plus its OTLP exporter would add its
+// metric/log/propagation modules, protobuf, and (through the exporter)
+// gRPC to the module graph, along with their lockstep version bumps. All
+// of that would be for a handful of spans per heartbeat. OTLP/HTTP with
+// JSON is a stable, documented wire format that every collector accepts,
+// and the export here is a single POST. The cost is scope: there is no
+// sampling, no metrics or logs, and no other exporters or propagators. If
+// gt needs any of those, switch to the SDK rather than growing this
+// package.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default settings
+const (
+	// DefaultServiceName is the service.name resource attribute.
+	DefaultServiceName = "gastown-daemon"
+
+	// maxBufferedSpans caps spans held between exports. When the collector
+	// is unreachable the oldest are dropped.
+	maxBufferedSpans = 4096
+)
+
+// Config configures tracing, under "tracing" in mayor/daemon.json:
+//
+//	"tracing": {
+//	  "enabled": true,
+//	  "endpoint": "http://localhost:4318",
+//	  "headers": {"x-honeycomb-team": "$HONEYCOMB_API_KEY"}
+//	}
+type Config struct {
+	// Enabled turns on span recording and export. Default: false.
+	Enabled bool `json:"enabled"`
+
+	// Endpoint is the base URL of the OTLP/HTTP collector; spans are posted
+	// to <endpoint>/v1/traces. Default: OTEL_EXPORTER_OTLP_ENDPOINT, then
+	// http://localhost:4318.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// ServiceName is the service.name of exported spans (default
+	// OTEL_SERVICE_NAME, then "gastown-daemon").
+	ServiceName string `json:"service_name,omitempty"`
+
+	// Headers are sent with every export. Values starting with "$" are
+	// read from that environment variable, so secrets stay out of config.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Validate checks the config for errors.
+func (c *Config) Validate() error {
+	if c == nil || !c.Enabled || c.Endpoint == "" {
+		return nil
+	}
+	if !strings.HasPrefix(c.Endpoint, "https://") && !strings.HasPrefix(c.Endpoint, "http://") {
+		return fmt.Errorf("tracing: endpoint %q must be an http(s) URL", c.Endpoint)
+	}
+	return nil
+}
+
+// endpoint returns the collector URL, falling back to the OTel env var.
+func (c *Config) endpoint(getenv func(string) string) string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	if env := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); env != "" {
+		return env
+	}
+	return "http://localhost:4318"
+}
+
+// serviceName returns the service name, falling back to the OTel env var.
+func (c *Config) serviceName(getenv func(string) string) string {
+	if c.ServiceName != "" {
+		return c.ServiceName
+	}
+	if env := getenv("OTEL_SERVICE_NAME"); env != "" {
+		return env
+	}
+	return DefaultServiceName
+}
+
+// headers resolves $VAR header values from the environment.
+func (c *Config) headers(getenv func(string) string) map[string]string {
+	out := make(map[string]string, len(c.Headers))
+	for k, v := range c.Headers {
+		if strings.HasPrefix(v, "$") {
+			v = getenv(strings.TrimPrefix(v, "$"))
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer starts spans and buffers finished ones until Flush.
+type Tracer struct {
+	exporter Exporter
+
+	mu       sync.Mutex
+	finished []*Span
+	dropped  int
+}
+
+// New returns a tracer for cfg, or nil when tracing is off.
+func New(cfg *Config) *Tracer {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	return NewWithExporter(NewOTLPExporter(cfg.endpoint(os.Getenv), cfg.serviceName(os.Getenv), cfg.headers(os.Getenv)))
+}
+
+// NewWithExporter returns a tracer that exports to e.
+func NewWithExporter(e Exporter) *Tracer {
+	return &Tracer{exporter: e}
+}
+
+// Start begins a span. With a nil parent it starts a new trace.
+func (t *Tracer) Start(parent *Span, name string, attrs ...string) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{
+		tracer: t,
+		parent: parent,
+		Name:   name,
+		SpanID: newID(8),
+		Start:  time.Now(),
+	}
+	if parent != nil {
+		s.TraceID, s.ParentID = parent.TraceID, parent.SpanID
+	} else {
+		s.TraceID = newID(16)
+	}
+	s.SetAttributes(attrs...)
+	return s
+}
+
+// Flush exports the spans finished since the last flush. Spans that fail
+// to export are kept for the next flush, up to the buffer cap.
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.finished
+	t.finished = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	err := t.exporter.Export(spans)
+	if err != nil {
+		t.mu.Lock()
+		t.finished = append(spans, t.finished...)
+		t.trim()
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// finish buffers a finished span for export.
+func (t *Tracer) finish(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = append(t.finished, s)
+	t.trim()
+}
+
+// trim drops the oldest spans over the buffer cap. Caller holds t.mu.
+func (t *Tracer) trim() {
+	if over := len(t.finished) - maxBufferedSpans; over > 0 {
+		t.finished = t.finished[over:]
+		t.dropped += over
+	}
+}
+
+// Dropped returns how many spans were discarded because the buffer was full.
+func (t *Tracer) Dropped() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// Span is one timed operation in a trace.
+type Span struct {
+	tracer *Tracer
+	parent *Span
+
+	Name     string
+	TraceID  string // 32 hex digits
+	SpanID   string // 16 hex digits
+	ParentID string // "" for a root span
+	Start    time.Time
+	End      time.Time
+
+	// Attributes describe the operation (identity, session, message id...).
+	Attributes map[string]string
+
+	// Error is set when the operation failed.
+	Error string
+}
+
+// SetAttributes adds key/value pairs. A trailing key without a value is
+// ignored.
+func (s *Span) SetAttributes(kv ...string) {
+	if s == nil {
+		return
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if s.Attributes == nil {
+			s.Attributes = make(map[string]string)
+		}
+		s.Attributes[kv[i]] = kv[i+1]
+	}
+}
+
+// Finish ends the span, recording err as its status, and queues it for
+// export. Finishing twice has no effect.
+func (s *Span) Finish(err error) {
+	if s == nil || !s.End.IsZero() {
+		return
+	}
+	s.End = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.tracer.finish(s)
+}
+
+// Parent returns the span this one was started under, or nil.
+func (s *Span) Parent() *Span {
+	if s == nil {
+		return nil
+	}
+	return s.parent
+}
+
+// Traceparent returns the W3C traceparent header value identifying the
+// span, for propagation into mail and logs. Empty for a nil span.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + s.TraceID + "-" + s.SpanID + "-01"
+}
+
+// ErrInvalidTraceparent is returned for a malformed traceparent value.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent extracts the trace and span IDs from a W3C traceparent
+// value ("00-<trace-id>-<span-id>-<flags>").
+func ParseTraceparent(v string) (traceID, spanID string, err error) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || !isHex(parts[1], 32) || !isHex(parts[2], 16) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidTraceparent, v)
+	}
+	return parts[1], parts[2], nil
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// newID returns n random bytes as hex.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recorder collects exported spans and fails while err is set.
+type recorder struct {
+	spans []*Span
+	err   error
+}
+
+func (r *recorder) Export(spans []*Span) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestSpanTree(t *testing.T) {
+	rec := &recorder{}
+	tr := NewWithExporter(rec)
+
+	root := tr.Start(nil, "lifecycle.message", "gt.message_id", "hq-1")
+	child := tr.Start(root, "session.kill")
+	child.Finish(errors.New("no such session"))
+	child.Finish(nil) // no effect
+	root.Finish(nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(rec.spans))
+	}
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || root.ParentID != "" {
+		t.Errorf("child %s/%s parent %s, root %s/%s", child.TraceID, child.SpanID, child.ParentID, root.TraceID, root.SpanID)
+	}
+	if child.Error != "no such session" || root.Attributes["gt.message_id"] != "hq-1" {
+		t.Errorf("child error %q, root attributes %v", child.Error, root.Attributes)
+	}
+	if child.Parent() != root {
+		t.Error("Parent() does not return the parent span")
+	}
+
+	traceID, spanID, err := ParseTraceparent(child.Traceparent())
+	if err != nil || traceID != root.TraceID || spanID != child.SpanID {
+		t.Errorf("ParseTraceparent(%q) = %s, %s, %v", child.Traceparent(), traceID, spanID, err)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tr *Tracer
+	span := tr.Start(nil, "mail.poll")
+	if span != nil {
+		t.Fatalf("nil tracer started %+v", span)
+	}
+	span.SetAttributes("k", "v")
+	span.Finish(nil)
+	if span.Traceparent() != "" || span.Parent() != nil || tr.Flush() != nil {
+		t.Error("nil span or tracer did something")
+	}
+	if New(&Config{}) != nil || New(nil) != nil {
+		t.Error("disabled config returned a tracer")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, v := range []string{
+		"",
+		"00-abc-def-01",
+		"00-00000000000000000000000000000000-0000000000000001-01",
+		"00-0af7651916cd43dd8448eb211c80319c-zzzzzzzzzzzzzzzz-01",
+	} {
+		if _, _, err := ParseTraceparent(v); !errors.Is(err, ErrInvalidTraceparent) {
+			t.Errorf("ParseTraceparent(%q) = %v, want ErrInvalidTraceparent", v, err)
+		}
+	}
+	traceID, spanID, err := ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if err != nil || traceID != "0af7651916cd43dd8448eb211c80319c" || spanID != "b7ad6b7169203331" {
+		t.Errorf("ParseTraceparent = %s, %s, %v", traceID, spanID, err)
+	}
+}
+
+func TestFlushKeepsSpansOnFailure(t *testing.T) {
+	rec := &recorder{err: errors.New("collector down")}
+	tr := NewWithExporter(rec)
+	tr.Start(nil, "beads.sync").Finish(nil)
+	if err := tr.Flush(); err == nil {
+		t.Fatal("Flush succeeded with a failing exporter")
+	}
+
+	rec.err = nil
+	tr.Start(nil, "mail.poll").Finish(nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.spans) != 2 || rec.spans[0].Name != "beads.sync" {
+		t.Errorf("exported %d spans after recovery, want the retained one first", len(rec.spans))
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	var got otlpRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		auth = r.Header.Get("x-api-key")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &Config{Enabled: true, Endpoint: srv.URL + "/", Headers: map[string]string{"x-api-key": "$TRACE_KEY"}}
+	getenv := func(k string) string { return map[string]string{"TRACE_KEY": "s3cret"}[k] }
+	tr := NewWithExporter(NewOTLPExporter(cfg.endpoint(getenv), cfg.serviceName(getenv), cfg.headers(getenv)))
+
+	root := tr.Start(nil, "lifecycle.cycle", "gt.identity", "gastown-crew-max")
+	tr.Start(root, "session.start").Finish(errors.New("creating session: boom"))
+	root.Finish(nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatalf("Flush = %v", err)
+	}
+
+	if auth != "s3cret" {
+		t.Errorf("x-api-key = %q", auth)
+	}
+	if len(got.ResourceSpans) != 1 || got.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != DefaultServiceName {
+		t.Fatalf("resource = %+v", got.ResourceSpans)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	start, cycle := spans[0], spans[1]
+	if start.Status.Code != otlpStatusError || start.Status.Message != "creating session: boom" || start.ParentSpanID != cycle.SpanID {
+		t.Errorf("session.start = %+v", start)
+	}
+	if cycle.Status.Code != otlpStatusOK || len(cycle.Attributes) != 1 || cycle.Attributes[0].Key != "gt.identity" {
+		t.Errorf("lifecycle.cycle = %+v", cycle)
+	}
+
+	if err := (&Config{Enabled: true, Endpoint: "localhost:4318"}).Validate(); err == nil {
+		t.Error("endpoint without scheme accepted")
+	}
+}
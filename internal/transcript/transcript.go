@@ -0,0 +1,186 @@
+// Package transcript persists tmux session output to rotated files.
+//
+// Capture is driven by tmux pipe-pane: the pane's output is piped into
+// "gt transcript record", which appends it to <town>/logs/transcripts/<session>/
+// transcript.log and rotates to transcript.log.1 ... transcript.log.N once the
+// current file passes the size limit. The files outlive the session, so the
+// witness and operators can see what an agent was doing before it died or was
+// cycled.
+package transcript
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults for rotation.
+const (
+	DefaultMaxSize = 10 * 1024 * 1024 // 10 MiB per file
+	DefaultKeep    = 5                // rotated files kept besides the current one
+)
+
+// currentFile is the name of the file being written.
+const currentFile = "transcript.log"
+
+// Dir returns the transcript directory for a tmux session.
+func Dir(townRoot, session string) string {
+	return filepath.Join(townRoot, "logs", "transcripts", session)
+}
+
+// Writer appends to a transcript directory, rotating by size.
+type Writer struct {
+	dir     string
+	maxSize int64
+	keep    int
+
+	file *os.File
+	size int64
+}
+
+// NewWriter opens (creating if needed) the current transcript file in dir.
+// maxSize <= 0 and keep <= 0 select the defaults.
+func NewWriter(dir string, maxSize int64, keep int) (*Writer, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	w := &Writer{dir: dir, maxSize: maxSize, keep: keep}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating transcript dir: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the current file for appending.
+func (w *Writer) open() error {
+	path := filepath.Join(w.dir, currentFile)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening transcript: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat transcript: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if the current file is full.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate shifts transcript.log.N-1 -> .N ... transcript.log -> .1, dropping
+// the oldest, and opens a fresh current file.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("closing transcript: %w", err)
+	}
+
+	base := filepath.Join(w.dir, currentFile)
+	_ = os.Remove(fmt.Sprintf("%s.%d", base, w.keep))
+	for i := w.keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", base, i), fmt.Sprintf("%s.%d", base, i+1))
+	}
+	if err := os.Rename(base, base+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotating transcript: %w", err)
+	}
+	return w.open()
+}
+
+// Close closes the current file.
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// Record copies r into a rotating transcript in dir until EOF.
+func Record(dir string, r io.Reader, maxSize int64, keep int) error {
+	w, err := NewWriter(dir, maxSize, keep)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// files returns the transcript files in dir, oldest first.
+func files(dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, currentFile+".*"))
+
+	// Rotated files: higher suffix is older
+	var rotated []string
+	for i := len(matches); i >= 1; i-- {
+		path := fmt.Sprintf("%s.%d", filepath.Join(dir, currentFile), i)
+		if _, err := os.Stat(path); err == nil {
+			rotated = append(rotated, path)
+		}
+	}
+	current := filepath.Join(dir, currentFile)
+	if _, err := os.Stat(current); err == nil {
+		rotated = append(rotated, current)
+	}
+	return rotated
+}
+
+// Tail returns the last n lines of the transcript in dir, reading across
+// rotated files as needed. Returns nil if there is no transcript.
+func Tail(dir string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// Walk files newest first, keeping only what's needed
+	paths := files(dir)
+	var lines []string
+	for i := len(paths) - 1; i >= 0 && len(lines) < n; i-- {
+		fileLines, err := readLines(paths[i])
+		if err != nil {
+			return nil, err
+		}
+		lines = append(fileLines, lines...)
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// readLines reads a file into lines, stripping carriage returns left by
+// terminal output.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path) //nolint:gosec // G304: path is built from the transcript dir
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,62 @@
+package transcript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriter_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 20, 2)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Current plus at most keep rotated files
+	matches, _ := filepath.Glob(filepath.Join(dir, "transcript.log*"))
+	if len(matches) != 3 {
+		t.Errorf("expected 3 transcript files, got %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "transcript.log.3")); err == nil {
+		t.Error("expected oldest file beyond keep to be dropped")
+	}
+}
+
+func TestTail_AcrossRotations(t *testing.T) {
+	dir := t.TempDir()
+	var input string
+	for i := 0; i < 10; i++ {
+		input += fmt.Sprintf("line %d\r\n", i)
+	}
+	if err := Record(dir, strings.NewReader(input), 30, 5); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	lines, err := Tail(dir, 4)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	expected := []string{"line 6", "line 7", "line 8", "line 9"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Tail = %q, expected %q", lines, expected)
+	}
+}
+
+func TestTail_Missing(t *testing.T) {
+	lines, err := Tail(filepath.Join(t.TempDir(), "nope"), 10)
+	if err != nil || lines != nil {
+		t.Errorf("expected nil, nil for missing transcript, got %v, %v", lines, err)
+	}
+}
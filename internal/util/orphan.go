@@ -12,6 +12,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/steveyegge/gastown/internal/tmux"
 )
 
 // minOrphanAge is the minimum age (in seconds) a process must be before
@@ -26,7 +28,7 @@ func getGasTownSessionPIDs() map[int]bool {
 	pids := make(map[int]bool)
 
 	// Get list of Gas Town tmux sessions (gt-* and hq-*)
-	out, err := exec.Command("tmux", "list-sessions", "-F", "#{session_name}").Output()
+	out, err := tmux.Command("list-sessions", "-F", "#{session_name}").Output()
 	if err != nil {
 		return pids // tmux not available or no sessions
 	}
@@ -40,7 +42,7 @@ func getGasTownSessionPIDs() map[int]bool {
 
 	// For each Gas Town session, get the PIDs of processes in its panes
 	for _, session := range gasTownSessions {
-		out, err := exec.Command("tmux", "list-panes", "-t", session, "-F", "#{pane_pid}").Output()
+		out, err := tmux.Command("list-panes", "-t", session, "-F", "#{pane_pid}").Output()
 		if err != nil {
 			continue
 		}
@@ -255,8 +257,7 @@ func FindOrphanedClaudeProcesses() ([]OrphanedProcess, error) {
 		}
 
 		// Match claude or codex command names
-		cmdLower := strings.ToLower(cmd)
-		if cmdLower != "claude" && cmdLower != "claude-code" && cmdLower != "codex" {
+		if !IsAgentCommand(cmd) {
 			continue
 		}
 
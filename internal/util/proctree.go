@@ -0,0 +1,135 @@
+//go:build !windows
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ProcessInfo is one entry of the process table.
+type ProcessInfo struct {
+	PID  int
+	PPID int
+	Cmd  string
+	Age  int // Age in seconds
+}
+
+// ListProcesses returns the process table (pid, parent, command, age) from ps.
+func ListProcesses() ([]ProcessInfo, error) {
+	out, err := exec.Command("ps", "-eo", "pid,ppid,comm,etime").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing processes: %w", err)
+	}
+	return parseProcessTable(string(out)), nil
+}
+
+// parseProcessTable parses "ps -eo pid,ppid,comm,etime" output. Lines that
+// don't parse (the header) are skipped.
+func parseProcessTable(out string) []ProcessInfo {
+	var procs []ProcessInfo
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		// comm may contain spaces; etime is always the last field
+		age, err := parseEtime(fields[len(fields)-1])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, ProcessInfo{
+			PID:  pid,
+			PPID: ppid,
+			Cmd:  strings.Join(fields[2:len(fields)-1], " "),
+			Age:  age,
+		})
+	}
+	return procs
+}
+
+// Descendants returns the PIDs of every descendant of root in procs.
+func Descendants(procs []ProcessInfo, root int) []int {
+	children := make(map[int][]int)
+	for _, p := range procs {
+		children[p.PPID] = append(children[p.PPID], p.PID)
+	}
+
+	var out []int
+	seen := map[int]bool{root: true}
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			out = append(out, child)
+			queue = append(queue, child)
+		}
+	}
+	return out
+}
+
+// IsAgentCommand reports whether a process command name is an agent runtime
+// (claude or codex).
+func IsAgentCommand(cmd string) bool {
+	if i := strings.LastIndexByte(cmd, '/'); i >= 0 {
+		cmd = cmd[i+1:]
+	}
+	switch strings.ToLower(cmd) {
+	case "claude", "claude-code", "codex":
+		return true
+	}
+	return false
+}
+
+// ProcessEnv returns the environment a process was started with, read from
+// /proc/<pid>/environ. It fails where /proc is unavailable (macOS) and for
+// processes owned by other users.
+func ProcessEnv(pid int) (map[string]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if k, v, ok := strings.Cut(string(entry), "="); ok && k != "" {
+			env[k] = v
+		}
+	}
+	return env, nil
+}
+
+// SignalProcess sends SIGTERM to pid, or SIGKILL if force is set. A process
+// that no longer exists is not an error.
+func SignalProcess(pid int, force bool) error {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// ProcessAlive reports whether a process exists.
+func ProcessAlive(pid int) bool {
+	return processExists(pid)
+}
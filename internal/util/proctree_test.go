@@ -0,0 +1,76 @@
+//go:build !windows
+
+package util
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestParseProcessTable(t *testing.T) {
+	out := `  PID  PPID COMMAND         ELAPSED
+    1     0 init            2-01:02:03
+  100     1 tmux: server       05:00
+  101   100 claude             04:59
+  bad     1 broken             00:01
+`
+	procs := parseProcessTable(out)
+	if len(procs) != 3 {
+		t.Fatalf("parsed %d processes, want 3: %+v", len(procs), procs)
+	}
+	if p := procs[1]; p.PID != 100 || p.PPID != 1 || p.Cmd != "tmux: server" || p.Age != 300 {
+		t.Errorf("tmux entry = %+v", p)
+	}
+	if p := procs[2]; p.PID != 101 || p.Cmd != "claude" || p.Age != 299 {
+		t.Errorf("claude entry = %+v", p)
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	procs := []ProcessInfo{
+		{PID: 1, PPID: 0},
+		{PID: 100, PPID: 1},
+		{PID: 101, PPID: 100},
+		{PID: 102, PPID: 101},
+		{PID: 103, PPID: 100},
+		{PID: 200, PPID: 1},
+	}
+	got := Descendants(procs, 100)
+	slices.Sort(got)
+	if !slices.Equal(got, []int{101, 102, 103}) {
+		t.Errorf("Descendants(100) = %v", got)
+	}
+	if got := Descendants(procs, 200); len(got) != 0 {
+		t.Errorf("Descendants(200) = %v, want none", got)
+	}
+}
+
+func TestIsAgentCommand(t *testing.T) {
+	for cmd, want := range map[string]bool{
+		"claude":                true,
+		"Claude":                true,
+		"/usr/local/bin/claude": true,
+		"claude-code":           true,
+		"codex":                 true,
+		"node":                  false,
+		"bash":                  false,
+	} {
+		if got := IsAgentCommand(cmd); got != want {
+			t.Errorf("IsAgentCommand(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
+
+func TestProcessEnv(t *testing.T) {
+	if _, err := os.Stat("/proc/self/environ"); err != nil {
+		t.Skip("/proc not available")
+	}
+	env, err := ProcessEnv(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["PATH"] != os.Getenv("PATH") {
+		t.Errorf("PATH = %q, want %q", env["PATH"], os.Getenv("PATH"))
+	}
+}
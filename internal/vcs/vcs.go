@@ -0,0 +1,400 @@
+// Package vcs abstracts the version control operations Gas Town performs on
+// agent workspaces (fetch and update before a session starts), so rigs can
+// use git, Jujutsu (jj), or Mercurial, track a remote other than origin or a
+// branch other than main, and fetch from several remotes.
+//
+// A rig opts in with a "vcs" section in its config.json:
+//
+//	"vcs": {
+//	  "kind": "jj",
+//	  "remote": "upstream",
+//	  "branch": "integration",
+//	  "fetch_remotes": ["upstream", "fork"],
+//	  "protected_branches": ["main", "release/*"]
+//	}
+//
+// Every field is optional. Without a kind the workspace is inspected (.jj,
+// .hg, otherwise git); the remote defaults to the backend's usual default and
+// the branch to the rig's default_branch, then "main". No branch is
+// protected unless listed.
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kind names a version control system.
+type Kind string
+
+// Supported version control systems.
+const (
+	Git       Kind = "git"
+	Jujutsu   Kind = "jj"
+	Mercurial Kind = "hg"
+)
+
+// DefaultBranch is used when neither the vcs config nor the rig names one.
+const DefaultBranch = "main"
+
+// Config is the "vcs" section of a rig's config.json.
+type Config struct {
+	// Kind is "git", "jj", or "hg". Empty means detect from the workspace.
+	Kind Kind `json:"kind,omitempty"`
+
+	// Remote is the remote to update from (default: origin for git and jj,
+	// default for hg).
+	Remote string `json:"remote,omitempty"`
+
+	// Branch is the branch to update to (default: the rig's default_branch).
+	Branch string `json:"branch,omitempty"`
+
+	// FetchRemotes are fetched before updating (default: just Remote).
+	// Remote is always fetched, first.
+	FetchRemotes []string `json:"fetch_remotes,omitempty"`
+
+	// ProtectedBranches are branches whose unpushed commits must never be
+	// rebased or reset by a sync. Entries may be glob patterns ("release/*").
+	ProtectedBranches []string `json:"protected_branches,omitempty"`
+}
+
+// Validate checks the config for unknown kinds and unsafe names.
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Kind {
+	case "", Git, Jujutsu, Mercurial:
+	default:
+		return fmt.Errorf("unknown vcs kind %q (want git, jj, or hg)", c.Kind)
+	}
+	for _, name := range append([]string{c.Remote, c.Branch}, c.FetchRemotes...) {
+		if strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("invalid vcs remote or branch name %q", name)
+		}
+	}
+	for _, pattern := range c.ProtectedBranches {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid protected branch pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Spec is a resolved sync target for one workspace.
+type Spec struct {
+	Kind   Kind
+	Remote string
+	Branch string
+
+	// Fetch lists the remotes to fetch, Remote first.
+	Fetch []string
+
+	// Protected are the protected branch patterns.
+	Protected []string
+}
+
+// IsProtected reports whether branch matches a protected branch pattern.
+func (s Spec) IsProtected(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, pattern := range s.Protected {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve fills in defaults for a workspace. kind is the detected kind, used
+// when the config doesn't name one; rigBranch is the rig's default_branch.
+func (c *Config) Resolve(kind Kind, rigBranch string) Spec {
+	var cfg Config
+	if c != nil {
+		cfg = *c
+	}
+	spec := Spec{Kind: cfg.Kind, Remote: cfg.Remote, Branch: cfg.Branch, Protected: cfg.ProtectedBranches}
+	if spec.Kind == "" {
+		spec.Kind = kind
+	}
+	if spec.Remote == "" {
+		spec.Remote = defaultRemote(spec.Kind)
+	}
+	if spec.Branch == "" {
+		spec.Branch = rigBranch
+	}
+	if spec.Branch == "" {
+		spec.Branch = DefaultBranch
+	}
+
+	spec.Fetch = []string{spec.Remote}
+	for _, remote := range cfg.FetchRemotes {
+		if remote != "" && !slices.Contains(spec.Fetch, remote) {
+			spec.Fetch = append(spec.Fetch, remote)
+		}
+	}
+	return spec
+}
+
+func defaultRemote(kind Kind) string {
+	if kind == Mercurial {
+		return "default"
+	}
+	return "origin"
+}
+
+// Detect returns the kind of repository dir belongs to, looking for a .jj or
+// .hg directory in dir and its parents. jj is checked first because
+// colocated jj repos also contain .git. Anything else is assumed to be git.
+func Detect(dir string) Kind {
+	for d := dir; ; {
+		if isDir(filepath.Join(d, ".jj")) {
+			return Jujutsu
+		}
+		if isDir(filepath.Join(d, ".hg")) {
+			return Mercurial
+		}
+		if exists(filepath.Join(d, ".git")) {
+			return Git
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return Git
+		}
+		d = parent
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// VCS performs workspace sync operations for one version control system.
+type VCS interface {
+	Kind() Kind
+
+	// Fetch downloads new changes from remote without touching the
+	// working copy.
+	Fetch(dir, remote string) error
+
+	// Update moves the working copy onto branch from remote, keeping local
+	// work on top (rebase).
+	Update(dir, remote, branch string) error
+
+	// The workspace guards below back branch protection, auto-stash, and
+	// conflict detection in syncWorkspace. Only git implements them; jj and
+	// hg return ErrUnsupported.
+
+	// CurrentBranch returns the checked-out branch, or "" when detached.
+	CurrentBranch(dir string) (string, error)
+
+	// Unpushed counts local commits on the current branch that no branch
+	// of remote contains.
+	Unpushed(dir, remote string) (int, error)
+
+	// Dirty reports uncommitted changes, including untracked files.
+	// Ignored files (.gitignore, info/exclude) don't count.
+	Dirty(dir string) (bool, error)
+
+	// Stash saves uncommitted changes, untracked but not ignored files
+	// included, and returns a reference that restores them.
+	Stash(dir, message string) (string, error)
+
+	// Conflicted lists files with unresolved conflicts, as left behind by
+	// an update that stopped partway.
+	Conflicted(dir string) ([]string, error)
+}
+
+// ErrUnsupported is returned by operations a backend doesn't implement.
+var ErrUnsupported = errors.New("not supported by this vcs")
+
+// For returns the backend for kind.
+func For(kind Kind) (VCS, error) {
+	switch kind {
+	case Git, "":
+		return gitVCS, nil
+	case Jujutsu:
+		return jjVCS, nil
+	case Mercurial:
+		return hgVCS, nil
+	default:
+		return nil, fmt.Errorf("unknown vcs kind %q", kind)
+	}
+}
+
+// cliVCS drives a VCS through its command-line tool. Nil commands are
+// unsupported by the tool.
+type cliVCS struct {
+	kind   Kind
+	tool   string
+	fetch  func(remote string) []string
+	update func(remote, branch string) []string
+
+	currentBranch []string
+	unpushed      func(remote string) []string // prints a count
+	status        []string                     // prints nothing when clean
+	stash         func(message string) []string
+	stashRef      []string // prints the reference of the last stash
+	conflicts     []string // prints one conflicted path per line
+
+	ctx context.Context // bounds every command; nil for none
+}
+
+// commandWaitDelay bounds the wait for a killed command's output pipes,
+// which a helper it spawned (ssh, a credential helper) may still hold open.
+const commandWaitDelay = time.Second
+
+// WithContext returns v with its commands killed when ctx is done, so a
+// caller's deadline covers a hung fetch. Backends that run no commands are
+// returned as they are.
+func WithContext(ctx context.Context, v VCS) VCS {
+	cli, ok := v.(*cliVCS)
+	if !ok {
+		return v
+	}
+	bound := *cli
+	bound.ctx = ctx
+	return &bound
+}
+
+var (
+	gitVCS = &cliVCS{
+		kind:   Git,
+		tool:   "git",
+		fetch:  func(remote string) []string { return []string{"fetch", remote} },
+		update: func(remote, branch string) []string { return []string{"pull", "--rebase", remote, branch} },
+
+		currentBranch: []string{"rev-parse", "--abbrev-ref", "HEAD"},
+		unpushed: func(remote string) []string {
+			return []string{"rev-list", "--count", "HEAD", "--not", "--remotes=" + remote}
+		},
+		status:    []string{"status", "--porcelain"},
+		stash:     func(message string) []string { return []string{"stash", "push", "--include-untracked", "-m", message} },
+		stashRef:  []string{"rev-parse", "stash@{0}"},
+		conflicts: []string{"diff", "--name-only", "--diff-filter=U"},
+	}
+
+	// jj has no pull: fetch, then rebase the working-copy commit's branch
+	// onto the remote bookmark.
+	jjVCS = &cliVCS{
+		kind:   Jujutsu,
+		tool:   "jj",
+		fetch:  func(remote string) []string { return []string{"git", "fetch", "--remote", remote} },
+		update: func(remote, branch string) []string { return []string{"rebase", "-d", branch + "@" + remote} },
+	}
+
+	// hg pull already fetched; rebase local work onto the branch head.
+	hgVCS = &cliVCS{
+		kind:   Mercurial,
+		tool:   "hg",
+		fetch:  func(remote string) []string { return []string{"pull", remote} },
+		update: func(remote, branch string) []string { return []string{"rebase", "-d", branch} },
+	}
+)
+
+func (v *cliVCS) Kind() Kind { return v.kind }
+
+func (v *cliVCS) Fetch(dir, remote string) error {
+	return v.run(dir, v.fetch(remote))
+}
+
+func (v *cliVCS) Update(dir, remote, branch string) error {
+	return v.run(dir, v.update(remote, branch))
+}
+
+func (v *cliVCS) CurrentBranch(dir string) (string, error) {
+	if v.currentBranch == nil {
+		return "", ErrUnsupported
+	}
+	out, err := v.output(dir, v.currentBranch)
+	if err != nil || out == "HEAD" {
+		return "", err
+	}
+	return out, nil
+}
+
+func (v *cliVCS) Unpushed(dir, remote string) (int, error) {
+	if v.unpushed == nil {
+		return 0, ErrUnsupported
+	}
+	out, err := v.output(dir, v.unpushed(remote))
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if _, err := fmt.Sscanf(out, "%d", &n); err != nil {
+		return 0, fmt.Errorf("%s: unexpected commit count %q", v.tool, out)
+	}
+	return n, nil
+}
+
+func (v *cliVCS) Dirty(dir string) (bool, error) {
+	if v.status == nil {
+		return false, ErrUnsupported
+	}
+	out, err := v.output(dir, v.status)
+	return out != "", err
+}
+
+func (v *cliVCS) Stash(dir, message string) (string, error) {
+	if v.stash == nil {
+		return "", ErrUnsupported
+	}
+	if err := v.run(dir, v.stash(message)); err != nil {
+		return "", err
+	}
+	return v.output(dir, v.stashRef)
+}
+
+func (v *cliVCS) Conflicted(dir string) ([]string, error) {
+	if v.conflicts == nil {
+		return nil, ErrUnsupported
+	}
+	out, err := v.output(dir, v.conflicts)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// run executes the tool in dir, folding stderr into the error.
+func (v *cliVCS) run(dir string, args []string) error {
+	_, err := v.output(dir, args)
+	return err
+}
+
+// output executes the tool in dir and returns its trimmed stdout.
+func (v *cliVCS) output(dir string, args []string) (string, error) {
+	cmd := exec.Command(v.tool, args...)
+	if v.ctx != nil {
+		cmd = exec.CommandContext(v.ctx, v.tool, args...)
+		cmd.WaitDelay = commandWaitDelay
+	}
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %s", v.tool, args[0], msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", v.tool, args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,230 @@
+package vcs
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	var none *Config
+	spec := none.Resolve(Git, "")
+	if spec.Kind != Git || spec.Remote != "origin" || spec.Branch != "main" || !slices.Equal(spec.Fetch, []string{"origin"}) {
+		t.Errorf("nil config = %+v, want git origin/main", spec)
+	}
+
+	if spec := none.Resolve(Mercurial, "default"); spec.Remote != "default" || spec.Branch != "default" {
+		t.Errorf("hg defaults = %+v", spec)
+	}
+
+	cfg := &Config{Kind: Jujutsu, Remote: "upstream", Branch: "integration", FetchRemotes: []string{"fork", "upstream", ""}}
+	spec = cfg.Resolve(Git, "main")
+	if spec.Kind != Jujutsu || spec.Remote != "upstream" || spec.Branch != "integration" {
+		t.Errorf("configured spec = %+v", spec)
+	}
+	if !slices.Equal(spec.Fetch, []string{"upstream", "fork"}) {
+		t.Errorf("Fetch = %v, want primary remote first without duplicates", spec.Fetch)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, cfg := range []*Config{nil, {}, {Kind: Jujutsu, Remote: "upstream"}} {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", cfg, err)
+		}
+	}
+	for _, cfg := range []*Config{{Kind: "svn"}, {Remote: "--upload-pack=x"}, {FetchRemotes: []string{"a b"}}} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid config", cfg)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "crew", "max")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := Detect(work); got != Git {
+		t.Errorf("Detect(plain dir) = %s, want git", got)
+	}
+
+	// Colocated jj repos have both .git and .jj
+	if err := os.WriteFile(filepath.Join(work, ".git"), []byte("gitdir: elsewhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(work, ".jj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := Detect(work); got != Jujutsu {
+		t.Errorf("Detect(colocated jj) = %s, want jj", got)
+	}
+
+	hgRepo := filepath.Join(root, "hgrepo")
+	sub := filepath.Join(hgRepo, "src")
+	if err := os.MkdirAll(filepath.Join(hgRepo, ".hg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := Detect(sub); got != Mercurial {
+		t.Errorf("Detect(hg subdir) = %s, want hg", got)
+	}
+}
+
+func TestBackendArgs(t *testing.T) {
+	tests := []struct {
+		kind   Kind
+		fetch  []string
+		update []string
+	}{
+		{Git, []string{"fetch", "upstream"}, []string{"pull", "--rebase", "upstream", "dev"}},
+		{Jujutsu, []string{"git", "fetch", "--remote", "upstream"}, []string{"rebase", "-d", "dev@upstream"}},
+		{Mercurial, []string{"pull", "upstream"}, []string{"rebase", "-d", "dev"}},
+	}
+	for _, tt := range tests {
+		v, err := For(tt.kind)
+		if err != nil {
+			t.Fatalf("For(%s): %v", tt.kind, err)
+		}
+		cli := v.(*cliVCS)
+		if got := cli.fetch("upstream"); !slices.Equal(got, tt.fetch) {
+			t.Errorf("%s fetch = %v, want %v", tt.kind, got, tt.fetch)
+		}
+		if got := cli.update("upstream", "dev"); !slices.Equal(got, tt.update) {
+			t.Errorf("%s update = %v, want %v", tt.kind, got, tt.update)
+		}
+	}
+	if _, err := For("svn"); err == nil {
+		t.Error("For(svn) succeeded")
+	}
+}
+
+func TestIsProtected(t *testing.T) {
+	cfg := &Config{ProtectedBranches: []string{"main", "release/*"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	spec := cfg.Resolve(Git, "main")
+	for branch, want := range map[string]bool{
+		"main":          true,
+		"release/1.2":   true,
+		"release":       false,
+		"polecat/nux-1": false,
+		"":              false,
+	} {
+		if got := spec.IsProtected(branch); got != want {
+			t.Errorf("IsProtected(%q) = %v, want %v", branch, got, want)
+		}
+	}
+
+	if err := (&Config{ProtectedBranches: []string{"release/["}}).Validate(); err == nil {
+		t.Error("Validate accepted a malformed protected branch pattern")
+	}
+}
+
+func TestGitWorkspaceGuards(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	gitRun(t, root, "init", "--bare", "-b", "main", remote)
+	gitRun(t, root, "clone", remote, work)
+	gitRun(t, work, "commit", "--allow-empty", "-m", "first")
+	gitRun(t, work, "push", "origin", "main")
+
+	v, _ := For(Git)
+	if branch, err := v.CurrentBranch(work); err != nil || branch != "main" {
+		t.Errorf("CurrentBranch = %q, %v", branch, err)
+	}
+	if n, err := v.Unpushed(work, "origin"); err != nil || n != 0 {
+		t.Errorf("Unpushed after push = %d, %v", n, err)
+	}
+	gitRun(t, work, "commit", "--allow-empty", "-m", "second")
+	if n, err := v.Unpushed(work, "origin"); err != nil || n != 1 {
+		t.Errorf("Unpushed = %d, %v, want 1", n, err)
+	}
+
+	if dirty, err := v.Dirty(work); err != nil || dirty {
+		t.Errorf("Dirty(clean) = %v, %v", dirty, err)
+	}
+	if err := os.WriteFile(filepath.Join(work, "notes.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := v.Dirty(work); err != nil || !dirty {
+		t.Errorf("Dirty(untracked file) = %v, %v", dirty, err)
+	}
+	ref, err := v.Stash(work, "gt auto-stash test")
+	if err != nil || len(ref) != 40 {
+		t.Fatalf("Stash = %q, %v", ref, err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "notes.txt")); !os.IsNotExist(err) {
+		t.Error("stash left the untracked file in place")
+	}
+
+	for _, kind := range []Kind{Jujutsu, Mercurial} {
+		v, _ := For(kind)
+		if _, err := v.Dirty(work); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s Dirty = %v, want ErrUnsupported", kind, err)
+		}
+	}
+}
+
+func TestGitConflicted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	other := filepath.Join(root, "other")
+	gitRun(t, root, "init", "--bare", "-b", "main", remote)
+	gitRun(t, root, "clone", remote, work)
+	writeAndCommit(t, work, "a.txt", "base\n")
+	gitRun(t, work, "push", "origin", "main")
+	gitRun(t, root, "clone", remote, other)
+	writeAndCommit(t, other, "a.txt", "theirs\n")
+	gitRun(t, other, "push", "origin", "main")
+	writeAndCommit(t, work, "a.txt", "ours\n")
+
+	v, _ := For(Git)
+	if files, err := v.Conflicted(work); err != nil || len(files) != 0 {
+		t.Errorf("Conflicted before update = %v, %v", files, err)
+	}
+	if err := v.Update(work, "origin", "main"); err == nil {
+		t.Fatal("Update of diverged edits to the same line succeeded")
+	}
+	if files, err := v.Conflicted(work); err != nil || !slices.Equal(files, []string{"a.txt"}) {
+		t.Errorf("Conflicted after failed rebase = %v, %v", files, err)
+	}
+
+	v, _ = For(Mercurial)
+	if _, err := v.Conflicted(work); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("hg Conflicted = %v, want ErrUnsupported", err)
+	}
+}
+
+func writeAndCommit(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "add", name)
+	gitRun(t, dir, "commit", "-m", "edit "+name)
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
@@ -0,0 +1,219 @@
+// Package eventstream is the client for the Gas Town daemon's event stream,
+// for dashboards that want to follow a town live.
+//
+// The daemon serves the stream at /events on its API listener (the "api"
+// section of mayor/daemon.json) as server-sent events: one JSON Event per
+// message, named by its type, with the event's sequence number as the SSE id.
+//
+//	c := &eventstream.Client{URL: "http://127.0.0.1:7474", Types: []string{eventstream.TypeAgentState}}
+//	err := c.Stream(ctx, func(ev eventstream.Event) error {
+//		fmt.Println(ev.Identity, ev.Data["to"])
+//		return nil
+//	})
+//
+// Stream reconnects when the connection drops and resumes after the last
+// event it delivered, so a short outage loses nothing the daemon still has
+// buffered.
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types published by the daemon.
+const (
+	// TypeLifecycleStarted: the daemon began executing a lifecycle request.
+	// Data: action, session, dry_run.
+	TypeLifecycleStarted = "lifecycle_started"
+
+	// TypeLifecycleCompleted: a lifecycle request the daemon executed
+	// finished. Data: action, session, dry_run, status (ok|failed), error.
+	TypeLifecycleCompleted = "lifecycle_completed"
+
+	// TypeAgentState: an agent session changed state. Data: to (running,
+	// stopped, dead), session, reason, requested_by.
+	TypeAgentState = "agent_state"
+
+	// TypeMailProcessed: the daemon closed a message from its inbox. The
+	// identity is the sender. Data: message_id, reason, error.
+	TypeMailProcessed = "mail_processed"
+
+	// TypeSyncWarning: a workspace or beads sync went wrong. Data: workdir,
+	// message.
+	TypeSyncWarning = "sync_warning"
+)
+
+// Event is one daemon event.
+type Event struct {
+	// Seq increases by one per event for the life of a daemon process.
+	Seq int64 `json:"seq"`
+
+	Time time.Time `json:"ts"`
+	Type string    `json:"type"`
+
+	// Identity is the agent the event is about (e.g. gastown-crew-max), if any.
+	Identity string `json:"identity,omitempty"`
+
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Client subscribes to a daemon's event stream.
+type Client struct {
+	// URL is the daemon API base URL, e.g. http://127.0.0.1:7474.
+	URL string
+
+	// Types limits the stream to these event types (default: all).
+	Types []string
+
+	// Token is sent as a bearer token, for daemons that require API
+	// authentication (a token with the "read" scope).
+	Token string
+
+	// HTTPClient is used for requests (default: a client without timeout,
+	// since the stream stays open).
+	HTTPClient *http.Client
+
+	// RetryDelay is how long Stream waits before reconnecting (default 2s).
+	RetryDelay time.Duration
+
+	// lastSeq is the sequence number of the last delivered event.
+	lastSeq int64
+}
+
+// ErrStop can be returned by a handler to end Stream without error.
+var ErrStop = errors.New("stop streaming")
+
+// Stream delivers events to handle until ctx is canceled or handle returns
+// an error. Dropped connections are retried; the returned error is ctx's,
+// handle's, or nil for ErrStop.
+func (c *Client) Stream(ctx context.Context, handle func(Event) error) error {
+	delay := c.RetryDelay
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+	for {
+		err := c.Subscribe(ctx, handle)
+		var herr handlerError
+		switch {
+		case errors.As(err, &herr):
+			if errors.Is(herr.err, ErrStop) {
+				return nil
+			}
+			return herr.err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// handlerError marks an error returned by the caller's handler.
+type handlerError struct{ err error }
+
+func (e handlerError) Error() string { return e.err.Error() }
+
+// Subscribe opens one connection and delivers events until it closes. It
+// resumes after the last event this client delivered.
+func (c *Client) Subscribe(ctx context.Context, handle func(Event) error) error {
+	u, err := url.Parse(strings.TrimSuffix(c.URL, "/") + "/events")
+	if err != nil {
+		return err
+	}
+	if len(c.Types) > 0 {
+		q := u.Query()
+		q.Set("types", strings.Join(c.Types, ","))
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.lastSeq > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(c.lastSeq, 10))
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("event stream: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return readEvents(resp.Body, func(ev Event) error {
+		if err := handle(ev); err != nil {
+			return handlerError{err}
+		}
+		c.lastSeq = ev.Seq
+		return nil
+	})
+}
+
+// readEvents parses a server-sent event stream. Only data lines matter:
+// each event's data is one JSON Event. Comments (keepalives) are skipped.
+func readEvents(r io.Reader, handle func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var ev Event
+			err := json.Unmarshal([]byte(data.String()), &ev)
+			data.Reset()
+			if err != nil {
+				return fmt.Errorf("event stream: bad event: %w", err)
+			}
+			if err := handle(ev); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// WriteEvent writes ev to w in the stream's wire format.
+func WriteEvent(w io.Writer, ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+	return err
+}
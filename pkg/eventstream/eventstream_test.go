@@ -0,0 +1,103 @@
+package eventstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteAndReadEvents(t *testing.T) {
+	var buf bytes.Buffer
+	for i, typ := range []string{TypeLifecycleStarted, TypeAgentState} {
+		ev := Event{Seq: int64(i + 1), Type: typ, Identity: "gastown-crew-max", Data: map[string]any{"to": "running"}}
+		if err := WriteEvent(&buf, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString(": keepalive\n\n")
+
+	var got []Event
+	err := readEvents(&buf, func(ev Event) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err == nil {
+		t.Error("readEvents should report the end of the stream")
+	}
+	if len(got) != 2 || got[1].Type != TypeAgentState || got[1].Data["to"] != "running" {
+		t.Errorf("events = %+v", got)
+	}
+}
+
+func TestClientResumesAfterDisconnect(t *testing.T) {
+	var mu sync.Mutex
+	var lastIDs []string
+	var filters []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		filters = append(filters, r.URL.Query().Get("types"))
+		first := len(lastIDs) == 1
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		start := int64(1)
+		if !first {
+			start = 3
+		}
+		// Each connection sends two events, then drops
+		for seq := start; seq < start+2; seq++ {
+			_ = WriteEvent(w, Event{Seq: seq, Type: TypeMailProcessed})
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL + "/", Types: []string{TypeMailProcessed, TypeSyncWarning}, RetryDelay: time.Millisecond}
+	var seqs []string
+	err := c.Stream(context.Background(), func(ev Event) error {
+		seqs = append(seqs, fmt.Sprint(ev.Seq))
+		if ev.Seq == 4 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream = %v", err)
+	}
+	if got := strings.Join(seqs, ","); got != "1,2,3,4" {
+		t.Errorf("delivered %s, want 1,2,3,4", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lastIDs) != 2 || lastIDs[0] != "" || lastIDs[1] != "2" {
+		t.Errorf("Last-Event-ID headers = %q, want [\"\" \"2\"]", lastIDs)
+	}
+	if filters[0] != "mail_processed,sync_warning" {
+		t.Errorf("types filter = %q", filters[0])
+	}
+}
+
+func TestClientHandlerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = WriteEvent(w, Event{Seq: 1, Type: TypeSyncWarning})
+	}))
+	defer srv.Close()
+
+	boom := fmt.Errorf("dashboard full")
+	c := &Client{URL: srv.URL}
+	if err := c.Stream(context.Background(), func(Event) error { return boom }); err != boom {
+		t.Errorf("Stream = %v, want the handler's error", err)
+	}
+
+	bad := httptest.NewServer(http.NotFoundHandler())
+	defer bad.Close()
+	if err := (&Client{URL: bad.URL}).Subscribe(context.Background(), func(Event) error { return nil }); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Subscribe to a non-daemon = %v", err)
+	}
+}